@@ -0,0 +1,126 @@
+package dlq
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// AlertRule fires when more than Threshold unrecovered entries match it,
+// e.g. more than 20 unrecovered no_capable_agent entries in 10 minutes.
+type AlertRule struct {
+	// Name identifies the rule in notifications and must be unique.
+	Name string `json:"name"`
+	// Reason and Source narrow the rule; empty matches any.
+	Reason string `json:"reason,omitempty"`
+	Source string `json:"source,omitempty"`
+	// Window counts only entries that failed within it. Zero counts every
+	// unrecovered entry.
+	Window    time.Duration `json:"window,omitempty"`
+	Threshold int           `json:"threshold"`
+}
+
+// describe renders r for logs and messages, e.g.
+// "reason=no_capable_agent in 10m0s".
+func (r AlertRule) describe() string {
+	s := "all"
+	switch {
+	case r.Reason != "" && r.Source != "":
+		s = fmt.Sprintf("reason=%s source=%s", r.Reason, r.Source)
+	case r.Reason != "":
+		s = "reason=" + r.Reason
+	case r.Source != "":
+		s = "source=" + r.Source
+	}
+	if r.Window > 0 {
+		s += " in " + r.Window.String()
+	}
+	return s
+}
+
+// Alerter evaluates AlertRules against the store on an interval and sends a
+// NotifyAlert notification when a rule starts firing and NotifyAlertResolved
+// when it stops, so systemic failures are noticed without watching /stats.
+type Alerter struct {
+	store    Reader
+	notifier Notifier
+	interval time.Duration
+	rules    []AlertRule
+	// firing holds the names of rules currently over their threshold; only
+	// the evaluation loop touches it.
+	firing map[string]bool
+	done   chan struct{}
+}
+
+// NewAlerter creates an alerter that evaluates rules every interval and
+// notifies n.
+func NewAlerter(store Reader, n Notifier, interval time.Duration, rules ...AlertRule) *Alerter {
+	return &Alerter{
+		store:    store,
+		notifier: n,
+		interval: interval,
+		rules:    rules,
+		firing:   make(map[string]bool),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start begins the periodic evaluation loop. Call with a cancellable context
+// for shutdown.
+func (a *Alerter) Start(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	go func() {
+		defer ticker.Stop()
+		defer close(a.done)
+		for {
+			select {
+			case <-ticker.C:
+				a.evaluate(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Wait blocks until the alerter has stopped.
+func (a *Alerter) Wait() {
+	<-a.done
+}
+
+// evaluate checks every rule once. A rule whose count cannot be read keeps
+// its previous state.
+func (a *Alerter) evaluate(ctx context.Context) {
+	now := time.Now().UTC()
+	unrecovered := false
+	for _, r := range a.rules {
+		opts := ListOpts{Recovered: &unrecovered, Reason: r.Reason, Source: r.Source}
+		if r.Window > 0 {
+			opts.FailedAfter = now.Add(-r.Window)
+		}
+		count, err := a.store.Count(ctx, opts)
+		if err != nil {
+			slog.Error("dlq alerter: failed to evaluate rule", "rule", r.Name, "error", err)
+			continue
+		}
+
+		firing := count > r.Threshold
+		if firing == a.firing[r.Name] {
+			continue
+		}
+		a.firing[r.Name] = firing
+		kind := NotifyAlertResolved
+		if firing {
+			kind = NotifyAlert
+			slog.Warn("dlq alerter: rule firing", "rule", r.Name, "match", r.describe(), "count", count, "threshold", r.Threshold)
+		} else {
+			slog.Info("dlq alerter: rule resolved", "rule", r.Name, "count", count)
+		}
+		notify(ctx, a.notifier, Notification{
+			Kind: kind, At: now, Rule: r.Name, Detail: r.describe(),
+			Reason: r.Reason, Source: r.Source,
+			Unrecovered: count, Threshold: r.Threshold,
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package dlq
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAlerter_FiresAndResolves(t *testing.T) {
+	store := newMockStore()
+	rec := &recordNotifier{}
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		store.seed(Entry{DLQID: fmt.Sprintf("al-%d", i), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: now.Add(-time.Minute)})
+	}
+	// Outside the window and a different reason: neither counts.
+	store.seed(
+		Entry{DLQID: "al-old", Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: now.Add(-time.Hour)},
+		Entry{DLQID: "al-other", Reason: ReasonPolicyDenied, Source: SourceDispatch, FailedAt: now},
+	)
+	a := NewAlerter(store, rec, time.Minute, AlertRule{Name: "no-agents", Reason: ReasonNoCapableAgent, Window: 10 * time.Minute, Threshold: 2})
+
+	a.evaluate(context.Background())
+	a.evaluate(context.Background())
+	if kinds := rec.kinds(); len(kinds) != 1 || kinds[0] != NotifyAlert {
+		t.Fatalf("expected a single alert while firing, got %v", kinds)
+	}
+	if n := rec.notes[0]; n.Rule != "no-agents" || n.Unrecovered != 3 || n.Threshold != 2 || n.Detail != "reason=no_capable_agent in 10m0s" {
+		t.Errorf("unexpected alert: %+v", n)
+	}
+
+	_ = store.MarkRecovered(context.Background(), "al-0", RecoveredByAPIRetry)
+	a.evaluate(context.Background())
+	if kinds := rec.kinds(); len(kinds) != 2 || kinds[1] != NotifyAlertResolved {
+		t.Errorf("expected the alert to resolve, got %v", kinds)
+	}
+}
+
+func TestAlerter_CountErrorKeepsState(t *testing.T) {
+	store := newMockStore()
+	store.listErr = fmt.Errorf("db down")
+	rec := &recordNotifier{}
+	NewAlerter(store, rec, time.Minute, AlertRule{Name: "any", Threshold: 0}).evaluate(context.Background())
+	if kinds := rec.kinds(); len(kinds) != 0 {
+		t.Errorf("expected no notifications when the count fails, got %v", kinds)
+	}
+}
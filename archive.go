@@ -0,0 +1,128 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrEmptyArchiveFilter is returned by Archive when the filter selects
+// neither recovered nor expired entries.
+var ErrEmptyArchiveFilter = errors.New("archive filter must set recovered, recovered_before or failed_before")
+
+// ArchiveFilter selects entries to move to swarm_dlq_archive. At least one of
+// Recovered, RecoveredBefore or FailedBefore must be set; Reason, Source and
+// ExcludeReasons narrow further.
+type ArchiveFilter struct {
+	// Recovered selects entries that were recovered, discarded or expired.
+	Recovered bool `json:"recovered"`
+	// FailedBefore selects entries that failed before this time, recovered
+	// or not, i.e. expired ones.
+	FailedBefore time.Time `json:"failed_before"`
+	// RecoveredBefore selects entries recovered, discarded or expired
+	// before this time.
+	RecoveredBefore time.Time `json:"recovered_before"`
+	Reason          string    `json:"reason,omitempty"`
+	Source          string    `json:"source,omitempty"`
+	// ExcludeReasons leaves entries with these reasons out, e.g. those the
+	// Janitor keeps for a retention of their own.
+	ExcludeReasons []string `json:"exclude_reasons,omitempty"`
+}
+
+func (f ArchiveFilter) where() (string, []any, error) {
+	if !f.Recovered && f.FailedBefore.IsZero() && f.RecoveredBefore.IsZero() {
+		return "", nil, ErrEmptyArchiveFilter
+	}
+	var q string
+	args := []any{}
+	if f.Recovered || !f.RecoveredBefore.IsZero() {
+		q += ` AND recovered = true`
+	}
+	if !f.RecoveredBefore.IsZero() {
+		args = append(args, f.RecoveredBefore)
+		q += fmt.Sprintf(` AND coalesce(recovered_at, discarded_at, expired_at) < $%d`, len(args))
+	}
+	if !f.FailedBefore.IsZero() {
+		args = append(args, f.FailedBefore)
+		q += fmt.Sprintf(` AND failed_at < $%d`, len(args))
+	}
+	if f.Reason != "" {
+		args = append(args, f.Reason)
+		q += fmt.Sprintf(` AND reason = $%d`, len(args))
+	}
+	if f.Source != "" {
+		args = append(args, f.Source)
+		q += fmt.Sprintf(` AND source = $%d`, len(args))
+	}
+	if len(f.ExcludeReasons) > 0 {
+		args = append(args, f.ExcludeReasons)
+		q += fmt.Sprintf(` AND reason <> ALL($%d)`, len(args))
+	}
+	return q, args, nil
+}
+
+// Archive moves the entries selected by f from swarm_dlq to
+// swarm_dlq_archive in one statement and returns how many were moved.
+func (s *Store) Archive(ctx context.Context, f ArchiveFilter) (moved int, err error) {
+	defer s.observe("archive", time.Now(), &err)
+	where, args, err := f.where()
+	if err != nil {
+		return 0, err
+	}
+	args = append(args, s.tenant(ctx))
+	where += " AND " + tenantClause(len(args))
+	tag, err := s.pool.Exec(ctx, `
+		WITH moved AS (
+			DELETE FROM swarm_dlq WHERE true`+where+`
+			RETURNING `+entryColumns+`, payload_agents
+		)
+		INSERT INTO swarm_dlq_archive (`+entryColumns+`, payload_agents)
+		SELECT `+entryColumns+`, payload_agents FROM moved
+	`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("archive dlq: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// ListArchived returns archived entries matching opts, most recently
+// failed first.
+func (s *Store) ListArchived(ctx context.Context, opts ListOpts) (_ []Entry, err error) {
+	defer s.observe("list_archived", time.Now(), &err)
+	where, args := s.tenantFilter(ctx, opts)
+	q := `SELECT ` + entryColumns + `, archived_at FROM swarm_dlq_archive WHERE 1=1` + where +
+		fmt.Sprintf(` ORDER BY failed_at DESC LIMIT $%d`, len(args)+1)
+	args = append(args, listLimit(opts))
+
+	rows, err := s.pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list archived dlq: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var archivedAt time.Time
+		e, err := scanEntry(rows, &archivedAt)
+		if err != nil {
+			return nil, err
+		}
+		e.ArchivedAt = &archivedAt
+		entries = append(entries, *e)
+	}
+	return entries, rows.Err()
+}
+
+// GetArchived retrieves a single archived entry by ID.
+func (s *Store) GetArchived(ctx context.Context, dlqID string) (_ *Entry, err error) {
+	defer s.observe("get_archived", time.Now(), &err)
+	var archivedAt time.Time
+	row := s.pool.QueryRow(ctx, `SELECT `+entryColumns+`, archived_at FROM swarm_dlq_archive WHERE dlq_id = $1 AND `+tenantClause(2), dlqID, s.tenant(ctx))
+	e, err := scanEntry(row, &archivedAt)
+	if err != nil {
+		return nil, err
+	}
+	e.ArchivedAt = &archivedAt
+	return e, nil
+}
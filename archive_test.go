@@ -0,0 +1,89 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_Archive_MovesRecovered(t *testing.T) {
+	store := newMockStore()
+	store.seed(
+		Entry{DLQID: "ar-1", Recovered: true, FailedAt: time.Now()},
+		Entry{DLQID: "ar-2", FailedAt: time.Now()},
+	)
+	r := newTestRouter(store, newMockNATS())
+
+	req := httptest.NewRequest("POST", "/dlq/archive", strings.NewReader(`{"recovered":true}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body map[string]int
+	_ = json.NewDecoder(w.Body).Decode(&body)
+	if body["archived"] != 1 {
+		t.Errorf("expected 1 archived, got %d", body["archived"])
+	}
+	if _, err := store.Get(context.Background(), "ar-1"); err == nil {
+		t.Error("archived entry should leave the hot table")
+	}
+
+	req = httptest.NewRequest("GET", "/dlq/archive/ar-1", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected archived entry to be readable, got %d", w.Code)
+	}
+	var e Entry
+	_ = json.NewDecoder(w.Body).Decode(&e)
+	if e.ArchivedAt == nil {
+		t.Error("expected archived_at to be set")
+	}
+
+	req = httptest.NewRequest("GET", "/dlq/archive/", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var entries []Entry
+	_ = json.NewDecoder(w.Body).Decode(&entries)
+	if len(entries) != 1 {
+		t.Errorf("expected 1 archived entry listed, got %d", len(entries))
+	}
+}
+
+func TestHandler_Archive_OlderThan(t *testing.T) {
+	store := newMockStore()
+	store.seed(
+		Entry{DLQID: "ar-3", FailedAt: time.Now().Add(-48 * time.Hour)},
+		Entry{DLQID: "ar-4", FailedAt: time.Now()},
+	)
+	r := newTestRouter(store, newMockNATS())
+
+	req := httptest.NewRequest("POST", "/dlq/archive", strings.NewReader(`{"older_than":"24h"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body map[string]int
+	_ = json.NewDecoder(w.Body).Decode(&body)
+	if body["archived"] != 1 {
+		t.Errorf("expected only the expired entry to be archived, got %d", body["archived"])
+	}
+}
+
+func TestHandler_Archive_RequiresFilter(t *testing.T) {
+	r := newTestRouter(newMockStore(), newMockNATS())
+
+	for _, body := range []string{`{}`, `{"older_than":"soon"}`} {
+		req := httptest.NewRequest("POST", "/dlq/archive", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package dlq
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrEntryAssigned is returned when assigning an entry that is already
+// assigned to someone else without asking to reassign it.
+var ErrEntryAssigned = errors.New("dlq entry is assigned to another engineer")
+
+type assignRequest struct {
+	// AssignedTo defaults to the requesting actor.
+	AssignedTo string `json:"assigned_to"`
+	// Reassign takes over an entry assigned to someone else.
+	Reassign bool `json:"reassign"`
+}
+
+// handleAssign serves POST /{dlqID}/assign. An entry already assigned to
+// someone else is refused with 409 unless the request sets reassign. The
+// check and the update are one compare-and-swap on the entry's version, so
+// two engineers picking up the same entry cannot both succeed.
+func (h *Handler) handleAssign(w http.ResponseWriter, r *http.Request) {
+	dlqID := chi.URLParam(r, "dlqID")
+
+	var req assignRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid assign body"})
+			return
+		}
+	}
+	who := strings.TrimSpace(req.AssignedTo)
+	if who == "" {
+		who = actorFromRequest(r)
+	}
+	if who == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "assignee is required (body or " + ActorHeader + " header)"})
+		return
+	}
+
+	e, err := h.store.Get(r.Context(), dlqID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "dlq entry not found"})
+		return
+	}
+	if e.AssignedTo != "" && e.AssignedTo != who && !req.Reassign {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": ErrEntryAssigned.Error(), "assigned_to": e.AssignedTo})
+		return
+	}
+	h.updateAssignee(w, r, dlqID, EntryPatch{AssignedTo: &who, Version: e.Version})
+}
+
+// handleUnassign serves DELETE /{dlqID}/assign.
+func (h *Handler) handleUnassign(w http.ResponseWriter, r *http.Request) {
+	none := ""
+	h.updateAssignee(w, r, chi.URLParam(r, "dlqID"), EntryPatch{AssignedTo: &none})
+}
+
+// updateAssignee applies patch and writes the response shared by assign and
+// unassign.
+func (h *Handler) updateAssignee(w http.ResponseWriter, r *http.Request, dlqID string, patch EntryPatch) {
+	entry, err := h.store.UpdateEntry(r.Context(), dlqID, patch)
+	switch {
+	case errors.Is(err, ErrVersionConflict):
+		// Someone else changed the entry between the read and the update.
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, pgx.ErrNoRows):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "dlq entry not found"})
+		return
+	case err != nil:
+		slog.Error("failed to assign dlq entry", "dlq_id", dlqID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	h.auditEntry(r, AuditAssign, dlqID, AuditResultOK, entry.AssignedTo)
+	status := "assigned"
+	if entry.AssignedTo == "" {
+		status = "unassigned"
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": status, "dlq_id": dlqID, "assigned_to": entry.AssignedTo, "version": entry.Version})
+}
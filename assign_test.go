@@ -0,0 +1,60 @@
+package dlq
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func assign(r http.Handler, method, dlqID, actor, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/dlq/"+dlqID+"/assign", strings.NewReader(body))
+	if actor != "" {
+		req.Header.Set(ActorHeader, actor)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandler_Assign(t *testing.T) {
+	store := newMockStore()
+	store.seed(
+		Entry{DLQID: "a1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`)},
+		Entry{DLQID: "a2", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`)},
+	)
+	r := newTestRouter(store, newMockNATS())
+
+	if w := assign(r, "POST", "a1", "alice", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	w := assign(r, "POST", "a1", "bob", "")
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), `"assigned_to":"alice"`) {
+		t.Errorf("expected 409 naming alice, got %d: %s", w.Code, w.Body)
+	}
+	if w := assign(r, "POST", "a1", "bob", `{"reassign":true}`); w.Code != http.StatusOK {
+		t.Errorf("expected reassign to succeed, got %d", w.Code)
+	}
+	if w := assign(r, "POST", "a2", "bob", `{"assigned_to":"carol"}`); w.Code != http.StatusOK {
+		t.Errorf("expected assigning someone else to succeed, got %d", w.Code)
+	}
+	if w := assign(r, "POST", "a2", "", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without an assignee, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/?assigned_to=bob", nil))
+	var entries []Entry
+	_ = json.Unmarshal(w.Body.Bytes(), &entries)
+	if len(entries) != 1 || entries[0].DLQID != "a1" {
+		t.Errorf("expected only a1 assigned to bob, got %+v", entries)
+	}
+
+	if w := assign(r, "DELETE", "a1", "bob", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"unassigned"`) {
+		t.Errorf("expected unassign to succeed, got %d: %s", w.Code, w.Body)
+	}
+	if w := assign(r, "POST", "missing", "bob", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,192 @@
+package dlq
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// AuditAction names an operation recorded in the audit trail.
+type AuditAction string
+
+const (
+	// AuditPayloadRead is a single entry, with its payload, being read.
+	AuditPayloadRead AuditAction = "payload.read"
+	// AuditPayloadExport is an entry's payload leaving in a list or stream.
+	AuditPayloadExport AuditAction = "payload.export"
+)
+
+// Actions on entries, recorded by the Handler, Scanner and Processor.
+const (
+	AuditIngest        AuditAction = "entry.ingest"
+	AuditRetry         AuditAction = "entry.retry"
+	AuditDiscard       AuditAction = "entry.discard"
+	AuditReplay        AuditAction = "entry.replay"
+	AuditUpdate        AuditAction = "entry.update"
+	AuditAssign        AuditAction = "entry.assign"
+	AuditScheduleRetry AuditAction = "entry.schedule_retry"
+)
+
+// Results of an audited action.
+const (
+	AuditResultOK     = "ok"
+	AuditResultFailed = "failed"
+	// AuditResultStale marks a retry that found the entry's task already
+	// finished and marked it recovered without republishing.
+	AuditResultStale = "stale"
+)
+
+// AuditRecord is one entry in the audit trail.
+type AuditRecord struct {
+	Actor  string      `json:"actor"`
+	Action AuditAction `json:"action"`
+	DLQID  string      `json:"dlq_id"`
+	At     time.Time   `json:"at"`
+	// Result is one of the AuditResult* values for actions on entries.
+	Result string `json:"result,omitempty"`
+	// Detail is free-form context, such as the request path or an error.
+	Detail string `json:"detail,omitempty"`
+}
+
+// AuditRecorder persists audit records.
+type AuditRecorder interface {
+	RecordAudit(ctx context.Context, records ...AuditRecord) error
+}
+
+// AuditRecorderFunc adapts a function to AuditRecorder.
+type AuditRecorderFunc func(ctx context.Context, records ...AuditRecord) error
+
+// RecordAudit calls f.
+func (f AuditRecorderFunc) RecordAudit(ctx context.Context, records ...AuditRecord) error {
+	return f(ctx, records...)
+}
+
+// AuditLog is an AuditRecorder that can also return the trail of a single
+// entry. *Store implements it on the swarm_dlq_audit table.
+type AuditLog interface {
+	AuditRecorder
+	ListAudit(ctx context.Context, dlqID string) ([]AuditRecord, error)
+}
+
+// WithAuditRecorder records every read of entry payloads through the API
+// (single entry, archived entry and list/stream exports) and every retry,
+// discard and replay, single or bulk, in a, attributed to the X-DLQ-Actor of
+// the request. If a is an AuditLog, GET /{dlqID}/audit is mounted.
+func WithAuditRecorder(a AuditRecorder) HandlerOption {
+	return func(h *Handler) { h.audit = a }
+}
+
+// recordAudit writes records to a. Failures are logged but never fail the
+// caller; a nil a records nothing.
+func recordAudit(ctx context.Context, a AuditRecorder, records ...AuditRecord) {
+	if a == nil || len(records) == 0 {
+		return
+	}
+	if err := a.RecordAudit(ctx, records...); err != nil {
+		slog.Error("dlq: failed to record audit", "action", records[0].Action, "count", len(records), "error", err)
+	}
+}
+
+// auditEntry records that the request's actor performed action on dlqID.
+func (h *Handler) auditEntry(r *http.Request, action AuditAction, dlqID, result, detail string) {
+	if h.audit == nil {
+		return
+	}
+	if detail == "" {
+		detail = r.URL.RequestURI()
+	}
+	recordAudit(r.Context(), h.audit, AuditRecord{
+		Actor: actorFromRequest(r), Action: action, DLQID: dlqID,
+		At: time.Now().UTC(), Result: result, Detail: detail,
+	})
+}
+
+// auditPayloadAccess records one action per entry. Failures are logged but
+// never fail the read.
+func (h *Handler) auditPayloadAccess(r *http.Request, action AuditAction, entries ...Entry) {
+	if h.audit == nil || len(entries) == 0 {
+		return
+	}
+	actor, now := actorFromRequest(r), time.Now().UTC()
+	records := make([]AuditRecord, len(entries))
+	for i, e := range entries {
+		records[i] = AuditRecord{Actor: actor, Action: action, DLQID: e.DLQID, At: now, Detail: r.URL.Path}
+	}
+	recordAudit(r.Context(), h.audit, records...)
+}
+
+func (h *Handler) handleAudit(w http.ResponseWriter, r *http.Request) {
+	dlqID := chi.URLParam(r, "dlqID")
+	records, err := h.audit.(AuditLog).ListAudit(r.Context(), dlqID)
+	if err != nil {
+		slog.Error("dlq audit list failed", "dlq_id", dlqID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if records == nil {
+		records = []AuditRecord{}
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+// RecordAudit appends records to swarm_dlq_audit in one batch.
+func (s *Store) RecordAudit(ctx context.Context, records ...AuditRecord) (err error) {
+	defer s.observe("record_audit", time.Now(), &err)
+	batch := &pgx.Batch{}
+	tenant := s.tenant(ctx)
+	for _, rec := range records {
+		batch.Queue(`
+			INSERT INTO swarm_dlq_audit (dlq_id, actor, action, result, detail, at, tenant_id)
+			VALUES ($1, $2, $3, nullif($4, ''), nullif($5, ''), $6, $7)
+		`, rec.DLQID, rec.Actor, string(rec.Action), rec.Result, rec.Detail, rec.At, tenant)
+	}
+	if err := s.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("record audit: %w", err)
+	}
+	return nil
+}
+
+// ListAudit returns the audit trail of one entry, oldest first. It works for
+// archived and deleted entries too.
+func (s *Store) ListAudit(ctx context.Context, dlqID string) (_ []AuditRecord, err error) {
+	defer s.observe("list_audit", time.Now(), &err)
+	rows, err := s.pool.Query(ctx, `
+		SELECT dlq_id, actor, action, result, detail, at
+		FROM swarm_dlq_audit
+		WHERE dlq_id = $1 AND `+tenantClause(2)+`
+		ORDER BY at, id
+	`, dlqID, s.tenant(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list audit: %w", err)
+	}
+	defer rows.Close()
+
+	var records []AuditRecord
+	for rows.Next() {
+		var (
+			rec    AuditRecord
+			action string
+			result *string
+			detail *string
+		)
+		if err := rows.Scan(&rec.DLQID, &rec.Actor, &action, &result, &detail, &rec.At); err != nil {
+			return nil, fmt.Errorf("list audit: %w", err)
+		}
+		rec.Action = AuditAction(action)
+		if result != nil {
+			rec.Result = *result
+		}
+		if detail != nil {
+			rec.Detail = *detail
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+var _ AuditLog = (*Store)(nil)
@@ -0,0 +1,194 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// memAudit collects audit records in memory.
+type memAudit struct {
+	mu      sync.Mutex
+	records []AuditRecord
+	err     error
+}
+
+func (m *memAudit) RecordAudit(_ context.Context, records ...AuditRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, records...)
+	return m.err
+}
+
+func (m *memAudit) ListAudit(_ context.Context, dlqID string) ([]AuditRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []AuditRecord
+	for _, rec := range m.records {
+		if rec.DLQID == dlqID {
+			out = append(out, rec)
+		}
+	}
+	return out, nil
+}
+
+// actions returns the recorded actions other than payload reads.
+func (m *memAudit) actions() []AuditRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []AuditRecord
+	for _, rec := range m.records {
+		if rec.Action != AuditPayloadRead && rec.Action != AuditPayloadExport {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+func TestHandler_AuditPayloadAccess(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "au-1"}, Entry{DLQID: "au-2"})
+	audit := &memAudit{}
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, newMockNATS(), WithAuditRecorder(audit)).Routes())
+
+	if w := doAs(r, "GET", "/dlq/au-1", "alice", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if len(audit.records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(audit.records))
+	}
+	rec := audit.records[0]
+	if rec.Actor != "alice" || rec.Action != AuditPayloadRead || rec.DLQID != "au-1" || rec.At.IsZero() {
+		t.Errorf("unexpected record: %+v", rec)
+	}
+
+	doAs(r, "GET", "/dlq/", "bob", "")
+	if len(audit.records) != 3 {
+		t.Fatalf("expected a record per listed entry, got %d", len(audit.records))
+	}
+	for _, rec := range audit.records[1:] {
+		if rec.Actor != "bob" || rec.Action != AuditPayloadExport {
+			t.Errorf("unexpected export record: %+v", rec)
+		}
+	}
+
+	doAs(r, "GET", "/dlq/missing", "bob", "")
+	if len(audit.records) != 3 {
+		t.Error("a failed read must not be recorded")
+	}
+}
+
+func TestHandler_AuditPayloadAccess_ErrorDoesNotFailRead(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "au-3"})
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, newMockNATS(), WithAuditRecorder(&memAudit{err: fmt.Errorf("audit down")})).Routes())
+
+	if w := doAs(r, "GET", "/dlq/au-3", "alice", ""); w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+}
+
+func TestHandler_AuditActions(t *testing.T) {
+	store := newMockStore()
+	payload := json.RawMessage(`{"task_id":"t1"}`)
+	store.seed(
+		Entry{DLQID: "aa-1", OriginalSubject: "swarm.task.request", OriginalPayload: payload},
+		Entry{DLQID: "aa-2", OriginalSubject: "swarm.task.request", OriginalPayload: payload},
+	)
+	audit := &memAudit{}
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, newMockNATS(), WithAuditRecorder(audit)).Routes())
+
+	doAs(r, "POST", "/dlq/aa-1/replay?prefix=staging.", "alice", "")
+	doAs(r, "POST", "/dlq/aa-1/retry", "alice", "")
+	doAs(r, "POST", "/dlq/aa-2/discard", "bob", `{"reason":"duplicate","note":"same as aa-1"}`)
+
+	got := audit.actions()
+	want := []struct {
+		actor  string
+		action AuditAction
+		dlqID  string
+	}{
+		{"alice", AuditReplay, "aa-1"},
+		{"alice", AuditRetry, "aa-1"},
+		{"bob", AuditDiscard, "aa-2"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d action records, got %+v", len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Actor != w.actor || got[i].Action != w.action || got[i].DLQID != w.dlqID || got[i].Result != AuditResultOK {
+			t.Errorf("record %d: expected %+v, got %+v", i, w, got[i])
+		}
+	}
+	if got[0].Detail != "staging.swarm.task.request" {
+		t.Errorf("expected replay subject in detail, got %q", got[0].Detail)
+	}
+	if got[2].Detail != "reason=duplicate; note=same as aa-1" {
+		t.Errorf("expected discard reason and note in detail, got %q", got[2].Detail)
+	}
+
+	w := doAs(r, "GET", "/dlq/aa-1/audit", "carol", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var trail []AuditRecord
+	_ = json.NewDecoder(w.Body).Decode(&trail)
+	if len(trail) != 2 || trail[0].Action != AuditReplay || trail[1].Action != AuditRetry {
+		t.Errorf("unexpected trail for aa-1: %+v", trail)
+	}
+}
+
+func TestHandler_AuditRoute_RequiresAuditLog(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "aa-3"})
+	recorder := AuditRecorderFunc(func(context.Context, ...AuditRecord) error { return nil })
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, newMockNATS(), WithAuditRecorder(recorder)).Routes())
+
+	if w := doAs(r, "GET", "/dlq/aa-3/audit", "alice", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without an AuditLog, got %d", w.Code)
+	}
+}
+
+func TestScanner_Audit(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(Entry{DLQID: "sa-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true})
+	audit := &memAudit{}
+
+	NewScanner(store, nc, time.Minute, WithScannerAudit(audit)).scan(context.Background())
+
+	if len(audit.records) != 1 {
+		t.Fatalf("expected 1 record, got %+v", audit.records)
+	}
+	rec := audit.records[0]
+	if rec.Actor != RecoveredByScanner || rec.Action != AuditRetry || rec.Result != AuditResultOK || rec.DLQID != "sa-1" {
+		t.Errorf("unexpected record: %+v", rec)
+	}
+}
+
+func TestProcessor_Audit(t *testing.T) {
+	store := newMockStore()
+	audit := &memAudit{}
+	proc := NewProcessor(store, WithProcessorAudit(audit))
+
+	data, _ := json.Marshal(Entry{DLQID: "pa-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Source: SourceWarren, FailedAt: time.Now()})
+	proc.Process(context.Background(), SubjectAgentBootFailure, data)
+	proc.Process(context.Background(), SubjectAgentBootFailure, data)
+
+	if len(audit.records) != 1 {
+		t.Fatalf("expected one ingest record (duplicates are not recorded), got %+v", audit.records)
+	}
+	if rec := audit.records[0]; rec.Actor != SourceWarren || rec.Action != AuditIngest || rec.DLQID != "pa-1" {
+		t.Errorf("unexpected record: %+v", rec)
+	}
+}
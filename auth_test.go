@@ -0,0 +1,138 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func authRouter(store *mockStore, opts ...HandlerOption) chi.Router {
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, newMockNATS(), opts...).Routes())
+	return r
+}
+
+func doWithKey(r http.Handler, method, path, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if key != "" {
+		req.Header.Set(APIKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuth_RequireAuthForMutations(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "au-r1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`)})
+	r := authRouter(store,
+		WithAuthMiddleware(APIKeyAuth(map[string]Principal{"k-alice": {Subject: "alice"}})),
+		WithRequireAuth(),
+	)
+
+	if w := doWithKey(r, "GET", "/dlq/au-r1", ""); w.Code != http.StatusOK {
+		t.Errorf("anonymous read: expected 200, got %d", w.Code)
+	}
+	if w := doWithKey(r, "POST", "/dlq/au-r1/retry", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous retry: expected 401, got %d", w.Code)
+	}
+	if w := doWithKey(r, "POST", "/dlq/au-r1/retry", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("bad key: expected 401, got %d", w.Code)
+	}
+	if w := doWithKey(r, "POST", "/dlq/au-r1/retry", "k-alice"); w.Code != http.StatusOK {
+		t.Fatalf("authenticated retry: expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	e, _ := store.Get(context.Background(), "au-r1")
+	if e.RecoveredBy != "api-retry:alice" {
+		t.Errorf("expected recovered_by api-retry:alice, got %q", e.RecoveredBy)
+	}
+}
+
+func TestAuth_PrincipalOverridesActorHeader(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "au-p1"}, Entry{DLQID: "au-p2"})
+	audit := &memAudit{}
+	r := authRouter(store,
+		WithAuthMiddleware(APIKeyAuth(map[string]Principal{"k-alice": {Subject: "alice"}})),
+		WithAuditRecorder(audit),
+	)
+
+	req := httptest.NewRequest("POST", "/dlq/au-p1/discard", nil)
+	req.Header.Set(APIKeyHeader, "k-alice")
+	req.Header.Set(ActorHeader, "mallory")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Without a principal the header is not trusted either.
+	doAs(r, "POST", "/dlq/au-p2/discard", "mallory", "")
+
+	got := audit.actions()
+	if len(got) != 2 || got[0].Actor != "alice" || got[1].Actor != "" {
+		t.Errorf("unexpected audit actors: %+v", got)
+	}
+	e, _ := store.Get(context.Background(), "au-p1")
+	if e.DiscardedBy != "manual-discard:alice" {
+		t.Errorf("expected discarded_by manual-discard:alice, got %q", e.DiscardedBy)
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	verify := func(_ context.Context, token string) (Principal, error) {
+		if token != "good" {
+			return Principal{}, errors.New("bad signature")
+		}
+		return Principal{Subject: "svc-ops", Scopes: []string{ScopePayload}}, nil
+	}
+	var seen Principal
+	h := BearerAuth(verify)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = PrincipalFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		header string
+		code   int
+		sub    string
+	}{
+		{"", http.StatusOK, ""},
+		{"Bearer good", http.StatusOK, "svc-ops"},
+		{"Bearer forged", http.StatusUnauthorized, ""},
+		{"Basic dXNlcjpwdw==", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		seen = Principal{}
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tt.code || seen.Subject != tt.sub {
+			t.Errorf("%q: expected %d/%q, got %d/%q", tt.header, tt.code, tt.sub, w.Code, seen.Subject)
+		}
+	}
+}
+
+func TestPrincipalScopes_FeedPayloadMask(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "au-m1", OriginalPayload: json.RawMessage(`{"email":"a@example.com"}`)})
+	r := authRouter(store,
+		WithAuthMiddleware(APIKeyAuth(map[string]Principal{
+			"k-ops":     {Subject: "ops", Scopes: []string{ScopePayload}},
+			"k-support": {Subject: "support"},
+		})),
+		WithPayloadMask(PayloadMask{Scopes: PrincipalScopes, Fields: []string{"email"}}),
+	)
+
+	if w := doWithKey(r, "GET", "/dlq/au-m1", "k-ops"); !strings.Contains(w.Body.String(), "a@example.com") {
+		t.Errorf("ops should see the payload: %s", w.Body)
+	}
+	if w := doWithKey(r, "GET", "/dlq/au-m1", "k-support"); strings.Contains(w.Body.String(), "a@example.com") {
+		t.Errorf("support should see a masked payload: %s", w.Body)
+	}
+}
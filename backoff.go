@@ -0,0 +1,32 @@
+package dlq
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Defaults for the Scanner's per-entry retry backoff.
+const (
+	DefaultRetryBackoffBase = time.Minute
+	DefaultRetryBackoffMax  = time.Hour
+)
+
+// RetryBackoff spaces out automatic retries of an entry that keeps failing.
+// After its nth failed auto-retry an entry waits Base*2^(n-1), capped at Max,
+// with jitter so entries that failed together are not retried together.
+type RetryBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay returns how long to wait after the given number of failed
+// auto-retries. The result lies between half and all of the exponential
+// delay.
+func (b RetryBackoff) Delay(failures int) time.Duration {
+	d := b.Base
+	for i := 1; i < failures && d < b.Max; i++ {
+		d *= 2
+	}
+	d = min(d, b.Max)
+	return d/2 + rand.N(d/2+1)
+}
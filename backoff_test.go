@@ -0,0 +1,29 @@
+package dlq
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff_Delay(t *testing.T) {
+	b := RetryBackoff{Base: time.Minute, Max: 10 * time.Minute}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 8 * time.Minute},
+		{5, 10 * time.Minute},
+		{50, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			got := b.Delay(tt.failures)
+			if got < tt.want/2 || got > tt.want {
+				t.Fatalf("Delay(%d) = %v, want between %v and %v", tt.failures, got, tt.want/2, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nats-io/nats.go"
+)
+
+// BlobReader fetches payloads written by a BlobStore. Blob stores that
+// implement it let the API serve and retry offloaded payloads.
+type BlobReader interface {
+	Get(ctx context.Context, ref string) ([]byte, error)
+}
+
+// objectRefPrefix marks payload_refs written by ObjectStoreBlobs.
+const objectRefPrefix = "nats-object:"
+
+// ObjectStoreBlobs is a BlobStore and BlobReader on a JetStream object store
+// bucket. Payloads are stored under their dlq_id.
+type ObjectStoreBlobs struct {
+	obs nats.ObjectStore
+}
+
+var (
+	_ BlobStore  = (*ObjectStoreBlobs)(nil)
+	_ BlobReader = (*ObjectStoreBlobs)(nil)
+)
+
+// NewObjectStoreBlobs stores offloaded payloads in obs, e.g. the result of
+// js.CreateObjectStore(&nats.ObjectStoreConfig{Bucket: "dlq-payloads"}).
+func NewObjectStoreBlobs(obs nats.ObjectStore) *ObjectStoreBlobs {
+	return &ObjectStoreBlobs{obs: obs}
+}
+
+// Put stores data under key and returns a "nats-object:<key>" reference.
+func (b *ObjectStoreBlobs) Put(ctx context.Context, key string, data []byte) (string, error) {
+	if _, err := b.obs.PutBytes(key, data, nats.Context(ctx)); err != nil {
+		return "", fmt.Errorf("put payload %s: %w", key, err)
+	}
+	return objectRefPrefix + key, nil
+}
+
+// Get returns the payload behind a reference returned by Put.
+func (b *ObjectStoreBlobs) Get(ctx context.Context, ref string) ([]byte, error) {
+	key, ok := strings.CutPrefix(ref, objectRefPrefix)
+	if !ok {
+		return nil, fmt.Errorf("not an object store payload ref: %q", ref)
+	}
+	data, err := b.obs.GetBytes(key, nats.Context(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("get payload %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// Delete removes the payload behind a reference returned by Put.
+func (b *ObjectStoreBlobs) Delete(ctx context.Context, ref string) error {
+	key, ok := strings.CutPrefix(ref, objectRefPrefix)
+	if !ok {
+		return fmt.Errorf("not an object store payload ref: %q", ref)
+	}
+	if err := b.obs.Delete(key); err != nil && !errors.Is(err, nats.ErrObjectNotFound) {
+		return fmt.Errorf("delete payload %s: %w", key, err)
+	}
+	return nil
+}
+
+// WithStoreBlobs deletes the offloaded payloads of entries removed by Erase,
+// DeleteOlderThan and Purge from b. If b is also a BlobReader, Erase matches
+// the request against offloaded payloads too, since the stored copy is only
+// a truncated prefix.
+func WithStoreBlobs(b BlobStore) StoreOption {
+	return func(s *Store) { s.blobs = b }
+}
+
+// deleteBlobs removes the offloaded payloads behind refs, if the store has
+// a BlobStore. Empty refs are skipped.
+func (s *Store) deleteBlobs(ctx context.Context, refs []string) error {
+	if s.blobs == nil {
+		return nil
+	}
+	for _, ref := range refs {
+		if ref == "" {
+			continue
+		}
+		if err := s.blobs.Delete(ctx, ref); err != nil {
+			return fmt.Errorf("delete offloaded payload: %w", err)
+		}
+	}
+	return nil
+}
+
+// WithBlobReader mounts GET /{dlqID}/payload and lets retries of offloaded
+// entries republish the full payload fetched from b.
+func WithBlobReader(b BlobReader) HandlerOption {
+	return func(h *Handler) { h.blobs = b }
+}
+
+// loadOffloaded replaces a truncated payload with the full one from the
+// blob store, if it was offloaded and a BlobReader is configured. It
+// reports whether e now carries the full payload.
+func (h *Handler) loadOffloaded(ctx context.Context, e *Entry) (bool, error) {
+	if h.blobs == nil || !e.PayloadTruncated || e.PayloadRef == "" {
+		return false, nil
+	}
+	data, err := h.blobs.Get(ctx, e.PayloadRef)
+	if err != nil {
+		return false, err
+	}
+	if !json.Valid(data) {
+		return false, errors.New("offloaded payload is not valid JSON")
+	}
+	e.OriginalPayload = data
+	e.PayloadTruncated = false
+	return true, nil
+}
+
+// handlePayload serves an entry's full original payload, fetching offloaded
+// payloads from the blob store.
+func (h *Handler) handlePayload(w http.ResponseWriter, r *http.Request) {
+	if h.mask.applies(r) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": ScopePayload + " scope required to read payloads"})
+		return
+	}
+	dlqID := chi.URLParam(r, "dlqID")
+	entry, err := h.store.Get(r.Context(), dlqID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "dlq entry not found"})
+		return
+	}
+	if _, err := h.loadOffloaded(r.Context(), entry); err != nil {
+		slog.Error("dlq payload fetch failed", "dlq_id", dlqID, "ref", entry.PayloadRef, "error", err)
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "failed to fetch offloaded payload"})
+		return
+	}
+	h.auditPayloadAccess(r, AuditPayloadRead, *entry)
+
+	w.Header().Set("Content-Type", "application/json")
+	if entry.PayloadTruncated {
+		w.Header().Set("X-DLQ-Payload-Truncated", "true")
+	}
+	w.WriteHeader(http.StatusOK)
+	if len(entry.OriginalPayload) == 0 {
+		_, _ = w.Write([]byte("null"))
+		return
+	}
+	_, _ = w.Write(entry.OriginalPayload)
+}
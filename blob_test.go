@@ -0,0 +1,148 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nats-io/nats.go"
+)
+
+// fakeObjectStore implements the byte helpers of nats.ObjectStore.
+type fakeObjectStore struct {
+	nats.ObjectStore
+	objects map[string][]byte
+}
+
+func (f *fakeObjectStore) PutBytes(name string, data []byte, _ ...nats.ObjectOpt) (*nats.ObjectInfo, error) {
+	f.objects[name] = data
+	return &nats.ObjectInfo{}, nil
+}
+
+func (f *fakeObjectStore) GetBytes(name string, _ ...nats.GetObjectOpt) ([]byte, error) {
+	data, ok := f.objects[name]
+	if !ok {
+		return nil, nats.ErrObjectNotFound
+	}
+	return data, nil
+}
+
+func (f *fakeObjectStore) Delete(name string) error {
+	if _, ok := f.objects[name]; !ok {
+		return nats.ErrObjectNotFound
+	}
+	delete(f.objects, name)
+	return nil
+}
+
+func TestObjectStoreBlobs(t *testing.T) {
+	ctx := context.Background()
+	blobs := NewObjectStoreBlobs(&fakeObjectStore{objects: map[string][]byte{}})
+
+	ref, err := blobs.Put(ctx, "ob-1", []byte(`{"big":true}`))
+	if err != nil || ref != "nats-object:ob-1" {
+		t.Fatalf("unexpected put: %q %v", ref, err)
+	}
+	data, err := blobs.Get(ctx, ref)
+	if err != nil || string(data) != `{"big":true}` {
+		t.Errorf("unexpected get: %s %v", data, err)
+	}
+	if _, err := blobs.Get(ctx, "s3://bucket/ob-1"); err == nil {
+		t.Error("foreign refs should be rejected")
+	}
+	if _, err := blobs.Get(ctx, "nats-object:missing"); !errors.Is(err, nats.ErrObjectNotFound) {
+		t.Errorf("expected ErrObjectNotFound, got %v", err)
+	}
+
+	if err := blobs.Delete(ctx, ref); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := blobs.Get(ctx, ref); !errors.Is(err, nats.ErrObjectNotFound) {
+		t.Errorf("expected the payload gone, got %v", err)
+	}
+	if err := blobs.Delete(ctx, ref); err != nil {
+		t.Errorf("deleting a missing payload should succeed, got %v", err)
+	}
+}
+
+func offloadedEntry(t *testing.T, blobs *memBlobStore) Entry {
+	t.Helper()
+	l := PayloadLimit{MaxBytes: 16, Policy: PayloadOffload, Blobs: blobs}
+	e := bigEntry("ob-2")
+	e.OriginalSubject, e.FailedAt = "swarm.task.request", time.Now()
+	e, _, err := l.enforce(context.Background(), e)
+	if err != nil || !e.PayloadTruncated || e.PayloadRef == "" {
+		t.Fatalf("expected an offloaded entry, got %+v (%v)", e, err)
+	}
+	return e
+}
+
+func TestHandler_Payload(t *testing.T) {
+	blobs := &memBlobStore{}
+	store := newMockStore()
+	e := offloadedEntry(t, blobs)
+	store.seed(e)
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, newMockNATS(), WithBlobReader(blobs)).Routes())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/ob-2/payload", nil))
+	if w.Code != http.StatusOK || w.Body.String() != string(bigEntry("").OriginalPayload) {
+		t.Errorf("expected the full offloaded payload, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-DLQ-Payload-Truncated") != "" {
+		t.Error("a fetched payload is not truncated")
+	}
+
+	blobs.err = errors.New("object store down")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/ob-2/payload", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected 502 when the blob store fails, got %d", w.Code)
+	}
+}
+
+func TestHandler_Payload_Masked(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "ob-3", OriginalPayload: []byte(`{}`)})
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, newMockNATS(), WithBlobReader(&memBlobStore{}),
+		WithPayloadMask(PayloadMask{Scopes: func(*http.Request) []string { return nil }})).Routes())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/ob-3/payload", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without the payload scope, got %d", w.Code)
+	}
+}
+
+func TestHandler_Retry_Offloaded(t *testing.T) {
+	blobs := &memBlobStore{}
+	store := newMockStore()
+	store.seed(offloadedEntry(t, blobs))
+	nc := newMockNATS()
+
+	r := newTestRouter(store, nc)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/dlq/ob-2/retry", nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("without a blob reader a truncated payload cannot be retried, got %d", w.Code)
+	}
+
+	withBlobs := chi.NewRouter()
+	withBlobs.Mount("/dlq", NewHandler(store, nc, WithBlobReader(blobs)).Routes())
+	w = httptest.NewRecorder()
+	withBlobs.ServeHTTP(w, httptest.NewRequest("POST", "/dlq/ob-2/retry", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	msgs := nc.published()
+	if len(msgs) != 1 || !strings.Contains(string(msgs[0].Data), strings.Repeat("x", 100)) {
+		t.Errorf("expected the full payload republished, got %+v", msgs)
+	}
+}
@@ -0,0 +1,236 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Defaults for NewCircuitBreaker.
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned by a CircuitBreaker while it refuses publishes.
+var ErrCircuitOpen = errors.New("dlq: nats circuit breaker open")
+
+// natsConnState reports whether a connection is up. *nats.Conn satisfies it.
+type natsConnState interface {
+	IsConnected() bool
+}
+
+// CircuitBreaker is a NATSPublisher that fails fast with ErrCircuitOpen once
+// NATS looks dead, instead of letting every publish wait for its timeout.
+// It opens after a number of consecutive publish failures, or at once when
+// the wrapped connection reports it is disconnected, and stays open for a
+// cooldown. After that a single publish is let through as a probe: success
+// closes the breaker, failure opens it for another cooldown.
+//
+// Share one breaker between the Handler and the Scanner. The Handler answers
+// retries and replays with 503 and a Retry-After header while it is open, and
+// the Scanner ends its pass early without counting the outage against the
+// entries' retry budgets.
+type CircuitBreaker struct {
+	nc        NATSPublisher
+	threshold int
+	cooldown  time.Duration
+	metrics   *Metrics
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+var (
+	_ NATSPublisher        = (*CircuitBreaker)(nil)
+	_ NATSContextPublisher = (*CircuitBreaker)(nil)
+	_ NATSMsgPublisher     = (*CircuitBreaker)(nil)
+)
+
+// BreakerOption configures a CircuitBreaker.
+type BreakerOption func(*CircuitBreaker)
+
+// WithBreakerThreshold opens the breaker after n consecutive failures. The
+// default is DefaultBreakerThreshold.
+func WithBreakerThreshold(n int) BreakerOption {
+	return func(b *CircuitBreaker) {
+		if n > 0 {
+			b.threshold = n
+		}
+	}
+}
+
+// WithBreakerCooldown keeps the breaker open for d before probing NATS
+// again. The default is DefaultBreakerCooldown.
+func WithBreakerCooldown(d time.Duration) BreakerOption {
+	return func(b *CircuitBreaker) {
+		if d > 0 {
+			b.cooldown = d
+		}
+	}
+}
+
+// WithBreakerMetrics records the breaker's state in MetricBreakerOpen and
+// refused publishes in MetricBreakerRejected.
+func WithBreakerMetrics(m *Metrics) BreakerOption {
+	return func(b *CircuitBreaker) { b.metrics = m }
+}
+
+// NewCircuitBreaker wraps nc in a circuit breaker.
+func NewCircuitBreaker(nc NATSPublisher, opts ...BreakerOption) *CircuitBreaker {
+	b := &CircuitBreaker{
+		nc:        nc,
+		threshold: DefaultBreakerThreshold,
+		cooldown:  DefaultBreakerCooldown,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Publish implements NATSPublisher.
+func (b *CircuitBreaker) Publish(subject string, data []byte) error {
+	return b.do(func() error { return b.nc.Publish(subject, data) })
+}
+
+// PublishContext implements NATSContextPublisher.
+func (b *CircuitBreaker) PublishContext(ctx context.Context, subject string, data []byte) error {
+	return b.do(func() error { return publishContext(ctx, b.nc, subject, data) })
+}
+
+// PublishMsg implements NATSMsgPublisher. Headers are dropped if the wrapped
+// publisher does not support them.
+func (b *CircuitBreaker) PublishMsg(msg *nats.Msg) error {
+	return b.do(func() error {
+		if mp, ok := b.nc.(NATSMsgPublisher); ok {
+			return mp.PublishMsg(msg)
+		}
+		return b.nc.Publish(msg.Subject, msg.Data)
+	})
+}
+
+// RetryAfter returns how long the breaker stays open, or 0 if it would let a
+// publish through now.
+func (b *CircuitBreaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.checkConnection(now)
+	if b.openedAt.IsZero() {
+		return 0
+	}
+	if d := b.openedAt.Add(b.cooldown).Sub(now); d > 0 {
+		return d
+	}
+	if b.probing {
+		// Another caller is probing; it will decide shortly.
+		return time.Second
+	}
+	return 0
+}
+
+func (b *CircuitBreaker) do(publish func() error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := publish()
+	b.record(err)
+	return err
+}
+
+// allow reports ErrCircuitOpen if the publish must be refused. Once the
+// cooldown has passed it admits a single probe.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.checkConnection(now)
+	switch {
+	case b.openedAt.IsZero():
+		return nil
+	case now.Sub(b.openedAt) < b.cooldown || b.probing:
+		b.metrics.Inc(MetricBreakerRejected)
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+// record updates the breaker with the result of a publish. A cancelled
+// context says nothing about NATS, so it is not counted.
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasProbe := b.probing
+	b.probing = false
+	switch {
+	case err == nil:
+		if !b.openedAt.IsZero() {
+			slog.Info("dlq: nats circuit breaker closed")
+		}
+		b.failures = 0
+		b.openedAt = time.Time{}
+		b.metrics.Set(MetricBreakerOpen, 0)
+	case errors.Is(err, context.Canceled):
+	default:
+		b.failures++
+		if wasProbe || b.failures >= b.threshold {
+			b.open(b.now(), err)
+		}
+	}
+}
+
+// checkConnection opens the breaker if the wrapped connection is down.
+// Callers must hold b.mu.
+func (b *CircuitBreaker) checkConnection(now time.Time) {
+	if cs, ok := b.nc.(natsConnState); ok && !cs.IsConnected() && !b.probing && (b.openedAt.IsZero() || now.Sub(b.openedAt) >= b.cooldown) {
+		b.open(now, nats.ErrDisconnected)
+	}
+}
+
+// open starts a cooldown. Callers must hold b.mu.
+func (b *CircuitBreaker) open(now time.Time, cause error) {
+	if b.openedAt.IsZero() {
+		slog.Warn("dlq: nats circuit breaker opened", "failures", b.failures, "cooldown", b.cooldown, "error", cause)
+	}
+	b.openedAt = now
+	b.metrics.Set(MetricBreakerOpen, 1)
+}
+
+// circuitRetryAfter returns how long nc refuses publishes if it is an open
+// CircuitBreaker, or 0.
+func circuitRetryAfter(nc NATSPublisher) time.Duration {
+	if b, ok := nc.(*CircuitBreaker); ok {
+		return b.RetryAfter()
+	}
+	return 0
+}
+
+// writeCircuitOpen responds 503 with a Retry-After of at least retryAfter.
+func writeCircuitOpen(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(retryAfter.Seconds())))))
+	writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": ErrCircuitOpen.Error()})
+}
+
+// natsUnavailable responds 503 and reports true if h's NATS publisher is an
+// open CircuitBreaker.
+func (h *Handler) natsUnavailable(w http.ResponseWriter) bool {
+	d := circuitRetryAfter(h.nc)
+	if d <= 0 {
+		return false
+	}
+	writeCircuitOpen(w, d)
+	return true
+}
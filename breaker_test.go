@@ -0,0 +1,82 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	nc := newMockNATS()
+	m := NewMetrics()
+	b := NewCircuitBreaker(nc, WithBreakerThreshold(2), WithBreakerCooldown(time.Minute), WithBreakerMetrics(m))
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	nc.err = errors.New("nats: timeout")
+	for i := 0; i < 2; i++ {
+		if err := b.Publish("s", nil); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("publish %d: breaker opened too early", i)
+		}
+	}
+	if err := b.Publish("s", nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen after 2 failures, got %v", err)
+	}
+	if got := b.RetryAfter(); got != time.Minute {
+		t.Errorf("RetryAfter = %s", got)
+	}
+	if m.Get(MetricBreakerOpen) != 1 || m.Get(MetricBreakerRejected) != 1 {
+		t.Errorf("unexpected metrics %v", m.Snapshot())
+	}
+
+	// A failed probe opens it for another cooldown.
+	now = now.Add(time.Minute)
+	if err := b.Publish("s", nil); errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("expected a probe after the cooldown")
+	}
+	if err := b.Publish("s", nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("a failed probe should reopen the breaker, got %v", err)
+	}
+
+	// A successful probe closes it.
+	now = now.Add(time.Minute)
+	nc.err = nil
+	if err := b.PublishContext(context.Background(), "s", nil); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if b.RetryAfter() != 0 || m.Get(MetricBreakerOpen) != 0 {
+		t.Error("expected the breaker to close")
+	}
+	if len(nc.messages) != 1 {
+		t.Errorf("expected only the probe to reach NATS, got %d messages", len(nc.messages))
+	}
+}
+
+func TestCircuitBreaker_HandlerAndScanner(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "cb-1", OriginalSubject: "swarm.task.request", Recoverable: true})
+	nc := newMockNATS()
+	nc.err = errors.New("nats: connection closed")
+	b := NewCircuitBreaker(nc, WithBreakerThreshold(1), WithBreakerCooldown(90*time.Second))
+	r := newTestRouter(store, b)
+
+	if w := doWithKey(r, "POST", "/dlq/cb-1/retry", ""); w.Code != http.StatusInternalServerError {
+		t.Fatalf("first failure: expected 500, got %d", w.Code)
+	}
+	for _, path := range []string{"/dlq/cb-1/retry", "/dlq/retry-all", "/dlq/cb-1/replay?prefix=replay."} {
+		w := doWithKey(r, "POST", path, "")
+		if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "90" {
+			t.Errorf("%s: expected 503 with Retry-After 90, got %d %q", path, w.Code, w.Header().Get("Retry-After"))
+		}
+	}
+	if e, _ := store.Get(context.Background(), "cb-1"); e.Status == StatusRetrying {
+		t.Error("a refused retry must not leave the entry retrying")
+	}
+
+	NewScanner(store, b, time.Minute).scan(context.Background())
+	if e, _ := store.Get(context.Background(), "cb-1"); e.AutoRetryCount != 0 || e.Recovered {
+		t.Errorf("an open breaker must not use up the entry's retries, got %+v", e)
+	}
+}
@@ -0,0 +1,156 @@
+package dlq
+
+import (
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// BundleVersion is the format version written by WriteBundle.
+const BundleVersion = 1
+
+// MaxBundleBytes bounds the compressed size of an imported bundle.
+const MaxBundleBytes = 64 << 20
+
+// ErrBundleSignature is returned when a bundle's signature does not match.
+var ErrBundleSignature = errors.New("dlq bundle signature mismatch")
+
+// Bundle is a portable set of entries, used to copy failures from one
+// environment to another for replay.
+type Bundle struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Entries   []Entry   `json:"entries"`
+}
+
+// signedBundle is the wire form: the bundle JSON exactly as signed, plus an
+// HMAC-SHA256 of it, gzip-compressed as a whole.
+type signedBundle struct {
+	Bundle    json.RawMessage `json:"bundle"`
+	Signature string          `json:"signature"`
+}
+
+// WriteBundle writes entries to w as a gzip-compressed bundle signed with key.
+func WriteBundle(w io.Writer, key []byte, entries []Entry) error {
+	if entries == nil {
+		entries = []Entry{}
+	}
+	body, err := json.Marshal(Bundle{Version: BundleVersion, CreatedAt: time.Now().UTC(), Entries: entries})
+	if err != nil {
+		return fmt.Errorf("marshal bundle: %w", err)
+	}
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(signedBundle{Bundle: body, Signature: signBundle(key, body)}); err != nil {
+		return fmt.Errorf("write bundle: %w", err)
+	}
+	return zw.Close()
+}
+
+// ReadBundle reads a bundle written by WriteBundle and verifies its signature
+// against key.
+func ReadBundle(r io.Reader, key []byte) (*Bundle, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("read bundle: %w", err)
+	}
+	defer zr.Close()
+
+	var signed signedBundle
+	if err := json.NewDecoder(zr).Decode(&signed); err != nil {
+		return nil, fmt.Errorf("read bundle: %w", err)
+	}
+	want, err := hex.DecodeString(signed.Signature)
+	if err != nil || !hmac.Equal(want, bundleMAC(key, signed.Bundle)) {
+		return nil, ErrBundleSignature
+	}
+	var b Bundle
+	if err := json.Unmarshal(signed.Bundle, &b); err != nil {
+		return nil, fmt.Errorf("decode bundle: %w", err)
+	}
+	if b.Version != BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+	return &b, nil
+}
+
+func bundleMAC(key, body []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+func signBundle(key, body []byte) string {
+	return hex.EncodeToString(bundleMAC(key, body))
+}
+
+// WithBundleKey mounts GET and POST /bundle for exporting and importing
+// entry bundles signed with key. Use the same key in every environment that
+// exchanges bundles.
+func WithBundleKey(key []byte) HandlerOption {
+	return func(h *Handler) { h.bundleKey = key }
+}
+
+// handleExportBundle writes the entries matching the list filters as a
+// signed bundle.
+func (h *Handler) handleExportBundle(w http.ResponseWriter, r *http.Request) {
+	if h.mask.applies(r) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": ScopePayload + " scope required to export payloads"})
+		return
+	}
+	entries, err := h.store.List(r.Context(), listOptsFromQuery(r.URL.Query()))
+	if err != nil {
+		slog.Error("bundle export: list failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	h.auditPayloadAccess(r, AuditPayloadExport, entries...)
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="dlq-bundle.json.gz"`)
+	if err := WriteBundle(w, h.bundleKey, entries); err != nil {
+		slog.Error("bundle export: write failed", "error", err)
+	}
+}
+
+// handleImportBundle verifies a bundle and inserts its entries. Entries are
+// imported unrecovered and unclaimed; ids that already exist are skipped.
+func (h *Handler) handleImportBundle(w http.ResponseWriter, r *http.Request) {
+	b, err := ReadBundle(http.MaxBytesReader(w, r.Body, MaxBundleBytes), h.bundleKey)
+	if errors.Is(err, ErrBundleSignature) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	for i := range b.Entries {
+		b.Entries[i].reopen()
+	}
+	created, err := h.store.InsertBatch(r.Context(), b.Entries)
+	if err != nil {
+		slog.Error("bundle import: insert failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	imported := 0
+	for _, c := range created {
+		if c {
+			imported++
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]int{
+		"imported": imported,
+		"skipped":  len(b.Entries) - imported,
+		"total":    len(b.Entries),
+	})
+}
@@ -0,0 +1,109 @@
+package dlq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestBundle_RoundTrip(t *testing.T) {
+	key := []byte("secret")
+	in := []Entry{{DLQID: "bd-1", OriginalPayload: json.RawMessage(`{"task_id":"t1"}`)}}
+
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, key, in); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	b, err := ReadBundle(bytes.NewReader(buf.Bytes()), key)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if b.Version != BundleVersion || len(b.Entries) != 1 || string(b.Entries[0].OriginalPayload) != `{"task_id":"t1"}` {
+		t.Errorf("unexpected bundle: %+v", b)
+	}
+
+	if _, err := ReadBundle(bytes.NewReader(buf.Bytes()), []byte("other")); !errors.Is(err, ErrBundleSignature) {
+		t.Errorf("expected ErrBundleSignature with the wrong key, got %v", err)
+	}
+	if _, err := ReadBundle(bytes.NewReader([]byte("not gzip")), key); err == nil {
+		t.Error("expected error for garbage input")
+	}
+}
+
+func TestHandler_Bundle_ExportImport(t *testing.T) {
+	key := []byte("shared")
+	prod := newMockStore()
+	now := time.Now()
+	prod.seed(
+		Entry{DLQID: "bd-2", OriginalSubject: "swarm.task.request", Reason: ReasonNoCapableAgent},
+		Entry{DLQID: "bd-3", OriginalSubject: "swarm.task.request", Reason: ReasonNoCapableAgent, Recovered: true, RecoveredAt: &now},
+		Entry{DLQID: "bd-4", OriginalSubject: "swarm.task.request", Reason: ReasonBootFailure},
+	)
+	pr := chi.NewRouter()
+	pr.Mount("/dlq", NewHandler(prod, newMockNATS(), WithBundleKey(key)).Routes())
+
+	req := httptest.NewRequest("GET", "/dlq/bundle?reason="+ReasonNoCapableAgent, nil)
+	w := httptest.NewRecorder()
+	pr.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/gzip" {
+		t.Fatalf("export: got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	bundle := w.Body.Bytes()
+
+	staging := newMockStore()
+	staging.seed(Entry{DLQID: "bd-2"})
+	sr := chi.NewRouter()
+	sr.Mount("/dlq", NewHandler(staging, newMockNATS(), WithBundleKey(key)).Routes())
+
+	req = httptest.NewRequest("POST", "/dlq/bundle", bytes.NewReader(bundle))
+	w = httptest.NewRecorder()
+	sr.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("import: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body map[string]int
+	_ = json.NewDecoder(w.Body).Decode(&body)
+	if body["imported"] != 1 || body["skipped"] != 1 || body["total"] != 2 {
+		t.Errorf("unexpected import result: %v", body)
+	}
+	e, err := staging.Get(context.Background(), "bd-3")
+	if err != nil || e.Recovered {
+		t.Errorf("expected bd-3 imported unrecovered, got %+v (%v)", e, err)
+	}
+}
+
+func TestHandler_Bundle_Import_BadSignature(t *testing.T) {
+	var buf bytes.Buffer
+	_ = WriteBundle(&buf, []byte("prod"), []Entry{{DLQID: "bd-5"}})
+
+	store := newMockStore()
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, newMockNATS(), WithBundleKey([]byte("staging"))).Routes())
+
+	req := httptest.NewRequest("POST", "/dlq/bundle", &buf)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", w.Code)
+	}
+	if len(store.entries) != 0 {
+		t.Error("nothing may be imported from an unverified bundle")
+	}
+}
+
+func TestHandler_Bundle_NotMountedWithoutKey(t *testing.T) {
+	r := newTestRouter(newMockStore(), newMockNATS())
+	req := httptest.NewRequest("POST", "/dlq/bundle", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code == http.StatusOK {
+		t.Error("bundle import must not be mounted without a key")
+	}
+}
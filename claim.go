@@ -0,0 +1,162 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// ActorHeader identifies the operator making an API call. It is used as the
+// claim holder and to decide whether a claim blocks a retry or discard. With
+// auth middleware installed (WithAuthMiddleware) it is ignored in favour of
+// the authenticated Principal.
+const ActorHeader = "X-DLQ-Actor"
+
+// DefaultClaimTTL is the lease length used when a claim request gives none.
+const DefaultClaimTTL = 5 * time.Minute
+
+// MaxClaimTTL caps how long a single claim may last.
+const MaxClaimTTL = time.Hour
+
+// ErrEntryClaimed is returned when an entry is under an unexpired claim held
+// by someone else.
+var ErrEntryClaimed = errors.New("dlq entry is claimed by another operator")
+
+// claimedByOther reports whether e is under an unexpired claim not held by
+// actor.
+func (e Entry) claimedByOther(actor string, now time.Time) bool {
+	return e.ClaimedBy != "" && e.ClaimedBy != actor &&
+		e.ClaimExpiresAt != nil && now.Before(*e.ClaimExpiresAt)
+}
+
+// actorFromRequest returns the caller's identity, or "" if unknown.
+func actorFromRequest(r *http.Request) string {
+	if p, ok := PrincipalFromContext(r.Context()); ok {
+		return p.Subject
+	}
+	return strings.TrimSpace(r.Header.Get(ActorHeader))
+}
+
+// Claim places a lease on an entry for holder until now+ttl. Claiming an
+// entry whose claim has expired, or that holder already claims, succeeds;
+// otherwise ErrEntryClaimed is returned.
+func (s *Store) Claim(ctx context.Context, dlqID, holder string, ttl time.Duration) (_ time.Time, err error) {
+	defer s.observe("claim", time.Now(), &err)
+	var expires time.Time
+	err = s.pool.QueryRow(ctx, `
+		UPDATE swarm_dlq
+		SET claimed_by = $2, claim_expires_at = now() + $3 * interval '1 microsecond'
+		WHERE dlq_id = $1 AND `+tenantClause(4)+`
+		  AND (claimed_by IS NULL OR claimed_by = $2 OR claim_expires_at <= now())
+		RETURNING claim_expires_at
+	`, dlqID, holder, ttl.Microseconds(), s.tenant(ctx)).Scan(&expires)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		if err := s.pool.QueryRow(ctx, `SELECT exists(SELECT 1 FROM swarm_dlq WHERE dlq_id = $1 AND `+tenantClause(2)+`)`, dlqID, s.tenant(ctx)).Scan(&exists); err != nil {
+			return time.Time{}, fmt.Errorf("claim dlq entry: %w", err)
+		}
+		if exists {
+			return time.Time{}, ErrEntryClaimed
+		}
+		return time.Time{}, fmt.Errorf("claim dlq entry %s: %w", dlqID, pgx.ErrNoRows)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("claim dlq entry: %w", err)
+	}
+	return expires, nil
+}
+
+// ReleaseClaim drops holder's claim on an entry. Releasing an entry holder
+// does not claim is a no-op.
+func (s *Store) ReleaseClaim(ctx context.Context, dlqID, holder string) (err error) {
+	defer s.observe("release_claim", time.Now(), &err)
+	_, err = s.pool.Exec(ctx, `
+		UPDATE swarm_dlq SET claimed_by = NULL, claim_expires_at = NULL
+		WHERE dlq_id = $1 AND claimed_by = $2 AND `+tenantClause(3)+`
+	`, dlqID, holder, s.tenant(ctx))
+	if err != nil {
+		return fmt.Errorf("release claim: %w", err)
+	}
+	return nil
+}
+
+// claimRequest is the body of POST /{dlqID}/claim. TTL is a Go duration.
+// Holder defaults to the caller; with auth middleware it must be the
+// authenticated Principal if given.
+type claimRequest struct {
+	Holder string `json:"holder"`
+	TTL    string `json:"ttl"`
+}
+
+func (h *Handler) handleClaim(w http.ResponseWriter, r *http.Request) {
+	dlqID := chi.URLParam(r, "dlqID")
+
+	var req claimRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid claim body"})
+			return
+		}
+	}
+	holder := strings.TrimSpace(req.Holder)
+	if p, ok := PrincipalFromContext(r.Context()); ok {
+		// An authenticated caller can only claim in its own name.
+		if holder != "" && holder != p.Subject {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "claim holder must be the authenticated caller"})
+			return
+		}
+		holder = p.Subject
+	}
+	if holder == "" {
+		holder = actorFromRequest(r)
+	}
+	if holder == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "claim holder is required (body or " + ActorHeader + " header)"})
+		return
+	}
+	ttl := DefaultClaimTTL
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 || d > MaxClaimTTL {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "ttl must be a positive duration of at most " + MaxClaimTTL.String()})
+			return
+		}
+		ttl = d
+	}
+
+	expires, err := h.store.Claim(r.Context(), dlqID, holder, ttl)
+	switch {
+	case errors.Is(err, ErrEntryClaimed):
+		writeJSON(w, http.StatusLocked, map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, pgx.ErrNoRows):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "dlq entry not found"})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"dlq_id": dlqID, "claimed_by": holder, "claim_expires_at": expires})
+}
+
+func (h *Handler) handleReleaseClaim(w http.ResponseWriter, r *http.Request) {
+	dlqID := chi.URLParam(r, "dlqID")
+	holder := actorFromRequest(r)
+	if holder == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": ActorHeader + " header is required"})
+		return
+	}
+	if err := h.store.ReleaseClaim(r.Context(), dlqID, holder); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "released", "dlq_id": dlqID})
+}
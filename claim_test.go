@@ -0,0 +1,201 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func doAs(r http.Handler, method, path, actor, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if actor != "" {
+		req.Header.Set(ActorHeader, actor)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandler_Claim(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(Entry{DLQID: "cl-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`)})
+	r := newTestRouter(store, nc)
+
+	w := doAs(r, "POST", "/dlq/cl-1/claim", "alice", `{"ttl":"2m"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	e, _ := store.Get(context.Background(), "cl-1")
+	if e.ClaimedBy != "alice" || e.ClaimExpiresAt == nil || time.Until(*e.ClaimExpiresAt) > 2*time.Minute {
+		t.Fatalf("unexpected claim: %+v", e)
+	}
+
+	if w := doAs(r, "POST", "/dlq/cl-1/claim", "bob", ""); w.Code != http.StatusLocked {
+		t.Errorf("second claim: expected 423, got %d", w.Code)
+	}
+	if w := doAs(r, "POST", "/dlq/cl-1/retry", "bob", ""); w.Code != http.StatusLocked {
+		t.Errorf("retry by other: expected 423, got %d", w.Code)
+	}
+	if w := doAs(r, "POST", "/dlq/cl-1/discard", "bob", ""); w.Code != http.StatusLocked {
+		t.Errorf("discard by other: expected 423, got %d", w.Code)
+	}
+	if len(nc.published()) != 0 {
+		t.Fatal("a claimed entry must not be republished by another operator")
+	}
+
+	if w := doAs(r, "POST", "/dlq/cl-1/retry", "alice", ""); w.Code != http.StatusOK {
+		t.Errorf("retry by holder: expected 200, got %d", w.Code)
+	}
+}
+
+func TestHandler_Claim_HolderIsPrincipal(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "cl-8"})
+	r := authRouter(store, WithAuthMiddleware(APIKeyAuth(map[string]Principal{
+		"k-alice": {Subject: "alice"},
+	})))
+	claim := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/dlq/cl-8/claim", strings.NewReader(body))
+		req.Header.Set(APIKeyHeader, "k-alice")
+		req.Header.Set(ActorHeader, "bob")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := claim(`{"holder":"bob"}`); w.Code != http.StatusForbidden {
+		t.Errorf("claim in another operator's name: expected 403, got %d", w.Code)
+	}
+	if e, _ := store.Get(context.Background(), "cl-8"); e.ClaimedBy != "" {
+		t.Fatalf("a refused claim must not be placed, got holder %q", e.ClaimedBy)
+	}
+	for _, body := range []string{"", `{"holder":"alice"}`} {
+		if w := claim(body); w.Code != http.StatusOK {
+			t.Errorf("%q: expected 200, got %d", body, w.Code)
+		}
+		if e, _ := store.Get(context.Background(), "cl-8"); e.ClaimedBy != "alice" {
+			t.Errorf("%q: expected the claim held by the principal, got %q", body, e.ClaimedBy)
+		}
+	}
+}
+
+func TestHandler_Claim_Release(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "cl-2"})
+	r := newTestRouter(store, newMockNATS())
+
+	doAs(r, "POST", "/dlq/cl-2/claim", "alice", "")
+	if w := doAs(r, "DELETE", "/dlq/cl-2/claim", "bob", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if e, _ := store.Get(context.Background(), "cl-2"); e.ClaimedBy != "alice" {
+		t.Error("only the holder may release a claim")
+	}
+	doAs(r, "DELETE", "/dlq/cl-2/claim", "alice", "")
+	if w := doAs(r, "POST", "/dlq/cl-2/discard", "bob", ""); w.Code != http.StatusOK {
+		t.Errorf("discard after release: expected 200, got %d", w.Code)
+	}
+}
+
+func TestHandler_Claim_Expired(t *testing.T) {
+	store := newMockStore()
+	past := time.Now().Add(-time.Minute)
+	store.seed(Entry{DLQID: "cl-3", ClaimedBy: "alice", ClaimExpiresAt: &past})
+	r := newTestRouter(store, newMockNATS())
+
+	if w := doAs(r, "POST", "/dlq/cl-3/claim", "bob", ""); w.Code != http.StatusOK {
+		t.Errorf("expected an expired claim to be taken over, got %d", w.Code)
+	}
+}
+
+func TestHandler_Claim_Validation(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "cl-4"})
+	r := newTestRouter(store, newMockNATS())
+
+	cases := []struct {
+		name, path, actor, body string
+		want                    int
+	}{
+		{"no holder", "/dlq/cl-4/claim", "", "", http.StatusBadRequest},
+		{"bad ttl", "/dlq/cl-4/claim", "alice", `{"ttl":"soon"}`, http.StatusBadRequest},
+		{"ttl too long", "/dlq/cl-4/claim", "alice", `{"ttl":"3h"}`, http.StatusBadRequest},
+		{"holder in body", "/dlq/cl-4/claim", "", `{"holder":"carol"}`, http.StatusOK},
+		{"not found", "/dlq/missing/claim", "alice", "", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if w := doAs(r, "POST", tc.path, tc.actor, tc.body); w.Code != tc.want {
+				t.Errorf("expected %d, got %d: %s", tc.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+// claimAfterGetStore is a mockStore on which holder claims the entry right
+// after every Get, as a concurrent operator would.
+type claimAfterGetStore struct {
+	*mockStore
+	holder string
+}
+
+func (s *claimAfterGetStore) Get(ctx context.Context, dlqID string) (*Entry, error) {
+	e, err := s.mockStore.Get(ctx, dlqID)
+	if err == nil {
+		_, _ = s.Claim(ctx, dlqID, s.holder, time.Minute)
+	}
+	return e, err
+}
+
+func TestHandler_Discard_ClaimTakenMeanwhile(t *testing.T) {
+	store := &claimAfterGetStore{mockStore: newMockStore(), holder: "alice"}
+	store.seed(Entry{DLQID: "cl-7"})
+	r := newTestRouter(store, newMockNATS())
+
+	w := doAs(r, "POST", "/dlq/cl-7/discard", "bob", "")
+	if w.Code != http.StatusLocked || !strings.Contains(w.Body.String(), `"claimed_by":"alice"`) {
+		t.Fatalf("expected 423 naming alice, got %d: %s", w.Code, w.Body.String())
+	}
+	if e, _ := store.mockStore.Get(context.Background(), "cl-7"); e.Recovered {
+		t.Error("an entry claimed before the discard landed must not be discarded")
+	}
+}
+
+func TestHandler_RetryAll_SkipsClaimed(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	future := time.Now().Add(time.Minute)
+	store.seed(
+		Entry{DLQID: "cl-5", OriginalSubject: "swarm.task.request", Recoverable: true, ClaimedBy: "alice", ClaimExpiresAt: &future},
+		Entry{DLQID: "cl-6", OriginalSubject: "swarm.task.request", Recoverable: true},
+	)
+	r := newTestRouter(store, nc)
+
+	w := doAs(r, "POST", "/dlq/retry-all", "bob", "")
+	var body map[string]int
+	_ = json.NewDecoder(w.Body).Decode(&body)
+	if body["retried"] != 1 || body["claimed"] != 1 {
+		t.Errorf("expected 1 retried and 1 claimed, got %v", body)
+	}
+	if e, _ := store.Get(context.Background(), "cl-5"); e.Recovered {
+		t.Error("claimed entry must not be retried")
+	}
+}
+
+func TestScanner_Scan_SkipsClaimed(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	future := time.Now().Add(time.Minute)
+	store.seed(Entry{DLQID: "cl-7", OriginalSubject: "swarm.task.request", Recoverable: true, ClaimedBy: "alice", ClaimExpiresAt: &future})
+
+	NewScanner(store, nc, time.Minute).scan(context.Background())
+
+	if len(nc.published()) != 0 {
+		t.Error("scanner must not retry a claimed entry")
+	}
+}
@@ -0,0 +1,377 @@
+// Command dlqctl operates the swarm DLQ from a terminal. It talks to the DLQ
+// HTTP API, or — when no API URL is given — serves the same API in-process
+// against the database named by DATABASE_URL.
+//
+//	dlqctl list --reason boot_failure
+//	dlqctl retry <dlq_id>
+//	dlqctl stats
+//	dlqctl purge --older-than 30d
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nats-io/nats.go"
+
+	dlq "github.com/MikeSquared-Agency/swarm-dlq"
+)
+
+const usage = `usage: dlqctl [global flags] <command> [flags]
+
+commands:
+  list     list entries (--reason, --source, --status, --recovered, --limit, --json)
+  retry    republish an entry to its original subject: retry <dlq_id>
+  stats    show DLQ counts (--json)
+  purge    delete handled entries older than a duration: purge --older-than 30d
+
+global flags:
+  --url      DLQ API base URL, e.g. http://dlq:8080/dlq (env DLQ_URL)
+  --api-key  API key sent as X-API-Key (env DLQ_API_KEY)
+  --token    bearer token (env DLQ_TOKEN)
+  --timeout  request timeout (default 30s)
+
+Without --url, dlqctl connects to DATABASE_URL directly; retry then also
+needs NATS_URL.
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "dlqctl:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("dlqctl", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(fs.Output(), usage) }
+	baseURL := fs.String("url", os.Getenv("DLQ_URL"), "")
+	apiKey := fs.String("api-key", os.Getenv("DLQ_API_KEY"), "")
+	token := fs.String("token", os.Getenv("DLQ_TOKEN"), "")
+	timeout := fs.Duration("timeout", 30*time.Second, "")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	c := &client{base: strings.TrimSuffix(*baseURL, "/"), http: &http.Client{}, header: http.Header{}}
+	if c.base == "" {
+		closeDirect, err := c.direct(ctx)
+		if err != nil {
+			return err
+		}
+		defer closeDirect()
+	}
+	if *apiKey != "" {
+		c.header.Set(dlq.APIKeyHeader, *apiKey)
+	}
+	if *token != "" {
+		c.header.Set("Authorization", "Bearer "+*token)
+	}
+	c.header.Set(dlq.ActorHeader, actor())
+
+	cmd, rest := fs.Arg(0), fs.Args()[1:]
+	switch cmd {
+	case "list":
+		return cmdList(ctx, c, rest, out)
+	case "retry":
+		return cmdRetry(ctx, c, rest, out)
+	case "stats":
+		return cmdStats(ctx, c, rest, out)
+	case "purge":
+		return cmdPurge(ctx, c, rest, out)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
+
+func cmdList(ctx context.Context, c *client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	reason := fs.String("reason", "", "only entries with this reason")
+	source := fs.String("source", "", "only entries from this source")
+	status := fs.String("status", "", "only entries in this lifecycle status")
+	recovered := fs.String("recovered", "false", `"true", "false" or "" for both`)
+	limit := fs.Int("limit", 50, "maximum entries to return")
+	asJSON := fs.Bool("json", false, "print entries as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	q := url.Values{}
+	setIf(q, "reason", *reason)
+	setIf(q, "source", *source)
+	setIf(q, "status", *status)
+	setIf(q, "recovered", *recovered)
+	q.Set("limit", strconv.Itoa(*limit))
+
+	var entries []dlq.Entry
+	if err := c.do(ctx, http.MethodGet, "/?"+q.Encode(), nil, &entries); err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(out, entries)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DLQ_ID\tREASON\tSOURCE\tSTATUS\tFAILED_AT\tSUBJECT")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.DLQID, e.Reason, e.Source, e.Status, e.FailedAt.Format(time.RFC3339), e.OriginalSubject)
+	}
+	return tw.Flush()
+}
+
+func cmdRetry(ctx context.Context, c *client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("retry", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: dlqctl retry <dlq_id>")
+	}
+	id := fs.Arg(0)
+
+	if err := c.do(ctx, http.MethodPost, "/"+url.PathEscape(id)+"/retry", nil, nil); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "retried %s\n", id)
+	return nil
+}
+
+func cmdStats(ctx context.Context, c *client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print stats as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var st dlq.Stats
+	if err := c.do(ctx, http.MethodGet, "/stats", nil, &st); err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(out, st)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "total\t%d\n", st.Total)
+	fmt.Fprintf(tw, "unrecovered\t%d\n", st.Unrecovered)
+	fmt.Fprintf(tw, "recoverable\t%d\n", st.Recoverable)
+	fmt.Fprintf(tw, "expired\t%d\n", st.Expired)
+	fmt.Fprintf(tw, "oldest_unrecovered\t%s\n", seconds(st.OldestUnrecoveredAge))
+	fmt.Fprintf(tw, "recovered_last_24h\t%d\n", st.RecoveredLast24h)
+	ttr := st.TimeToRecovery
+	fmt.Fprintf(tw, "time_to_recovery\tavg %s  p50 %s  p90 %s  p99 %s  (n=%d)\n",
+		seconds(ttr.Avg), seconds(ttr.P50), seconds(ttr.P90), seconds(ttr.P99), ttr.Count)
+	for _, g := range []struct {
+		name   string
+		counts map[string]int
+	}{{"reason", st.ByReason}, {"source", st.BySource}, {"status", st.ByStatus}, {"subject", st.BySubject}} {
+		keys := make([]string, 0, len(g.counts))
+		for k := range g.counts {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(tw, "%s/%s\t%d\n", g.name, k, g.counts[k])
+		}
+	}
+	return tw.Flush()
+}
+
+// seconds formats a duration in seconds, rounded to the second.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Second)
+}
+
+func cmdPurge(ctx context.Context, c *client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+	olderThan := fs.String("older-than", "", `age of handled entries to delete, e.g. "30d" or "720h"`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	d, err := parseAge(*olderThan)
+	if err != nil {
+		return fmt.Errorf("--older-than: %w", err)
+	}
+
+	var res struct {
+		Deleted int `json:"deleted"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/purge", map[string]string{"older_than": d.String()}, &res); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "deleted %d entries\n", res.Deleted)
+	return nil
+}
+
+// parseAge parses a Go duration, additionally accepting a whole number of
+// days such as "30d".
+func parseAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, errors.New("required")
+	}
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration %q must be positive", s)
+	}
+	return d, nil
+}
+
+// client issues requests against the DLQ API rooted at base.
+type client struct {
+	base   string
+	http   *http.Client
+	header http.Header
+}
+
+// direct points c at an in-process Handler backed by DATABASE_URL, so the CLI
+// works without a running DLQ service. The returned func releases the
+// connections.
+func (c *client) direct(ctx context.Context) (func(), error) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		return nil, errors.New("set --url (or DLQ_URL) or DATABASE_URL")
+	}
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("connect database: %w", err)
+	}
+
+	var pub dlq.NATSPublisher = noNATS{}
+	var nc *nats.Conn
+	if u := os.Getenv("NATS_URL"); u != "" {
+		if nc, err = nats.Connect(u); err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("connect nats: %w", err)
+		}
+		pub = nc
+	}
+
+	h := dlq.NewHandler(dlq.NewStore(pool), pub)
+	c.base = "http://dlq.local"
+	c.http = &http.Client{Transport: handlerTransport{h.Routes()}}
+	return func() {
+		if nc != nil {
+			_ = nc.Drain()
+		}
+		pool.Close()
+	}, nil
+}
+
+func (c *client) do(ctx context.Context, method, path string, body, out any) error {
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, rd)
+	if err != nil {
+		return err
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		if e.Error == "" {
+			e.Error = http.StatusText(resp.StatusCode)
+		}
+		return fmt.Errorf("%s %s: %d %s", method, path, resp.StatusCode, e.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// handlerTransport serves requests with an in-process http.Handler.
+type handlerTransport struct{ h http.Handler }
+
+func (t handlerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.h.ServeHTTP(rec, r)
+	return rec.Result(), nil
+}
+
+// noNATS fails every publish; it stands in when NATS_URL is unset so that
+// read-only commands work and retry reports why it cannot.
+type noNATS struct{}
+
+func (noNATS) Publish(string, []byte) error {
+	return errors.New("NATS_URL is not set")
+}
+
+func actor() string {
+	if u := os.Getenv("USER"); u != "" {
+		return "dlqctl:" + u
+	}
+	return "dlqctl"
+}
+
+func setIf(q url.Values, k, v string) {
+	if v != "" {
+		q.Set(k, v)
+	}
+}
+
+func printJSON(out io.Writer, v any) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
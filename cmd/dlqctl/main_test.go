@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	cases := map[string]time.Duration{
+		"30d":  30 * 24 * time.Hour,
+		"720h": 720 * time.Hour,
+		"90m":  90 * time.Minute,
+	}
+	for in, want := range cases {
+		got, err := parseAge(in)
+		if err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "d", "xd", "-1d", "0h", "soon"} {
+		if _, err := parseAge(in); err == nil {
+			t.Errorf("parseAge(%q) should fail", in)
+		}
+	}
+}
+
+func TestRun_AgainstAPI(t *testing.T) {
+	var got []*http.Request
+	var purgeBody map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r)
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/dlq/":
+			_, _ = w.Write([]byte(`[{"dlq_id":"d-1","reason":"boot_failure","source":"agent","status":"open","original_subject":"swarm.task.1"}]`))
+		case r.Method == "POST" && r.URL.Path == "/dlq/d-1/retry":
+			_, _ = w.Write([]byte(`{"status":"retried","dlq_id":"d-1"}`))
+		case r.Method == "POST" && r.URL.Path == "/dlq/d-2/retry":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"not found"}`))
+		case r.Method == "POST" && r.URL.Path == "/dlq/purge":
+			_ = json.NewDecoder(r.Body).Decode(&purgeBody)
+			_, _ = w.Write([]byte(`{"deleted":3}`))
+		default:
+			w.WriteHeader(http.StatusTeapot)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	base := []string{"--url", srv.URL + "/dlq/", "--api-key", "k"}
+
+	var out bytes.Buffer
+	if err := run(ctx, append(base, "list", "--reason", "boot_failure"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if q := got[0].URL.Query(); q.Get("reason") != "boot_failure" || q.Get("recovered") != "false" {
+		t.Errorf("unexpected list query %q", got[0].URL.RawQuery)
+	}
+	if got[0].Header.Get("X-API-Key") != "k" {
+		t.Error("expected the API key to be sent")
+	}
+	if !strings.Contains(out.String(), "d-1") || !strings.Contains(out.String(), "swarm.task.1") {
+		t.Errorf("list output missing entry:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := run(ctx, append(base, "retry", "d-1"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(ctx, append(base, "retry", "d-2"), &out); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected the API error to surface, got %v", err)
+	}
+
+	out.Reset()
+	if err := run(ctx, append(base, "purge", "--older-than", "30d"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if purgeBody["older_than"] != "720h0m0s" {
+		t.Errorf("expected 30d sent as 720h, got %q", purgeBody["older_than"])
+	}
+	if !strings.Contains(out.String(), "deleted 3") {
+		t.Errorf("unexpected purge output %q", out.String())
+	}
+}
@@ -0,0 +1,174 @@
+package dlq
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// PayloadCompression is a codec for payloads stored compressed.
+type PayloadCompression string
+
+const (
+	CompressGzip PayloadCompression = "gzip"
+	CompressZstd PayloadCompression = "zstd"
+)
+
+// maxDecompressedPayload bounds a decompressed payload, so a corrupt or
+// hostile row cannot exhaust memory.
+const maxDecompressedPayload = 64 << 20
+
+// WithStoreCompression makes Insert and InsertBatch store payloads of at
+// least minBytes compressed with c in payload_compressed, leaving
+// original_payload NULL. Reads decompress transparently whatever the
+// current setting, so compression can be turned on or off at any time.
+//
+// Only the payload is compressed: retry_history stays jsonb because stats
+// and the agent filter query it. Compressed payloads are opaque to SQL, so
+// their agent and agent_id values are copied to payload_agents for the
+// agent filter, and compliance erasure decompresses them in the store to
+// find matches.
+func WithStoreCompression(c PayloadCompression, minBytes int) StoreOption {
+	return func(s *Store) {
+		s.compression = c
+		s.compressMin = minBytes
+	}
+}
+
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) { return zstd.NewWriter(nil) })
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecompressedPayload))
+	})
+)
+
+// encodePayload returns the original_payload, payload_compressed and
+// payload_encoding values to insert for p.
+func (s *Store) encodePayload(p json.RawMessage) (json.RawMessage, []byte, *string, error) {
+	if s.compression == "" || len(p) < s.compressMin || len(p) == 0 {
+		return p, nil, nil, nil
+	}
+	z, err := compressPayload(s.compression, p)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	enc := string(s.compression)
+	return nil, z, &enc, nil
+}
+
+func compressPayload(c PayloadCompression, p []byte) ([]byte, error) {
+	switch c {
+	case CompressGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(p); err != nil {
+			return nil, fmt.Errorf("gzip payload: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("gzip payload: %w", err)
+		}
+		return buf.Bytes(), nil
+	case CompressZstd:
+		enc, err := zstdEncoder()
+		if err != nil {
+			return nil, fmt.Errorf("zstd payload: %w", err)
+		}
+		return enc.EncodeAll(p, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown payload compression %q", c)
+	}
+}
+
+func decompressPayload(encoding string, z []byte) (json.RawMessage, error) {
+	switch PayloadCompression(encoding) {
+	case CompressGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(z))
+		if err != nil {
+			return nil, fmt.Errorf("gunzip payload: %w", err)
+		}
+		p, err := io.ReadAll(io.LimitReader(zr, maxDecompressedPayload+1))
+		if err != nil {
+			return nil, fmt.Errorf("gunzip payload: %w", err)
+		}
+		if len(p) > maxDecompressedPayload {
+			return nil, fmt.Errorf("gunzip payload: larger than %d bytes", maxDecompressedPayload)
+		}
+		return p, nil
+	case CompressZstd:
+		dec, err := zstdDecoder()
+		if err != nil {
+			return nil, fmt.Errorf("zstd payload: %w", err)
+		}
+		p, err := dec.DecodeAll(z, nil)
+		if err != nil {
+			return nil, fmt.Errorf("zstd payload: %w", err)
+		}
+		return p, nil
+	default:
+		return nil, fmt.Errorf("unknown payload encoding %q", encoding)
+	}
+}
+
+// payloadAgents returns the string agent and agent_id values of a JSON
+// object payload, the fields the agent filter reads from original_payload.
+func payloadAgents(p json.RawMessage) []string {
+	var fields struct {
+		Agent   any `json:"agent"`
+		AgentID any `json:"agent_id"`
+	}
+	if json.Unmarshal(p, &fields) != nil {
+		return nil
+	}
+	var agents []string
+	for _, v := range []any{fields.Agent, fields.AgentID} {
+		if a, ok := v.(string); ok {
+			agents = append(agents, a)
+		}
+	}
+	return agents
+}
+
+// jsonContains reports whether a contains b with the semantics of jsonb @>:
+// objects contain every key of b with contained values, arrays contain
+// every element of b somewhere, and scalars must be equal.
+func jsonContains(a, b any) bool {
+	switch b := b.(type) {
+	case map[string]any:
+		am, ok := a.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, bv := range b {
+			av, ok := am[k]
+			if !ok || !jsonContains(av, bv) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		aa, ok := a.([]any)
+		if !ok {
+			return false
+		}
+		for _, bv := range b {
+			found := false
+			for _, av := range aa {
+				if jsonContains(av, bv) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
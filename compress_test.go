@@ -0,0 +1,135 @@
+package dlq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompressPayload_RoundTrip(t *testing.T) {
+	payload := []byte(`{"task_id":"t1","body":"` + strings.Repeat("x", 4096) + `"}`)
+	for _, c := range []PayloadCompression{CompressGzip, CompressZstd} {
+		t.Run(string(c), func(t *testing.T) {
+			z, err := compressPayload(c, payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(z) >= len(payload) {
+				t.Errorf("expected %s to shrink a repetitive payload, got %d >= %d bytes", c, len(z), len(payload))
+			}
+			got, err := decompressPayload(string(c), z)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, payload) {
+				t.Errorf("round-trip mismatch: %s", got)
+			}
+		})
+	}
+
+	if _, err := compressPayload("lz4", payload); err == nil {
+		t.Error("unknown codecs should fail to compress")
+	}
+	if _, err := decompressPayload("lz4", payload); err == nil {
+		t.Error("unknown encodings should fail to decompress")
+	}
+	if _, err := decompressPayload(string(CompressGzip), []byte("not gzip")); err == nil {
+		t.Error("corrupt payloads should fail to decompress")
+	}
+}
+
+func TestStore_EncodePayload(t *testing.T) {
+	small := json.RawMessage(`{"a":1}`)
+	large := json.RawMessage(`{"a":"` + strings.Repeat("y", 1024) + `"}`)
+
+	s := &Store{}
+	if p, z, enc, err := s.encodePayload(large); err != nil || !bytes.Equal(p, large) || z != nil || enc != nil {
+		t.Errorf("without compression the payload should be stored as is: %s %v %v %v", p, z, enc, err)
+	}
+
+	WithStoreCompression(CompressZstd, 512)(s)
+	if p, z, enc, err := s.encodePayload(small); err != nil || !bytes.Equal(p, small) || z != nil || enc != nil {
+		t.Errorf("payloads under the threshold should be stored as is: %s %v %v %v", p, z, enc, err)
+	}
+	p, z, enc, err := s.encodePayload(large)
+	if err != nil || p != nil || enc == nil || *enc != "zstd" {
+		t.Fatalf("expected a zstd-compressed payload, got %s %v %v", p, enc, err)
+	}
+	if got, err := decompressPayload(*enc, z); err != nil || !bytes.Equal(got, large) {
+		t.Errorf("compressed payload should decode to the original: %s %v", got, err)
+	}
+
+	agentPayload := json.RawMessage(`{"agent":"a1","a":"` + strings.Repeat("y", 1024) + `"}`)
+	args, err := s.insertArgs(context.Background(), Entry{DLQID: "d1", OriginalPayload: agentPayload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args[2].(json.RawMessage) != nil || args[26].([]byte) == nil {
+		t.Error("insert should write payload_compressed instead of original_payload")
+	}
+	if agents := args[len(args)-1].([]string); len(agents) != 1 || agents[0] != "a1" {
+		t.Errorf("insert should copy the compressed payload's agent to payload_agents, got %v", agents)
+	}
+	s2 := &Store{}
+	args, _ = s2.insertArgs(context.Background(), Entry{DLQID: "d1", OriginalPayload: agentPayload})
+	if agents := args[len(args)-1].([]string); agents != nil {
+		t.Errorf("uncompressed payloads should leave payload_agents NULL, got %v", agents)
+	}
+
+	s = &Store{}
+	WithStoreCompression("lz4", 0)(s)
+	if _, err := s.insertArgs(context.Background(), Entry{DLQID: "d1", OriginalPayload: small}); err == nil {
+		t.Error("an unknown codec should fail the insert")
+	}
+}
+
+func TestPayloadAgents(t *testing.T) {
+	cases := []struct {
+		payload string
+		want    []string
+	}{
+		{`{"agent":"a1","agent_id":"a2","x":1}`, []string{"a1", "a2"}},
+		{`{"agent_id":"a2"}`, []string{"a2"}},
+		{`{"agent":7,"agent_id":null}`, nil},
+		{`["agent"]`, nil},
+		{`not json`, nil},
+	}
+	for _, c := range cases {
+		if got := payloadAgents(json.RawMessage(c.payload)); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("payloadAgents(%s) = %v, want %v", c.payload, got, c.want)
+		}
+	}
+}
+
+func TestJSONContains(t *testing.T) {
+	doc := map[string]any{}
+	if err := json.Unmarshal([]byte(`{"user_id":"u1","n":2,"tags":["a","b"],"nested":{"k":"v","x":1}}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		want string
+		ok   bool
+	}{
+		{`{"user_id":"u1"}`, true},
+		{`{"user_id":"u2"}`, false},
+		{`{"n":2}`, true},
+		{`{"n":"2"}`, false},
+		{`{"tags":["b"]}`, true},
+		{`{"tags":["c"]}`, false},
+		{`{"nested":{"k":"v"}}`, true},
+		{`{"nested":"v"}`, false},
+		{`{"missing":null}`, false},
+	}
+	for _, tt := range tests {
+		var want any
+		if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+			t.Fatal(err)
+		}
+		if got := jsonContains(doc, want); got != tt.ok {
+			t.Errorf("jsonContains(%s) = %v, want %v", tt.want, got, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultRetryAckTimeout bounds how long a confirmed retry waits for a reply.
+const DefaultRetryAckTimeout = 5 * time.Second
+
+// maxAckResponse caps how much of a reply is stored on the entry.
+const maxAckResponse = 1024
+
+// Retry acknowledgment outcomes recorded in RetryAck.Status.
+const (
+	RetryAckAccepted     = "accepted"
+	RetryAckRejected     = "rejected"
+	RetryAckTimeout      = "timeout"
+	RetryAckNoResponders = "no_responders"
+	RetryAckError        = "error"
+)
+
+// ErrRetryNotAcknowledged is returned when a confirmed retry is not accepted.
+// The wrapped error names the RetryAck status.
+var ErrRetryNotAcknowledged = errors.New("dlq retry not acknowledged")
+
+// ErrRetryAckTimeout is the ErrRetryNotAcknowledged returned when no reply
+// arrived in time.
+var ErrRetryAckTimeout = fmt.Errorf("%w: %s", ErrRetryNotAcknowledged, RetryAckTimeout)
+
+// RetryAck records the outcome of the last confirmed retry of an entry.
+type RetryAck struct {
+	Status   string    `json:"status"`
+	Response string    `json:"response,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// NATSRequester sends NATS requests. *nats.Conn satisfies it.
+type NATSRequester interface {
+	Request(subject string, data []byte, timeout time.Duration) (*nats.Msg, error)
+}
+
+// RetryConfirmation makes retries publish the payload as a NATS request and
+// wait for the receiver to acknowledge it before the entry is marked
+// recovered. A reply is an acknowledgment unless it carries a NATS service
+// error header (see micro.Request.Error).
+type RetryConfirmation struct {
+	Requester NATSRequester
+	// Timeout defaults to DefaultRetryAckTimeout.
+	Timeout time.Duration
+}
+
+// WithConfirmedRetry makes /retry and retry-all wait for an acknowledgment
+// from the receiver (see RetryConfirmation). Unacknowledged entries stay
+// unrecovered; /retry responds 504 on timeout and 502 otherwise.
+func WithConfirmedRetry(c RetryConfirmation) HandlerOption {
+	return func(h *Handler) { h.confirm = &c }
+}
+
+// WithScannerConfirmedRetry makes the scanner wait for an acknowledgment
+// before marking entries recovered.
+func WithScannerConfirmedRetry(c RetryConfirmation) ScannerOption {
+	return func(s *Scanner) { s.confirm = &c }
+}
+
+// request sends payload and classifies the outcome. hdr and the trace
+// context of ctx are sent in the request headers when the requester supports
+// them.
+func (c *RetryConfirmation) request(ctx context.Context, subject string, payload []byte, hdr nats.Header) RetryAck {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultRetryAckTimeout
+	}
+	ack := RetryAck{At: time.Now().UTC()}
+	var (
+		msg *nats.Msg
+		err error
+	)
+	if mr, ok := c.Requester.(natsMsgRequester); ok {
+		msg, err = mr.RequestMsg(tracedMsg(ctx, subject, payload, hdr), timeout)
+	} else {
+		msg, err = c.Requester.Request(subject, payload, timeout)
+	}
+	switch {
+	case errors.Is(err, nats.ErrTimeout):
+		ack.Status = RetryAckTimeout
+	case errors.Is(err, nats.ErrNoResponders):
+		ack.Status = RetryAckNoResponders
+	case err != nil:
+		ack.Status, ack.Response = RetryAckError, err.Error()
+	case msg.Header.Get(micro.ErrorCodeHeader) != "":
+		ack.Status = RetryAckRejected
+		ack.Response = msg.Header.Get(micro.ErrorCodeHeader) + " " + msg.Header.Get(micro.ErrorHeader)
+	default:
+		ack.Status, ack.Response = RetryAckAccepted, string(msg.Data)
+	}
+	if len(ack.Response) > maxAckResponse {
+		ack.Response = ack.Response[:maxAckResponse]
+	}
+	return ack
+}
+
+// republish sends payload to subject as a retry of e triggered by retryBy.
+// Without confirmation it is a plain publish. With it, the payload is sent as
+// a request, the acknowledgment is recorded on the entry, and an error
+// wrapping ErrRetryNotAcknowledged is returned unless the receiver accepted
+// it.
+//
+// The republish runs in a dlq.republish span carrying the entry id and
+// linked to the failed message's trace. When the connection supports
+// headers the message carries e's original headers (see republishHeader)
+// and the republish's trace context, so the retried task can be traced back
+// to its entry.
+func republish(ctx context.Context, nc NATSPublisher, c *RetryConfirmation, store Writer, e Entry, retryBy, subject string, payload []byte) (err error) {
+	dlqID := e.DLQID
+	opts := []trace.SpanStartOption{trace.WithAttributes(AttrDLQID.String(dlqID), AttrOriginalSubject.String(subject))}
+	if sc := originalSpanContext(e); sc.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+	}
+	ctx, span := tracer().Start(ctx, "dlq.republish", opts...)
+	defer func() { endSpan(span, err) }()
+
+	hdr := republishHeader(e, retryBy)
+	if c == nil {
+		return publishTraced(ctx, nc, subject, payload, hdr, AttrDLQID.String(dlqID))
+	}
+	ack := c.request(ctx, subject, payload, hdr)
+	span.SetAttributes(attribute.String("dlq.retry_ack", ack.Status))
+	if err := store.RecordRetryAck(ctx, dlqID, ack); err != nil {
+		slog.Warn("dlq: failed to record retry acknowledgment", "dlq_id", dlqID, "status", ack.Status, "error", err)
+	}
+	switch ack.Status {
+	case RetryAckAccepted:
+		return nil
+	case RetryAckTimeout:
+		return ErrRetryAckTimeout
+	default:
+		return fmt.Errorf("%w: %s", ErrRetryNotAcknowledged, ack.Status)
+	}
+}
+
+// RecordRetryAck stores the outcome of a confirmed retry on the entry.
+func (s *Store) RecordRetryAck(ctx context.Context, dlqID string, ack RetryAck) (err error) {
+	defer s.observe("record_retry_ack", time.Now(), &err)
+	_, err = s.pool.Exec(ctx, `UPDATE swarm_dlq SET retry_ack = $2 WHERE dlq_id = $1 AND `+tenantClause(3), dlqID, ack, s.tenant(ctx))
+	if err != nil {
+		return fmt.Errorf("record retry ack: %w", err)
+	}
+	return nil
+}
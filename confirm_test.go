@@ -0,0 +1,103 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+)
+
+// fakeRequester answers every request with msg or err.
+type fakeRequester struct {
+	msg      *nats.Msg
+	err      error
+	subjects []string
+}
+
+func (f *fakeRequester) Request(subject string, _ []byte, _ time.Duration) (*nats.Msg, error) {
+	f.subjects = append(f.subjects, subject)
+	return f.msg, f.err
+}
+
+func TestRetryConfirmation_Request(t *testing.T) {
+	rejected := nats.NewMsg("_INBOX.x")
+	rejected.Header.Set(micro.ErrorCodeHeader, "409")
+	rejected.Header.Set(micro.ErrorHeader, "task already assigned")
+
+	cases := []struct {
+		name string
+		req  *fakeRequester
+		want string
+	}{
+		{"accepted", &fakeRequester{msg: &nats.Msg{Data: []byte(`{"ok":true}`)}}, RetryAckAccepted},
+		{"rejected", &fakeRequester{msg: rejected}, RetryAckRejected},
+		{"timeout", &fakeRequester{err: nats.ErrTimeout}, RetryAckTimeout},
+		{"no responders", &fakeRequester{err: nats.ErrNoResponders}, RetryAckNoResponders},
+		{"error", &fakeRequester{err: errors.New("conn closed")}, RetryAckError},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &RetryConfirmation{Requester: tc.req}
+			if got := c.request(context.Background(), "swarm.task.request", nil, nil); got.Status != tc.want || got.At.IsZero() {
+				t.Errorf("expected %s, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestHandler_Retry_Confirmed(t *testing.T) {
+	cases := []struct {
+		name      string
+		req       *fakeRequester
+		code      int
+		recovered bool
+	}{
+		{"accepted", &fakeRequester{msg: &nats.Msg{Data: []byte(`accepted`)}}, http.StatusOK, true},
+		{"timeout", &fakeRequester{err: nats.ErrTimeout}, http.StatusGatewayTimeout, false},
+		{RetryAckNoResponders, &fakeRequester{err: nats.ErrNoResponders}, http.StatusBadGateway, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newMockStore()
+			nc := newMockNATS()
+			store.seed(Entry{DLQID: "cf-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`)})
+			r := chi.NewRouter()
+			r.Mount("/dlq", NewHandler(store, nc, WithConfirmedRetry(RetryConfirmation{Requester: tc.req})).Routes())
+
+			if w := doAs(r, "POST", "/dlq/cf-1/retry", "", ""); w.Code != tc.code {
+				t.Fatalf("expected %d, got %d", tc.code, w.Code)
+			}
+			if len(nc.published()) != 0 {
+				t.Error("a confirmed retry must be sent as a request, not a publish")
+			}
+			e, _ := store.Get(context.Background(), "cf-1")
+			if e.Recovered != tc.recovered {
+				t.Errorf("expected recovered=%v, got %v", tc.recovered, e.Recovered)
+			}
+			if e.RetryAck == nil || e.RetryAck.Status != tc.name {
+				t.Errorf("expected ack %q recorded, got %+v", tc.name, e.RetryAck)
+			}
+		})
+	}
+}
+
+func TestScanner_ConfirmedRetry(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "cf-2", OriginalSubject: "swarm.task.request", Recoverable: true})
+	req := &fakeRequester{err: nats.ErrTimeout}
+
+	NewScanner(store, newMockNATS(), time.Minute, WithScannerConfirmedRetry(RetryConfirmation{Requester: req})).scan(context.Background())
+
+	if len(req.subjects) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(req.subjects))
+	}
+	if e, _ := store.Get(context.Background(), "cf-2"); e.Recovered || e.RetryAck == nil || e.RetryAck.Status != RetryAckTimeout {
+		t.Errorf("expected unrecovered entry with timeout ack, got %+v", e)
+	}
+}
@@ -0,0 +1,309 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+)
+
+// SubjectAll matches every DLQ event. Lifecycle notifications published under
+// dlq.events.> are delivered too and skipped by the Processor.
+const SubjectAll = "dlq.>"
+
+// Defaults for JetStream pull consumption.
+const (
+	DefaultConsumerFetchBatch = 32
+	DefaultConsumerFetchWait  = 5 * time.Second
+	DefaultConsumerNakDelay   = 10 * time.Second
+)
+
+// subscription is the part of *nats.Subscription the Consumer uses.
+type subscription interface {
+	Unsubscribe() error
+}
+
+// pullSubscription is the part of a JetStream pull *nats.Subscription the
+// Consumer uses.
+type pullSubscription interface {
+	subscription
+	Fetch(batch int, opts ...nats.PullOpt) ([]*nats.Msg, error)
+}
+
+// jsAcker acknowledges a JetStream message. *nats.Msg satisfies it.
+type jsAcker interface {
+	Ack(opts ...nats.AckOpt) error
+	NakWithDelay(delay time.Duration, opts ...nats.AckOpt) error
+	Term(opts ...nats.AckOpt) error
+}
+
+// ConsumerOption configures a Consumer.
+type ConsumerOption func(*Consumer)
+
+// WithConsumerSubject subscribes to subject instead of SubjectAll.
+func WithConsumerSubject(subject string) ConsumerOption {
+	return func(c *Consumer) { c.subject = subject }
+}
+
+// WithConsumerQueue joins the queue group so that several Chronicle replicas
+// share the DLQ traffic instead of each ingesting every event.
+func WithConsumerQueue(group string) ConsumerOption {
+	return func(c *Consumer) { c.queue = group }
+}
+
+// WithConsumerJetStream consumes from a JetStream stream covering the subject
+// through a pull consumer instead of core NATS, so events published while
+// Chronicle is down are ingested once it is back. Each message is acked only
+// once its entry is stored, nak'ed with a delay when the store fails, and
+// terminated when it can never be stored (malformed or oversized).
+//
+// durable names the consumer so its position survives restarts; every
+// Chronicle replica using the same name shares the work, and the queue group
+// is ignored. The durable consumer is left in place when the Consumer stops.
+// Leave durable empty for an ephemeral consumer.
+func WithConsumerJetStream(js nats.JetStreamContext, durable string) ConsumerOption {
+	return func(c *Consumer) {
+		c.js = js
+		c.durable = durable
+	}
+}
+
+// WithConsumerNakDelay sets how long JetStream waits before redelivering an
+// event the store failed to insert. The default is DefaultConsumerNakDelay.
+func WithConsumerNakDelay(d time.Duration) ConsumerOption {
+	return func(c *Consumer) { c.nakDelay = d }
+}
+
+// WithConsumerFetch sets how many JetStream messages each fetch requests and
+// how long it waits for them. The defaults are DefaultConsumerFetchBatch and
+// DefaultConsumerFetchWait.
+func WithConsumerFetch(batch int, wait time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		c.fetchBatch = batch
+		c.fetchWait = wait
+	}
+}
+
+// Consumer subscribes to the DLQ subjects and feeds every event to a
+// Processor, so Chronicle does not have to wire the subscription itself.
+//
+// Core NATS events are handed to the Processor's worker pool with Enqueue,
+// which slows the subscription down while the pool is saturated. With
+// JetStream, each of the Processor's workers fetches and acknowledges its
+// own batches instead. If the Processor was built WithProcessorBatching,
+// each fetched batch is stored with ProcessBatch; otherwise its messages are
+// processed one by one.
+//
+// Reconnects are handled by the NATS connection: subscriptions are restored
+// automatically once it reconnects, and the Consumer only logs the outage.
+// Configure the connection with nats.MaxReconnects(-1) to keep retrying
+// indefinitely. If the connection is closed for good the Consumer stops.
+type Consumer struct {
+	nc         *nats.Conn
+	proc       *Processor
+	subject    string
+	queue      string
+	js         nats.JetStreamContext
+	durable    string
+	nakDelay   time.Duration
+	fetchBatch int
+	fetchWait  time.Duration
+
+	subscribe     func(nats.MsgHandler) (subscription, error)
+	pullSubscribe func() (pullSubscription, error)
+	pullers       sync.WaitGroup
+	done          chan struct{}
+}
+
+// NewConsumer creates a consumer that drives proc from nc. Do not Start proc
+// yourself; the Consumer starts and stops it.
+func NewConsumer(nc *nats.Conn, proc *Processor, opts ...ConsumerOption) *Consumer {
+	c := &Consumer{
+		nc:         nc,
+		proc:       proc,
+		subject:    SubjectAll,
+		nakDelay:   DefaultConsumerNakDelay,
+		fetchBatch: DefaultConsumerFetchBatch,
+		fetchWait:  DefaultConsumerFetchWait,
+		done:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.subscribe = c.natsSubscribe
+	c.pullSubscribe = func() (pullSubscription, error) {
+		return c.js.PullSubscribe(c.subject, c.durable, nats.ManualAck())
+	}
+	return c
+}
+
+func (c *Consumer) natsSubscribe(cb nats.MsgHandler) (subscription, error) {
+	if c.queue != "" {
+		return c.nc.QueueSubscribe(c.subject, c.queue, cb)
+	}
+	return c.nc.Subscribe(c.subject, cb)
+}
+
+// Start subscribes and launches the processor. The consumer runs until ctx
+// is cancelled or the connection is closed; call Wait to block until it has
+// unsubscribed and the processor has drained. If Start returns an error
+// nothing was started and Wait returns immediately.
+func (c *Consumer) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	var sub subscription
+	var err error
+	if c.js != nil {
+		sub, err = c.startPull(ctx)
+	} else {
+		sub, err = c.subscribe(func(msg *nats.Msg) { c.handle(ctx, msg) })
+		if err == nil {
+			c.proc.Start(ctx)
+		}
+	}
+	if err != nil {
+		cancel()
+		close(c.done)
+		return fmt.Errorf("dlq consumer: subscribe %s: %w", c.subject, err)
+	}
+
+	var status chan nats.Status
+	if c.nc != nil {
+		status = c.nc.StatusChanged(nats.DISCONNECTED, nats.RECONNECTING, nats.CONNECTED, nats.CLOSED)
+	}
+	slog.Info("dlq consumer: subscribed", "subject", c.subject, "queue", c.queue, "jetstream", c.js != nil)
+
+	go func() {
+		defer close(c.done)
+		c.watch(ctx, status)
+		// Unblocks handlers waiting in Enqueue and stops the workers and
+		// pull loops.
+		cancel()
+		c.pullers.Wait()
+		// Unsubscribing would delete a durable JetStream consumer created
+		// by the client, losing its position.
+		if c.js == nil || c.durable == "" {
+			if err := sub.Unsubscribe(); err != nil && !errors.Is(err, nats.ErrConnectionClosed) {
+				slog.Warn("dlq consumer: unsubscribe failed", "subject", c.subject, "error", err)
+			}
+		}
+		c.proc.Wait()
+	}()
+	return nil
+}
+
+// startPull creates the JetStream pull subscription and starts one fetch
+// loop per processor worker.
+func (c *Consumer) startPull(ctx context.Context) (subscription, error) {
+	sub, err := c.pullSubscribe()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < c.proc.workers; i++ {
+		c.pullers.Add(1)
+		go func() {
+			defer c.pullers.Done()
+			c.pull(ctx, sub)
+		}()
+	}
+	return sub, nil
+}
+
+// pull fetches and processes batches until ctx is done. A batch already
+// fetched is finished on shutdown rather than left to time out.
+func (c *Consumer) pull(ctx context.Context, sub pullSubscription) {
+	for ctx.Err() == nil {
+		fetchCtx, cancel := context.WithTimeout(ctx, c.fetchWait)
+		msgs, err := sub.Fetch(c.fetchBatch, nats.Context(fetchCtx))
+		cancel()
+		if c.proc.batchSize > 1 && len(msgs) > 1 {
+			c.processBatch(context.WithoutCancel(ctx), msgs)
+		} else {
+			for _, msg := range msgs {
+				c.settle(msg, msg.Subject, c.processMsg(context.WithoutCancel(ctx), msg))
+			}
+		}
+		switch {
+		case err == nil, errors.Is(err, context.DeadlineExceeded), errors.Is(err, nats.ErrTimeout):
+			// An empty fetch just means the stream is idle.
+		case ctx.Err() != nil:
+		default:
+			slog.Warn("dlq consumer: fetch failed", "subject", c.subject, "error", err)
+			select {
+			case <-time.After(c.fetchWait):
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+// processMsg processes msg, continuing the publisher's trace if it sent one.
+func (c *Consumer) processMsg(ctx context.Context, msg *nats.Msg) error {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, natsHeaderCarrier(msg.Header))
+	return c.proc.ProcessWithResult(ctx, msg.Subject, msg.Data)
+}
+
+// processBatch processes msgs with ProcessBatch and settles each of them.
+func (c *Consumer) processBatch(ctx context.Context, msgs []*nats.Msg) {
+	events := make([]RawEvent, len(msgs))
+	for i, msg := range msgs {
+		events[i] = RawEvent{Subject: msg.Subject, Data: msg.Data}
+	}
+	for i, err := range c.proc.ProcessBatch(ctx, events) {
+		c.settle(msgs[i], msgs[i].Subject, err)
+	}
+}
+
+// settle acknowledges a JetStream message according to the result of
+// processing it: ack on success, term for invalid events and nak otherwise.
+func (c *Consumer) settle(m jsAcker, subject string, result error) {
+	var err error
+	switch {
+	case result == nil:
+		err = m.Ack()
+	case errors.Is(result, ErrInvalidEvent):
+		slog.Warn("dlq consumer: terminating unprocessable event", "subject", subject)
+		err = m.Term()
+	default:
+		err = m.NakWithDelay(c.nakDelay)
+	}
+	if err != nil {
+		slog.Warn("dlq consumer: ack failed", "subject", subject, "error", err)
+	}
+}
+
+// Wait blocks until the consumer has stopped.
+func (c *Consumer) Wait() {
+	<-c.done
+}
+
+// watch logs connection state changes until ctx is done or the connection
+// is closed.
+func (c *Consumer) watch(ctx context.Context, status <-chan nats.Status) {
+	for {
+		select {
+		case s := <-status:
+			switch s {
+			case nats.DISCONNECTED, nats.RECONNECTING:
+				slog.Warn("dlq consumer: connection lost, waiting to reconnect", "subject", c.subject, "status", s.String())
+			case nats.CONNECTED:
+				slog.Info("dlq consumer: reconnected", "subject", c.subject)
+			case nats.CLOSED:
+				slog.Error("dlq consumer: connection closed, stopping", "subject", c.subject)
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Consumer) handle(ctx context.Context, msg *nats.Msg) {
+	if err := c.proc.Enqueue(ctx, msg.Subject, msg.Data); err != nil {
+		slog.Warn("dlq consumer: dropped event on shutdown", "subject", msg.Subject, "error", err)
+	}
+}
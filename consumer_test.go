@@ -0,0 +1,205 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type fakeSubscription struct{ unsubscribed atomic.Bool }
+
+func (f *fakeSubscription) Unsubscribe() error {
+	f.unsubscribed.Store(true)
+	return nil
+}
+
+// fakeSubscribe replaces c's subscription and returns the captured handler.
+func fakeSubscribe(c *Consumer) (*fakeSubscription, func() nats.MsgHandler) {
+	sub := &fakeSubscription{}
+	handler := make(chan nats.MsgHandler, 1)
+	c.subscribe = func(cb nats.MsgHandler) (subscription, error) {
+		handler <- cb
+		return sub, nil
+	}
+	return sub, func() nats.MsgHandler { return <-handler }
+}
+
+func dlqMsg(t *testing.T, subject, dlqID string) *nats.Msg {
+	t.Helper()
+	data, err := json.Marshal(Entry{DLQID: dlqID, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), FailedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &nats.Msg{Subject: subject, Data: data}
+}
+
+func TestConsumer_CoreNATS(t *testing.T) {
+	store := newMockStore()
+	c := NewConsumer(nil, NewProcessor(store))
+	sub, handler := fakeSubscribe(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	cb := handler()
+	cb(dlqMsg(t, SubjectTaskUnassignable, "cs-1"))
+	cb(dlqMsg(t, SubjectAgentBootFailure, "cs-2"))
+
+	deadline := time.Now().Add(time.Second)
+	for store.inserted() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 inserts, got %d", store.inserted())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	c.Wait()
+	if !sub.unsubscribed.Load() {
+		t.Error("expected the consumer to unsubscribe on shutdown")
+	}
+	if e, _ := store.Get(context.Background(), "cs-2"); e == nil || e.Source != SourceWarren {
+		t.Errorf("expected cs-2 from warren, got %+v", e)
+	}
+}
+
+// stubJetStream marks a consumer as JetStream-backed; its methods are never
+// called because the subscription is faked.
+type stubJetStream struct{ nats.JetStreamContext }
+
+// fakePull hands out queued batches, then reports idle fetches.
+type fakePull struct {
+	fakeSubscription
+	mu      sync.Mutex
+	batches [][]*nats.Msg
+}
+
+func (f *fakePull) Fetch(int, ...nats.PullOpt) ([]*nats.Msg, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.batches) == 0 {
+		time.Sleep(time.Millisecond)
+		return nil, nats.ErrTimeout
+	}
+	b := f.batches[0]
+	f.batches = f.batches[1:]
+	return b, nil
+}
+
+func TestConsumer_JetStreamPull(t *testing.T) {
+	rec := recordSpans(t)
+	store := newMockStore()
+	c := NewConsumer(nil, NewProcessor(store, WithProcessorWorkers(2)), WithConsumerJetStream(stubJetStream{}, "chronicle"))
+
+	parent, pub := otel.Tracer("test").Start(context.Background(), "publish")
+	pub.End()
+	traced := dlqMsg(t, SubjectTaskPolicyDenied, "cs-js1")
+	traced.Header = nats.Header{}
+	otel.GetTextMapPropagator().Inject(parent, natsHeaderCarrier(traced.Header))
+	sub := &fakePull{batches: [][]*nats.Msg{{traced, dlqMsg(t, SubjectAgentCrashLoop, "cs-js2")}}}
+	c.pullSubscribe = func() (pullSubscription, error) { return sub, nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for store.inserted() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 inserts, got %d", store.inserted())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	c.Wait()
+
+	if sub.unsubscribed.Load() {
+		t.Error("stopping must not unsubscribe (and so delete) the durable consumer")
+	}
+	span := spanNamed(rec, "dlq.process "+SubjectTaskPolicyDenied)
+	if span == nil || span.SpanContext().TraceID() != trace.SpanContextFromContext(parent).TraceID() {
+		t.Errorf("expected dlq.process to continue the publisher's trace, got %v", rec.Ended())
+	}
+}
+
+type fakeAcker struct{ acks []string }
+
+func (f *fakeAcker) Ack(...nats.AckOpt) error {
+	f.acks = append(f.acks, "ack")
+	return nil
+}
+
+func (f *fakeAcker) NakWithDelay(d time.Duration, _ ...nats.AckOpt) error {
+	f.acks = append(f.acks, "nak "+d.String())
+	return nil
+}
+
+func (f *fakeAcker) Term(...nats.AckOpt) error {
+	f.acks = append(f.acks, "term")
+	return nil
+}
+
+func TestConsumer_SettlesByOutcome(t *testing.T) {
+	store := newMockStore()
+	proc := NewProcessor(store, WithProcessorPayloadLimit(PayloadLimit{MaxBytes: 64, Policy: PayloadReject}))
+	c := NewConsumer(nil, proc, WithConsumerNakDelay(time.Minute))
+
+	big := dlqMsg(t, SubjectTaskUnassignable, "cs-big")
+	big.Data, _ = json.Marshal(Entry{DLQID: "cs-big", OriginalPayload: json.RawMessage(`"` + strings.Repeat("x", 100) + `"`)})
+
+	tests := []struct {
+		name     string
+		msg      *nats.Msg
+		storeErr error
+		want     string
+	}{
+		{"stored", dlqMsg(t, SubjectTaskUnassignable, "cs-ok"), nil, "ack"},
+		{"duplicate", dlqMsg(t, SubjectTaskUnassignable, "cs-ok"), nil, "ack"},
+		{"lifecycle event", &nats.Msg{Subject: SubjectEntryCreated, Data: []byte(`{}`)}, nil, "ack"},
+		{"store down", dlqMsg(t, SubjectTaskUnassignable, "cs-down"), errors.New("connection refused"), "nak 1m0s"},
+		{"malformed", &nats.Msg{Subject: SubjectTaskUnassignable, Data: []byte(`{not json`)}, nil, "term"},
+		{"oversized", big, nil, "term"},
+	}
+	for _, tt := range tests {
+		store.mu.Lock()
+		store.insertErr = tt.storeErr
+		store.mu.Unlock()
+
+		acker := &fakeAcker{}
+		c.settle(acker, tt.msg.Subject, c.processMsg(context.Background(), tt.msg))
+		if len(acker.acks) != 1 || acker.acks[0] != tt.want {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.want, acker.acks)
+		}
+	}
+}
+
+func TestConsumer_SubscribeError(t *testing.T) {
+	c := NewConsumer(nil, NewProcessor(newMockStore()))
+	c.subscribe = func(nats.MsgHandler) (subscription, error) {
+		return nil, errors.New("permissions violation")
+	}
+	if err := c.Start(context.Background()); err == nil {
+		t.Fatal("expected Start to fail")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait blocked after a failed Start")
+	}
+}
@@ -0,0 +1,102 @@
+package dlq
+
+import (
+	"sync"
+	"time"
+)
+
+// DedupeKey selects what the Processor's dedupe cache matches events on.
+type DedupeKey int
+
+const (
+	// DedupeByID drops events whose dlq_id was persisted within the window,
+	// such as messages redelivered after a Chronicle restart.
+	DedupeByID DedupeKey = 1 << iota
+	// DedupeByFingerprint drops events whose fingerprint was persisted
+	// within the window, even under a different dlq_id.
+	DedupeByFingerprint
+)
+
+// WithProcessorDedupe drops events already persisted within window, matched
+// by dlq_id, fingerprint or both (DedupeByID|DedupeByFingerprint). Dropped
+// events never reach the store, quotas or duplicate counters and are counted
+// in MetricProcessorDeduplicated instead. The cache is per Processor and lost
+// on restart; the store's dlq_id conflict handling still backs it up.
+func WithProcessorDedupe(window time.Duration, by DedupeKey) ProcessorOption {
+	return func(p *Processor) {
+		if window > 0 && by != 0 {
+			p.dedupe = newDedupeCache(window, by)
+		}
+	}
+}
+
+// dedupeCache remembers the keys of recently persisted entries.
+type dedupeCache struct {
+	mu        sync.Mutex
+	window    time.Duration
+	by        DedupeKey
+	now       func() time.Time
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+func newDedupeCache(window time.Duration, by DedupeKey) *dedupeCache {
+	return &dedupeCache{
+		window: window,
+		by:     by,
+		now:    time.Now,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// keys returns the cache keys of e. Keys are scoped to the tenant so that
+// environments sharing a Processor never suppress each other's events.
+func (c *dedupeCache) keys(e Entry) []string {
+	var keys []string
+	if c.by&DedupeByID != 0 && e.DLQID != "" {
+		keys = append(keys, e.TenantID+"\x00id\x00"+e.DLQID)
+	}
+	if c.by&DedupeByFingerprint != 0 && e.Fingerprint != "" {
+		keys = append(keys, e.TenantID+"\x00fp\x00"+e.Fingerprint)
+	}
+	return keys
+}
+
+// contains reports whether any key of e was remembered within the window.
+func (c *dedupeCache) contains(e Entry) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for _, key := range c.keys(e) {
+		if at, ok := c.seen[key]; ok && now.Sub(at) <= c.window {
+			return true
+		}
+	}
+	return false
+}
+
+// remember records the keys of e once it has been persisted. Events are only
+// remembered after the store accepted them, so a redelivery after a failed
+// insert is not dropped.
+func (c *dedupeCache) remember(e Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	c.sweep(now)
+	for _, key := range c.keys(e) {
+		c.seen[key] = now
+	}
+}
+
+// sweep discards keys older than the window, at most once per window.
+func (c *dedupeCache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < c.window {
+		return
+	}
+	c.lastSweep = now
+	for key, at := range c.seen {
+		if now.Sub(at) > c.window {
+			delete(c.seen, key)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestProcessor_Dedupe(t *testing.T) {
+	store := newMockStore()
+	m := NewMetrics()
+	proc := NewProcessor(store, WithProcessorMetrics(m), WithProcessorDedupe(time.Minute, DedupeByID))
+	now := time.Now()
+	proc.dedupe.now = func() time.Time { return now }
+	ctx := context.Background()
+	event := eventJSON(Entry{DLQID: "dd-1", Reason: ReasonNoCapableAgent})
+
+	store.insertErr = errors.New("db down")
+	if err := proc.ProcessWithResult(ctx, SubjectTaskUnassignable, event); err == nil {
+		t.Fatal("expected the insert to fail")
+	}
+	store.insertErr = nil
+	if err := proc.ProcessWithResult(ctx, SubjectTaskUnassignable, event); err != nil {
+		t.Fatalf("a redelivery after a failed insert must be stored: %v", err)
+	}
+	if err := proc.ProcessWithResult(ctx, SubjectTaskUnassignable, event); err != nil {
+		t.Fatal(err)
+	}
+	if store.insertCalls != 2 {
+		t.Errorf("expected the redelivery to skip the store, got %d inserts", store.insertCalls)
+	}
+	if got := m.Get(MetricProcessorDeduplicated); got != 1 {
+		t.Errorf("deduplicated metric = %d", got)
+	}
+	if got := m.Get(MetricProcessorDuplicates); got != 0 {
+		t.Errorf("duplicates metric = %d, want 0", got)
+	}
+
+	// Other tenants and expired keys are not deduplicated.
+	other := NewProcessor(store, WithProcessorDedupe(time.Minute, DedupeByID), WithProcessorTenant("staging"))
+	other.dedupe = proc.dedupe
+	other.Process(ctx, SubjectTaskUnassignable, event)
+	now = now.Add(2 * time.Minute)
+	proc.Process(ctx, SubjectTaskUnassignable, event)
+	if store.insertCalls != 4 {
+		t.Errorf("expected 4 inserts, got %d", store.insertCalls)
+	}
+}
+
+func TestProcessor_DedupeByFingerprint(t *testing.T) {
+	store := newMockStore()
+	proc := NewProcessor(store, WithProcessorDedupe(time.Minute, DedupeByFingerprint))
+	ctx := context.Background()
+
+	for _, id := range []string{"fp-1", "fp-2"} {
+		proc.Process(ctx, SubjectTaskUnassignable, eventJSON(Entry{DLQID: id, Reason: ReasonNoCapableAgent}))
+	}
+	proc.Process(ctx, SubjectTaskUnassignable, eventJSON(Entry{DLQID: "fp-3", Reason: ReasonNoCapableAgent, OriginalSubject: "swarm.task.other"}))
+	if store.insertCalls != 2 {
+		t.Errorf("expected the repeat fingerprint to be dropped, got %d inserts", store.insertCalls)
+	}
+	if _, err := store.Get(ctx, "fp-2"); err == nil {
+		t.Error("fp-2 shares fp-1's fingerprint and should not be stored")
+	}
+}
@@ -0,0 +1,79 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// AuditDelete records an entry being permanently deleted.
+const AuditDelete AuditAction = "entry.delete"
+
+// Delete permanently removes an entry, live or archived, together with any
+// recovery still waiting in the outbox. Unlike a discard nothing is kept,
+// except the entry's audit trail. A payload offloaded to a BlobStore is not
+// removed. It fails with a wrapped pgx.ErrNoRows if there is no such entry.
+func (s *Store) Delete(ctx context.Context, dlqID string) (err error) {
+	defer s.observe("delete", time.Now(), &err)
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("delete dlq entry: begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var deleted int64
+	for _, table := range []string{"swarm_dlq", "swarm_dlq_archive"} {
+		tag, err := tx.Exec(ctx, `DELETE FROM `+table+` WHERE dlq_id = $1 AND `+tenantClause(2), dlqID, s.tenant(ctx))
+		if err != nil {
+			return fmt.Errorf("delete dlq entry from %s: %w", table, err)
+		}
+		deleted += tag.RowsAffected()
+	}
+	if deleted == 0 {
+		return fmt.Errorf("delete dlq entry %s: %w", dlqID, pgx.ErrNoRows)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM swarm_dlq_outbox WHERE dlq_id = $1`, dlqID); err != nil {
+		return fmt.Errorf("delete dlq entry outbox records: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("delete dlq entry: commit: %w", err)
+	}
+	return nil
+}
+
+// handleDelete serves DELETE /{dlqID}, the admin-only hard delete for entries
+// that must be removed rather than discarded. The response carries the
+// entry's payload_ref, if any, so an offloaded payload can be removed too.
+func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	dlqID := chi.URLParam(r, "dlqID")
+	var payloadRef string
+	if e, err := h.store.Get(r.Context(), dlqID); err == nil {
+		payloadRef = e.PayloadRef
+	} else if e, err := h.store.GetArchived(r.Context(), dlqID); err == nil {
+		payloadRef = e.PayloadRef
+	}
+
+	err := h.store.Delete(r.Context(), dlqID)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "dlq entry not found"})
+		return
+	case err != nil:
+		slog.Error("failed to delete dlq entry", "dlq_id", dlqID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	h.auditEntry(r, AuditDelete, dlqID, AuditResultOK, "")
+	slog.Info("dlq entry deleted", "dlq_id", dlqID, "actor", actorFromRequest(r))
+	resp := map[string]string{"status": "deleted", "dlq_id": dlqID}
+	if payloadRef != "" {
+		resp["payload_ref"] = payloadRef
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
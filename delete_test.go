@@ -0,0 +1,60 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_Delete(t *testing.T) {
+	store := newMockStore()
+	store.seed(
+		Entry{DLQID: "del-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{"ssn":"x"}`), PayloadRef: "nats-object:del-1"},
+		Entry{DLQID: "del-2", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recovered: true},
+	)
+	if _, err := store.Archive(context.Background(), ArchiveFilter{Recovered: true}); err != nil {
+		t.Fatal(err)
+	}
+	audit := &memAudit{}
+	r := authRouter(store,
+		WithAuthMiddleware(APIKeyAuth(map[string]Principal{
+			"k-admin": {Subject: "root", Scopes: []string{ScopeAdmin}},
+			"k-alice": {Subject: "alice"},
+		})),
+		WithAuditRecorder(audit),
+	)
+
+	if w := doWithKey(r, "DELETE", "/dlq/del-1", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous delete: expected 401, got %d", w.Code)
+	}
+	if w := doWithKey(r, "DELETE", "/dlq/del-1", "k-alice"); w.Code != http.StatusForbidden {
+		t.Errorf("delete without dlq:admin: expected 403, got %d", w.Code)
+	}
+	w := doWithKey(r, "DELETE", "/dlq/del-1", "k-admin")
+	if w.Code != http.StatusOK {
+		t.Fatalf("admin delete: expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp map[string]string
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["payload_ref"] != "nats-object:del-1" {
+		t.Errorf("expected the payload_ref reported, got %v", resp)
+	}
+	if _, err := store.Get(context.Background(), "del-1"); err == nil {
+		t.Error("expected the entry gone")
+	}
+	if w := doWithKey(r, "DELETE", "/dlq/del-2", "k-admin"); w.Code != http.StatusOK {
+		t.Errorf("archived delete: expected 200, got %d", w.Code)
+	}
+	if _, err := store.GetArchived(context.Background(), "del-2"); err == nil {
+		t.Error("expected the archived entry gone")
+	}
+	if w := doWithKey(r, "DELETE", "/dlq/del-1", "k-admin"); w.Code != http.StatusNotFound {
+		t.Errorf("second delete: expected 404, got %d", w.Code)
+	}
+
+	recs, _ := audit.ListAudit(context.Background(), "del-1")
+	if len(recs) != 1 || recs[0].Action != AuditDelete || recs[0].Actor != "root" {
+		t.Errorf("expected one entry.delete record by root, got %+v", recs)
+	}
+}
@@ -0,0 +1,78 @@
+package dlq
+
+import (
+	"sort"
+	"sync"
+)
+
+// DenyRules lists original subjects and reasons the Scanner must never
+// re-drive, even when an entry is marked recoverable.
+type DenyRules struct {
+	Subjects []string `json:"subjects"`
+	Reasons  []string `json:"reasons"`
+}
+
+// DenyList is a concurrency-safe set of DenyRules shared between the Scanner,
+// which consults it, and the Handler, which exposes it for viewing and editing.
+type DenyList struct {
+	mu       sync.RWMutex
+	subjects map[string]struct{}
+	reasons  map[string]struct{}
+}
+
+// NewDenyList creates a DenyList seeded with rules.
+func NewDenyList(rules DenyRules) *DenyList {
+	d := &DenyList{}
+	d.Set(rules)
+	return d
+}
+
+// Denied reports whether e must be skipped by automated recovery.
+// A nil *DenyList denies nothing.
+func (d *DenyList) Denied(e Entry) bool {
+	if d == nil {
+		return false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, subject := d.subjects[e.OriginalSubject]
+	_, reason := d.reasons[e.Reason]
+	return subject || reason
+}
+
+// Rules returns the current rules, sorted.
+func (d *DenyList) Rules() DenyRules {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return DenyRules{Subjects: sortedKeys(d.subjects), Reasons: sortedKeys(d.reasons)}
+}
+
+// Set replaces the current rules.
+func (d *DenyList) Set(rules DenyRules) {
+	subjects := make(map[string]struct{}, len(rules.Subjects))
+	for _, s := range rules.Subjects {
+		if s != "" {
+			subjects[s] = struct{}{}
+		}
+	}
+	reasons := make(map[string]struct{}, len(rules.Reasons))
+	for _, r := range rules.Reasons {
+		if r != "" {
+			reasons[r] = struct{}{}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.subjects = subjects
+	d.reasons = reasons
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
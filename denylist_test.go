@@ -0,0 +1,68 @@
+package dlq
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestDenyList_Denied(t *testing.T) {
+	d := NewDenyList(DenyRules{Subjects: []string{"billing.charge"}, Reasons: []string{ReasonBootFailure}})
+
+	if !d.Denied(Entry{OriginalSubject: "billing.charge", Reason: ReasonNoCapableAgent}) {
+		t.Error("expected denied subject")
+	}
+	if !d.Denied(Entry{OriginalSubject: "swarm.task.request", Reason: ReasonBootFailure}) {
+		t.Error("expected denied reason")
+	}
+	if d.Denied(Entry{OriginalSubject: "swarm.task.request", Reason: ReasonNoCapableAgent}) {
+		t.Error("expected entry to be allowed")
+	}
+
+	var nilList *DenyList
+	if nilList.Denied(Entry{OriginalSubject: "billing.charge"}) {
+		t.Error("nil deny-list should deny nothing")
+	}
+}
+
+func TestHandler_DenyList_GetAndPut(t *testing.T) {
+	d := NewDenyList(DenyRules{Reasons: []string{ReasonBootFailure}})
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(newMockStore(), newMockNATS(), WithDenyList(d)).Routes())
+
+	req := httptest.NewRequest("PUT", "/dlq/deny-list", strings.NewReader(`{"subjects":["billing.charge","billing.charge"],"reasons":[]}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/dlq/deny-list", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var rules DenyRules
+	_ = json.NewDecoder(w.Body).Decode(&rules)
+	if len(rules.Subjects) != 1 || rules.Subjects[0] != "billing.charge" {
+		t.Errorf("unexpected subjects: %v", rules.Subjects)
+	}
+	if len(rules.Reasons) != 0 {
+		t.Errorf("expected reasons to be replaced, got %v", rules.Reasons)
+	}
+}
+
+func TestHandler_DenyList_InvalidBody(t *testing.T) {
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(newMockStore(), newMockNATS(), WithDenyList(NewDenyList(DenyRules{}))).Routes())
+
+	req := httptest.NewRequest("PUT", "/dlq/deny-list", strings.NewReader(`{`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
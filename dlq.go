@@ -21,12 +21,42 @@ const (
 	ReasonCrashLoop           = "crash_loop"
 )
 
-// Sources that publish DLQ events.
+// ReasonQuotaExceeded marks an aggregate entry written by the Processor in
+// place of events from a source that exceeded its ingest quota.
+const ReasonQuotaExceeded = "quota_exceeded"
+
+// Sources that publish DLQ events. Other components can add their own with
+// RegisterSource.
 const (
 	SourceDispatch = "dispatch"
 	SourceWarren   = "warren"
 )
 
+// Actors recorded in recovered_by when an entry is recovered, and in
+// discarded_by (RecoveredByDiscard) when it is discarded.
+const (
+	RecoveredByAPIRetry    = "api-retry"
+	RecoveredByAPIRetryAll = "api-retry-all"
+	RecoveredByScanner     = "auto-scanner"
+	RecoveredByDiscard     = "manual-discard"
+	RecoveredByStale       = "stale"
+)
+
+// Lifecycle statuses of an entry. Entries are ingested as StatusPending;
+// MarkRecovered moves them to StatusRecovered and MarkDiscarded to
+// StatusDiscarded. Recovered stays true for every status past pending and
+// retrying, so existing recovered=false filters keep working. The exception
+// is StatusExhausted: the scanner gave up on the entry, but it stays open so
+// an operator can still retry or discard it.
+const (
+	StatusPending   = "pending"
+	StatusRetrying  = "retrying"
+	StatusRecovered = "recovered"
+	StatusDiscarded = "discarded"
+	StatusExpired   = "expired"
+	StatusExhausted = "exhausted"
+)
+
 // NATS subjects for DLQ events.
 const (
 	SubjectTaskUnassignable    = "dlq.task.unassignable"
@@ -56,41 +86,144 @@ type Entry struct {
 	Recovered       bool            `json:"recovered"`
 	RecoveredAt     *time.Time      `json:"recovered_at,omitempty"`
 	RecoveredBy     string          `json:"recovered_by,omitempty"`
+	Status          string          `json:"status"`
+
+	// Occurrences counts how many near-identical events were collapsed into
+	// this entry during a storm. FailedAt is the first, LastSeenAt the last.
+	Occurrences    int               `json:"occurrences,omitempty"`
+	LastSeenAt     *time.Time        `json:"last_seen_at,omitempty"`
+	PayloadSamples []json.RawMessage `json:"payload_samples,omitempty"`
+
+	// Fingerprint identifies repeats of the same failure across entries (see
+	// Fingerprint). The Processor computes it unless the publisher set one.
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// TaskID identifies the work the failed message was about. The
+	// Processor copies it from the payload (see WithProcessorTaskIDFields)
+	// unless the publisher set one.
+	TaskID string `json:"task_id,omitempty"`
+
+	// TenantID is the swarm environment the entry belongs to when several
+	// share one database (see ContextWithTenant).
+	TenantID string `json:"tenant_id,omitempty"`
+
+	// PayloadOmitted is set when the Processor was sampling under overload and
+	// did not keep this entry's payload. SampleRate is the 1-in-N rate that was
+	// in effect when the entry was ingested (0 when not sampling).
+	PayloadOmitted bool `json:"payload_omitted,omitempty"`
+	SampleRate     int  `json:"sample_rate,omitempty"`
+
+	// PayloadTruncated is set when OriginalPayload exceeded the configured
+	// PayloadLimit and was replaced by a JSON string of its first bytes.
+	// PayloadSize is the original size; PayloadRef locates the full payload
+	// when it was offloaded to a BlobStore.
+	PayloadTruncated bool   `json:"payload_truncated,omitempty"`
+	PayloadSize      int    `json:"payload_size,omitempty"`
+	PayloadRef       string `json:"payload_ref,omitempty"`
+
+	// PayloadErased is set when a compliance erasure scrubbed the payload.
+	PayloadErased bool `json:"payload_erased,omitempty"`
+
+	// PayloadRedacted is set when a Redactor masked fields of the payload at
+	// ingest. SealedPayload then holds the unredacted payload encrypted with
+	// the Redactor's key, or is empty if it was dropped.
+	PayloadRedacted bool   `json:"payload_redacted,omitempty"`
+	SealedPayload   []byte `json:"sealed_payload,omitempty"`
+
+	// DiscardedAt and DiscardedBy record a manual discard; DiscardReason and
+	// DiscardNote explain it. Discards leave RecoveredAt and RecoveredBy
+	// empty so they do not count as recoveries.
+	DiscardedAt   *time.Time `json:"discarded_at,omitempty"`
+	DiscardedBy   string     `json:"discarded_by,omitempty"`
+	DiscardReason string     `json:"discard_reason,omitempty"`
+	DiscardNote   string     `json:"discard_note,omitempty"`
+
+	// Priority orders automated recovery: ListRecoverable, and so the
+	// Scanner and retry-all, take higher priorities first. 0 is normal;
+	// negative values go behind it.
+	Priority int `json:"priority,omitempty"`
+
+	// Notes holds operators' triage findings (see PATCH /{dlqID}).
+	Notes string `json:"notes,omitempty"`
+	// AssignedTo is the engineer triaging the entry (see POST
+	// /{dlqID}/assign). Unlike a claim it does not expire or block others.
+	AssignedTo string `json:"assigned_to,omitempty"`
+
+	// ProducerService, ProducerVersion, ProducerHost and ProducerPID identify
+	// the process that published the entry (see NewPublisher).
+	ProducerService string `json:"producer_service,omitempty"`
+	ProducerVersion string `json:"producer_version,omitempty"`
+	ProducerHost    string `json:"producer_host,omitempty"`
+	ProducerPID     int    `json:"producer_pid,omitempty"`
+
+	// Metadata holds external context added at ingest by Enrichers.
+	Metadata map[string]any `json:"metadata,omitempty"`
+
+	// OriginalHeaders are the NATS headers of the failed message. Retries and
+	// replays send them again alongside HeaderDLQID and HeaderDLQRetry.
+	OriginalHeaders map[string][]string `json:"original_headers,omitempty"`
+
+	// ClaimedBy holds a short operator lease on the entry until
+	// ClaimExpiresAt; while it is live, only the holder may retry or discard.
+	ClaimedBy      string     `json:"claimed_by,omitempty"`
+	ClaimExpiresAt *time.Time `json:"claim_expires_at,omitempty"`
+
+	// AutoRetryCount counts the Scanner's failed attempts to retry the
+	// entry. NextRetryAt is when it may try again (see RetryBackoff).
+	AutoRetryCount int        `json:"auto_retry_count,omitempty"`
+	NextRetryAt    *time.Time `json:"next_retry_at,omitempty"`
+
+	// ExpiredAt is set when a recoverable entry was not recovered within
+	// RecoveryWindow and the Scanner moved it to StatusExpired.
+	ExpiredAt *time.Time `json:"expired_at,omitempty"`
+
+	// ReopenedAt and ReopenedBy record the last time the entry was reopened
+	// after a recovery, discard or expiry (see POST /{dlqID}/reopen). Its
+	// recovery window then runs from ReopenedAt instead of FailedAt.
+	ReopenedAt *time.Time `json:"reopened_at,omitempty"`
+	ReopenedBy string     `json:"reopened_by,omitempty"`
+
+	// RetryAt is when an operator scheduled the Scanner to retry the entry,
+	// and RetryScheduledBy who (see POST /{dlqID}/retry?at=).
+	RetryAt          *time.Time `json:"retry_at,omitempty"`
+	RetryScheduledBy string     `json:"retry_scheduled_by,omitempty"`
+
+	// RetryStartedAt is when the retry holding the entry in StatusRetrying
+	// began (see BeginRetry).
+	RetryStartedAt *time.Time `json:"retry_started_at,omitempty"`
+
+	// Version counts changes to the entry's state (status, claim and
+	// metadata); conditional updates compare it to detect concurrent
+	// changes (see ErrVersionConflict). UpdatedAt is when the row was last
+	// written.
+	Version   int        `json:"version,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+
+	// RetryAck is the outcome of the last confirmed retry (see
+	// RetryConfirmation).
+	RetryAck *RetryAck `json:"retry_ack,omitempty"`
+
+	// ArchivedAt is set on entries read from swarm_dlq_archive.
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
 }
 
 // RetryAttempt records one retry attempt before dead-lettering.
+// FailureReason is one of the Failure* constants; Publisher and Processor
+// normalize free text onto them and keep the original in FailureDetail.
 type RetryAttempt struct {
 	Attempt       int       `json:"attempt"`
 	AttemptedAt   time.Time `json:"attempted_at"`
 	Agent         string    `json:"agent,omitempty"`
 	FailureReason string    `json:"failure_reason"`
+	FailureDetail string    `json:"failure_detail,omitempty"`
 }
 
 // SubjectForReason returns the NATS subject to publish to for a given reason and source.
+// Reasons without a subject (see RegisterReason) go to the source's subject
+// prefix (see RegisterSource) followed by ".unknown".
 func SubjectForReason(source, reason string) string {
-	switch reason {
-	case ReasonNoCapableAgent:
-		return SubjectTaskUnassignable
-	case ReasonAllAgentsUnavailable:
-		return SubjectTaskNoAvailableAgent
-	case ReasonPolicyDenied:
-		return SubjectTaskPolicyDenied
-	case ReasonTimeoutAssigned:
-		return SubjectTaskAssignTimeout
-	case ReasonTimeoutInProgress:
-		return SubjectTaskExecTimeout
-	case ReasonAgentCrashed:
-		return SubjectTaskAgentCrashed
-	case ReasonBootFailure:
-		return SubjectAgentBootFailure
-	case ReasonPullFailure:
-		return SubjectAgentPullFailure
-	case ReasonCrashLoop:
-		return SubjectAgentCrashLoop
-	default:
-		if source == SourceWarren {
-			return "dlq.agent.unknown"
-		}
-		return "dlq.task.unknown"
+	if spec, ok := LookupReason(reason); ok && spec.Subject != "" {
+		return spec.Subject
 	}
+	return sourceSubjectPrefix(source) + ".unknown"
 }
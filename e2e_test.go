@@ -215,8 +215,8 @@ func TestE2E_DiscardFlow(t *testing.T) {
 
 	// Verify it's marked as manually discarded.
 	discarded, _ := store.Get(ctx, "e2e-discard-1")
-	if discarded.RecoveredBy != "manual-discard" {
-		t.Errorf("expected recovered_by manual-discard, got %s", discarded.RecoveredBy)
+	if discarded.DiscardedBy != "manual-discard" {
+		t.Errorf("expected discarded_by manual-discard, got %s", discarded.DiscardedBy)
 	}
 
 	// No NATS messages should have been sent (discard doesn't republish).
@@ -0,0 +1,166 @@
+package dlq
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultEnrichTimeout bounds the time enrichment may add to ingesting one
+// entry.
+const DefaultEnrichTimeout = 200 * time.Millisecond
+
+// DefaultEnrichCacheSize is the number of results a cached enricher keeps.
+const DefaultEnrichCacheSize = 1024
+
+// Enricher annotates an entry with external context at ingest, e.g. the task
+// title and requester from Dispatch or the agent image tag from Warren. The
+// returned keys are merged into Entry.Metadata.
+type Enricher interface {
+	Enrich(ctx context.Context, e Entry) (map[string]any, error)
+}
+
+// EnricherFunc adapts a function to Enricher.
+type EnricherFunc func(ctx context.Context, e Entry) (map[string]any, error)
+
+// Enrich calls f.
+func (f EnricherFunc) Enrich(ctx context.Context, e Entry) (map[string]any, error) {
+	return f(ctx, e)
+}
+
+// WithProcessorEnrichers runs enrichers concurrently on every ingested entry
+// and merges their results into Metadata, later enrichers winning on key
+// clashes. Enrichment never blocks ingest for longer than timeout (default
+// DefaultEnrichTimeout): enrichers that fail or are still running are
+// skipped for that entry and counted in MetricProcessorEnrichFailures.
+func WithProcessorEnrichers(timeout time.Duration, enrichers ...Enricher) ProcessorOption {
+	return func(p *Processor) {
+		if timeout <= 0 {
+			timeout = DefaultEnrichTimeout
+		}
+		p.enrichers = enrichers
+		p.enrichTimeout = timeout
+	}
+}
+
+// enrich merges the results of p's enrichers into e.Metadata.
+func (p *Processor) enrich(ctx context.Context, e Entry) Entry {
+	if len(p.enrichers) == 0 {
+		return e
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.enrichTimeout)
+	defer cancel()
+
+	type result struct {
+		i    int
+		meta map[string]any
+		err  error
+	}
+	// Buffered so enrichers that outlive the timeout never block.
+	results := make(chan result, len(p.enrichers))
+	for i, en := range p.enrichers {
+		go func(i int, en Enricher, e Entry) {
+			meta, err := en.Enrich(ctx, e)
+			results <- result{i, meta, err}
+		}(i, en, e)
+	}
+
+	collected := make([]map[string]any, len(p.enrichers))
+collect:
+	for pending := len(p.enrichers); pending > 0; pending-- {
+		select {
+		case r := <-results:
+			if r.err != nil {
+				p.metrics.Inc(MetricProcessorEnrichFailures)
+				slog.Warn("dlq processor: enrichment failed", "dlq_id", e.DLQID, "enricher", r.i, "error", r.err)
+				continue
+			}
+			collected[r.i] = r.meta
+		case <-ctx.Done():
+			p.metrics.Add(MetricProcessorEnrichFailures, int64(pending))
+			slog.Warn("dlq processor: enrichment timed out", "dlq_id", e.DLQID, "pending", pending)
+			break collect
+		}
+	}
+
+	meta := make(map[string]any, len(e.Metadata))
+	for k, v := range e.Metadata {
+		meta[k] = v
+	}
+	for _, m := range collected {
+		for k, v := range m {
+			meta[k] = v
+		}
+	}
+	if len(meta) > 0 {
+		e.Metadata = meta
+	}
+	return e
+}
+
+// cachedEnricher memoizes an Enricher's results by key.
+type cachedEnricher struct {
+	next Enricher
+	key  func(Entry) string
+	ttl  time.Duration
+	size int
+
+	mu      sync.Mutex
+	entries map[string]cachedMeta
+}
+
+type cachedMeta struct {
+	meta    map[string]any
+	expires time.Time
+}
+
+// CachedEnricher wraps next so results are reused for ttl across entries with
+// the same key (e.g. the task or agent id), keeping at most
+// DefaultEnrichCacheSize results. Entries for which key returns "" and
+// failed lookups are not cached.
+func CachedEnricher(next Enricher, key func(Entry) string, ttl time.Duration) Enricher {
+	return &cachedEnricher{next: next, key: key, ttl: ttl, size: DefaultEnrichCacheSize, entries: make(map[string]cachedMeta)}
+}
+
+func (c *cachedEnricher) Enrich(ctx context.Context, e Entry) (map[string]any, error) {
+	k := c.key(e)
+	if k == "" {
+		return c.next.Enrich(ctx, e)
+	}
+	now := time.Now()
+	c.mu.Lock()
+	hit, ok := c.entries[k]
+	c.mu.Unlock()
+	if ok && now.Before(hit.expires) {
+		return hit.meta, nil
+	}
+
+	meta, err := c.next.Enrich(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.size {
+		c.evict(now)
+	}
+	c.entries[k] = cachedMeta{meta: meta, expires: now.Add(c.ttl)}
+	return meta, nil
+}
+
+// evict drops expired results, or an arbitrary one if none have expired.
+// Callers hold c.mu.
+func (c *cachedEnricher) evict(now time.Time) {
+	for k, v := range c.entries {
+		if !now.Before(v.expires) {
+			delete(c.entries, k)
+		}
+	}
+	for k := range c.entries {
+		if len(c.entries) < c.size {
+			return
+		}
+		delete(c.entries, k)
+	}
+}
@@ -0,0 +1,81 @@
+package dlq
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func staticEnricher(meta map[string]any) Enricher {
+	return EnricherFunc(func(context.Context, Entry) (map[string]any, error) { return meta, nil })
+}
+
+func TestProcessor_Enrichers(t *testing.T) {
+	store := newMockStore()
+	m := NewMetrics()
+	slow := EnricherFunc(func(ctx context.Context, _ Entry) (map[string]any, error) {
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond) // ignores cancellation for a while
+		return map[string]any{"slow": true}, nil
+	})
+	failing := EnricherFunc(func(context.Context, Entry) (map[string]any, error) {
+		return nil, fmt.Errorf("dispatch unreachable")
+	})
+	proc := NewProcessor(store, WithProcessorMetrics(m), WithProcessorEnrichers(20*time.Millisecond,
+		staticEnricher(map[string]any{"task_title": "Research competitors", "requester": "alice"}),
+		staticEnricher(map[string]any{"requester": "bob"}),
+		failing,
+		slow,
+	))
+
+	data := eventJSON(Entry{DLQID: "en-1", Reason: ReasonNoCapableAgent})
+	proc.Process(context.Background(), "dlq.task.unassignable", data)
+
+	e, err := store.Get(context.Background(), "en-1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if e.Metadata["task_title"] != "Research competitors" || e.Metadata["requester"] != "bob" {
+		t.Errorf("unexpected metadata: %v", e.Metadata)
+	}
+	if _, ok := e.Metadata["slow"]; ok {
+		t.Error("results arriving after the timeout must be ignored")
+	}
+	if got := m.Get(MetricProcessorEnrichFailures); got != 2 {
+		t.Errorf("expected 2 enrich failures, got %d", got)
+	}
+}
+
+func TestCachedEnricher(t *testing.T) {
+	var calls atomic.Int32
+	next := EnricherFunc(func(_ context.Context, e Entry) (map[string]any, error) {
+		calls.Add(1)
+		return map[string]any{"agent_image": "warren/agent:" + e.DLQID}, nil
+	})
+	c := CachedEnricher(next, func(e Entry) string { return e.Source }, time.Minute)
+
+	first, _ := c.Enrich(context.Background(), Entry{DLQID: "v1", Source: SourceWarren})
+	second, _ := c.Enrich(context.Background(), Entry{DLQID: "v2", Source: SourceWarren})
+	if calls.Load() != 1 || second["agent_image"] != first["agent_image"] {
+		t.Errorf("expected cached result, calls=%d second=%v", calls.Load(), second)
+	}
+
+	_, _ = c.Enrich(context.Background(), Entry{DLQID: "v3"})
+	_, _ = c.Enrich(context.Background(), Entry{DLQID: "v4"})
+	if calls.Load() != 3 {
+		t.Errorf("entries without a key must not be cached, calls=%d", calls.Load())
+	}
+}
+
+func TestCachedEnricher_Evicts(t *testing.T) {
+	c := CachedEnricher(staticEnricher(map[string]any{}), func(e Entry) string { return e.DLQID }, time.Minute).(*cachedEnricher)
+	c.size = 2
+	for i := 0; i < 5; i++ {
+		_, _ = c.Enrich(context.Background(), Entry{DLQID: fmt.Sprint(i)})
+	}
+	if len(c.entries) > 2 {
+		t.Errorf("expected at most 2 cached results, got %d", len(c.entries))
+	}
+}
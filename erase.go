@@ -0,0 +1,267 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErasureMode selects what happens to entries matched by an erasure.
+type ErasureMode string
+
+const (
+	// EraseDelete removes matching entries entirely.
+	EraseDelete ErasureMode = "delete"
+	// EraseScrub replaces the payload and payload samples of matching entries
+	// with null, keeping reason, history and other metadata. Scrubbed entries
+	// are marked payload_erased and are no longer recoverable.
+	EraseScrub ErasureMode = "scrub"
+)
+
+// AuditComplianceErase records an entry erased by a compliance request.
+const AuditComplianceErase AuditAction = "compliance.erase"
+
+// ErrInvalidErasure is returned for an erasure request without a field and
+// value or with an unknown mode.
+var ErrInvalidErasure = errors.New("erasure requires field, value and mode delete or scrub")
+
+// ErasureRequest erases every entry, live or archived, whose payload (or one
+// of its payload samples) has Value at top-level key Field, and every parked
+// invalid event whose raw bytes carry such a payload.
+type ErasureRequest struct {
+	Field string          `json:"field"`
+	Value json.RawMessage `json:"value"`
+	Mode  ErasureMode     `json:"mode"`
+	// DryRun reports the matching entries without changing anything.
+	DryRun bool `json:"dry_run"`
+}
+
+func (req ErasureRequest) validate() error {
+	if strings.TrimSpace(req.Field) == "" || len(req.Value) == 0 || !json.Valid(req.Value) {
+		return ErrInvalidErasure
+	}
+	if req.Mode != EraseDelete && req.Mode != EraseScrub {
+		return ErrInvalidErasure
+	}
+	return nil
+}
+
+// eraseMatch selects rows whose payload or samples contain {$1: $2}, plus
+// the compressed and offloaded rows in $3 found to match by eraseCompressed
+// and eraseOffloaded.
+const eraseMatch = `(original_payload @> jsonb_build_object($1::text, $2::jsonb)
+	OR payload_samples @> jsonb_build_array(jsonb_build_object($1::text, $2::jsonb))
+	OR dlq_id::text = ANY($3::text[]))`
+
+// Erase applies req to swarm_dlq, swarm_dlq_archive and swarm_dlq_invalid
+// in one transaction and returns the ids of the matching entries; parked
+// invalid events are reported as "invalid:<id>". With WithStoreBlobs the
+// offloaded payloads of erased entries are deleted before the transaction
+// commits, so a failed blob delete leaves the entries in place to retry.
+func (s *Store) Erase(ctx context.Context, req ErasureRequest) (_ []string, err error) {
+	defer s.observe("erase", time.Now(), &err)
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	var stmt string
+	match := eraseMatch + ` AND ` + tenantClause(4)
+	switch {
+	case req.DryRun:
+		stmt = `SELECT dlq_id, coalesce(payload_ref, '') FROM %s WHERE ` + match
+	case req.Mode == EraseDelete:
+		stmt = `DELETE FROM %s WHERE ` + match + ` RETURNING dlq_id, coalesce(payload_ref, '')`
+	default:
+		scrubRef := ""
+		if s.blobs != nil {
+			scrubRef = ", payload_ref = NULL"
+		}
+		// RETURNING sees the updated row, so the old payload_ref comes from
+		// the locked subquery.
+		stmt = `UPDATE %[1]s t SET original_payload = 'null', payload_samples = NULL,
+			sealed_payload = NULL, payload_compressed = NULL, payload_encoding = NULL,
+			payload_agents = NULL, recoverable = false, payload_erased = true` + scrubRef + `
+		FROM (SELECT dlq_id, payload_ref FROM %[1]s WHERE ` + match + ` FOR UPDATE) old
+		WHERE t.dlq_id = old.dlq_id
+		RETURNING t.dlq_id, coalesce(old.payload_ref, '')`
+	}
+	tenant := s.tenant(ctx)
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erase: begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	ids := []string{}
+	var refs []string
+	for _, table := range []string{"swarm_dlq", "swarm_dlq_archive"} {
+		extra, err := eraseCompressed(ctx, tx, table, tenant, req)
+		if err != nil {
+			return nil, err
+		}
+		offloaded, err := s.eraseOffloaded(ctx, tx, table, tenant, req)
+		if err != nil {
+			return nil, err
+		}
+		extra = append(extra, offloaded...)
+		rows, err := tx.Query(ctx, fmt.Sprintf(stmt, table), req.Field, []byte(req.Value), extra, tenant)
+		if err != nil {
+			return nil, fmt.Errorf("erase from %s: %w", table, err)
+		}
+		for rows.Next() {
+			var id, ref string
+			if err := rows.Scan(&id, &ref); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("erase from %s: %w", table, err)
+			}
+			ids = append(ids, id)
+			refs = append(refs, ref)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("erase from %s: %w", table, err)
+		}
+	}
+	invalid, err := eraseInvalid(ctx, tx, tenant, req)
+	if err != nil {
+		return nil, err
+	}
+	ids = append(ids, invalid...)
+	if !req.DryRun {
+		if err := s.deleteBlobs(ctx, refs); err != nil {
+			return nil, fmt.Errorf("erase: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("erase: commit: %w", err)
+	}
+	return ids, nil
+}
+
+// eraseCompressed returns the ids of tenant's rows in table whose compressed
+// payload has req.Value at req.Field. Compressed payloads are opaque to SQL, so they
+// are decompressed and matched here.
+func eraseCompressed(ctx context.Context, tx pgx.Tx, table, tenant string, req ErasureRequest) ([]string, error) {
+	var want any
+	if err := json.Unmarshal(req.Value, &want); err != nil {
+		return nil, ErrInvalidErasure
+	}
+	rows, err := tx.Query(ctx, fmt.Sprintf(`
+		SELECT dlq_id::text, payload_encoding, payload_compressed
+		FROM %s WHERE payload_encoding IS NOT NULL AND `+tenantClause(1), table), tenant)
+	if err != nil {
+		return nil, fmt.Errorf("erase compressed from %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var (
+			id, encoding string
+			z            []byte
+		)
+		if err := rows.Scan(&id, &encoding, &z); err != nil {
+			return nil, fmt.Errorf("erase compressed from %s: %w", table, err)
+		}
+		p, err := decompressPayload(encoding, z)
+		if err != nil {
+			return nil, fmt.Errorf("erase compressed from %s: %s: %w", table, id, err)
+		}
+		var doc any
+		if json.Unmarshal(p, &doc) == nil && jsonContains(doc, map[string]any{req.Field: want}) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, rows.Err()
+}
+
+// eraseOffloaded returns the ids of tenant's rows in table whose offloaded
+// payload has req.Value at req.Field. The inline copy of an offloaded payload
+// is truncated, so the full payload is fetched from the blob store. Without
+// a BlobReader there is nothing to fetch and no ids are returned.
+func (s *Store) eraseOffloaded(ctx context.Context, tx pgx.Tx, table, tenant string, req ErasureRequest) ([]string, error) {
+	blobs, ok := s.blobs.(BlobReader)
+	if !ok {
+		return nil, nil
+	}
+	var want any
+	if err := json.Unmarshal(req.Value, &want); err != nil {
+		return nil, ErrInvalidErasure
+	}
+	rows, err := tx.Query(ctx, fmt.Sprintf(`
+		SELECT dlq_id::text, payload_ref
+		FROM %s WHERE payload_ref IS NOT NULL AND `+tenantClause(1), table), tenant)
+	if err != nil {
+		return nil, fmt.Errorf("erase offloaded from %s: %w", table, err)
+	}
+	type offloaded struct{ id, ref string }
+	candidates, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (offloaded, error) {
+		var o offloaded
+		err := row.Scan(&o.id, &o.ref)
+		return o, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erase offloaded from %s: %w", table, err)
+	}
+
+	ids := []string{}
+	for _, o := range candidates {
+		p, err := blobs.Get(ctx, o.ref)
+		if err != nil {
+			return nil, fmt.Errorf("erase offloaded from %s: %s: %w", table, o.id, err)
+		}
+		var doc any
+		if json.Unmarshal(p, &doc) == nil && jsonContains(doc, map[string]any{req.Field: want}) {
+			ids = append(ids, o.id)
+		}
+	}
+	return ids, nil
+}
+
+// handleErase serves POST /compliance/erase.
+func (h *Handler) handleErase(w http.ResponseWriter, r *http.Request) {
+	var req ErasureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid erase body"})
+		return
+	}
+	ids, err := h.store.Erase(r.Context(), req)
+	if errors.Is(err, ErrInvalidErasure) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		slog.Error("dlq compliance erase failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+
+	if !req.DryRun && len(ids) > 0 {
+		// The identifier itself is personal data; only the field is logged.
+		actor, now := actorFromRequest(r), time.Now().UTC()
+		detail := fmt.Sprintf("mode=%s field=%s", req.Mode, req.Field)
+		records := make([]AuditRecord, len(ids))
+		for i, id := range ids {
+			records[i] = AuditRecord{Actor: actor, Action: AuditComplianceErase, DLQID: id, At: now, Detail: detail}
+		}
+		if h.audit != nil {
+			if err := h.audit.RecordAudit(r.Context(), records...); err != nil {
+				slog.Error("dlq: failed to record compliance erase", "count", len(records), "error", err)
+			}
+		}
+		slog.Info("dlq compliance erase", "mode", req.Mode, "field", req.Field, "count", len(ids), "actor", actor)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"mode":    req.Mode,
+		"dry_run": req.DryRun,
+		"matched": len(ids),
+		"dlq_ids": ids,
+	})
+}
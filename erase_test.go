@@ -0,0 +1,144 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func seedErasure(store *mockStore) {
+	store.seed(
+		Entry{DLQID: "er-1", OriginalPayload: json.RawMessage(`{"user_id":"u-42","task_id":"t1"}`), Reason: ReasonNoCapableAgent, Recoverable: true},
+		Entry{DLQID: "er-2", OriginalPayload: json.RawMessage(`{"user_id":"u-7"}`)},
+		Entry{DLQID: "er-3", OriginalPayload: json.RawMessage(`{}`), PayloadSamples: []json.RawMessage{json.RawMessage(`{"user_id":"u-42"}`)}},
+	)
+	store.archived["er-4"] = &Entry{DLQID: "er-4", OriginalPayload: json.RawMessage(`{"user_id":"u-42"}`)}
+}
+
+func eraseRouter(store *mockStore, audit AuditRecorder) http.Handler {
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, newMockNATS(),
+		WithAuditRecorder(audit),
+		WithAuthMiddleware(APIKeyAuth(map[string]Principal{
+			"k-dpo":   {Subject: "dpo", Scopes: []string{ScopeAdmin}},
+			"k-alice": {Subject: "alice"},
+		})),
+	).Routes())
+	return r
+}
+
+// erase posts body to /dlq/compliance/erase with the given API key.
+func erase(r http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/dlq/compliance/erase", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(APIKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+type eraseResponse struct {
+	Matched int      `json:"matched"`
+	DLQIDs  []string `json:"dlq_ids"`
+	DryRun  bool     `json:"dry_run"`
+}
+
+func TestHandler_Erase_DryRun(t *testing.T) {
+	store := newMockStore()
+	seedErasure(store)
+	audit := &memAudit{}
+
+	w := erase(eraseRouter(store, audit), "k-dpo", `{"field":"user_id","value":"u-42","mode":"delete","dry_run":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp eraseResponse
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	sort.Strings(resp.DLQIDs)
+	if resp.Matched != 3 || !resp.DryRun || len(resp.DLQIDs) != 3 || resp.DLQIDs[2] != "er-4" {
+		t.Errorf("unexpected dry run result: %+v", resp)
+	}
+	if len(store.entries) != 3 || len(store.archived) != 1 || len(audit.records) != 0 {
+		t.Error("a dry run must not change or audit anything")
+	}
+}
+
+func TestHandler_Erase_Delete(t *testing.T) {
+	store := newMockStore()
+	seedErasure(store)
+	audit := &memAudit{}
+
+	w := erase(eraseRouter(store, audit), "k-dpo", `{"field":"user_id","value":"u-42","mode":"delete"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if _, ok := store.entries["er-2"]; !ok || len(store.entries) != 1 || len(store.archived) != 0 {
+		t.Errorf("expected only er-2 to remain, got %d live, %d archived", len(store.entries), len(store.archived))
+	}
+	if len(audit.records) != 3 {
+		t.Fatalf("expected an audit record per erased entry, got %d", len(audit.records))
+	}
+	for _, rec := range audit.records {
+		if rec.Actor != "dpo" || rec.Action != AuditComplianceErase || rec.Detail != "mode=delete field=user_id" {
+			t.Errorf("unexpected audit record: %+v", rec)
+		}
+	}
+}
+
+func TestHandler_Erase_Scrub(t *testing.T) {
+	store := newMockStore()
+	seedErasure(store)
+	r := eraseRouter(store, &memAudit{})
+
+	if w := erase(r, "k-dpo", `{"field":"user_id","value":"u-42","mode":"scrub"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	e, _ := store.Get(context.Background(), "er-1")
+	if string(e.OriginalPayload) != "null" || !e.PayloadErased || e.Recoverable || e.Reason != ReasonNoCapableAgent {
+		t.Errorf("expected scrubbed payload with metadata kept, got %+v", e)
+	}
+	if e, _ := store.Get(context.Background(), "er-3"); e.PayloadSamples != nil {
+		t.Error("expected payload samples scrubbed")
+	}
+	if w := doAs(r, "POST", "/dlq/er-1/retry", "", ""); w.Code != http.StatusConflict {
+		t.Errorf("retrying an erased entry: expected 409, got %d", w.Code)
+	}
+}
+
+func TestHandler_Erase_Validation(t *testing.T) {
+	r := eraseRouter(newMockStore(), &memAudit{})
+	for _, body := range []string{
+		`{"value":"u-42","mode":"delete"}`,
+		`{"field":"user_id","mode":"delete"}`,
+		`{"field":"user_id","value":"u-42","mode":"shred"}`,
+		`not json`,
+	} {
+		if w := erase(r, "k-dpo", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestHandler_Erase_RequiresAdmin(t *testing.T) {
+	store := newMockStore()
+	seedErasure(store)
+	r := eraseRouter(store, &memAudit{})
+	body := `{"field":"user_id","value":"u-42","mode":"delete"}`
+
+	if w := erase(r, "", body); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous erase: expected 401, got %d", w.Code)
+	}
+	if w := erase(r, "k-alice", body); w.Code != http.StatusForbidden {
+		t.Errorf("erase without dlq:admin: expected 403, got %d", w.Code)
+	}
+	if len(store.entries) != 3 || len(store.archived) != 1 {
+		t.Error("a refused erase must not change anything")
+	}
+}
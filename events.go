@@ -0,0 +1,131 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// NATS subjects for DLQ lifecycle notifications. These live under dlq.> so
+// Chronicle records them, but the Processor never ingests them as entries.
+const (
+	SubjectRecovered     = "dlq.recovered"
+	SubjectEntryCreated  = "dlq.entry.created"
+	SubjectQuotaExceeded = "dlq.quota.exceeded"
+	SubjectScanSummary   = "dlq.scanner.summary"
+	SubjectExhausted     = "dlq.exhausted"
+	SubjectExpired       = "dlq.expired"
+)
+
+// RecoveredEvent is published to SubjectRecovered after an entry has been
+// republished and marked recovered.
+type RecoveredEvent struct {
+	DLQID           string    `json:"dlq_id"`
+	RecoveredBy     string    `json:"recovered_by"`
+	RecoveredAt     time.Time `json:"recovered_at"`
+	OriginalSubject string    `json:"original_subject"`
+	Reason          string    `json:"reason"`
+	Source          string    `json:"source"`
+}
+
+// ExhaustedEvent is published to SubjectExhausted when the Scanner gives up
+// on an entry after its maximum number of automatic retries.
+type ExhaustedEvent struct {
+	DLQID           string    `json:"dlq_id"`
+	Attempts        int       `json:"attempts"`
+	LastError       string    `json:"last_error,omitempty"`
+	ExhaustedAt     time.Time `json:"exhausted_at"`
+	OriginalSubject string    `json:"original_subject"`
+	Reason          string    `json:"reason"`
+	Source          string    `json:"source"`
+}
+
+// ExpiredEvent is published to SubjectExpired when the Scanner expires a
+// recoverable entry that was not recovered within RecoveryWindow.
+type ExpiredEvent struct {
+	DLQID           string    `json:"dlq_id"`
+	FailedAt        time.Time `json:"failed_at"`
+	ExpiredAt       time.Time `json:"expired_at"`
+	OriginalSubject string    `json:"original_subject"`
+	Reason          string    `json:"reason"`
+	Source          string    `json:"source"`
+}
+
+// EntryCreatedEvent is published to SubjectEntryCreated after the Processor
+// persists a new entry. It deliberately omits the payload.
+type EntryCreatedEvent struct {
+	DLQID           string    `json:"dlq_id"`
+	Reason          string    `json:"reason"`
+	Source          string    `json:"source"`
+	OriginalSubject string    `json:"original_subject"`
+	FailedAt        time.Time `json:"failed_at"`
+}
+
+// ScanSummaryEvent is published to SubjectScanSummary after every Scanner
+// pass. Skipped counts entries held back by the deny-list, throttle, an
+// operator claim or the before-retry hook; Stale counts entries closed
+// because their task had already finished; Exhausted counts failed entries
+// that reached the scanner's maximum number of automatic retries; Expired
+// counts entries that left the recovery window unrecovered. In a dry run
+// the counts are what the pass would have done.
+type ScanSummaryEvent struct {
+	StartedAt  time.Time `json:"started_at"`
+	Considered int       `json:"considered"`
+	Retried    int       `json:"retried"`
+	Failed     int       `json:"failed"`
+	Skipped    int       `json:"skipped"`
+	Stale      int       `json:"stale"`
+	Exhausted  int       `json:"exhausted"`
+	Expired    int       `json:"expired"`
+	DurationMS int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	DryRun     bool      `json:"dry_run,omitempty"`
+}
+
+// isEventSubject reports whether subject carries a DLQ lifecycle notification
+// or query rather than a dead-lettered item.
+func isEventSubject(subject string) bool {
+	switch subject {
+	case SubjectRecovered, SubjectEntryCreated, SubjectQuotaExceeded, SubjectScanSummary, SubjectExhausted, SubjectExpired:
+		return true
+	}
+	return strings.HasPrefix(subject, SubjectQueryPrefix)
+}
+
+// publishEvent marshals v and publishes it to subject. Failures are logged
+// but never returned: notifications must not fail the operation they describe.
+func publishEvent(ctx context.Context, nc NATSPublisher, subject string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("dlq: failed to marshal event", "subject", subject, "error", err)
+		return
+	}
+	if err := publishContext(ctx, nc, subject, data); err != nil {
+		slog.Warn("dlq: failed to publish event", "subject", subject, "error", err)
+	}
+}
+
+// publishRecovered emits a RecoveredEvent for entry.
+func publishRecovered(ctx context.Context, nc NATSPublisher, entry Entry, recoveredBy string) {
+	publishEvent(ctx, nc, SubjectRecovered, RecoveredEvent{
+		DLQID:           entry.DLQID,
+		RecoveredBy:     recoveredBy,
+		RecoveredAt:     time.Now().UTC(),
+		OriginalSubject: entry.OriginalSubject,
+		Reason:          entry.Reason,
+		Source:          entry.Source,
+	})
+}
+
+// publishEntryCreated emits an EntryCreatedEvent for entry.
+func publishEntryCreated(ctx context.Context, nc NATSPublisher, entry Entry) {
+	publishEvent(ctx, nc, SubjectEntryCreated, EntryCreatedEvent{
+		DLQID:           entry.DLQID,
+		Reason:          entry.Reason,
+		Source:          entry.Source,
+		OriginalSubject: entry.OriginalSubject,
+		FailedAt:        entry.FailedAt,
+	})
+}
@@ -0,0 +1,198 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEvents_RecoveredOnAPIRetry(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(Entry{DLQID: "ev-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true})
+	r := newTestRouter(store, nc)
+
+	req := httptest.NewRequest("POST", "/dlq/ev-1/retry", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	events := nc.events(SubjectRecovered)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 recovered event, got %d", len(events))
+	}
+	var ev RecoveredEvent
+	if err := json.Unmarshal(events[0].Data, &ev); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if ev.DLQID != "ev-1" || ev.RecoveredBy != RecoveredByAPIRetry || ev.OriginalSubject != "swarm.task.request" {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+}
+
+func TestEvents_RecoveredOnRetryAllAndScanner(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(
+		Entry{DLQID: "ev-2", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true},
+		Entry{DLQID: "ev-3", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true},
+	)
+	r := newTestRouter(store, nc)
+
+	req := httptest.NewRequest("POST", "/dlq/retry-all", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if got := len(nc.events(SubjectRecovered)); got != 2 {
+		t.Errorf("expected 2 recovered events from retry-all, got %d", got)
+	}
+
+	store.seed(Entry{DLQID: "ev-4", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true})
+	NewScanner(store, nc, time.Minute).scan(context.Background())
+
+	var ev RecoveredEvent
+	events := nc.events(SubjectRecovered)
+	_ = json.Unmarshal(events[len(events)-1].Data, &ev)
+	if ev.DLQID != "ev-4" || ev.RecoveredBy != RecoveredByScanner {
+		t.Errorf("unexpected scanner event: %+v", ev)
+	}
+}
+
+func TestEvents_NoRecoveredEventWhenMarkFails(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.recoverErr = context.DeadlineExceeded
+	store.seed(Entry{DLQID: "ev-5", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true})
+
+	NewScanner(store, nc, time.Minute).scan(context.Background())
+
+	if got := len(nc.events(SubjectRecovered)); got != 0 {
+		t.Errorf("expected no recovered events, got %d", got)
+	}
+}
+
+func TestProcessor_IgnoresLifecycleEvents(t *testing.T) {
+	store := newMockStore()
+	proc := NewProcessor(store)
+
+	data, _ := json.Marshal(RecoveredEvent{DLQID: "ev-6", RecoveredBy: RecoveredByScanner})
+	proc.Process(context.Background(), SubjectRecovered, data)
+
+	if store.insertCalls != 0 {
+		t.Errorf("expected lifecycle event to be ignored, got %d inserts", store.insertCalls)
+	}
+}
+
+func TestEvents_EntryCreatedAfterInsert(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	proc := NewProcessor(store, WithProcessorEvents(nc))
+
+	data := eventJSON(Entry{DLQID: "ev-7", OriginalSubject: "swarm.task.request", Reason: ReasonNoCapableAgent})
+	proc.Process(context.Background(), SubjectTaskUnassignable, data)
+
+	events := nc.events(SubjectEntryCreated)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 created event, got %d", len(events))
+	}
+	var ev EntryCreatedEvent
+	_ = json.Unmarshal(events[0].Data, &ev)
+	if ev.DLQID != "ev-7" || ev.Source != SourceDispatch || ev.Reason != ReasonNoCapableAgent || ev.OriginalSubject != "swarm.task.request" {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+}
+
+func TestEvents_NoEntryCreatedOnInsertError(t *testing.T) {
+	store := newMockStore()
+	store.insertErr = context.DeadlineExceeded
+	nc := newMockNATS()
+	proc := NewProcessor(store, WithProcessorEvents(nc))
+
+	data := eventJSON(Entry{DLQID: "ev-8"})
+	proc.Process(context.Background(), SubjectTaskUnassignable, data)
+
+	if got := len(nc.events(SubjectEntryCreated)); got != 0 {
+		t.Errorf("expected no created events, got %d", got)
+	}
+}
+
+func TestEvents_ScanSummary(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(
+		Entry{DLQID: "ss-1", OriginalSubject: "swarm.task.request", Recoverable: true},
+		Entry{DLQID: "ss-2", OriginalSubject: "billing.charge", Recoverable: true},
+	)
+	deny := NewDenyList(DenyRules{Subjects: []string{"billing.charge"}})
+
+	NewScanner(store, nc, time.Minute, WithScannerDenyList(deny)).scan(context.Background())
+
+	events := nc.events(SubjectScanSummary)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 summary event, got %d", len(events))
+	}
+	var ev ScanSummaryEvent
+	_ = json.Unmarshal(events[0].Data, &ev)
+	if ev.Considered != 2 || ev.Retried != 1 || ev.Skipped != 1 || ev.Failed != 0 {
+		t.Errorf("unexpected summary: %+v", ev)
+	}
+}
+
+func TestEvents_ScanSummaryOnListError(t *testing.T) {
+	store := newMockStore()
+	store.listErr = context.DeadlineExceeded
+	nc := newMockNATS()
+
+	NewScanner(store, nc, time.Minute).scan(context.Background())
+
+	events := nc.events(SubjectScanSummary)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 summary event, got %d", len(events))
+	}
+	var ev ScanSummaryEvent
+	_ = json.Unmarshal(events[0].Data, &ev)
+	if ev.Error == "" {
+		t.Error("expected the listing error to be reported")
+	}
+}
+
+// ctxNATS is a mockNATS that also records the contexts it was given.
+type ctxNATS struct {
+	mockNATS
+	ctxs []context.Context
+}
+
+func (c *ctxNATS) PublishContext(ctx context.Context, subject string, data []byte) error {
+	c.mu.Lock()
+	c.ctxs = append(c.ctxs, ctx)
+	c.mu.Unlock()
+	return c.Publish(subject, data)
+}
+
+func TestEvents_PublishedWithRequestContext(t *testing.T) {
+	store := newMockStore()
+	nc := &ctxNATS{}
+	store.seed(Entry{DLQID: "ev-ctx", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`)})
+	r := newTestRouter(store, nc)
+
+	type key struct{}
+	req := httptest.NewRequest("POST", "/dlq/ev-ctx/retry", nil)
+	req = req.WithContext(context.WithValue(req.Context(), key{}, "req"))
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	// The republish and the recovered event.
+	if len(nc.ctxs) != 2 {
+		t.Fatalf("expected 2 publishes through PublishContext, got %d", len(nc.ctxs))
+	}
+	for _, ctx := range nc.ctxs {
+		if ctx.Value(key{}) != "req" {
+			t.Error("expected publishes to use the request context")
+		}
+	}
+	if len(nc.events(SubjectRecovered)) != 1 {
+		t.Error("expected a recovered event")
+	}
+}
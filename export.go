@@ -0,0 +1,139 @@
+package dlq
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// exportBatch is how many rows Export reads per query. Entries are handed to
+// the callback as they are scanned, so at most one batch is in flight.
+const exportBatch = 500
+
+// Export calls fn for every entry matching opts, oldest first. It pages
+// through swarm_dlq with a keyset cursor on (failed_at, dlq_id), so exports
+// of any size run in constant memory and never hold a long transaction.
+// opts.Limit caps the total when set; unlike List there is no default limit.
+// An error from fn stops the export and is returned.
+func (s *Store) Export(ctx context.Context, opts ListOpts, fn func(Entry) error) (err error) {
+	defer s.observe("export", time.Now(), &err)
+	where, args := s.tenantFilter(ctx, opts)
+
+	var (
+		afterAt time.Time
+		afterID string
+		total   int
+	)
+	for {
+		batch := exportBatch
+		if opts.Limit > 0 && opts.Limit-total < batch {
+			batch = opts.Limit - total
+		}
+		if batch <= 0 {
+			return nil
+		}
+
+		q := `SELECT ` + entryColumns + ` FROM swarm_dlq WHERE 1=1` + where
+		pageArgs := args
+		if total > 0 {
+			q += fmt.Sprintf(` AND (failed_at, dlq_id) > ($%d, $%d)`, len(args)+1, len(args)+2)
+			pageArgs = append(pageArgs[:len(pageArgs):len(pageArgs)], afterAt, afterID)
+		}
+		q += fmt.Sprintf(` ORDER BY failed_at, dlq_id LIMIT $%d`, len(pageArgs)+1)
+		pageArgs = append(pageArgs[:len(pageArgs):len(pageArgs)], batch)
+
+		n, err := s.exportPage(ctx, q, pageArgs, func(e Entry) error {
+			afterAt, afterID = e.FailedAt, e.DLQID
+			return fn(e)
+		})
+		if err != nil {
+			return err
+		}
+		total += n
+		if n < batch {
+			return nil
+		}
+	}
+}
+
+func (s *Store) exportPage(ctx context.Context, q string, args []any, fn func(Entry) error) (int, error) {
+	rows, err := s.pool.Query(ctx, q, args...)
+	if err != nil {
+		return 0, fmt.Errorf("export dlq: %w", err)
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return n, err
+		}
+		n++
+		if err := fn(*e); err != nil {
+			return n, err
+		}
+	}
+	return n, rows.Err()
+}
+
+// exportFormats maps the ?format= values of GET /export to stream formats.
+var exportFormats = map[string]streamFormat{
+	"ndjson": streamNDJSON,
+	"csv":    streamCSV,
+	"json":   streamJSON,
+}
+
+// handleExport streams every entry matching the list filters as NDJSON (the
+// default), CSV or a JSON array. Payload reads are audited, or payloads
+// masked, one flush batch at a time.
+func (h *Handler) handleExport(w http.ResponseWriter, r *http.Request) {
+	format := streamNDJSON
+	if v := r.URL.Query().Get("format"); v != "" {
+		f, ok := exportFormats[v]
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "format must be ndjson, csv or json"})
+			return
+		}
+		format = f
+	}
+
+	// The stream is opened on the first entry so that a store error before
+	// any row is read can still be reported with a status code.
+	var (
+		stream  *entryStream
+		pending []Entry
+	)
+	flushAudit := func() {
+		h.auditPayloadAccess(r, AuditPayloadExport, pending...)
+		pending = pending[:0]
+	}
+	masked := h.mask.applies(r)
+	err := h.store.Export(r.Context(), listOptsFromQuery(r.URL.Query()), func(e Entry) error {
+		if stream == nil {
+			stream = newEntryStream(w, http.StatusOK, format)
+		}
+		if masked {
+			e = h.mask.entry(e)
+		} else if pending = append(pending, e); len(pending) == streamFlushEvery {
+			flushAudit()
+		}
+		return stream.Write(e)
+	})
+	if !masked {
+		flushAudit()
+	}
+	if err != nil {
+		slog.Error("dlq export failed", "error", err)
+		if stream == nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		}
+		return
+	}
+	if stream == nil {
+		stream = newEntryStream(w, http.StatusOK, format)
+	}
+	_ = stream.Close()
+}
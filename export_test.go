@@ -0,0 +1,94 @@
+package dlq
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandler_Export_NDJSON(t *testing.T) {
+	store := newMockStore()
+	base := time.Now().Add(-time.Hour)
+	// More than the list endpoint's default limit of 50.
+	for i := 0; i < 120; i++ {
+		store.seed(Entry{DLQID: fmt.Sprintf("ex-%03d", i), Reason: ReasonBootFailure, FailedAt: base.Add(time.Duration(i) * time.Second)})
+	}
+	store.seed(Entry{DLQID: "ex-other", Reason: ReasonCrashLoop, FailedAt: base})
+	audit := &memAudit{}
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, newMockNATS(), WithAuditRecorder(audit)).Routes())
+
+	req := httptest.NewRequest("GET", "/dlq/export?format=ndjson&reason="+ReasonBootFailure, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected application/x-ndjson, got %s", ct)
+	}
+	var ids []string
+	sc := bufio.NewScanner(w.Body)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("line %d: %v", len(ids)+1, err)
+		}
+		ids = append(ids, e.DLQID)
+	}
+	if len(ids) != 120 {
+		t.Fatalf("expected all 120 matching entries, got %d", len(ids))
+	}
+	if ids[0] != "ex-000" || ids[119] != "ex-119" {
+		t.Errorf("expected oldest first, got %s..%s", ids[0], ids[119])
+	}
+	if len(audit.records) != 120 {
+		t.Errorf("expected every exported payload audited, got %d records", len(audit.records))
+	}
+}
+
+func TestHandler_Export_Formats(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "ex-1", FailedAt: time.Now()})
+	r := newTestRouter(store, newMockNATS())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/export", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected ndjson by default, got %s", ct)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/export?format=csv", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv" || !strings.Contains(w.Body.String(), "ex-1") {
+		t.Errorf("expected a csv export, got %s: %s", ct, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/export?format=xml", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown format, got %d", w.Code)
+	}
+}
+
+func TestHandler_Export_StoreError(t *testing.T) {
+	store := newMockStore()
+	store.listErr = errors.New("db down")
+	r := newTestRouter(store, newMockNATS())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/export", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 when the export fails before any row, got %d", w.Code)
+	}
+}
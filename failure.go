@@ -0,0 +1,102 @@
+package dlq
+
+import "strings"
+
+// Failure reasons recorded in RetryAttempt.FailureReason.
+const (
+	FailureAgentUnavailable  = "agent_unavailable"
+	FailureNoCapableAgent    = "no_capable_agent"
+	FailureAgentCrashed      = "agent_crashed"
+	FailureBootFailure       = "boot_failure"
+	FailurePullFailure       = "pull_failure"
+	FailureCrashLoop         = "crash_loop"
+	FailureHealthCheckFailed = "health_check_failed"
+	FailureTimeout           = "timeout"
+	FailurePolicyDenied      = "policy_denied"
+	FailureRejected          = "rejected"
+	FailureOther             = "other"
+)
+
+// failureReasons is the canonical taxonomy.
+var failureReasons = map[string]bool{
+	FailureAgentUnavailable:  true,
+	FailureNoCapableAgent:    true,
+	FailureAgentCrashed:      true,
+	FailureBootFailure:       true,
+	FailurePullFailure:       true,
+	FailureCrashLoop:         true,
+	FailureHealthCheckFailed: true,
+	FailureTimeout:           true,
+	FailurePolicyDenied:      true,
+	FailureRejected:          true,
+	FailureOther:             true,
+}
+
+// failureAliases maps variants seen in the wild to canonical reasons.
+var failureAliases = map[string]string{
+	"unavailable":         FailureAgentUnavailable,
+	"agent_not_available": FailureAgentUnavailable,
+	"agent_offline":       FailureAgentUnavailable,
+	"no_agent":            FailureNoCapableAgent,
+	"no_capable_agents":   FailureNoCapableAgent,
+	"crashed":             FailureAgentCrashed,
+	"agent_crash":         FailureAgentCrashed,
+	"crash":               FailureAgentCrashed,
+	"boot_failed":         FailureBootFailure,
+	"pull_failed":         FailurePullFailure,
+	"image_pull_failure":  FailurePullFailure,
+	"crashloop":           FailureCrashLoop,
+	"crash_loop_backoff":  FailureCrashLoop,
+	"healthcheck_failed":  FailureHealthCheckFailed,
+	"unhealthy":           FailureHealthCheckFailed,
+	"timed_out":           FailureTimeout,
+	"timeout_assigned":    FailureTimeout,
+	"timeout_in_progress": FailureTimeout,
+	"deadline_exceeded":   FailureTimeout,
+	"denied":              FailurePolicyDenied,
+	"policy_violation":    FailurePolicyDenied,
+}
+
+// ValidFailureReason reports whether reason is part of the taxonomy.
+func ValidFailureReason(reason string) bool {
+	return failureReasons[reason]
+}
+
+// NormalizeFailureReason maps a free-text failure reason onto the taxonomy:
+// it lower-cases, converts spaces and dashes to underscores and resolves
+// known aliases. Anything else becomes FailureOther; known reports whether
+// the input could be mapped.
+func NormalizeFailureReason(reason string) (normalized string, known bool) {
+	r := strings.ToLower(strings.TrimSpace(reason))
+	r = strings.NewReplacer(" ", "_", "-", "_").Replace(r)
+	if failureReasons[r] {
+		return r, true
+	}
+	if canonical, ok := failureAliases[r]; ok {
+		return canonical, true
+	}
+	return FailureOther, false
+}
+
+// normalizeRetryHistory normalizes every attempt's FailureReason in place,
+// keeping the original text in FailureDetail whenever it changes. It returns
+// how many reasons could not be mapped.
+func normalizeRetryHistory(history []RetryAttempt) (unknown int) {
+	for i := range history {
+		a := &history[i]
+		if a.FailureReason == "" {
+			continue
+		}
+		normalized, known := NormalizeFailureReason(a.FailureReason)
+		if !known {
+			unknown++
+		}
+		if normalized != a.FailureReason {
+			if a.FailureDetail == "" {
+				a.FailureDetail = a.FailureReason
+			}
+			a.FailureReason = normalized
+		}
+	}
+	return unknown
+}
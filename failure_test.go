@@ -0,0 +1,87 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormalizeFailureReason(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  string
+		known bool
+	}{
+		{"agent_unavailable", FailureAgentUnavailable, true},
+		{"Agent Unavailable", FailureAgentUnavailable, true},
+		{"agent-offline", FailureAgentUnavailable, true},
+		{"timed out", FailureTimeout, true},
+		{"CrashLoop", FailureCrashLoop, true},
+		{"the moon was wrong", FailureOther, false},
+	}
+	for _, tt := range tests {
+		got, known := NormalizeFailureReason(tt.in)
+		if got != tt.want || known != tt.known {
+			t.Errorf("NormalizeFailureReason(%q) = %q, %v; want %q, %v", tt.in, got, known, tt.want, tt.known)
+		}
+		if !ValidFailureReason(got) {
+			t.Errorf("%q is not in the taxonomy", got)
+		}
+	}
+}
+
+func TestProcessor_NormalizesFailureReasons(t *testing.T) {
+	store := newMockStore()
+	metrics := NewMetrics()
+	proc := NewProcessor(store, WithProcessorMetrics(metrics))
+
+	data := eventJSON(Entry{DLQID: "fr-1", RetryHistory: []RetryAttempt{
+		{Attempt: 1, FailureReason: "agent_unavailable"},
+		{Attempt: 2, FailureReason: "Timed Out"},
+		{Attempt: 3, FailureReason: "gremlins"},
+	}})
+	proc.Process(context.Background(), SubjectTaskUnassignable, data)
+
+	e, _ := store.Get(context.Background(), "fr-1")
+	got := []string{e.RetryHistory[0].FailureReason, e.RetryHistory[1].FailureReason, e.RetryHistory[2].FailureReason}
+	want := []string{FailureAgentUnavailable, FailureTimeout, FailureOther}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("attempt %d: expected %q, got %q", i+1, want[i], got[i])
+		}
+	}
+	if e.RetryHistory[0].FailureDetail != "" {
+		t.Error("canonical reasons should not get a detail")
+	}
+	if e.RetryHistory[2].FailureDetail != "gremlins" {
+		t.Errorf("expected original text kept, got %q", e.RetryHistory[2].FailureDetail)
+	}
+	if n := metrics.Get(MetricProcessorUnknownFailures); n != 1 {
+		t.Errorf("expected 1 unknown failure reason, got %d", n)
+	}
+}
+
+func TestHandler_FailureStats(t *testing.T) {
+	store := newMockStore()
+	store.seed(
+		Entry{DLQID: "fs-1", RetryHistory: []RetryAttempt{{FailureReason: FailureTimeout}, {FailureReason: FailureAgentCrashed}}},
+		Entry{DLQID: "fs-2", RetryHistory: []RetryAttempt{{FailureReason: FailureTimeout}}},
+		Entry{DLQID: "fs-3", Recovered: true, RetryHistory: []RetryAttempt{{FailureReason: FailureTimeout}}},
+	)
+	r := newTestRouter(store, newMockNATS())
+
+	req := httptest.NewRequest("GET", "/dlq/stats/failures", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var counts map[string]int
+	_ = json.NewDecoder(w.Body).Decode(&counts)
+	if counts[FailureTimeout] != 2 || counts[FailureAgentCrashed] != 1 {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+}
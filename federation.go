@@ -0,0 +1,267 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Remote is a DLQ HTTP API in another swarm cluster.
+type Remote struct {
+	// Name labels the cluster in federated responses.
+	Name string
+	// BaseURL is where the remote mounts the DLQ routes,
+	// e.g. "https://chronicle.eu.example.com/api/v1/dlq".
+	BaseURL string
+	// Headers are sent with every request, e.g. Authorization.
+	Headers map[string]string
+}
+
+// FederatedStats aggregates Stats across clusters. Clusters that could not be
+// reached are reported in Errors and excluded from Global.
+type FederatedStats struct {
+	Global   Stats             `json:"global"`
+	Clusters map[string]*Stats `json:"clusters"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+// FederatedEntry is an Entry labelled with the cluster it came from.
+type FederatedEntry struct {
+	Cluster string `json:"cluster"`
+	Entry
+}
+
+// FederatedList is a merged entry listing across clusters.
+type FederatedList struct {
+	Entries []FederatedEntry  `json:"entries"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+// Federation gives a single view over the local DLQ and a set of remote DLQ
+// APIs. Remotes are queried concurrently; a failing remote is reported but
+// does not fail the whole request.
+type Federation struct {
+	localName string
+	local     Reader
+	remotes   []Remote
+	client    *http.Client
+}
+
+// NewFederation creates a federation over local (labelled localName) and
+// remotes. A nil client defaults to one with a 10 second timeout.
+func NewFederation(localName string, local Reader, remotes []Remote, client *http.Client) *Federation {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Federation{localName: localName, local: local, remotes: remotes, client: client}
+}
+
+// Stats returns per-cluster and global statistics.
+func (f *Federation) Stats(ctx context.Context) *FederatedStats {
+	out := &FederatedStats{
+		Global:   Stats{ByReason: make(map[string]int), BySource: make(map[string]int), ByStatus: make(map[string]int), BySubject: make(map[string]int)},
+		Clusters: make(map[string]*Stats),
+	}
+	var mu sync.Mutex
+	record := func(cluster string, st *Stats, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			if out.Errors == nil {
+				out.Errors = make(map[string]string)
+			}
+			out.Errors[cluster] = err.Error()
+			return
+		}
+		out.Clusters[cluster] = st
+		mergeStats(&out.Global, st)
+	}
+
+	var wg sync.WaitGroup
+	if f.local != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			st, err := f.local.Stats(ctx)
+			record(f.localName, st, err)
+		}()
+	}
+	for _, rm := range f.remotes {
+		wg.Add(1)
+		go func(rm Remote) {
+			defer wg.Done()
+			var st Stats
+			err := f.get(ctx, rm, "/stats", nil, &st)
+			record(rm.Name, &st, err)
+		}(rm)
+	}
+	wg.Wait()
+	return out
+}
+
+// List returns entries matching opts from every cluster, in opts.Sort order,
+// truncated to opts.Limit (default 50).
+func (f *Federation) List(ctx context.Context, opts ListOpts) *FederatedList {
+	out := &FederatedList{Entries: []FederatedEntry{}}
+	var mu sync.Mutex
+	record := func(cluster string, entries []Entry, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			if out.Errors == nil {
+				out.Errors = make(map[string]string)
+			}
+			out.Errors[cluster] = err.Error()
+			return
+		}
+		for _, e := range entries {
+			out.Entries = append(out.Entries, FederatedEntry{Cluster: cluster, Entry: e})
+		}
+	}
+
+	var wg sync.WaitGroup
+	if f.local != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entries, err := f.local.List(ctx, opts)
+			record(f.localName, entries, err)
+		}()
+	}
+	q := listOptsQuery(opts)
+	for _, rm := range f.remotes {
+		wg.Add(1)
+		go func(rm Remote) {
+			defer wg.Done()
+			var entries []Entry
+			err := f.get(ctx, rm, "/", q, &entries)
+			record(rm.Name, entries, err)
+		}(rm)
+	}
+	wg.Wait()
+
+	sort.SliceStable(out.Entries, func(i, j int) bool {
+		return listLess(opts.Sort, &out.Entries[i].Entry, &out.Entries[j].Entry)
+	})
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	if len(out.Entries) > limit {
+		out.Entries = out.Entries[:limit]
+	}
+	return out
+}
+
+func (f *Federation) get(ctx context.Context, rm Remote, path string, q url.Values, v any) error {
+	u := strings.TrimRight(rm.BaseURL, "/") + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, val := range rm.Headers {
+		req.Header.Set(k, val)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("query %s: %w", rm.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("query %s: unexpected status %d", rm.Name, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode %s response: %w", rm.Name, err)
+	}
+	return nil
+}
+
+// listOptsQuery is the inverse of listOptsFromQuery.
+func listOptsQuery(opts ListOpts) url.Values {
+	q := url.Values{}
+	if opts.Status != "" {
+		q.Set("status", opts.Status)
+	}
+	if opts.Recovered != nil {
+		q.Set("recovered", strconv.FormatBool(*opts.Recovered))
+	}
+	if opts.Reason != "" {
+		q.Set("reason", opts.Reason)
+	}
+	if opts.Source != "" {
+		q.Set("source", opts.Source)
+	}
+	if opts.Agent != "" {
+		q.Set("agent", opts.Agent)
+	}
+	for k, v := range map[string]string{
+		"producer_service": opts.ProducerService,
+		"producer_version": opts.ProducerVersion,
+		"producer_host":    opts.ProducerHost,
+		"fingerprint":      opts.Fingerprint,
+		"assigned_to":      opts.AssignedTo,
+		"task_id":          opts.TaskID,
+		"sort":             opts.Sort,
+	} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	return q
+}
+
+// mergeStats adds src's counts into dst.
+func mergeStats(dst, src *Stats) {
+	if src == nil {
+		return
+	}
+	dst.Total += src.Total
+	dst.Unrecovered += src.Unrecovered
+	dst.Recoverable += src.Recoverable
+	dst.Expired += src.Expired
+	for k, v := range src.ByReason {
+		dst.ByReason[k] += v
+	}
+	for k, v := range src.BySource {
+		dst.BySource[k] += v
+	}
+	for k, v := range src.ByStatus {
+		dst.ByStatus[k] += v
+	}
+	for k, v := range src.BySubject {
+		dst.BySubject[k] += v
+	}
+	dst.OldestUnrecoveredAge = max(dst.OldestUnrecoveredAge, src.OldestUnrecoveredAge)
+	dst.RecoveredLast24h += src.RecoveredLast24h
+	mergeTimeToRecovery(&dst.TimeToRecovery, src.TimeToRecovery)
+}
+
+// mergeTimeToRecovery weights the averages by count. Percentiles cannot be
+// combined exactly, so the merged ones are the highest of either side: an
+// upper bound suitable for SLO reporting.
+func mergeTimeToRecovery(dst *TimeToRecovery, src TimeToRecovery) {
+	n := dst.Count + src.Count
+	if n == 0 {
+		return
+	}
+	dst.Avg = (dst.Avg*float64(dst.Count) + src.Avg*float64(src.Count)) / float64(n)
+	dst.Count = n
+	dst.P50 = max(dst.P50, src.P50)
+	dst.P90 = max(dst.P90, src.P90)
+	dst.P99 = max(dst.P99, src.P99)
+}
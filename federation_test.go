@@ -0,0 +1,151 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// newRemoteDLQ serves a DLQ API backed by store, requiring the given bearer token.
+func newRemoteDLQ(t *testing.T, store DataStore, token string) *httptest.Server {
+	t.Helper()
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("Authorization") != "Bearer "+token {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	})
+	r.Mount("/dlq", NewHandler(store, newMockNATS()).Routes())
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFederation_StatsAndList(t *testing.T) {
+	now := time.Now().UTC()
+	local := newMockStore()
+	local.seed(Entry{DLQID: "l-1", OriginalSubject: "swarm.task.request", Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: now.Add(-time.Hour)})
+
+	remoteStore := newMockStore()
+	remoteStore.seed(
+		Entry{DLQID: "r-1", OriginalSubject: "swarm.task.request", Reason: ReasonBootFailure, Source: SourceWarren, FailedAt: now},
+		Entry{DLQID: "r-2", Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: now.Add(-2 * time.Hour)},
+	)
+	srv := newRemoteDLQ(t, remoteStore, "secret")
+
+	fed := NewFederation("us", local, []Remote{
+		{Name: "eu", BaseURL: srv.URL + "/dlq", Headers: map[string]string{"Authorization": "Bearer secret"}},
+		{Name: "ap", BaseURL: srv.URL + "/dlq", Headers: map[string]string{"Authorization": "Bearer wrong"}},
+	}, nil)
+
+	st := fed.Stats(context.Background())
+	if st.Global.Total != 3 {
+		t.Errorf("expected global total 3, got %d", st.Global.Total)
+	}
+	if st.Global.ByReason[ReasonNoCapableAgent] != 2 {
+		t.Errorf("expected 2 no_capable_agent globally, got %d", st.Global.ByReason[ReasonNoCapableAgent])
+	}
+	if st.Global.BySubject["swarm.task.request"] != 2 {
+		t.Errorf("expected 2 swarm.task.request globally, got %v", st.Global.BySubject)
+	}
+	if st.Clusters["eu"] == nil || st.Clusters["eu"].Total != 2 {
+		t.Errorf("expected eu total 2, got %+v", st.Clusters["eu"])
+	}
+	if _, ok := st.Errors["ap"]; !ok {
+		t.Error("expected an error for the unauthorized cluster")
+	}
+
+	list := fed.List(context.Background(), ListOpts{Limit: 2})
+	if len(list.Entries) != 2 {
+		t.Fatalf("expected 2 entries after limit, got %d", len(list.Entries))
+	}
+	if list.Entries[0].DLQID != "r-1" || list.Entries[0].Cluster != "eu" {
+		t.Errorf("expected newest entry r-1 from eu first, got %s from %s", list.Entries[0].DLQID, list.Entries[0].Cluster)
+	}
+	if list.Entries[1].DLQID != "l-1" || list.Entries[1].Cluster != "us" {
+		t.Errorf("expected l-1 from us second, got %s from %s", list.Entries[1].DLQID, list.Entries[1].Cluster)
+	}
+}
+
+func TestFederation_StatsExpired(t *testing.T) {
+	expired := func(id string) Entry {
+		return Entry{DLQID: id, Reason: ReasonAgentCrashed, Source: SourceDispatch, FailedAt: time.Now().Add(-48 * time.Hour), Recovered: true, Status: StatusExpired}
+	}
+	local := newMockStore()
+	local.seed(expired("l-exp"))
+	remoteStore := newMockStore()
+	remoteStore.seed(expired("r-exp-1"), expired("r-exp-2"))
+	srv := newRemoteDLQ(t, remoteStore, "secret")
+
+	fed := NewFederation("us", local, []Remote{
+		{Name: "eu", BaseURL: srv.URL + "/dlq", Headers: map[string]string{"Authorization": "Bearer secret"}},
+	}, nil)
+	st := fed.Stats(context.Background())
+	if st.Global.Expired != 3 || st.Clusters["eu"].Expired != 2 {
+		t.Errorf("expected 3 expired globally and 2 in eu, got %d and %+v", st.Global.Expired, st.Clusters["eu"])
+	}
+}
+
+func TestMergeStats_Recovery(t *testing.T) {
+	dst := Stats{OldestUnrecoveredAge: 10, RecoveredLast24h: 1,
+		TimeToRecovery: TimeToRecovery{Count: 1, Avg: 100, P50: 100, P90: 100, P99: 100}}
+	mergeStats(&dst, &Stats{OldestUnrecoveredAge: 50, RecoveredLast24h: 2,
+		TimeToRecovery: TimeToRecovery{Count: 3, Avg: 20, P50: 10, P90: 200, P99: 300}})
+
+	if dst.OldestUnrecoveredAge != 50 || dst.RecoveredLast24h != 3 {
+		t.Errorf("expected the oldest age and summed recoveries, got %+v", dst)
+	}
+	if ttr := dst.TimeToRecovery; ttr.Count != 4 || ttr.Avg != 40 || ttr.P50 != 100 || ttr.P99 != 300 {
+		t.Errorf("expected a weighted average and upper-bound percentiles, got %+v", ttr)
+	}
+}
+
+func TestHandler_FederationRoutes(t *testing.T) {
+	local := newMockStore()
+	local.seed(Entry{DLQID: "l-1", Reason: ReasonNoCapableAgent, Source: SourceDispatch})
+	fed := NewFederation("us", local, nil, nil)
+
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(local, newMockNATS(), WithFederation(fed)).Routes())
+
+	req := httptest.NewRequest("GET", "/dlq/federation/stats", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var st FederatedStats
+	_ = json.NewDecoder(w.Body).Decode(&st)
+	if st.Clusters["us"] == nil || st.Clusters["us"].Total != 1 {
+		t.Errorf("expected us total 1, got %+v", st.Clusters["us"])
+	}
+
+	req = httptest.NewRequest("GET", "/dlq/federation/entries?reason=no_capable_agent", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var list FederatedList
+	_ = json.NewDecoder(w.Body).Decode(&list)
+	if len(list.Entries) != 1 || list.Entries[0].Cluster != "us" {
+		t.Errorf("unexpected federated list: %+v", list)
+	}
+}
+
+func TestHandler_FederationRoutes_DisabledByDefault(t *testing.T) {
+	r := newTestRouter(newMockStore(), newMockNATS())
+
+	req := httptest.NewRequest("GET", "/dlq/federation/stats", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without federation, got %d", w.Code)
+	}
+}
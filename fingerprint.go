@@ -0,0 +1,167 @@
+package dlq
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Fingerprint identifies repeats of the same failure: it is the hex SHA-256
+// of the original subject, the reason and a hash of the payload. JSON
+// payloads are hashed in canonical form, so key order and whitespace do
+// not matter.
+func Fingerprint(subject, reason string, payload json.RawMessage) string {
+	ph := sha256.Sum256(canonicalJSON(payload))
+	h := sha256.New()
+	h.Write([]byte(subject))
+	h.Write([]byte{0})
+	h.Write([]byte(reason))
+	h.Write([]byte{0})
+	h.Write(ph[:])
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalJSON re-encodes p with sorted keys and no insignificant
+// whitespace. Payloads that are not valid JSON are returned as they are.
+func canonicalJSON(p json.RawMessage) []byte {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return p
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return p
+	}
+	return out
+}
+
+// WithProcessorFingerprintCollapse folds events whose fingerprint matches an
+// open entry (not recovered, discarded or expired) into that entry: its
+// occurrences counter and last_seen_at are advanced instead of inserting a
+// new row. Without it, repeats are stored as separate entries linked by
+// their shared fingerprint (see GET /?group=fingerprint).
+func WithProcessorFingerprintCollapse() ProcessorOption {
+	return func(p *Processor) { p.fingerprints = true }
+}
+
+// collapseFingerprint folds entry into the open entry sharing its
+// fingerprint, reporting whether it was absorbed. Store errors are logged
+// and the entry is inserted on its own rather than lost.
+func (p *Processor) collapseFingerprint(ctx context.Context, entry Entry) bool {
+	if !p.fingerprints || entry.Fingerprint == "" {
+		return false
+	}
+	into, err := p.store.RecordFingerprint(ctx, entry.Fingerprint, entry.DLQID, time.Now().UTC())
+	if err != nil {
+		slog.Error("dlq processor: failed to collapse by fingerprint",
+			"dlq_id", entry.DLQID,
+			"fingerprint", entry.Fingerprint,
+			"error", err,
+		)
+		return false
+	}
+	if into == "" {
+		return false
+	}
+	p.metrics.Inc(MetricProcessorRepeats)
+	slog.Info("dlq processor: collapsed repeat failure",
+		"dlq_id", entry.DLQID,
+		"into", into,
+		"fingerprint", entry.Fingerprint,
+	)
+	return true
+}
+
+// RecordFingerprint adds one occurrence to the newest open entry with
+// fingerprint and returns its dlq_id, or "" if there is none. An open entry
+// that is dlqID itself is left alone, so redeliveries of a stored event are
+// still reported as duplicates by Insert.
+func (s *Store) RecordFingerprint(ctx context.Context, fingerprint, dlqID string, lastSeen time.Time) (into string, err error) {
+	defer s.observe("record_fingerprint", time.Now(), &err)
+	err = s.pool.QueryRow(ctx, `
+		UPDATE swarm_dlq
+		SET occurrences  = occurrences + 1,
+		    last_seen_at = greatest(coalesce(last_seen_at, failed_at), $2)
+		WHERE dlq_id = (
+			SELECT dlq_id FROM swarm_dlq
+			WHERE fingerprint = $1 AND status NOT IN ('recovered', 'discarded', 'expired')
+			  AND `+tenantClause(4)+`
+			ORDER BY failed_at DESC LIMIT 1
+		) AND dlq_id::text <> $3
+		RETURNING dlq_id::text
+	`, fingerprint, lastSeen, dlqID, s.tenant(ctx)).Scan(&into)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("record fingerprint: %w", err)
+	}
+	return into, nil
+}
+
+// FingerprintGroup summarises the entries sharing a fingerprint.
+type FingerprintGroup struct {
+	Fingerprint     string    `json:"fingerprint"`
+	OriginalSubject string    `json:"original_subject"`
+	Reason          string    `json:"reason"`
+	Entries         int       `json:"entries"`
+	Occurrences     int       `json:"occurrences"`
+	FirstFailedAt   time.Time `json:"first_failed_at"`
+	LastSeenAt      time.Time `json:"last_seen_at"`
+	LatestDLQID     string    `json:"latest_dlq_id"`
+}
+
+// ListFingerprintGroups groups the entries matching opts by fingerprint,
+// most recently seen first. Entries without a fingerprint are left out.
+func (s *Store) ListFingerprintGroups(ctx context.Context, opts ListOpts) (_ []FingerprintGroup, err error) {
+	defer s.observe("list_fingerprint_groups", time.Now(), &err)
+	where, args := s.tenantFilter(ctx, opts)
+	q := `
+		SELECT fingerprint, min(original_subject), min(reason), count(*), sum(occurrences),
+		       min(failed_at), max(coalesce(last_seen_at, failed_at)),
+		       (array_agg(dlq_id::text ORDER BY failed_at DESC))[1]
+		FROM swarm_dlq WHERE fingerprint IS NOT NULL` + where + `
+		GROUP BY fingerprint
+		ORDER BY max(coalesce(last_seen_at, failed_at)) DESC` +
+		fmt.Sprintf(` LIMIT $%d`, len(args)+1)
+	args = append(args, listLimit(opts))
+
+	rows, err := s.pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list fingerprint groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []FingerprintGroup{}
+	for rows.Next() {
+		var g FingerprintGroup
+		if err := rows.Scan(&g.Fingerprint, &g.OriginalSubject, &g.Reason, &g.Entries, &g.Occurrences,
+			&g.FirstFailedAt, &g.LastSeenAt, &g.LatestDLQID); err != nil {
+			return nil, fmt.Errorf("list fingerprint groups: %w", err)
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// handleListGroups serves GET /?group=fingerprint.
+func (h *Handler) handleListGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.store.ListFingerprintGroups(r.Context(), listOptsFromQuery(r.URL.Query()))
+	if err != nil {
+		slog.Error("list dlq fingerprint groups failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, groups)
+}
@@ -0,0 +1,133 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+	base := Fingerprint("swarm.task.request", ReasonNoCapableAgent, json.RawMessage(`{"task_id":"t1","n":1}`))
+	if got := Fingerprint("swarm.task.request", ReasonNoCapableAgent, json.RawMessage(`{ "n": 1, "task_id": "t1" }`)); got != base {
+		t.Error("key order and whitespace must not change the fingerprint")
+	}
+	for name, fp := range map[string]string{
+		"subject": Fingerprint("swarm.task.other", ReasonNoCapableAgent, json.RawMessage(`{"task_id":"t1","n":1}`)),
+		"reason":  Fingerprint("swarm.task.request", ReasonPolicyDenied, json.RawMessage(`{"task_id":"t1","n":1}`)),
+		"payload": Fingerprint("swarm.task.request", ReasonNoCapableAgent, json.RawMessage(`{"task_id":"t2","n":1}`)),
+	} {
+		if fp == base {
+			t.Errorf("a different %s must change the fingerprint", name)
+		}
+	}
+	if Fingerprint("s", "r", json.RawMessage(`not json`)) == Fingerprint("s", "r", json.RawMessage(`not json!`)) {
+		t.Error("invalid JSON payloads should be hashed as they are")
+	}
+}
+
+func fingerprintEvent(id, taskID string) []byte {
+	data := eventJSON(Entry{
+		DLQID:           id,
+		OriginalSubject: "swarm.task.request",
+		OriginalPayload: json.RawMessage(`{"task_id":"` + taskID + `"}`),
+		Reason:          ReasonNoCapableAgent,
+		Source:          SourceDispatch,
+		FailedAt:        time.Now().UTC(),
+	})
+	return data
+}
+
+func TestProcessor_FingerprintLinks(t *testing.T) {
+	store := newMockStore()
+	proc := NewProcessor(store)
+
+	for i := 0; i < 3; i++ {
+		proc.Process(context.Background(), SubjectTaskUnassignable, fingerprintEvent(fmt.Sprintf("fp-%d", i), "t1"))
+	}
+	a, _ := store.Get(context.Background(), "fp-0")
+	b, _ := store.Get(context.Background(), "fp-2")
+	if store.insertCalls != 3 || a.Fingerprint == "" || a.Fingerprint != b.Fingerprint {
+		t.Errorf("expected 3 entries sharing a fingerprint, got %d inserts, %q and %q", store.insertCalls, a.Fingerprint, b.Fingerprint)
+	}
+
+	data := eventJSON(Entry{DLQID: "fp-custom", OriginalSubject: "s", Reason: ReasonNoCapableAgent, Fingerprint: "custom"})
+	proc.Process(context.Background(), SubjectTaskUnassignable, data)
+	if e, _ := store.Get(context.Background(), "fp-custom"); e.Fingerprint != "custom" {
+		t.Errorf("a publisher-set fingerprint should be kept, got %q", e.Fingerprint)
+	}
+}
+
+func TestProcessor_FingerprintCollapse(t *testing.T) {
+	store := newMockStore()
+	metrics := NewMetrics()
+	proc := NewProcessor(store, WithProcessorFingerprintCollapse(), WithProcessorMetrics(metrics))
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		proc.Process(ctx, SubjectTaskUnassignable, fingerprintEvent(fmt.Sprintf("fpc-%d", i), "t1"))
+	}
+	proc.Process(ctx, SubjectTaskUnassignable, fingerprintEvent("fpc-other", "t2"))
+	// A redelivery of the stored event is a duplicate, not an occurrence.
+	proc.Process(ctx, SubjectTaskUnassignable, fingerprintEvent("fpc-0", "t1"))
+
+	head, _ := store.Get(ctx, "fpc-0")
+	if head.Occurrences != 4 || head.LastSeenAt == nil {
+		t.Errorf("expected 4 occurrences with last_seen_at, got %d %v", head.Occurrences, head.LastSeenAt)
+	}
+	if got := metrics.Get(MetricProcessorRepeats); got != 3 {
+		t.Errorf("expected 3 collapsed, got %d", got)
+	}
+	if _, err := store.Get(ctx, "fpc-other"); err != nil {
+		t.Error("a different payload should get its own entry")
+	}
+
+	_ = store.MarkRecovered(ctx, "fpc-0", "op")
+	proc.Process(ctx, SubjectTaskUnassignable, fingerprintEvent("fpc-again", "t1"))
+	if _, err := store.Get(ctx, "fpc-again"); err != nil {
+		t.Error("a repeat of a recovered failure should open a new entry")
+	}
+}
+
+func TestHandler_ListGroupByFingerprint(t *testing.T) {
+	store := newMockStore()
+	now := time.Now().UTC()
+	store.seed(
+		Entry{DLQID: "g1", OriginalSubject: "s", Reason: ReasonNoCapableAgent, Fingerprint: "a", FailedAt: now.Add(-time.Hour)},
+		Entry{DLQID: "g2", OriginalSubject: "s", Reason: ReasonNoCapableAgent, Fingerprint: "a", FailedAt: now, Occurrences: 3},
+		Entry{DLQID: "g3", OriginalSubject: "s", Reason: ReasonBootFailure, Fingerprint: "b", FailedAt: now.Add(-2 * time.Hour)},
+		Entry{DLQID: "g4", OriginalSubject: "s", Reason: ReasonBootFailure},
+	)
+	r := newTestRouter(store, newMockNATS())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/?group=fingerprint", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var groups []FingerprintGroup
+	_ = json.NewDecoder(w.Body).Decode(&groups)
+	if len(groups) != 2 || groups[0].Fingerprint != "a" {
+		t.Fatalf("expected groups a then b, got %+v", groups)
+	}
+	if g := groups[0]; g.Entries != 2 || g.Occurrences != 4 || g.LatestDLQID != "g2" {
+		t.Errorf("unexpected group: %+v", g)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/?fingerprint=a", nil))
+	var entries []Entry
+	_ = json.NewDecoder(w.Body).Decode(&entries)
+	if len(entries) != 2 {
+		t.Errorf("expected the 2 entries of group a, got %d", len(entries))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/?group=reason", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown group, got %d", w.Code)
+	}
+}
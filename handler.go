@@ -1,61 +1,292 @@
 package dlq
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
-// NATSPublisher is the interface for publishing messages to NATS.
+// NATSPublisher is the interface for publishing messages to NATS. Despite
+// the name it is the package's transport abstraction: *nats.Conn satisfies
+// it, and so do CircuitBreaker and KafkaPublisher.
 type NATSPublisher interface {
 	Publish(subject string, data []byte) error
 }
 
+// NATSContextPublisher is a NATSPublisher that honours a context's deadline
+// and cancellation. When the NATSPublisher given to a component also
+// implements it, every publish is made through PublishContext with the
+// context of the request or scan that triggered it.
+type NATSContextPublisher interface {
+	PublishContext(ctx context.Context, subject string, data []byte) error
+}
+
+// DefaultRetryAllConcurrency is the number of entries retry-all republishes in parallel.
+const DefaultRetryAllConcurrency = 8
+
 // Handler provides HTTP endpoints for DLQ management.
 type Handler struct {
-	store DataStore
-	nc    NATSPublisher
+	store                DataStore
+	nc                   NATSPublisher
+	retryAllConcurrency  int
+	federation           *Federation
+	readOnly             bool
+	requireDiscardReason bool
+	transformer          *Transformer
+	replayPrefixes       []string
+	denyList             *DenyList
+	throttle             *SubjectThrottle
+	recovery             RecoveryActions
+	taskStatus           TaskStatusChecker
+	audit                AuditRecorder
+	mask                 *PayloadMask
+	bundleKey            []byte
+	confirm              *RetryConfirmation
+	auth                 []func(http.Handler) http.Handler
+	requireAuth          bool
+	live                 *LiveFeed
+	scanner              *Scanner
+	importer             *Processor
+	blobs                BlobReader
+	outbox               *Outbox
+	idempotency          IdempotencyStore
+	tenant               string
+	tenantFunc           func(*http.Request) string
+	redactor             *Redactor
+}
+
+// HandlerOption configures a Handler.
+type HandlerOption func(*Handler)
+
+// WithRetryAllConcurrency sets how many entries retry-all publishes and
+// marks recovered in parallel.
+func WithRetryAllConcurrency(n int) HandlerOption {
+	return func(h *Handler) {
+		if n > 0 {
+			h.retryAllConcurrency = n
+		}
+	}
+}
+
+// WithFederation mounts GET /federation/stats and GET /federation/entries,
+// which aggregate this instance with the remote clusters in f.
+func WithFederation(f *Federation) HandlerOption {
+	return func(h *Handler) { h.federation = f }
+}
+
+// WithReadOnly disables every mutating route: retry, discard and retry-all
+// respond 405 Method Not Allowed. Use it for instances exposed to broad
+// audiences while keeping mutations on the ops deployment.
+func WithReadOnly() HandlerOption {
+	return func(h *Handler) { h.readOnly = true }
+}
+
+// WithRequireDiscardReason makes POST /{dlqID}/discard reject requests whose
+// JSON body does not include a non-empty "reason".
+func WithRequireDiscardReason() HandlerOption {
+	return func(h *Handler) { h.requireDiscardReason = true }
+}
+
+// WithPayloadTransformer rewrites payloads with t before they are republished
+// by retry and retry-all.
+func WithPayloadTransformer(t *Transformer) HandlerOption {
+	return func(h *Handler) { h.transformer = t }
+}
+
+// WithReplayPrefixes restricts the subject prefixes accepted by the replay
+// endpoints. Without it any prefix ending in "." is accepted.
+func WithReplayPrefixes(prefixes ...string) HandlerOption {
+	return func(h *Handler) { h.replayPrefixes = prefixes }
+}
+
+// WithDenyList mounts GET and PUT /deny-list for viewing and replacing the
+// scanner deny-list d.
+func WithDenyList(d *DenyList) HandlerOption {
+	return func(h *Handler) { h.denyList = d }
+}
+
+// WithRetryAllThrottle limits how fast retry-all republishes per original
+// subject. Throttled entries stay unrecovered and are reported as "throttled".
+func WithRetryAllThrottle(t *SubjectThrottle) HandlerOption {
+	return func(h *Handler) { h.throttle = t }
+}
+
+// WithRecoveryActions recovers entries with the configured reasons via their
+// action instead of republishing the payload, for retry and retry-all.
+func WithRecoveryActions(a RecoveryActions) HandlerOption {
+	return func(h *Handler) { h.recovery = a }
+}
+
+// WithTaskStatusChecker consults c before retry and retry-all republish an
+// entry; entries whose task already completed or was cancelled are marked
+// recovered with recovered_by=stale instead.
+func WithTaskStatusChecker(c TaskStatusChecker) HandlerOption {
+	return func(h *Handler) { h.taskStatus = c }
+}
+
+// WithScanner mounts GET /scanner/status, which reports s.Status() so
+// operators can see whether automated recovery is working.
+func WithScanner(s *Scanner) HandlerOption {
+	return func(h *Handler) { h.scanner = s }
 }
 
 // NewHandler creates a DLQ HTTP handler.
-func NewHandler(store DataStore, nc NATSPublisher) *Handler {
-	return &Handler{store: store, nc: nc}
+func NewHandler(store DataStore, nc NATSPublisher, opts ...HandlerOption) *Handler {
+	h := &Handler{store: store, nc: nc, retryAllConcurrency: DefaultRetryAllConcurrency}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // Routes returns a chi.Router with all DLQ endpoints mounted.
 func (h *Handler) Routes() chi.Router {
 	r := chi.NewRouter()
+	r.Use(traceRoutes)
+	if len(h.auth) > 0 {
+		r.Use(ignoreActorHeader)
+		r.Use(h.auth...)
+	}
+	r.Use(h.scopeTenant)
 	r.Get("/", h.handleList)
+	r.Get("/openapi.json", h.handleOpenAPI(r))
+	r.Get("/export", h.handleExport)
 	r.Get("/stats", h.handleStats)
+	r.Get("/stats/agents", h.handleAgentStats)
+	r.Get("/stats/failures", h.handleFailureStats)
+	r.Get("/stats/timeseries", h.handleTimeseries)
+	if h.federation != nil {
+		r.Get("/federation/stats", h.handleFederatedStats)
+		r.Get("/federation/entries", h.handleFederatedList)
+	}
+	if h.denyList != nil {
+		r.Get("/deny-list", h.handleGetDenyList)
+		r.Put("/deny-list", h.mutating(h.handlePutDenyList))
+	}
+	if h.bundleKey != nil {
+		r.Get("/bundle", h.handleExportBundle)
+		r.Post("/bundle", h.mutating(h.handleImportBundle))
+	}
+	if h.live != nil {
+		r.Get("/live", h.live.ServeHTTP)
+	}
+	if h.scanner != nil {
+		r.Get("/scanner/status", h.handleScannerStatus)
+	}
+	if h.importer != nil {
+		r.Post("/import", h.mutating(h.handleImport))
+	}
+	r.Post("/compliance/erase", h.mutating(requireScope(ScopeAdmin, h.handleErase)))
+	r.Route("/archive", func(r chi.Router) {
+		r.Get("/", h.handleListArchived)
+		r.Post("/", h.mutating(h.handleArchive))
+		r.Get("/{dlqID}", h.handleGetArchived)
+	})
+	r.Post("/purge", h.mutating(requireScope(ScopeAdmin, h.handlePurge)))
 	r.Get("/{dlqID}", h.handleGet)
-	r.Post("/{dlqID}/retry", h.handleRetry)
-	r.Post("/{dlqID}/discard", h.handleDiscard)
-	r.Post("/retry-all", h.handleRetryAll)
+	r.Patch("/{dlqID}", h.mutating(h.handlePatch))
+	r.Delete("/{dlqID}", h.mutating(requireScope(ScopeAdmin, h.handleDelete)))
+	r.Get("/{dlqID}/related", h.handleRelated)
+	if h.blobs != nil {
+		r.Get("/{dlqID}/payload", h.handlePayload)
+	}
+	if _, ok := h.audit.(AuditLog); ok {
+		r.Get("/{dlqID}/audit", h.handleAudit)
+	}
+	r.Post("/{dlqID}/retry", h.mutating(h.idempotent(h.handleRetry)))
+	r.Delete("/{dlqID}/retry", h.mutating(h.handleCancelRetry))
+	r.Post("/{dlqID}/discard", h.mutating(h.idempotent(h.handleDiscard)))
+	r.Post("/{dlqID}/reopen", h.mutating(h.handleReopen))
+	r.Post("/{dlqID}/assign", h.mutating(h.handleAssign))
+	r.Delete("/{dlqID}/assign", h.mutating(h.handleUnassign))
+	r.Post("/{dlqID}/claim", h.mutating(h.handleClaim))
+	r.Delete("/{dlqID}/claim", h.mutating(h.handleReleaseClaim))
+	r.Post("/retry-all", h.mutating(h.idempotent(h.handleRetryAll)))
+	r.Post("/{dlqID}/replay", h.mutating(h.handleReplay))
+	r.Post("/replay", h.mutating(h.handleReplayBulk))
 	return r
 }
 
-func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+// mutating wraps a handler for a route that changes DLQ state.
+func (h *Handler) mutating(next http.HandlerFunc) http.HandlerFunc {
+	if h.readOnly {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", "GET")
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "dlq api is read-only"})
+		}
+	}
+	if !h.requireAuth {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := PrincipalFromContext(r.Context()); !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "authentication required"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// listOptsFromQuery parses the list filters shared by all listing endpoints.
+func listOptsFromQuery(q url.Values) ListOpts {
 	opts := ListOpts{}
 
-	if v := r.URL.Query().Get("recovered"); v != "" {
+	if v := q.Get("recovered"); v != "" {
 		b := v == "true"
 		opts.Recovered = &b
 	}
-	if v := r.URL.Query().Get("reason"); v != "" {
+	opts.Status = q.Get("status")
+	if v := q.Get("reason"); v != "" {
 		opts.Reason = v
 	}
-	if v := r.URL.Query().Get("source"); v != "" {
+	if v := q.Get("source"); v != "" {
 		opts.Source = v
 	}
-	if v := r.URL.Query().Get("limit"); v != "" {
+	if v := q.Get("agent"); v != "" {
+		opts.Agent = v
+	}
+	opts.ProducerService = q.Get("producer_service")
+	opts.ProducerVersion = q.Get("producer_version")
+	opts.ProducerHost = q.Get("producer_host")
+	opts.Fingerprint = q.Get("fingerprint")
+	opts.AssignedTo = q.Get("assigned_to")
+	opts.TaskID = q.Get("task_id")
+	opts.Sort = q.Get("sort")
+	if v := q.Get("limit"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
 			opts.Limit = n
 		}
 	}
+	return opts
+}
+
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Query().Get("group") {
+	case "":
+	case "fingerprint":
+		h.handleListGroups(w, r)
+		return
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "group must be fingerprint"})
+		return
+	}
+	opts := listOptsFromQuery(r.URL.Query())
+	if !validListSort(opts.Sort) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "sort must be failed_at or priority"})
+		return
+	}
 
 	entries, err := h.store.List(r.Context(), opts)
 	if err != nil {
@@ -63,10 +294,22 @@ func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
 		return
 	}
-	if entries == nil {
-		entries = []Entry{}
+	h.readPayloads(r, AuditPayloadExport, entries)
+	writeEntries(w, http.StatusOK, negotiateFormat(r), entries)
+}
+
+func (h *Handler) handleFederatedStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.federation.Stats(r.Context()))
+}
+
+func (h *Handler) handleFederatedList(w http.ResponseWriter, r *http.Request) {
+	list := h.federation.List(r.Context(), listOptsFromQuery(r.URL.Query()))
+	if h.mask.applies(r) {
+		for i := range list.Entries {
+			list.Entries[i].Entry = h.mask.entry(list.Entries[i].Entry)
+		}
 	}
-	writeJSON(w, http.StatusOK, entries)
+	writeJSON(w, http.StatusOK, list)
 }
 
 func (h *Handler) handleGet(w http.ResponseWriter, r *http.Request) {
@@ -76,33 +319,145 @@ func (h *Handler) handleGet(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": "dlq entry not found"})
 		return
 	}
-	writeJSON(w, http.StatusOK, entry)
+	read := []Entry{*entry}
+	h.readPayloads(r, AuditPayloadRead, read)
+	writeJSON(w, http.StatusOK, read[0])
 }
 
 func (h *Handler) handleRetry(w http.ResponseWriter, r *http.Request) {
 	dlqID := chi.URLParam(r, "dlqID")
-
-	entry, err := h.store.Get(r.Context(), dlqID)
+	at, err := retryScheduleFromQuery(r, time.Now())
 	if err != nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "dlq entry not found"})
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if !at.IsZero() {
+		h.scheduleRetry(w, r, dlqID, at)
+		return
+	}
+	if h.outbox == nil && h.natsUnavailable(w) {
 		return
 	}
 
-	if entry.Recovered {
+	// Take the entry into StatusRetrying first, so a concurrent retry of
+	// the same entry is refused instead of publishing a second time.
+	entry, err := h.store.BeginRetry(r.Context(), dlqID, actorFromRequest(r))
+	switch {
+	case errors.Is(err, ErrAlreadyRecovered):
 		writeJSON(w, http.StatusConflict, map[string]string{"error": "already recovered"})
 		return
+	case errors.Is(err, ErrRetryInProgress):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, ErrEntryClaimed):
+		resp := map[string]string{"error": err.Error()}
+		if e, err := h.store.Get(r.Context(), dlqID); err == nil {
+			resp["claimed_by"] = e.ClaimedBy
+		}
+		writeJSON(w, http.StatusLocked, resp)
+		return
+	case err != nil:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "dlq entry not found"})
+		return
+	}
+	published := false
+	defer func() {
+		if !published {
+			h.abortRetry(r.Context(), dlqID)
+		}
+	}()
+
+	if _, err := h.loadOffloaded(r.Context(), entry); err != nil {
+		slog.Error("failed to fetch offloaded dlq payload", "dlq_id", dlqID, "ref", entry.PayloadRef, "error", err)
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "failed to fetch offloaded payload"})
+		return
+	}
+	*entry = unredact(h.redactor, *entry)
+	if msg := payloadUnavailable(*entry); msg != "" {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": msg})
+		return
+	}
+
+	stale, err := staleTask(r.Context(), h.taskStatus, *entry)
+	if err != nil {
+		slog.Error("dlq task status check failed", "dlq_id", dlqID, "error", err)
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "task status check failed"})
+		return
+	}
+	if stale {
+		if err := markStale(r.Context(), h.store, h.nc, *entry); err != nil {
+			slog.Error("failed to mark stale", "dlq_id", dlqID, "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+			return
+		}
+		h.auditEntry(r, AuditRetry, dlqID, AuditResultStale, "")
+		writeJSON(w, http.StatusOK, map[string]string{"status": "stale", "dlq_id": dlqID})
+		return
+	}
+
+	payload, err := h.transformer.Apply(*entry)
+	if err != nil {
+		slog.Error("failed to transform dlq payload", "dlq_id", dlqID, "error", err)
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": "payload transform failed"})
+		return
+	}
+
+	subject, payload, err := h.recovery.resolve(*entry, payload)
+	if err != nil {
+		slog.Error("dlq recovery action failed", "dlq_id", dlqID, "error", err)
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": "recovery action failed"})
+		return
 	}
 
-	// Republish original payload to the original subject.
-	if err := h.nc.Publish(entry.OriginalSubject, entry.OriginalPayload); err != nil {
+	by := recoveredBy(r, RecoveredByAPIRetry)
+	if h.outbox != nil {
+		queued, err := h.outbox.Recover(r.Context(), *entry, by, subject, payload)
+		if err != nil {
+			slog.Error("failed to record dlq recovery", "dlq_id", dlqID, "error", err)
+			h.auditEntry(r, AuditRetry, dlqID, AuditResultFailed, err.Error())
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to record recovery"})
+			return
+		}
+		published = true
+		publishRecovered(r.Context(), h.nc, *entry, by)
+		h.auditEntry(r, AuditRetry, dlqID, AuditResultOK, "")
+		h.live.Broadcast(liveEvent(LiveRetried, *entry, actorFromRequest(r)))
+		if queued {
+			writeJSON(w, http.StatusAccepted, map[string]string{"status": "queued", "dlq_id": dlqID})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "retried", "dlq_id": dlqID})
+		return
+	}
+
+	// Republish original payload to the original subject (or run the
+	// configured recovery action).
+	if err := republish(r.Context(), h.nc, h.confirm, h.store, *entry, by, subject, payload); err != nil {
 		slog.Error("failed to republish dlq entry", "dlq_id", dlqID, "error", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to republish"})
+		h.auditEntry(r, AuditRetry, dlqID, AuditResultFailed, err.Error())
+		switch {
+		case errors.Is(err, ErrCircuitOpen):
+			writeCircuitOpen(w, circuitRetryAfter(h.nc))
+		case errors.Is(err, ErrRetryAckTimeout):
+			writeJSON(w, http.StatusGatewayTimeout, map[string]string{"error": err.Error()})
+		case errors.Is(err, ErrRetryNotAcknowledged):
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to republish"})
+		}
 		return
 	}
+	// From here the entry stays retrying until MarkRecovered or the lease
+	// expires: releasing it could republish the payload again.
+	published = true
 
-	if err := h.store.MarkRecovered(r.Context(), dlqID, "api-retry"); err != nil {
+	if err := h.store.MarkRecoveredIfVersion(r.Context(), dlqID, by, entry.Version); err != nil {
 		slog.Error("failed to mark recovered", "dlq_id", dlqID, "error", err)
+	} else {
+		publishRecovered(r.Context(), h.nc, *entry, by)
 	}
+	h.auditEntry(r, AuditRetry, dlqID, AuditResultOK, "")
+	h.live.Broadcast(liveEvent(LiveRetried, *entry, actorFromRequest(r)))
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "retried", "dlq_id": dlqID})
 }
@@ -110,15 +465,64 @@ func (h *Handler) handleRetry(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) handleDiscard(w http.ResponseWriter, r *http.Request) {
 	dlqID := chi.URLParam(r, "dlqID")
 
-	if err := h.store.MarkRecovered(r.Context(), dlqID, "manual-discard"); err != nil {
+	var opts DiscardOpts
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil && !errors.Is(err, io.EOF) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid discard body"})
+			return
+		}
+	}
+	opts.Reason = strings.TrimSpace(opts.Reason)
+	if h.requireDiscardReason && opts.Reason == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "discard reason is required"})
+		return
+	}
+	entry, _ := h.store.Get(r.Context(), dlqID)
+
+	// The store refuses the discard while someone else holds a claim, so a
+	// claim taken after the Get above still wins.
+	opts.Actor = actorFromRequest(r)
+	if err := h.store.MarkDiscarded(r.Context(), dlqID, recoveredBy(r, RecoveredByDiscard), opts); err != nil {
+		var conflict *VersionConflictError
+		if errors.As(err, &conflict) {
+			writeJSON(w, http.StatusConflict, map[string]any{"error": ErrVersionConflict.Error(), "version": conflict.Current})
+			return
+		}
+		if errors.Is(err, ErrEntryClaimed) {
+			resp := map[string]string{"error": err.Error()}
+			if e, err := h.store.Get(r.Context(), dlqID); err == nil {
+				resp["claimed_by"] = e.ClaimedBy
+			}
+			writeJSON(w, http.StatusLocked, resp)
+			return
+		}
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("discard failed: %v", err)})
 		return
 	}
+	h.auditEntry(r, AuditDiscard, dlqID, AuditResultOK, discardDetail(opts))
+	if entry != nil {
+		h.live.Broadcast(liveEvent(LiveDiscarded, *entry, actorFromRequest(r)))
+	}
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "discarded", "dlq_id": dlqID})
 }
 
+// discardDetail is the audit detail of a discard: its reason and note.
+func discardDetail(opts DiscardOpts) string {
+	var parts []string
+	if opts.Reason != "" {
+		parts = append(parts, "reason="+opts.Reason)
+	}
+	if opts.Note != "" {
+		parts = append(parts, "note="+opts.Note)
+	}
+	return strings.Join(parts, "; ")
+}
+
 func (h *Handler) handleRetryAll(w http.ResponseWriter, r *http.Request) {
+	if h.outbox == nil && h.natsUnavailable(w) {
+		return
+	}
 	entries, err := h.store.ListRecoverable(r.Context())
 	if err != nil {
 		slog.Error("list recoverable failed", "error", err)
@@ -126,24 +530,273 @@ func (h *Handler) handleRetryAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	retried := 0
-	failed := 0
-	for _, entry := range entries {
-		if err := h.nc.Publish(entry.OriginalSubject, entry.OriginalPayload); err != nil {
+	var retried, failed, throttled, staleCount, claimed, inProgress atomic.Int64
+	actor, now := actorFromRequest(r), time.Now()
+	by := recoveredBy(r, RecoveredByAPIRetryAll)
+	forEachConcurrent(entries, h.retryAllConcurrency, func(entry Entry) {
+		if entry.claimedByOther(actor, now) {
+			claimed.Add(1)
+			return
+		}
+		locked, err := h.store.BeginRetry(r.Context(), entry.DLQID, actor)
+		switch {
+		case errors.Is(err, ErrEntryClaimed):
+			claimed.Add(1)
+			return
+		case errors.Is(err, ErrRetryInProgress), errors.Is(err, ErrAlreadyRecovered):
+			inProgress.Add(1)
+			return
+		case err != nil:
+			slog.Error("retry-all: failed to begin retry", "dlq_id", entry.DLQID, "error", err)
+			failed.Add(1)
+			return
+		}
+		entry = *locked
+		published := false
+		defer func() {
+			if !published {
+				h.abortRetry(r.Context(), entry.DLQID)
+			}
+		}()
+
+		stale, err := staleTask(r.Context(), h.taskStatus, entry)
+		if err != nil {
+			slog.Error("retry-all: task status check failed", "dlq_id", entry.DLQID, "error", err)
+			failed.Add(1)
+			return
+		}
+		if stale {
+			if err := markStale(r.Context(), h.store, h.nc, entry); err != nil {
+				slog.Error("retry-all: failed to mark stale", "dlq_id", entry.DLQID, "error", err)
+				failed.Add(1)
+				return
+			}
+			h.auditEntry(r, AuditRetry, entry.DLQID, AuditResultStale, "")
+			staleCount.Add(1)
+			return
+		}
+		entry = unredact(h.redactor, entry)
+		if msg := payloadUnavailable(entry); msg != "" {
+			slog.Warn("retry-all: payload unavailable", "dlq_id", entry.DLQID, "reason", msg)
+			failed.Add(1)
+			return
+		}
+		if !h.throttle.Allow(entry.OriginalSubject) {
+			throttled.Add(1)
+			return
+		}
+		payload, err := h.transformer.Apply(entry)
+		if err != nil {
+			slog.Error("retry-all: failed to transform payload", "dlq_id", entry.DLQID, "error", err)
+			failed.Add(1)
+			return
+		}
+		subject, payload, err := h.recovery.resolve(entry, payload)
+		if err != nil {
+			slog.Error("retry-all: recovery action failed", "dlq_id", entry.DLQID, "error", err)
+			failed.Add(1)
+			return
+		}
+		if h.outbox != nil {
+			if _, err := h.outbox.Recover(r.Context(), entry, by, subject, payload); err != nil {
+				slog.Error("retry-all: failed to record recovery", "dlq_id", entry.DLQID, "error", err)
+				h.auditEntry(r, AuditRetry, entry.DLQID, AuditResultFailed, err.Error())
+				failed.Add(1)
+				return
+			}
+			published = true
+			publishRecovered(r.Context(), h.nc, entry, by)
+			h.auditEntry(r, AuditRetry, entry.DLQID, AuditResultOK, "")
+			h.live.Broadcast(liveEvent(LiveRetried, entry, actor))
+			retried.Add(1)
+			return
+		}
+		if err := republish(r.Context(), h.nc, h.confirm, h.store, entry, by, subject, payload); err != nil {
 			slog.Error("retry-all: failed to republish", "dlq_id", entry.DLQID, "error", err)
-			failed++
-			continue
+			h.auditEntry(r, AuditRetry, entry.DLQID, AuditResultFailed, err.Error())
+			failed.Add(1)
+			return
 		}
-		if err := h.store.MarkRecovered(r.Context(), entry.DLQID, "api-retry-all"); err != nil {
+		published = true
+		if err := h.store.MarkRecoveredIfVersion(r.Context(), entry.DLQID, by, entry.Version); err != nil {
 			slog.Error("retry-all: failed to mark recovered", "dlq_id", entry.DLQID, "error", err)
+		} else {
+			publishRecovered(r.Context(), h.nc, entry, by)
 		}
-		retried++
+		h.auditEntry(r, AuditRetry, entry.DLQID, AuditResultOK, "")
+		h.live.Broadcast(liveEvent(LiveRetried, entry, actor))
+		retried.Add(1)
+	})
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"retried":     retried.Load(),
+		"failed":      failed.Load(),
+		"throttled":   throttled.Load(),
+		"stale":       staleCount.Load(),
+		"claimed":     claimed.Load(),
+		"in_progress": inProgress.Load(),
+		"total":       len(entries),
+	})
+}
+
+// abortRetry releases an entry taken by BeginRetry that was not published.
+// It runs even if the request was cancelled.
+func (h *Handler) abortRetry(ctx context.Context, dlqID string) {
+	if err := h.store.AbortRetry(context.WithoutCancel(ctx), dlqID); err != nil {
+		slog.Error("failed to release dlq retry", "dlq_id", dlqID, "error", err)
+	}
+}
+
+// payloadUnavailable explains why e's stored payload cannot be republished,
+// or returns "" if it can.
+func payloadUnavailable(e Entry) string {
+	switch {
+	case e.PayloadOmitted:
+		return "payload was not retained (sampled out during overload)"
+	case e.PayloadTruncated:
+		return "payload was truncated (exceeded the size limit)"
+	case e.PayloadErased:
+		return "payload was erased by a compliance request"
+	case e.PayloadRedacted:
+		return "payload was redacted and the original cannot be unsealed"
+	}
+	return ""
+}
+
+// forEachConcurrent calls fn for every entry using at most n goroutines and
+// returns once all calls have completed.
+func forEachConcurrent(entries []Entry, n int, fn func(Entry)) {
+	if n <= 1 || len(entries) <= 1 {
+		for _, e := range entries {
+			fn(e)
+		}
+		return
+	}
+	if n > len(entries) {
+		n = len(entries)
+	}
+
+	work := make(chan Entry)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range work {
+				fn(e)
+			}
+		}()
+	}
+	for _, e := range entries {
+		work <- e
+	}
+	close(work)
+	wg.Wait()
+}
+
+// replayPrefix validates the ?prefix= parameter of the replay endpoints.
+func (h *Handler) replayPrefix(r *http.Request) (string, error) {
+	prefix := r.URL.Query().Get("prefix")
+	if prefix == "" {
+		return "", errors.New("prefix is required")
+	}
+	if !strings.HasSuffix(prefix, ".") || strings.HasPrefix(prefix, ".") ||
+		strings.Contains(prefix, "..") || strings.ContainsAny(prefix, "*> \t") {
+		return "", fmt.Errorf("invalid subject prefix %q", prefix)
+	}
+	if len(h.replayPrefixes) > 0 {
+		for _, allowed := range h.replayPrefixes {
+			if prefix == allowed {
+				return prefix, nil
+			}
+		}
+		return "", fmt.Errorf("subject prefix %q is not allowed", prefix)
+	}
+	return prefix, nil
+}
+
+// handleReplay publishes an entry's original payload under a prefixed subject
+// (e.g. staging.swarm.task.request) without marking it recovered.
+func (h *Handler) handleReplay(w http.ResponseWriter, r *http.Request) {
+	dlqID := chi.URLParam(r, "dlqID")
+	if h.natsUnavailable(w) {
+		return
+	}
+
+	prefix, err := h.replayPrefix(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
 	}
 
+	entry, err := h.store.Get(r.Context(), dlqID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "dlq entry not found"})
+		return
+	}
+	*entry = unredact(h.redactor, *entry)
+	if msg := payloadUnavailable(*entry); msg != "" {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": msg})
+		return
+	}
+
+	subject := prefix + entry.OriginalSubject
+	if err := publishTraced(r.Context(), h.nc, subject, entry.OriginalPayload, republishHeader(*entry, ""), AttrDLQID.String(dlqID)); err != nil {
+		slog.Error("failed to replay dlq entry", "dlq_id", dlqID, "subject", subject, "error", err)
+		h.auditEntry(r, AuditReplay, dlqID, AuditResultFailed, err.Error())
+		if errors.Is(err, ErrCircuitOpen) {
+			writeCircuitOpen(w, circuitRetryAfter(h.nc))
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to replay"})
+		return
+	}
+
+	h.auditEntry(r, AuditReplay, dlqID, AuditResultOK, subject)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "replayed", "dlq_id": dlqID, "subject": subject})
+}
+
+// handleReplayBulk replays every entry matching the list filters under a
+// prefixed subject. Entries are not marked recovered.
+func (h *Handler) handleReplayBulk(w http.ResponseWriter, r *http.Request) {
+	if h.natsUnavailable(w) {
+		return
+	}
+	prefix, err := h.replayPrefix(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	entries, err := h.store.List(r.Context(), listOptsFromQuery(r.URL.Query()))
+	if err != nil {
+		slog.Error("replay: list failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+
+	var replayed, failed atomic.Int64
+	forEachConcurrent(entries, h.retryAllConcurrency, func(entry Entry) {
+		entry = unredact(h.redactor, entry)
+		if payloadUnavailable(entry) != "" {
+			failed.Add(1)
+			return
+		}
+		if err := publishTraced(r.Context(), h.nc, prefix+entry.OriginalSubject, entry.OriginalPayload, republishHeader(entry, ""), AttrDLQID.String(entry.DLQID)); err != nil {
+			slog.Error("replay: failed to publish", "dlq_id", entry.DLQID, "error", err)
+			h.auditEntry(r, AuditReplay, entry.DLQID, AuditResultFailed, err.Error())
+			failed.Add(1)
+			return
+		}
+		h.auditEntry(r, AuditReplay, entry.DLQID, AuditResultOK, prefix+entry.OriginalSubject)
+		replayed.Add(1)
+	})
+
 	writeJSON(w, http.StatusOK, map[string]any{
-		"retried": retried,
-		"failed":  failed,
-		"total":   len(entries),
+		"replayed": replayed.Load(),
+		"failed":   failed.Load(),
+		"total":    len(entries),
+		"prefix":   prefix,
 	})
 }
 
@@ -162,3 +815,140 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
+
+func (h *Handler) handleAgentStats(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = n
+		}
+	}
+
+	agents, err := h.store.AgentStats(r.Context(), limit)
+	if err != nil {
+		slog.Error("dlq agent stats failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, agents)
+}
+
+func (h *Handler) handleScannerStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.scanner.Status())
+}
+
+func (h *Handler) handleGetDenyList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.denyList.Rules())
+}
+
+func (h *Handler) handlePutDenyList(w http.ResponseWriter, r *http.Request) {
+	var rules DenyRules
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid deny-list body"})
+		return
+	}
+	h.denyList.Set(rules)
+	slog.Info("dlq deny-list updated", "subjects", len(rules.Subjects), "reasons", len(rules.Reasons))
+	writeJSON(w, http.StatusOK, h.denyList.Rules())
+}
+
+func (h *Handler) handleFailureStats(w http.ResponseWriter, r *http.Request) {
+	counts, err := h.store.FailureReasonStats(r.Context())
+	if err != nil {
+		slog.Error("dlq failure reason stats failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, counts)
+}
+
+// archiveRequest is the body of POST /archive. OlderThan is a Go duration
+// (e.g. "720h") selecting entries that failed longer ago than that.
+type archiveRequest struct {
+	Recovered bool   `json:"recovered"`
+	OlderThan string `json:"older_than"`
+	Reason    string `json:"reason"`
+	Source    string `json:"source"`
+}
+
+func (h *Handler) handleArchive(w http.ResponseWriter, r *http.Request) {
+	var req archiveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid archive body"})
+		return
+	}
+	f := ArchiveFilter{Recovered: req.Recovered, Reason: req.Reason, Source: req.Source}
+	if req.OlderThan != "" {
+		d, err := time.ParseDuration(req.OlderThan)
+		if err != nil || d <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid older_than duration"})
+			return
+		}
+		f.FailedBefore = time.Now().UTC().Add(-d)
+	}
+
+	moved, err := h.store.Archive(r.Context(), f)
+	if errors.Is(err, ErrEmptyArchiveFilter) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		slog.Error("dlq archive failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	slog.Info("dlq entries archived", "count", moved)
+	writeJSON(w, http.StatusOK, map[string]int{"archived": moved})
+}
+
+// purgeRequest is the body of POST /purge. OlderThan is a Go duration
+// (e.g. "720h"); handled entries older than that are deleted.
+type purgeRequest struct {
+	OlderThan string `json:"older_than"`
+}
+
+// handlePurge permanently deletes recovered, discarded and expired entries
+// handled more than older_than ago — the janitor's sweep on demand.
+func (h *Handler) handlePurge(w http.ResponseWriter, r *http.Request) {
+	var req purgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid purge body"})
+		return
+	}
+	d, err := time.ParseDuration(req.OlderThan)
+	if err != nil || d <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid older_than duration"})
+		return
+	}
+
+	deleted, err := h.store.DeleteOlderThan(r.Context(), time.Now().UTC().Add(-d))
+	if err != nil {
+		slog.Error("dlq purge failed", "deleted", deleted, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	slog.Info("dlq entries purged", "count", deleted, "older_than", d)
+	writeJSON(w, http.StatusOK, map[string]int{"deleted": deleted})
+}
+
+func (h *Handler) handleListArchived(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.store.ListArchived(r.Context(), listOptsFromQuery(r.URL.Query()))
+	if err != nil {
+		slog.Error("list archived dlq failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	h.readPayloads(r, AuditPayloadExport, entries)
+	writeEntries(w, http.StatusOK, negotiateFormat(r), entries)
+}
+
+func (h *Handler) handleGetArchived(w http.ResponseWriter, r *http.Request) {
+	entry, err := h.store.GetArchived(r.Context(), chi.URLParam(r, "dlqID"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "archived dlq entry not found"})
+		return
+	}
+	read := []Entry{*entry}
+	h.readPayloads(r, AuditPayloadRead, read)
+	writeJSON(w, http.StatusOK, read[0])
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -88,6 +90,26 @@ func TestHandler_List_FilterByRecovered(t *testing.T) {
 	}
 }
 
+func TestHandler_List_FilterByStatus(t *testing.T) {
+	store := newMockStore()
+	store.seed(
+		Entry{DLQID: "e1", Status: StatusPending},
+		Entry{DLQID: "e2", Recovered: true, Status: StatusRecovered},
+		Entry{DLQID: "e3", Recovered: true, Status: StatusDiscarded},
+	)
+	r := newTestRouter(store, newMockNATS())
+
+	req := httptest.NewRequest("GET", "/dlq/?status=discarded", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var entries []Entry
+	_ = json.NewDecoder(w.Body).Decode(&entries)
+	if len(entries) != 1 || entries[0].DLQID != "e3" {
+		t.Errorf("expected only e3, got %+v", entries)
+	}
+}
+
 func TestHandler_List_FilterByReason(t *testing.T) {
 	store := newMockStore()
 	store.seed(
@@ -126,6 +148,51 @@ func TestHandler_List_FilterBySource(t *testing.T) {
 	}
 }
 
+func TestHandler_List_FilterByAgent(t *testing.T) {
+	store := newMockStore()
+	store.seed(
+		Entry{DLQID: "e1", RetryHistory: []RetryAttempt{{Attempt: 1, Agent: "scout"}}},
+		Entry{DLQID: "e2", OriginalPayload: json.RawMessage(`{"agent_id":"scout"}`)},
+		Entry{DLQID: "e3", OriginalPayload: json.RawMessage(`{"agent":"forge"}`), RetryHistory: []RetryAttempt{{Attempt: 1, Agent: "forge"}}},
+	)
+	r := newTestRouter(store, newMockNATS())
+
+	req := httptest.NewRequest("GET", "/dlq/?agent=scout", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var entries []Entry
+	_ = json.NewDecoder(w.Body).Decode(&entries)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	for _, e := range entries {
+		if e.DLQID == "e3" {
+			t.Error("entry for another agent should be filtered out")
+		}
+	}
+}
+
+func TestHandler_List_FilterByProducer(t *testing.T) {
+	store := newMockStore()
+	store.seed(
+		Entry{DLQID: "e1", ProducerService: "dispatch", ProducerVersion: "v1.4.2", ProducerHost: "dispatch-7f9c"},
+		Entry{DLQID: "e2", ProducerService: "dispatch", ProducerVersion: "v1.4.1", ProducerHost: "dispatch-2b1a"},
+		Entry{DLQID: "e3", ProducerService: "warren", ProducerVersion: "v1.4.2"},
+	)
+	r := newTestRouter(store, newMockNATS())
+
+	req := httptest.NewRequest("GET", "/dlq/?producer_service=dispatch&producer_version=v1.4.2", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var entries []Entry
+	_ = json.NewDecoder(w.Body).Decode(&entries)
+	if len(entries) != 1 || entries[0].DLQID != "e1" || entries[0].ProducerHost != "dispatch-7f9c" {
+		t.Errorf("expected only e1, got %+v", entries)
+	}
+}
+
 func TestHandler_List_StoreError(t *testing.T) {
 	store := newMockStore()
 	store.listErr = fmt.Errorf("db down")
@@ -299,8 +366,14 @@ func TestHandler_Discard_Success(t *testing.T) {
 	if !entry.Recovered {
 		t.Error("expected entry to be marked recovered after discard")
 	}
-	if entry.RecoveredBy != "manual-discard" {
-		t.Errorf("expected recovered_by manual-discard, got %s", entry.RecoveredBy)
+	if entry.DiscardedBy != RecoveredByDiscard || entry.DiscardedAt == nil {
+		t.Errorf("expected discarded_by manual-discard with discarded_at, got %q %v", entry.DiscardedBy, entry.DiscardedAt)
+	}
+	if entry.RecoveredBy != "" || entry.RecoveredAt != nil {
+		t.Errorf("discard must not look like a recovery, got recovered_by %q", entry.RecoveredBy)
+	}
+	if entry.Status != StatusDiscarded {
+		t.Errorf("expected status discarded, got %s", entry.Status)
 	}
 }
 
@@ -407,10 +480,10 @@ func TestHandler_RetryAll_Empty(t *testing.T) {
 func TestHandler_Stats(t *testing.T) {
 	store := newMockStore()
 	store.seed(
-		Entry{DLQID: "s1", Reason: ReasonNoCapableAgent, Source: SourceDispatch},
-		Entry{DLQID: "s2", Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true},
-		Entry{DLQID: "s3", Reason: ReasonBootFailure, Source: SourceWarren},
-		Entry{DLQID: "s4", Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recovered: true},
+		Entry{DLQID: "s1", OriginalSubject: "swarm.task.request", Reason: ReasonNoCapableAgent, Source: SourceDispatch},
+		Entry{DLQID: "s2", OriginalSubject: "swarm.task.request", Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true},
+		Entry{DLQID: "s3", OriginalSubject: "swarm.agent.boot", Reason: ReasonBootFailure, Source: SourceWarren},
+		Entry{DLQID: "s4", OriginalSubject: "swarm.task.request", Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recovered: true},
 	)
 	r := newTestRouter(store, newMockNATS())
 
@@ -440,6 +513,42 @@ func TestHandler_Stats(t *testing.T) {
 	if stats.BySource[SourceWarren] != 1 {
 		t.Errorf("expected 1 warren, got %d", stats.BySource[SourceWarren])
 	}
+	if stats.ByStatus[StatusPending] != 3 || stats.ByStatus[StatusRecovered] != 1 {
+		t.Errorf("expected 3 pending and 1 recovered, got %v", stats.ByStatus)
+	}
+	if stats.BySubject["swarm.task.request"] != 2 || stats.BySubject["swarm.agent.boot"] != 1 {
+		t.Errorf("expected unrecovered counts by subject, got %v", stats.BySubject)
+	}
+}
+
+func TestHandler_Stats_Recovery(t *testing.T) {
+	store := newMockStore()
+	now := time.Now().UTC()
+	at := func(d time.Duration) *time.Time { t := now.Add(-d); return &t }
+	store.seed(
+		Entry{DLQID: "o1", FailedAt: now.Add(-3 * time.Hour)},
+		Entry{DLQID: "o2", FailedAt: now.Add(-time.Hour)},
+		Entry{DLQID: "r1", FailedAt: now.Add(-2 * time.Hour), Recovered: true, RecoveredAt: at(time.Hour)},
+		Entry{DLQID: "r2", FailedAt: now.Add(-50 * time.Hour), Recovered: true, RecoveredAt: at(47 * time.Hour)},
+		Entry{DLQID: "r3", FailedAt: now.Add(-60 * 24 * time.Hour), Recovered: true, RecoveredAt: at(59 * 24 * time.Hour)},
+	)
+	r := newTestRouter(store, newMockNATS())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/stats", nil))
+	var stats Stats
+	_ = json.NewDecoder(w.Body).Decode(&stats)
+
+	if age := time.Duration(stats.OldestUnrecoveredAge * float64(time.Second)); age < 3*time.Hour || age > 3*time.Hour+time.Minute {
+		t.Errorf("expected the oldest unrecovered entry to be 3h old, got %s", age)
+	}
+	if stats.RecoveredLast24h != 1 {
+		t.Errorf("expected 1 recovery in the last 24h, got %d", stats.RecoveredLast24h)
+	}
+	ttr := stats.TimeToRecovery
+	if ttr.Count != 2 || ttr.Avg != 7200 || ttr.P50 != 7200 || ttr.P99 <= ttr.P50 {
+		t.Errorf("expected 1h and 3h recoveries within the window, got %+v", ttr)
+	}
 }
 
 func TestHandler_Stats_Error(t *testing.T) {
@@ -456,6 +565,74 @@ func TestHandler_Stats_Error(t *testing.T) {
 	}
 }
 
+func TestHandler_AgentStats(t *testing.T) {
+	t0 := time.Now().Add(-time.Hour)
+	store := newMockStore()
+	store.seed(
+		Entry{DLQID: "a1", RetryHistory: []RetryAttempt{
+			{Attempt: 1, Agent: "scout", AttemptedAt: t0},
+			{Attempt: 2, Agent: "scout", AttemptedAt: t0.Add(time.Minute)},
+			{Attempt: 3, Agent: "forge", AttemptedAt: t0.Add(2 * time.Minute)},
+		}},
+		Entry{DLQID: "a2", RetryHistory: []RetryAttempt{
+			{Attempt: 1, Agent: "scout", AttemptedAt: t0.Add(3 * time.Minute)},
+			{Attempt: 2, FailureReason: "no agent"},
+		}},
+		Entry{DLQID: "a3", Recovered: true, RetryHistory: []RetryAttempt{
+			{Attempt: 1, Agent: "forge", AttemptedAt: t0},
+			{Attempt: 2, Agent: "forge", AttemptedAt: t0},
+		}},
+	)
+	r := newTestRouter(store, newMockNATS())
+
+	req := httptest.NewRequest("GET", "/dlq/stats/agents", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var agents []AgentFailureStats
+	_ = json.NewDecoder(w.Body).Decode(&agents)
+
+	if len(agents) != 2 {
+		t.Fatalf("expected 2 agents, got %d", len(agents))
+	}
+	if agents[0].Agent != "scout" || agents[0].Failures != 3 || agents[0].Entries != 2 {
+		t.Errorf("unexpected top agent: %+v", agents[0])
+	}
+	if agents[1].Agent != "forge" || agents[1].Failures != 1 {
+		t.Errorf("recovered entries should not count: %+v", agents[1])
+	}
+	if agents[0].LastFailureAt == nil || !agents[0].LastFailureAt.Equal(t0.Add(3*time.Minute)) {
+		t.Errorf("unexpected last failure: %v", agents[0].LastFailureAt)
+	}
+
+	req = httptest.NewRequest("GET", "/dlq/stats/agents?limit=1", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	agents = nil
+	_ = json.NewDecoder(w.Body).Decode(&agents)
+	if len(agents) != 1 {
+		t.Errorf("expected limit to apply, got %d agents", len(agents))
+	}
+}
+
+func TestHandler_AgentStats_Error(t *testing.T) {
+	store := newMockStore()
+	store.statsErr = fmt.Errorf("db down")
+	r := newTestRouter(store, newMockNATS())
+
+	req := httptest.NewRequest("GET", "/dlq/stats/agents", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+}
+
 func TestWriteJSON(t *testing.T) {
 	w := httptest.NewRecorder()
 	writeJSON(w, http.StatusOK, map[string]string{"key": "value"})
@@ -473,3 +650,160 @@ func TestWriteJSON(t *testing.T) {
 		t.Errorf("expected value, got %s", body["key"])
 	}
 }
+
+func TestHandler_RetryAll_Concurrent(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	for i := 0; i < 50; i++ {
+		store.seed(Entry{
+			DLQID:           fmt.Sprintf("par-%d", i),
+			OriginalSubject: "swarm.task.request",
+			OriginalPayload: json.RawMessage(`{}`),
+			Reason:          ReasonNoCapableAgent,
+			Source:          SourceDispatch,
+			Recoverable:     true,
+		})
+	}
+
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, nc, WithRetryAllConcurrency(4)).Routes())
+
+	req := httptest.NewRequest("POST", "/dlq/retry-all", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body map[string]any
+	_ = json.NewDecoder(w.Body).Decode(&body)
+	if retried := int(body["retried"].(float64)); retried != 50 {
+		t.Errorf("expected 50 retried, got %d", retried)
+	}
+	if len(nc.published()) != 50 {
+		t.Errorf("expected 50 published messages, got %d", len(nc.published()))
+	}
+	for i := 0; i < 50; i++ {
+		e, _ := store.Get(context.Background(), fmt.Sprintf("par-%d", i))
+		if !e.Recovered {
+			t.Errorf("par-%d should be recovered", i)
+		}
+	}
+}
+
+func TestForEachConcurrent_BoundsWorkers(t *testing.T) {
+	entries := make([]Entry, 20)
+	var mu sync.Mutex
+	active, peak, calls := 0, 0, 0
+
+	forEachConcurrent(entries, 3, func(Entry) {
+		mu.Lock()
+		active++
+		calls++
+		if active > peak {
+			peak = active
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+	})
+
+	if calls != 20 {
+		t.Errorf("expected 20 calls, got %d", calls)
+	}
+	if peak > 3 {
+		t.Errorf("expected at most 3 concurrent calls, got %d", peak)
+	}
+}
+
+func TestHandler_ReadOnly_RejectsMutations(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(Entry{DLQID: "ro-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true})
+
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, nc, WithReadOnly()).Routes())
+
+	for _, path := range []string{"/dlq/ro-1/retry", "/dlq/ro-1/discard", "/dlq/retry-all"} {
+		req := httptest.NewRequest("POST", path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s: expected 405, got %d", path, w.Code)
+		}
+	}
+
+	if len(nc.published()) != 0 {
+		t.Error("expected nothing republished in read-only mode")
+	}
+	if e, _ := store.Get(context.Background(), "ro-1"); e.Recovered {
+		t.Error("entry should not be modified in read-only mode")
+	}
+
+	// Reads still work.
+	req := httptest.NewRequest("GET", "/dlq/ro-1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 for get, got %d", w.Code)
+	}
+}
+
+func TestHandler_Discard_WithReasonAndNote(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "discard-2", Reason: ReasonPolicyDenied, Source: SourceDispatch})
+	r := newTestRouter(store, newMockNATS())
+
+	body := strings.NewReader(`{"reason":"obsolete","note":"task was redone manually by ops"}`)
+	req := httptest.NewRequest("POST", "/dlq/discard-2/discard", body)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	entry, _ := store.Get(context.Background(), "discard-2")
+	if entry.DiscardReason != "obsolete" {
+		t.Errorf("expected discard_reason obsolete, got %q", entry.DiscardReason)
+	}
+	if entry.DiscardNote != "task was redone manually by ops" {
+		t.Errorf("unexpected discard_note %q", entry.DiscardNote)
+	}
+}
+
+func TestHandler_Discard_InvalidBody(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "discard-3"})
+	r := newTestRouter(store, newMockNATS())
+
+	req := httptest.NewRequest("POST", "/dlq/discard-3/discard", strings.NewReader(`{not json`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestHandler_Discard_RequireReason(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "discard-4"})
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, newMockNATS(), WithRequireDiscardReason()).Routes())
+
+	req := httptest.NewRequest("POST", "/dlq/discard-4/discard", strings.NewReader(`{"note":"no reason"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without reason, got %d", w.Code)
+	}
+	if e, _ := store.Get(context.Background(), "discard-4"); e.Recovered {
+		t.Error("entry should not be discarded without a reason")
+	}
+
+	req = httptest.NewRequest("POST", "/dlq/discard-4/discard", strings.NewReader(`{"reason":"duplicate"}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 with reason, got %d", w.Code)
+	}
+}
@@ -0,0 +1,50 @@
+package dlq
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Headers added to retried and replayed messages so downstream consumers can
+// tell them from first deliveries.
+const (
+	// HeaderDLQID carries the dlq_id of the entry being republished.
+	HeaderDLQID = "X-DLQ-ID"
+	// HeaderDLQRetry marks a retry and names what triggered it, in the form
+	// recorded as recovered_by (e.g. "auto-scanner", "api-retry:alice").
+	// Replays carry HeaderDLQID only.
+	HeaderDLQRetry = "X-DLQ-Retry"
+)
+
+// republishHeader returns the headers for republishing e: its original
+// headers plus HeaderDLQID and, when retryBy is set, HeaderDLQRetry.
+// Trace context headers are dropped because the republish carries its own,
+// and Nats-Expected-* headers because the stream has moved on since the
+// original publish and JetStream would reject the message.
+func republishHeader(e Entry, retryBy string) nats.Header {
+	traceFields := otel.GetTextMapPropagator().Fields()
+	hdr := nats.Header{}
+	for k, vs := range e.OriginalHeaders {
+		if strings.HasPrefix(k, "Nats-Expected-") || slices.Contains(traceFields, strings.ToLower(k)) {
+			continue
+		}
+		hdr[k] = append([]string(nil), vs...)
+	}
+	hdr.Set(HeaderDLQID, e.DLQID)
+	if retryBy != "" {
+		hdr.Set(HeaderDLQRetry, retryBy)
+	}
+	return hdr
+}
+
+// originalSpanContext returns the trace context the failed message was
+// published with, if its headers carried one.
+func originalSpanContext(e Entry) trace.SpanContext {
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), natsHeaderCarrier(e.OriginalHeaders))
+	return trace.SpanContextFromContext(ctx)
+}
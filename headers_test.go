@@ -0,0 +1,61 @@
+package dlq
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+)
+
+func TestRepublish_CarriesOriginalHeaders(t *testing.T) {
+	rec := recordSpans(t)
+
+	// The failed message was published inside a trace of its own.
+	orig, span := otel.Tracer("test").Start(context.Background(), "dispatch")
+	span.End()
+	hdr := nats.Header{}
+	otel.GetTextMapPropagator().Inject(orig, natsHeaderCarrier(hdr))
+	hdr.Set("Nats-Msg-Id", "task-42")
+	hdr.Set("Nats-Expected-Last-Sequence", "17")
+	hdr.Set("X-Tenant", "acme")
+	e := Entry{DLQID: "hd-1", OriginalSubject: "swarm.task.request", OriginalHeaders: hdr}
+
+	nc := &msgNATS{}
+	if err := republish(context.Background(), nc, nil, newMockStore(), e, RecoveredByScanner, e.OriginalSubject, []byte(`{}`)); err != nil {
+		t.Fatalf("republish: %v", err)
+	}
+	if len(nc.msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(nc.msgs))
+	}
+	got := nc.msgs[0].Header
+	if got.Get("Nats-Msg-Id") != "task-42" || got.Get("X-Tenant") != "acme" {
+		t.Errorf("expected the original headers, got %v", got)
+	}
+	if got.Get("Nats-Expected-Last-Sequence") != "" {
+		t.Error("JetStream expectations must not be republished")
+	}
+	if got.Get(HeaderDLQID) != "hd-1" || got.Get(HeaderDLQRetry) != RecoveredByScanner {
+		t.Errorf("expected DLQ headers, got %v", got)
+	}
+
+	rs := spanNamed(rec, "dlq.republish")
+	if rs == nil {
+		t.Fatal("expected a dlq.republish span")
+	}
+	if !strings.Contains(got.Get("traceparent"), rs.SpanContext().TraceID().String()) {
+		t.Error("the republished traceparent should be the republish's own")
+	}
+	links := rs.Links()
+	if len(links) != 1 || links[0].SpanContext.TraceID() != span.SpanContext().TraceID() {
+		t.Errorf("expected a link to the original trace, got %v", links)
+	}
+}
+
+func TestReplay_MarksDLQIDWithoutRetry(t *testing.T) {
+	h := republishHeader(Entry{DLQID: "hd-2", OriginalHeaders: map[string][]string{"X-Tenant": {"acme"}}}, "")
+	if h.Get(HeaderDLQID) != "hd-2" || h.Get(HeaderDLQRetry) != "" || h.Get("X-Tenant") != "acme" {
+		t.Errorf("unexpected replay headers %v", h)
+	}
+}
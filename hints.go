@@ -0,0 +1,70 @@
+package dlq
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// DefaultHintsField is the payload field DispatchHints writes to when no
+// field is given.
+const DefaultHintsField = "dispatch_hints"
+
+// HintBuilder derives dispatch hints from an entry, typically from its
+// retry_history. Returning no hints leaves the payload unchanged.
+type HintBuilder func(e Entry) map[string]any
+
+// ExcludeFailedAgents returns {"exclude_agents": [...]} listing every agent
+// that already failed this entry, so Dispatch does not reassign the retried
+// task to the same broken agent.
+func ExcludeFailedAgents(e Entry) map[string]any {
+	seen := map[string]bool{}
+	var agents []string
+	for _, a := range e.RetryHistory {
+		if a.Agent != "" && !seen[a.Agent] {
+			seen[a.Agent] = true
+			agents = append(agents, a.Agent)
+		}
+	}
+	if len(agents) == 0 {
+		return nil
+	}
+	sort.Strings(agents)
+	return map[string]any{"exclude_agents": agents}
+}
+
+// DispatchHints returns a transform that merges the hints from each builder
+// into an object under field (DefaultHintsField if empty) at the top level of
+// the payload. Use it in a TransformRule scoped to task subjects.
+func DispatchHints(field string, builders ...HintBuilder) PayloadTransform {
+	if field == "" {
+		field = DefaultHintsField
+	}
+	return PayloadTransformFunc(func(e Entry, payload json.RawMessage) (json.RawMessage, error) {
+		hints := map[string]any{}
+		for _, b := range builders {
+			for k, v := range b(e) {
+				hints[k] = v
+			}
+		}
+		if len(hints) == 0 {
+			return payload, nil
+		}
+
+		var doc map[string]any
+		if err := decodePayload(payload, &doc); err != nil {
+			return nil, fmt.Errorf("decode payload: %w", err)
+		}
+		if doc == nil {
+			doc = map[string]any{}
+		}
+		if existing, ok := doc[field].(map[string]any); ok {
+			for k, v := range hints {
+				existing[k] = v
+			}
+		} else {
+			doc[field] = hints
+		}
+		return json.Marshal(doc)
+	})
+}
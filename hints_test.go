@@ -0,0 +1,54 @@
+package dlq
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestDispatchHints_ExcludesFailedAgents(t *testing.T) {
+	tr := NewTransformer(TransformRule{Subject: "swarm.task.request", Transform: DispatchHints("", ExcludeFailedAgents)})
+	e := Entry{
+		DLQID:           "h-1",
+		OriginalSubject: "swarm.task.request",
+		OriginalPayload: json.RawMessage(`{"task_id":"t1","seq":9007199254740993,"dispatch_hints":{"priority":"high"}}`),
+		RetryHistory: []RetryAttempt{
+			{Attempt: 1, Agent: "scout"},
+			{Attempt: 2, Agent: "kai"},
+			{Attempt: 3, Agent: "scout"},
+			{Attempt: 4},
+		},
+	}
+
+	out, err := tr.Apply(e)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	var doc struct {
+		TaskID string `json:"task_id"`
+		Hints  struct {
+			Priority      string   `json:"priority"`
+			ExcludeAgents []string `json:"exclude_agents"`
+		} `json:"dispatch_hints"`
+	}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if doc.TaskID != "t1" || doc.Hints.Priority != "high" || !bytes.Contains(out, []byte(`"seq":9007199254740993`)) {
+		t.Errorf("existing fields should be preserved: %s", out)
+	}
+	if len(doc.Hints.ExcludeAgents) != 2 || doc.Hints.ExcludeAgents[0] != "kai" || doc.Hints.ExcludeAgents[1] != "scout" {
+		t.Errorf("unexpected exclude list: %v", doc.Hints.ExcludeAgents)
+	}
+}
+
+func TestDispatchHints_NoHistoryLeavesPayload(t *testing.T) {
+	payload := json.RawMessage(`{"task_id":"t2"}`)
+	out, err := DispatchHints("hints", ExcludeFailedAgents).Transform(Entry{RetryHistory: []RetryAttempt{}}, payload)
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+	if string(out) != string(payload) {
+		t.Errorf("expected unchanged payload, got %s", out)
+	}
+}
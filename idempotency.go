@@ -0,0 +1,207 @@
+package dlq
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5"
+)
+
+// IdempotencyKeyHeader carries the client's idempotency key on retry,
+// discard and retry-all.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Idempotency key lifetimes: a completed key is replayed for
+// IdempotencyKeyTTL, and a key whose request never completed (its process
+// died) is freed after idempotencyLease.
+const (
+	IdempotencyKeyTTL = 24 * time.Hour
+	idempotencyLease  = RetryLease
+)
+
+var (
+	// ErrIdempotencyInProgress is returned by ReserveIdempotencyKey while
+	// another request with the key is still running.
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is in progress")
+	// ErrIdempotencyKeyReused is returned by ReserveIdempotencyKey when the
+	// key was first used for a different request.
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used for a different request")
+)
+
+// IdempotentResponse is the response stored for an idempotency key.
+type IdempotentResponse struct {
+	Status int
+	Body   []byte
+}
+
+// IdempotencyStore remembers the responses of mutating requests by
+// idempotency key. *Store implements it on the swarm_dlq_idempotency table.
+type IdempotencyStore interface {
+	// ReserveIdempotencyKey claims key for request (a digest of the
+	// method, URL and body). It returns nil if the caller should run the
+	// request, the stored response if a request with the key completed, or
+	// ErrIdempotencyInProgress / ErrIdempotencyKeyReused.
+	ReserveIdempotencyKey(ctx context.Context, key, request string) (*IdempotentResponse, error)
+	// CompleteIdempotencyKey stores the response of the request holding key.
+	CompleteIdempotencyKey(ctx context.Context, key string, resp IdempotentResponse) error
+	// ReleaseIdempotencyKey frees key so the request can be tried again.
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+}
+
+// WithIdempotency makes retry, discard and retry-all honour the
+// Idempotency-Key header: a repeated request with the same key gets the
+// original response (with Idempotent-Replayed: true) instead of running
+// again, a request racing one with the same key gets 409, and reusing a key
+// for a different request gets 422. Server errors are not stored, so a
+// request that failed with a 5xx can be retried under the same key.
+func WithIdempotency(s IdempotencyStore) HandlerOption {
+	return func(h *Handler) { h.idempotency = s }
+}
+
+// idempotent wraps next with the Idempotency-Key handling of
+// WithIdempotency. Requests without the header pass straight through.
+func (h *Handler) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	if h.idempotency == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if t := TenantFromContext(r.Context()); t != "" {
+			key = t + "/" + key
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "failed to read request body"})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(append([]byte(r.Method+" "+r.URL.RequestURI()+"\n"), body...))
+
+		stored, err := h.idempotency.ReserveIdempotencyKey(r.Context(), key, hex.EncodeToString(sum[:]))
+		switch {
+		case errors.Is(err, ErrIdempotencyInProgress):
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		case errors.Is(err, ErrIdempotencyKeyReused):
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+			return
+		case err != nil:
+			slog.Error("dlq idempotency key lookup failed", "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+			return
+		case stored != nil:
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			_, _ = w.Write(stored.Body)
+			return
+		}
+
+		var buf bytes.Buffer
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(&buf)
+		completed := false
+		defer func() {
+			// Runs on panics too, so the key is not held for the lease.
+			ctx := context.WithoutCancel(r.Context())
+			if completed {
+				if err := h.idempotency.CompleteIdempotencyKey(ctx, key, IdempotentResponse{Status: ww.Status(), Body: buf.Bytes()}); err != nil {
+					slog.Error("dlq idempotency: failed to store response", "error", err)
+				}
+				return
+			}
+			if err := h.idempotency.ReleaseIdempotencyKey(ctx, key); err != nil {
+				slog.Error("dlq idempotency: failed to release key", "error", err)
+			}
+		}()
+		next(ww, r)
+		completed = ww.Status() < http.StatusInternalServerError
+	}
+}
+
+// ReserveIdempotencyKey implements IdempotencyStore.
+func (s *Store) ReserveIdempotencyKey(ctx context.Context, key, request string) (_ *IdempotentResponse, err error) {
+	defer s.observe("reserve_idempotency_key", time.Now(), &err)
+	// Take the key if it is new, expired, or held by a request that never
+	// completed within the lease.
+	var reserved string
+	err = s.pool.QueryRow(ctx, `
+		INSERT INTO swarm_dlq_idempotency (key, request) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE
+		SET request = excluded.request, status = NULL, body = NULL, created_at = now()
+		WHERE swarm_dlq_idempotency.created_at <= now() - $3 * interval '1 microsecond'
+		   OR (swarm_dlq_idempotency.status IS NULL AND swarm_dlq_idempotency.created_at <= now() - $4 * interval '1 microsecond')
+		RETURNING key
+	`, key, request, IdempotencyKeyTTL.Microseconds(), idempotencyLease.Microseconds()).Scan(&reserved)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("reserve idempotency key: %w", err)
+	}
+
+	var stored string
+	var status *int
+	var body []byte
+	err = s.pool.QueryRow(ctx, `SELECT request, status, body FROM swarm_dlq_idempotency WHERE key = $1`, key).Scan(&stored, &status, &body)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		// Released between the two statements.
+		return nil, ErrIdempotencyInProgress
+	case err != nil:
+		return nil, fmt.Errorf("reserve idempotency key: %w", err)
+	case stored != request:
+		return nil, ErrIdempotencyKeyReused
+	case status == nil:
+		return nil, ErrIdempotencyInProgress
+	}
+	return &IdempotentResponse{Status: *status, Body: body}, nil
+}
+
+// CompleteIdempotencyKey implements IdempotencyStore.
+func (s *Store) CompleteIdempotencyKey(ctx context.Context, key string, resp IdempotentResponse) (err error) {
+	defer s.observe("complete_idempotency_key", time.Now(), &err)
+	_, err = s.pool.Exec(ctx, `
+		UPDATE swarm_dlq_idempotency SET status = $2, body = $3, created_at = now() WHERE key = $1
+	`, key, resp.Status, resp.Body)
+	if err != nil {
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey implements IdempotencyStore.
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, key string) (err error) {
+	defer s.observe("release_idempotency_key", time.Now(), &err)
+	_, err = s.pool.Exec(ctx, `DELETE FROM swarm_dlq_idempotency WHERE key = $1 AND status IS NULL`, key)
+	if err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys removes keys older than IdempotencyKeyTTL
+// and returns how many were removed. The Janitor calls it on every sweep.
+func (s *Store) DeleteExpiredIdempotencyKeys(ctx context.Context) (deleted int, err error) {
+	defer s.observe("delete_expired_idempotency_keys", time.Now(), &err)
+	tag, err := s.pool.Exec(ctx, `
+		DELETE FROM swarm_dlq_idempotency WHERE created_at <= now() - $1 * interval '1 microsecond'
+	`, IdempotencyKeyTTL.Microseconds())
+	if err != nil {
+		return 0, fmt.Errorf("delete expired idempotency keys: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,102 @@
+package dlq
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func newIdempotentRouter(store *mockStore, nc NATSPublisher) http.Handler {
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, nc, WithIdempotency(store)).Routes())
+	return r
+}
+
+func idempotentPost(r http.Handler, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandler_Retry_IdempotencyKeyReplaysResponse(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "ik1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true})
+	nc := newMockNATS()
+	r := newIdempotentRouter(store, nc)
+
+	first := idempotentPost(r, "/dlq/ik1/retry", "click-1", "")
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", first.Code, first.Body)
+	}
+	second := idempotentPost(r, "/dlq/ik1/retry", "click-1", "")
+	if second.Code != http.StatusOK || second.Body.String() != first.Body.String() {
+		t.Errorf("expected the original response replayed, got %d: %s", second.Code, second.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("expected the replay to be marked")
+	}
+	if got := len(nc.published()); got != 1 {
+		t.Errorf("expected a single republish, got %d", got)
+	}
+
+	// Without the key the second click is an ordinary retry of a recovered
+	// entry.
+	if w := idempotentPost(r, "/dlq/ik1/retry", "", ""); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 without a key, got %d", w.Code)
+	}
+}
+
+func TestHandler_IdempotencyKeyReusedOrInProgress(t *testing.T) {
+	store := newMockStore()
+	store.seed(
+		Entry{DLQID: "ik2", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true},
+		Entry{DLQID: "ik3", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true},
+	)
+	nc := &gatedNATS{mockNATS: newMockNATS(), entered: make(chan struct{}, 1), release: make(chan struct{})}
+	r := newIdempotentRouter(store, nc)
+
+	var first *httptest.ResponseRecorder
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first = idempotentPost(r, "/dlq/ik2/retry", "k", "")
+	}()
+	<-nc.entered
+	if w := idempotentPost(r, "/dlq/ik2/retry", "k", ""); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 while the keyed request runs, got %d", w.Code)
+	}
+	close(nc.release)
+	wg.Wait()
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected the first request to succeed, got %d", first.Code)
+	}
+
+	if w := idempotentPost(r, "/dlq/ik3/discard", "k", `{"reason":"dup"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for a key reused on another request, got %d", w.Code)
+	}
+}
+
+func TestHandler_IdempotencyKeyReleasedOnServerError(t *testing.T) {
+	store := newMockStore()
+	r := newIdempotentRouter(store, newMockNATS())
+	store.listErr = errors.New("db down")
+
+	if w := idempotentPost(r, "/dlq/retry-all", "k", ""); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	store.listErr = nil
+	if w := idempotentPost(r, "/dlq/retry-all", "k", ""); w.Code != http.StatusOK || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("expected the request to run again after a server error, got %d", w.Code)
+	}
+}
@@ -0,0 +1,117 @@
+package dlq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// MaxImportLineBytes bounds a single NDJSON line accepted by POST /import.
+const MaxImportLineBytes = 8 << 20
+
+// maxImportErrors caps how many per-line errors an import reports.
+const maxImportErrors = 100
+
+// WithImportProcessor mounts POST /import, which re-ingests NDJSON entries
+// (such as a GET /export from another environment) through p, so imports
+// get the same defaults, quotas, payload limits, events and audit as
+// entries arriving over NATS.
+func WithImportProcessor(p *Processor) HandlerOption {
+	return func(h *Handler) { h.importer = p }
+}
+
+// ImportError reports why one line of an import was not ingested.
+type ImportError struct {
+	Line  int    `json:"line"`
+	DLQID string `json:"dlq_id,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportResult is the response of POST /import. Processed entries include
+// repeats of dlq_ids already stored, which the store ignores.
+type ImportResult struct {
+	Processed int           `json:"processed"`
+	Invalid   int           `json:"invalid"`
+	Failed    int           `json:"failed"`
+	Total     int           `json:"total"`
+	Errors    []ImportError `json:"errors,omitempty"`
+}
+
+func (r *ImportResult) fail(line int, dlqID, msg string) {
+	if len(r.Errors) < maxImportErrors {
+		r.Errors = append(r.Errors, ImportError{Line: line, DLQID: dlqID, Error: msg})
+	}
+}
+
+// reopen clears e's lifecycle so it is stored as a new, open entry: not
+// recovered, discarded, expired, claimed or archived, and with no pending
+// automatic retry.
+func (e *Entry) reopen() {
+	e.Recovered, e.RecoveredAt, e.RecoveredBy = false, nil, ""
+	e.DiscardedAt, e.DiscardedBy, e.DiscardReason, e.DiscardNote = nil, "", "", ""
+	e.Status = StatusPending
+	e.ClaimedBy, e.ClaimExpiresAt, e.ArchivedAt = "", nil, nil
+	e.ExpiredAt, e.AutoRetryCount, e.NextRetryAt, e.RetryAck = nil, 0, nil, nil
+}
+
+// handleImport reads NDJSON entries from the body, validates each, reopens
+// it and hands it to the import Processor. Bad lines are reported and
+// skipped; the rest of the body is still imported.
+func (h *Handler) handleImport(w http.ResponseWriter, r *http.Request) {
+	sc := bufio.NewScanner(r.Body)
+	sc.Buffer(make([]byte, 0, 64<<10), MaxImportLineBytes)
+
+	var res ImportResult
+	line := 0
+	for sc.Scan() {
+		line++
+		raw := sc.Bytes()
+		if len(bytes.TrimSpace(raw)) == 0 {
+			continue
+		}
+		res.Total++
+
+		var e Entry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			res.Invalid++
+			res.fail(line, "", "malformed entry: "+err.Error())
+			continue
+		}
+		if err := validateEvent(e); err != nil {
+			res.Invalid++
+			res.fail(line, e.DLQID, err.Error())
+			continue
+		}
+		e.reopen()
+		data, err := json.Marshal(e)
+		if err != nil {
+			res.Invalid++
+			res.fail(line, e.DLQID, err.Error())
+			continue
+		}
+
+		switch err := h.importer.ProcessWithResult(r.Context(), SubjectForReason(e.Source, e.Reason), data); {
+		case err == nil:
+			res.Processed++
+		case errors.Is(err, ErrInvalidEvent):
+			res.Invalid++
+			res.fail(line, e.DLQID, "rejected by the processor")
+		default:
+			res.Failed++
+			res.fail(line, e.DLQID, "store insert failed")
+		}
+	}
+	if err := sc.Err(); err != nil {
+		msg := "invalid import body"
+		if errors.Is(err, bufio.ErrTooLong) {
+			msg = fmt.Sprintf("line %d exceeds %d bytes", line+1, MaxImportLineBytes)
+		}
+		res.fail(line+1, "", msg)
+		writeJSON(w, http.StatusBadRequest, res)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
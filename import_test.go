@@ -0,0 +1,88 @@
+package dlq
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func newImportRouter(store DataStore, opts ...ProcessorOption) http.Handler {
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, newMockNATS(), WithImportProcessor(NewProcessor(store, opts...))).Routes())
+	return r
+}
+
+func TestHandler_Import(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	r := newImportRouter(store, WithProcessorEvents(nc))
+
+	recoveredAt := time.Now().Add(-time.Hour)
+	lines := []string{
+		`{"dlq_id":"im-1","original_subject":"swarm.task.request","original_payload":{"task_id":"t-1"},"reason":"no_capable_agent","source":"dispatch","failed_at":"2026-01-01T00:00:00Z","recovered":true,"recovered_at":"` + recoveredAt.Format(time.RFC3339) + `","status":"recovered","claimed_by":"bob"}`,
+		``,
+		`{"dlq_id":"im-2","original_subject":"swarm.agent.boot","original_payload":{},"reason":"boot_failure","failed_at":"2026-01-01T00:00:00Z"}`,
+		`{"dlq_id":"im-3","reason":"boot_failure"}`,
+		`not json`,
+	}
+	req := httptest.NewRequest("POST", "/dlq/import", strings.NewReader(strings.Join(lines, "\n")))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var res ImportResult
+	_ = json.NewDecoder(w.Body).Decode(&res)
+	if res.Processed != 2 || res.Invalid != 2 || res.Total != 4 {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if len(res.Errors) != 2 || res.Errors[0].Line != 4 || res.Errors[0].DLQID != "im-3" || res.Errors[1].Line != 5 {
+		t.Errorf("unexpected errors %+v", res.Errors)
+	}
+
+	e := store.entries["im-1"]
+	if e == nil {
+		t.Fatal("im-1 should be imported")
+	}
+	if e.Recovered || e.RecoveredAt != nil || e.Status != StatusPending || e.ClaimedBy != "" {
+		t.Errorf("imported entries must be reopened, got %+v", e)
+	}
+	if store.entries["im-2"].Source != SourceWarren {
+		t.Errorf("expected the processor to infer the warren source, got %q", store.entries["im-2"].Source)
+	}
+	if got := len(nc.events(SubjectEntryCreated)); got != 2 {
+		t.Errorf("expected 2 entry-created events from the processor path, got %d", got)
+	}
+}
+
+func TestHandler_Import_StoreFailure(t *testing.T) {
+	store := newMockStore()
+	store.insertErr = errors.New("db down")
+	r := newImportRouter(store)
+
+	req := httptest.NewRequest("POST", "/dlq/import", strings.NewReader(`{"dlq_id":"im-9","original_subject":"s","reason":"no_capable_agent","failed_at":"2026-01-01T00:00:00Z"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var res ImportResult
+	_ = json.NewDecoder(w.Body).Decode(&res)
+	if res.Failed != 1 || res.Processed != 0 {
+		t.Errorf("expected the insert failure to be reported, got %+v", res)
+	}
+}
+
+func TestHandler_Import_NotMounted(t *testing.T) {
+	r := newTestRouter(newMockStore(), newMockNATS())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/dlq/import", strings.NewReader(`{}`)))
+	if w.Code == http.StatusOK {
+		t.Error("POST /import must not be mounted without WithImportProcessor")
+	}
+}
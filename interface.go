@@ -1,14 +1,62 @@
 package dlq
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
 
-// DataStore is the interface for DLQ persistence.
-// The concrete implementation is *Store (pgx-backed).
-type DataStore interface {
-	Insert(ctx context.Context, e Entry) error
+// Reader is the read side of DLQ persistence. Dashboards, reporters and
+// federation only need a Reader.
+type Reader interface {
 	Get(ctx context.Context, dlqID string) (*Entry, error)
 	List(ctx context.Context, opts ListOpts) ([]Entry, error)
-	MarkRecovered(ctx context.Context, dlqID, recoveredBy string) error
+	ListFingerprintGroups(ctx context.Context, opts ListOpts) ([]FingerprintGroup, error)
+	Count(ctx context.Context, opts ListOpts) (int, error)
+	Export(ctx context.Context, opts ListOpts, fn func(Entry) error) error
 	ListRecoverable(ctx context.Context) ([]Entry, error)
+	ListScheduledRetries(ctx context.Context) ([]Entry, error)
 	Stats(ctx context.Context) (*Stats, error)
+	AgentStats(ctx context.Context, limit int) ([]AgentFailureStats, error)
+	FailureReasonStats(ctx context.Context) (map[string]int, error)
+	Timeseries(ctx context.Context, opts TimeseriesOpts) (*Timeseries, error)
+	ListArchived(ctx context.Context, opts ListOpts) ([]Entry, error)
+	GetArchived(ctx context.Context, dlqID string) (*Entry, error)
+}
+
+// Writer is the write side of DLQ persistence. The Processor only needs a
+// Writer.
+type Writer interface {
+	Insert(ctx context.Context, e Entry) (created bool, err error)
+	InsertBatch(ctx context.Context, entries []Entry) (created []bool, err error)
+	RecordOccurrences(ctx context.Context, dlqID string, n int, lastSeen time.Time, samples []json.RawMessage) error
+	RecordFingerprint(ctx context.Context, fingerprint, dlqID string, lastSeen time.Time) (into string, err error)
+	MarkRecovered(ctx context.Context, dlqID, recoveredBy string) error
+	MarkRecoveredIfVersion(ctx context.Context, dlqID, recoveredBy string, version int) error
+	BeginRetry(ctx context.Context, dlqID, actor string) (*Entry, error)
+	AbortRetry(ctx context.Context, dlqID string) error
+	MarkDiscarded(ctx context.Context, dlqID, discardedBy string, opts DiscardOpts) error
+	Reopen(ctx context.Context, dlqID, by string, version int) (*Entry, error)
+	UpdateEntry(ctx context.Context, dlqID string, patch EntryPatch) (*Entry, error)
+	Archive(ctx context.Context, f ArchiveFilter) (moved int, err error)
+	Claim(ctx context.Context, dlqID, holder string, ttl time.Duration) (expiresAt time.Time, err error)
+	ReleaseClaim(ctx context.Context, dlqID, holder string) error
+	RecordRetryAck(ctx context.Context, dlqID string, ack RetryAck) error
+	RecordAutoRetryFailure(ctx context.Context, dlqID string, nextRetryAt time.Time) error
+	MarkExhausted(ctx context.Context, dlqID string) error
+	ScheduleRetry(ctx context.Context, dlqID, by string, at time.Time) error
+	ExpireRecoverable(ctx context.Context, failedBefore time.Time, limit int) ([]Entry, error)
+	Erase(ctx context.Context, req ErasureRequest) (dlqIDs []string, err error)
+	DeleteOlderThan(ctx context.Context, cutoff time.Time) (deleted int, err error)
+	Purge(ctx context.Context, f ArchiveFilter) (deleted int, err error)
+	Delete(ctx context.Context, dlqID string) error
 }
+
+// DataStore is the interface for DLQ persistence.
+// The concrete implementation is *Store (pgx-backed).
+type DataStore interface {
+	Reader
+	Writer
+}
+
+var _ DataStore = (*Store)(nil)
@@ -0,0 +1,190 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// InvalidEvent is a DLQ event the Processor could parse but not store,
+// parked in swarm_dlq_invalid with its raw bytes for an operator to fix and
+// re-import.
+type InvalidEvent struct {
+	ID         int64     `json:"id"`
+	Subject    string    `json:"subject"`
+	Data       []byte    `json:"data"`
+	Error      string    `json:"error"`
+	TenantID   string    `json:"tenant_id,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// InvalidEventStore parks invalid events. When the Processor's store
+// implements it (*Store does), events missing a required field or with an
+// unknown reason are parked instead of dropped.
+type InvalidEventStore interface {
+	ParkInvalid(ctx context.Context, ev InvalidEvent) error
+}
+
+var _ InvalidEventStore = (*Store)(nil)
+
+// validateEvent checks that e carries what the store and a later retry
+// need.
+func validateEvent(e Entry) error {
+	switch {
+	case strings.TrimSpace(e.DLQID) == "":
+		return errors.New("dlq_id is required")
+	case strings.TrimSpace(e.OriginalSubject) == "":
+		return errors.New("original_subject is required")
+	case strings.TrimSpace(e.Reason) == "":
+		return errors.New("reason is required")
+	case e.FailedAt.IsZero():
+		return errors.New("failed_at is required")
+	case len(e.OriginalPayload) > 0 && !json.Valid(e.OriginalPayload):
+		return errors.New("original_payload is not valid JSON")
+	}
+	return nil
+}
+
+// park records an event decode rejected with reason. It returns reason
+// wrapped in ErrInvalidEvent once the event is parked, or when the store
+// cannot park, and a transient error if parking failed so the event is
+// redelivered rather than lost.
+func (p *Processor) park(ctx context.Context, subject string, data []byte, reason error) error {
+	parker, ok := p.store.(InvalidEventStore)
+	if !ok {
+		slog.Warn("dlq processor: rejecting invalid event", "subject", subject, "error", reason)
+		return fmt.Errorf("%w: %w", ErrInvalidEvent, reason)
+	}
+	ev := InvalidEvent{Subject: subject, Data: data, Error: reason.Error(), TenantID: p.tenant}
+	if err := parker.ParkInvalid(ctx, ev); err != nil {
+		slog.Error("dlq processor: failed to park invalid event",
+			"subject", subject,
+			"reason", reason,
+			"error", err,
+		)
+		return fmt.Errorf("park invalid event: %w", err)
+	}
+	p.metrics.Inc(MetricProcessorParked)
+	slog.Warn("dlq processor: parked invalid event", "subject", subject, "error", reason)
+	return fmt.Errorf("%w: %w", ErrInvalidEvent, reason)
+}
+
+// ParkInvalid stores ev in swarm_dlq_invalid. An empty TenantID is taken
+// from ctx (see ContextWithTenant).
+func (s *Store) ParkInvalid(ctx context.Context, ev InvalidEvent) (err error) {
+	defer s.observe("park_invalid", time.Now(), &err)
+	tenant := ev.TenantID
+	if tenant == "" {
+		tenant = s.tenant(ctx)
+	}
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO swarm_dlq_invalid (subject, data, error, tenant_id)
+		VALUES ($1, $2, $3, $4)
+	`, ev.Subject, ev.Data, ev.Error, tenant)
+	if err != nil {
+		return fmt.Errorf("park invalid dlq event: %w", err)
+	}
+	return nil
+}
+
+// ListInvalid returns up to limit parked events, newest first.
+// invalidIDPrefix marks the ids Erase reports for parked invalid events.
+const invalidIDPrefix = "invalid:"
+
+// eraseInvalid applies req to tenant's parked invalid events whose raw
+// bytes, or the original_payload or a payload sample inside them, have
+// req.Value at req.Field. Delete removes the row; scrub empties its data,
+// keeping the subject and error. Raw bytes that are not JSON cannot be
+// matched.
+func eraseInvalid(ctx context.Context, tx pgx.Tx, tenant string, req ErasureRequest) ([]string, error) {
+	var want any
+	if err := json.Unmarshal(req.Value, &want); err != nil {
+		return nil, ErrInvalidErasure
+	}
+	rows, err := tx.Query(ctx, `SELECT id, data FROM swarm_dlq_invalid WHERE `+tenantClause(1)+` FOR UPDATE`, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("erase invalid events: %w", err)
+	}
+	defer rows.Close()
+
+	field := map[string]any{req.Field: want}
+	var matched []int64
+	for rows.Next() {
+		var (
+			id   int64
+			data []byte
+		)
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, fmt.Errorf("erase invalid events: %w", err)
+		}
+		var doc any
+		if json.Unmarshal(data, &doc) != nil {
+			continue
+		}
+		hit := jsonContains(doc, field)
+		if env, ok := doc.(map[string]any); ok {
+			hit = hit || jsonContains(env["original_payload"], field)
+			samples, _ := env["payload_samples"].([]any)
+			for _, sample := range samples {
+				hit = hit || jsonContains(sample, field)
+			}
+		}
+		if hit {
+			matched = append(matched, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erase invalid events: %w", err)
+	}
+
+	ids := make([]string, len(matched))
+	for i, id := range matched {
+		ids[i] = invalidIDPrefix + strconv.FormatInt(id, 10)
+	}
+	if req.DryRun || len(matched) == 0 {
+		return ids, nil
+	}
+	stmt := `DELETE FROM swarm_dlq_invalid WHERE id = ANY($1)`
+	if req.Mode == EraseScrub {
+		stmt = `UPDATE swarm_dlq_invalid SET data = ''::bytea WHERE id = ANY($1)`
+	}
+	if _, err := tx.Exec(ctx, stmt, matched); err != nil {
+		return nil, fmt.Errorf("erase invalid events: %w", err)
+	}
+	return ids, nil
+}
+
+func (s *Store) ListInvalid(ctx context.Context, limit int) (_ []InvalidEvent, err error) {
+	defer s.observe("list_invalid", time.Now(), &err)
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, subject, data, error, tenant_id, received_at
+		FROM swarm_dlq_invalid
+		WHERE `+tenantClause(2)+`
+		ORDER BY received_at DESC, id DESC
+		LIMIT $1
+	`, limit, s.tenant(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list invalid dlq events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []InvalidEvent{}
+	for rows.Next() {
+		var ev InvalidEvent
+		if err := rows.Scan(&ev.ID, &ev.Subject, &ev.Data, &ev.Error, &ev.TenantID, &ev.ReceivedAt); err != nil {
+			return nil, fmt.Errorf("list invalid dlq events: %w", err)
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
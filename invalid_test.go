@@ -0,0 +1,69 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// parkingStore is a mockStore that parks invalid events.
+type parkingStore struct {
+	*mockStore
+	parked  []InvalidEvent
+	parkErr error
+}
+
+func (s *parkingStore) ParkInvalid(_ context.Context, ev InvalidEvent) error {
+	if s.parkErr != nil {
+		return s.parkErr
+	}
+	s.parked = append(s.parked, ev)
+	return nil
+}
+
+func TestProcessor_ParksInvalidEvents(t *testing.T) {
+	store := &parkingStore{mockStore: newMockStore()}
+	m := NewMetrics()
+	proc := NewProcessor(store, WithProcessorMetrics(m), WithProcessorTenant("prod"))
+	ctx := context.Background()
+
+	tests := []struct {
+		data    string
+		invalid bool
+		wantErr string
+	}{
+		{`{"dlq_id":"iv-1","original_subject":"s","failed_at":"2026-01-01T00:00:00Z"}`, false, ""},
+		{`{"dlq_id":"iv-2","original_subject":"s"}`, true, "failed_at is required"},
+		{`{"original_subject":"s","failed_at":"2026-01-01T00:00:00Z"}`, true, "dlq_id is required"},
+		{`{"dlq_id":"iv-3","original_subject":"s","reason":"made_up","failed_at":"2026-01-01T00:00:00Z"}`, true, "unknown dlq reason"},
+		{`not json`, true, ""},
+	}
+	for _, tt := range tests {
+		err := proc.ProcessWithResult(ctx, SubjectTaskUnassignable, []byte(tt.data))
+		if errors.Is(err, ErrInvalidEvent) != tt.invalid || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: unexpected result %v", tt.data, err)
+		}
+	}
+
+	if store.insertCalls != 1 {
+		t.Errorf("expected only the valid event to be inserted, got %d", store.insertCalls)
+	}
+	if len(store.parked) != 3 {
+		t.Fatalf("expected 3 parked events, got %+v", store.parked)
+	}
+	for i, want := range []string{"failed_at is required", "dlq_id is required", "unknown dlq reason"} {
+		ev := store.parked[i]
+		if !strings.Contains(ev.Error, want) || string(ev.Data) != tests[i+1].data || ev.Subject != SubjectTaskUnassignable || ev.TenantID != "prod" {
+			t.Errorf("parked[%d] = %+v, want error %q with the raw bytes", i, ev, want)
+		}
+	}
+	if got := m.Get(MetricProcessorParked); got != 3 {
+		t.Errorf("parked metric = %d", got)
+	}
+
+	store.parkErr = errors.New("db down")
+	if err := proc.ProcessWithResult(ctx, SubjectTaskUnassignable, []byte(`{"dlq_id":"iv-4"}`)); err == nil || errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("a failed park should be redelivered, got %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package dlq
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// janitorDeleteBatch bounds how many rows one DeleteOlderThan statement
+// removes, so a large backlog never holds long locks on swarm_dlq.
+const janitorDeleteBatch = 1000
+
+// Janitor periodically removes recovered, discarded and expired entries once
+// they are older than the retention period, or the retention set for their
+// reason, deleting them or moving them to swarm_dlq_archive. Unrecovered
+// entries are never touched. When the store is a *Store it also drops
+// idempotency keys past IdempotencyKeyTTL.
+type Janitor struct {
+	store     Writer
+	retention time.Duration
+	reasons   map[string]time.Duration
+	interval  time.Duration
+	archive   bool
+	metrics   *Metrics
+	done      chan struct{}
+}
+
+// JanitorOption configures a Janitor.
+type JanitorOption func(*Janitor)
+
+// WithJanitorArchive moves expired entries to swarm_dlq_archive instead of
+// deleting them.
+func WithJanitorArchive() JanitorOption {
+	return func(j *Janitor) { j.archive = true }
+}
+
+// WithJanitorReasonRetention keeps entries with reason for retention instead
+// of the janitor's default, e.g. policy_denied for 90 days for audits and
+// boot_failure for 14. It may be longer or shorter than the default.
+func WithJanitorReasonRetention(reason string, retention time.Duration) JanitorOption {
+	return func(j *Janitor) {
+		if retention <= 0 {
+			return
+		}
+		if j.reasons == nil {
+			j.reasons = make(map[string]time.Duration)
+		}
+		j.reasons[reason] = retention
+	}
+}
+
+// WithJanitorMetrics counts deleted and archived entries in m.
+func WithJanitorMetrics(m *Metrics) JanitorOption {
+	return func(j *Janitor) { j.metrics = m }
+}
+
+// NewJanitor creates a retention janitor that, every interval, removes
+// entries recovered or discarded more than retention ago.
+func NewJanitor(store Writer, retention, interval time.Duration, opts ...JanitorOption) *Janitor {
+	j := &Janitor{
+		store:     store,
+		retention: retention,
+		interval:  interval,
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// Start begins the periodic sweep loop. Call with a cancellable context for shutdown.
+func (j *Janitor) Start(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	go func() {
+		defer ticker.Stop()
+		defer close(j.done)
+		for {
+			select {
+			case <-ticker.C:
+				j.sweep(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Wait blocks until the janitor has stopped.
+func (j *Janitor) Wait() {
+	<-j.done
+}
+
+func (j *Janitor) sweep(ctx context.Context) {
+	if keys, ok := j.store.(interface {
+		DeleteExpiredIdempotencyKeys(ctx context.Context) (int, error)
+	}); ok {
+		if _, err := keys.DeleteExpiredIdempotencyKeys(ctx); err != nil {
+			slog.Error("dlq janitor: failed to delete expired idempotency keys", "error", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	reasons := make([]string, 0, len(j.reasons))
+	for reason := range j.reasons {
+		reasons = append(reasons, reason)
+	}
+	slices.Sort(reasons)
+	for _, reason := range reasons {
+		j.remove(ctx, ArchiveFilter{RecoveredBefore: now.Add(-j.reasons[reason]), Reason: reason})
+	}
+	j.remove(ctx, ArchiveFilter{RecoveredBefore: now.Add(-j.retention), ExcludeReasons: reasons})
+}
+
+// remove deletes or archives the entries selected by f.
+func (j *Janitor) remove(ctx context.Context, f ArchiveFilter) {
+	if j.archive {
+		moved, err := j.store.Archive(ctx, f)
+		if err != nil {
+			slog.Error("dlq janitor: failed to archive expired entries", "reason", f.Reason, "error", err)
+			return
+		}
+		j.metrics.Add(MetricJanitorArchived, int64(moved))
+		if moved > 0 {
+			slog.Info("dlq janitor: archived expired entries", "count", moved, "reason", f.Reason, "cutoff", f.RecoveredBefore)
+		}
+		return
+	}
+
+	deleted, err := j.store.Purge(ctx, f)
+	j.metrics.Add(MetricJanitorDeleted, int64(deleted))
+	if err != nil {
+		slog.Error("dlq janitor: failed to delete expired entries", "deleted", deleted, "reason", f.Reason, "error", err)
+		return
+	}
+	if deleted > 0 {
+		slog.Info("dlq janitor: deleted expired entries", "count", deleted, "reason", f.Reason, "cutoff", f.RecoveredBefore)
+	}
+}
+
+// DeleteOlderThan permanently deletes entries recovered, discarded or
+// expired before cutoff and returns how many were removed. See Purge.
+func (s *Store) DeleteOlderThan(ctx context.Context, cutoff time.Time) (int, error) {
+	return s.Purge(ctx, ArchiveFilter{RecoveredBefore: cutoff})
+}
+
+// Purge permanently deletes the entries selected by f, those Archive would
+// move, and returns how many were removed. Rows are deleted in batches; on
+// error the count covers the batches already committed. With WithStoreBlobs
+// each batch's offloaded payloads are deleted before it commits.
+func (s *Store) Purge(ctx context.Context, f ArchiveFilter) (deleted int, err error) {
+	defer s.observe("purge", time.Now(), &err)
+	where, args, err := f.where()
+	if err != nil {
+		return 0, err
+	}
+	args = append(args, s.tenant(ctx))
+	where += " AND " + tenantClause(len(args))
+	args = append(args, janitorDeleteBatch)
+	where += fmt.Sprintf(" LIMIT $%d", len(args))
+	for {
+		n, err := s.purgeBatch(ctx, where, args)
+		if err != nil {
+			return deleted, fmt.Errorf("delete expired dlq entries: %w", err)
+		}
+		deleted += n
+		if n < janitorDeleteBatch {
+			return deleted, nil
+		}
+	}
+}
+
+// purgeBatch deletes up to janitorDeleteBatch entries matching where and
+// their offloaded payloads in one transaction.
+func (s *Store) purgeBatch(ctx context.Context, where string, args []any) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `
+		DELETE FROM swarm_dlq WHERE dlq_id IN (
+			SELECT dlq_id FROM swarm_dlq WHERE true`+where+`
+		)
+		RETURNING coalesce(payload_ref, '')
+	`, args...)
+	if err != nil {
+		return 0, err
+	}
+	refs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, err
+	}
+	if err := s.deleteBlobs(ctx, refs); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(refs), nil
+}
@@ -0,0 +1,176 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func seedRetention(store *mockStore) {
+	old := time.Now().Add(-45 * 24 * time.Hour)
+	recent := time.Now().Add(-time.Hour)
+	store.seed(
+		Entry{DLQID: "jn-1", Recovered: true, RecoveredAt: &old, RecoveredBy: RecoveredByAPIRetry},
+		Entry{DLQID: "jn-2", Recovered: true, Status: StatusDiscarded, DiscardedAt: &old, DiscardedBy: RecoveredByDiscard},
+		Entry{DLQID: "jn-3", Recovered: true, RecoveredAt: &recent},
+		Entry{DLQID: "jn-4", FailedAt: old},
+	)
+}
+
+func TestJanitor_Sweep_Deletes(t *testing.T) {
+	store := newMockStore()
+	seedRetention(store)
+	m := NewMetrics()
+
+	NewJanitor(store, 30*24*time.Hour, time.Hour, WithJanitorMetrics(m)).sweep(context.Background())
+
+	for _, id := range []string{"jn-1", "jn-2"} {
+		if _, ok := store.entries[id]; ok {
+			t.Errorf("%s is past retention and should be deleted", id)
+		}
+	}
+	for _, id := range []string{"jn-3", "jn-4"} {
+		if _, ok := store.entries[id]; !ok {
+			t.Errorf("%s should be kept", id)
+		}
+	}
+	if len(store.archived) != 0 {
+		t.Error("delete mode must not archive")
+	}
+	if got := m.Get(MetricJanitorDeleted); got != 2 {
+		t.Errorf("expected 2 deleted, got %d", got)
+	}
+}
+
+func TestJanitor_Sweep_Archives(t *testing.T) {
+	store := newMockStore()
+	seedRetention(store)
+	m := NewMetrics()
+
+	NewJanitor(store, 30*24*time.Hour, time.Hour, WithJanitorArchive(), WithJanitorMetrics(m)).sweep(context.Background())
+
+	if len(store.archived) != 2 || store.archived["jn-1"] == nil || store.archived["jn-2"] == nil {
+		t.Errorf("expected jn-1 and jn-2 archived, got %d", len(store.archived))
+	}
+	if len(store.entries) != 2 {
+		t.Errorf("expected 2 live entries left, got %d", len(store.entries))
+	}
+	if got := m.Get(MetricJanitorArchived); got != 2 {
+		t.Errorf("expected 2 archived, got %d", got)
+	}
+}
+
+func TestJanitor_Sweep_ReasonRetention(t *testing.T) {
+	for _, archive := range []bool{false, true} {
+		store := newMockStore()
+		old := time.Now().Add(-45 * 24 * time.Hour)
+		week := time.Now().Add(-20 * 24 * time.Hour)
+		store.seed(
+			Entry{DLQID: "jr-policy", Reason: ReasonPolicyDenied, Recovered: true, RecoveredAt: &old},
+			Entry{DLQID: "jr-boot", Reason: ReasonBootFailure, Recovered: true, RecoveredAt: &week},
+			Entry{DLQID: "jr-crash", Reason: ReasonCrashLoop, Recovered: true, RecoveredAt: &old},
+			Entry{DLQID: "jr-recent", Reason: ReasonCrashLoop, Recovered: true, RecoveredAt: &week},
+		)
+		opts := []JanitorOption{
+			WithJanitorReasonRetention(ReasonPolicyDenied, 90*24*time.Hour),
+			WithJanitorReasonRetention(ReasonBootFailure, 14*24*time.Hour),
+		}
+		if archive {
+			opts = append(opts, WithJanitorArchive())
+		}
+
+		NewJanitor(store, 30*24*time.Hour, time.Hour, opts...).sweep(context.Background())
+
+		for _, id := range []string{"jr-boot", "jr-crash"} {
+			if _, ok := store.entries[id]; ok {
+				t.Errorf("archive=%v: %s is past its retention and should be removed", archive, id)
+			}
+		}
+		for _, id := range []string{"jr-policy", "jr-recent"} {
+			if _, ok := store.entries[id]; !ok {
+				t.Errorf("archive=%v: %s is within its retention and should be kept", archive, id)
+			}
+		}
+		if archive && len(store.archived) != 2 {
+			t.Errorf("expected 2 archived, got %d", len(store.archived))
+		}
+	}
+}
+
+func TestJanitor_StartStop(t *testing.T) {
+	store := newMockStore()
+	seedRetention(store)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	j := NewJanitor(store, 30*24*time.Hour, 10*time.Millisecond)
+	j.Start(ctx)
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		store.mu.Lock()
+		n := len(store.entries)
+		store.mu.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	j.Wait()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.entries) != 2 {
+		t.Errorf("expected the janitor to sweep while running, %d entries left", len(store.entries))
+	}
+}
+
+func TestHandler_Purge(t *testing.T) {
+	store := newMockStore()
+	seedRetention(store)
+	r := authRouter(store, WithAuthMiddleware(APIKeyAuth(map[string]Principal{
+		"k-admin": {Subject: "root", Scopes: []string{ScopeAdmin}},
+		"k-alice": {Subject: "alice"},
+	})))
+	purge := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/dlq/purge", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := purge("", `{"older_than":"720h"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous purge: expected 401, got %d", w.Code)
+	}
+	if w := purge("k-alice", `{"older_than":"720h"}`); w.Code != http.StatusForbidden {
+		t.Errorf("purge without dlq:admin: expected 403, got %d", w.Code)
+	}
+	if len(store.entries) != 4 {
+		t.Fatalf("a refused purge must not delete anything, %d entries left", len(store.entries))
+	}
+
+	w := purge("k-admin", `{"older_than":"720h"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body map[string]int
+	_ = json.NewDecoder(w.Body).Decode(&body)
+	if body["deleted"] != 2 {
+		t.Errorf("expected 2 deleted, got %d", body["deleted"])
+	}
+	if _, ok := store.entries["jn-4"]; !ok {
+		t.Error("unrecovered entries must never be purged")
+	}
+
+	for _, b := range []string{`{}`, `{"older_than":"-1h"}`, `{"older_than":"30d"}`} {
+		if w := purge("k-admin", b); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", b, w.Code)
+		}
+	}
+}
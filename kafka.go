@@ -0,0 +1,238 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+)
+
+// Defaults for the Kafka adapters.
+const (
+	DefaultKafkaWriteTimeout = 10 * time.Second
+	DefaultKafkaRetryDelay   = 5 * time.Second
+)
+
+// KafkaSubjectHeader carries the DLQ subject of a Kafka record, so events can
+// share one topic and still be routed by subject (see WithKafkaTopic).
+const KafkaSubjectHeader = "dlq-subject"
+
+// KafkaMessage is a Kafka record as the adapters see it. Headers with the
+// same key keep their order.
+type KafkaMessage struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string][]string
+}
+
+// KafkaWriter writes records to Kafka. It is satisfied by a few lines
+// around any client, e.g. segmentio/kafka-go's Writer.WriteMessages or
+// franz-go's Client.ProduceSync, so this package does not depend on one.
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...KafkaMessage) error
+}
+
+// KafkaReader reads records from a consumer group. FetchMessage blocks until
+// a record arrives or ctx is done; CommitMessages commits the group's offset
+// past msgs.
+type KafkaReader interface {
+	FetchMessage(ctx context.Context) (KafkaMessage, error)
+	CommitMessages(ctx context.Context, msgs ...KafkaMessage) error
+}
+
+// KafkaPublisher is a NATSPublisher that writes to Kafka, for swarms that
+// run on Kafka instead of NATS. Pass it wherever a NATSPublisher is taken
+// (Handler, Scanner, Outbox, WithProcessorEvents) and to
+// WithPublisherTransport. Each subject is written to the topic returned by
+// WithKafkaTopic, the subject itself by default, with the subject in the
+// KafkaSubjectHeader header. Message headers, including the trace context,
+// become record headers.
+type KafkaPublisher struct {
+	w       KafkaWriter
+	topic   func(subject string) string
+	timeout time.Duration
+}
+
+var (
+	_ NATSPublisher        = (*KafkaPublisher)(nil)
+	_ NATSContextPublisher = (*KafkaPublisher)(nil)
+	_ NATSMsgPublisher     = (*KafkaPublisher)(nil)
+)
+
+// KafkaPublisherOption configures a KafkaPublisher.
+type KafkaPublisherOption func(*KafkaPublisher)
+
+// WithKafkaTopic maps NATS subjects to Kafka topics, e.g. every dlq.> subject
+// to a single "swarm-dlq" topic.
+func WithKafkaTopic(fn func(subject string) string) KafkaPublisherOption {
+	return func(p *KafkaPublisher) { p.topic = fn }
+}
+
+// WithKafkaWriteTimeout bounds writes made without a context (Publish and
+// PublishMsg). The default is DefaultKafkaWriteTimeout.
+func WithKafkaWriteTimeout(d time.Duration) KafkaPublisherOption {
+	return func(p *KafkaPublisher) {
+		if d > 0 {
+			p.timeout = d
+		}
+	}
+}
+
+// NewKafkaPublisher creates a publisher writing through w.
+func NewKafkaPublisher(w KafkaWriter, opts ...KafkaPublisherOption) *KafkaPublisher {
+	p := &KafkaPublisher{
+		w:       w,
+		topic:   func(subject string) string { return subject },
+		timeout: DefaultKafkaWriteTimeout,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Publish implements NATSPublisher.
+func (p *KafkaPublisher) Publish(subject string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	return p.write(ctx, subject, data, nil)
+}
+
+// PublishContext implements NATSContextPublisher.
+func (p *KafkaPublisher) PublishContext(ctx context.Context, subject string, data []byte) error {
+	return p.write(ctx, subject, data, nil)
+}
+
+// PublishMsg implements NATSMsgPublisher.
+func (p *KafkaPublisher) PublishMsg(msg *nats.Msg) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	return p.write(ctx, msg.Subject, msg.Data, msg.Header)
+}
+
+func (p *KafkaPublisher) write(ctx context.Context, subject string, data []byte, hdr nats.Header) error {
+	headers := make(map[string][]string, len(hdr)+1)
+	for k, vs := range hdr {
+		headers[k] = vs
+	}
+	headers[KafkaSubjectHeader] = []string{subject}
+	topic := p.topic(subject)
+	if err := p.w.WriteMessages(ctx, KafkaMessage{Topic: topic, Value: data, Headers: headers}); err != nil {
+		return fmt.Errorf("write %s to kafka topic %s: %w", subject, topic, err)
+	}
+	return nil
+}
+
+// WithPublisherTransport sends dead letters through t, e.g. a KafkaPublisher,
+// instead of the NATS connection given to NewPublisher, which may then be
+// nil. JetStream (WithPublisherJetStream) still takes precedence.
+func WithPublisherTransport(t NATSPublisher) PublisherOption {
+	return func(p *Publisher) { p.transport = t }
+}
+
+// KafkaConsumer reads DLQ events from Kafka and feeds them to a Processor,
+// the Kafka counterpart of Consumer with JetStream. Each record's offset is
+// committed once its entry is stored, or once it is found invalid (and
+// parked, see InvalidEventStore). When the store fails the same record is
+// processed again after a delay, so nothing behind it is committed early.
+//
+// The subject of a record is its KafkaSubjectHeader header, or its topic if
+// it has none, and a trace context in its headers is continued.
+type KafkaConsumer struct {
+	r          KafkaReader
+	proc       *Processor
+	retryDelay time.Duration
+	done       chan struct{}
+}
+
+// KafkaConsumerOption configures a KafkaConsumer.
+type KafkaConsumerOption func(*KafkaConsumer)
+
+// WithKafkaRetryDelay sets how long the consumer waits before processing a
+// record again after a store failure, or fetching again after a fetch error.
+// The default is DefaultKafkaRetryDelay.
+func WithKafkaRetryDelay(d time.Duration) KafkaConsumerOption {
+	return func(c *KafkaConsumer) {
+		if d > 0 {
+			c.retryDelay = d
+		}
+	}
+}
+
+// NewKafkaConsumer creates a consumer that drives proc from r. proc is used
+// through ProcessWithResult; do not Start it for this consumer.
+func NewKafkaConsumer(r KafkaReader, proc *Processor, opts ...KafkaConsumerOption) *KafkaConsumer {
+	c := &KafkaConsumer{
+		r:          r,
+		proc:       proc,
+		retryDelay: DefaultKafkaRetryDelay,
+		done:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Start consumes records until ctx is cancelled. A record being processed
+// when that happens is finished first; call Wait to block until it is.
+func (c *KafkaConsumer) Start(ctx context.Context) {
+	go func() {
+		defer close(c.done)
+		for ctx.Err() == nil {
+			msg, err := c.r.FetchMessage(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Warn("dlq kafka consumer: fetch failed", "error", err)
+					c.sleep(ctx)
+				}
+				continue
+			}
+			c.handle(ctx, msg)
+		}
+	}()
+}
+
+// Wait blocks until the consumer has stopped.
+func (c *KafkaConsumer) Wait() {
+	<-c.done
+}
+
+// handle processes msg until it is stored or found invalid, then commits it.
+// It gives up without committing if ctx is cancelled between attempts, so
+// the record is delivered again after a restart.
+func (c *KafkaConsumer) handle(ctx context.Context, msg KafkaMessage) {
+	subject := msg.Topic
+	if vs := msg.Headers[KafkaSubjectHeader]; len(vs) > 0 && vs[0] != "" {
+		subject = vs[0]
+	}
+	pctx := otel.GetTextMapPropagator().Extract(context.WithoutCancel(ctx), natsHeaderCarrier(msg.Headers))
+	for {
+		err := c.proc.ProcessWithResult(pctx, subject, msg.Value)
+		if err == nil || errors.Is(err, ErrInvalidEvent) {
+			break
+		}
+		slog.Warn("dlq kafka consumer: processing failed, will retry", "subject", subject, "error", err)
+		if !c.sleep(ctx) {
+			return
+		}
+	}
+	if err := c.r.CommitMessages(context.WithoutCancel(ctx), msg); err != nil {
+		slog.Warn("dlq kafka consumer: commit failed", "topic", msg.Topic, "error", err)
+	}
+}
+
+// sleep waits for the retry delay, reporting false if ctx is done first.
+func (c *KafkaConsumer) sleep(ctx context.Context) bool {
+	select {
+	case <-time.After(c.retryDelay):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
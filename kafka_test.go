@@ -0,0 +1,111 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeKafka is a KafkaWriter and KafkaReader over an in-memory log.
+type fakeKafka struct {
+	mu        sync.Mutex
+	written   []KafkaMessage
+	writeErr  error
+	fetch     chan KafkaMessage
+	committed []KafkaMessage
+}
+
+func (k *fakeKafka) WriteMessages(_ context.Context, msgs ...KafkaMessage) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.writeErr != nil {
+		return k.writeErr
+	}
+	k.written = append(k.written, msgs...)
+	return nil
+}
+
+func (k *fakeKafka) FetchMessage(ctx context.Context) (KafkaMessage, error) {
+	select {
+	case msg := <-k.fetch:
+		return msg, nil
+	case <-ctx.Done():
+		return KafkaMessage{}, ctx.Err()
+	}
+}
+
+func (k *fakeKafka) CommitMessages(_ context.Context, msgs ...KafkaMessage) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.committed = append(k.committed, msgs...)
+	return nil
+}
+
+func (k *fakeKafka) commits() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.committed)
+}
+
+func TestKafkaPublisher(t *testing.T) {
+	k := &fakeKafka{}
+	kp := NewKafkaPublisher(k, WithKafkaTopic(func(string) string { return "swarm-dlq" }))
+	pub := NewPublisher(nil, SourceDispatch, WithPublisherTransport(kp))
+
+	opts := PublishOpts{OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonPolicyDenied}
+	if err := pub.Publish(opts); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if len(k.written) != 1 {
+		t.Fatalf("expected one record, got %d", len(k.written))
+	}
+	rec := k.written[0]
+	if rec.Topic != "swarm-dlq" || rec.Headers[KafkaSubjectHeader][0] != SubjectTaskPolicyDenied {
+		t.Errorf("unexpected record %+v", rec)
+	}
+
+	k.writeErr = errors.New("broker unavailable")
+	if err := pub.Publish(opts); !errors.Is(err, k.writeErr) {
+		t.Errorf("expected the write error, got %v", err)
+	}
+}
+
+func TestKafkaConsumer(t *testing.T) {
+	store := newMockStore()
+	k := &fakeKafka{fetch: make(chan KafkaMessage, 3)}
+	c := NewKafkaConsumer(k, NewProcessor(store), WithKafkaRetryDelay(time.Millisecond))
+
+	store.insertErr = errors.New("db down")
+	k.fetch <- KafkaMessage{Topic: "swarm-dlq", Value: eventJSON(Entry{DLQID: "kc-1"}),
+		Headers: map[string][]string{KafkaSubjectHeader: {SubjectTaskUnassignable}}}
+	k.fetch <- KafkaMessage{Topic: SubjectTaskUnassignable, Value: []byte("not json")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.Start(ctx)
+
+	time.Sleep(20 * time.Millisecond)
+	if got := k.commits(); got != 0 {
+		t.Fatalf("a record the store failed must not be committed, got %d commits", got)
+	}
+	store.mu.Lock()
+	store.insertErr = nil
+	store.mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for k.commits() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	c.Wait()
+
+	if got := k.commits(); got != 2 {
+		t.Fatalf("expected the stored and the invalid record to be committed, got %d", got)
+	}
+	if e, err := store.Get(context.Background(), "kc-1"); err != nil || e.Reason != ReasonNoCapableAgent {
+		t.Errorf("expected kc-1 stored under the header subject, got %+v, %v", e, err)
+	}
+}
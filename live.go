@@ -0,0 +1,240 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Live event types broadcast by a LiveFeed.
+const (
+	LiveIngested  = "ingested"
+	LiveRetried   = "retried"
+	LiveDiscarded = "discarded"
+	LiveExhausted = "exhausted"
+	LiveExpired   = "expired"
+)
+
+const (
+	// liveBuffer is how many events a subscriber may fall behind before
+	// further events are dropped for it.
+	liveBuffer = 64
+	// livePingInterval keeps idle connections open through proxies.
+	livePingInterval = 30 * time.Second
+	liveWriteTimeout = 10 * time.Second
+)
+
+// LiveEvent is one entry lifecycle change sent to live subscribers.
+type LiveEvent struct {
+	Type            string    `json:"type"`
+	DLQID           string    `json:"dlq_id"`
+	Reason          string    `json:"reason"`
+	Source          string    `json:"source"`
+	OriginalSubject string    `json:"original_subject"`
+	Actor           string    `json:"actor,omitempty"`
+	TenantID        string    `json:"tenant_id,omitempty"`
+	At              time.Time `json:"at"`
+}
+
+// liveEvent builds an event of type typ for e.
+func liveEvent(typ string, e Entry, actor string) LiveEvent {
+	return LiveEvent{
+		Type: typ, DLQID: e.DLQID, Reason: e.Reason, Source: e.Source,
+		OriginalSubject: e.OriginalSubject, Actor: actor, TenantID: e.TenantID, At: time.Now().UTC(),
+	}
+}
+
+// LiveFilter narrows a subscription. Empty lists match everything.
+type LiveFilter struct {
+	Reasons []string `json:"reasons,omitempty"`
+	Sources []string `json:"sources,omitempty"`
+	Types   []string `json:"types,omitempty"`
+}
+
+func (f LiveFilter) matches(ev LiveEvent) bool {
+	return (len(f.Reasons) == 0 || slices.Contains(f.Reasons, ev.Reason)) &&
+		(len(f.Sources) == 0 || slices.Contains(f.Sources, ev.Source)) &&
+		(len(f.Types) == 0 || slices.Contains(f.Types, ev.Type))
+}
+
+// liveFilterFromQuery reads comma-separated reason, source and type
+// parameters.
+func liveFilterFromQuery(r *http.Request) LiveFilter {
+	split := func(v string) []string {
+		if v == "" {
+			return nil
+		}
+		return strings.Split(v, ",")
+	}
+	q := r.URL.Query()
+	return LiveFilter{Reasons: split(q.Get("reason")), Sources: split(q.Get("source")), Types: split(q.Get("type"))}
+}
+
+// LiveFeed broadcasts entry lifecycle events to WebSocket subscribers. Share
+// one feed between the Handler (which serves GET /live and reports retries
+// and discards), the Processor (ingest) and the Scanner (automatic retries).
+// A nil *LiveFeed discards all events.
+type LiveFeed struct {
+	mu       sync.RWMutex
+	subs     map[*liveSub]struct{}
+	upgrader websocket.Upgrader
+}
+
+type liveSub struct {
+	mu     sync.Mutex
+	filter LiveFilter
+	tenant string
+	events chan LiveEvent
+}
+
+func (s *liveSub) setFilter(f LiveFilter) {
+	s.mu.Lock()
+	s.filter = f
+	s.mu.Unlock()
+}
+
+func (s *liveSub) wants(ev LiveEvent) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return (s.tenant == "" || ev.TenantID == s.tenant) && s.filter.matches(ev)
+}
+
+// LiveFeedOption configures a LiveFeed.
+type LiveFeedOption func(*LiveFeed)
+
+// WithLiveAllowedOrigins accepts WebSocket connections from the given
+// origins (e.g. "https://admin.example.com") in addition to same-origin ones.
+func WithLiveAllowedOrigins(origins ...string) LiveFeedOption {
+	return func(f *LiveFeed) {
+		f.upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || slices.Contains(origins, origin) || sameOrigin(r)
+		}
+	}
+}
+
+// sameOrigin mirrors the websocket package's default origin check.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	_, host, ok := strings.Cut(origin, "://")
+	return ok && strings.EqualFold(host, r.Host)
+}
+
+// NewLiveFeed creates an empty feed.
+func NewLiveFeed(opts ...LiveFeedOption) *LiveFeed {
+	f := &LiveFeed{subs: make(map[*liveSub]struct{})}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Broadcast sends ev to every subscriber whose filter matches. Subscribers
+// that have fallen liveBuffer events behind miss it; Broadcast never blocks.
+func (f *LiveFeed) Broadcast(ev LiveEvent) {
+	if f == nil {
+		return
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for sub := range f.subs {
+		if !sub.wants(ev) {
+			continue
+		}
+		select {
+		case sub.events <- ev:
+		default:
+			slog.Debug("dlq live: subscriber too slow, dropping event", "dlq_id", ev.DLQID, "type", ev.Type)
+		}
+	}
+}
+
+func (f *LiveFeed) subscribe(filter LiveFilter, tenant string) *liveSub {
+	sub := &liveSub{filter: filter, tenant: tenant, events: make(chan LiveEvent, liveBuffer)}
+	f.mu.Lock()
+	f.subs[sub] = struct{}{}
+	f.mu.Unlock()
+	return sub
+}
+
+func (f *LiveFeed) unsubscribe(sub *liveSub) {
+	f.mu.Lock()
+	delete(f.subs, sub)
+	f.mu.Unlock()
+}
+
+// ServeHTTP upgrades the request to a WebSocket and streams matching events
+// as JSON text messages until the client disconnects. The initial filter
+// comes from ?reason=, ?source= and ?type= (comma-separated); the client
+// may replace it at any time by sending a LiveFilter as JSON. A request
+// scoped to a tenant (see ContextWithTenant) only receives its events.
+func (f *LiveFeed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := f.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written an error response.
+		return
+	}
+	defer conn.Close()
+
+	sub := f.subscribe(liveFilterFromQuery(r), TenantFromContext(r.Context()))
+	defer f.unsubscribe(sub)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var filter LiveFilter
+			if err := json.Unmarshal(data, &filter); err != nil {
+				slog.Debug("dlq live: ignoring invalid filter", "error", err)
+				continue
+			}
+			sub.setFilter(filter)
+		}
+	}()
+
+	ping := time.NewTicker(livePingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case ev := <-sub.events:
+			_ = conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			if err := conn.WriteJSON(ev); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveWriteTimeout)); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// WithLiveFeed mounts GET /live, a WebSocket that streams f's events, and
+// broadcasts retries and discards made through the API to f.
+func WithLiveFeed(f *LiveFeed) HandlerOption {
+	return func(h *Handler) { h.live = f }
+}
+
+// WithProcessorLiveFeed broadcasts every newly ingested entry to f.
+func WithProcessorLiveFeed(f *LiveFeed) ProcessorOption {
+	return func(p *Processor) { p.live = f }
+}
+
+// WithScannerLiveFeed broadcasts every entry the scanner retries to f.
+func WithScannerLiveFeed(f *LiveFeed) ScannerOption {
+	return func(s *Scanner) { s.live = f }
+}
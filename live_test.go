@@ -0,0 +1,144 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+)
+
+func (f *LiveFeed) subscribers() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.subs)
+}
+
+// dialLive connects to the feed's endpoint and waits until it has n
+// subscribers.
+func dialLive(t *testing.T, srv *httptest.Server, f *LiveFeed, query string, n int) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/dlq/live" + query
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	deadline := time.Now().Add(time.Second)
+	for f.subscribers() < n {
+		if time.Now().After(deadline) {
+			t.Fatal("subscriber never registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return conn
+}
+
+func readLive(t *testing.T, conn *websocket.Conn) LiveEvent {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	var ev LiveEvent
+	if err := conn.ReadJSON(&ev); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return ev
+}
+
+func TestLiveFeed_BroadcastsHandlerActions(t *testing.T) {
+	store := newMockStore()
+	store.seed(
+		Entry{DLQID: "lv-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonPolicyDenied, Source: SourceDispatch},
+		Entry{DLQID: "lv-2", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch},
+	)
+	feed := NewLiveFeed()
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, newMockNATS(), WithLiveFeed(feed)).Routes())
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	conn := dialLive(t, srv, feed, "?reason=no_capable_agent", 1)
+
+	for _, path := range []string{"/dlq/lv-1/discard", "/dlq/lv-2/discard"} {
+		req, _ := http.NewRequest("POST", srv.URL+path, nil)
+		req.Header.Set(ActorHeader, "alice")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("discard: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	ev := readLive(t, conn)
+	if ev.Type != LiveDiscarded || ev.DLQID != "lv-2" || ev.Actor != "alice" {
+		t.Errorf("expected only the filtered discard of lv-2, got %+v", ev)
+	}
+}
+
+func TestLiveFeed_ClientUpdatesFilter(t *testing.T) {
+	feed := NewLiveFeed()
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(newMockStore(), newMockNATS(), WithLiveFeed(feed)).Routes())
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	conn := dialLive(t, srv, feed, "", 1)
+	if err := conn.WriteJSON(LiveFilter{Sources: []string{SourceWarren}}); err != nil {
+		t.Fatalf("write filter: %v", err)
+	}
+	// The filter is applied asynchronously; keep broadcasting until a
+	// dispatch event stops getting through.
+	deadline := time.Now().Add(time.Second)
+	for {
+		feed.Broadcast(LiveEvent{Type: LiveIngested, DLQID: "probe", Source: SourceDispatch})
+		feed.Broadcast(LiveEvent{Type: LiveIngested, DLQID: "lv-w", Source: SourceWarren})
+		if ev := readLive(t, conn); ev.DLQID == "lv-w" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("filter update never applied")
+		}
+	}
+}
+
+func TestLiveFeed_ProcessorIngest(t *testing.T) {
+	feed := NewLiveFeed()
+	sub := feed.subscribe(LiveFilter{Types: []string{LiveIngested}}, "")
+	proc := NewProcessor(newMockStore(), WithProcessorLiveFeed(feed))
+
+	data, _ := json.Marshal(Entry{DLQID: "lv-p", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), FailedAt: time.Now()})
+	proc.Process(context.Background(), SubjectTaskUnassignable, data)
+
+	select {
+	case ev := <-sub.events:
+		if ev.DLQID != "lv-p" || ev.Type != LiveIngested {
+			t.Errorf("unexpected event %+v", ev)
+		}
+	default:
+		t.Fatal("expected an ingested event")
+	}
+}
+
+func TestLiveFeed_SlowSubscriberDoesNotBlock(t *testing.T) {
+	feed := NewLiveFeed()
+	feed.subscribe(LiveFilter{}, "")
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < liveBuffer*2; i++ {
+			feed.Broadcast(LiveEvent{DLQID: "x"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Broadcast blocked on a full subscriber")
+	}
+
+	var nilFeed *LiveFeed
+	nilFeed.Broadcast(LiveEvent{})
+}
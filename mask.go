@@ -0,0 +1,102 @@
+package dlq
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+)
+
+// ScopePayload grants access to unmasked entry payloads.
+const ScopePayload = "dlq:payload"
+
+// maskedValue replaces masked payload fields, or the whole payload.
+const maskedValue = `"***"`
+
+// ScopesFunc returns the scopes granted to the caller of r.
+type ScopesFunc func(r *http.Request) []string
+
+// PayloadMask hides payload contents from callers without ScopePayload while
+// leaving metadata, reason and retry history visible.
+type PayloadMask struct {
+	// Scopes resolves the caller's scopes. Required.
+	Scopes ScopesFunc
+	// Fields lists object keys whose values are masked, at any depth. With
+	// no fields the entire payload is masked.
+	Fields []string
+}
+
+// WithPayloadMask masks payloads returned by the read endpoints for callers
+// m.Scopes does not grant ScopePayload.
+func WithPayloadMask(m PayloadMask) HandlerOption {
+	return func(h *Handler) { h.mask = &m }
+}
+
+// applies reports whether r's caller must see masked payloads.
+func (m *PayloadMask) applies(r *http.Request) bool {
+	return m != nil && m.Scopes != nil && !slices.Contains(m.Scopes(r), ScopePayload)
+}
+
+// entry returns e with its payload and samples masked and any sealed
+// payload removed.
+func (m *PayloadMask) entry(e Entry) Entry {
+	e.OriginalPayload = m.payload(e.OriginalPayload)
+	e.SealedPayload = nil
+	if len(e.PayloadSamples) > 0 {
+		samples := make([]json.RawMessage, len(e.PayloadSamples))
+		for i, s := range e.PayloadSamples {
+			samples[i] = m.payload(s)
+		}
+		e.PayloadSamples = samples
+	}
+	return e
+}
+
+// payload masks the configured fields of p. Payloads that are not valid JSON
+// are masked entirely.
+func (m *PayloadMask) payload(p json.RawMessage) json.RawMessage {
+	if len(p) == 0 {
+		return p
+	}
+	if len(m.Fields) == 0 {
+		return json.RawMessage(maskedValue)
+	}
+	var doc any
+	if err := json.Unmarshal(p, &doc); err != nil {
+		return json.RawMessage(maskedValue)
+	}
+	out, err := json.Marshal(maskFields(doc, m.Fields))
+	if err != nil {
+		return json.RawMessage(maskedValue)
+	}
+	return out
+}
+
+func maskFields(v any, fields []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if slices.Contains(fields, k) {
+				v[k] = "***"
+				continue
+			}
+			v[k] = maskFields(child, fields)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = maskFields(child, fields)
+		}
+	}
+	return v
+}
+
+// readPayloads prepares entries for a read response: it masks them in place
+// for callers without ScopePayload, and otherwise records the payload access.
+func (h *Handler) readPayloads(r *http.Request, action AuditAction, entries []Entry) {
+	if h.mask.applies(r) {
+		for i := range entries {
+			entries[i] = h.mask.entry(entries[i])
+		}
+		return
+	}
+	h.auditPayloadAccess(r, action, entries...)
+}
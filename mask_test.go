@@ -0,0 +1,84 @@
+package dlq
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// scopesFromHeader grants the comma-separated scopes in X-Scopes.
+func scopesFromHeader(r *http.Request) []string {
+	return strings.Split(r.Header.Get("X-Scopes"), ",")
+}
+
+func TestPayloadMask_Payload(t *testing.T) {
+	m := &PayloadMask{Fields: []string{"email", "token"}}
+	cases := []struct{ name, in, want string }{
+		{"top level", `{"email":"a@b.c","task_id":"t1"}`, `{"email":"***","task_id":"t1"}`},
+		{"nested", `{"user":{"email":"a@b.c"},"items":[{"token":"x"}]}`, `{"items":[{"token":"***"}],"user":{"email":"***"}}`},
+		{"invalid json", `not json`, `"***"`},
+		{"empty", ``, ``},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := string(m.payload(json.RawMessage(tc.in))); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	whole := &PayloadMask{}
+	if got := string(whole.payload(json.RawMessage(`{"a":1}`))); got != `"***"` {
+		t.Errorf("expected whole payload masked, got %s", got)
+	}
+}
+
+func TestHandler_PayloadMask(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{
+		DLQID: "mk-1", Reason: ReasonNoCapableAgent,
+		OriginalPayload: json.RawMessage(`{"email":"a@b.c","task_id":"t1"}`),
+		RetryHistory:    []RetryAttempt{{Attempt: 1, Agent: "agent-1"}},
+	})
+	audit := &memAudit{}
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, newMockNATS(),
+		WithPayloadMask(PayloadMask{Scopes: scopesFromHeader, Fields: []string{"email"}}),
+		WithAuditRecorder(audit),
+	).Routes())
+
+	req := httptest.NewRequest("GET", "/dlq/mk-1", nil)
+	req.Header.Set("X-Scopes", "dlq:read")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var viewer Entry
+	_ = json.NewDecoder(w.Body).Decode(&viewer)
+	if string(viewer.OriginalPayload) != `{"email":"***","task_id":"t1"}` {
+		t.Errorf("expected masked payload, got %s", viewer.OriginalPayload)
+	}
+	if viewer.Reason != ReasonNoCapableAgent || len(viewer.RetryHistory) != 1 {
+		t.Errorf("metadata must stay visible, got %+v", viewer)
+	}
+	if len(audit.records) != 0 {
+		t.Error("a masked read is not a payload access")
+	}
+
+	req = httptest.NewRequest("GET", "/dlq/?limit=10", nil)
+	req.Header.Set("X-Scopes", "dlq:read,"+ScopePayload)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var full []Entry
+	_ = json.NewDecoder(w.Body).Decode(&full)
+	if len(full) != 1 || !strings.Contains(string(full[0].OriginalPayload), "a@b.c") {
+		t.Errorf("expected unmasked payload with %s, got %+v", ScopePayload, full)
+	}
+	if e, _ := store.Get(req.Context(), "mk-1"); !strings.Contains(string(e.OriginalPayload), "a@b.c") {
+		t.Error("masking must not modify stored entries")
+	}
+}
@@ -0,0 +1,117 @@
+package dlq
+
+import "sync"
+
+// Metric names recorded by the DLQ components.
+const (
+	MetricProcessorQueueDepth      = "processor_queue_depth"
+	MetricProcessorRejected        = "processor_rejected_total"
+	MetricProcessorQuotaDropped    = "processor_quota_dropped_total"
+	MetricProcessorCollapsed       = "processor_collapsed_total"
+	MetricProcessorPayloadsOmitted = "processor_payloads_omitted_total"
+	MetricProcessorPayloadTooLarge = "processor_payload_too_large_total"
+	MetricProcessorDuplicates      = "processor_duplicates_total"
+	MetricProcessorDeduplicated    = "processor_deduplicated_total"
+	MetricProcessorUnknownFailures = "processor_unknown_failure_reasons_total"
+	MetricProcessorUnknownReasons  = "processor_unknown_reasons_total"
+	MetricProcessorParked          = "processor_parked_total"
+	MetricProcessorEnrichFailures  = "processor_enrich_failures_total"
+	MetricProcessorRedacted        = "processor_redacted_total"
+	MetricProcessorRepeats         = "processor_repeats_collapsed_total"
+	MetricPublisherPayloadTooLarge = "publisher_payload_too_large_total"
+	MetricPublisherRetries         = "publisher_retries_total"
+	MetricPublisherBuffered        = "publisher_buffered_total"
+	MetricPublisherFlushed         = "publisher_flushed_total"
+	MetricPublisherQueueDepth      = "publisher_queue_depth"
+	MetricPublisherDropped         = "publisher_dropped_total"
+	MetricBreakerOpen              = "nats_breaker_open"
+	MetricBreakerRejected          = "nats_breaker_rejected_total"
+	MetricReplicationSent          = "replication_sent_total"
+	MetricReplicationFailed        = "replication_failed_total"
+	MetricReplicationDropped       = "replication_dropped_total"
+	MetricJanitorDeleted           = "janitor_deleted_total"
+	MetricJanitorArchived          = "janitor_archived_total"
+	MetricScannerRuns              = "scanner_runs_total"
+	MetricScannerConsidered        = "scanner_considered_total"
+	MetricScannerRetried           = "scanner_retried_total"
+	MetricScannerFailed            = "scanner_failed_total"
+	MetricScannerSkipped           = "scanner_skipped_total"
+	MetricScannerLastDurationMS    = "scanner_last_duration_ms"
+	MetricOutboxPublished          = "outbox_published_total"
+	MetricOutboxFailed             = "outbox_failed_total"
+)
+
+// Connection pool gauges recorded by a Store with WithStoreMetrics. Per-method
+// counters are named store_<method>_calls_total, store_<method>_errors_total
+// and store_<method>_duration_us_total (e.g. store_insert_calls_total).
+const (
+	MetricStorePoolAcquiredConns = "store_pool_acquired_conns"
+	MetricStorePoolIdleConns     = "store_pool_idle_conns"
+	MetricStorePoolTotalConns    = "store_pool_total_conns"
+	MetricStorePoolMaxConns      = "store_pool_max_conns"
+	MetricStorePoolAcquireCount  = "store_pool_acquire_total"
+	MetricStorePoolEmptyAcquires = "store_pool_empty_acquire_total"
+	MetricStorePoolAcquireWaitUS = "store_pool_acquire_wait_us_total"
+)
+
+// Metrics is a minimal in-process registry of named counters and gauges.
+// Hosting services can export a Snapshot to whatever monitoring system they use.
+// A nil *Metrics is valid and discards all updates.
+type Metrics struct {
+	mu     sync.Mutex
+	values map[string]int64
+}
+
+// NewMetrics creates an empty metrics registry.
+func NewMetrics() *Metrics {
+	return &Metrics{values: make(map[string]int64)}
+}
+
+// Inc increments a counter by one.
+func (m *Metrics) Inc(name string) {
+	m.Add(name, 1)
+}
+
+// Add adds delta to a counter.
+func (m *Metrics) Add(name string, delta int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[name] += delta
+}
+
+// Set sets a gauge to an absolute value.
+func (m *Metrics) Set(name string, v int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[name] = v
+}
+
+// Get returns the current value of a counter or gauge.
+func (m *Metrics) Get(name string) int64 {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[name]
+}
+
+// Snapshot returns a copy of all current values.
+func (m *Metrics) Snapshot() map[string]int64 {
+	out := make(map[string]int64)
+	if m == nil {
+		return out
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, v := range m.values {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,62 @@
+package dlq
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestMetrics_CountersAndGauges(t *testing.T) {
+	m := NewMetrics()
+	m.Inc("a")
+	m.Add("a", 2)
+	m.Set("g", 7)
+	m.Set("g", 4)
+
+	if got := m.Get("a"); got != 3 {
+		t.Errorf("expected a=3, got %d", got)
+	}
+	snap := m.Snapshot()
+	if snap["g"] != 4 {
+		t.Errorf("expected g=4, got %d", snap["g"])
+	}
+
+	// Snapshot is a copy.
+	snap["a"] = 100
+	if m.Get("a") != 3 {
+		t.Error("snapshot mutation leaked into registry")
+	}
+}
+
+func TestMetrics_NilIsNoop(t *testing.T) {
+	var m *Metrics
+	m.Inc("a")
+	m.Set("g", 1)
+	if m.Get("a") != 0 {
+		t.Error("expected 0 from nil metrics")
+	}
+	if len(m.Snapshot()) != 0 {
+		t.Error("expected empty snapshot from nil metrics")
+	}
+}
+
+func TestStore_ObserveRecordsMethodMetrics(t *testing.T) {
+	m := NewMetrics()
+	s := NewStore(nil, WithStoreMetrics(m))
+
+	var err error
+	s.observe("get", time.Now(), &err)
+	err = pgx.ErrNoRows
+	s.observe("get", time.Now(), &err)
+	err = fmt.Errorf("connection refused")
+	s.observe("get", time.Now(), &err)
+
+	if got := m.Get("store_get_calls_total"); got != 3 {
+		t.Errorf("expected 3 calls, got %d", got)
+	}
+	if got := m.Get("store_get_errors_total"); got != 1 {
+		t.Errorf("expected not-found to be excluded from errors, got %d", got)
+	}
+}
@@ -0,0 +1,6 @@
+-- Storm collapsing: near-identical events folded into one entry.
+
+alter table swarm_dlq
+  add column if not exists occurrences     int not null default 1,
+  add column if not exists last_seen_at    timestamptz,
+  add column if not exists payload_samples jsonb default '[]';
@@ -0,0 +1,5 @@
+-- Adaptive sampling: payloads not kept while the Processor is overloaded.
+
+alter table swarm_dlq
+  add column if not exists payload_omitted boolean not null default false,
+  add column if not exists sample_rate     int not null default 0;
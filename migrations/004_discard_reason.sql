@@ -0,0 +1,5 @@
+-- Discard context: why an operator discarded an entry.
+
+alter table swarm_dlq
+  add column if not exists discard_reason text,
+  add column if not exists discard_note   text;
@@ -0,0 +1,7 @@
+-- Payload size limits: payloads over the limit are truncated and optionally
+-- offloaded to blob storage.
+
+alter table swarm_dlq
+  add column if not exists payload_truncated boolean not null default false,
+  add column if not exists payload_size      int not null default 0,
+  add column if not exists payload_ref       text;
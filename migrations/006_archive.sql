@@ -0,0 +1,11 @@
+-- Archive: recovered and expired entries moved out of the hot table.
+-- Columns added to swarm_dlq by later migrations must be added here too.
+
+create table if not exists swarm_dlq_archive (like swarm_dlq including defaults);
+
+alter table swarm_dlq_archive
+  add column if not exists archived_at timestamptz not null default now();
+
+create unique index if not exists idx_dlq_archive_id        on swarm_dlq_archive (dlq_id);
+create index if not exists idx_dlq_archive_failed_at       on swarm_dlq_archive (failed_at desc);
+create index if not exists idx_dlq_archive_reason          on swarm_dlq_archive (reason);
@@ -0,0 +1,9 @@
+-- Claims: short operator leases that block retry/discard by anyone else.
+
+alter table swarm_dlq
+  add column if not exists claimed_by       text,
+  add column if not exists claim_expires_at timestamptz;
+
+alter table swarm_dlq_archive
+  add column if not exists claimed_by       text,
+  add column if not exists claim_expires_at timestamptz;
@@ -0,0 +1,8 @@
+-- Confirmed retries: the receiver's acknowledgment (or timeout) of the last
+-- retry sent as a NATS request.
+
+alter table swarm_dlq
+  add column if not exists retry_ack jsonb;
+
+alter table swarm_dlq_archive
+  add column if not exists retry_ack jsonb;
@@ -0,0 +1,15 @@
+-- Publisher provenance: which service, version and instance produced an entry.
+
+alter table swarm_dlq
+  add column if not exists producer_service text,
+  add column if not exists producer_version text,
+  add column if not exists producer_host    text,
+  add column if not exists producer_pid     int not null default 0;
+
+alter table swarm_dlq_archive
+  add column if not exists producer_service text,
+  add column if not exists producer_version text,
+  add column if not exists producer_host    text,
+  add column if not exists producer_pid     int not null default 0;
+
+create index if not exists idx_dlq_producer on swarm_dlq (producer_service, producer_version);
@@ -0,0 +1,7 @@
+-- Metadata: external context attached to entries at ingest by enrichers.
+
+alter table swarm_dlq
+  add column if not exists metadata jsonb;
+
+alter table swarm_dlq_archive
+  add column if not exists metadata jsonb;
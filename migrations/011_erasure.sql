@@ -0,0 +1,7 @@
+-- Compliance erasure: entries whose payload was scrubbed on request.
+
+alter table swarm_dlq
+  add column if not exists payload_erased boolean not null default false;
+
+alter table swarm_dlq_archive
+  add column if not exists payload_erased boolean not null default false;
@@ -0,0 +1,22 @@
+-- Explicit lifecycle status, replacing the recovered + recovered_by =
+-- 'manual-discard' convention. recovered is kept for existing filters.
+
+alter table swarm_dlq
+  add column if not exists status text not null default 'pending';
+
+alter table swarm_dlq_archive
+  add column if not exists status text not null default 'pending';
+
+update swarm_dlq set status = case
+    when recovered_by = 'manual-discard' then 'discarded'
+    else 'recovered'
+  end
+  where recovered = true and status = 'pending';
+
+update swarm_dlq_archive set status = case
+    when recovered_by = 'manual-discard' then 'discarded'
+    else 'recovered'
+  end
+  where recovered = true and status = 'pending';
+
+create index if not exists idx_dlq_status     on swarm_dlq (status);
@@ -0,0 +1,20 @@
+-- First-class discards: discarded_at/discarded_by instead of recovered_at and
+-- recovered_by = 'manual-discard', so discards no longer count as recoveries.
+
+alter table swarm_dlq
+  add column if not exists discarded_at timestamptz,
+  add column if not exists discarded_by text;
+
+alter table swarm_dlq_archive
+  add column if not exists discarded_at timestamptz,
+  add column if not exists discarded_by text;
+
+update swarm_dlq
+  set discarded_at = recovered_at, discarded_by = recovered_by,
+      recovered_at = null, recovered_by = null
+  where status = 'discarded' and discarded_at is null;
+
+update swarm_dlq_archive
+  set discarded_at = recovered_at, discarded_by = recovered_by,
+      recovered_at = null, recovered_by = null
+  where status = 'discarded' and discarded_at is null;
@@ -0,0 +1,16 @@
+-- Audit trail of actions on DLQ entries (retries, discards, replays, scanner
+-- recoveries, ingest, payload reads). No foreign key: the trail outlives
+-- archived, erased and deleted entries.
+
+create table if not exists swarm_dlq_audit (
+  id      bigserial primary key,
+  dlq_id  text not null,
+  actor   text not null default '',
+  action  text not null,
+  result  text,
+  detail  text,
+  at      timestamptz not null default now()
+);
+
+create index if not exists idx_dlq_audit_dlq_id on swarm_dlq_audit (dlq_id, at);
+create index if not exists idx_dlq_audit_at     on swarm_dlq_audit (at desc);
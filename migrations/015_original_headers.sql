@@ -0,0 +1,8 @@
+-- Original headers: the failed message's NATS headers, republished on retry
+-- and replay.
+
+alter table swarm_dlq
+  add column if not exists original_headers jsonb;
+
+alter table swarm_dlq_archive
+  add column if not exists original_headers jsonb;
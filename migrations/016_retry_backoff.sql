@@ -0,0 +1,13 @@
+-- Per-entry backoff: failed automatic retries and when the scanner may try
+-- again.
+
+alter table swarm_dlq
+  add column if not exists auto_retry_count int not null default 0,
+  add column if not exists next_retry_at timestamptz;
+
+alter table swarm_dlq_archive
+  add column if not exists auto_retry_count int not null default 0,
+  add column if not exists next_retry_at timestamptz;
+
+create index if not exists idx_dlq_next_retry on swarm_dlq (next_retry_at)
+  where recoverable = true and recovered = false;
@@ -0,0 +1,8 @@
+-- Expiry: recoverable entries the scanner did not recover within the
+-- recovery window move to status 'expired' at expired_at.
+
+alter table swarm_dlq
+  add column if not exists expired_at timestamptz;
+
+alter table swarm_dlq_archive
+  add column if not exists expired_at timestamptz;
@@ -0,0 +1,10 @@
+-- Redaction: payload_redacted marks payloads whose sensitive fields were
+-- masked at ingest; sealed_payload keeps the encrypted original, if any.
+
+alter table swarm_dlq
+  add column if not exists payload_redacted boolean not null default false,
+  add column if not exists sealed_payload bytea;
+
+alter table swarm_dlq_archive
+  add column if not exists payload_redacted boolean not null default false,
+  add column if not exists sealed_payload bytea;
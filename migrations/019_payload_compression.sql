@@ -0,0 +1,12 @@
+-- Payload compression: large payloads may be stored compressed in
+-- payload_compressed (codec in payload_encoding) with original_payload NULL.
+
+alter table swarm_dlq
+  alter column original_payload drop not null,
+  add column if not exists payload_compressed bytea,
+  add column if not exists payload_encoding text;
+
+alter table swarm_dlq_archive
+  alter column original_payload drop not null,
+  add column if not exists payload_compressed bytea,
+  add column if not exists payload_encoding text;
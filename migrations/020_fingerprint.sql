@@ -0,0 +1,11 @@
+-- Fingerprints: repeats of the same failure (subject, reason and payload)
+-- share a fingerprint, used to collapse or group them.
+
+alter table swarm_dlq
+  add column if not exists fingerprint text;
+
+alter table swarm_dlq_archive
+  add column if not exists fingerprint text;
+
+create index if not exists idx_dlq_fingerprint on swarm_dlq (fingerprint, failed_at desc)
+  where fingerprint is not null;
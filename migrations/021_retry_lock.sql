@@ -0,0 +1,11 @@
+-- Retry locking: a retry moves the entry to status 'retrying' for a lease
+-- starting at retry_started_at, remembering the status to go back to if it
+-- does not publish.
+
+alter table swarm_dlq
+  add column if not exists retry_started_at    timestamptz,
+  add column if not exists status_before_retry text;
+
+alter table swarm_dlq_archive
+  add column if not exists retry_started_at    timestamptz,
+  add column if not exists status_before_retry text;
@@ -0,0 +1,18 @@
+-- Recovery outbox: a recovery marks the entry recovered and records the
+-- message to republish in one transaction. Rows are deleted once published;
+-- the relay retries the rest. No foreign key: a recovered entry may be
+-- archived or deleted before its republish goes out.
+
+create table if not exists swarm_dlq_outbox (
+  id            bigserial primary key,
+  dlq_id        text not null,
+  subject       text not null,
+  payload       bytea,
+  headers       jsonb,
+  recovered_by  text not null default '',
+  created_at    timestamptz not null default now(),
+  attempts      int not null default 0,
+  last_error    text
+);
+
+create index if not exists idx_dlq_outbox_created_at on swarm_dlq_outbox (created_at);
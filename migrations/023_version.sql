@@ -0,0 +1,27 @@
+-- Optimistic concurrency: version counts changes to an entry's state
+-- (status, claim, metadata) and updated_at records the last write of any
+-- kind. Conditional updates compare version to detect concurrent changes.
+-- Bookkeeping writes (occurrence counts, retry acks, backoff) leave version
+-- alone so they do not invalidate an operator's read.
+
+alter table swarm_dlq
+  add column if not exists version    int not null default 1,
+  add column if not exists updated_at timestamptz;
+
+alter table swarm_dlq_archive
+  add column if not exists version    int not null default 1,
+  add column if not exists updated_at timestamptz;
+
+create or replace function swarm_dlq_touch() returns trigger as $$
+begin
+  new.updated_at := now();
+  if (new.status, new.claimed_by, new.metadata) is distinct from (old.status, old.claimed_by, old.metadata) then
+    new.version := old.version + 1;
+  end if;
+  return new;
+end;
+$$ language plpgsql;
+
+drop trigger if exists swarm_dlq_touch on swarm_dlq;
+create trigger swarm_dlq_touch before update on swarm_dlq
+  for each row execute function swarm_dlq_touch();
@@ -0,0 +1,13 @@
+-- Idempotency keys for retry, discard and retry-all. A row with a null
+-- status is a request still running; completed rows hold the response that
+-- repeats of the request get back until they expire.
+
+create table if not exists swarm_dlq_idempotency (
+  key         text primary key,
+  request     text not null,
+  status      int,
+  body        bytea,
+  created_at  timestamptz not null default now()
+);
+
+create index if not exists idx_dlq_idempotency_created_at on swarm_dlq_idempotency (created_at);
@@ -0,0 +1,16 @@
+-- Operator notes on entries. Edits to notes count as state changes, so the
+-- touch trigger now bumps version for them too.
+
+alter table swarm_dlq add column if not exists notes text;
+alter table swarm_dlq_archive add column if not exists notes text;
+
+create or replace function swarm_dlq_touch() returns trigger as $$
+begin
+  new.updated_at := now();
+  if (new.status, new.claimed_by, new.metadata, new.notes)
+     is distinct from (old.status, old.claimed_by, old.metadata, old.notes) then
+    new.version := old.version + 1;
+  end if;
+  return new;
+end;
+$$ language plpgsql;
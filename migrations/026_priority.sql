@@ -0,0 +1,7 @@
+-- Entry priority: automated recovery takes higher priorities first, and
+-- listings can sort by it.
+
+alter table swarm_dlq add column if not exists priority int not null default 0;
+alter table swarm_dlq_archive add column if not exists priority int not null default 0;
+
+create index if not exists idx_dlq_priority on swarm_dlq (priority desc, failed_at) where recovered = false;
@@ -0,0 +1,18 @@
+-- Triage assignment. Assigning counts as a state change, so the touch
+-- trigger bumps version for it too.
+
+alter table swarm_dlq add column if not exists assigned_to text;
+alter table swarm_dlq_archive add column if not exists assigned_to text;
+
+create index if not exists idx_dlq_assigned_to on swarm_dlq (assigned_to) where assigned_to is not null;
+
+create or replace function swarm_dlq_touch() returns trigger as $$
+begin
+  new.updated_at := now();
+  if (new.status, new.claimed_by, new.metadata, new.notes, new.assigned_to)
+     is distinct from (old.status, old.claimed_by, old.metadata, old.notes, old.assigned_to) then
+    new.version := old.version + 1;
+  end if;
+  return new;
+end;
+$$ language plpgsql;
@@ -0,0 +1,7 @@
+-- Task identifier copied from the payload at ingest, so entries for one task
+-- can be found without scanning original_payload.
+
+alter table swarm_dlq add column if not exists task_id text;
+alter table swarm_dlq_archive add column if not exists task_id text;
+
+create index if not exists idx_dlq_task_id on swarm_dlq (task_id) where task_id is not null;
@@ -0,0 +1,8 @@
+-- Scheduled retries: the scanner retries an entry once retry_at has passed.
+
+alter table swarm_dlq add column if not exists retry_at timestamptz;
+alter table swarm_dlq add column if not exists retry_scheduled_by text;
+alter table swarm_dlq_archive add column if not exists retry_at timestamptz;
+alter table swarm_dlq_archive add column if not exists retry_scheduled_by text;
+
+create index if not exists idx_dlq_retry_at on swarm_dlq (retry_at) where retry_at is not null and recovered = false;
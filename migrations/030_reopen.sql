@@ -0,0 +1,7 @@
+-- Reopening recovered, discarded or expired entries. A reopened entry's
+-- recovery window runs from reopened_at.
+
+alter table swarm_dlq add column if not exists reopened_at timestamptz;
+alter table swarm_dlq add column if not exists reopened_by text;
+alter table swarm_dlq_archive add column if not exists reopened_at timestamptz;
+alter table swarm_dlq_archive add column if not exists reopened_by text;
@@ -0,0 +1,8 @@
+-- Multi-tenancy for swarm environments sharing one database. The empty
+-- tenant is the default for single-tenant deployments and existing rows.
+
+alter table swarm_dlq add column if not exists tenant_id text not null default '';
+alter table swarm_dlq_archive add column if not exists tenant_id text not null default '';
+alter table swarm_dlq_audit add column if not exists tenant_id text not null default '';
+
+create index if not exists idx_dlq_tenant on swarm_dlq (tenant_id, failed_at desc);
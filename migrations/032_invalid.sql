@@ -0,0 +1,13 @@
+-- Parking table for DLQ events the Processor could parse but not store
+-- (missing required fields, unknown reason), kept with their raw bytes.
+
+create table if not exists swarm_dlq_invalid (
+  id           bigserial primary key,
+  subject      text not null,
+  data         bytea not null,
+  error        text not null,
+  tenant_id    text not null default '',
+  received_at  timestamptz not null default now()
+);
+
+create index if not exists idx_dlq_invalid_received_at on swarm_dlq_invalid (tenant_id, received_at desc);
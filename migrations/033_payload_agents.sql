@@ -0,0 +1,9 @@
+-- Agent filter for compressed payloads: payload_agents holds the agent and
+-- agent_id values of a payload stored in payload_compressed, which SQL
+-- cannot read.
+
+alter table swarm_dlq
+  add column if not exists payload_agents text[];
+
+alter table swarm_dlq_archive
+  add column if not exists payload_agents text[];
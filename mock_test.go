@@ -2,56 +2,119 @@ package dlq
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"sort"
 	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 // mockStore is a thread-safe in-memory DataStore for unit tests.
 type mockStore struct {
-	mu      sync.Mutex
-	entries map[string]*Entry
+	mu       sync.Mutex
+	entries  map[string]*Entry
+	archived map[string]*Entry
 
-	insertErr   error
-	getErr      error
-	listErr     error
-	recoverErr  error
-	statsErr    error
+	insertErr  error
+	batchErr   error
+	getErr     error
+	listErr    error
+	recoverErr error
+	statsErr   error
+
+	// beforeRetry holds the status BeginRetry replaced, by dlq_id.
+	beforeRetry map[string]string
+	// outbox holds unpublished recoveries, in EnqueueRecovery order.
+	outbox    []OutboxRecord
+	outboxSeq int64
+	// idempotency holds reserved and completed idempotency keys.
+	idempotency map[string]*mockIdempotencyKey
 
 	insertCalls  int
+	batchCalls   int
 	recoverCalls int
 }
 
+// eventJSON marshals e as a DLQ event, filling in the original_subject and
+// failed_at the Processor requires when a test does not care about them.
+func eventJSON(e Entry) []byte {
+	if e.OriginalSubject == "" {
+		e.OriginalSubject = "swarm.task.request"
+	}
+	if e.FailedAt.IsZero() {
+		e.FailedAt = time.Now().UTC()
+	}
+	data, _ := json.Marshal(e)
+	return data
+}
+
 func newMockStore() *mockStore {
-	return &mockStore{entries: make(map[string]*Entry)}
+	return &mockStore{entries: make(map[string]*Entry), archived: make(map[string]*Entry)}
 }
 
-func (m *mockStore) Insert(_ context.Context, e Entry) error {
+func (m *mockStore) Insert(ctx context.Context, e Entry) (bool, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.insertCalls++
 	if m.insertErr != nil {
-		return m.insertErr
+		return false, m.insertErr
+	}
+	if e.TenantID == "" {
+		e.TenantID = TenantFromContext(ctx)
+	}
+	return m.put(e), nil
+}
+
+func (m *mockStore) InsertBatch(_ context.Context, entries []Entry) ([]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batchCalls++
+	if m.batchErr != nil {
+		return nil, m.batchErr
+	}
+	created := make([]bool, len(entries))
+	for i, e := range entries {
+		created[i] = m.put(e)
+	}
+	return created, nil
+}
+
+// put mirrors ON CONFLICT DO NOTHING. Callers must hold m.mu.
+func (m *mockStore) put(e Entry) bool {
+	if _, ok := m.entries[e.DLQID]; ok {
+		return false
 	}
 	cp := e
+	cp.Status = initialStatus(e)
+	cp.Version = 1
 	m.entries[e.DLQID] = &cp
-	return nil
+	return true
+}
+
+func (m *mockStore) batches() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.batchCalls
 }
 
-func (m *mockStore) Get(_ context.Context, dlqID string) (*Entry, error) {
+func (m *mockStore) Get(ctx context.Context, dlqID string) (*Entry, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if m.getErr != nil {
 		return nil, m.getErr
 	}
 	e, ok := m.entries[dlqID]
-	if !ok {
-		return nil, fmt.Errorf("not found: %s", dlqID)
+	if !ok || !tenantMatches(ctx, e) {
+		return nil, fmt.Errorf("not found: %s: %w", dlqID, pgx.ErrNoRows)
 	}
 	cp := *e
 	return &cp, nil
 }
 
-func (m *mockStore) List(_ context.Context, opts ListOpts) ([]Entry, error) {
+func (m *mockStore) List(ctx context.Context, opts ListOpts) ([]Entry, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if m.listErr != nil {
@@ -59,28 +122,228 @@ func (m *mockStore) List(_ context.Context, opts ListOpts) ([]Entry, error) {
 	}
 	var result []Entry
 	for _, e := range m.entries {
-		if opts.Recovered != nil && e.Recovered != *opts.Recovered {
+		if !listMatches(e, opts) || !tenantMatches(ctx, e) {
 			continue
 		}
-		if opts.Reason != "" && e.Reason != opts.Reason {
+		result = append(result, *e)
+	}
+	if opts.Sort != "" {
+		sort.SliceStable(result, func(i, j int) bool { return listLess(opts.Sort, &result[i], &result[j]) })
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (m *mockStore) Export(_ context.Context, opts ListOpts, fn func(Entry) error) error {
+	m.mu.Lock()
+	if m.listErr != nil {
+		m.mu.Unlock()
+		return m.listErr
+	}
+	var result []Entry
+	for _, e := range m.entries {
+		if listMatches(e, opts) {
+			result = append(result, *e)
+		}
+	}
+	m.mu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].FailedAt.Equal(result[j].FailedAt) {
+			return result[i].FailedAt.Before(result[j].FailedAt)
+		}
+		return result[i].DLQID < result[j].DLQID
+	})
+	if opts.Limit > 0 && len(result) > opts.Limit {
+		result = result[:opts.Limit]
+	}
+	for _, e := range result {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockStore) Count(_ context.Context, opts ListOpts) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.listErr != nil {
+		return 0, m.listErr
+	}
+	n := 0
+	for _, e := range m.entries {
+		if listMatches(e, opts) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// listMatches mirrors the Store's list filters.
+// tenantMatches mirrors the Store's tenant scoping for Get and List.
+func tenantMatches(ctx context.Context, e *Entry) bool {
+	t := TenantFromContext(ctx)
+	return t == "" || e.TenantID == t
+}
+
+func listMatches(e *Entry, opts ListOpts) bool {
+	return (opts.Recovered == nil || e.Recovered == *opts.Recovered) &&
+		(opts.Status == "" || e.Status == opts.Status) &&
+		(opts.Reason == "" || e.Reason == opts.Reason) &&
+		(opts.Source == "" || e.Source == opts.Source) &&
+		producerMatches(e, opts) &&
+		(opts.Fingerprint == "" || e.Fingerprint == opts.Fingerprint) &&
+		(opts.AssignedTo == "" || e.AssignedTo == opts.AssignedTo) &&
+		(opts.TaskID == "" || e.TaskID == opts.TaskID) &&
+		(opts.Agent == "" || referencesAgent(e, opts.Agent)) &&
+		(opts.FailedAfter.IsZero() || e.FailedAt.After(opts.FailedAfter))
+}
+
+// referencesAgent mirrors the Store's agent filter.
+func referencesAgent(e *Entry, agent string) bool {
+	for _, a := range e.RetryHistory {
+		if a.Agent == agent {
+			return true
+		}
+	}
+	var p struct {
+		Agent   string `json:"agent"`
+		AgentID string `json:"agent_id"`
+	}
+	_ = json.Unmarshal(e.OriginalPayload, &p)
+	return p.Agent == agent || p.AgentID == agent
+}
+
+func (m *mockStore) Timeseries(_ context.Context, opts TimeseriesOpts) (*Timeseries, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.statsErr != nil {
+		return nil, m.statsErr
+	}
+	group := func(e *Entry) string {
+		switch opts.GroupBy {
+		case "reason":
+			return e.Reason
+		case "source":
+			return e.Source
+		}
+		return ""
+	}
+	var rows []timeseriesRow
+	for _, e := range m.entries {
+		rows = append(rows, timeseriesRow{start: bucketStart(e.FailedAt, opts.Bucket), group: group(e), ingested: 1})
+		if e.RecoveredAt != nil {
+			rows = append(rows, timeseriesRow{start: bucketStart(*e.RecoveredAt, opts.Bucket), group: group(e), recovered: 1})
+		}
+	}
+	return buildTimeseries(opts, time.Now().UTC(), rows), nil
+}
+
+func (m *mockStore) RecordFingerprint(_ context.Context, fingerprint, dlqID string, lastSeen time.Time) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var newest *Entry
+	for _, e := range m.entries {
+		switch {
+		case e.Fingerprint != fingerprint,
+			e.Status == StatusRecovered, e.Status == StatusDiscarded, e.Status == StatusExpired:
 			continue
 		}
-		if opts.Source != "" && e.Source != opts.Source {
+		if newest == nil || e.FailedAt.After(newest.FailedAt) {
+			newest = e
+		}
+	}
+	if newest == nil || newest.DLQID == dlqID {
+		return "", nil
+	}
+	if newest.Occurrences < 1 {
+		newest.Occurrences = 1
+	}
+	newest.Occurrences++
+	ls := lastSeen
+	newest.LastSeenAt = &ls
+	return newest.DLQID, nil
+}
+
+func (m *mockStore) ListFingerprintGroups(_ context.Context, opts ListOpts) ([]FingerprintGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.listErr != nil {
+		return nil, m.listErr
+	}
+	byFP := map[string]*FingerprintGroup{}
+	latest := map[string]time.Time{}
+	for _, e := range m.entries {
+		if e.Fingerprint == "" || !listMatches(e, opts) {
 			continue
 		}
-		result = append(result, *e)
-		limit := opts.Limit
-		if limit <= 0 {
-			limit = 50
+		g := byFP[e.Fingerprint]
+		if g == nil {
+			g = &FingerprintGroup{Fingerprint: e.Fingerprint, OriginalSubject: e.OriginalSubject,
+				Reason: e.Reason, FirstFailedAt: e.FailedAt}
+			byFP[e.Fingerprint] = g
 		}
-		if len(result) >= limit {
-			break
+		g.Entries++
+		g.Occurrences += max(e.Occurrences, 1)
+		if e.FailedAt.Before(g.FirstFailedAt) {
+			g.FirstFailedAt = e.FailedAt
+		}
+		seen := e.FailedAt
+		if e.LastSeenAt != nil && e.LastSeenAt.After(seen) {
+			seen = *e.LastSeenAt
+		}
+		if seen.After(g.LastSeenAt) {
+			g.LastSeenAt = seen
+		}
+		if e.FailedAt.After(latest[e.Fingerprint]) || g.LatestDLQID == "" {
+			latest[e.Fingerprint] = e.FailedAt
+			g.LatestDLQID = e.DLQID
 		}
 	}
-	return result, nil
+	groups := []FingerprintGroup{}
+	for _, g := range byFP {
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].LastSeenAt.After(groups[j].LastSeenAt) })
+	return groups, nil
+}
+
+func (m *mockStore) RecordOccurrences(_ context.Context, dlqID string, n int, lastSeen time.Time, samples []json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[dlqID]
+	if !ok {
+		return fmt.Errorf("not found: %s", dlqID)
+	}
+	if e.Occurrences < 1 {
+		e.Occurrences = 1
+	}
+	e.Occurrences += n
+	ls := lastSeen
+	e.LastSeenAt = &ls
+	e.PayloadSamples = append(e.PayloadSamples, samples...)
+	return nil
 }
 
 func (m *mockStore) MarkRecovered(_ context.Context, dlqID, recoveredBy string) error {
+	return m.markRecovered(dlqID, recoveredBy, 0)
+}
+
+func (m *mockStore) MarkRecoveredIfVersion(_ context.Context, dlqID, recoveredBy string, version int) error {
+	return m.markRecovered(dlqID, recoveredBy, version)
+}
+
+func (m *mockStore) markRecovered(dlqID, recoveredBy string, version int) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.recoverCalls++
@@ -91,14 +354,236 @@ func (m *mockStore) MarkRecovered(_ context.Context, dlqID, recoveredBy string)
 	if !ok {
 		return fmt.Errorf("not found: %s", dlqID)
 	}
+	if err := versionMismatch(e, version); err != nil {
+		return err
+	}
 	if e.Recovered {
 		return fmt.Errorf("already recovered: %s", dlqID)
 	}
 	e.Recovered = true
 	e.RecoveredBy = recoveredBy
+	e.Status = StatusRecovered
+	e.RetryStartedAt = nil
+	e.Version++
+	return nil
+}
+
+func (m *mockStore) MarkDiscarded(_ context.Context, dlqID, discardedBy string, opts DiscardOpts) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recoverCalls++
+	if m.recoverErr != nil {
+		return m.recoverErr
+	}
+	e, ok := m.entries[dlqID]
+	if !ok {
+		return fmt.Errorf("not found: %s", dlqID)
+	}
+	if err := versionMismatch(e, opts.Version); err != nil {
+		return err
+	}
+	if e.Recovered {
+		return fmt.Errorf("already recovered: %s", dlqID)
+	}
+	if e.claimedByOther(opts.Actor, time.Now()) {
+		return ErrEntryClaimed
+	}
+	now := time.Now().UTC()
+	e.Version++
+	e.Recovered = true
+	e.DiscardedAt = &now
+	e.DiscardedBy = discardedBy
+	e.Status = StatusDiscarded
+	e.DiscardReason = opts.Reason
+	e.DiscardNote = opts.Note
 	return nil
 }
 
+func (m *mockStore) Reopen(_ context.Context, dlqID, by string, version int) (*Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[dlqID]
+	if !ok {
+		return nil, fmt.Errorf("reopen dlq entry %s: %w", dlqID, pgx.ErrNoRows)
+	}
+	if err := versionMismatch(e, version); err != nil {
+		return nil, err
+	}
+	if !e.Recovered {
+		return nil, ErrNotRecovered
+	}
+	now := time.Now().UTC()
+	e.Recovered, e.RecoveredAt, e.RecoveredBy = false, nil, ""
+	e.DiscardedAt, e.DiscardedBy, e.DiscardReason, e.DiscardNote = nil, "", "", ""
+	e.ExpiredAt, e.AutoRetryCount, e.NextRetryAt = nil, 0, nil
+	e.ReopenedAt, e.ReopenedBy = &now, by
+	e.Status = StatusPending
+	e.Version++
+	cp := *e
+	return &cp, nil
+}
+
+func (m *mockStore) BeginRetry(_ context.Context, dlqID, actor string) (*Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.getErr != nil {
+		return nil, m.getErr
+	}
+	e, ok := m.entries[dlqID]
+	now := time.Now()
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("begin retry %s: %w", dlqID, pgx.ErrNoRows)
+	case e.Recovered:
+		return nil, ErrAlreadyRecovered
+	case retryLeased(e, now):
+		return nil, ErrRetryInProgress
+	case e.claimedByOther(actor, now):
+		return nil, ErrEntryClaimed
+	}
+	if m.beforeRetry == nil {
+		m.beforeRetry = make(map[string]string)
+	}
+	if e.Status != StatusRetrying {
+		m.beforeRetry[dlqID] = e.Status
+	}
+	e.Status = StatusRetrying
+	e.RetryStartedAt = &now
+	e.Version++
+	cp := *e
+	return &cp, nil
+}
+
+func (m *mockStore) AbortRetry(_ context.Context, dlqID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[dlqID]; ok && e.Status == StatusRetrying {
+		e.Status = m.beforeRetry[dlqID]
+		if e.Status == "" {
+			e.Status = StatusPending
+		}
+		e.RetryStartedAt = nil
+		e.Version++
+		delete(m.beforeRetry, dlqID)
+	}
+	return nil
+}
+
+func (m *mockStore) EnqueueRecovery(_ context.Context, rec OutboxRecord) (OutboxRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[rec.DLQID]
+	if ok {
+		if err := versionMismatch(e, rec.Version); err != nil {
+			return rec, err
+		}
+	}
+	if !ok || e.Recovered {
+		return rec, ErrAlreadyRecovered
+	}
+	e.Version++
+	e.Recovered = true
+	e.RecoveredBy = rec.RecoveredBy
+	e.Status = StatusRecovered
+	e.RetryStartedAt = nil
+	m.outboxSeq++
+	rec.ID, rec.CreatedAt = m.outboxSeq, time.Now().UTC()
+	m.outbox = append(m.outbox, rec)
+	return rec, nil
+}
+
+func (m *mockStore) PendingRecoveries(_ context.Context, createdBefore time.Time, limit int) ([]OutboxRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var recs []OutboxRecord
+	for _, rec := range m.outbox {
+		if rec.CreatedAt.Before(createdBefore) && len(recs) < limit {
+			recs = append(recs, rec)
+		}
+	}
+	return recs, nil
+}
+
+func (m *mockStore) MarkRecoveryPublished(_ context.Context, id int64, publishErr error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.outbox {
+		if m.outbox[i].ID != id {
+			continue
+		}
+		if publishErr == nil {
+			m.outbox = append(m.outbox[:i], m.outbox[i+1:]...)
+		} else {
+			m.outbox[i].Attempts++
+			m.outbox[i].LastError = publishErr.Error()
+		}
+		return nil
+	}
+	return nil
+}
+
+func (m *mockStore) pendingOutbox() []OutboxRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]OutboxRecord(nil), m.outbox...)
+}
+
+type mockIdempotencyKey struct {
+	request string
+	resp    *IdempotentResponse
+}
+
+func (m *mockStore) ReserveIdempotencyKey(_ context.Context, key, request string) (*IdempotentResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.idempotency[key]
+	switch {
+	case !ok:
+		if m.idempotency == nil {
+			m.idempotency = make(map[string]*mockIdempotencyKey)
+		}
+		m.idempotency[key] = &mockIdempotencyKey{request: request}
+		return nil, nil
+	case k.request != request:
+		return nil, ErrIdempotencyKeyReused
+	case k.resp == nil:
+		return nil, ErrIdempotencyInProgress
+	}
+	return k.resp, nil
+}
+
+func (m *mockStore) CompleteIdempotencyKey(_ context.Context, key string, resp IdempotentResponse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if k, ok := m.idempotency[key]; ok {
+		k.resp = &resp
+	}
+	return nil
+}
+
+func (m *mockStore) ReleaseIdempotencyKey(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if k, ok := m.idempotency[key]; ok && k.resp == nil {
+		delete(m.idempotency, key)
+	}
+	return nil
+}
+
+// versionMismatch mirrors the version check of the conditional updates;
+// version 0 matches any entry.
+func versionMismatch(e *Entry, version int) error {
+	if version != 0 && e.Version != version {
+		return &VersionConflictError{DLQID: e.DLQID, Expected: version, Current: e.Version}
+	}
+	return nil
+}
+
+// retryLeased mirrors the RetryLease check of BeginRetry and ListRecoverable.
+func retryLeased(e *Entry, now time.Time) bool {
+	return e.Status == StatusRetrying && e.RetryStartedAt != nil && now.Sub(*e.RetryStartedAt) < RetryLease
+}
+
 func (m *mockStore) ListRecoverable(_ context.Context) ([]Entry, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -106,14 +591,112 @@ func (m *mockStore) ListRecoverable(_ context.Context) ([]Entry, error) {
 		return nil, m.listErr
 	}
 	var result []Entry
+	now := time.Now()
 	for _, e := range m.entries {
-		if e.Recoverable && !e.Recovered {
+		if e.Recoverable && !e.Recovered && e.Status != StatusExhausted && !retryLeased(e, now) &&
+			(e.NextRetryAt == nil || !e.NextRetryAt.After(now)) && (e.RetryAt == nil || !e.RetryAt.After(now)) {
 			result = append(result, *e)
 		}
 	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Priority != result[j].Priority {
+			return result[i].Priority > result[j].Priority
+		}
+		return result[i].FailedAt.Before(result[j].FailedAt)
+	})
+	return result, nil
+}
+
+func (m *mockStore) ListScheduledRetries(_ context.Context) ([]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.listErr != nil {
+		return nil, m.listErr
+	}
+	var result []Entry
+	now := time.Now()
+	for _, e := range m.entries {
+		if e.RetryAt != nil && !e.RetryAt.After(now) && !e.Recovered && !retryLeased(e, now) &&
+			(e.NextRetryAt == nil || !e.NextRetryAt.After(now)) {
+			result = append(result, *e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].RetryAt.Before(*result[j].RetryAt) })
 	return result, nil
 }
 
+func (m *mockStore) ScheduleRetry(_ context.Context, dlqID, by string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[dlqID]
+	if !ok {
+		return fmt.Errorf("schedule retry %s: %w", dlqID, pgx.ErrNoRows)
+	}
+	if e.Recovered {
+		return ErrAlreadyRecovered
+	}
+	if at.IsZero() {
+		e.RetryAt, e.RetryScheduledBy = nil, ""
+		return nil
+	}
+	e.RetryAt, e.RetryScheduledBy, e.NextRetryAt = &at, by, nil
+	return nil
+}
+
+func (m *mockStore) RecordAutoRetryFailure(_ context.Context, dlqID string, nextRetryAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[dlqID]; ok {
+		e.AutoRetryCount++
+		e.NextRetryAt = &nextRetryAt
+	}
+	return nil
+}
+
+func (m *mockStore) MarkExhausted(_ context.Context, dlqID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[dlqID]
+	if !ok {
+		return fmt.Errorf("not found: %s", dlqID)
+	}
+	if e.Recovered {
+		return fmt.Errorf("already recovered: %s", dlqID)
+	}
+	e.AutoRetryCount++
+	e.NextRetryAt = nil
+	e.RetryAt = nil
+	e.Status = StatusExhausted
+	e.Version++
+	return nil
+}
+
+func (m *mockStore) ExpireRecoverable(_ context.Context, failedBefore time.Time, limit int) ([]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var expired []Entry
+	now := time.Now().UTC()
+	for _, e := range m.entries {
+		if len(expired) >= limit {
+			break
+		}
+		// Entries seeded without failed_at count as fresh.
+		start := e.FailedAt
+		if e.ReopenedAt != nil {
+			start = *e.ReopenedAt
+		}
+		if e.Recoverable && !e.Recovered && e.Status != StatusExhausted && !start.IsZero() && start.Before(failedBefore) {
+			e.Recovered = true
+			e.Status = StatusExpired
+			e.ExpiredAt = &now
+			e.NextRetryAt = nil
+			e.Version++
+			expired = append(expired, *e)
+		}
+	}
+	return expired, nil
+}
+
 func (m *mockStore) Stats(_ context.Context) (*Stats, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -121,23 +704,361 @@ func (m *mockStore) Stats(_ context.Context) (*Stats, error) {
 		return nil, m.statsErr
 	}
 	s := &Stats{
-		ByReason: make(map[string]int),
-		BySource: make(map[string]int),
+		ByReason:  make(map[string]int),
+		BySource:  make(map[string]int),
+		ByStatus:  make(map[string]int),
+		BySubject: make(map[string]int),
 	}
+	now := time.Now().UTC()
+	var ttr []float64
 	for _, e := range m.entries {
+		if !e.Recovered && !e.FailedAt.IsZero() {
+			s.OldestUnrecoveredAge = max(s.OldestUnrecoveredAge, now.Sub(e.FailedAt).Seconds())
+		}
+		if e.RecoveredAt != nil && now.Sub(*e.RecoveredAt) <= RecoveryWindow {
+			s.RecoveredLast24h++
+		}
+		if e.RecoveredAt != nil && now.Sub(*e.RecoveredAt) <= StatsRecoveryWindow {
+			ttr = append(ttr, e.RecoveredAt.Sub(e.FailedAt).Seconds())
+		}
 		s.Total++
+		s.ByStatus[e.Status]++
+		if e.Status == StatusExpired {
+			s.Expired++
+		}
 		if !e.Recovered {
 			s.Unrecovered++
 			s.ByReason[e.Reason]++
 			s.BySource[e.Source]++
+			s.BySubject[e.OriginalSubject]++
 			if e.Recoverable {
 				s.Recoverable++
 			}
 		}
 	}
+	if len(ttr) > 0 {
+		sort.Float64s(ttr)
+		sum := 0.0
+		for _, v := range ttr {
+			sum += v
+		}
+		s.TimeToRecovery = TimeToRecovery{
+			Count: len(ttr), Avg: sum / float64(len(ttr)),
+			P50: percentileCont(ttr, 0.5), P90: percentileCont(ttr, 0.9), P99: percentileCont(ttr, 0.99),
+		}
+	}
 	return s, nil
 }
 
+// percentileCont mirrors Postgres percentile_cont on sorted values.
+func percentileCont(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lo := int(pos)
+	if lo+1 >= len(sorted) {
+		return sorted[lo]
+	}
+	return sorted[lo] + (pos-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
+
+func (m *mockStore) inserted() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.insertCalls
+}
+
+func (m *mockStore) AgentStats(_ context.Context, limit int) ([]AgentFailureStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.statsErr != nil {
+		return nil, m.statsErr
+	}
+	byAgent := map[string]*AgentFailureStats{}
+	for _, e := range m.entries {
+		if e.Recovered {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, a := range e.RetryHistory {
+			if a.Agent == "" {
+				continue
+			}
+			st := byAgent[a.Agent]
+			if st == nil {
+				st = &AgentFailureStats{Agent: a.Agent}
+				byAgent[a.Agent] = st
+			}
+			st.Failures++
+			if !seen[a.Agent] {
+				st.Entries++
+				seen[a.Agent] = true
+			}
+			if st.LastFailureAt == nil || a.AttemptedAt.After(*st.LastFailureAt) {
+				at := a.AttemptedAt
+				st.LastFailureAt = &at
+			}
+		}
+	}
+	out := []AgentFailureStats{}
+	for _, st := range byAgent {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Failures != out[j].Failures {
+			return out[i].Failures > out[j].Failures
+		}
+		return out[i].Agent < out[j].Agent
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (m *mockStore) FailureReasonStats(_ context.Context) (map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.statsErr != nil {
+		return nil, m.statsErr
+	}
+	out := make(map[string]int)
+	for _, e := range m.entries {
+		if e.Recovered {
+			continue
+		}
+		for _, a := range e.RetryHistory {
+			if a.FailureReason != "" {
+				out[a.FailureReason]++
+			}
+		}
+	}
+	return out, nil
+}
+
+func (m *mockStore) Archive(_ context.Context, f ArchiveFilter) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, _, err := f.where(); err != nil {
+		return 0, err
+	}
+	moved := 0
+	for id, e := range m.entries {
+		if !archiveMatches(f, e) {
+			continue
+		}
+		now := time.Now().UTC()
+		e.ArchivedAt = &now
+		m.archived[id] = e
+		delete(m.entries, id)
+		moved++
+	}
+	return moved, nil
+}
+
+func (m *mockStore) Claim(_ context.Context, dlqID, holder string, ttl time.Duration) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[dlqID]
+	if !ok {
+		return time.Time{}, fmt.Errorf("claim dlq entry %s: %w", dlqID, pgx.ErrNoRows)
+	}
+	now := time.Now().UTC()
+	if e.claimedByOther(holder, now) {
+		return time.Time{}, ErrEntryClaimed
+	}
+	expires := now.Add(ttl)
+	if e.ClaimedBy != holder {
+		e.Version++
+	}
+	e.ClaimedBy = holder
+	e.ClaimExpiresAt = &expires
+	return expires, nil
+}
+
+func (m *mockStore) ReleaseClaim(_ context.Context, dlqID, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[dlqID]; ok && e.ClaimedBy == holder {
+		e.ClaimedBy = ""
+		e.ClaimExpiresAt = nil
+		e.Version++
+	}
+	return nil
+}
+
+func (m *mockStore) UpdateEntry(_ context.Context, dlqID string, patch EntryPatch) (*Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[dlqID]
+	if !ok {
+		return nil, fmt.Errorf("update dlq entry %s: %w", dlqID, pgx.ErrNoRows)
+	}
+	if err := versionMismatch(e, patch.Version); err != nil {
+		return nil, err
+	}
+	if patch.Notes != nil && *patch.Notes != e.Notes {
+		e.Notes = *patch.Notes
+		e.Version++
+	}
+	if patch.AssignedTo != nil && *patch.AssignedTo != e.AssignedTo {
+		e.AssignedTo = *patch.AssignedTo
+		e.Version++
+	}
+	cp := *e
+	return &cp, nil
+}
+
+func (m *mockStore) RecordRetryAck(_ context.Context, dlqID string, ack RetryAck) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[dlqID]
+	if !ok {
+		return fmt.Errorf("not found: %s", dlqID)
+	}
+	e.RetryAck = &ack
+	return nil
+}
+
+func (m *mockStore) Erase(_ context.Context, req ErasureRequest) ([]string, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	matches := func(p json.RawMessage) bool {
+		var doc map[string]json.RawMessage
+		if json.Unmarshal(p, &doc) != nil {
+			return false
+		}
+		return string(doc[req.Field]) == string(req.Value)
+	}
+	ids := []string{}
+	for _, table := range []map[string]*Entry{m.entries, m.archived} {
+		for id, e := range table {
+			hit := matches(e.OriginalPayload)
+			for _, s := range e.PayloadSamples {
+				hit = hit || matches(s)
+			}
+			if !hit {
+				continue
+			}
+			ids = append(ids, id)
+			switch {
+			case req.DryRun:
+			case req.Mode == EraseDelete:
+				delete(table, id)
+			default:
+				e.OriginalPayload = json.RawMessage("null")
+				e.PayloadSamples = nil
+				e.Recoverable = false
+				e.PayloadErased = true
+			}
+		}
+	}
+	return ids, nil
+}
+
+func archiveMatches(f ArchiveFilter, e *Entry) bool {
+	if (f.Recovered || !f.RecoveredBefore.IsZero()) && !e.Recovered {
+		return false
+	}
+	if !f.RecoveredBefore.IsZero() && !handledBefore(e, f.RecoveredBefore) {
+		return false
+	}
+	if !f.FailedBefore.IsZero() && !e.FailedAt.Before(f.FailedBefore) {
+		return false
+	}
+	if (f.Reason != "" && e.Reason != f.Reason) || (f.Source != "" && e.Source != f.Source) {
+		return false
+	}
+	return !slices.Contains(f.ExcludeReasons, e.Reason)
+}
+
+func (m *mockStore) Purge(_ context.Context, f ArchiveFilter) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, _, err := f.where(); err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for id, e := range m.entries {
+		if archiveMatches(f, e) {
+			delete(m.entries, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *mockStore) DeleteOlderThan(_ context.Context, cutoff time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deleted := 0
+	for id, e := range m.entries {
+		if e.Recovered && handledBefore(e, cutoff) {
+			delete(m.entries, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *mockStore) Delete(_ context.Context, dlqID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, live := m.entries[dlqID]
+	_, archived := m.archived[dlqID]
+	if !live && !archived {
+		return fmt.Errorf("delete dlq entry %s: %w", dlqID, pgx.ErrNoRows)
+	}
+	delete(m.entries, dlqID)
+	delete(m.archived, dlqID)
+	m.outbox = slices.DeleteFunc(m.outbox, func(rec OutboxRecord) bool { return rec.DLQID == dlqID })
+	return nil
+}
+
+// handledBefore mirrors coalesce(recovered_at, discarded_at) < t.
+func handledBefore(e *Entry, t time.Time) bool {
+	at := e.RecoveredAt
+	if at == nil {
+		at = e.DiscardedAt
+	}
+	return at != nil && at.Before(t)
+}
+
+// producerMatches mirrors the producer_* filters of listFilter.
+func producerMatches(e *Entry, opts ListOpts) bool {
+	return (opts.ProducerService == "" || e.ProducerService == opts.ProducerService) &&
+		(opts.ProducerVersion == "" || e.ProducerVersion == opts.ProducerVersion) &&
+		(opts.ProducerHost == "" || e.ProducerHost == opts.ProducerHost)
+}
+
+func (m *mockStore) ListArchived(_ context.Context, opts ListOpts) ([]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.listErr != nil {
+		return nil, m.listErr
+	}
+	var result []Entry
+	for _, e := range m.archived {
+		if !listMatches(e, opts) {
+			continue
+		}
+		result = append(result, *e)
+	}
+	return result, nil
+}
+
+func (m *mockStore) GetArchived(_ context.Context, dlqID string) (*Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.archived[dlqID]
+	if !ok {
+		return nil, fmt.Errorf("not found: %s", dlqID)
+	}
+	cp := *e
+	return &cp, nil
+}
+
 func (m *mockStore) seed(entries ...Entry) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -146,6 +1067,14 @@ func (m *mockStore) seed(entries ...Entry) {
 		if e.RetryHistory == nil {
 			e.RetryHistory = []RetryAttempt{}
 		}
+		if e.Status == "" && e.Recovered {
+			e.Status = StatusRecovered
+		} else if e.Status == "" {
+			e.Status = StatusPending
+		}
+		if e.Version == 0 {
+			e.Version = 1
+		}
 		m.entries[e.DLQID] = &e
 	}
 }
@@ -176,11 +1105,30 @@ func (m *mockNATS) Publish(subject string, data []byte) error {
 	return nil
 }
 
+// published returns messages republished to their original subjects,
+// excluding DLQ lifecycle notifications.
 func (m *mockNATS) published() []publishedMsg {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	cp := make([]publishedMsg, len(m.messages))
-	copy(cp, m.messages)
+	var cp []publishedMsg
+	for _, msg := range m.messages {
+		if !isEventSubject(msg.Subject) {
+			cp = append(cp, msg)
+		}
+	}
+	return cp
+}
+
+// events returns DLQ lifecycle notifications published to subject.
+func (m *mockNATS) events(subject string) []publishedMsg {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var cp []publishedMsg
+	for _, msg := range m.messages {
+		if msg.Subject == subject {
+			cp = append(cp, msg)
+		}
+	}
 	return cp
 }
 
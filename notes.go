@@ -0,0 +1,105 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// MaxNotesLength bounds Entry.Notes, in bytes.
+const MaxNotesLength = 4096
+
+// EntryPatch is a partial update of an entry's operator-maintained fields,
+// applied by UpdateEntry and PATCH /{dlqID}. Nil fields are left unchanged;
+// a non-zero Version makes the update conditional on the entry still being
+// at that version.
+type EntryPatch struct {
+	Notes *string `json:"notes,omitempty"`
+	// AssignedTo sets Entry.AssignedTo; "" unassigns.
+	AssignedTo *string `json:"assigned_to,omitempty"`
+	Version    int     `json:"version,omitempty"`
+}
+
+// validate checks p against the field limits.
+func (p EntryPatch) validate() error {
+	if p.Notes != nil && len(*p.Notes) > MaxNotesLength {
+		return fmt.Errorf("notes must not exceed %d bytes", MaxNotesLength)
+	}
+	return nil
+}
+
+// UpdateEntry applies patch to an entry and returns the updated entry. It
+// fails with a *VersionConflictError if patch.Version is set and the entry
+// changed, or a wrapped pgx.ErrNoRows. Recovered entries can be updated too.
+func (s *Store) UpdateEntry(ctx context.Context, dlqID string, patch EntryPatch) (_ *Entry, err error) {
+	defer s.observe("update_entry", time.Now(), &err)
+	row := s.pool.QueryRow(ctx, `
+		UPDATE swarm_dlq
+		SET notes       = CASE WHEN $2 THEN nullif($3, '') ELSE notes END,
+		    assigned_to = CASE WHEN $5 THEN nullif($6, '') ELSE assigned_to END
+		WHERE dlq_id = $1 AND ($4 = 0 OR version = $4) AND `+tenantClause(7)+`
+		RETURNING `+entryColumns, dlqID, patch.Notes != nil, deref(patch.Notes), patch.Version,
+		patch.AssignedTo != nil, deref(patch.AssignedTo), s.tenant(ctx))
+	e, err := scanEntry(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if patch.Version != 0 {
+			return nil, s.versionConflict(ctx, dlqID, patch.Version)
+		}
+		return nil, fmt.Errorf("update dlq entry %s: %w", dlqID, pgx.ErrNoRows)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update dlq entry: %w", err)
+	}
+	return e, nil
+}
+
+// deref returns *p, or "" for nil.
+func deref(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+// handlePatch serves PATCH /{dlqID}, which edits an entry's notes and
+// assignee.
+func (h *Handler) handlePatch(w http.ResponseWriter, r *http.Request) {
+	dlqID := chi.URLParam(r, "dlqID")
+	var patch EntryPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid patch body"})
+		return
+	}
+	if err := patch.validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if e, err := h.store.Get(r.Context(), dlqID); err == nil && e.claimedByOther(actorFromRequest(r), time.Now()) {
+		writeJSON(w, http.StatusLocked, map[string]string{"error": ErrEntryClaimed.Error(), "claimed_by": e.ClaimedBy})
+		return
+	}
+
+	entry, err := h.store.UpdateEntry(r.Context(), dlqID, patch)
+	var conflict *VersionConflictError
+	switch {
+	case errors.As(err, &conflict):
+		writeJSON(w, http.StatusConflict, map[string]any{"error": ErrVersionConflict.Error(), "version": conflict.Current})
+		return
+	case errors.Is(err, pgx.ErrNoRows):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "dlq entry not found"})
+		return
+	case err != nil:
+		slog.Error("failed to update dlq entry", "dlq_id", dlqID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	h.auditEntry(r, AuditUpdate, dlqID, AuditResultOK, "")
+	writeJSON(w, http.StatusOK, map[string]any{"status": "updated", "dlq_id": dlqID, "notes": entry.Notes, "assigned_to": entry.AssignedTo, "version": entry.Version})
+}
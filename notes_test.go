@@ -0,0 +1,60 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func patchEntry(r http.Handler, dlqID, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("PATCH", "/dlq/"+dlqID, strings.NewReader(body)))
+	return w
+}
+
+func TestHandler_Patch_Notes(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "n1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`)})
+	r := newTestRouter(store, newMockNATS())
+
+	w := patchEntry(r, "n1", `{"notes":"waiting on agent image fix"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/n1", nil))
+	var got Entry
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	if got.Notes != "waiting on agent image fix" || got.Version != 2 {
+		t.Errorf("expected the notes on the entry at version 2, got %q v%d", got.Notes, got.Version)
+	}
+
+	if w := patchEntry(r, "n1", `{"notes":"stale edit","version":1}`); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a stale version, got %d", w.Code)
+	}
+	if w := patchEntry(r, "n1", `{"notes":"`+strings.Repeat("x", MaxNotesLength+1)+`"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for oversized notes, got %d", w.Code)
+	}
+	if w := patchEntry(r, "missing", `{"notes":"x"}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestHandler_Patch_ClaimedByOther(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "n2", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`)})
+	_, _ = store.Claim(context.Background(), "n2", "alice", time.Minute)
+	r := newTestRouter(store, newMockNATS())
+
+	req := httptest.NewRequest("PATCH", "/dlq/n2", strings.NewReader(`{"notes":"mine"}`))
+	req.Header.Set(ActorHeader, "bob")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusLocked {
+		t.Errorf("expected 423 for an entry claimed by someone else, got %d", w.Code)
+	}
+}
@@ -0,0 +1,229 @@
+package dlq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Notification kinds.
+const (
+	// NotifyEntryCreated is sent by the Processor for every new entry.
+	NotifyEntryCreated = "entry_created"
+	// NotifyExhausted is sent by the Scanner when it gives up on an entry
+	// (see WithScannerMaxRetries).
+	NotifyExhausted = "exhausted"
+	// NotifyThreshold is sent by the Scanner when the unrecovered count
+	// rises to its threshold (see WithScannerUnrecoveredThreshold).
+	NotifyThreshold = "threshold"
+	// NotifyAlert is sent by an Alerter when a rule starts firing, and
+	// NotifyAlertResolved when it stops.
+	NotifyAlert         = "alert"
+	NotifyAlertResolved = "alert_resolved"
+)
+
+// DefaultNotifyQueueSize is how many notifications an AsyncNotifier holds.
+const DefaultNotifyQueueSize = 256
+
+// Notification tells operators about a DLQ event. Entry notifications carry
+// the entry's identifying fields but never its payload.
+type Notification struct {
+	Kind            string    `json:"kind"`
+	At              time.Time `json:"at"`
+	DLQID           string    `json:"dlq_id,omitempty"`
+	Reason          string    `json:"reason,omitempty"`
+	ReasonDetail    string    `json:"reason_detail,omitempty"`
+	Source          string    `json:"source,omitempty"`
+	OriginalSubject string    `json:"original_subject,omitempty"`
+	// Attempts is the number of failed automatic retries of an exhausted
+	// entry; Detail is the last error.
+	Attempts int    `json:"attempts,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	// Unrecovered and Threshold are set on threshold and alert
+	// notifications; for alerts Unrecovered counts the entries matching
+	// Rule, and Detail describes what it matches.
+	Unrecovered int    `json:"unrecovered,omitempty"`
+	Threshold   int    `json:"threshold,omitempty"`
+	Rule        string `json:"rule,omitempty"`
+}
+
+// entryNotification builds a notification of kind about e.
+func entryNotification(kind string, e Entry) Notification {
+	return Notification{
+		Kind: kind, At: time.Now().UTC(), DLQID: e.DLQID,
+		Reason: e.Reason, ReasonDetail: e.ReasonDetail, Source: e.Source,
+		OriginalSubject: e.OriginalSubject,
+	}
+}
+
+// Notifier delivers notifications, e.g. to a webhook or chat channel.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface.
+type NotifierFunc func(ctx context.Context, n Notification) error
+
+// Notify calls f.
+func (f NotifierFunc) Notify(ctx context.Context, n Notification) error {
+	return f(ctx, n)
+}
+
+// Notifiers sends every notification to each of its notifiers.
+type Notifiers []Notifier
+
+// Notify implements Notifier. Every notifier is tried; the failures are
+// returned together.
+func (ns Notifiers) Notify(ctx context.Context, n Notification) error {
+	var errs []error
+	for _, notifier := range ns {
+		if err := notifier.Notify(ctx, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// notify sends n through notifier, logging rather than returning failures:
+// notifications must not fail the operation they describe.
+func notify(ctx context.Context, notifier Notifier, n Notification) {
+	if notifier == nil {
+		return
+	}
+	if err := notifier.Notify(ctx, n); err != nil {
+		slog.Warn("dlq: notification failed", "kind", n.Kind, "dlq_id", n.DLQID, "error", err)
+	}
+}
+
+// Webhook is an endpoint a WebhookNotifier posts to.
+type Webhook struct {
+	URL string
+	// Headers are sent with every request, e.g. Authorization.
+	Headers map[string]string
+}
+
+// WebhookNotifier POSTs each Notification as JSON to every configured
+// webhook. Any 2xx response counts as delivered.
+type WebhookNotifier struct {
+	hooks  []Webhook
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting to hooks. A nil client
+// defaults to one with a 10 second timeout.
+func NewWebhookNotifier(client *http.Client, hooks ...Webhook) *WebhookNotifier {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &WebhookNotifier{hooks: hooks, client: client}
+}
+
+// Notify implements Notifier. Every webhook is tried; the failures are
+// returned together.
+func (w *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("marshal notification: %w", err)
+	}
+	var errs []error
+	for _, hook := range w.hooks {
+		if err := postJSON(ctx, w.client, hook.URL, hook.Headers, body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// postJSON posts body to url and fails on any non-2xx response.
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post to %s: %w", req.URL.Redacted(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("post to %s: unexpected status %d", req.URL.Redacted(), resp.StatusCode)
+	}
+	return nil
+}
+
+// AsyncNotifier hands notifications to another Notifier in the background,
+// so a slow or unreachable endpoint never holds up ingestion or a scan.
+// Notifications arriving while the queue is full are dropped.
+type AsyncNotifier struct {
+	next  Notifier
+	queue chan Notification
+	done  chan struct{}
+}
+
+// NewAsyncNotifier wraps next with a queue of queueSize notifications
+// (DefaultNotifyQueueSize if zero). Call Start to begin delivering.
+func NewAsyncNotifier(next Notifier, queueSize int) *AsyncNotifier {
+	if queueSize <= 0 {
+		queueSize = DefaultNotifyQueueSize
+	}
+	return &AsyncNotifier{next: next, queue: make(chan Notification, queueSize), done: make(chan struct{})}
+}
+
+// Notify implements Notifier by queueing n without blocking.
+func (a *AsyncNotifier) Notify(_ context.Context, n Notification) error {
+	select {
+	case a.queue <- n:
+		return nil
+	default:
+		return fmt.Errorf("notification queue full, dropping %s", n.Kind)
+	}
+}
+
+// Start delivers queued notifications until ctx is cancelled, then delivers
+// whatever is still queued.
+func (a *AsyncNotifier) Start(ctx context.Context) {
+	go func() {
+		defer close(a.done)
+		for {
+			select {
+			case n := <-a.queue:
+				notify(ctx, a.next, n)
+			case <-ctx.Done():
+				a.drain(context.WithoutCancel(ctx))
+				return
+			}
+		}
+	}()
+}
+
+// drain delivers the queued notifications without blocking for new ones.
+func (a *AsyncNotifier) drain(ctx context.Context) {
+	for {
+		select {
+		case n := <-a.queue:
+			notify(ctx, a.next, n)
+		default:
+			return
+		}
+	}
+}
+
+// Wait blocks until the notifier has stopped.
+func (a *AsyncNotifier) Wait() {
+	<-a.done
+}
+
+var (
+	_ Notifier = (*WebhookNotifier)(nil)
+	_ Notifier = (*AsyncNotifier)(nil)
+	_ Notifier = Notifiers(nil)
+)
@@ -0,0 +1,139 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordNotifier collects notifications.
+type recordNotifier struct {
+	mu    sync.Mutex
+	notes []Notification
+}
+
+func (r *recordNotifier) Notify(_ context.Context, n Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notes = append(r.notes, n)
+	return nil
+}
+
+func (r *recordNotifier) kinds() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []string
+	for _, n := range r.notes {
+		out = append(out, n.Kind)
+	}
+	return out
+}
+
+func TestWebhookNotifier_PostsJSON(t *testing.T) {
+	var got Notification
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	ok := NewWebhookNotifier(nil, Webhook{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer t"}})
+	n := entryNotification(NotifyEntryCreated, Entry{DLQID: "wh-1", Reason: ReasonCrashLoop, Source: SourceWarren})
+	if err := ok.Notify(context.Background(), n); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	if got.Kind != NotifyEntryCreated || got.DLQID != "wh-1" || got.Reason != ReasonCrashLoop || auth != "Bearer t" {
+		t.Errorf("unexpected delivery: %+v (auth %q)", got, auth)
+	}
+
+	both := NewWebhookNotifier(nil, Webhook{URL: failing.URL}, Webhook{URL: srv.URL})
+	got = Notification{}
+	if err := both.Notify(context.Background(), n); err == nil {
+		t.Error("expected an error from the failing webhook")
+	}
+	if got.DLQID != "wh-1" {
+		t.Error("expected the healthy webhook to be posted to despite the failure")
+	}
+}
+
+func TestAsyncNotifier_DeliversAndDrains(t *testing.T) {
+	rec := &recordNotifier{}
+	a := NewAsyncNotifier(rec, 1)
+	if err := a.Notify(context.Background(), Notification{Kind: NotifyThreshold}); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	if err := a.Notify(context.Background(), Notification{Kind: NotifyThreshold}); err == nil {
+		t.Error("expected a full queue to reject the notification")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.Start(ctx)
+	cancel()
+	a.Wait()
+	if kinds := rec.kinds(); len(kinds) != 1 {
+		t.Errorf("expected the queued notification to be delivered, got %v", kinds)
+	}
+}
+
+func TestProcessor_NotifiesNewEntries(t *testing.T) {
+	rec := &recordNotifier{}
+	proc := NewProcessor(newMockStore(), WithProcessorNotifier(rec))
+
+	data := eventJSON(Entry{DLQID: "pn-1", OriginalSubject: "swarm.task.request", Reason: ReasonNoCapableAgent})
+	proc.Process(context.Background(), SubjectTaskUnassignable, data)
+	proc.Process(context.Background(), SubjectTaskUnassignable, data)
+
+	if kinds := rec.kinds(); len(kinds) != 1 || kinds[0] != NotifyEntryCreated {
+		t.Errorf("expected one entry_created notification, got %v", kinds)
+	}
+}
+
+func TestScanner_Notifications(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	rec := &recordNotifier{}
+	failing := NewTransformer(TransformRule{Transform: JSONPatch(PatchOp{Op: "remove", Path: "/missing"})})
+	for i := 0; i < 3; i++ {
+		store.seed(Entry{DLQID: fmt.Sprintf("sn-%d", i), OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true})
+	}
+	scanner := NewScanner(store, nc, time.Minute,
+		WithScannerTransformer(failing), WithScannerMaxRetries(2),
+		WithScannerNotifier(rec), WithScannerUnrecoveredThreshold(3))
+
+	// First pass: threshold reached, entries back off.
+	scanner.scan(context.Background())
+	if kinds := rec.kinds(); len(kinds) != 1 || kinds[0] != NotifyThreshold {
+		t.Fatalf("expected one threshold notification, got %v", kinds)
+	}
+
+	// Second pass: still over the threshold, so no repeat; entries exhaust.
+	store.mu.Lock()
+	for _, e := range store.entries {
+		e.NextRetryAt = nil
+	}
+	store.mu.Unlock()
+	scanner.scan(context.Background())
+	kinds := rec.kinds()
+	if len(kinds) != 4 {
+		t.Fatalf("expected 3 exhausted notifications after the threshold, got %v", kinds)
+	}
+	for _, k := range kinds[1:] {
+		if k != NotifyExhausted {
+			t.Errorf("expected exhausted notifications, got %v", kinds)
+		}
+	}
+	if n := rec.notes[1]; n.Attempts != 2 || n.Detail == "" {
+		t.Errorf("unexpected exhausted notification: %+v", n)
+	}
+}
@@ -0,0 +1,492 @@
+package dlq
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// openAPIVersion is the OpenAPI version of the document served at
+// GET /openapi.json.
+const openAPIVersion = "3.0.3"
+
+// apiOp documents one route. The paths and methods in the served document
+// come from walking the router, and schemas are reflected from the Go types
+// the handlers encode and decode, so the document follows the code.
+type apiOp struct {
+	summary string
+	query   []apiParam
+	// body is a value of the type the handler decodes; nil for no body.
+	body any
+	// bodyType is the request media type when it is not JSON.
+	bodyType string
+	// response is a value of the type written with 200; nil for none.
+	response any
+	// responseType is the 200 media type when it is not JSON.
+	responseType string
+	errors       []int
+}
+
+// apiParam is a query parameter.
+type apiParam struct {
+	name, typ, description string
+}
+
+// apiObject documents a JSON object the handler writes from a map literal,
+// mapping property names to JSON types; "array" is an array of strings.
+type apiObject map[string]string
+
+// apiError is the body of every non-2xx JSON response.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+var listParams = []apiParam{
+	{"recovered", "boolean", "Only recovered (true) or open (false) entries"},
+	{"status", "string", "Lifecycle status"},
+	{"reason", "string", "DLQ reason"},
+	{"source", "string", "Source service"},
+	{"agent", "string", "Agent that appears in the retry history"},
+	{"producer_service", "string", "Publishing service"},
+	{"producer_version", "string", "Publishing service version"},
+	{"producer_host", "string", "Publishing host"},
+	{"fingerprint", "string", "Repeats of the same failure (see Fingerprint)"},
+	{"assigned_to", "string", "Entries assigned to this engineer"},
+	{"task_id", "string", "Entries for this task (see WithProcessorTaskIDFields)"},
+	{"limit", "integer", "Maximum entries to return"},
+}
+
+var relatedParams = []apiParam{
+	{"archived", "boolean", "Include archived entries"},
+	{"limit", "integer", "Maximum entries to return"},
+}
+
+var retryParams = []apiParam{
+	{"at", "string", "Schedule the retry for this RFC 3339 time instead of retrying now"},
+	{"delay", "string", "Schedule the retry this long from now (a Go duration, e.g. 10m)"},
+}
+
+var sortParam = apiParam{"sort", "string", "failed_at (newest first, the default) or priority (highest first)"}
+
+var replayParams = []apiParam{
+	{"prefix", "string", `Subject prefix ending in "." (required)`},
+}
+
+// apiOps documents every route Routes can mount, keyed by "METHOD path".
+var apiOps = map[string]apiOp{
+	"GET /": {
+		summary: "List entries",
+		query: append([]apiParam{{"group", "string",
+			"fingerprint: return one FingerprintGroup per fingerprint instead of entries"}, sortParam}, listParams...),
+		response: []Entry{}, errors: []int{400},
+	},
+	"GET /export": {
+		summary:      "Stream every matching entry, oldest first",
+		query:        append([]apiParam{{"format", "string", "ndjson (default), csv or json"}}, listParams...),
+		responseType: "application/x-ndjson", errors: []int{400},
+	},
+	"POST /import": {
+		summary: "Re-ingest NDJSON entries through the processor", bodyType: "application/x-ndjson",
+		response: ImportResult{}, errors: []int{400},
+	},
+	"GET /openapi.json": {
+		summary: "This OpenAPI document", response: map[string]any{},
+	},
+	"GET /stats": {
+		summary: "Counts by reason, source, status and top subjects", response: Stats{},
+	},
+	"GET /stats/agents": {
+		summary:  "Agents with the most failed attempts on open entries",
+		query:    []apiParam{{"limit", "integer", "Maximum agents to return (default 20)"}},
+		response: []AgentFailureStats{},
+	},
+	"GET /stats/failures": {
+		summary: "Open entries by retry failure reason", response: map[string]int{},
+	},
+	"GET /stats/timeseries": {
+		summary: "Entries failing and recovered per time bucket",
+		query: []apiParam{
+			{"window", "string", "How far back to look, as a Go duration (default 24h)"},
+			{"bucket", "string", "Bucket width, whole seconds (default 1h); at most 1000 buckets"},
+			{"group_by", "string", "reason or source: one series per value"},
+		},
+		response: Timeseries{}, errors: []int{400},
+	},
+	"GET /federation/stats": {
+		summary: "Stats across the local DLQ and remote clusters", response: FederatedStats{},
+	},
+	"GET /federation/entries": {
+		summary: "Entries across the local DLQ and remote clusters", query: append([]apiParam{sortParam}, listParams...), response: FederatedList{},
+	},
+	"GET /deny-list": {
+		summary: "Subjects and reasons the scanner never re-drives", response: DenyRules{},
+	},
+	"PUT /deny-list": {
+		summary: "Replace the deny-list", body: DenyRules{}, response: DenyRules{}, errors: []int{400},
+	},
+	"GET /bundle": {
+		summary: "Export matching entries as a signed bundle", query: listParams,
+		responseType: "application/gzip", errors: []int{403},
+	},
+	"POST /bundle": {
+		summary: "Import a signed bundle", bodyType: "application/gzip",
+		response: apiObject{"imported": "integer", "skipped": "integer", "total": "integer"},
+		errors:   []int{400, 401},
+	},
+	"GET /live": {
+		summary: "WebSocket stream of entry events",
+		query: []apiParam{
+			{"reason", "string", "Only events for this reason"},
+			{"source", "string", "Only events from this source"},
+			{"type", "string", "Only events of this type"},
+		},
+	},
+	"GET /scanner/status": {
+		summary: "Recovery scanner totals and last run", response: ScannerStatus{},
+	},
+	"POST /compliance/erase": {
+		summary: "Erase entries whose payload has a field value (requires the dlq:admin scope)", body: ErasureRequest{},
+		response: apiObject{"mode": "string", "dry_run": "boolean", "matched": "integer", "dlq_ids": "array"},
+		errors:   []int{400, 401, 403},
+	},
+	"GET /archive/": {
+		summary: "List archived entries", query: listParams, response: []Entry{},
+	},
+	"POST /archive/": {
+		summary: "Move entries to the archive", body: archiveRequest{},
+		response: apiObject{"archived": "integer"}, errors: []int{400},
+	},
+	"GET /archive/{dlqID}": {
+		summary: "Get an archived entry", response: Entry{}, errors: []int{404},
+	},
+	"POST /purge": {
+		summary: "Delete handled entries older than a duration (requires the dlq:admin scope)", body: purgeRequest{},
+		response: apiObject{"deleted": "integer"}, errors: []int{400, 401, 403},
+	},
+	"GET /{dlqID}": {
+		summary: "Get an entry", response: Entry{}, errors: []int{404},
+	},
+	"PATCH /{dlqID}": {
+		summary: "Edit an entry's notes and assignee", body: EntryPatch{},
+		response: apiObject{"status": "string", "dlq_id": "string", "notes": "string", "assigned_to": "string", "version": "integer"},
+		errors:   []int{400, 404, 409, 423},
+	},
+	"DELETE /{dlqID}": {
+		summary:  "Permanently delete an entry (requires the dlq:admin scope)",
+		response: apiObject{"status": "string", "dlq_id": "string", "payload_ref": "string"},
+		errors:   []int{401, 403, 404},
+	},
+	"GET /{dlqID}/related": {
+		summary: "Other entries with the same task id or fingerprint", query: relatedParams,
+		response: []Entry{}, errors: []int{404},
+	},
+	"GET /{dlqID}/payload": {
+		summary: "Full original payload, fetched from the blob store if offloaded", response: json.RawMessage{},
+		errors: []int{403, 404, 502},
+	},
+	"GET /{dlqID}/audit": {
+		summary: "Audit trail of an entry", response: []AuditRecord{},
+	},
+	"POST /{dlqID}/retry": {
+		summary: "Republish an entry to its original subject, now or at a scheduled time", query: retryParams,
+		response: apiObject{"status": "string", "dlq_id": "string", "retry_at": "string"},
+		errors:   []int{400, 404, 409, 422, 423, 502, 504},
+	},
+	"DELETE /{dlqID}/retry": {
+		summary:  "Cancel a scheduled retry",
+		response: apiObject{"status": "string", "dlq_id": "string"},
+		errors:   []int{404, 409, 423},
+	},
+	"POST /{dlqID}/discard": {
+		summary: "Mark an entry handled without retrying it", body: DiscardOpts{},
+		response: apiObject{"status": "string", "dlq_id": "string"}, errors: []int{400, 404, 409, 423},
+	},
+	"POST /{dlqID}/reopen": {
+		summary: "Reopen a recovered, discarded or expired entry", body: reopenRequest{},
+		response: apiObject{"status": "string", "dlq_id": "string", "version": "integer"},
+		errors:   []int{400, 404, 409, 423},
+	},
+	"POST /{dlqID}/assign": {
+		summary: "Assign an entry for triage", body: assignRequest{},
+		response: apiObject{"status": "string", "dlq_id": "string", "assigned_to": "string", "version": "integer"},
+		errors:   []int{400, 404, 409},
+	},
+	"DELETE /{dlqID}/assign": {
+		summary:  "Unassign an entry",
+		response: apiObject{"status": "string", "dlq_id": "string", "assigned_to": "string", "version": "integer"},
+		errors:   []int{404},
+	},
+	"POST /{dlqID}/claim": {
+		summary: "Take a short lease on an entry", body: claimRequest{},
+		response: apiObject{"dlq_id": "string", "claimed_by": "string", "claim_expires_at": "string"},
+		errors:   []int{400, 403, 404, 423},
+	},
+	"DELETE /{dlqID}/claim": {
+		summary:  "Release a lease on an entry",
+		response: apiObject{"status": "string", "dlq_id": "string"}, errors: []int{404, 423},
+	},
+	"POST /retry-all": {
+		summary: "Retry every recoverable entry",
+		response: apiObject{
+			"retried": "integer", "failed": "integer", "throttled": "integer",
+			"stale": "integer", "claimed": "integer", "in_progress": "integer", "total": "integer",
+		},
+	},
+	"POST /{dlqID}/replay": {
+		summary: "Publish an entry under a prefixed subject", query: replayParams,
+		response: apiObject{"status": "string", "dlq_id": "string", "subject": "string"},
+		errors:   []int{400, 404, 409},
+	},
+	"POST /replay": {
+		summary: "Publish matching entries under a prefixed subject", query: append(replayParams, listParams...),
+		response: apiObject{"replayed": "integer", "failed": "integer", "total": "integer", "prefix": "string"},
+		errors:   []int{400},
+	},
+}
+
+// handleOpenAPI serves the OpenAPI document for routes. It is built once;
+// the server URL is taken from the request path so it matches wherever the
+// router is mounted.
+func (h *Handler) handleOpenAPI(routes chi.Routes) http.HandlerFunc {
+	var (
+		once sync.Once
+		doc  map[string]any
+	)
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { doc = h.openAPIDocument(routes) })
+		out := make(map[string]any, len(doc)+1)
+		for k, v := range doc {
+			out[k] = v
+		}
+		server := strings.TrimSuffix(r.URL.Path, "/openapi.json")
+		if server == "" {
+			server = "/"
+		}
+		out["servers"] = []map[string]string{{"url": server}}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// openAPIDocument builds the document for the routes actually mounted.
+func (h *Handler) openAPIDocument(routes chi.Routes) map[string]any {
+	b := &schemaBuilder{components: map[string]any{}}
+	b.components["Error"] = b.schema(reflect.TypeOf(apiError{}))
+	paths := map[string]map[string]any{}
+
+	_ = chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		op, ok := apiOps[method+" "+route]
+		if !ok {
+			op = apiOp{summary: method + " " + route}
+		}
+		if paths[route] == nil {
+			paths[route] = map[string]any{}
+		}
+		paths[route][strings.ToLower(method)] = b.operation(op, route, len(h.auth) > 0)
+		return nil
+	})
+
+	doc := map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]string{
+			"title":   "swarm-dlq",
+			"version": "1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": b.components,
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]string{"type": "apiKey", "in": "header", "name": APIKeyHeader},
+				"bearer": map[string]string{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+	if len(h.auth) > 0 {
+		doc["security"] = []map[string][]string{{"apiKey": {}}, {"bearer": {}}}
+	}
+	return doc
+}
+
+func (b *schemaBuilder) operation(op apiOp, route string, auth bool) map[string]any {
+	out := map[string]any{"summary": op.summary}
+
+	var params []map[string]any
+	for _, name := range pathParams(route) {
+		params = append(params, map[string]any{
+			"name": name, "in": "path", "required": true, "schema": map[string]string{"type": "string"},
+		})
+	}
+	for _, p := range op.query {
+		params = append(params, map[string]any{
+			"name": p.name, "in": "query", "description": p.description, "schema": map[string]string{"type": p.typ},
+		})
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+
+	switch {
+	case op.bodyType != "":
+		out["requestBody"] = map[string]any{"content": map[string]any{
+			op.bodyType: map[string]any{"schema": map[string]string{"type": "string", "format": "binary"}},
+		}}
+	case op.body != nil:
+		out["requestBody"] = map[string]any{"content": map[string]any{
+			"application/json": map[string]any{"schema": b.value(op.body)},
+		}}
+	}
+
+	responses := map[string]any{}
+	switch {
+	case op.responseType != "":
+		responses["200"] = map[string]any{"description": "OK", "content": map[string]any{
+			op.responseType: map[string]any{"schema": map[string]string{"type": "string", "format": "binary"}},
+		}}
+	case op.response != nil:
+		responses["200"] = map[string]any{"description": "OK", "content": map[string]any{
+			"application/json": map[string]any{"schema": b.value(op.response)},
+		}}
+	default:
+		responses["101"] = map[string]any{"description": "Switching Protocols"}
+	}
+	errs := op.errors
+	if auth {
+		errs = append(errs[:len(errs):len(errs)], http.StatusUnauthorized)
+	}
+	errs = append(errs[:len(errs):len(errs)], http.StatusInternalServerError)
+	for _, code := range errs {
+		responses[strconv.Itoa(code)] = map[string]any{
+			"description": http.StatusText(code),
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": map[string]string{"$ref": "#/components/schemas/Error"}},
+			},
+		}
+	}
+	out["responses"] = responses
+	return out
+}
+
+// pathParams returns the {name} segments of a chi route.
+func pathParams(route string) []string {
+	var names []string
+	for _, seg := range strings.Split(route, "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			names = append(names, strings.TrimSuffix(strings.TrimPrefix(seg, "{"), "}"))
+		}
+	}
+	return names
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaBuilder reflects JSON schemas from Go types, collecting named structs
+// under components.
+type schemaBuilder struct {
+	components map[string]any
+}
+
+func (b *schemaBuilder) value(v any) map[string]any {
+	if obj, ok := v.(apiObject); ok {
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		props := map[string]any{}
+		for _, name := range names {
+			prop := map[string]any{"type": obj[name]}
+			if obj[name] == "array" {
+				prop["items"] = map[string]string{"type": "string"}
+			}
+			props[name] = prop
+		}
+		return map[string]any{"type": "object", "properties": props}
+	}
+	return b.schema(reflect.TypeOf(v))
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" || !isExportedName(t.Name()) {
+			return b.object(t)
+		}
+		if _, ok := b.components[t.Name()]; !ok {
+			b.components[t.Name()] = map[string]any{} // placeholder for recursive types
+			b.components[t.Name()] = b.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]any{}
+	}
+}
+
+// object returns the schema of a struct, following encoding/json's field
+// naming and flattening embedded structs.
+func (b *schemaBuilder) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	b.fields(t, props)
+	return map[string]any{"type": "object", "properties": props}
+}
+
+func (b *schemaBuilder) fields(t reflect.Type, props map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			b.fields(f.Type, props)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = b.schema(f.Type)
+	}
+}
+
+func isExportedName(name string) bool {
+	return name != "" && strings.ToUpper(name[:1]) == name[:1]
+}
@@ -0,0 +1,88 @@
+package dlq
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// fullHandler mounts every optional route.
+func fullHandler() *Handler {
+	store, nc := newMockStore(), newMockNATS()
+	return NewHandler(store, nc,
+		WithFederation(NewFederation("local", store, nil, nil)),
+		WithDenyList(NewDenyList(DenyRules{})),
+		WithBundleKey([]byte("k")),
+		WithLiveFeed(NewLiveFeed()),
+		WithScanner(NewScanner(store, nc, time.Minute)),
+		WithAuditRecorder(&memAudit{}),
+		WithImportProcessor(NewProcessor(store)),
+		WithBlobReader(&memBlobStore{}),
+	)
+}
+
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+	mounted := map[string]bool{}
+	_ = chi.Walk(fullHandler().Routes(), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		mounted[method+" "+route] = true
+		if _, ok := apiOps[method+" "+route]; !ok {
+			t.Errorf("%s %s is not documented in apiOps", method, route)
+		}
+		return nil
+	})
+	for key := range apiOps {
+		if !mounted[key] {
+			t.Errorf("apiOps documents %q, which is not mounted", key)
+		}
+	}
+}
+
+func TestHandler_OpenAPI(t *testing.T) {
+	r := chi.NewRouter()
+	r.Mount("/dlq", fullHandler().Routes())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var doc struct {
+		OpenAPI    string                                `json:"openapi"`
+		Servers    []struct{ URL string }                `json:"servers"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != openAPIVersion {
+		t.Errorf("unexpected openapi version %q", doc.OpenAPI)
+	}
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "/dlq" {
+		t.Errorf("expected server /dlq, got %+v", doc.Servers)
+	}
+	if _, ok := doc.Paths["/{dlqID}/retry"]["post"]; !ok {
+		t.Error("expected POST /{dlqID}/retry in paths")
+	}
+	entry := doc.Components.Schemas["Entry"].Properties
+	for _, field := range []string{"dlq_id", "original_payload", "retry_history", "expired_at"} {
+		if _, ok := entry[field]; !ok {
+			t.Errorf("Entry schema missing %q", field)
+		}
+	}
+	if _, ok := doc.Components.Schemas["RetryAttempt"]; !ok {
+		t.Error("expected nested RetryAttempt schema")
+	}
+	if _, ok := doc.Components.Schemas["FederatedEntry"].Properties["cluster"]; !ok {
+		t.Error("expected embedded Entry fields flattened with cluster")
+	}
+}
@@ -0,0 +1,319 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Outbox defaults.
+const (
+	DefaultOutboxInterval = 5 * time.Second
+	DefaultOutboxGrace    = 30 * time.Second
+	outboxBatch           = 100
+)
+
+// OutboxRecord is a recovery that has been committed to the store but not
+// yet confirmed as published.
+type OutboxRecord struct {
+	ID          int64
+	DLQID       string
+	Subject     string
+	Payload     []byte
+	Header      nats.Header
+	RecoveredBy string
+	// Version is the entry version the recovery expects; 0 accepts any.
+	// It is not stored.
+	Version   int
+	CreatedAt time.Time
+	Attempts  int
+	LastError string
+}
+
+// OutboxStore persists recovery intents. *Store implements it on the
+// swarm_dlq_outbox table.
+type OutboxStore interface {
+	// EnqueueRecovery marks rec.DLQID recovered by rec.RecoveredBy and
+	// records rec in one transaction, returning it with ID and CreatedAt
+	// set. It fails with ErrAlreadyRecovered if the entry was recovered in
+	// the meantime, or a *VersionConflictError if rec.Version is set and
+	// the entry changed, in which case nothing is recorded.
+	EnqueueRecovery(ctx context.Context, rec OutboxRecord) (OutboxRecord, error)
+	// PendingRecoveries returns up to limit unpublished records created
+	// before createdBefore, oldest first.
+	PendingRecoveries(ctx context.Context, createdBefore time.Time, limit int) ([]OutboxRecord, error)
+	// MarkRecoveryPublished removes record id once published (publishErr is
+	// nil), or counts a failed attempt against it.
+	MarkRecoveryPublished(ctx context.Context, id int64, publishErr error) error
+}
+
+// Outbox republishes recovered entries without letting the publish and the
+// store diverge. Recover commits the recovery and the message to publish in
+// one transaction before publishing, so an entry is marked recovered exactly
+// once; a publish that fails, or whose confirmation is lost, is retried by
+// the relay loop (Start) until it succeeds. Every publish of a record
+// carries the same Nats-Msg-Id, so JetStream drops the duplicates a relay
+// retry can produce.
+//
+// An Outbox publishes without retry confirmation (WithConfirmedRetry): the
+// receiver's acknowledgment is not waited for.
+type Outbox struct {
+	store    OutboxStore
+	nc       NATSPublisher
+	interval time.Duration
+	grace    time.Duration
+	metrics  *Metrics
+	done     chan struct{}
+}
+
+// OutboxOption configures an Outbox.
+type OutboxOption func(*Outbox)
+
+// WithOutboxInterval sets how often the relay looks for unpublished
+// records. The default is DefaultOutboxInterval.
+func WithOutboxInterval(d time.Duration) OutboxOption {
+	return func(o *Outbox) {
+		if d > 0 {
+			o.interval = d
+		}
+	}
+}
+
+// WithOutboxGrace sets how old a record must be before the relay publishes
+// it, leaving the Recover call that created it time to publish first. The
+// default is DefaultOutboxGrace.
+func WithOutboxGrace(d time.Duration) OutboxOption {
+	return func(o *Outbox) {
+		if d >= 0 {
+			o.grace = d
+		}
+	}
+}
+
+// WithOutboxMetrics counts published and failed outbox publishes in m.
+func WithOutboxMetrics(m *Metrics) OutboxOption {
+	return func(o *Outbox) { o.metrics = m }
+}
+
+// WithRecoveryOutbox makes /retry and retry-all recover entries through o.
+// It takes precedence over WithConfirmedRetry. /retry responds 202 with
+// status "queued" when the entry was recovered but its publish was left to
+// the relay; retry-all counts such entries as retried.
+func WithRecoveryOutbox(o *Outbox) HandlerOption {
+	return func(h *Handler) { h.outbox = o }
+}
+
+// WithScannerOutbox makes the scanner recover entries through o. It takes
+// precedence over WithScannerConfirmedRetry.
+func WithScannerOutbox(o *Outbox) ScannerOption {
+	return func(s *Scanner) { s.outbox = o }
+}
+
+// NewOutbox creates an Outbox that records recoveries in store and
+// publishes them on nc.
+func NewOutbox(store OutboxStore, nc NATSPublisher, opts ...OutboxOption) *Outbox {
+	o := &Outbox{
+		store:    store,
+		nc:       nc,
+		interval: DefaultOutboxInterval,
+		grace:    DefaultOutboxGrace,
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Recover marks e recovered by by and publishes payload to subject as its
+// republish, provided e is still at e.Version (see ErrVersionConflict). It
+// returns an error only if the recovery could not be
+// recorded, in which case nothing was published. queued reports that the
+// publish failed and was left to the relay.
+func (o *Outbox) Recover(ctx context.Context, e Entry, by, subject string, payload []byte) (queued bool, err error) {
+	rec, err := o.store.EnqueueRecovery(ctx, OutboxRecord{
+		DLQID:       e.DLQID,
+		Subject:     subject,
+		Payload:     payload,
+		Header:      republishHeader(e, by),
+		RecoveredBy: by,
+		Version:     e.Version,
+	})
+	if err != nil {
+		return false, err
+	}
+	return !o.publish(ctx, rec), nil
+}
+
+// publish sends rec and records the outcome, reporting whether the publish
+// succeeded.
+func (o *Outbox) publish(ctx context.Context, rec OutboxRecord) bool {
+	hdr := nats.Header{}
+	for k, vs := range rec.Header {
+		hdr[k] = vs
+	}
+	hdr.Set(nats.MsgIdHdr, "dlq-outbox-"+strconv.FormatInt(rec.ID, 10))
+
+	err := publishTraced(ctx, o.nc, rec.Subject, rec.Payload, hdr, AttrDLQID.String(rec.DLQID))
+	if err != nil {
+		o.metrics.Inc(MetricOutboxFailed)
+		slog.Warn("dlq outbox: publish failed, left for relay",
+			"dlq_id", rec.DLQID,
+			"outbox_id", rec.ID,
+			"attempts", rec.Attempts+1,
+			"error", err,
+		)
+	} else {
+		o.metrics.Inc(MetricOutboxPublished)
+	}
+	if merr := o.store.MarkRecoveryPublished(ctx, rec.ID, err); merr != nil {
+		// The record stays pending and is published again by the relay;
+		// Nats-Msg-Id lets JetStream drop the duplicate.
+		slog.Error("dlq outbox: failed to record publish",
+			"dlq_id", rec.DLQID,
+			"outbox_id", rec.ID,
+			"error", merr,
+		)
+	}
+	return err == nil
+}
+
+// Start begins the relay loop that publishes records left pending. Call
+// with a cancellable context for shutdown.
+func (o *Outbox) Start(ctx context.Context) {
+	ticker := time.NewTicker(o.interval)
+	go func() {
+		defer ticker.Stop()
+		defer close(o.done)
+		for {
+			select {
+			case <-ticker.C:
+				o.relay(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Wait blocks until the relay loop has stopped.
+func (o *Outbox) Wait() {
+	<-o.done
+}
+
+// relay publishes pending records older than the grace period.
+func (o *Outbox) relay(ctx context.Context) {
+	recs, err := o.store.PendingRecoveries(ctx, time.Now().UTC().Add(-o.grace), outboxBatch)
+	if err != nil {
+		slog.Error("dlq outbox: failed to list pending recoveries", "error", err)
+		return
+	}
+	published := 0
+	for _, rec := range recs {
+		if ctx.Err() != nil {
+			return
+		}
+		if o.publish(ctx, rec) {
+			published++
+		}
+	}
+	if published > 0 {
+		slog.Info("dlq outbox: relayed pending recoveries", "published", published, "pending", len(recs)-published)
+	}
+}
+
+// EnqueueRecovery implements OutboxStore.
+func (s *Store) EnqueueRecovery(ctx context.Context, rec OutboxRecord) (_ OutboxRecord, err error) {
+	defer s.observe("enqueue_recovery", time.Now(), &err)
+	hdr, err := json.Marshal(rec.Header)
+	if err != nil {
+		return rec, fmt.Errorf("enqueue recovery: %w", err)
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return rec, fmt.Errorf("enqueue recovery: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE swarm_dlq
+		SET recovered = true, recovered_at = now(), recovered_by = $2, status = 'recovered',
+		    retry_started_at = NULL, status_before_retry = NULL
+		WHERE dlq_id = $1 AND recovered = false AND ($3 = 0 OR version = $3) AND `+tenantClause(4)+`
+	`, rec.DLQID, rec.RecoveredBy, rec.Version, s.tenant(ctx))
+	if err != nil {
+		return rec, fmt.Errorf("enqueue recovery: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		if rec.Version != 0 {
+			return rec, s.versionConflict(ctx, rec.DLQID, rec.Version)
+		}
+		return rec, ErrAlreadyRecovered
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO swarm_dlq_outbox (dlq_id, subject, payload, headers, recovered_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, rec.DLQID, rec.Subject, rec.Payload, hdr, rec.RecoveredBy).Scan(&rec.ID, &rec.CreatedAt)
+	if err != nil {
+		return rec, fmt.Errorf("enqueue recovery: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return rec, fmt.Errorf("enqueue recovery: %w", err)
+	}
+	return rec, nil
+}
+
+// PendingRecoveries implements OutboxStore.
+func (s *Store) PendingRecoveries(ctx context.Context, createdBefore time.Time, limit int) (_ []OutboxRecord, err error) {
+	defer s.observe("pending_recoveries", time.Now(), &err)
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, dlq_id, subject, payload, headers, recovered_by, created_at, attempts, coalesce(last_error, '')
+		FROM swarm_dlq_outbox
+		WHERE created_at < $1
+		ORDER BY created_at, id
+		LIMIT $2
+	`, createdBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("pending recoveries: %w", err)
+	}
+	defer rows.Close()
+
+	var recs []OutboxRecord
+	for rows.Next() {
+		var rec OutboxRecord
+		var hdr []byte
+		if err := rows.Scan(&rec.ID, &rec.DLQID, &rec.Subject, &rec.Payload, &hdr, &rec.RecoveredBy,
+			&rec.CreatedAt, &rec.Attempts, &rec.LastError); err != nil {
+			return nil, fmt.Errorf("pending recoveries: %w", err)
+		}
+		if len(hdr) > 0 {
+			if err := json.Unmarshal(hdr, &rec.Header); err != nil {
+				return nil, fmt.Errorf("pending recoveries: headers of %d: %w", rec.ID, err)
+			}
+		}
+		recs = append(recs, rec)
+	}
+	return recs, rows.Err()
+}
+
+// MarkRecoveryPublished implements OutboxStore.
+func (s *Store) MarkRecoveryPublished(ctx context.Context, id int64, publishErr error) (err error) {
+	defer s.observe("mark_recovery_published", time.Now(), &err)
+	if publishErr == nil {
+		_, err = s.pool.Exec(ctx, `DELETE FROM swarm_dlq_outbox WHERE id = $1`, id)
+	} else {
+		_, err = s.pool.Exec(ctx, `
+			UPDATE swarm_dlq_outbox SET attempts = attempts + 1, last_error = $2 WHERE id = $1
+		`, id, publishErr.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("mark recovery published: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nats-io/nats.go"
+)
+
+func TestOutbox_RecoverPublishesAndConfirms(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "o1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true})
+	nc := &msgNATS{}
+	o := NewOutbox(store, nc)
+
+	e, _ := store.Get(context.Background(), "o1")
+	queued, err := o.Recover(context.Background(), *e, RecoveredByAPIRetry, "swarm.task.request", []byte(`{"a":1}`))
+	if err != nil || queued {
+		t.Fatalf("recover: queued=%v err=%v", queued, err)
+	}
+	if len(nc.msgs) != 1 || nc.msgs[0].Header.Get(nats.MsgIdHdr) != "dlq-outbox-1" || nc.msgs[0].Header.Get(HeaderDLQID) != "o1" {
+		t.Fatalf("expected one republish carrying the outbox msg id, got %+v", nc.msgs)
+	}
+	if got, _ := store.Get(context.Background(), "o1"); got.Status != StatusRecovered {
+		t.Errorf("expected the entry recovered, got %s", got.Status)
+	}
+	if pending := store.pendingOutbox(); len(pending) != 0 {
+		t.Errorf("expected the outbox record removed once published, got %+v", pending)
+	}
+	again, _ := store.Get(context.Background(), "o1")
+	if _, err := o.Recover(context.Background(), *again, RecoveredByAPIRetry, "swarm.task.request", nil); !errors.Is(err, ErrAlreadyRecovered) {
+		t.Errorf("expected ErrAlreadyRecovered on a second recovery, got %v", err)
+	}
+	if len(nc.msgs) != 1 {
+		t.Errorf("expected no publish for the second recovery, got %d", len(nc.msgs))
+	}
+}
+
+func TestOutbox_RelayPublishesFailedRecoveries(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "o2", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true})
+	nc := newMockNATS()
+	nc.err = errors.New("nats down")
+	m := NewMetrics()
+	o := NewOutbox(store, nc, WithOutboxGrace(0), WithOutboxMetrics(m))
+
+	e, _ := store.Get(context.Background(), "o2")
+	queued, err := o.Recover(context.Background(), *e, RecoveredByScanner, "swarm.task.request", []byte(`{}`))
+	if err != nil || !queued {
+		t.Fatalf("expected the recovery queued, got queued=%v err=%v", queued, err)
+	}
+	if got, _ := store.Get(context.Background(), "o2"); !got.Recovered {
+		t.Error("expected the entry recovered even though the publish failed")
+	}
+	pending := store.pendingOutbox()
+	if len(pending) != 1 || pending[0].Attempts != 1 || pending[0].LastError != "nats down" {
+		t.Fatalf("expected one pending record with the failed attempt, got %+v", pending)
+	}
+
+	nc.mu.Lock()
+	nc.err = nil
+	nc.mu.Unlock()
+	o.relay(context.Background())
+
+	if got := nc.published(); len(got) != 1 || got[0].Subject != "swarm.task.request" {
+		t.Errorf("expected the relay to publish once, got %+v", got)
+	}
+	if pending := store.pendingOutbox(); len(pending) != 0 {
+		t.Errorf("expected the outbox drained, got %+v", pending)
+	}
+	if m.Get(MetricOutboxFailed) != 1 || m.Get(MetricOutboxPublished) != 1 {
+		t.Errorf("unexpected metrics: %v", m.Snapshot())
+	}
+}
+
+func TestHandler_Retry_Outbox(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "o3", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true})
+	nc := newMockNATS()
+	nc.err = errors.New("nats down")
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, nc, WithRecoveryOutbox(NewOutbox(store, nc))).Routes())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/dlq/o3/retry", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 when the publish is left to the relay, got %d: %s", w.Code, w.Body)
+	}
+	var resp map[string]string
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["status"] != "queued" {
+		t.Errorf("expected status queued, got %v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/dlq/o3/retry", nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for an entry already recovered through the outbox, got %d", w.Code)
+	}
+	if pending := store.pendingOutbox(); len(pending) != 1 {
+		t.Errorf("expected a single pending recovery, got %d", len(pending))
+	}
+}
@@ -0,0 +1,90 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// ErrPayloadTooLarge is returned when a payload exceeds the configured
+// PayloadLimit and the policy is PayloadReject.
+var ErrPayloadTooLarge = errors.New("dlq payload too large")
+
+// PayloadPolicy selects what happens to a payload larger than the limit.
+type PayloadPolicy string
+
+const (
+	// PayloadReject refuses the dead letter.
+	PayloadReject PayloadPolicy = "reject"
+	// PayloadTruncate keeps the first MaxBytes of the payload, stored as a
+	// JSON string, and marks the entry payload_truncated.
+	PayloadTruncate PayloadPolicy = "truncate"
+	// PayloadOffload truncates like PayloadTruncate but first writes the
+	// full payload to a BlobStore and records its reference in payload_ref.
+	PayloadOffload PayloadPolicy = "offload"
+)
+
+// BlobStore holds payloads too large to store inline.
+type BlobStore interface {
+	// Put stores data under key and returns a reference that can later be
+	// used to fetch it.
+	Put(ctx context.Context, key string, data []byte) (ref string, err error)
+	// Delete removes the payload behind a reference returned by Put.
+	// Deleting a payload that is already gone is not an error.
+	Delete(ctx context.Context, ref string) error
+}
+
+// PayloadLimit bounds the size of OriginalPayload.
+type PayloadLimit struct {
+	MaxBytes int
+	Policy   PayloadPolicy
+	// Blobs is required for PayloadOffload.
+	Blobs BlobStore
+}
+
+// enforce applies the limit to e. It reports whether the payload was over
+// the limit, and returns ErrPayloadTooLarge if the entry must be refused.
+// Truncated entries are made non-recoverable since their payload can no
+// longer be republished as-is. If offloading fails the payload is still
+// truncated so the dead letter itself is never lost.
+func (l PayloadLimit) enforce(ctx context.Context, e Entry) (Entry, bool, error) {
+	size := len(e.OriginalPayload)
+	if l.MaxBytes <= 0 || size <= l.MaxBytes {
+		return e, false, nil
+	}
+
+	switch l.Policy {
+	case PayloadTruncate:
+	case PayloadOffload:
+		if l.Blobs == nil {
+			slog.Warn("dlq payload offload requested without a blob store, truncating", "dlq_id", e.DLQID)
+			break
+		}
+		ref, err := l.Blobs.Put(ctx, e.DLQID, e.OriginalPayload)
+		if err != nil {
+			slog.Error("dlq payload offload failed, truncating",
+				"dlq_id", e.DLQID,
+				"size", size,
+				"error", err,
+			)
+			break
+		}
+		e.PayloadRef = ref
+	default:
+		return e, true, fmt.Errorf("%w: %d bytes exceeds limit of %d", ErrPayloadTooLarge, size, l.MaxBytes)
+	}
+
+	head := strings.ToValidUTF8(string(e.OriginalPayload[:l.MaxBytes]), "")
+	truncated, err := json.Marshal(head)
+	if err != nil {
+		return e, true, fmt.Errorf("truncate payload: %w", err)
+	}
+	e.OriginalPayload = truncated
+	e.PayloadTruncated = true
+	e.PayloadSize = size
+	e.Recoverable = false
+	return e, true, nil
+}
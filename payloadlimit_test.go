@@ -0,0 +1,165 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type memBlobStore struct {
+	blobs map[string][]byte
+	err   error
+}
+
+func (m *memBlobStore) Put(_ context.Context, key string, data []byte) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	if m.blobs == nil {
+		m.blobs = make(map[string][]byte)
+	}
+	m.blobs[key] = data
+	return "mem://" + key, nil
+}
+
+func (m *memBlobStore) Delete(_ context.Context, ref string) error {
+	if m.err != nil {
+		return m.err
+	}
+	delete(m.blobs, strings.TrimPrefix(ref, "mem://"))
+	return nil
+}
+
+func (m *memBlobStore) Get(_ context.Context, ref string) ([]byte, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	data, ok := m.blobs[strings.TrimPrefix(ref, "mem://")]
+	if !ok {
+		return nil, fmt.Errorf("no blob %s", ref)
+	}
+	return data, nil
+}
+
+func bigEntry(id string) Entry {
+	return Entry{
+		DLQID:           id,
+		OriginalPayload: json.RawMessage(`{"data":"` + strings.Repeat("x", 100) + `"}`),
+		Recoverable:     true,
+	}
+}
+
+func TestPayloadLimit_UnderLimit(t *testing.T) {
+	l := PayloadLimit{MaxBytes: 1000, Policy: PayloadReject}
+	e, violated, err := l.enforce(context.Background(), bigEntry("pl-1"))
+	if err != nil || violated {
+		t.Fatalf("expected no violation, got %v %v", violated, err)
+	}
+	if e.PayloadTruncated {
+		t.Error("payload under the limit should be untouched")
+	}
+}
+
+func TestPayloadLimit_Reject(t *testing.T) {
+	l := PayloadLimit{MaxBytes: 16, Policy: PayloadReject}
+	_, violated, err := l.enforce(context.Background(), bigEntry("pl-2"))
+	if !violated || !errors.Is(err, ErrPayloadTooLarge) {
+		t.Fatalf("expected ErrPayloadTooLarge, got %v", err)
+	}
+}
+
+func TestPayloadLimit_Truncate(t *testing.T) {
+	l := PayloadLimit{MaxBytes: 16, Policy: PayloadTruncate}
+	orig := bigEntry("pl-3")
+	e, violated, err := l.enforce(context.Background(), orig)
+	if err != nil || !violated {
+		t.Fatalf("expected violation without error, got %v %v", violated, err)
+	}
+	if !e.PayloadTruncated || e.PayloadSize != len(orig.OriginalPayload) {
+		t.Errorf("expected truncation marker and original size, got %+v", e)
+	}
+	if e.Recoverable {
+		t.Error("truncated entries must not be recoverable")
+	}
+	var head string
+	if err := json.Unmarshal(e.OriginalPayload, &head); err != nil {
+		t.Fatalf("truncated payload should be a JSON string: %v", err)
+	}
+	if head != string(orig.OriginalPayload[:16]) {
+		t.Errorf("unexpected head %q", head)
+	}
+}
+
+func TestPayloadLimit_Offload(t *testing.T) {
+	blobs := &memBlobStore{}
+	l := PayloadLimit{MaxBytes: 16, Policy: PayloadOffload, Blobs: blobs}
+	orig := bigEntry("pl-4")
+	e, _, err := l.enforce(context.Background(), orig)
+	if err != nil {
+		t.Fatalf("enforce: %v", err)
+	}
+	if e.PayloadRef != "mem://pl-4" {
+		t.Errorf("expected payload ref, got %q", e.PayloadRef)
+	}
+	if string(blobs.blobs["pl-4"]) != string(orig.OriginalPayload) {
+		t.Error("expected full payload in blob store")
+	}
+	if !e.PayloadTruncated {
+		t.Error("offloaded payloads are truncated inline")
+	}
+}
+
+func TestPayloadLimit_OffloadFailureStillTruncates(t *testing.T) {
+	l := PayloadLimit{MaxBytes: 16, Policy: PayloadOffload, Blobs: &memBlobStore{err: fmt.Errorf("s3 down")}}
+	e, _, err := l.enforce(context.Background(), bigEntry("pl-5"))
+	if err != nil {
+		t.Fatalf("offload failure must not lose the entry: %v", err)
+	}
+	if !e.PayloadTruncated || e.PayloadRef != "" {
+		t.Errorf("expected truncated entry without ref, got %+v", e)
+	}
+}
+
+func TestProcessor_PayloadLimit(t *testing.T) {
+	store := newMockStore()
+	metrics := NewMetrics()
+	proc := NewProcessor(store,
+		WithProcessorPayloadLimit(PayloadLimit{MaxBytes: 16, Policy: PayloadReject}),
+		WithProcessorMetrics(metrics),
+	)
+
+	small := eventJSON(Entry{DLQID: "pl-ok", OriginalPayload: json.RawMessage(`{}`)})
+	big := eventJSON(bigEntry("pl-big"))
+	proc.Process(context.Background(), SubjectTaskUnassignable, small)
+	proc.Process(context.Background(), SubjectTaskUnassignable, big)
+
+	if store.insertCalls != 1 {
+		t.Errorf("expected only the small entry to be inserted, got %d", store.insertCalls)
+	}
+	if got := metrics.Get(MetricProcessorPayloadTooLarge); got != 1 {
+		t.Errorf("expected 1 violation, got %d", got)
+	}
+}
+
+func TestHandler_Retry_PayloadTruncated(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(Entry{DLQID: "tr-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`"{\"da"`), PayloadTruncated: true})
+	r := newTestRouter(store, nc)
+
+	req := httptest.NewRequest("POST", "/dlq/tr-1/retry", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", w.Code)
+	}
+	if len(nc.published()) != 0 {
+		t.Error("expected nothing republished")
+	}
+}
@@ -0,0 +1,32 @@
+package dlq
+
+// Sort orders for List and GET /?sort=.
+const (
+	// ListSortFailedAt lists the newest failures first. It is the default.
+	ListSortFailedAt = "failed_at"
+	// ListSortPriority lists the highest Priority first, newest first
+	// within a priority.
+	ListSortPriority = "priority"
+)
+
+// listOrder returns the ORDER BY clause for sortBy, which must be valid.
+func listOrder(sortBy string) string {
+	if sortBy == ListSortPriority {
+		return ` ORDER BY priority DESC, failed_at DESC`
+	}
+	return ` ORDER BY failed_at DESC`
+}
+
+// validListSort reports whether sortBy is a sort order List understands.
+func validListSort(sortBy string) bool {
+	return sortBy == "" || sortBy == ListSortFailedAt || sortBy == ListSortPriority
+}
+
+// listLess reports whether a comes before b in a listing sorted by sortBy,
+// for merging listings in memory the way List orders them.
+func listLess(sortBy string, a, b *Entry) bool {
+	if sortBy == ListSortPriority && a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return a.FailedAt.After(b.FailedAt)
+}
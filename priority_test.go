@@ -0,0 +1,75 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func seedPriorities(store *mockStore) {
+	now := time.Now().UTC()
+	store.seed(
+		Entry{DLQID: "p-low", OriginalSubject: "low", OriginalPayload: json.RawMessage(`{}`), Recoverable: true, FailedAt: now.Add(-3 * time.Minute), Priority: -1},
+		Entry{DLQID: "p-old", OriginalSubject: "normal.old", OriginalPayload: json.RawMessage(`{}`), Recoverable: true, FailedAt: now.Add(-2 * time.Minute)},
+		Entry{DLQID: "p-new", OriginalSubject: "normal.new", OriginalPayload: json.RawMessage(`{}`), Recoverable: true, FailedAt: now.Add(-time.Minute)},
+		Entry{DLQID: "p-high", OriginalSubject: "high", OriginalPayload: json.RawMessage(`{}`), Recoverable: true, FailedAt: now, Priority: 10},
+	)
+}
+
+func TestScanner_RetriesHighPriorityFirst(t *testing.T) {
+	store := newMockStore()
+	seedPriorities(store)
+	nc := newMockNATS()
+	NewScanner(store, nc, time.Minute).scan(context.Background())
+
+	var got []string
+	for _, m := range nc.published() {
+		got = append(got, m.Subject)
+	}
+	want := []string{"high", "normal.old", "normal.new", "low"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestHandler_List_SortByPriority(t *testing.T) {
+	store := newMockStore()
+	seedPriorities(store)
+	r := newTestRouter(store, newMockNATS())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/?sort=priority&limit=2", nil))
+	var entries []Entry
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("decode: %v: %s", err, w.Body)
+	}
+	if len(entries) != 2 || entries[0].DLQID != "p-high" || entries[1].DLQID != "p-new" {
+		t.Errorf("expected p-high then p-new, got %+v", entries)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/?sort=size", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown sort, got %d", w.Code)
+	}
+}
+
+func TestPublisher_CopiesPriority(t *testing.T) {
+	js := &fakeJetStream{}
+	p := NewPublisher(nil, SourceDispatch, WithPublisherJetStream(js, 0))
+	if err := p.Publish(PublishOpts{OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Priority: 5}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	var e Entry
+	if err := json.Unmarshal(js.msg.Data, &e); err != nil || e.Priority != 5 {
+		t.Errorf("expected priority 5 in the event, got %d (%v)", e.Priority, err)
+	}
+}
@@ -3,32 +3,473 @@ package dlq
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
-	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Default worker pool sizing for the Processor.
+const (
+	DefaultProcessorWorkers   = 4
+	DefaultProcessorQueueSize = 256
+	DefaultProcessorBatchWait = 50 * time.Millisecond
 )
 
+// ErrProcessorBusy is returned by TryEnqueue when the ingest queue is full.
+// JetStream consumers should NAK the message so it is redelivered later.
+var ErrProcessorBusy = errors.New("dlq processor: ingest queue full")
+
+// ErrInvalidEvent is wrapped by ProcessWithResult errors for events that can
+// never be stored: malformed, missing a required field, with an unknown
+// reason or too large. Redelivering them will not help, so JetStream
+// consumers should terminate the message. Any other error is transient.
+var ErrInvalidEvent = errors.New("invalid dlq event")
+
 // Processor handles incoming DLQ NATS messages and persists them to swarm_dlq.
 // This is used by Chronicle: on any dlq.> event, call Process() to write to the
 // structured DLQ table in addition to the raw swarm_events log.
+//
+// For high-volume subscriptions, call Start() and feed messages through
+// Enqueue() or TryEnqueue() instead: events are then processed by a bounded
+// worker pool, and a full queue pushes back on the subscription rather than
+// fanning out an unbounded number of goroutines.
 type Processor struct {
-	store DataStore
+	store     Writer
+	events    NATSPublisher
+	metrics   *Metrics
+	workers   int
+	queue     chan RawEvent
+	wg        sync.WaitGroup
+	batchSize int
+	batchWait time.Duration
+	quotas    *sourceQuotas
+	storms    *stormDetector
+	dedupe    *dedupeCache
+	sampler   *overloadSampler
+	limit     *PayloadLimit
+	replicas  *Replicator
+	audit     AuditRecorder
+	live      *LiveFeed
+	notifier  Notifier
+	redactor  *Redactor
+
+	fingerprints bool
+	taskIDFields []string
+	tenant       string
+
+	allowUnknownReasons bool
+
+	enrichers     []Enricher
+	enrichTimeout time.Duration
+}
+
+// RawEvent is a DLQ event as received from NATS, for ProcessBatch.
+type RawEvent struct {
+	Subject string
+	Data    []byte
+}
+
+// ProcessorOption configures a Processor.
+type ProcessorOption func(*Processor)
+
+// WithProcessorWorkers sets the number of workers started by Start.
+func WithProcessorWorkers(n int) ProcessorOption {
+	return func(p *Processor) {
+		if n > 0 {
+			p.workers = n
+		}
+	}
+}
+
+// WithProcessorQueueSize sets the capacity of the ingest queue.
+func WithProcessorQueueSize(n int) ProcessorOption {
+	return func(p *Processor) {
+		if n > 0 {
+			p.queue = make(chan RawEvent, n)
+		}
+	}
+}
+
+// WithProcessorMetrics records queue depth and rejections in m.
+func WithProcessorMetrics(m *Metrics) ProcessorOption {
+	return func(p *Processor) { p.metrics = m }
+}
+
+// WithProcessorEvents publishes a dlq.entry.created notification to nc after
+// each entry is persisted.
+func WithProcessorEvents(nc NATSPublisher) ProcessorOption {
+	return func(p *Processor) { p.events = nc }
+}
+
+// WithProcessorNotifier sends a NotifyEntryCreated notification to n for
+// each newly created entry. Wrap slow notifiers in an AsyncNotifier so they
+// do not hold up ingestion.
+func WithProcessorNotifier(n Notifier) ProcessorOption {
+	return func(p *Processor) { p.notifier = n }
+}
+
+// WithProcessorAudit records an entry.ingest audit record in a for every
+// newly created entry, attributed to its producer service (or source).
+func WithProcessorAudit(a AuditRecorder) ProcessorOption {
+	return func(p *Processor) { p.audit = a }
+}
+
+// WithProcessorSourceQuota limits how many entries per hour are persisted for
+// source. Beyond the limit, a single quota_exceeded entry is written for the
+// rest of the hour, the remaining events are dropped and counted, and a
+// dlq.quota.exceeded alert is emitted (see WithProcessorEvents). When the
+// hour is up the dropped count is written to the aggregate's occurrences.
+func WithProcessorSourceQuota(source string, perHour int) ProcessorOption {
+	return func(p *Processor) {
+		if p.quotas == nil {
+			p.quotas = newSourceQuotas()
+		}
+		p.quotas.limits[source] = perHour
+	}
+}
+
+// WithProcessorStormCollapse collapses bursts of near-identical events (same
+// fingerprint and source, each within window of the last) into the first
+// entry of the burst. The entry's occurrences counter and last_seen_at are
+// advanced instead of inserting new rows, and up to maxSamples distinct
+// encodings of the payload are kept in payload_samples.
+func WithProcessorStormCollapse(window time.Duration, maxSamples int) ProcessorOption {
+	return func(p *Processor) {
+		if maxSamples < 0 {
+			maxSamples = DefaultStormSamples
+		}
+		p.storms = newStormDetector(window, maxSamples)
+	}
+}
+
+// WithProcessorAdaptiveSampling keeps only one in every keepOneIn payloads
+// while more than perSecond events per second are being ingested. Every event
+// still gets a row; entries whose payload was dropped are marked
+// payload_omitted, made non-recoverable, and all entries ingested while
+// sampling record the sample_rate in effect.
+func WithProcessorAdaptiveSampling(perSecond, keepOneIn int) ProcessorOption {
+	return func(p *Processor) {
+		if perSecond > 0 && keepOneIn > 0 {
+			p.sampler = newOverloadSampler(perSecond, keepOneIn)
+		}
+	}
+}
+
+// WithProcessorPayloadLimit enforces l on every ingested payload. Rejected
+// events are dropped with a warning; every violation is counted in
+// MetricProcessorPayloadTooLarge.
+func WithProcessorPayloadLimit(l PayloadLimit) ProcessorOption {
+	return func(p *Processor) { p.limit = &l }
+}
+
+// WithProcessorReplication hands every newly persisted entry to r for
+// forwarding to another environment. Duplicates are not replicated.
+func WithProcessorReplication(r *Replicator) ProcessorOption {
+	return func(p *Processor) { p.replicas = r }
+}
+
+// WithProcessorBatching makes each worker buffer decoded entries and write
+// them with InsertBatch once maxSize entries are pending or maxWait has
+// elapsed since the first one arrived, whichever comes first. If a batch
+// insert fails the entries are retried individually so each failure is
+// reported on its own.
+func WithProcessorBatching(maxSize int, maxWait time.Duration) ProcessorOption {
+	return func(p *Processor) {
+		p.batchSize = maxSize
+		p.batchWait = maxWait
+		if p.batchWait <= 0 {
+			p.batchWait = DefaultProcessorBatchWait
+		}
+	}
 }
 
 // NewProcessor creates a DLQ processor for Chronicle integration.
-func NewProcessor(store DataStore) *Processor {
-	return &Processor{store: store}
+func NewProcessor(store Writer, opts ...ProcessorOption) *Processor {
+	p := &Processor{
+		store:        store,
+		workers:      DefaultProcessorWorkers,
+		queue:        make(chan RawEvent, DefaultProcessorQueueSize),
+		taskIDFields: DefaultTaskIDFields,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Start launches the worker pool. When ctx is cancelled workers process
+// what is still queued and exit; call Wait to block until they have
+// stopped.
+func (p *Processor) Start(ctx context.Context) {
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			if p.batchSize > 1 {
+				p.runBatchWorker(ctx)
+			} else {
+				p.runWorker(ctx)
+			}
+		}()
+	}
+}
+
+// Wait blocks until all workers have drained the queue and stopped. Events
+// enqueued after that are dropped and logged.
+func (p *Processor) Wait() {
+	p.wg.Wait()
+	if n := len(p.queue); n > 0 {
+		slog.Warn("dlq processor: stopped with queued events", "count", n)
+	}
+}
+
+// Enqueue queues an event for the worker pool, blocking while the queue is
+// full. This slows the calling subscription down to the rate the store can
+// sustain. It returns ctx.Err() if ctx is cancelled first.
+func (p *Processor) Enqueue(ctx context.Context, subject string, data []byte) error {
+	select {
+	case p.queue <- RawEvent{Subject: subject, Data: data}:
+		p.metrics.Set(MetricProcessorQueueDepth, int64(len(p.queue)))
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryEnqueue queues an event without blocking. It returns ErrProcessorBusy
+// if the queue is full so the caller can NAK the message.
+func (p *Processor) TryEnqueue(subject string, data []byte) error {
+	select {
+	case p.queue <- RawEvent{Subject: subject, Data: data}:
+		p.metrics.Set(MetricProcessorQueueDepth, int64(len(p.queue)))
+		return nil
+	default:
+		p.metrics.Inc(MetricProcessorRejected)
+		return ErrProcessorBusy
+	}
+}
+
+func (p *Processor) runWorker(ctx context.Context) {
+	for {
+		select {
+		case ev := <-p.queue:
+			p.metrics.Set(MetricProcessorQueueDepth, int64(len(p.queue)))
+			p.Process(ctx, ev.Subject, ev.Data)
+		case <-ctx.Done():
+			ctx = context.WithoutCancel(ctx)
+			for {
+				select {
+				case ev := <-p.queue:
+					p.metrics.Set(MetricProcessorQueueDepth, int64(len(p.queue)))
+					p.Process(ctx, ev.Subject, ev.Data)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// pendingEntry is a decoded entry waiting in a worker's batch buffer.
+type pendingEntry struct {
+	subject string
+	entry   Entry
+}
+
+func (p *Processor) runBatchWorker(ctx context.Context) {
+	var batch []pendingEntry
+	timer := time.NewTimer(p.batchWait)
+	stopTimer(timer)
+	defer timer.Stop()
+
+	// add decodes ev into the batch, flushing it once full.
+	add := func(ctx context.Context, ev RawEvent) {
+		p.metrics.Set(MetricProcessorQueueDepth, int64(len(p.queue)))
+		entry, _ := p.decode(ctx, ev.Subject, ev.Data)
+		if entry == nil || p.collapse(ctx, *entry) {
+			return
+		}
+		if len(batch) == 0 {
+			timer.Reset(p.batchWait)
+		}
+		batch = append(batch, pendingEntry{subject: ev.Subject, entry: p.enrich(ctx, *entry)})
+		if len(batch) >= p.batchSize {
+			stopTimer(timer)
+			p.flush(ctx, batch)
+			batch = nil
+		}
+	}
+
+	for {
+		select {
+		case ev := <-p.queue:
+			add(ctx, ev)
+		case <-timer.C:
+			p.flush(ctx, batch)
+			batch = nil
+		case <-ctx.Done():
+			// Don't lose what is buffered or queued just because we're
+			// shutting down.
+			ctx = context.WithoutCancel(ctx)
+			for {
+				select {
+				case ev := <-p.queue:
+					add(ctx, ev)
+				default:
+					p.flush(ctx, batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// stopTimer stops t and drains a fire that was not received, so a later
+// Reset cannot deliver a stale tick (needed before Go 1.23 timer semantics).
+func stopTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+}
+
+// flush writes batch with one InsertBatch call. It returns the error of each
+// entry, in batch order; all nil unless the batch insert failed and entries
+// had to be inserted individually.
+func (p *Processor) flush(ctx context.Context, batch []pendingEntry) []error {
+	errs := make([]error, len(batch))
+	if len(batch) == 0 {
+		return errs
+	}
+	ctx, span := tracer().Start(ctx, "dlq.process.batch", trace.WithAttributes(attribute.Int("dlq.batch_size", len(batch))))
+	defer span.End()
+
+	entries := make([]Entry, len(batch))
+	for i, pe := range batch {
+		entries[i] = pe.entry
+	}
+	created, err := p.store.InsertBatch(ctx, entries)
+	if err == nil {
+		for i, e := range entries {
+			p.persisted(ctx, e, i < len(created) && created[i])
+		}
+		return errs
+	}
+
+	slog.Warn("dlq processor: batch insert failed, retrying individually",
+		"count", len(batch),
+		"error", err,
+	)
+	for i, pe := range batch {
+		errs[i] = p.insert(ctx, pe.subject, pe.entry)
+	}
+	return errs
 }
 
 // Process parses a raw DLQ event payload and inserts it into swarm_dlq.
-// subject is the NATS subject (e.g. "dlq.task.unassignable").
+// subject is the NATS subject (e.g. "dlq.task.unassignable"). Failures are
+// logged; use ProcessWithResult to act on them.
 func (p *Processor) Process(ctx context.Context, subject string, data []byte) {
+	_ = p.ProcessWithResult(ctx, subject, data)
+}
+
+// ProcessWithResult is Process for consumers that acknowledge messages. It
+// returns nil once the event is stored, is a repeat of a stored entry or was
+// deliberately not stored (lifecycle notification, quota, storm). Errors
+// wrapping ErrInvalidEvent are permanent and the message should be
+// terminated; any other error, such as a failed insert, is transient and the
+// message should be redelivered.
+func (p *Processor) ProcessWithResult(ctx context.Context, subject string, data []byte) error {
+	ctx, span := tracer().Start(ctx, "dlq.process "+subject, trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attribute.String("messaging.destination.name", subject)))
+	defer span.End()
+
+	entry, err := p.decode(ctx, subject, data)
+	if err != nil {
+		return err
+	}
+	if entry == nil || p.collapse(ctx, *entry) {
+		return nil
+	}
+	span.SetAttributes(AttrDLQID.String(entry.DLQID), AttrDLQReason.String(entry.Reason), AttrDLQSource.String(entry.Source))
+	return p.insert(ctx, subject, p.enrich(ctx, *entry))
+}
+
+// ProcessBatch processes events together, as fetched by a JetStream pull
+// consumer. Events are decoded and enriched concurrently, up to the
+// Processor's worker count at a time, and the resulting entries are written
+// with a single InsertBatch. It returns the result of each event, in order,
+// with the same meaning as the error of ProcessWithResult.
+func (p *Processor) ProcessBatch(ctx context.Context, events []RawEvent) []error {
+	errs := make([]error, len(events))
+	decoded := make([]*Entry, len(events))
+	p.parallel(len(events), func(i int) {
+		decoded[i], errs[i] = p.decode(ctx, events[i].Subject, events[i].Data)
+	})
+
+	// Collapsing is order dependent, so it runs before the parallel
+	// enrichment rather than inside it.
+	var batch []pendingEntry
+	var index []int
+	for i, entry := range decoded {
+		if entry == nil || p.collapse(ctx, *entry) {
+			continue
+		}
+		batch = append(batch, pendingEntry{subject: events[i].Subject, entry: *entry})
+		index = append(index, i)
+	}
+	p.parallel(len(batch), func(i int) {
+		batch[i].entry = p.enrich(ctx, batch[i].entry)
+	})
+	for i, err := range p.flush(ctx, batch) {
+		errs[index[i]] = err
+	}
+	return errs
+}
+
+// parallel calls fn for 0 <= i < n, running at most p.workers calls at a
+// time, and returns once all have returned.
+func (p *Processor) parallel(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(p.workers, 1))
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+// decode parses a raw DLQ event, fills in defaults and applies ingest quotas
+// and the payload limit. It returns the entry to store, or nil if the event
+// is a lifecycle notification or was dropped by a quota; malformed and
+// oversized events are logged and reported as ErrInvalidEvent.
+func (p *Processor) decode(ctx context.Context, subject string, data []byte) (*Entry, error) {
+	if isEventSubject(subject) {
+		// Our own lifecycle notifications share the dlq.> namespace.
+		return nil, nil
+	}
+
 	var entry Entry
 	if err := json.Unmarshal(data, &entry); err != nil {
 		slog.Warn("dlq processor: malformed dlq event",
 			"subject", subject,
 			"error", err,
 		)
-		return
+		return nil, fmt.Errorf("%w: %w", ErrInvalidEvent, err)
 	}
 
 	// Fill in defaults if publisher didn't set them.
@@ -38,19 +479,228 @@ func (p *Processor) Process(ctx context.Context, subject string, data []byte) {
 	if entry.Source == "" {
 		entry.Source = inferSource(subject)
 	}
+	if entry.Reason == "" {
+		entry.Reason = inferReason(subject)
+	}
+	if err := validateEvent(entry); err != nil {
+		return nil, p.park(ctx, subject, data, err)
+	}
+	if err := p.checkReason(data, &entry); err != nil {
+		return nil, p.park(ctx, subject, data, err)
+	}
+	if unknown := normalizeRetryHistory(entry.RetryHistory); unknown > 0 {
+		p.metrics.Add(MetricProcessorUnknownFailures, int64(unknown))
+	}
+	if p.redactor != nil {
+		redacted, changed, err := p.redactor.apply(entry)
+		if err != nil {
+			// Never store a payload that should have been redacted, and
+			// don't redeliver an event that cannot be: park it.
+			slog.Error("dlq processor: failed to redact payload",
+				"subject", subject,
+				"dlq_id", entry.DLQID,
+				"error", err,
+			)
+			return nil, p.park(ctx, subject, data, fmt.Errorf("redact payload: %w", err))
+		}
+		if changed {
+			p.metrics.Inc(MetricProcessorRedacted)
+		}
+		entry = redacted
+	}
+	if entry.Fingerprint == "" {
+		entry.Fingerprint = Fingerprint(entry.OriginalSubject, entry.Reason, entry.OriginalPayload)
+	}
+	if entry.TaskID == "" {
+		entry.TaskID = extractTaskID(entry.OriginalPayload, p.taskIDFields)
+	}
+	if entry.TenantID == "" {
+		entry.TenantID = p.tenant
+	}
+	if p.dedupe != nil && p.dedupe.contains(entry) {
+		p.metrics.Inc(MetricProcessorDeduplicated)
+		return nil, nil
+	}
+	entry, ok := p.admit(ctx, subject, entry)
+	if !ok {
+		return nil, nil
+	}
+	if p.sampler != nil {
+		var omitted bool
+		if entry, omitted = p.sampler.apply(entry); omitted {
+			p.metrics.Inc(MetricProcessorPayloadsOmitted)
+		}
+	}
+	if p.limit != nil {
+		limited, violated, err := p.limit.enforce(ctx, entry)
+		if violated {
+			p.metrics.Inc(MetricProcessorPayloadTooLarge)
+		}
+		if err != nil {
+			slog.Warn("dlq processor: dropping oversized payload",
+				"subject", subject,
+				"dlq_id", entry.DLQID,
+				"error", err,
+			)
+			return nil, fmt.Errorf("%w: %w", ErrInvalidEvent, err)
+		}
+		entry = limited
+	}
+	return &entry, nil
+}
 
-	if err := p.store.Insert(ctx, entry); err != nil {
+// admit applies per-source ingest quotas, possibly replacing entry with a
+// quota_exceeded aggregate or dropping it.
+func (p *Processor) admit(ctx context.Context, subject string, entry Entry) (Entry, bool) {
+	if p.quotas == nil {
+		return entry, true
+	}
+	d := p.quotas.admit(subject, entry)
+	for _, ev := range d.alerts {
+		p.quotaAlert(ctx, ev)
+	}
+	if !d.admit {
+		p.metrics.Inc(MetricProcessorQuotaDropped)
+		return Entry{}, false
+	}
+	if d.aggregate != nil {
+		p.metrics.Inc(MetricProcessorQuotaDropped)
+		return *d.aggregate, true
+	}
+	return entry, true
+}
+
+// quotaAlert logs and publishes a quota event. The first alert of a window
+// schedules its close for when the hour is up; the final one writes the
+// dropped count to the aggregate entry.
+func (p *Processor) quotaAlert(ctx context.Context, ev QuotaExceededEvent) {
+	logQuotaAlert(ev)
+	if p.events != nil {
+		publishEvent(ctx, p.events, SubjectQuotaExceeded, ev)
+	}
+	if ev.Final {
+		p.recordQuotaDrops(ctx, ev)
+		return
+	}
+	time.AfterFunc(ev.WindowEnd.Sub(p.quotas.now()), func() {
+		if final, ok := p.quotas.expire(ev.Source, ev.DLQID); ok {
+			p.quotaAlert(context.Background(), final)
+		}
+	})
+}
+
+// recordQuotaDrops folds the events dropped in a closed quota window into
+// the aggregate entry's occurrences. The aggregate itself stands for the
+// first of them.
+func (p *Processor) recordQuotaDrops(ctx context.Context, ev QuotaExceededEvent) {
+	if ev.Dropped <= 1 {
+		return
+	}
+	if err := p.store.RecordOccurrences(ctx, ev.DLQID, ev.Dropped-1, ev.LastDroppedAt, nil); err != nil {
+		slog.Error("dlq processor: failed to record quota drops",
+			"dlq_id", ev.DLQID,
+			"source", ev.Source,
+			"dropped", ev.Dropped,
+			"error", err,
+		)
+	}
+}
+
+func (p *Processor) insert(ctx context.Context, subject string, entry Entry) error {
+	created, err := p.store.Insert(ctx, entry)
+	if err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
 		slog.Error("dlq processor: failed to insert",
 			"dlq_id", entry.DLQID,
 			"subject", subject,
 			"error", err,
 		)
+		if p.storms != nil {
+			p.storms.forget(entry)
+		}
+		return err
+	}
+	p.persisted(ctx, entry, created)
+	return nil
+}
+
+// persisted runs after entry has been written to the store. created is false
+// when the store already held an entry with the same dlq_id: the event is a
+// repeat of a known failure, so it is counted as a duplicate and no
+// entry-created notification is sent.
+func (p *Processor) persisted(ctx context.Context, entry Entry, created bool) {
+	if p.dedupe != nil {
+		p.dedupe.remember(entry)
 	}
+	if p.storms != nil {
+		if delta := p.storms.persisted(entry); delta.count > 0 {
+			p.recordOccurrences(ctx, entry.DLQID, delta)
+		}
+	}
+	if !created {
+		p.metrics.Inc(MetricProcessorDuplicates)
+		slog.Info("dlq processor: duplicate entry", "dlq_id", entry.DLQID, "reason", entry.Reason)
+		return
+	}
+	if p.events != nil {
+		publishEntryCreated(ctx, p.events, entry)
+	}
+	if p.replicas != nil {
+		p.replicas.Enqueue(entry)
+	}
+	p.live.Broadcast(liveEvent(LiveIngested, entry, ""))
+	notify(ctx, p.notifier, entryNotification(NotifyEntryCreated, entry))
+	if p.audit != nil {
+		actor := entry.ProducerService
+		if actor == "" {
+			actor = entry.Source
+		}
+		recordAudit(ctx, p.audit, AuditRecord{
+			Actor: actor, Action: AuditIngest, DLQID: entry.DLQID,
+			At: time.Now().UTC(), Result: AuditResultOK, Detail: entry.OriginalSubject,
+		})
+	}
+}
+
+// collapse folds entry into an open entry with the same fingerprint or an
+// ongoing storm. It reports true if the entry was absorbed and must not be
+// inserted on its own.
+func (p *Processor) collapse(ctx context.Context, entry Entry) bool {
+	if entry.Reason == ReasonQuotaExceeded {
+		// A quota aggregate carries its sample's fingerprint but must stay
+		// its own entry so the dropped count lands on it.
+		return false
+	}
+	if !p.collapseFingerprint(ctx, entry) && !p.collapseStorm(ctx, entry) {
+		return false
+	}
+	if p.dedupe != nil {
+		p.dedupe.remember(entry)
+	}
+	return true
 }
 
-func inferSource(subject string) string {
-	if strings.HasPrefix(subject, "dlq.agent.") {
-		return SourceWarren
+func (p *Processor) collapseStorm(ctx context.Context, entry Entry) bool {
+	if p.storms == nil {
+		return false
+	}
+	dlqID, delta, collapsed := p.storms.observe(entry)
+	if !collapsed {
+		return false
+	}
+	p.metrics.Inc(MetricProcessorCollapsed)
+	if delta.count > 0 {
+		p.recordOccurrences(ctx, dlqID, delta)
+	}
+	return true
+}
+
+func (p *Processor) recordOccurrences(ctx context.Context, dlqID string, delta stormDelta) {
+	if err := p.store.RecordOccurrences(ctx, dlqID, delta.count, delta.lastSeen, delta.samples); err != nil {
+		slog.Error("dlq processor: failed to record collapsed occurrences",
+			"dlq_id", dlqID,
+			"count", delta.count,
+			"error", err,
+		)
 	}
-	return SourceDispatch
 }
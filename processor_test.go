@@ -3,6 +3,7 @@ package dlq
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -160,6 +161,33 @@ func TestProcessor_Process_InsertError(t *testing.T) {
 	}
 }
 
+func TestProcessor_ProcessWithResult(t *testing.T) {
+	store := newMockStore()
+	proc := NewProcessor(store)
+	ctx := context.Background()
+	valid := eventJSON(Entry{DLQID: "pwr-1", Reason: ReasonNoCapableAgent})
+
+	if err := proc.ProcessWithResult(ctx, SubjectTaskUnassignable, valid); err != nil {
+		t.Errorf("valid event: %v", err)
+	}
+	if err := proc.ProcessWithResult(ctx, SubjectEntryCreated, []byte(`{}`)); err != nil {
+		t.Errorf("lifecycle event: %v", err)
+	}
+	if err := proc.ProcessWithResult(ctx, SubjectTaskUnassignable, []byte("not json")); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("malformed event: expected ErrInvalidEvent, got %v", err)
+	}
+	if err := proc.ProcessWithResult(ctx, SubjectTaskUnassignable, []byte(`{"dlq_id":"pwr-2"}`)); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("incomplete event: expected ErrInvalidEvent, got %v", err)
+	}
+
+	dbErr := errors.New("db write failed")
+	store.insertErr = dbErr
+	err := proc.ProcessWithResult(ctx, SubjectTaskUnassignable, eventJSON(Entry{DLQID: "pwr-3", Reason: ReasonNoCapableAgent}))
+	if !errors.Is(err, dbErr) || errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("insert failure: expected a transient error, got %v", err)
+	}
+}
+
 func TestProcessor_Process_PreservesExistingSource(t *testing.T) {
 	store := newMockStore()
 	proc := NewProcessor(store)
@@ -182,3 +210,239 @@ func TestProcessor_Process_PreservesExistingSource(t *testing.T) {
 		t.Errorf("expected preserved source dispatch, got %s", stored.Source)
 	}
 }
+
+func TestProcessor_WorkerPool_ProcessesQueuedEvents(t *testing.T) {
+	store := newMockStore()
+	metrics := NewMetrics()
+	proc := NewProcessor(store, WithProcessorWorkers(2), WithProcessorMetrics(metrics))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	proc.Start(ctx)
+
+	for i := 0; i < 10; i++ {
+		data := eventJSON(Entry{DLQID: fmt.Sprintf("pool-%d", i), Reason: ReasonNoCapableAgent})
+		if err := proc.Enqueue(ctx, "dlq.task.unassignable", data); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for store.inserted() < 10 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	proc.Wait()
+
+	if got := store.inserted(); got != 10 {
+		t.Errorf("expected 10 inserts, got %d", got)
+	}
+	if depth := metrics.Get(MetricProcessorQueueDepth); depth != 0 {
+		t.Errorf("expected queue depth 0, got %d", depth)
+	}
+}
+
+func TestProcessor_TryEnqueue_QueueFull(t *testing.T) {
+	metrics := NewMetrics()
+	proc := NewProcessor(newMockStore(), WithProcessorQueueSize(1), WithProcessorMetrics(metrics))
+
+	// Workers not started, so the queue fills after one event.
+	if err := proc.TryEnqueue("dlq.task.unassignable", []byte(`{}`)); err != nil {
+		t.Fatalf("first enqueue: %v", err)
+	}
+	if err := proc.TryEnqueue("dlq.task.unassignable", []byte(`{}`)); err != ErrProcessorBusy {
+		t.Errorf("expected ErrProcessorBusy, got %v", err)
+	}
+	if got := metrics.Get(MetricProcessorRejected); got != 1 {
+		t.Errorf("expected 1 rejection, got %d", got)
+	}
+	if got := metrics.Get(MetricProcessorQueueDepth); got != 1 {
+		t.Errorf("expected queue depth 1, got %d", got)
+	}
+}
+
+func TestProcessor_Enqueue_BlocksUntilContextDone(t *testing.T) {
+	proc := NewProcessor(newMockStore(), WithProcessorQueueSize(1))
+	_ = proc.TryEnqueue("dlq.task.unassignable", []byte(`{}`))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := proc.Enqueue(ctx, "dlq.task.unassignable", []byte(`{}`)); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestProcessor_Batching_FlushesOnSize(t *testing.T) {
+	store := newMockStore()
+	proc := NewProcessor(store, WithProcessorWorkers(1), WithProcessorBatching(5, time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	proc.Start(ctx)
+
+	for i := 0; i < 5; i++ {
+		data := eventJSON(Entry{DLQID: fmt.Sprintf("batch-%d", i)})
+		_ = proc.Enqueue(ctx, "dlq.task.unassignable", data)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for store.batches() < 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	proc.Wait()
+
+	if got := store.batches(); got != 1 {
+		t.Errorf("expected 1 batch insert, got %d", got)
+	}
+	if got := store.inserted(); got != 0 {
+		t.Errorf("expected no individual inserts, got %d", got)
+	}
+	if _, err := store.Get(context.Background(), "batch-4"); err != nil {
+		t.Errorf("expected batch-4 stored: %v", err)
+	}
+}
+
+func TestProcessor_Batching_FlushesOnTimeout(t *testing.T) {
+	store := newMockStore()
+	proc := NewProcessor(store, WithProcessorWorkers(1), WithProcessorBatching(100, 10*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	proc.Start(ctx)
+
+	data := eventJSON(Entry{DLQID: "batch-timeout"})
+	_ = proc.Enqueue(ctx, "dlq.task.unassignable", data)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for store.batches() < 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := store.Get(context.Background(), "batch-timeout"); err != nil {
+		t.Errorf("expected entry flushed after timeout: %v", err)
+	}
+}
+
+func TestProcessor_Batching_FallsBackToIndividualInserts(t *testing.T) {
+	store := newMockStore()
+	store.batchErr = fmt.Errorf("batch failed")
+	proc := NewProcessor(store, WithProcessorWorkers(1), WithProcessorBatching(2, time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	proc.Start(ctx)
+
+	for i := 0; i < 2; i++ {
+		data := eventJSON(Entry{DLQID: fmt.Sprintf("fallback-%d", i)})
+		_ = proc.Enqueue(ctx, "dlq.task.unassignable", data)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for store.inserted() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	proc.Wait()
+
+	if got := store.inserted(); got != 2 {
+		t.Errorf("expected 2 individual inserts after batch failure, got %d", got)
+	}
+}
+
+func TestProcessor_ProcessBatch(t *testing.T) {
+	store := newMockStore()
+	proc := NewProcessor(store)
+	ctx := context.Background()
+
+	events := []RawEvent{
+		{Subject: SubjectTaskUnassignable, Data: eventJSON(Entry{DLQID: "pb-1"})},
+		{Subject: SubjectTaskUnassignable, Data: []byte("not json")},
+		{Subject: SubjectEntryCreated, Data: []byte(`{}`)},
+		{Subject: SubjectTaskUnassignable, Data: eventJSON(Entry{DLQID: "pb-2"})},
+	}
+	errs := proc.ProcessBatch(ctx, events)
+	if len(errs) != len(events) || errs[0] != nil || !errors.Is(errs[1], ErrInvalidEvent) || errs[2] != nil || errs[3] != nil {
+		t.Fatalf("unexpected results %v", errs)
+	}
+	if store.batches() != 1 || store.inserted() != 0 {
+		t.Errorf("expected one batch insert, got %d batches and %d inserts", store.batches(), store.inserted())
+	}
+	for _, id := range []string{"pb-1", "pb-2"} {
+		if _, err := store.Get(ctx, id); err != nil {
+			t.Errorf("%s not stored: %v", id, err)
+		}
+	}
+
+	store.batchErr = errors.New("batch failed")
+	store.insertErr = errors.New("db down")
+	errs = proc.ProcessBatch(ctx, []RawEvent{{Subject: SubjectTaskUnassignable, Data: eventJSON(Entry{DLQID: "pb-3"})}})
+	if len(errs) != 1 || errs[0] == nil || errors.Is(errs[0], ErrInvalidEvent) {
+		t.Errorf("expected a transient error, got %v", errs)
+	}
+}
+
+func TestProcessor_Batching_FlushesOnShutdown(t *testing.T) {
+	store := newMockStore()
+	proc := NewProcessor(store, WithProcessorWorkers(1), WithProcessorBatching(100, time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	proc.Start(ctx)
+
+	data := eventJSON(Entry{DLQID: "batch-shutdown"})
+	_ = proc.Enqueue(ctx, "dlq.task.unassignable", data)
+
+	// Give the worker a moment to pick the event off the queue.
+	deadline := time.Now().Add(2 * time.Second)
+	for len(proc.queue) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+	proc.Wait()
+
+	if _, err := store.Get(context.Background(), "batch-shutdown"); err != nil {
+		t.Errorf("expected buffered entry flushed on shutdown: %v", err)
+	}
+}
+
+func TestProcessor_DrainsQueueOnShutdown(t *testing.T) {
+	for name, opts := range map[string][]ProcessorOption{
+		"single":  {WithProcessorWorkers(2)},
+		"batched": {WithProcessorWorkers(2), WithProcessorBatching(3, time.Hour)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := newMockStore()
+			proc := NewProcessor(store, opts...)
+			ctx, cancel := context.WithCancel(context.Background())
+
+			const n = 20
+			for i := 0; i < n; i++ {
+				_ = proc.Enqueue(ctx, SubjectTaskUnassignable, eventJSON(Entry{DLQID: fmt.Sprintf("drain-%d", i)}))
+			}
+			cancel()
+			proc.Start(ctx)
+			proc.Wait()
+
+			for i := 0; i < n; i++ {
+				if _, err := store.Get(context.Background(), fmt.Sprintf("drain-%d", i)); err != nil {
+					t.Errorf("drain-%d: expected the queued event stored on shutdown: %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessor_Process_CountsDuplicates(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	metrics := NewMetrics()
+	proc := NewProcessor(store, WithProcessorEvents(nc), WithProcessorMetrics(metrics))
+
+	data := eventJSON(Entry{DLQID: "dup-1", Reason: ReasonNoCapableAgent})
+	proc.Process(context.Background(), SubjectTaskUnassignable, data)
+	proc.Process(context.Background(), SubjectTaskUnassignable, data)
+
+	if got := metrics.Get(MetricProcessorDuplicates); got != 1 {
+		t.Errorf("expected 1 duplicate, got %d", got)
+	}
+	if got := len(nc.events(SubjectEntryCreated)); got != 1 {
+		t.Errorf("expected only the first insert to emit a created event, got %d", got)
+	}
+}
@@ -0,0 +1,149 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultPublisherQueueSize is the capacity of the async publish queue when
+// WithPublisherAsync is given a non-positive size.
+const DefaultPublisherQueueSize = 1024
+
+// ErrPublishQueueFull is returned by an async Publisher whose queue is full
+// and that has no buffer to spill to.
+var ErrPublishQueueFull = errors.New("dlq publisher: async queue full")
+
+// WithPublisherAsync makes Publish return as soon as the event is queued, so
+// a slow or unreachable NATS never holds up the producer. A background
+// sender started by Start publishes queued events with the configured retry.
+// Events it still cannot publish are spilled to the buffer (see
+// WithPublisherBuffer; use a DiskPublishBuffer to survive restarts), as are
+// events published while the queue is full or while the connection is down.
+// The buffer is replayed on reconnect. Without a buffer such events are
+// logged and counted in MetricPublisherDropped.
+//
+// Publish still fails synchronously for events it cannot build, such as
+// payloads refused by the payload limit. Events queued when Start's context
+// is cancelled are spilled to the buffer, or sent once without retry. While
+// the sender is not running, before Start or after its context is
+// cancelled, Publish sends synchronously as without WithPublisherAsync,
+// spilling to the buffer on failure, and returns an error if neither works.
+func WithPublisherAsync(queueSize int) PublisherOption {
+	return func(p *Publisher) {
+		if queueSize <= 0 {
+			queueSize = DefaultPublisherQueueSize
+		}
+		p.queue = make(chan asyncEvent, queueSize)
+	}
+}
+
+// asyncEvent is a queued event and the span context of the Publish call, so
+// the background publish continues the producer's trace.
+type asyncEvent struct {
+	event BufferedEvent
+	span  trace.SpanContext
+}
+
+// enqueue queues ev for the background sender, spilling it to the buffer if
+// the queue is full. Without a running sender nothing would drain the queue,
+// so ev is published synchronously instead.
+func (p *Publisher) enqueue(ctx context.Context, ev BufferedEvent) error {
+	p.asyncMu.RLock()
+	if !p.running {
+		p.asyncMu.RUnlock()
+		return p.publishNow(ctx, ev)
+	}
+	select {
+	case p.queue <- asyncEvent{event: ev, span: trace.SpanContextFromContext(ctx)}:
+		p.asyncMu.RUnlock()
+		p.metrics.Set(MetricPublisherQueueDepth, int64(len(p.queue)))
+		return nil
+	default:
+		p.asyncMu.RUnlock()
+	}
+	if p.buffer == nil {
+		p.metrics.Inc(MetricPublisherDropped)
+		return ErrPublishQueueFull
+	}
+	return p.spill(ev, ErrPublishQueueFull)
+}
+
+// runAsync publishes queued events until ctx is done, then drains the queue.
+func (p *Publisher) runAsync(ctx context.Context) {
+	for {
+		select {
+		case a := <-p.queue:
+			p.metrics.Set(MetricPublisherQueueDepth, int64(len(p.queue)))
+			p.deliver(ctx, a)
+		case <-ctx.Done():
+			// Once running is cleared no Publish adds to the queue, so the
+			// drain below sees every queued event.
+			p.asyncMu.Lock()
+			p.running = false
+			p.asyncMu.Unlock()
+			p.drainAsync(context.WithoutCancel(ctx))
+			return
+		}
+	}
+}
+
+// deliver publishes one queued event, spilling it to the buffer if the
+// connection is down or publishing fails after all retries.
+func (p *Publisher) deliver(ctx context.Context, a asyncEvent) {
+	ctx = trace.ContextWithSpanContext(ctx, a.span)
+	ev := a.event
+	var err error
+	if p.buffer != nil && p.nc != nil && !p.nc.IsConnected() {
+		// Don't spend the retries on an outage the connection already knows
+		// about; the buffer is flushed on reconnect.
+		err = fmt.Errorf("publish to %s: connection %s", ev.Subject, p.nc.Status())
+	} else {
+		err = p.sendWithRetry(ctx, ev.Subject, ev.DLQID, ev.Data)
+	}
+	if err == nil {
+		return
+	}
+	if p.buffer != nil {
+		if err = p.spill(ev, err); err == nil {
+			return
+		}
+	}
+	p.metrics.Inc(MetricPublisherDropped)
+	slog.Error("dlq publisher: dropped dead letter",
+		"dlq_id", ev.DLQID,
+		"subject", ev.Subject,
+		"error", err,
+	)
+}
+
+// drainAsync empties the queue on shutdown: into the buffer if there is one,
+// otherwise by publishing each event once.
+func (p *Publisher) drainAsync(ctx context.Context) {
+	for {
+		select {
+		case a := <-p.queue:
+			ev := a.event
+			err := errors.New("publisher stopped")
+			if p.buffer == nil {
+				err = p.send(trace.ContextWithSpanContext(ctx, a.span), ev.Subject, ev.DLQID, ev.Data)
+			} else {
+				err = p.spill(ev, err)
+			}
+			if err != nil {
+				p.metrics.Inc(MetricPublisherDropped)
+				slog.Error("dlq publisher: dropped dead letter on shutdown",
+					"dlq_id", ev.DLQID,
+					"subject", ev.Subject,
+					"error", err,
+				)
+			}
+		default:
+			p.metrics.Set(MetricPublisherQueueDepth, 0)
+			return
+		}
+	}
+}
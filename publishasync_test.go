@@ -0,0 +1,128 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// signalJetStream is a JetStreamPublisher that reports every published
+// subject on a channel.
+type signalJetStream struct{ sent chan string }
+
+func (s *signalJetStream) PublishMsg(m *nats.Msg, _ ...nats.PubOpt) (*nats.PubAck, error) {
+	s.sent <- m.Subject
+	return &nats.PubAck{Stream: "DLQ", Sequence: 1}, nil
+}
+
+func TestPublisher_Async(t *testing.T) {
+	js := &signalJetStream{sent: make(chan string)}
+	p := NewPublisher(nil, SourceDispatch, WithPublisherJetStream(js, 0), WithPublisherAsync(1))
+	opts := PublishOpts{OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonPolicyDenied}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Start(ctx)
+	if err := p.Publish(opts); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	// The sender takes the first event and blocks publishing it, so the
+	// second fills the queue and the third overflows it.
+	deadline := time.Now().Add(2 * time.Second)
+	for len(p.queue) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := p.Publish(opts); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if err := p.Publish(opts); !errors.Is(err, ErrPublishQueueFull) {
+		t.Errorf("expected ErrPublishQueueFull without a buffer, got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case subject := <-js.sent:
+			if subject != SubjectTaskPolicyDenied {
+				t.Errorf("published to %s", subject)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("the queued event was not published")
+		}
+	}
+	cancel()
+	p.Wait()
+}
+
+func TestPublisher_AsyncNotRunning(t *testing.T) {
+	opts := PublishOpts{OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonPolicyDenied}
+
+	// Before Start, events are published synchronously.
+	js := &signalJetStream{sent: make(chan string, 1)}
+	p := NewPublisher(nil, SourceDispatch, WithPublisherJetStream(js, 0), WithPublisherAsync(1))
+	if err := p.Publish(opts); err != nil {
+		t.Fatalf("publish before Start: %v", err)
+	}
+	if len(js.sent) != 1 || len(p.queue) != 0 {
+		t.Error("an event published before Start must not wait in the queue")
+	}
+
+	// After Start's context is cancelled, they are spilled to the buffer,
+	// and fail when that is impossible. A nil connection fails every
+	// publish with nats.ErrInvalidConnection.
+	buf := NewMemoryPublishBuffer(1)
+	p = NewPublisher(nil, SourceDispatch, WithPublisherAsync(1), WithPublisherBuffer(buf))
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Start(ctx)
+	cancel()
+	p.Wait()
+	if err := p.Publish(opts); err != nil {
+		t.Fatalf("expected the event buffered after shutdown, got %v", err)
+	}
+	if pending, _ := buf.Pending(); len(pending) != 1 || len(p.queue) != 0 {
+		t.Errorf("expected the event in the buffer, got %d buffered, %d queued", len(pending), len(p.queue))
+	}
+	if err := p.Publish(opts); !errors.Is(err, ErrPublishBufferFull) || !errors.Is(err, nats.ErrInvalidConnection) {
+		t.Errorf("expected the publish and buffer errors, got %v", err)
+	}
+}
+
+func TestPublisher_AsyncSpillsToBuffer(t *testing.T) {
+	metrics := NewMetrics()
+	buf := NewMemoryPublishBuffer(10)
+	// A nil connection fails every publish with nats.ErrInvalidConnection.
+	p := NewPublisher(nil, SourceWarren,
+		WithPublisherAsync(1),
+		WithPublisherRetry(2, time.Millisecond, time.Millisecond),
+		WithPublisherBuffer(buf),
+		WithPublisherMetrics(metrics),
+	)
+	opts := PublishOpts{OriginalSubject: "swarm.agent.boot", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonBootFailure}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Start(ctx)
+	for i := 0; i < 2; i++ {
+		if err := p.Publish(opts); err != nil {
+			t.Fatalf("publish %d: %v", i, err)
+		}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for metrics.Get(MetricPublisherBuffered) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	p.Wait()
+	if got := metrics.Get(MetricPublisherBuffered); got != 2 {
+		t.Fatalf("expected the failed events to be spilled, buffered = %d", got)
+	}
+	if got := metrics.Get(MetricPublisherDropped); got != 0 {
+		t.Errorf("dropped = %d", got)
+	}
+
+	// NATS is back: the spool is replayed.
+	p.js = &fakeJetStream{}
+	if n, err := p.Flush(context.Background()); n != 2 || err != nil {
+		t.Errorf("flush: %d, %v", n, err)
+	}
+}
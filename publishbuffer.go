@@ -0,0 +1,190 @@
+package dlq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrPublishBufferFull is returned when a PublishBuffer is at capacity.
+var ErrPublishBufferFull = errors.New("dlq publisher: buffer full")
+
+// BufferedEvent is a marshalled dead letter waiting to be published.
+type BufferedEvent struct {
+	DLQID   string          `json:"dlq_id"`
+	Subject string          `json:"subject"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// PublishBuffer holds dead letters the Publisher could not send, until the
+// NATS connection recovers. Implementations must be safe for concurrent use.
+type PublishBuffer interface {
+	// Put stores ev, returning ErrPublishBufferFull at capacity.
+	Put(ev BufferedEvent) error
+	// Pending returns the buffered events, oldest first.
+	Pending() ([]BufferedEvent, error)
+	// Remove drops the event with the given dlq_id once it was published.
+	Remove(dlqID string) error
+}
+
+// MemoryPublishBuffer is a PublishBuffer that keeps up to max events in
+// memory. Buffered events are lost if the process exits.
+type MemoryPublishBuffer struct {
+	mu     sync.Mutex
+	max    int
+	events []BufferedEvent
+}
+
+// NewMemoryPublishBuffer creates an in-memory buffer holding up to max events.
+func NewMemoryPublishBuffer(max int) *MemoryPublishBuffer {
+	return &MemoryPublishBuffer{max: max}
+}
+
+// Put implements PublishBuffer.
+func (b *MemoryPublishBuffer) Put(ev BufferedEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.events) >= b.max {
+		return ErrPublishBufferFull
+	}
+	b.events = append(b.events, ev)
+	return nil
+}
+
+// Pending implements PublishBuffer.
+func (b *MemoryPublishBuffer) Pending() ([]BufferedEvent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]BufferedEvent(nil), b.events...), nil
+}
+
+// Remove implements PublishBuffer.
+func (b *MemoryPublishBuffer) Remove(dlqID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, ev := range b.events {
+		if ev.DLQID == dlqID {
+			b.events = append(b.events[:i], b.events[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// DiskPublishBuffer is a PublishBuffer that writes each event to its own file
+// in a directory, so buffered dead letters survive a restart of the
+// publishing service.
+type DiskPublishBuffer struct {
+	mu  sync.Mutex
+	dir string
+	max int
+}
+
+// NewDiskPublishBuffer creates a buffer in dir, creating the directory if
+// needed, holding up to max events. Events left by a previous run are
+// pending again.
+func NewDiskPublishBuffer(dir string, max int) (*DiskPublishBuffer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create publish buffer dir: %w", err)
+	}
+	return &DiskPublishBuffer{dir: dir, max: max}, nil
+}
+
+const bufferFileExt = ".json"
+
+// files returns the buffered event files, oldest first. Callers must hold
+// b.mu.
+func (b *DiskPublishBuffer) files() ([]string, error) {
+	dirents, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read publish buffer: %w", err)
+	}
+	var names []string
+	for _, d := range dirents {
+		if !d.IsDir() && strings.HasSuffix(d.Name(), bufferFileExt) {
+			names = append(names, d.Name())
+		}
+	}
+	// Names start with a fixed-width timestamp.
+	sort.Strings(names)
+	return names, nil
+}
+
+// Put implements PublishBuffer. The event is written to a temporary file and
+// renamed into place so a crash never leaves a partial event behind.
+func (b *DiskPublishBuffer) Put(ev BufferedEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	names, err := b.files()
+	if err != nil {
+		return err
+	}
+	if len(names) >= b.max {
+		return ErrPublishBufferFull
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshal buffered event: %w", err)
+	}
+	name := fmt.Sprintf("%020d-%s%s", time.Now().UnixNano(), ev.DLQID, bufferFileExt)
+	tmp := filepath.Join(b.dir, "."+name+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write buffered event: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(b.dir, name)); err != nil {
+		return fmt.Errorf("write buffered event: %w", err)
+	}
+	return nil
+}
+
+// Pending implements PublishBuffer.
+func (b *DiskPublishBuffer) Pending() ([]BufferedEvent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	names, err := b.files()
+	if err != nil {
+		return nil, err
+	}
+	events := make([]BufferedEvent, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(b.dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("read buffered event: %w", err)
+		}
+		var ev BufferedEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return nil, fmt.Errorf("decode buffered event %s: %w", name, err)
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// Remove implements PublishBuffer.
+func (b *DiskPublishBuffer) Remove(dlqID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	names, err := b.files()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if strings.HasSuffix(name, "-"+dlqID+bufferFileExt) {
+			if err := os.Remove(filepath.Join(b.dir, name)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("remove buffered event: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	_ PublishBuffer = (*MemoryPublishBuffer)(nil)
+	_ PublishBuffer = (*DiskPublishBuffer)(nil)
+)
@@ -0,0 +1,43 @@
+package dlq
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDiskPublishBuffer(t *testing.T) {
+	dir := t.TempDir()
+	buf, err := NewDiskPublishBuffer(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"pb-1", "pb-2"} {
+		if err := buf.Put(BufferedEvent{DLQID: id, Subject: SubjectTaskPolicyDenied, Data: json.RawMessage(`{"dlq_id":"` + id + `"}`)}); err != nil {
+			t.Fatalf("put %s: %v", id, err)
+		}
+	}
+	if err := buf.Put(BufferedEvent{DLQID: "pb-3"}); !errors.Is(err, ErrPublishBufferFull) {
+		t.Errorf("expected ErrPublishBufferFull, got %v", err)
+	}
+
+	// A new buffer over the same directory picks up where the last one left off.
+	reopened, err := NewDiskPublishBuffer(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := reopened.Pending()
+	if err != nil {
+		t.Fatalf("pending: %v", err)
+	}
+	if len(pending) != 2 || pending[0].DLQID != "pb-1" || string(pending[1].Data) != `{"dlq_id":"pb-2"}` {
+		t.Fatalf("unexpected pending events %+v", pending)
+	}
+
+	if err := reopened.Remove("pb-1"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if pending, _ := reopened.Pending(); len(pending) != 1 || pending[0].DLQID != "pb-2" {
+		t.Errorf("expected only pb-2 left, got %+v", pending)
+	}
+}
@@ -1,43 +1,196 @@
 package dlq
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
 )
 
+const (
+	// DefaultPublisherAckTimeout bounds how long a JetStream publish waits
+	// for the stream to acknowledge the event.
+	DefaultPublisherAckTimeout = 5 * time.Second
+	// DefaultPublisherFlushInterval is how often a started Publisher tries to
+	// flush its buffer, in addition to flushing on reconnect.
+	DefaultPublisherFlushInterval = 30 * time.Second
+)
+
+// ErrPublishNotAcked is returned by Publish in JetStream mode when the stream
+// did not confirm that it persisted the event.
+var ErrPublishNotAcked = errors.New("dlq publisher: event not acknowledged by stream")
+
+// JetStreamPublisher publishes to a JetStream stream and waits for its
+// acknowledgement. nats.JetStreamContext satisfies it.
+type JetStreamPublisher interface {
+	PublishMsg(m *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error)
+}
+
+// DLQPublisher is what producers need to dead-letter a message. *Publisher
+// implements it; NopPublisher and RecordingPublisher stand in for it in unit
+// tests that should not need a NATS connection. The method is PublishContext,
+// not Publish, so that *Publisher keeps its context-free Publish.
+type DLQPublisher interface {
+	PublishContext(ctx context.Context, opts PublishOpts) error
+}
+
+var _ DLQPublisher = (*Publisher)(nil)
+
 // Publisher sends dead-letter events to the DLQ NATS stream.
 type Publisher struct {
-	nc     *nats.Conn
-	source string
+	nc         *nats.Conn
+	transport  NATSPublisher
+	js         JetStreamPublisher
+	ackTimeout time.Duration
+	source     string
+	limit      *PayloadLimit
+	metrics    *Metrics
+
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	buffer     PublishBuffer
+	flushEvery time.Duration
+	flushMu    sync.Mutex
+	done       chan struct{}
+	queue      chan asyncEvent
+	asyncMu    sync.RWMutex
+	running    bool // the async sender is draining queue
+
+	service string
+	version string
+	host    string
+	pid     int
+	tenant  string
+}
+
+// PublisherOption configures a Publisher.
+type PublisherOption func(*Publisher)
+
+// WithPublisherPayloadLimit enforces l before publishing. With PayloadReject,
+// Publish returns an error wrapping ErrPayloadTooLarge.
+func WithPublisherPayloadLimit(l PayloadLimit) PublisherOption {
+	return func(p *Publisher) { p.limit = &l }
+}
+
+// WithPublisherMetrics records payload limit violations in m.
+func WithPublisherMetrics(m *Metrics) PublisherOption {
+	return func(p *Publisher) { p.metrics = m }
+}
+
+// WithPublisherJetStream publishes through JetStream instead of core NATS.
+// Publish then waits up to ackTimeout (DefaultPublisherAckTimeout if zero) for
+// the stream's acknowledgement and returns an error wrapping
+// ErrPublishNotAcked if it does not arrive, so the caller knows the dead
+// letter was not persisted. The dlq_id is sent as the message ID, so
+// republishing after a lost acknowledgement is deduplicated by the stream.
+func WithPublisherJetStream(js JetStreamPublisher, ackTimeout time.Duration) PublisherOption {
+	return func(p *Publisher) {
+		p.js = js
+		p.ackTimeout = ackTimeout
+		if p.ackTimeout <= 0 {
+			p.ackTimeout = DefaultPublisherAckTimeout
+		}
+	}
+}
+
+// WithPublisherRetry makes Publish try up to attempts times before giving up,
+// sleeping initial between the first two tries and doubling the delay up to
+// max after that; a max of zero or less leaves the delay uncapped. Publish
+// blocks for the whole sequence.
+func WithPublisherRetry(attempts int, initial, max time.Duration) PublisherOption {
+	return func(p *Publisher) {
+		p.attempts = attempts
+		p.backoff = initial
+		p.maxBackoff = max
+	}
+}
+
+// WithPublisherBuffer keeps dead letters that could not be published (after
+// any retries) in b instead of failing, so a NATS outage does not drop them.
+// Publish then only fails if b is full. Call Start to flush the buffer when
+// the connection recovers, or call Flush yourself.
+func WithPublisherBuffer(b PublishBuffer) PublisherOption {
+	return func(p *Publisher) { p.buffer = b }
+}
+
+// WithPublisherFlushInterval sets how often a started Publisher tries to
+// flush its buffer. The default is DefaultPublisherFlushInterval.
+func WithPublisherFlushInterval(d time.Duration) PublisherOption {
+	return func(p *Publisher) { p.flushEvery = d }
+}
+
+// WithPublisherProvenance sets the service name and version stamped on every
+// entry, overriding the defaults taken from the executable name and the main
+// module version in its build info.
+func WithPublisherProvenance(service, version string) PublisherOption {
+	return func(p *Publisher) {
+		p.service = service
+		p.version = version
+	}
 }
 
 // NewPublisher creates a DLQ publisher. Source should be "dispatch" or "warren".
-func NewPublisher(nc *nats.Conn, source string) *Publisher {
-	return &Publisher{nc: nc, source: source}
+// Entries are stamped with the producing service, version, hostname and pid.
+func NewPublisher(nc *nats.Conn, source string, opts ...PublisherOption) *Publisher {
+	p := &Publisher{
+		nc: nc, source: source, service: filepath.Base(os.Args[0]), pid: os.Getpid(),
+		attempts: 1, flushEvery: DefaultPublisherFlushInterval, done: make(chan struct{}),
+	}
+	p.host, _ = os.Hostname()
+	if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Version != "(devel)" {
+		p.version = bi.Main.Version
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // PublishOpts configures a dead-letter event.
 type PublishOpts struct {
 	OriginalSubject string
 	OriginalPayload json.RawMessage
+	// OriginalHeaders are the failed message's NATS headers (a nats.Header
+	// converts directly), republished on retry.
+	OriginalHeaders map[string][]string
 	Reason          string
 	ReasonDetail    string
 	RetryCount      int
 	MaxRetries      int
 	RetryHistory    []RetryAttempt
 	Recoverable     bool
+	// Priority is copied to Entry.Priority.
+	Priority int
 }
 
-// Publish sends a dead-letter event to the appropriate DLQ subject.
+// Publish sends a dead-letter event to the appropriate DLQ subject. It is
+// PublishContext with context.Background().
 func (p *Publisher) Publish(opts PublishOpts) error {
+	return p.PublishContext(context.Background(), opts)
+}
+
+// PublishContext sends a dead-letter event to the appropriate DLQ subject.
+// It gives up once ctx is done, including while waiting for a JetStream
+// acknowledgement or between retries; with a buffer the event is then
+// buffered rather than dropped. The publish is traced and the trace context
+// is carried in the message headers. With WithPublisherAsync it only queues
+// the event.
+func (p *Publisher) PublishContext(ctx context.Context, opts PublishOpts) error {
 	entry := Entry{
 		DLQID:           uuid.New().String(),
 		OriginalSubject: opts.OriginalSubject,
 		OriginalPayload: opts.OriginalPayload,
+		OriginalHeaders: opts.OriginalHeaders,
 		Reason:          opts.Reason,
 		ReasonDetail:    opts.ReasonDetail,
 		FailedAt:        time.Now().UTC(),
@@ -46,10 +199,32 @@ func (p *Publisher) Publish(opts PublishOpts) error {
 		RetryHistory:    opts.RetryHistory,
 		Source:          p.source,
 		Recoverable:     opts.Recoverable,
+		Priority:        opts.Priority,
+		ProducerService: p.service,
+		ProducerVersion: p.version,
+		ProducerHost:    p.host,
+		ProducerPID:     p.pid,
+		TenantID:        TenantFromContext(ctx),
+	}
+	if entry.TenantID == "" {
+		entry.TenantID = p.tenant
 	}
 
 	if entry.RetryHistory == nil {
 		entry.RetryHistory = []RetryAttempt{}
+	} else {
+		entry.RetryHistory = append([]RetryAttempt(nil), entry.RetryHistory...)
+		normalizeRetryHistory(entry.RetryHistory)
+	}
+	if p.limit != nil {
+		limited, violated, err := p.limit.enforce(ctx, entry)
+		if violated {
+			p.metrics.Inc(MetricPublisherPayloadTooLarge)
+		}
+		if err != nil {
+			return err
+		}
+		entry = limited
 	}
 
 	data, err := json.Marshal(entry)
@@ -57,10 +232,156 @@ func (p *Publisher) Publish(opts PublishOpts) error {
 		return fmt.Errorf("marshal dlq entry: %w", err)
 	}
 
-	subject := SubjectForReason(p.source, opts.Reason)
-	if err := p.nc.Publish(subject, data); err != nil {
-		return fmt.Errorf("publish to %s: %w", subject, err)
+	ev := BufferedEvent{DLQID: entry.DLQID, Subject: SubjectForReason(p.source, opts.Reason), Data: data}
+	if p.queue != nil {
+		return p.enqueue(ctx, ev)
 	}
+	return p.publishNow(ctx, ev)
+}
+
+// publishNow publishes ev with the configured retry, spilling it to the
+// buffer if that fails.
+func (p *Publisher) publishNow(ctx context.Context, ev BufferedEvent) error {
+	err := p.sendWithRetry(ctx, ev.Subject, ev.DLQID, ev.Data)
+	if err == nil || p.buffer == nil {
+		return err
+	}
+	return p.spill(ev, err)
+}
 
+// spill keeps ev, which could not be published because of cause, in the
+// buffer until it can be flushed.
+func (p *Publisher) spill(ev BufferedEvent, cause error) error {
+	if err := p.buffer.Put(ev); err != nil {
+		return fmt.Errorf("%w (not buffered: %w)", cause, err)
+	}
+	p.metrics.Inc(MetricPublisherBuffered)
+	slog.Warn("dlq publisher: buffered dead letter after publish failure",
+		"dlq_id", ev.DLQID,
+		"subject", ev.Subject,
+		"error", cause,
+	)
 	return nil
 }
+
+// send publishes one marshalled event, through JetStream if configured.
+func (p *Publisher) send(ctx context.Context, subject, dlqID string, data []byte) (err error) {
+	if p.js == nil {
+		var nc NATSPublisher = p.nc
+		if p.transport != nil {
+			nc = p.transport
+		}
+		if err := publishTraced(ctx, nc, subject, data, nil, AttrDLQID.String(dlqID)); err != nil {
+			return fmt.Errorf("publish to %s: %w", subject, err)
+		}
+		return nil
+	}
+
+	ctx, span := startPublishSpan(ctx, subject, AttrDLQID.String(dlqID))
+	defer func() { endSpan(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, p.ackTimeout)
+	defer cancel()
+	if _, err := p.js.PublishMsg(tracedMsg(ctx, subject, data, nil), nats.MsgId(dlqID), nats.Context(ctx)); err != nil {
+		return fmt.Errorf("%w: publish to %s: %w", ErrPublishNotAcked, subject, err)
+	}
+	return nil
+}
+
+func (p *Publisher) sendWithRetry(ctx context.Context, subject, dlqID string, data []byte) error {
+	delay := p.backoff
+	for attempt := 1; ; attempt++ {
+		err := p.send(ctx, subject, dlqID, data)
+		if err == nil || attempt >= p.attempts || ctx.Err() != nil {
+			return err
+		}
+		p.metrics.Inc(MetricPublisherRetries)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+		delay *= 2
+		if p.maxBackoff > 0 {
+			delay = min(delay, p.maxBackoff)
+		}
+	}
+}
+
+// Flush publishes buffered dead letters, oldest first, and removes them from
+// the buffer. It stops at the first failure and returns how many were sent.
+func (p *Publisher) Flush(ctx context.Context) (int, error) {
+	if p.buffer == nil {
+		return 0, nil
+	}
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
+	events, err := p.buffer.Pending()
+	if err != nil {
+		return 0, err
+	}
+	for i, ev := range events {
+		if err := p.send(ctx, ev.Subject, ev.DLQID, ev.Data); err != nil {
+			return i, err
+		}
+		if err := p.buffer.Remove(ev.DLQID); err != nil {
+			return i + 1, err
+		}
+		p.metrics.Inc(MetricPublisherFlushed)
+	}
+	return len(events), nil
+}
+
+// Start flushes the buffer whenever the NATS connection reconnects and every
+// flush interval, until ctx is cancelled. With WithPublisherAsync it also
+// runs the background sender. It is only needed with WithPublisherBuffer or
+// WithPublisherAsync.
+func (p *Publisher) Start(ctx context.Context) {
+	var reconnected chan nats.Status
+	if p.nc != nil {
+		reconnected = p.nc.StatusChanged(nats.CONNECTED)
+	}
+	var sender sync.WaitGroup
+	if p.queue != nil {
+		p.asyncMu.Lock()
+		p.running = true
+		p.asyncMu.Unlock()
+		sender.Add(1)
+		go func() {
+			defer sender.Done()
+			p.runAsync(ctx)
+		}()
+	}
+	ticker := time.NewTicker(p.flushEvery)
+	go func() {
+		defer ticker.Stop()
+		defer close(p.done)
+		for {
+			select {
+			case <-reconnected:
+				p.flushBuffered(ctx)
+			case <-ticker.C:
+				p.flushBuffered(ctx)
+			case <-ctx.Done():
+				sender.Wait()
+				return
+			}
+		}
+	}()
+}
+
+// Wait blocks until the publisher's flush loop and async sender have
+// stopped.
+func (p *Publisher) Wait() {
+	<-p.done
+}
+
+func (p *Publisher) flushBuffered(ctx context.Context) {
+	n, err := p.Flush(ctx)
+	if n > 0 {
+		slog.Info("dlq publisher: flushed buffered dead letters", "count", n)
+	}
+	if err != nil {
+		slog.Warn("dlq publisher: buffer flush stopped", "error", err)
+	}
+}
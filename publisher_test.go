@@ -1,11 +1,16 @@
 package dlq
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
 )
 
 func TestPublisher_MarshalEntry(t *testing.T) {
@@ -76,3 +81,136 @@ func TestNewPublisher(t *testing.T) {
 		t.Errorf("expected source dispatch, got %s", p.source)
 	}
 }
+
+func TestNewPublisher_Provenance(t *testing.T) {
+	p := NewPublisher((*nats.Conn)(nil), SourceDispatch)
+	if p.service == "" || p.pid != os.Getpid() {
+		t.Errorf("expected default provenance, got service=%q pid=%d", p.service, p.pid)
+	}
+	if host, _ := os.Hostname(); p.host != host {
+		t.Errorf("expected host %q, got %q", host, p.host)
+	}
+
+	p = NewPublisher((*nats.Conn)(nil), SourceDispatch, WithPublisherProvenance("dispatch", "v1.4.2"))
+	if p.service != "dispatch" || p.version != "v1.4.2" {
+		t.Errorf("expected overridden provenance, got %q %q", p.service, p.version)
+	}
+}
+
+type fakeJetStream struct {
+	err     error
+	subject string
+	msg     *nats.Msg
+}
+
+func (f *fakeJetStream) PublishMsg(m *nats.Msg, _ ...nats.PubOpt) (*nats.PubAck, error) {
+	f.subject, f.msg = m.Subject, m
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &nats.PubAck{Stream: "DLQ", Sequence: 1}, nil
+}
+
+func TestPublisher_JetStream(t *testing.T) {
+	js := &fakeJetStream{}
+	p := NewPublisher(nil, SourceDispatch, WithPublisherJetStream(js, 0))
+	if p.ackTimeout != DefaultPublisherAckTimeout {
+		t.Errorf("expected default ack timeout, got %s", p.ackTimeout)
+	}
+
+	opts := PublishOpts{OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonPolicyDenied}
+	if err := p.Publish(opts); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if js.subject != SubjectTaskPolicyDenied {
+		t.Errorf("expected subject %s, got %s", SubjectTaskPolicyDenied, js.subject)
+	}
+
+	js.err = nats.ErrTimeout
+	err := p.Publish(opts)
+	if !errors.Is(err, ErrPublishNotAcked) || !errors.Is(err, nats.ErrTimeout) {
+		t.Errorf("expected ErrPublishNotAcked wrapping the timeout, got %v", err)
+	}
+}
+
+func TestPublisher_RetryThenBuffer(t *testing.T) {
+	metrics := NewMetrics()
+	buf := NewMemoryPublishBuffer(1)
+	// A nil connection fails every publish with nats.ErrInvalidConnection.
+	p := NewPublisher(nil, SourceWarren,
+		WithPublisherRetry(3, time.Millisecond, 2*time.Millisecond),
+		WithPublisherBuffer(buf),
+		WithPublisherMetrics(metrics),
+	)
+
+	opts := PublishOpts{OriginalSubject: "swarm.agent.boot", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonBootFailure}
+	if err := p.Publish(opts); err != nil {
+		t.Fatalf("expected the dead letter to be buffered, got %v", err)
+	}
+	if err := p.Publish(opts); !errors.Is(err, ErrPublishBufferFull) || !errors.Is(err, nats.ErrInvalidConnection) {
+		t.Errorf("expected a full-buffer error, got %v", err)
+	}
+	snap := metrics.Snapshot()
+	if snap[MetricPublisherRetries] != 4 || snap[MetricPublisherBuffered] != 1 {
+		t.Errorf("unexpected metrics %v", snap)
+	}
+
+	// The connection recovers.
+	js := &fakeJetStream{}
+	p.js = js
+	if n, err := p.Flush(context.Background()); n != 1 || err != nil {
+		t.Fatalf("flush: %d, %v", n, err)
+	}
+	if js.subject != SubjectAgentBootFailure {
+		t.Errorf("expected the buffered event on %s, got %s", SubjectAgentBootFailure, js.subject)
+	}
+	if pending, _ := buf.Pending(); len(pending) != 0 {
+		t.Errorf("expected an empty buffer, got %d events", len(pending))
+	}
+}
+
+func TestPublisher_RetryUncappedBackoff(t *testing.T) {
+	p := NewPublisher(nil, SourceWarren, WithPublisherRetry(4, 5*time.Millisecond, 0))
+
+	start := time.Now()
+	opts := PublishOpts{OriginalSubject: "swarm.agent.boot", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonBootFailure}
+	if err := p.Publish(opts); !errors.Is(err, nats.ErrInvalidConnection) {
+		t.Fatalf("expected the publish error, got %v", err)
+	}
+	// 5ms + 10ms + 20ms: a zero max must not collapse the later delays.
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("expected doubling delays without a cap, retries took %s", elapsed)
+	}
+}
+
+func TestPublisher_PublishContext(t *testing.T) {
+	rec := recordSpans(t)
+	js := &fakeJetStream{}
+	p := NewPublisher(nil, SourceDispatch, WithPublisherJetStream(js, 0))
+
+	opts := PublishOpts{OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonPolicyDenied}
+	ctx, span := otel.Tracer("test").Start(context.Background(), "dispatch")
+	if err := p.PublishContext(ctx, opts); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	span.End()
+	pub := spanNamed(rec, "dlq.publish "+SubjectTaskPolicyDenied)
+	if pub == nil || pub.Parent().SpanID() != span.SpanContext().SpanID() {
+		t.Fatalf("expected a dlq.publish child span, got %v", rec.Ended())
+	}
+	if !strings.Contains(js.msg.Header.Get("traceparent"), pub.SpanContext().TraceID().String()) {
+		t.Errorf("expected the message to carry the trace, got headers %v", js.msg.Header)
+	}
+
+	// A cancelled context stops the retries instead of sleeping through them.
+	core := NewPublisher(nil, SourceDispatch, WithPublisherRetry(5, time.Hour, time.Hour))
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if err := core.PublishContext(cancelled, opts); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("PublishContext kept retrying after its context was cancelled")
+	}
+}
@@ -0,0 +1,59 @@
+package dlq
+
+import (
+	"context"
+	"sync"
+)
+
+// NopPublisher is a DLQPublisher that discards every event.
+type NopPublisher struct{}
+
+var _ DLQPublisher = NopPublisher{}
+
+// PublishContext discards opts.
+func (NopPublisher) PublishContext(context.Context, PublishOpts) error { return nil }
+
+// RecordingPublisher is a DLQPublisher that keeps every event it is given, for
+// producer tests that assert on their dead-lettering paths. The zero value is
+// ready to use and it is safe for concurrent use.
+type RecordingPublisher struct {
+	mu     sync.Mutex
+	events []PublishOpts
+	err    error
+}
+
+var _ DLQPublisher = (*RecordingPublisher)(nil)
+
+// PublishContext records opts, or returns the error set with FailWith
+// without recording anything.
+func (r *RecordingPublisher) PublishContext(_ context.Context, opts PublishOpts) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.events = append(r.events, opts)
+	return nil
+}
+
+// FailWith makes later publishes fail with err, as a NATS outage would; nil
+// makes them succeed again.
+func (r *RecordingPublisher) FailWith(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+// Events returns the recorded events, oldest first.
+func (r *RecordingPublisher) Events() []PublishOpts {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]PublishOpts(nil), r.events...)
+}
+
+// Reset forgets the recorded events.
+func (r *RecordingPublisher) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
@@ -0,0 +1,32 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRecordingPublisher(t *testing.T) {
+	var pub DLQPublisher = &RecordingPublisher{}
+	rec := pub.(*RecordingPublisher)
+	ctx := context.Background()
+
+	if err := pub.PublishContext(ctx, PublishOpts{OriginalSubject: "swarm.task.request", Reason: ReasonNoCapableAgent}); err != nil {
+		t.Fatal(err)
+	}
+	down := errors.New("nats down")
+	rec.FailWith(down)
+	if err := pub.PublishContext(ctx, PublishOpts{Reason: ReasonAgentCrashed}); !errors.Is(err, down) {
+		t.Errorf("expected the configured error, got %v", err)
+	}
+	if got := rec.Events(); len(got) != 1 || got[0].Reason != ReasonNoCapableAgent {
+		t.Errorf("unexpected events %+v", got)
+	}
+	rec.Reset()
+	if got := rec.Events(); len(got) != 0 {
+		t.Errorf("expected no events after Reset, got %+v", got)
+	}
+	if err := (NopPublisher{}).PublishContext(ctx, PublishOpts{}); err != nil {
+		t.Errorf("NopPublisher: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+)
+
+// NATS request/reply subjects answered by the query service. Like the
+// lifecycle events they live under dlq.> but are never ingested as entries.
+const (
+	SubjectQueryPrefix = "dlq.query."
+	SubjectQueryStats  = SubjectQueryPrefix + "stats"
+	SubjectQueryGet    = SubjectQueryPrefix + "get"
+)
+
+// QueryServiceName and QueryServiceVersion identify the query service to
+// NATS service discovery ($SRV.PING, $SRV.INFO, $SRV.STATS).
+const (
+	QueryServiceName    = "swarm-dlq"
+	QueryServiceVersion = "1.0.0"
+)
+
+// queryTimeout bounds the store call behind each query.
+const queryTimeout = 5 * time.Second
+
+// QueryGetRequest is the body of a dlq.query.get request.
+type QueryGetRequest struct {
+	DLQID string `json:"dlq_id"`
+}
+
+// QueryResponder answers DLQ queries over NATS request/reply so services
+// without HTTP access to the API can read DLQ state.
+type QueryResponder struct {
+	store Reader
+	mask  *PayloadMask
+}
+
+// QueryOption configures a QueryResponder.
+type QueryOption func(*QueryResponder)
+
+// WithQueryMask masks the given payload fields, at any depth, in entries
+// returned by dlq.query.get. With no fields the whole payload is masked.
+func WithQueryMask(fields ...string) QueryOption {
+	return func(q *QueryResponder) { q.mask = &PayloadMask{Fields: fields} }
+}
+
+// NewQueryResponder creates a QueryResponder reading from store.
+func NewQueryResponder(store Reader, opts ...QueryOption) *QueryResponder {
+	q := &QueryResponder{store: store}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// Register adds the query endpoints to nc as a NATS micro service, load
+// balanced across instances by the service queue group. Stop the returned
+// service on shutdown.
+func (q *QueryResponder) Register(nc *nats.Conn) (micro.Service, error) {
+	svc, err := micro.AddService(nc, micro.Config{
+		Name:        QueryServiceName,
+		Version:     QueryServiceVersion,
+		Description: "Dead-letter queue queries",
+	})
+	if err != nil {
+		return nil, err
+	}
+	g := svc.AddGroup(strings.TrimSuffix(SubjectQueryPrefix, "."))
+	if err := g.AddEndpoint("stats", micro.HandlerFunc(q.handleStats)); err != nil {
+		_ = svc.Stop()
+		return nil, err
+	}
+	if err := g.AddEndpoint("get", micro.HandlerFunc(q.handleGet)); err != nil {
+		_ = svc.Stop()
+		return nil, err
+	}
+	return svc, nil
+}
+
+func (q *QueryResponder) handleStats(req micro.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	st, err := q.store.Stats(ctx)
+	if err != nil {
+		_ = req.Error("500", "internal error", nil)
+		return
+	}
+	_ = req.RespondJSON(st)
+}
+
+// handleGet accepts either a QueryGetRequest or the bare dlq_id.
+func (q *QueryResponder) handleGet(req micro.Request) {
+	var body QueryGetRequest
+	if err := json.Unmarshal(req.Data(), &body); err != nil {
+		body.DLQID = string(req.Data())
+	}
+	body.DLQID = strings.TrimSpace(body.DLQID)
+	if body.DLQID == "" {
+		_ = req.Error("400", "dlq_id is required", nil)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	entry, err := q.store.Get(ctx, body.DLQID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		_ = req.Error("404", "dlq entry not found", nil)
+		return
+	}
+	if err != nil {
+		_ = req.Error("500", "internal error", nil)
+		return
+	}
+	if q.mask != nil {
+		*entry = q.mask.entry(*entry)
+	}
+	_ = req.RespondJSON(entry)
+}
@@ -0,0 +1,94 @@
+package dlq
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/nats-io/nats.go/micro"
+)
+
+// fakeRequest is an in-memory micro.Request.
+type fakeRequest struct {
+	data    []byte
+	resp    []byte
+	errCode string
+}
+
+func (r *fakeRequest) Respond(b []byte, _ ...micro.RespondOpt) error { r.resp = b; return nil }
+func (r *fakeRequest) RespondJSON(v any, _ ...micro.RespondOpt) error {
+	r.resp, _ = json.Marshal(v)
+	return nil
+}
+func (r *fakeRequest) Error(code, _ string, _ []byte, _ ...micro.RespondOpt) error {
+	r.errCode = code
+	return nil
+}
+func (r *fakeRequest) Data() []byte           { return r.data }
+func (r *fakeRequest) Headers() micro.Headers { return nil }
+func (r *fakeRequest) Subject() string        { return "" }
+func (r *fakeRequest) Reply() string          { return "" }
+
+func TestQueryResponder_Stats(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "q-1", Reason: ReasonNoCapableAgent, Source: SourceDispatch})
+	q := NewQueryResponder(store)
+
+	req := &fakeRequest{}
+	q.handleStats(req)
+
+	var st Stats
+	if err := json.Unmarshal(req.resp, &st); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if st.Total != 1 {
+		t.Errorf("expected total 1, got %+v", st)
+	}
+
+	store.statsErr = fmt.Errorf("db down")
+	req = &fakeRequest{}
+	q.handleStats(req)
+	if req.errCode != "500" {
+		t.Errorf("expected 500, got %q", req.errCode)
+	}
+}
+
+func TestQueryResponder_Get(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "q-2", OriginalPayload: json.RawMessage(`{"email":"a@b.c","task_id":"t1"}`)})
+	q := NewQueryResponder(store, WithQueryMask("email"))
+
+	cases := []struct {
+		name, data, wantErr string
+	}{
+		{"json body", `{"dlq_id":"q-2"}`, ""},
+		{"bare id", `q-2`, ""},
+		{"missing id", ``, "400"},
+		{"not found", `{"dlq_id":"nope"}`, "404"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &fakeRequest{data: []byte(tc.data)}
+			q.handleGet(req)
+			if req.errCode != tc.wantErr {
+				t.Fatalf("expected error %q, got %q", tc.wantErr, req.errCode)
+			}
+			if tc.wantErr != "" {
+				return
+			}
+			var e Entry
+			_ = json.Unmarshal(req.resp, &e)
+			if e.DLQID != "q-2" || string(e.OriginalPayload) != `{"email":"***","task_id":"t1"}` {
+				t.Errorf("unexpected entry: %+v", e)
+			}
+		})
+	}
+}
+
+func TestIsEventSubject_Query(t *testing.T) {
+	for _, s := range []string{SubjectQueryStats, SubjectQueryGet} {
+		if !isEventSubject(s) {
+			t.Errorf("%s must not be ingested as a dead letter", s)
+		}
+	}
+}
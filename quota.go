@@ -0,0 +1,169 @@
+package dlq
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// quotaWindow is the length of the per-source ingest quota window.
+const quotaWindow = time.Hour
+
+// QuotaExceededEvent is published to SubjectQuotaExceeded when a source first
+// exceeds its ingest quota in a window, and again when that window closes
+// with the final number of events that were dropped.
+type QuotaExceededEvent struct {
+	Source        string    `json:"source"`
+	LimitPerHour  int       `json:"limit_per_hour"`
+	WindowStart   time.Time `json:"window_start"`
+	WindowEnd     time.Time `json:"window_end"`
+	Dropped       int       `json:"dropped"`
+	LastDroppedAt time.Time `json:"last_dropped_at,omitempty"`
+	DLQID         string    `json:"dlq_id"`
+	Final         bool      `json:"final"`
+}
+
+// sourceQuotas enforces per-source entries/hour limits at ingest.
+type sourceQuotas struct {
+	mu      sync.Mutex
+	limits  map[string]int
+	windows map[string]*sourceWindow
+	now     func() time.Time
+}
+
+type sourceWindow struct {
+	start       time.Time
+	count       int
+	dropped     int
+	lastDropped time.Time
+	overflowID  string
+	closed      bool // final event already emitted
+}
+
+func newSourceQuotas() *sourceQuotas {
+	return &sourceQuotas{
+		limits:  make(map[string]int),
+		windows: make(map[string]*sourceWindow),
+		now:     time.Now,
+	}
+}
+
+// quotaDecision is the outcome of admitting one entry.
+type quotaDecision struct {
+	admit     bool
+	aggregate *Entry               // replacement entry to persist instead
+	alerts    []QuotaExceededEvent // notifications to publish
+}
+
+// admit counts entry against its source's quota. Within the limit the entry
+// is admitted unchanged. The first entry over the limit is replaced by a
+// single quota_exceeded aggregate entry; later ones are dropped and counted.
+func (q *sourceQuotas) admit(subject string, entry Entry) quotaDecision {
+	limit, ok := q.limits[entry.Source]
+	if !ok || limit <= 0 {
+		return quotaDecision{admit: true}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var d quotaDecision
+	now := q.now().UTC()
+	w := q.windows[entry.Source]
+	if w == nil || !now.Before(w.start.Add(quotaWindow)) {
+		if w != nil && w.dropped > 0 && !w.closed {
+			w.closed = true
+			d.alerts = append(d.alerts, w.event(entry.Source, limit, true))
+		}
+		w = &sourceWindow{start: now}
+		q.windows[entry.Source] = w
+	}
+
+	w.count++
+	if w.count <= limit {
+		d.admit = true
+		return d
+	}
+
+	w.dropped++
+	w.lastDropped = now
+	if w.overflowID != "" {
+		return d
+	}
+
+	w.overflowID = uuid.New().String()
+	summary, _ := json.Marshal(map[string]any{
+		"source":         entry.Source,
+		"limit_per_hour": limit,
+		"window_start":   w.start,
+		"sample_dlq_id":  entry.DLQID,
+		"sample_reason":  entry.Reason,
+		"sample_payload": entry.OriginalPayload,
+	})
+	d.admit = true
+	d.aggregate = &Entry{
+		DLQID:           w.overflowID,
+		OriginalSubject: subject,
+		OriginalPayload: summary,
+		Reason:          ReasonQuotaExceeded,
+		ReasonDetail: fmt.Sprintf("source %s exceeded its ingest quota of %d entries/hour; further events are dropped until %s",
+			entry.Source, limit, w.start.Add(quotaWindow).Format(time.RFC3339)),
+		FailedAt:        now,
+		RetryHistory:    []RetryAttempt{},
+		Source:          entry.Source,
+		Fingerprint:     entry.Fingerprint,
+		TaskID:          entry.TaskID,
+		TenantID:        entry.TenantID,
+		Priority:        entry.Priority,
+		ProducerService: entry.ProducerService,
+		ProducerVersion: entry.ProducerVersion,
+		ProducerHost:    entry.ProducerHost,
+		ProducerPID:     entry.ProducerPID,
+		Recoverable:     false,
+	}
+	d.alerts = append(d.alerts, w.event(entry.Source, limit, false))
+	return d
+}
+
+// expire closes source's window if its aggregate is still overflowID and
+// the window has not been closed by a later event, returning the final event.
+// It is called when the window's hour is up, so the final count is recorded
+// even if the source goes quiet.
+func (q *sourceQuotas) expire(source, overflowID string) (QuotaExceededEvent, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	w := q.windows[source]
+	if w == nil || w.overflowID != overflowID || w.closed {
+		return QuotaExceededEvent{}, false
+	}
+	w.closed = true
+	return w.event(source, q.limits[source], true), true
+}
+
+func (w *sourceWindow) event(source string, limit int, final bool) QuotaExceededEvent {
+	return QuotaExceededEvent{
+		Source:        source,
+		LimitPerHour:  limit,
+		WindowStart:   w.start,
+		WindowEnd:     w.start.Add(quotaWindow),
+		Dropped:       w.dropped,
+		LastDroppedAt: w.lastDropped,
+		DLQID:         w.overflowID,
+		Final:         final,
+	}
+}
+
+// logQuotaAlert logs a quota event so it is visible even without a NATS publisher.
+func logQuotaAlert(ev QuotaExceededEvent) {
+	slog.Warn("dlq processor: source exceeded ingest quota",
+		"source", ev.Source,
+		"limit_per_hour", ev.LimitPerHour,
+		"dropped", ev.Dropped,
+		"final", ev.Final,
+		"dlq_id", ev.DLQID,
+	)
+}
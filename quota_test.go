@@ -0,0 +1,176 @@
+package dlq
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestProcessor_SourceQuota_AggregatesOverflow(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	metrics := NewMetrics()
+	proc := NewProcessor(store,
+		WithProcessorSourceQuota(SourceWarren, 3),
+		WithProcessorEvents(nc),
+		WithProcessorMetrics(metrics),
+	)
+
+	for i := 0; i < 10; i++ {
+		data := eventJSON(Entry{DLQID: fmt.Sprintf("q-%d", i), Reason: ReasonCrashLoop, Source: SourceWarren})
+		proc.Process(context.Background(), SubjectAgentCrashLoop, data)
+	}
+
+	// 3 within quota + 1 aggregate.
+	if store.insertCalls != 4 {
+		t.Fatalf("expected 4 inserts, got %d", store.insertCalls)
+	}
+	var aggregates []Entry
+	for _, e := range store.entries {
+		if e.Reason == ReasonQuotaExceeded {
+			aggregates = append(aggregates, *e)
+		}
+	}
+	if len(aggregates) != 1 {
+		t.Fatalf("expected 1 quota_exceeded entry, got %d", len(aggregates))
+	}
+	if aggregates[0].Source != SourceWarren || aggregates[0].Recoverable {
+		t.Errorf("unexpected aggregate entry: %+v", aggregates[0])
+	}
+	if got := metrics.Get(MetricProcessorQuotaDropped); got != 7 {
+		t.Errorf("expected 7 dropped, got %d", got)
+	}
+	if got := len(nc.events(SubjectQuotaExceeded)); got != 1 {
+		t.Errorf("expected 1 quota alert, got %d", got)
+	}
+}
+
+func TestProcessor_SourceQuota_OtherSourcesUnaffected(t *testing.T) {
+	store := newMockStore()
+	proc := NewProcessor(store, WithProcessorSourceQuota(SourceWarren, 1))
+
+	for i := 0; i < 5; i++ {
+		data := eventJSON(Entry{DLQID: fmt.Sprintf("qd-%d", i), Source: SourceDispatch})
+		proc.Process(context.Background(), SubjectTaskUnassignable, data)
+	}
+	if store.insertCalls != 5 {
+		t.Errorf("expected 5 inserts for unlimited source, got %d", store.insertCalls)
+	}
+}
+
+func TestSourceQuotas_WindowRollover(t *testing.T) {
+	q := newSourceQuotas()
+	q.limits[SourceWarren] = 1
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+
+	e := Entry{Source: SourceWarren}
+	if d := q.admit("s", e); !d.admit || d.aggregate != nil {
+		t.Fatal("first entry should be admitted unchanged")
+	}
+	if d := q.admit("s", e); d.aggregate == nil {
+		t.Fatal("second entry should become the aggregate")
+	}
+	if d := q.admit("s", e); d.admit {
+		t.Fatal("third entry should be dropped")
+	}
+
+	now = now.Add(quotaWindow)
+	d := q.admit("s", e)
+	if !d.admit || d.aggregate != nil {
+		t.Error("first entry of a new window should be admitted unchanged")
+	}
+	if len(d.alerts) != 1 || !d.alerts[0].Final || d.alerts[0].Dropped != 2 {
+		t.Errorf("expected final alert with 2 dropped, got %+v", d.alerts)
+	}
+}
+
+func TestProcessor_SourceQuota_AggregateCarriesEntryFields(t *testing.T) {
+	store := newMockStore()
+	proc := NewProcessor(store,
+		WithProcessorSourceQuota(SourceWarren, 1),
+		WithProcessorFingerprintCollapse(),
+		WithProcessorTenant("prod"),
+	)
+
+	for i := 0; i < 3; i++ {
+		data := eventJSON(Entry{DLQID: fmt.Sprintf("qf-%d", i), TaskID: "task-1", Source: SourceWarren,
+			ProducerService: "warren", Priority: 2})
+		proc.Process(context.Background(), SubjectAgentCrashLoop, data)
+	}
+
+	first, err := store.Get(context.Background(), "qf-0")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	var agg *Entry
+	for _, e := range store.entries {
+		if e.Reason == ReasonQuotaExceeded {
+			agg = e
+		}
+	}
+	if agg == nil {
+		t.Fatal("the aggregate must not be collapsed into the entry sharing its fingerprint")
+	}
+	if agg.TenantID != "prod" || agg.TaskID != "task-1" || agg.Fingerprint != first.Fingerprint ||
+		agg.ProducerService != "warren" || agg.Priority != 2 {
+		t.Errorf("expected the aggregate to carry the entry's fields, got %+v", agg)
+	}
+}
+
+func TestProcessor_SourceQuota_RecordsFinalDrops(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	proc := NewProcessor(store, WithProcessorSourceQuota(SourceWarren, 1), WithProcessorEvents(nc))
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	proc.quotas.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		data := eventJSON(Entry{DLQID: fmt.Sprintf("qr-%d", i), Source: SourceWarren})
+		proc.Process(context.Background(), SubjectAgentCrashLoop, data)
+	}
+	now = now.Add(quotaWindow)
+	proc.Process(context.Background(), SubjectAgentCrashLoop, eventJSON(Entry{DLQID: "qr-next", Source: SourceWarren}))
+
+	var agg *Entry
+	for _, e := range store.entries {
+		if e.Reason == ReasonQuotaExceeded {
+			agg = e
+		}
+	}
+	if agg == nil {
+		t.Fatal("expected a quota_exceeded entry")
+	}
+	if agg.Occurrences != 4 || agg.LastSeenAt == nil {
+		t.Errorf("expected the 4 dropped events recorded on the aggregate, got occurrences %d, last seen %v",
+			agg.Occurrences, agg.LastSeenAt)
+	}
+	if got := len(nc.events(SubjectQuotaExceeded)); got != 2 {
+		t.Errorf("expected the first and the final alert, got %d", got)
+	}
+}
+
+func TestSourceQuotas_Expire(t *testing.T) {
+	q := newSourceQuotas()
+	q.limits[SourceWarren] = 1
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+
+	e := Entry{Source: SourceWarren}
+	q.admit("s", e)
+	d := q.admit("s", e)
+	q.admit("s", e)
+
+	final, ok := q.expire(SourceWarren, d.aggregate.DLQID)
+	if !ok || !final.Final || final.Dropped != 2 || !final.LastDroppedAt.Equal(now) {
+		t.Fatalf("expected a final event with 2 dropped, got %+v, %v", final, ok)
+	}
+	if _, ok := q.expire(SourceWarren, d.aggregate.DLQID); ok {
+		t.Error("a closed window must not expire twice")
+	}
+	now = now.Add(quotaWindow)
+	if d := q.admit("s", e); len(d.alerts) != 0 {
+		t.Errorf("an expired window must not emit a second final alert, got %+v", d.alerts)
+	}
+}
@@ -0,0 +1,123 @@
+package dlq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownReason rejects an event whose reason is neither built in nor
+// registered with RegisterReason.
+var ErrUnknownReason = errors.New("unknown dlq reason")
+
+// ErrInvalidReason is returned by RegisterReason for an empty reason, a
+// subject outside dlq.> or a reason already registered differently.
+var ErrInvalidReason = errors.New("invalid dlq reason")
+
+// ReasonSpec describes a reason code.
+type ReasonSpec struct {
+	// Subject is the NATS subject events with the reason are published to.
+	// Empty sends them to the source's ".unknown" subject.
+	Subject string
+	// Recoverable is the Entry.Recoverable the Processor stores for events
+	// with the reason that do not set "recoverable" themselves.
+	Recoverable bool
+}
+
+// reasons holds the known reason codes: the built-in ones below and those
+// added with RegisterReason.
+var (
+	reasonsMu sync.RWMutex
+	reasons   = map[string]ReasonSpec{
+		ReasonNoCapableAgent:       {Subject: SubjectTaskUnassignable},
+		ReasonAllAgentsUnavailable: {Subject: SubjectTaskNoAvailableAgent},
+		ReasonPolicyDenied:         {Subject: SubjectTaskPolicyDenied},
+		ReasonTimeoutAssigned:      {Subject: SubjectTaskAssignTimeout},
+		ReasonTimeoutInProgress:    {Subject: SubjectTaskExecTimeout},
+		ReasonAgentCrashed:         {Subject: SubjectTaskAgentCrashed},
+		ReasonBootFailure:          {Subject: SubjectAgentBootFailure},
+		ReasonHealthCheckFailed:    {},
+		ReasonPullFailure:          {Subject: SubjectAgentPullFailure},
+		ReasonCrashLoop:            {Subject: SubjectAgentCrashLoop},
+		ReasonQuotaExceeded:        {},
+	}
+)
+
+// RegisterReason adds a reason code, so SubjectForReason maps it to
+// spec.Subject and the Processor accepts it (see
+// WithProcessorAllowUnknownReasons). Registering the same reason and spec
+// again is a no-op; built-in reasons cannot be redefined.
+func RegisterReason(reason string, spec ReasonSpec) error {
+	switch {
+	case reason == "" || strings.ContainsAny(reason, " \t\n"):
+		return fmt.Errorf("%w: reason %q", ErrInvalidReason, reason)
+	case spec.Subject != "" && !literalDLQSubject(spec.Subject):
+		return fmt.Errorf("%w: subject %q must be a literal subject under dlq.", ErrInvalidReason, spec.Subject)
+	case spec.Subject != "" && (isEventSubject(spec.Subject) || eventSubjectUnder(spec.Subject)):
+		return fmt.Errorf("%w: subject %q clashes with the lifecycle notifications", ErrInvalidReason, spec.Subject)
+	}
+
+	reasonsMu.Lock()
+	defer reasonsMu.Unlock()
+	if existing, ok := reasons[reason]; ok {
+		if existing == spec {
+			return nil
+		}
+		return fmt.Errorf("%w: reason %q is already registered as %+v", ErrInvalidReason, reason, existing)
+	}
+	reasons[reason] = spec
+	return nil
+}
+
+// LookupReason returns the spec of a built-in or registered reason.
+func LookupReason(reason string) (ReasonSpec, bool) {
+	reasonsMu.RLock()
+	defer reasonsMu.RUnlock()
+	spec, ok := reasons[reason]
+	return spec, ok
+}
+
+// inferReason returns the reason whose subject is subject, or "".
+func inferReason(subject string) string {
+	reasonsMu.RLock()
+	defer reasonsMu.RUnlock()
+	for reason, spec := range reasons {
+		if spec.Subject == subject {
+			return reason
+		}
+	}
+	return ""
+}
+
+// WithProcessorAllowUnknownReasons makes the Processor store events whose
+// reason is neither built in nor registered with RegisterReason. They are
+// still counted in MetricProcessorUnknownReasons. Without it such events are
+// rejected as invalid and parked (see InvalidEventStore).
+func WithProcessorAllowUnknownReasons() ProcessorOption {
+	return func(p *Processor) { p.allowUnknownReasons = true }
+}
+
+// checkReason validates the reason of entry, decoded from data, and applies
+// its recoverability default. It fails with ErrUnknownReason if the event
+// must be rejected.
+func (p *Processor) checkReason(data []byte, entry *Entry) error {
+	spec, ok := LookupReason(entry.Reason)
+	if !ok {
+		p.metrics.Inc(MetricProcessorUnknownReasons)
+		if !p.allowUnknownReasons {
+			return fmt.Errorf("%w %q", ErrUnknownReason, entry.Reason)
+		}
+		return nil
+	}
+	if spec.Recoverable && !entry.Recoverable {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(data, &fields) == nil {
+			if _, set := fields["recoverable"]; !set {
+				entry.Recoverable = true
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestBuiltinReasonsRegistered parses the package for Reason* string
+// constants, so a built-in added without a registry entry fails here.
+func TestBuiltinReasonsRegistered(t *testing.T) {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), ".", func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := 0
+	for _, f := range pkgs["dlq"].Files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if !strings.HasPrefix(name.Name, "Reason") || i >= len(vs.Values) {
+						continue
+					}
+					lit, ok := vs.Values[i].(*ast.BasicLit)
+					if !ok || lit.Kind != token.STRING {
+						continue
+					}
+					reason, _ := strconv.Unquote(lit.Value)
+					found++
+					if _, ok := LookupReason(reason); !ok {
+						t.Errorf("built-in %s (%q) is not registered", name.Name, reason)
+					}
+				}
+			}
+		}
+	}
+	if found < 11 {
+		t.Errorf("expected to find every built-in reason constant, found %d", found)
+	}
+}
+
+func TestRegisterReason(t *testing.T) {
+	spec := ReasonSpec{Subject: "dlq.task.budget_exceeded", Recoverable: true}
+	if err := RegisterReason("budget_exceeded", spec); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterReason("budget_exceeded", spec); err != nil {
+		t.Errorf("re-registering the same spec should be a no-op: %v", err)
+	}
+	if got := SubjectForReason(SourceDispatch, "budget_exceeded"); got != "dlq.task.budget_exceeded" {
+		t.Errorf("subject = %q", got)
+	}
+	if got := inferReason("dlq.task.budget_exceeded"); got != "budget_exceeded" {
+		t.Errorf("inferReason = %q", got)
+	}
+
+	for _, tt := range []struct {
+		reason string
+		spec   ReasonSpec
+	}{
+		{"", ReasonSpec{}},
+		{"budget_exceeded", ReasonSpec{Subject: "dlq.task.budget"}},
+		{ReasonCrashLoop, ReasonSpec{Subject: "dlq.agent.loop"}},
+		{"other", ReasonSpec{Subject: "task.other"}},
+		{"other", ReasonSpec{Subject: "dlq.task.*"}},
+		{"other", ReasonSpec{Subject: SubjectExpired}},
+	} {
+		if err := RegisterReason(tt.reason, tt.spec); !errors.Is(err, ErrInvalidReason) {
+			t.Errorf("RegisterReason(%q, %+v): expected ErrInvalidReason, got %v", tt.reason, tt.spec, err)
+		}
+	}
+}
+
+func TestProcessor_UnknownReasons(t *testing.T) {
+	if err := RegisterReason("quota_breach", ReasonSpec{Recoverable: true}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	store := newMockStore()
+	m := NewMetrics()
+	strict := NewProcessor(store, WithProcessorMetrics(m))
+	strict.Process(ctx, "dlq.task.unknown", []byte(`{"dlq_id":"rs-1","original_subject":"s","reason":"made_up","failed_at":"2026-01-01T00:00:00Z"}`))
+	strict.Process(ctx, "dlq.task.unknown", []byte(`{"dlq_id":"rs-2","original_subject":"s","reason":"quota_breach","failed_at":"2026-01-01T00:00:00Z"}`))
+	strict.Process(ctx, "dlq.task.unknown", []byte(`{"dlq_id":"rs-3","original_subject":"s","reason":"quota_breach","recoverable":false,"failed_at":"2026-01-01T00:00:00Z"}`))
+	strict.Process(ctx, SubjectAgentCrashLoop, []byte(`{"dlq_id":"rs-4","original_subject":"s","failed_at":"2026-01-01T00:00:00Z"}`))
+
+	if e, _ := store.Get(ctx, "rs-1"); e != nil {
+		t.Errorf("unknown reason should be rejected, got %+v", e)
+	}
+	if got := m.Get(MetricProcessorUnknownReasons); got != 1 {
+		t.Errorf("unknown reasons metric = %d", got)
+	}
+	if e, _ := store.Get(ctx, "rs-2"); e == nil || !e.Recoverable {
+		t.Errorf("registered reason should default to recoverable, got %+v", e)
+	}
+	if e, _ := store.Get(ctx, "rs-3"); e == nil || e.Recoverable {
+		t.Errorf("an explicit recoverable=false should win, got %+v", e)
+	}
+	if e, _ := store.Get(ctx, "rs-4"); e == nil || e.Reason != ReasonCrashLoop {
+		t.Errorf("a missing reason should be inferred from the subject, got %+v", e)
+	}
+
+	NewProcessor(store, WithProcessorAllowUnknownReasons()).Process(ctx, "dlq.task.unknown",
+		[]byte(`{"dlq_id":"rs-1","original_subject":"s","reason":"made_up","failed_at":"2026-01-01T00:00:00Z"}`))
+	if e, _ := store.Get(ctx, "rs-1"); e == nil || e.Reason != "made_up" {
+		t.Errorf("unknown reason should be stored when allowed, got %+v", e)
+	}
+}
@@ -0,0 +1,93 @@
+package dlq
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SubjectWarrenAgentRestart is the Warren command subject used by
+// RestartAgent by default.
+const SubjectWarrenAgentRestart = "warren.agent.restart"
+
+// RecoveryAction decides what to publish to recover an entry, given the
+// (already transformed) payload. The default action republishes the payload
+// to the entry's original subject.
+type RecoveryAction interface {
+	Recover(e Entry, payload json.RawMessage) (subject string, data json.RawMessage, err error)
+}
+
+// RecoveryActionFunc adapts a function to RecoveryAction.
+type RecoveryActionFunc func(e Entry, payload json.RawMessage) (string, json.RawMessage, error)
+
+// Recover calls f.
+func (f RecoveryActionFunc) Recover(e Entry, payload json.RawMessage) (string, json.RawMessage, error) {
+	return f(e, payload)
+}
+
+// RecoveryActions maps reasons to the action used to recover entries with
+// that reason. Reasons without an action, and a nil map, republish the
+// payload to the original subject.
+type RecoveryActions map[string]RecoveryAction
+
+// resolve returns the subject and data to publish to recover e.
+func (a RecoveryActions) resolve(e Entry, payload json.RawMessage) (string, json.RawMessage, error) {
+	action, ok := a[e.Reason]
+	if !ok {
+		return e.OriginalSubject, payload, nil
+	}
+	subject, data, err := action.Recover(e, payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("recovery action for %s: %w", e.DLQID, err)
+	}
+	return subject, data, nil
+}
+
+// AgentRestartCommand is published by RestartAgent.
+type AgentRestartCommand struct {
+	Agent  string `json:"agent"`
+	DLQID  string `json:"dlq_id"`
+	Reason string `json:"reason"`
+}
+
+// RestartAgent returns an action that publishes an AgentRestartCommand to
+// subject instead of the original payload. The agent name is read from the
+// first non-empty top-level payload field in fields (default "agent",
+// "agent_id", "name").
+func RestartAgent(subject string, fields ...string) RecoveryAction {
+	if len(fields) == 0 {
+		fields = []string{"agent", "agent_id", "name"}
+	}
+	return RecoveryActionFunc(func(e Entry, payload json.RawMessage) (string, json.RawMessage, error) {
+		var doc map[string]any
+		if err := json.Unmarshal(payload, &doc); err != nil {
+			return "", nil, fmt.Errorf("decode payload: %w", err)
+		}
+		var agent string
+		for _, f := range fields {
+			if v, ok := doc[f].(string); ok && v != "" {
+				agent = v
+				break
+			}
+		}
+		if agent == "" {
+			return "", nil, fmt.Errorf("no agent name in payload fields %v", fields)
+		}
+		data, err := json.Marshal(AgentRestartCommand{Agent: agent, DLQID: e.DLQID, Reason: e.Reason})
+		if err != nil {
+			return "", nil, err
+		}
+		return subject, data, nil
+	})
+}
+
+// WarrenRestartActions restarts the affected agent via
+// SubjectWarrenAgentRestart for boot_failure, crash_loop and pull_failure,
+// where republishing the original heartbeat would not restart anything.
+func WarrenRestartActions() RecoveryActions {
+	restart := RestartAgent(SubjectWarrenAgentRestart)
+	return RecoveryActions{
+		ReasonBootFailure: restart,
+		ReasonCrashLoop:   restart,
+		ReasonPullFailure: restart,
+	}
+}
@@ -0,0 +1,84 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestRecoveryActions_DefaultRepublishes(t *testing.T) {
+	var actions RecoveryActions
+	e := Entry{DLQID: "ra-1", OriginalSubject: "swarm.task.request", Reason: ReasonNoCapableAgent}
+	subject, data, err := actions.resolve(e, json.RawMessage(`{"t":1}`))
+	if err != nil || subject != "swarm.task.request" || string(data) != `{"t":1}` {
+		t.Errorf("expected original subject and payload, got %s %s %v", subject, data, err)
+	}
+}
+
+func TestRestartAgent(t *testing.T) {
+	actions := WarrenRestartActions()
+	e := Entry{DLQID: "ra-2", OriginalSubject: "warren.agent.heartbeat", Reason: ReasonCrashLoop}
+
+	subject, data, err := actions.resolve(e, json.RawMessage(`{"agent_id":"scout","status":"crashed"}`))
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if subject != SubjectWarrenAgentRestart {
+		t.Errorf("expected %s, got %s", SubjectWarrenAgentRestart, subject)
+	}
+	var cmd AgentRestartCommand
+	_ = json.Unmarshal(data, &cmd)
+	if cmd.Agent != "scout" || cmd.DLQID != "ra-2" || cmd.Reason != ReasonCrashLoop {
+		t.Errorf("unexpected command: %+v", cmd)
+	}
+
+	if _, _, err := actions.resolve(e, json.RawMessage(`{"status":"crashed"}`)); err == nil {
+		t.Error("expected an error when the payload names no agent")
+	}
+}
+
+func TestScanner_RecoveryActions(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(
+		Entry{DLQID: "ra-3", OriginalSubject: "warren.agent.heartbeat", OriginalPayload: json.RawMessage(`{"agent":"kai"}`), Reason: ReasonBootFailure, Recoverable: true},
+		Entry{DLQID: "ra-4", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Recoverable: true},
+	)
+
+	NewScanner(store, nc, time.Minute, WithScannerRecoveryActions(WarrenRestartActions())).scan(context.Background())
+
+	subjects := map[string]bool{}
+	for _, m := range nc.published() {
+		subjects[m.Subject] = true
+	}
+	if !subjects[SubjectWarrenAgentRestart] || !subjects["swarm.task.request"] || subjects["warren.agent.heartbeat"] {
+		t.Errorf("unexpected publishes: %v", subjects)
+	}
+	if e, _ := store.Get(context.Background(), "ra-3"); !e.Recovered {
+		t.Error("restarted entry should be recovered")
+	}
+}
+
+func TestHandler_Retry_RecoveryActionFails(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(Entry{DLQID: "ra-5", OriginalSubject: "warren.agent.heartbeat", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonPullFailure})
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, nc, WithRecoveryActions(WarrenRestartActions())).Routes())
+
+	req := httptest.NewRequest("POST", "/dlq/ra-5/retry", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422, got %d", w.Code)
+	}
+	if len(nc.published()) != 0 {
+		t.Error("expected nothing published")
+	}
+}
@@ -0,0 +1,254 @@
+package dlq
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// ErrNoSealedPayload is returned by Redactor.Unseal for entries whose
+// original payload was not kept.
+var ErrNoSealedPayload = errors.New("dlq: entry has no sealed payload")
+
+// Redactor masks sensitive fields of payloads before they are stored. Paths
+// are dot-separated object keys, optionally prefixed with "$."; "*" matches
+// any single key and "**" any number of levels. Arrays are descended into
+// transparently, so "users.email" masks the email of every element of users.
+//
+//	credentials.api_key   one nested field
+//	*.password            password one level down, under any key
+//	**.token              token at any depth
+//
+// Without a key the unredacted payload is dropped and the entry is made
+// non-recoverable, since republishing the masked payload would be wrong.
+// With WithRedactorKey it is sealed with AES-GCM into Entry.SealedPayload,
+// and Unseal recovers it. Give the Redactor to the Handler (WithRedactor) and
+// Scanner (WithScannerRedactor) so retries republish the original.
+type Redactor struct {
+	paths [][]string
+	aead  cipher.AEAD
+	rand  io.Reader // nonce source
+}
+
+// RedactorOption configures a Redactor.
+type RedactorOption func(*Redactor) error
+
+// WithRedactorKey keeps the unredacted payload encrypted with key, which
+// must be 16, 24 or 32 bytes (AES-128, -192 or -256).
+func WithRedactorKey(key []byte) RedactorOption {
+	return func(r *Redactor) error {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return fmt.Errorf("redactor key: %w", err)
+		}
+		r.aead, err = cipher.NewGCM(block)
+		return err
+	}
+}
+
+// NewRedactor creates a Redactor masking paths.
+func NewRedactor(paths []string, opts ...RedactorOption) (*Redactor, error) {
+	r := &Redactor{rand: rand.Reader}
+	for _, p := range paths {
+		segs := strings.Split(strings.TrimPrefix(p, "$."), ".")
+		for _, s := range segs {
+			if s == "" {
+				return nil, fmt.Errorf("redactor: invalid path %q", p)
+			}
+		}
+		if segs[len(segs)-1] == "**" {
+			return nil, fmt.Errorf("redactor: path %q must not end in **", p)
+		}
+		r.paths = append(r.paths, segs)
+	}
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// WithProcessorRedaction masks r's paths in every payload (and payload
+// sample) before the entry is stored. Redacted entries are marked
+// payload_redacted. Events that fail to redact are parked as invalid (see
+// InvalidEventStore) rather than stored or redelivered.
+func WithProcessorRedaction(r *Redactor) ProcessorOption {
+	return func(p *Processor) { p.redactor = r }
+}
+
+// WithRedactor lets retries and replays of redacted entries republish the
+// original payload unsealed with r. Redacted entries that cannot be unsealed
+// are refused with 409.
+func WithRedactor(r *Redactor) HandlerOption {
+	return func(h *Handler) { h.redactor = r }
+}
+
+// WithScannerRedactor lets the scanner retry redacted entries with the
+// original payload unsealed with r. Entries that cannot be unsealed are
+// skipped.
+func WithScannerRedactor(r *Redactor) ScannerOption {
+	return func(s *Scanner) { s.redactor = r }
+}
+
+// apply returns e with its payload redacted, reporting whether anything was
+// masked. Payloads that are not valid JSON cannot be inspected and are
+// masked entirely. Without a key the original is lost, so e is made
+// non-recoverable.
+func (r *Redactor) apply(e Entry) (Entry, bool, error) {
+	if len(e.OriginalPayload) == 0 || len(r.paths) == 0 {
+		return e, false, nil
+	}
+	redacted, changed := r.payload(e.OriginalPayload)
+	if !changed {
+		return e, false, nil
+	}
+	if r.aead != nil {
+		sealed, err := r.seal(e.OriginalPayload)
+		if err != nil {
+			return e, false, err
+		}
+		e.SealedPayload = sealed
+	} else {
+		e.Recoverable = false
+	}
+	e.OriginalPayload = redacted
+	e.PayloadRedacted = true
+	if len(e.PayloadSamples) > 0 {
+		samples := make([]json.RawMessage, len(e.PayloadSamples))
+		for i, s := range e.PayloadSamples {
+			samples[i], _ = r.payload(s)
+		}
+		e.PayloadSamples = samples
+	}
+	return e, true, nil
+}
+
+func (r *Redactor) payload(p json.RawMessage) (json.RawMessage, bool) {
+	// UseNumber keeps integers beyond 2^53 intact through the round trip.
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil || dec.More() {
+		return json.RawMessage(maskedValue), true
+	}
+	changed := false
+	for _, path := range r.paths {
+		if redactPath(doc, path) {
+			changed = true
+		}
+	}
+	if !changed {
+		return p, false
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return json.RawMessage(maskedValue), true
+	}
+	return out, true
+}
+
+// redactPath masks the values at path below v, reporting whether any were
+// found.
+func redactPath(v any, path []string) bool {
+	switch v := v.(type) {
+	case []any:
+		changed := false
+		for _, child := range v {
+			if redactPath(child, path) {
+				changed = true
+			}
+		}
+		return changed
+	case map[string]any:
+		seg, rest := path[0], path[1:]
+		changed := false
+		if seg == "**" {
+			// Zero levels: the rest of the path applies here.
+			if len(rest) > 0 && redactPath(v, rest) {
+				changed = true
+			}
+			// One or more levels: keep ** while descending.
+			for _, child := range v {
+				if redactPath(child, path) {
+					changed = true
+				}
+			}
+			return changed
+		}
+		for k, child := range v {
+			if seg != "*" && seg != k {
+				continue
+			}
+			if len(rest) == 0 {
+				v[k] = "***"
+				changed = true
+			} else if redactPath(child, rest) {
+				changed = true
+			}
+		}
+		return changed
+	}
+	return false
+}
+
+func (r *Redactor) seal(p []byte) ([]byte, error) {
+	nonce := make([]byte, r.aead.NonceSize())
+	if _, err := io.ReadFull(r.rand, nonce); err != nil {
+		return nil, fmt.Errorf("redactor: nonce: %w", err)
+	}
+	return r.aead.Seal(nonce, nonce, p, nil), nil
+}
+
+// Unseal returns the unredacted payload of e. It fails with
+// ErrNoSealedPayload if the payload was dropped or never redacted.
+func (r *Redactor) Unseal(e Entry) (json.RawMessage, error) {
+	if r.aead == nil || len(e.SealedPayload) == 0 {
+		return nil, ErrNoSealedPayload
+	}
+	n := r.aead.NonceSize()
+	if len(e.SealedPayload) < n {
+		return nil, errors.New("dlq: sealed payload is too short")
+	}
+	p, err := r.aead.Open(nil, e.SealedPayload[:n], e.SealedPayload[n:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("dlq: unseal payload: %w", err)
+	}
+	return p, nil
+}
+
+// unredact returns e with its original payload unsealed by r, no longer
+// marked redacted. Entries r cannot unseal are returned unchanged, so
+// payloadUnavailable refuses them.
+func unredact(r *Redactor, e Entry) Entry {
+	if !e.PayloadRedacted || r == nil {
+		return e
+	}
+	p, err := r.Unseal(e)
+	if err != nil {
+		if !errors.Is(err, ErrNoSealedPayload) {
+			slog.Warn("dlq: failed to unseal redacted payload", "dlq_id", e.DLQID, "error", err)
+		}
+		return e
+	}
+	e.OriginalPayload = p
+	e.PayloadRedacted = false
+	return e
+}
+
+// Transform makes a Redactor a PayloadTransform: retries of redacted
+// entries republish the unsealed original, and other entries are left as
+// they are.
+func (r *Redactor) Transform(e Entry, payload json.RawMessage) (json.RawMessage, error) {
+	if !e.PayloadRedacted {
+		return payload, nil
+	}
+	return r.Unseal(e)
+}
@@ -0,0 +1,182 @@
+package dlq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestRedactor_Paths(t *testing.T) {
+	payload := `{"api_key":"k1","credentials":{"api_key":"k2","user":"u"},` +
+		`"users":[{"email":"a@x","name":"a"},{"email":"b@x"}],` +
+		`"deep":{"a":{"b":{"token":"t"}}},"svc":{"password":"p"},"note":"keep"}`
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"credentials.api_key", `"credentials":{"api_key":"***","user":"u"}`},
+		{"$.api_key", `"api_key":"***"`},
+		{"users.email", `"users":[{"email":"***","name":"a"},{"email":"***"}]`},
+		{"*.password", `"svc":{"password":"***"}`},
+		{"**.token", `"deep":{"a":{"b":{"token":"***"}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			r, err := NewRedactor([]string{tt.path})
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, changed := r.payload(json.RawMessage(payload))
+			if !changed || !bytes.Contains(out, []byte(tt.want)) {
+				t.Errorf("expected %s in %s", tt.want, out)
+			}
+			if !bytes.Contains(out, []byte(`"note":"keep"`)) {
+				t.Errorf("unrelated fields must be kept: %s", out)
+			}
+		})
+	}
+
+	r, _ := NewRedactor([]string{"missing.field"})
+	if _, changed := r.payload(json.RawMessage(payload)); changed {
+		t.Error("a path that matches nothing must not report a change")
+	}
+	if out, changed := r.payload(json.RawMessage(`not json`)); !changed || string(out) != maskedValue {
+		t.Errorf("invalid JSON must be masked entirely, got %s", out)
+	}
+
+	r, _ = NewRedactor([]string{"api_key"})
+	if out, _ := r.payload(json.RawMessage(`{"api_key":"k","id":9007199254740993}`)); !bytes.Contains(out, []byte(`"id":9007199254740993`)) {
+		t.Errorf("large integers must survive redaction, got %s", out)
+	}
+}
+
+func TestNewRedactor_Invalid(t *testing.T) {
+	for _, p := range []string{"", "a..b", "a.**"} {
+		if _, err := NewRedactor([]string{p}); err == nil {
+			t.Errorf("path %q should be rejected", p)
+		}
+	}
+	if _, err := NewRedactor(nil, WithRedactorKey([]byte("short"))); err == nil {
+		t.Error("a key that is not 16, 24 or 32 bytes should be rejected")
+	}
+}
+
+func TestProcessor_Redaction(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	for _, tt := range []struct {
+		name string
+		opts []RedactorOption
+	}{
+		{"sealed", []RedactorOption{WithRedactorKey(key)}},
+		{"dropped", nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRedactor([]string{"api_key"}, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			store := newMockStore()
+			m := NewMetrics()
+			proc := NewProcessor(store, WithProcessorRedaction(r), WithProcessorMetrics(m))
+
+			original := `{"api_key":"secret","task_id":"t1"}`
+			data := eventJSON(Entry{DLQID: "rd-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(original), Reason: ReasonNoCapableAgent})
+			proc.Process(context.Background(), SubjectTaskUnassignable, data)
+
+			e := store.entries["rd-1"]
+			if e == nil {
+				t.Fatal("entry not stored")
+			}
+			if bytes.Contains(e.OriginalPayload, []byte("secret")) || !e.PayloadRedacted {
+				t.Errorf("expected a redacted payload, got %s", e.OriginalPayload)
+			}
+			if bytes.Contains(e.SealedPayload, []byte("secret")) {
+				t.Error("the sealed payload must be encrypted")
+			}
+			if got := m.Get(MetricProcessorRedacted); got != 1 {
+				t.Errorf("expected 1 redaction counted, got %d", got)
+			}
+
+			unsealed, err := r.Transform(*e, e.OriginalPayload)
+			if tt.opts == nil {
+				if e.Recoverable {
+					t.Error("an entry whose original payload was dropped must not be recoverable")
+				}
+				if !errors.Is(err, ErrNoSealedPayload) {
+					t.Errorf("expected ErrNoSealedPayload for a dropped payload, got %v", err)
+				}
+				return
+			}
+			if err != nil || string(unsealed) != original {
+				t.Errorf("expected the original payload back, got %s (%v)", unsealed, err)
+			}
+		})
+	}
+}
+
+func TestProcessor_RedactionFailureParks(t *testing.T) {
+	r, err := NewRedactor([]string{"api_key"}, WithRedactorKey(bytes.Repeat([]byte{7}, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.rand = iotest.ErrReader(errors.New("entropy exhausted"))
+	store := &parkingStore{mockStore: newMockStore()}
+	proc := NewProcessor(store, WithProcessorRedaction(r))
+
+	data := eventJSON(Entry{DLQID: "rd-fail", OriginalSubject: "swarm.task.request",
+		OriginalPayload: json.RawMessage(`{"api_key":"secret"}`), Reason: ReasonNoCapableAgent})
+	err = proc.ProcessWithResult(context.Background(), SubjectTaskUnassignable, data)
+	if !errors.Is(err, ErrInvalidEvent) {
+		t.Fatalf("expected a failed redaction to be reported invalid, got %v", err)
+	}
+	if store.insertCalls != 0 {
+		t.Error("an entry that failed to redact must not be stored")
+	}
+	if len(store.parked) != 1 || !strings.Contains(store.parked[0].Error, "redact payload") {
+		t.Errorf("expected the event parked with the redaction error, got %+v", store.parked)
+	}
+}
+
+func TestRedactor_RetryUnseals(t *testing.T) {
+	r, err := NewRedactor([]string{"api_key"}, WithRedactorKey(bytes.Repeat([]byte{7}, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := `{"api_key":"secret","task_id":"t1"}`
+	sealed, _, _ := r.apply(Entry{DLQID: "rd-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(original), Recoverable: true})
+	dropped := Entry{DLQID: "rd-2", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{"api_key":"***"}`), PayloadRedacted: true, Recoverable: true}
+
+	store := newMockStore()
+	store.seed(sealed, dropped)
+	nc := newMockNATS()
+	router := chi.NewRouter()
+	router.Mount("/dlq", NewHandler(store, nc, WithRedactor(r)).Routes())
+
+	if w := doWithKey(router, "POST", "/dlq/rd-1/retry", ""); w.Code != 200 {
+		t.Fatalf("retry: expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if msgs := nc.published(); len(msgs) != 1 || string(msgs[0].Data) != original {
+		t.Errorf("expected the unsealed payload to be republished, got %+v", msgs)
+	}
+	if w := doWithKey(router, "POST", "/dlq/rd-2/retry", ""); w.Code != 409 {
+		t.Errorf("retry of an unsealable entry: expected 409, got %d", w.Code)
+	}
+
+	store.seed(sealed)
+	scanNC := newMockNATS()
+	scanner := NewScanner(store, scanNC, time.Minute, WithScannerRedactor(r))
+	scanner.scan(context.Background())
+	if st := scanner.Status(); st.Retried != 1 || st.Skipped != 1 {
+		t.Errorf("expected the sealed entry retried and the dropped one skipped, got %+v", st)
+	}
+	if msgs := scanNC.published(); len(msgs) != 1 || string(msgs[0].Data) != original {
+		t.Errorf("expected the scanner to republish the unsealed payload, got %+v", msgs)
+	}
+}
@@ -0,0 +1,84 @@
+package dlq
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// relatedEntries returns the other entries that share e's task id or
+// fingerprint, newest first and at most limit of them. With archived, entries
+// moved to swarm_dlq_archive are included as well.
+func relatedEntries(ctx context.Context, store Reader, e Entry, limit int, archived bool) ([]Entry, error) {
+	var filters []ListOpts
+	if e.TaskID != "" {
+		filters = append(filters, ListOpts{TaskID: e.TaskID, Limit: limit + 1})
+	}
+	if e.Fingerprint != "" {
+		filters = append(filters, ListOpts{Fingerprint: e.Fingerprint, Limit: limit + 1})
+	}
+
+	seen := map[string]bool{e.DLQID: true}
+	related := []Entry{}
+	add := func(entries []Entry) {
+		for _, r := range entries {
+			if !seen[r.DLQID] {
+				seen[r.DLQID] = true
+				related = append(related, r)
+			}
+		}
+	}
+	for _, opts := range filters {
+		entries, err := store.List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		add(entries)
+		if archived {
+			if entries, err = store.ListArchived(ctx, opts); err != nil {
+				return nil, err
+			}
+			add(entries)
+		}
+	}
+	sort.SliceStable(related, func(i, j int) bool {
+		return listLess(ListSortFailedAt, &related[i], &related[j])
+	})
+	if len(related) > limit {
+		related = related[:limit]
+	}
+	return related, nil
+}
+
+// handleRelated serves GET /{dlqID}/related: the entry's failure history
+// across dead-letterings, i.e. the other entries with its task id or
+// fingerprint.
+func (h *Handler) handleRelated(w http.ResponseWriter, r *http.Request) {
+	dlqID := chi.URLParam(r, "dlqID")
+	entry, err := h.store.Get(r.Context(), dlqID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "dlq entry not found"})
+		return
+	}
+	q := r.URL.Query()
+	limit := listLimit(ListOpts{})
+	if v := q.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	archived, _ := strconv.ParseBool(q.Get("archived"))
+
+	related, err := relatedEntries(r.Context(), h.store, *entry, limit, archived)
+	if err != nil {
+		slog.Error("list related dlq entries failed", "dlq_id", dlqID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	h.readPayloads(r, AuditPayloadExport, related)
+	writeEntries(w, http.StatusOK, negotiateFormat(r), related)
+}
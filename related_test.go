@@ -0,0 +1,61 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_Related(t *testing.T) {
+	store := newMockStore()
+	now := time.Now().UTC()
+	store.seed(
+		Entry{DLQID: "rel-1", TaskID: "t1", Fingerprint: "fp-a", FailedAt: now},
+		Entry{DLQID: "rel-2", TaskID: "t1", Fingerprint: "fp-b", FailedAt: now.Add(-time.Minute)},
+		Entry{DLQID: "rel-3", Fingerprint: "fp-a", FailedAt: now.Add(-2 * time.Minute)},
+		Entry{DLQID: "rel-4", TaskID: "t1", Recovered: true, FailedAt: now.Add(-3 * time.Minute)},
+		Entry{DLQID: "rel-5", TaskID: "t2", Fingerprint: "fp-c", FailedAt: now},
+	)
+	if _, err := store.Archive(context.Background(), ArchiveFilter{Recovered: true}); err != nil {
+		t.Fatal(err)
+	}
+	r := newTestRouter(store, newMockNATS())
+
+	related := func(path string) []string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", path, w.Code, w.Body)
+		}
+		var entries []Entry
+		_ = json.Unmarshal(w.Body.Bytes(), &entries)
+		ids := []string{}
+		for _, e := range entries {
+			ids = append(ids, e.DLQID)
+		}
+		return ids
+	}
+
+	if got := related("/dlq/rel-1/related"); len(got) != 2 || got[0] != "rel-2" || got[1] != "rel-3" {
+		t.Errorf("expected [rel-2 rel-3], got %v", got)
+	}
+	if got := related("/dlq/rel-1/related?archived=true"); len(got) != 3 || got[2] != "rel-4" {
+		t.Errorf("expected the archived entry last, got %v", got)
+	}
+	if got := related("/dlq/rel-1/related?limit=1"); len(got) != 1 || got[0] != "rel-2" {
+		t.Errorf("expected only the newest related entry, got %v", got)
+	}
+	if got := related("/dlq/rel-5/related"); len(got) != 0 {
+		t.Errorf("expected no related entries, got %v", got)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/missing/related", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
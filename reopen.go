@@ -0,0 +1,102 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// AuditReopen records a recovered, discarded or expired entry being reopened.
+const AuditReopen AuditAction = "entry.reopen"
+
+// ErrNotRecovered is returned by Reopen for an entry that is still open.
+var ErrNotRecovered = errors.New("dlq entry is not recovered")
+
+// Reopen undoes a recovery, discard or expiry: the entry returns to
+// StatusPending with its recovered and discarded fields cleared and its
+// automatic retry count reset, so it can be retried again. Its recovery
+// window restarts at the reopen. A non-zero version makes the reopen
+// conditional on the entry still being at that version. It fails with
+// ErrNotRecovered, a *VersionConflictError or a wrapped pgx.ErrNoRows.
+func (s *Store) Reopen(ctx context.Context, dlqID, by string, version int) (_ *Entry, err error) {
+	defer s.observe("reopen", time.Now(), &err)
+	row := s.pool.QueryRow(ctx, `
+		UPDATE swarm_dlq
+		SET recovered = false, recovered_at = NULL, recovered_by = NULL, status = 'pending',
+		    discarded_at = NULL, discarded_by = NULL, discard_reason = NULL, discard_note = NULL,
+		    expired_at = NULL, auto_retry_count = 0, next_retry_at = NULL,
+		    reopened_at = now(), reopened_by = nullif($2, '')
+		WHERE dlq_id = $1 AND recovered = true AND ($3 = 0 OR version = $3) AND `+tenantClause(4)+`
+		RETURNING `+entryColumns, dlqID, by, version, s.tenant(ctx))
+	e, err := scanEntry(row)
+	if err == nil {
+		return e, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("reopen dlq entry: %w", err)
+	}
+
+	var recovered bool
+	var current int
+	err = s.pool.QueryRow(ctx, `SELECT recovered, version FROM swarm_dlq WHERE dlq_id = $1 AND `+tenantClause(2), dlqID, s.tenant(ctx)).Scan(&recovered, &current)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("reopen dlq entry %s: %w", dlqID, pgx.ErrNoRows)
+	case err != nil:
+		return nil, fmt.Errorf("reopen dlq entry: %w", err)
+	case version != 0 && current != version:
+		return nil, &VersionConflictError{DLQID: dlqID, Expected: version, Current: current}
+	}
+	return nil, ErrNotRecovered
+}
+
+// reopenRequest is the optional body of POST /{dlqID}/reopen. Reason is
+// recorded in the audit trail.
+type reopenRequest struct {
+	Reason  string `json:"reason,omitempty"`
+	Version int    `json:"version,omitempty"`
+}
+
+// handleReopen serves POST /{dlqID}/reopen, for entries recovered by mistake
+// or whose retry failed downstream.
+func (h *Handler) handleReopen(w http.ResponseWriter, r *http.Request) {
+	dlqID := chi.URLParam(r, "dlqID")
+	var req reopenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid reopen body"})
+		return
+	}
+	actor := actorFromRequest(r)
+	if e, err := h.store.Get(r.Context(), dlqID); err == nil && e.claimedByOther(actor, time.Now()) {
+		writeJSON(w, http.StatusLocked, map[string]string{"error": ErrEntryClaimed.Error(), "claimed_by": e.ClaimedBy})
+		return
+	}
+
+	entry, err := h.store.Reopen(r.Context(), dlqID, actor, req.Version)
+	var conflict *VersionConflictError
+	switch {
+	case errors.As(err, &conflict):
+		writeJSON(w, http.StatusConflict, map[string]any{"error": ErrVersionConflict.Error(), "version": conflict.Current})
+		return
+	case errors.Is(err, ErrNotRecovered):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, pgx.ErrNoRows):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "dlq entry not found"})
+		return
+	case err != nil:
+		slog.Error("failed to reopen dlq entry", "dlq_id", dlqID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	h.auditEntry(r, AuditReopen, dlqID, AuditResultOK, req.Reason)
+	writeJSON(w, http.StatusOK, map[string]any{"status": "reopened", "dlq_id": dlqID, "version": entry.Version})
+}
@@ -0,0 +1,84 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_Reopen(t *testing.T) {
+	store := newMockStore()
+	store.seed(
+		Entry{DLQID: "ro-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true},
+		Entry{DLQID: "ro-2", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`)},
+	)
+	audit := &memAudit{}
+	r := authRouter(store, WithAuditRecorder(audit))
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		return w
+	}
+
+	if w := post("/dlq/ro-1/retry", ""); w.Code != http.StatusOK {
+		t.Fatalf("retry: expected 200, got %d", w.Code)
+	}
+	if w := post("/dlq/ro-1/reopen", `{"reason":"consumer rejected the replay"}`); w.Code != http.StatusOK {
+		t.Fatalf("reopen: expected 200, got %d: %s", w.Code, w.Body)
+	}
+	e, _ := store.Get(context.Background(), "ro-1")
+	if e.Recovered || e.Status != StatusPending || e.RecoveredBy != "" || e.ReopenedAt == nil {
+		t.Fatalf("expected a pending entry with the recovery cleared, got %+v", e)
+	}
+	if w := post("/dlq/ro-1/retry", ""); w.Code != http.StatusOK {
+		t.Errorf("expected the reopened entry to be retryable, got %d", w.Code)
+	}
+	recs, _ := audit.ListAudit(context.Background(), "ro-1")
+	var reopened bool
+	for _, rec := range recs {
+		reopened = reopened || (rec.Action == AuditReopen && rec.Detail == "consumer rejected the replay")
+	}
+	if !reopened {
+		t.Errorf("expected an entry.reopen audit record with the reason, got %+v", recs)
+	}
+
+	if w := post("/dlq/ro-2/reopen", ""); w.Code != http.StatusConflict {
+		t.Errorf("reopening an open entry: expected 409, got %d", w.Code)
+	}
+	_ = store.MarkDiscarded(context.Background(), "ro-2", "alice", DiscardOpts{Reason: "dup"})
+	if w := post("/dlq/ro-2/reopen", `{"version":1}`); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), `"version":2`) {
+		t.Errorf("stale version: expected 409 with the current version, got %d: %s", w.Code, w.Body)
+	}
+	if w := post("/dlq/ro-2/reopen", `{"version":2}`); w.Code != http.StatusOK {
+		t.Errorf("reopening a discarded entry: expected 200, got %d", w.Code)
+	}
+	if e, _ := store.Get(context.Background(), "ro-2"); e.DiscardedAt != nil || e.DiscardReason != "" {
+		t.Errorf("expected the discard cleared, got %+v", e)
+	}
+	if w := post("/dlq/missing/reopen", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestScanner_ReopenRestartsRecoveryWindow(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "ro-3", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`),
+		Recoverable: true, FailedAt: time.Now().Add(-2 * RecoveryWindow)})
+	s := NewScanner(store, newMockNATS(), time.Minute)
+	s.scan(context.Background())
+	if e, _ := store.Get(context.Background(), "ro-3"); e.Status != StatusExpired {
+		t.Fatalf("expected the old entry expired, got %s", e.Status)
+	}
+
+	if _, err := store.Reopen(context.Background(), "ro-3", "alice", 0); err != nil {
+		t.Fatal(err)
+	}
+	s.scan(context.Background())
+	if e, _ := store.Get(context.Background(), "ro-3"); e.Status != StatusRecovered || e.RecoveredBy != RecoveredByScanner {
+		t.Errorf("expected the reopened entry retried within its fresh recovery window, got %s", e.Status)
+	}
+}
@@ -0,0 +1,94 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandler_Replay_PublishesUnderPrefix(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(Entry{DLQID: "rp-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{"task_id":"t1"}`)})
+	r := newTestRouter(store, nc)
+
+	req := httptest.NewRequest("POST", "/dlq/rp-1/replay?prefix=staging.", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	msgs := nc.published()
+	if len(msgs) != 1 || msgs[0].Subject != "staging.swarm.task.request" {
+		t.Fatalf("expected publish to staging.swarm.task.request, got %+v", msgs)
+	}
+	if string(msgs[0].Data) != `{"task_id":"t1"}` {
+		t.Errorf("expected original payload, got %s", msgs[0].Data)
+	}
+	if e, _ := store.Get(context.Background(), "rp-1"); e.Recovered {
+		t.Error("replay must not mark the entry recovered")
+	}
+}
+
+func TestHandler_Replay_InvalidPrefix(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "rp-2", OriginalSubject: "swarm.task.request"})
+	r := newTestRouter(store, newMockNATS())
+
+	for _, q := range []string{"", "?prefix=staging", "?prefix=.staging.", "?prefix=a.>.", "?prefix=a..b."} {
+		req := httptest.NewRequest("POST", "/dlq/rp-2/replay"+q, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", q, w.Code)
+		}
+	}
+}
+
+func TestHandler_Replay_AllowedPrefixes(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "rp-3", OriginalSubject: "swarm.task.request"})
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, newMockNATS(), WithReplayPrefixes("staging.")).Routes())
+
+	req := httptest.NewRequest("POST", "/dlq/rp-3/replay?prefix=prod.", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for disallowed prefix, got %d", w.Code)
+	}
+}
+
+func TestHandler_ReplayBulk(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(
+		Entry{DLQID: "rb-1", OriginalSubject: "swarm.task.request", Reason: ReasonNoCapableAgent, OriginalPayload: json.RawMessage(`{}`)},
+		Entry{DLQID: "rb-2", OriginalSubject: "swarm.task.request", Reason: ReasonNoCapableAgent, OriginalPayload: json.RawMessage(`{}`)},
+		Entry{DLQID: "rb-3", OriginalSubject: "swarm.agent.heartbeat", Reason: ReasonBootFailure, OriginalPayload: json.RawMessage(`{}`)},
+	)
+	r := newTestRouter(store, nc)
+
+	req := httptest.NewRequest("POST", "/dlq/replay?prefix=staging.&reason=no_capable_agent", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var body map[string]any
+	_ = json.NewDecoder(w.Body).Decode(&body)
+	if int(body["replayed"].(float64)) != 2 {
+		t.Errorf("expected 2 replayed, got %v", body["replayed"])
+	}
+	for _, m := range nc.published() {
+		if m.Subject != "staging.swarm.task.request" {
+			t.Errorf("unexpected subject %s", m.Subject)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package dlq
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Default Replicator sizing.
+const (
+	DefaultReplicationQueueSize = 1024
+	DefaultReplicationBatchSize = 100
+	DefaultReplicationBatchWait = time.Second
+)
+
+// ReplicationSink receives entries forwarded by a Replicator.
+type ReplicationSink interface {
+	Replicate(ctx context.Context, entries []Entry) error
+}
+
+// StoreSink replicates into another DLQ store, e.g. a Store connected to a
+// database in another region.
+type StoreSink struct {
+	Store Writer
+}
+
+// Replicate inserts entries; ids the target already has are left untouched.
+func (s StoreSink) Replicate(ctx context.Context, entries []Entry) error {
+	_, err := s.Store.InsertBatch(ctx, entries)
+	return err
+}
+
+// RemoteSink replicates to another DLQ HTTP API by posting signed bundles to
+// its POST /bundle endpoint. The remote must be configured with the same
+// bundle key (see WithBundleKey).
+type RemoteSink struct {
+	remote Remote
+	key    []byte
+	client *http.Client
+}
+
+// NewRemoteSink creates a RemoteSink. A nil client defaults to one with a 10
+// second timeout.
+func NewRemoteSink(rm Remote, bundleKey []byte, client *http.Client) *RemoteSink {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &RemoteSink{remote: rm, key: bundleKey, client: client}
+}
+
+// Replicate posts entries to the remote as a single bundle.
+func (s *RemoteSink) Replicate(ctx context.Context, entries []Entry) error {
+	var body bytes.Buffer
+	if err := WriteBundle(&body, s.key, entries); err != nil {
+		return err
+	}
+	u := strings.TrimRight(s.remote.BaseURL, "/") + "/bundle"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, &body)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+	for k, val := range s.remote.Headers {
+		req.Header.Set(k, val)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("replicate to %s: %w", s.remote.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("replicate to %s: unexpected status %d", s.remote.Name, resp.StatusCode)
+	}
+	return nil
+}
+
+// Replicator forwards newly ingested entries to a ReplicationSink in the
+// background, for disaster recovery or a central aggregate view. Entries are
+// filtered and optionally masked before they leave, and sent in batches.
+// Replication is best effort: when the queue is full or the sink fails,
+// entries are dropped and counted rather than slowing ingestion down.
+type Replicator struct {
+	sink      ReplicationSink
+	filter    func(Entry) bool
+	mask      *PayloadMask
+	metrics   *Metrics
+	queue     chan Entry
+	batchSize int
+	batchWait time.Duration
+	done      chan struct{}
+}
+
+// ReplicatorOption configures a Replicator.
+type ReplicatorOption func(*Replicator)
+
+// WithReplicationFilter replicates only entries for which keep returns true.
+func WithReplicationFilter(keep func(Entry) bool) ReplicatorOption {
+	return func(r *Replicator) { r.filter = keep }
+}
+
+// WithReplicationMask masks the given payload fields, at any depth, before
+// entries are replicated. With no fields the whole payload is masked.
+func WithReplicationMask(fields ...string) ReplicatorOption {
+	return func(r *Replicator) { r.mask = &PayloadMask{Fields: fields} }
+}
+
+// WithReplicationBatching sends once maxSize entries are pending or maxWait
+// has elapsed since the first one arrived.
+func WithReplicationBatching(maxSize int, maxWait time.Duration) ReplicatorOption {
+	return func(r *Replicator) {
+		if maxSize > 0 {
+			r.batchSize = maxSize
+		}
+		if maxWait > 0 {
+			r.batchWait = maxWait
+		}
+	}
+}
+
+// WithReplicationQueueSize sets how many entries may wait to be sent.
+func WithReplicationQueueSize(n int) ReplicatorOption {
+	return func(r *Replicator) {
+		if n > 0 {
+			r.queue = make(chan Entry, n)
+		}
+	}
+}
+
+// WithReplicationMetrics counts sent, failed and dropped entries in m.
+func WithReplicationMetrics(m *Metrics) ReplicatorOption {
+	return func(r *Replicator) { r.metrics = m }
+}
+
+// NewReplicator creates a Replicator sending to sink. Call Start to begin
+// forwarding.
+func NewReplicator(sink ReplicationSink, opts ...ReplicatorOption) *Replicator {
+	r := &Replicator{
+		sink:      sink,
+		queue:     make(chan Entry, DefaultReplicationQueueSize),
+		batchSize: DefaultReplicationBatchSize,
+		batchWait: DefaultReplicationBatchWait,
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Enqueue queues e for replication without blocking. Entries rejected by the
+// filter are ignored; if the queue is full e is dropped.
+func (r *Replicator) Enqueue(e Entry) {
+	if r.filter != nil && !r.filter(e) {
+		return
+	}
+	if r.mask != nil {
+		e = r.mask.entry(e)
+	}
+	select {
+	case r.queue <- e:
+	default:
+		r.metrics.Inc(MetricReplicationDropped)
+		slog.Warn("dlq replication: queue full, dropping entry", "dlq_id", e.DLQID)
+	}
+}
+
+// Start begins forwarding in the background until ctx is cancelled, at which
+// point everything still pending is sent one last time.
+func (r *Replicator) Start(ctx context.Context) {
+	go func() {
+		defer close(r.done)
+		var batch []Entry
+		timer := time.NewTimer(r.batchWait)
+		timer.Stop()
+		defer timer.Stop()
+
+		for {
+			select {
+			case e := <-r.queue:
+				if len(batch) == 0 {
+					timer.Reset(r.batchWait)
+				}
+				batch = append(batch, e)
+				if len(batch) >= r.batchSize {
+					timer.Stop()
+					r.send(ctx, batch)
+					batch = nil
+				}
+			case <-timer.C:
+				r.send(ctx, batch)
+				batch = nil
+			case <-ctx.Done():
+				r.send(context.WithoutCancel(ctx), append(batch, r.drain()...))
+				return
+			}
+		}
+	}()
+}
+
+// Wait blocks until the replicator has stopped.
+func (r *Replicator) Wait() {
+	<-r.done
+}
+
+// drain empties the queue without blocking.
+func (r *Replicator) drain() []Entry {
+	var out []Entry
+	for {
+		select {
+		case e := <-r.queue:
+			out = append(out, e)
+		default:
+			return out
+		}
+	}
+}
+
+func (r *Replicator) send(ctx context.Context, batch []Entry) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := r.sink.Replicate(ctx, batch); err != nil {
+		r.metrics.Add(MetricReplicationFailed, int64(len(batch)))
+		slog.Error("dlq replication: failed to replicate batch", "count", len(batch), "error", err)
+		return
+	}
+	r.metrics.Add(MetricReplicationSent, int64(len(batch)))
+}
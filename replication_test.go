@@ -0,0 +1,150 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// memSink records replicated batches.
+type memSink struct {
+	mu      sync.Mutex
+	batches [][]Entry
+	err     error
+}
+
+func (s *memSink) Replicate(_ context.Context, entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, append([]Entry(nil), entries...))
+	return nil
+}
+
+func (s *memSink) entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Entry
+	for _, b := range s.batches {
+		out = append(out, b...)
+	}
+	return out
+}
+
+func TestReplicator_FiltersMasksAndBatches(t *testing.T) {
+	sink := &memSink{}
+	m := NewMetrics()
+	r := NewReplicator(sink,
+		WithReplicationFilter(func(e Entry) bool { return e.Source == SourceDispatch }),
+		WithReplicationMask("email"),
+		WithReplicationBatching(2, time.Hour),
+		WithReplicationMetrics(m),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	r.Start(ctx)
+
+	r.Enqueue(Entry{DLQID: "rp-1", Source: SourceDispatch, OriginalPayload: json.RawMessage(`{"email":"a@b.c"}`)})
+	r.Enqueue(Entry{DLQID: "rp-2", Source: SourceWarren})
+	r.Enqueue(Entry{DLQID: "rp-3", Source: SourceDispatch})
+	r.Enqueue(Entry{DLQID: "rp-4", Source: SourceDispatch})
+	cancel()
+	r.Wait()
+
+	got := sink.entries()
+	if len(got) != 3 {
+		t.Fatalf("expected 3 replicated entries, got %d", len(got))
+	}
+	if string(got[0].OriginalPayload) != `{"email":"***"}` {
+		t.Errorf("expected masked payload, got %s", got[0].OriginalPayload)
+	}
+	if m.Get(MetricReplicationSent) != 3 {
+		t.Errorf("expected 3 sent, got %d", m.Get(MetricReplicationSent))
+	}
+}
+
+func TestReplicator_SendsFullBatch(t *testing.T) {
+	sink := &memSink{}
+	r := NewReplicator(sink, WithReplicationBatching(2, time.Hour))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() { cancel(); r.Wait() }()
+	r.Start(ctx)
+
+	r.Enqueue(Entry{DLQID: "rp-10"})
+	r.Enqueue(Entry{DLQID: "rp-11"})
+	deadline := time.Now().Add(2 * time.Second)
+	for len(sink.entries()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(sink.entries()) != 2 {
+		t.Error("expected a full batch to be sent without waiting for maxWait")
+	}
+}
+
+func TestReplicator_QueueFullAndSinkErrors(t *testing.T) {
+	sink := &memSink{err: fmt.Errorf("region down")}
+	m := NewMetrics()
+	r := NewReplicator(sink, WithReplicationQueueSize(1), WithReplicationMetrics(m))
+
+	r.Enqueue(Entry{DLQID: "rp-5"})
+	r.Enqueue(Entry{DLQID: "rp-6"})
+	if m.Get(MetricReplicationDropped) != 1 {
+		t.Errorf("expected 1 dropped, got %d", m.Get(MetricReplicationDropped))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.Start(ctx)
+	cancel()
+	r.Wait()
+	if m.Get(MetricReplicationFailed) != 1 {
+		t.Errorf("expected 1 failed, got %d", m.Get(MetricReplicationFailed))
+	}
+}
+
+func TestProcessor_Replication(t *testing.T) {
+	store := newMockStore()
+	sink := &memSink{}
+	r := NewReplicator(sink)
+	proc := NewProcessor(store, WithProcessorReplication(r))
+
+	data := eventJSON(Entry{DLQID: "rp-7", Reason: ReasonNoCapableAgent})
+	proc.Process(context.Background(), "dlq.task.unassignable", data)
+	proc.Process(context.Background(), "dlq.task.unassignable", data)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.Start(ctx)
+	cancel()
+	r.Wait()
+	if got := sink.entries(); len(got) != 1 || got[0].DLQID != "rp-7" {
+		t.Errorf("expected rp-7 replicated once, got %+v", got)
+	}
+}
+
+func TestRemoteSink(t *testing.T) {
+	key := []byte("dr-key")
+	target := newMockStore()
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(target, newMockNATS(), WithBundleKey(key)).Routes())
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	sink := NewRemoteSink(Remote{Name: "dr", BaseURL: srv.URL + "/dlq/"}, key, nil)
+	if err := sink.Replicate(context.Background(), []Entry{{DLQID: "rp-8"}}); err != nil {
+		t.Fatalf("replicate: %v", err)
+	}
+	if _, err := target.Get(context.Background(), "rp-8"); err != nil {
+		t.Errorf("expected rp-8 on the remote: %v", err)
+	}
+
+	bad := NewRemoteSink(Remote{Name: "dr", BaseURL: srv.URL + "/dlq"}, []byte("wrong"), nil)
+	if err := bad.Replicate(context.Background(), []Entry{{DLQID: "rp-9"}}); err == nil {
+		t.Error("expected error when the remote rejects the bundle")
+	}
+}
@@ -0,0 +1,81 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RetryLease bounds how long BeginRetry holds an entry in StatusRetrying.
+// A retry whose process died mid-flight stops blocking new retries once its
+// lease has run out.
+const RetryLease = 5 * time.Minute
+
+var (
+	// ErrRetryInProgress is returned by BeginRetry while another retry of
+	// the entry holds its lease.
+	ErrRetryInProgress = errors.New("dlq entry is already being retried")
+	// ErrAlreadyRecovered is returned by BeginRetry for recovered entries.
+	ErrAlreadyRecovered = errors.New("dlq entry is already recovered")
+)
+
+// BeginRetry atomically moves an unrecovered entry to StatusRetrying and
+// returns it, so that of several concurrent retries only one publishes. It
+// fails with ErrAlreadyRecovered, ErrRetryInProgress, ErrEntryClaimed (the
+// entry is under someone else's claim) or a wrapped pgx.ErrNoRows. The
+// caller finishes with MarkRecovered, or AbortRetry if it did not publish.
+func (s *Store) BeginRetry(ctx context.Context, dlqID, actor string) (_ *Entry, err error) {
+	defer s.observe("begin_retry", time.Now(), &err)
+	row := s.pool.QueryRow(ctx, `
+		UPDATE swarm_dlq
+		SET status = 'retrying', retry_started_at = now(),
+		    status_before_retry = CASE WHEN status = 'retrying' THEN status_before_retry ELSE status END
+		WHERE dlq_id = $1 AND recovered = false AND `+tenantClause(4)+`
+		  AND (status <> 'retrying' OR retry_started_at <= now() - $3 * interval '1 microsecond')
+		  AND (claimed_by IS NULL OR claimed_by = $2 OR claim_expires_at <= now())
+		RETURNING `+entryColumns, dlqID, actor, RetryLease.Microseconds(), s.tenant(ctx))
+	e, err := scanEntry(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, s.retryConflict(ctx, dlqID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("begin retry: %w", err)
+	}
+	return e, nil
+}
+
+// retryConflict explains why BeginRetry matched no row.
+func (s *Store) retryConflict(ctx context.Context, dlqID string) error {
+	var recovered bool
+	var status string
+	err := s.pool.QueryRow(ctx, `SELECT recovered, status FROM swarm_dlq WHERE dlq_id = $1 AND `+tenantClause(2), dlqID, s.tenant(ctx)).Scan(&recovered, &status)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("begin retry %s: %w", dlqID, pgx.ErrNoRows)
+	case err != nil:
+		return fmt.Errorf("begin retry: %w", err)
+	case recovered:
+		return ErrAlreadyRecovered
+	case status == StatusRetrying:
+		return ErrRetryInProgress
+	}
+	return ErrEntryClaimed
+}
+
+// AbortRetry returns an entry left in StatusRetrying by BeginRetry to the
+// status it had before. Entries no longer retrying are left alone.
+func (s *Store) AbortRetry(ctx context.Context, dlqID string) (err error) {
+	defer s.observe("abort_retry", time.Now(), &err)
+	_, err = s.pool.Exec(ctx, `
+		UPDATE swarm_dlq
+		SET status = coalesce(status_before_retry, 'pending'), status_before_retry = NULL, retry_started_at = NULL
+		WHERE dlq_id = $1 AND status = 'retrying' AND `+tenantClause(2)+`
+	`, dlqID, s.tenant(ctx))
+	if err != nil {
+		return fmt.Errorf("abort retry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedNATS blocks publishes to non-event subjects until release is closed,
+// signalling entered as each one starts.
+type gatedNATS struct {
+	*mockNATS
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (g *gatedNATS) Publish(subject string, data []byte) error {
+	if !isEventSubject(subject) {
+		g.entered <- struct{}{}
+		<-g.release
+	}
+	return g.mockNATS.Publish(subject, data)
+}
+
+func TestHandler_Retry_ConcurrentPublishesOnce(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "c1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true})
+	nc := &gatedNATS{mockNATS: newMockNATS(), entered: make(chan struct{}, 2), release: make(chan struct{})}
+	r := newTestRouter(store, nc)
+
+	first := httptest.NewRecorder()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.ServeHTTP(first, httptest.NewRequest("POST", "/dlq/c1/retry", nil))
+	}()
+	<-nc.entered
+
+	second := httptest.NewRecorder()
+	r.ServeHTTP(second, httptest.NewRequest("POST", "/dlq/c1/retry", nil))
+	if second.Code != http.StatusConflict {
+		t.Errorf("expected 409 while a retry is in flight, got %d: %s", second.Code, second.Body)
+	}
+	close(nc.release)
+	wg.Wait()
+
+	if first.Code != http.StatusOK {
+		t.Errorf("expected the first retry to succeed, got %d", first.Code)
+	}
+	if got := len(nc.published()); got != 1 {
+		t.Errorf("expected exactly 1 republish, got %d", got)
+	}
+	if e, _ := store.Get(context.Background(), "c1"); e.Status != StatusRecovered || e.RetryStartedAt != nil {
+		t.Errorf("expected a recovered entry with no retry lease, got %s %v", e.Status, e.RetryStartedAt)
+	}
+}
+
+func TestHandler_Retry_AbortRestoresStatus(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "a1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Status: StatusExhausted})
+	nc := newMockNATS()
+	nc.err = errors.New("nats down")
+	r := newTestRouter(store, nc)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/dlq/a1/retry", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	e, _ := store.Get(context.Background(), "a1")
+	if e.Status != StatusExhausted || e.RetryStartedAt != nil {
+		t.Errorf("a failed retry should restore the previous status, got %s %v", e.Status, e.RetryStartedAt)
+	}
+
+	nc.err = nil
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/dlq/a1/retry", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected the entry to be retryable again, got %d", w.Code)
+	}
+}
+
+func TestScanner_ConcurrentWithHandlerRetry(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "sh-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Recoverable: true})
+	nc := &gatedNATS{mockNATS: newMockNATS(), entered: make(chan struct{}, 2), release: make(chan struct{})}
+	r := newTestRouter(store, nc)
+	scanner := NewScanner(store, nc, time.Minute)
+
+	// The scanner publishes first: an API retry of the same entry is refused.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scanner.scan(context.Background())
+	}()
+	<-nc.entered
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/dlq/sh-1/retry", nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 while the scanner retries, got %d: %s", w.Code, w.Body)
+	}
+	close(nc.release)
+	wg.Wait()
+	if got := len(nc.published()); got != 1 {
+		t.Errorf("expected exactly 1 republish, got %d", got)
+	}
+
+	// An API retry starts publishing after the scanner listed the entry: the
+	// scanner skips it.
+	store.seed(Entry{DLQID: "sh-2", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Recoverable: true})
+	nc.release = make(chan struct{})
+	first := httptest.NewRecorder()
+	scanner = NewScanner(store, nc, time.Minute, WithScannerBeforeRetry(func(context.Context, *Entry) (bool, error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.ServeHTTP(first, httptest.NewRequest("POST", "/dlq/sh-2/retry", nil))
+		}()
+		<-nc.entered
+		return true, nil
+	}))
+	scanner.scan(context.Background())
+	close(nc.release)
+	wg.Wait()
+	if first.Code != http.StatusOK {
+		t.Errorf("expected the API retry to succeed, got %d", first.Code)
+	}
+	if got := len(nc.published()); got != 2 {
+		t.Errorf("expected the scanner not to republish sh-2, got %d republishes in total", got)
+	}
+	if st := scanner.Status(); st.LastRun == nil || st.LastRun.Skipped != 1 {
+		t.Errorf("expected the scanner to skip the entry being retried, got %+v", st.LastRun)
+	}
+}
+
+func TestScanner_FailedRetryRestoresStatus(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "sf-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Recoverable: true})
+	nc := newMockNATS()
+	nc.err = errors.New("nats down")
+
+	NewScanner(store, nc, time.Minute).scan(context.Background())
+	if e, _ := store.Get(context.Background(), "sf-1"); e.Status == StatusRetrying || e.RetryStartedAt != nil {
+		t.Errorf("a failed scanner retry must release the entry, got %s %v", e.Status, e.RetryStartedAt)
+	}
+}
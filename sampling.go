@@ -0,0 +1,73 @@
+package dlq
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// omittedPayload replaces payloads that were not kept while sampling.
+var omittedPayload = json.RawMessage(`null`)
+
+// overloadSampler switches to 1-in-N payload retention while the ingest rate
+// is above a threshold (events per second).
+type overloadSampler struct {
+	mu          sync.Mutex
+	threshold   int
+	keepOneIn   int
+	now         func() time.Time
+	windowStart time.Time
+	count       int
+	lastRate    float64
+	seq         int
+}
+
+func newOverloadSampler(threshold, keepOneIn int) *overloadSampler {
+	return &overloadSampler{threshold: threshold, keepOneIn: keepOneIn, now: time.Now}
+}
+
+// observe counts one event and reports whether sampling is active and, if
+// so, whether this event's payload should be kept.
+func (s *overloadSampler) observe() (sampling, keep bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if elapsed := now.Sub(s.windowStart); elapsed >= time.Second {
+		s.lastRate = float64(s.count) / elapsed.Seconds()
+		s.windowStart = now
+		s.count = 0
+	}
+	s.count++
+
+	if s.lastRate <= float64(s.threshold) && s.count <= s.threshold {
+		s.seq = 0
+		return false, true
+	}
+	s.seq++
+	return true, s.seq%s.keepOneIn == 1 || s.keepOneIn == 1
+}
+
+// apply marks entry according to the sampling decision.
+func (s *overloadSampler) apply(entry Entry) (Entry, bool) {
+	sampling, keep := s.observe()
+	if !sampling {
+		return entry, false
+	}
+	entry.SampleRate = s.keepOneIn
+	if keep {
+		return entry, false
+	}
+	entry.OriginalPayload = omittedPayload
+	entry.PayloadOmitted = true
+	// Without its payload the entry cannot be replayed.
+	entry.Recoverable = false
+	note := fmt.Sprintf("payload omitted: ingest overload, keeping 1 in %d", s.keepOneIn)
+	if entry.ReasonDetail == "" {
+		entry.ReasonDetail = note
+	} else {
+		entry.ReasonDetail += " (" + note + ")"
+	}
+	return entry, true
+}
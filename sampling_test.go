@@ -0,0 +1,100 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOverloadSampler_KeepsOneInN(t *testing.T) {
+	s := newOverloadSampler(5, 3)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	kept, omitted := 0, 0
+	for i := 0; i < 5; i++ {
+		if sampling, _ := s.observe(); sampling {
+			t.Fatalf("event %d: should not sample below threshold", i)
+		}
+	}
+	for i := 0; i < 9; i++ {
+		sampling, keep := s.observe()
+		if !sampling {
+			t.Fatalf("event %d: expected sampling above threshold", i)
+		}
+		if keep {
+			kept++
+		} else {
+			omitted++
+		}
+	}
+	if kept != 3 || omitted != 6 {
+		t.Errorf("expected 3 kept / 6 omitted, got %d / %d", kept, omitted)
+	}
+
+	// A quiet period turns sampling off again.
+	now = now.Add(10 * time.Second)
+	s.observe()
+	now = now.Add(10 * time.Second)
+	if sampling, _ := s.observe(); sampling {
+		t.Error("expected sampling to stop once the rate drops")
+	}
+}
+
+func TestProcessor_AdaptiveSampling_MarksEntries(t *testing.T) {
+	store := newMockStore()
+	metrics := NewMetrics()
+	proc := NewProcessor(store, WithProcessorAdaptiveSampling(2, 2), WithProcessorMetrics(metrics))
+
+	for i := 0; i < 6; i++ {
+		data := eventJSON(Entry{DLQID: fmt.Sprintf("smp-%d", i), OriginalPayload: json.RawMessage(`{"big":true}`), Recoverable: true})
+		proc.Process(context.Background(), SubjectTaskUnassignable, data)
+	}
+
+	if store.insertCalls != 6 {
+		t.Fatalf("expected every event to be inserted, got %d", store.insertCalls)
+	}
+	omitted := 0
+	for _, e := range store.entries {
+		if e.PayloadOmitted {
+			omitted++
+			if e.Recoverable {
+				t.Errorf("%s: omitted entries must not be recoverable", e.DLQID)
+			}
+			if string(e.OriginalPayload) != "null" {
+				t.Errorf("%s: expected null payload, got %s", e.DLQID, e.OriginalPayload)
+			}
+			if e.SampleRate != 2 {
+				t.Errorf("%s: expected sample_rate 2, got %d", e.DLQID, e.SampleRate)
+			}
+		}
+	}
+	if omitted != 2 {
+		t.Errorf("expected 2 omitted payloads, got %d", omitted)
+	}
+	if got := metrics.Get(MetricProcessorPayloadsOmitted); got != 2 {
+		t.Errorf("expected metric 2, got %d", got)
+	}
+}
+
+func TestHandler_Retry_PayloadOmitted(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(Entry{DLQID: "om-1", OriginalSubject: "swarm.task.request", OriginalPayload: omittedPayload, PayloadOmitted: true})
+	r := newTestRouter(store, nc)
+
+	req := httptest.NewRequest("POST", "/dlq/om-1/retry", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", w.Code)
+	}
+	if len(nc.published()) != 0 {
+		t.Error("expected nothing republished")
+	}
+}
@@ -0,0 +1,65 @@
+package dlq
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultScannerLockKey is the Postgres advisory lock key used by
+// NewAdvisoryLock when key is zero.
+const DefaultScannerLockKey int64 = 0x73776172_6d646c71 // "swarmdlq"
+
+// ScanLock elects the replica that scans. Each Scanner pass tries to take
+// the lock and skips the pass if another replica holds it.
+type ScanLock interface {
+	// TryLock takes the lock without waiting. If acquired is false another
+	// holder has it; otherwise release must be called to give it up.
+	TryLock(ctx context.Context) (release func(), acquired bool, err error)
+}
+
+// AdvisoryLock is a ScanLock backed by a Postgres session-level advisory
+// lock. The lock is held on a connection taken from the pool for as long as
+// the pass runs, and is freed by Postgres if the replica dies mid-pass.
+type AdvisoryLock struct {
+	pool *pgxpool.Pool
+	key  int64
+}
+
+// NewAdvisoryLock creates an advisory lock on key, or on
+// DefaultScannerLockKey if key is zero. Replicas must use the same key.
+func NewAdvisoryLock(pool *pgxpool.Pool, key int64) *AdvisoryLock {
+	if key == 0 {
+		key = DefaultScannerLockKey
+	}
+	return &AdvisoryLock{pool: pool, key: key}
+}
+
+// TryLock implements ScanLock.
+func (l *AdvisoryLock) TryLock(ctx context.Context) (func(), bool, error) {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("advisory lock: acquire connection: %w", err)
+	}
+	var acquired bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, l.key).Scan(&acquired); err != nil {
+		conn.Release()
+		return nil, false, fmt.Errorf("advisory lock: %w", err)
+	}
+	if !acquired {
+		conn.Release()
+		return nil, false, nil
+	}
+	release := func() {
+		ctx := context.WithoutCancel(ctx)
+		if _, err := conn.Exec(ctx, `SELECT pg_advisory_unlock($1)`, l.key); err != nil {
+			// Closing the session is the only other way to drop the lock.
+			_ = conn.Conn().Close(ctx)
+		}
+		conn.Release()
+	}
+	return release, true, nil
+}
+
+var _ ScanLock = (*AdvisoryLock)(nil)
@@ -2,39 +2,213 @@ package dlq
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"math/rand/v2"
+	"sync"
 	"time"
 )
 
+// RecoveryWindow is how long after failing (or being reopened) an entry
+// stays eligible for automatic recovery. Each Scanner pass moves recoverable
+// entries older than this to StatusExpired.
+const RecoveryWindow = 24 * time.Hour
+
+// scannerExpireBatch bounds how many entries one pass expires.
+const scannerExpireBatch = 1000
+
+// BeforeRetryFunc is called by the Scanner before it republishes an entry.
+// Returning false vetoes the retry for this pass; the entry stays
+// recoverable. The hook may modify e, for example to patch OriginalPayload,
+// and the changed entry is what gets transformed and republished. An error
+// also skips the entry and is counted as a failure.
+type BeforeRetryFunc func(ctx context.Context, e *Entry) (bool, error)
+
 // Scanner periodically checks for recoverable DLQ entries and republishes them.
 // This implements Phase 3 automated recovery from the spec.
 type Scanner struct {
-	store    DataStore
-	nc       NATSPublisher
-	interval time.Duration
-	done     chan struct{}
+	store       DataStore
+	nc          NATSPublisher
+	interval    time.Duration
+	done        chan struct{}
+	transformer *Transformer
+	denyList    *DenyList
+	throttle    *SubjectThrottle
+	recovery    RecoveryActions
+	taskStatus  TaskStatusChecker
+	confirm     *RetryConfirmation
+	audit       AuditRecorder
+	live        *LiveFeed
+	backoff     RetryBackoff
+	maxRetries  int
+	lock        ScanLock
+	dryRun      bool
+	beforeRetry BeforeRetryFunc
+	metrics     *Metrics
+	jitter      time.Duration
+	notifier    Notifier
+	threshold   int
+	outbox      *Outbox
+	redactor    *Redactor
+	// overThreshold is whether the last pass saw the unrecovered count at
+	// or above threshold; only the scan loop touches it.
+	overThreshold bool
+
+	mu     sync.Mutex
+	status ScannerStatus
+}
+
+// ScannerStatus reports what a Scanner has done since it was created. Totals
+// add up the scan summaries of every completed pass; passes skipped because
+// another replica held the scan lock are not counted.
+type ScannerStatus struct {
+	Interval   string            `json:"interval"`
+	DryRun     bool              `json:"dry_run,omitempty"`
+	Runs       int               `json:"runs"`
+	Considered int               `json:"considered"`
+	Retried    int               `json:"retried"`
+	Failed     int               `json:"failed"`
+	Skipped    int               `json:"skipped"`
+	LastRun    *ScanSummaryEvent `json:"last_run,omitempty"`
+	// LastSuccessAt is when the most recent pass that could list entries
+	// started.
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+}
+
+// ScannerOption configures a Scanner.
+type ScannerOption func(*Scanner)
+
+// WithScannerTransformer rewrites payloads with t before they are republished.
+func WithScannerTransformer(t *Transformer) ScannerOption {
+	return func(s *Scanner) { s.transformer = t }
+}
+
+// WithScannerAudit records every retry the scanner performs (including
+// stale and failed ones) in a, attributed to RecoveredByScanner.
+func WithScannerAudit(a AuditRecorder) ScannerOption {
+	return func(s *Scanner) { s.audit = a }
+}
+
+// WithScannerDenyList skips entries whose subject or reason is in d, even
+// when they are marked recoverable.
+func WithScannerDenyList(d *DenyList) ScannerOption {
+	return func(s *Scanner) { s.denyList = d }
+}
+
+// WithScannerThrottle limits how fast entries are republished per original
+// subject. Throttled entries are left for a later scan.
+func WithScannerThrottle(t *SubjectThrottle) ScannerOption {
+	return func(s *Scanner) { s.throttle = t }
+}
+
+// WithScannerRecoveryActions recovers entries with the configured reasons via
+// their action instead of republishing the payload.
+func WithScannerRecoveryActions(a RecoveryActions) ScannerOption {
+	return func(s *Scanner) { s.recovery = a }
+}
+
+// WithScannerTaskStatusChecker consults c before republishing; entries whose
+// task already completed or was cancelled are marked recovered with
+// recovered_by=stale instead. Entries whose status cannot be checked are left
+// for a later scan.
+func WithScannerTaskStatusChecker(c TaskStatusChecker) ScannerOption {
+	return func(s *Scanner) { s.taskStatus = c }
+}
+
+// WithScannerBackoff sets the per-entry backoff after failed automatic
+// retries. The defaults are DefaultRetryBackoffBase and
+// DefaultRetryBackoffMax.
+func WithScannerBackoff(base, max time.Duration) ScannerOption {
+	return func(s *Scanner) { s.backoff = RetryBackoff{Base: base, Max: max} }
+}
+
+// WithScannerMaxRetries caps how many times the scanner retries an entry
+// automatically. When the nth retry fails the entry moves to StatusExhausted,
+// is no longer retried and an ExhaustedEvent is published. Zero, the default,
+// retries until the entry leaves the recovery window.
+func WithScannerMaxRetries(n int) ScannerOption {
+	return func(s *Scanner) { s.maxRetries = n }
+}
+
+// WithScannerLock runs a pass only while holding l, so that when several
+// replicas run a Scanner only one republishes at a time. Replicas that do
+// not get the lock skip the pass without publishing a scan summary. Use
+// NewAdvisoryLock to elect through Postgres.
+func WithScannerLock(l ScanLock) ScannerOption {
+	return func(s *Scanner) { s.lock = l }
+}
+
+// WithScannerDryRun makes the scanner only report what it would do: entries
+// it would republish or close as stale are logged and counted in the scan
+// summary, which is flagged dry_run, but nothing is published, marked,
+// backed off or audited. Throttles are not consumed.
+func WithScannerDryRun() ScannerOption {
+	return func(s *Scanner) { s.dryRun = true }
+}
+
+// WithScannerBeforeRetry calls fn before each republish, so integrators can
+// veto a retry (e.g. until Dispatch reports a capable agent) or enrich the
+// entry first. It runs after the deny-list, claim and stale checks.
+func WithScannerBeforeRetry(fn BeforeRetryFunc) ScannerOption {
+	return func(s *Scanner) { s.beforeRetry = fn }
+}
+
+// WithScannerMetrics records per-pass counters and the duration of the last
+// pass in m.
+func WithScannerMetrics(m *Metrics) ScannerOption {
+	return func(s *Scanner) { s.metrics = m }
+}
+
+// WithScannerJitter spreads passes out so that several services scanning the
+// same table do not all tick at once: the first pass starts after a random
+// delay of up to one interval, and each following pass waits the interval
+// plus a random delay of up to jitter.
+func WithScannerJitter(jitter time.Duration) ScannerOption {
+	return func(s *Scanner) { s.jitter = jitter }
+}
+
+// WithScannerNotifier sends a NotifyExhausted notification to n whenever the
+// scanner gives up on an entry, and threshold notifications if
+// WithScannerUnrecoveredThreshold is set.
+func WithScannerNotifier(n Notifier) ScannerOption {
+	return func(s *Scanner) { s.notifier = n }
+}
+
+// WithScannerUnrecoveredThreshold makes every pass check the unrecovered
+// count and send a NotifyThreshold notification when it rises to threshold
+// or above. It fires again only after the count has dropped below.
+func WithScannerUnrecoveredThreshold(threshold int) ScannerOption {
+	return func(s *Scanner) { s.threshold = threshold }
 }
 
 // NewScanner creates a DLQ recovery scanner.
-func NewScanner(store DataStore, nc NATSPublisher, interval time.Duration) *Scanner {
-	return &Scanner{
+func NewScanner(store DataStore, nc NATSPublisher, interval time.Duration, opts ...ScannerOption) *Scanner {
+	s := &Scanner{
 		store:    store,
 		nc:       nc,
 		interval: interval,
 		done:     make(chan struct{}),
+		backoff:  RetryBackoff{Base: DefaultRetryBackoffBase, Max: DefaultRetryBackoffMax},
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-// Start begins the periodic scan loop. Call with a cancellable context for shutdown.
+// Start begins the periodic scan loop. Call with a cancellable context for
+// shutdown. The interval is measured from the end of one pass to the start
+// of the next.
 func (s *Scanner) Start(ctx context.Context) {
-	ticker := time.NewTicker(s.interval)
+	timer := time.NewTimer(s.firstDelay())
 	go func() {
-		defer ticker.Stop()
+		defer timer.Stop()
 		defer close(s.done)
 		for {
 			select {
-			case <-ticker.C:
+			case <-timer.C:
 				s.scan(ctx)
+				timer.Reset(s.nextDelay())
 			case <-ctx.Done():
 				return
 			}
@@ -42,44 +216,277 @@ func (s *Scanner) Start(ctx context.Context) {
 	}()
 }
 
+// firstDelay is how long Start waits before the first pass.
+func (s *Scanner) firstDelay() time.Duration {
+	if s.jitter <= 0 || s.interval <= 0 {
+		return s.interval
+	}
+	return rand.N(s.interval)
+}
+
+// nextDelay is how long the scanner waits between passes.
+func (s *Scanner) nextDelay() time.Duration {
+	if s.jitter <= 0 {
+		return s.interval
+	}
+	return s.interval + rand.N(s.jitter)
+}
+
 // Wait blocks until the scanner has stopped.
 func (s *Scanner) Wait() {
 	<-s.done
 }
 
+// Status returns the scanner's run totals and the summary of its last pass.
+func (s *Scanner) Status() ScannerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.status
+	st.Interval = s.interval.String()
+	st.DryRun = s.dryRun
+	if st.LastRun != nil {
+		last := *st.LastRun
+		st.LastRun = &last
+	}
+	return st
+}
+
+// record folds a finished pass into the status and metrics.
+func (s *Scanner) record(summary ScanSummaryEvent) {
+	s.metrics.Inc(MetricScannerRuns)
+	s.metrics.Add(MetricScannerConsidered, int64(summary.Considered))
+	s.metrics.Add(MetricScannerRetried, int64(summary.Retried))
+	s.metrics.Add(MetricScannerFailed, int64(summary.Failed))
+	s.metrics.Add(MetricScannerSkipped, int64(summary.Skipped))
+	s.metrics.Set(MetricScannerLastDurationMS, summary.DurationMS)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Runs++
+	s.status.Considered += summary.Considered
+	s.status.Retried += summary.Retried
+	s.status.Failed += summary.Failed
+	s.status.Skipped += summary.Skipped
+	s.status.LastRun = &summary
+	if summary.Error == "" {
+		at := summary.StartedAt
+		s.status.LastSuccessAt = &at
+	}
+}
+
 func (s *Scanner) scan(ctx context.Context) {
-	entries, err := s.store.ListRecoverable(ctx)
+	if s.lock != nil {
+		release, acquired, err := s.lock.TryLock(ctx)
+		if err != nil {
+			slog.Error("dlq scanner: failed to take scan lock", "error", err)
+			return
+		}
+		if !acquired {
+			slog.Debug("dlq scanner: another replica holds the scan lock, skipping pass")
+			return
+		}
+		defer release()
+	}
+
+	summary := ScanSummaryEvent{StartedAt: time.Now().UTC(), DryRun: s.dryRun}
+	defer func() {
+		summary.DurationMS = time.Since(summary.StartedAt).Milliseconds()
+		s.record(summary)
+		// Report the pass even if it was cut short by shutdown.
+		publishEvent(context.WithoutCancel(ctx), s.nc, SubjectScanSummary, summary)
+	}()
+
+	if !s.dryRun {
+		summary.Expired = s.expire(ctx)
+	}
+	s.checkThreshold(ctx)
+
+	entries, err := s.dueEntries(ctx)
 	if err != nil {
 		slog.Error("dlq scanner: failed to list recoverable entries", "error", err)
+		summary.Error = err.Error()
 		return
 	}
+	summary.Considered = len(entries)
 
 	if len(entries) == 0 {
 		return
 	}
 
 	slog.Info("dlq scanner: found recoverable entries", "count", len(entries))
+	if d := circuitRetryAfter(s.nc); d > 0 && s.outbox == nil && !s.dryRun {
+		slog.Warn("dlq scanner: nats unavailable, skipping scan", "retry_after", d)
+		summary.Error = ErrCircuitOpen.Error()
+		return
+	}
 
-	retried := 0
+	throttled := 0
+	now := time.Now()
 	for _, entry := range entries {
-		if err := s.nc.Publish(entry.OriginalSubject, entry.OriginalPayload); err != nil {
-			slog.Error("dlq scanner: failed to republish",
+		if s.denyList.Denied(entry) {
+			slog.Debug("dlq scanner: skipping denied entry",
+				"dlq_id", entry.DLQID,
+				"reason", entry.Reason,
+				"original_subject", entry.OriginalSubject,
+			)
+			summary.Skipped++
+			continue
+		}
+		if entry.claimedByOther("", now) {
+			slog.Debug("dlq scanner: skipping claimed entry", "dlq_id", entry.DLQID, "claimed_by", entry.ClaimedBy)
+			summary.Skipped++
+			continue
+		}
+		stale, err := staleTask(ctx, s.taskStatus, entry)
+		if err != nil {
+			slog.Warn("dlq scanner: task status check failed", "dlq_id", entry.DLQID, "error", err)
+			summary.Failed++
+			continue
+		}
+		if stale && s.dryRun {
+			slog.Info("dlq scanner: dry run, would mark stale", "dlq_id", entry.DLQID)
+			summary.Stale++
+			continue
+		}
+		if stale {
+			if err := markStale(ctx, s.store, s.nc, entry); err != nil {
+				slog.Error("dlq scanner: failed to mark stale", "dlq_id", entry.DLQID, "error", err)
+				summary.Failed++
+				continue
+			}
+			s.recordRetry(ctx, entry, AuditResultStale, "")
+			summary.Stale++
+			continue
+		}
+		if s.beforeRetry != nil {
+			ok, err := s.beforeRetry(ctx, &entry)
+			if err != nil {
+				slog.Warn("dlq scanner: before-retry hook failed", "dlq_id", entry.DLQID, "error", err)
+				summary.Failed++
+				continue
+			}
+			if !ok {
+				slog.Debug("dlq scanner: retry vetoed by before-retry hook", "dlq_id", entry.DLQID)
+				summary.Skipped++
+				continue
+			}
+		}
+		entry = unredact(s.redactor, entry)
+		if entry.PayloadRedacted {
+			slog.Debug("dlq scanner: skipping redacted entry that cannot be unsealed", "dlq_id", entry.DLQID)
+			summary.Skipped++
+			continue
+		}
+		if !s.dryRun && !s.throttle.Allow(entry.OriginalSubject) {
+			throttled++
+			summary.Skipped++
+			continue
+		}
+		if !s.dryRun {
+			// Take the entry into StatusRetrying, as the Handler does, so a
+			// concurrent API retry of it is refused instead of publishing a
+			// second time. Every path below that does not publish releases it.
+			locked, err := s.store.BeginRetry(ctx, entry.DLQID, "")
+			switch {
+			case errors.Is(err, ErrRetryInProgress), errors.Is(err, ErrAlreadyRecovered), errors.Is(err, ErrEntryClaimed):
+				slog.Debug("dlq scanner: skipping entry being retried elsewhere", "dlq_id", entry.DLQID, "error", err)
+				summary.Skipped++
+				continue
+			case err != nil:
+				slog.Error("dlq scanner: failed to begin retry", "dlq_id", entry.DLQID, "error", err)
+				summary.Failed++
+				continue
+			}
+			// Keep any changes the before-retry hook made; only the version
+			// must match for MarkRecoveredIfVersion.
+			entry.Version = locked.Version
+		}
+
+		payload, err := s.transformer.Apply(entry)
+		if err != nil {
+			slog.Error("dlq scanner: failed to transform payload",
 				"dlq_id", entry.DLQID,
-				"subject", entry.OriginalSubject,
 				"error", err,
 			)
+			s.abortRetry(ctx, entry.DLQID)
+			if s.backOff(ctx, entry, err) {
+				summary.Exhausted++
+			}
+			summary.Failed++
 			continue
 		}
 
-		if err := s.store.MarkRecovered(ctx, entry.DLQID, "auto-scanner"); err != nil {
-			slog.Error("dlq scanner: failed to mark recovered",
+		subject, payload, err := s.recovery.resolve(entry, payload)
+		if err != nil {
+			slog.Error("dlq scanner: recovery action failed",
 				"dlq_id", entry.DLQID,
 				"error", err,
 			)
+			s.abortRetry(ctx, entry.DLQID)
+			if s.backOff(ctx, entry, err) {
+				summary.Exhausted++
+			}
+			summary.Failed++
 			continue
 		}
 
-		retried++
+		if s.dryRun {
+			slog.Info("dlq scanner: dry run, would republish",
+				"dlq_id", entry.DLQID,
+				"reason", entry.Reason,
+				"subject", subject,
+			)
+			summary.Retried++
+			continue
+		}
+
+		by := scannerRecoveredBy(entry)
+		if s.outbox != nil {
+			if _, err := s.outbox.Recover(ctx, entry, by, subject, payload); err != nil {
+				slog.Error("dlq scanner: failed to record recovery",
+					"dlq_id", entry.DLQID,
+					"error", err,
+				)
+				s.abortRetry(ctx, entry.DLQID)
+				summary.Failed++
+				continue
+			}
+		} else {
+			if err := republish(ctx, s.nc, s.confirm, s.store, entry, by, subject, payload); errors.Is(err, ErrCircuitOpen) {
+				// NATS is down: not the entry's fault, so leave its retry
+				// budget alone and try again next scan.
+				slog.Warn("dlq scanner: nats unavailable, ending scan early", "dlq_id", entry.DLQID)
+				s.abortRetry(ctx, entry.DLQID)
+				summary.Error = err.Error()
+				break
+			} else if err != nil {
+				slog.Error("dlq scanner: failed to republish",
+					"dlq_id", entry.DLQID,
+					"subject", subject,
+					"error", err,
+				)
+				s.recordRetry(ctx, entry, AuditResultFailed, err.Error())
+				s.abortRetry(ctx, entry.DLQID)
+				if s.backOff(ctx, entry, err) {
+					summary.Exhausted++
+				}
+				summary.Failed++
+				continue
+			}
+			if err := s.store.MarkRecoveredIfVersion(ctx, entry.DLQID, by, entry.Version); err != nil {
+				slog.Error("dlq scanner: failed to mark recovered",
+					"dlq_id", entry.DLQID,
+					"error", err,
+				)
+				summary.Failed++
+				continue
+			}
+		}
+		publishRecovered(ctx, s.nc, entry, by)
+		s.recordRetry(ctx, entry, AuditResultOK, subject)
+		s.live.Broadcast(liveEvent(LiveRetried, entry, by))
+
+		summary.Retried++
 		slog.Info("dlq scanner: retried entry",
 			"dlq_id", entry.DLQID,
 			"reason", entry.Reason,
@@ -87,7 +494,114 @@ func (s *Scanner) scan(ctx context.Context) {
 		)
 	}
 
-	if retried > 0 {
-		slog.Info("dlq scanner: scan complete", "retried", retried, "total", len(entries))
+	if throttled > 0 {
+		slog.Info("dlq scanner: throttled entries deferred to next scan", "throttled", throttled)
+	}
+	if summary.Retried > 0 {
+		slog.Info("dlq scanner: scan complete", "retried", summary.Retried, "total", len(entries))
+	}
+}
+
+// expire moves entries past the recovery window to StatusExpired and
+// announces each one. It returns how many were expired.
+func (s *Scanner) expire(ctx context.Context) int {
+	expired, err := s.store.ExpireRecoverable(ctx, time.Now().Add(-RecoveryWindow), scannerExpireBatch)
+	if err != nil {
+		slog.Error("dlq scanner: failed to expire entries", "error", err)
+		return 0
+	}
+	for _, e := range expired {
+		at := time.Now().UTC()
+		if e.ExpiredAt != nil {
+			at = *e.ExpiredAt
+		}
+		publishEvent(ctx, s.nc, SubjectExpired, ExpiredEvent{
+			DLQID:           e.DLQID,
+			FailedAt:        e.FailedAt,
+			ExpiredAt:       at,
+			OriginalSubject: e.OriginalSubject,
+			Reason:          e.Reason,
+			Source:          e.Source,
+		})
+		s.live.Broadcast(liveEvent(LiveExpired, e, RecoveredByScanner))
+	}
+	if len(expired) > 0 {
+		slog.Info("dlq scanner: expired entries past the recovery window", "count", len(expired))
+	}
+	return len(expired)
+}
+
+// checkThreshold notifies when the unrecovered count reaches the threshold.
+func (s *Scanner) checkThreshold(ctx context.Context) {
+	if s.threshold <= 0 || s.notifier == nil {
+		return
+	}
+	st, err := s.store.Stats(ctx)
+	if err != nil {
+		slog.Warn("dlq scanner: failed to check unrecovered threshold", "error", err)
+		return
+	}
+	over := st.Unrecovered >= s.threshold
+	if over && !s.overThreshold {
+		slog.Warn("dlq scanner: unrecovered entries reached threshold", "unrecovered", st.Unrecovered, "threshold", s.threshold)
+		notify(ctx, s.notifier, Notification{
+			Kind: NotifyThreshold, At: time.Now().UTC(),
+			Unrecovered: st.Unrecovered, Threshold: s.threshold,
+		})
+	}
+	s.overThreshold = over
+}
+
+// abortRetry releases an entry taken by BeginRetry that was not published.
+func (s *Scanner) abortRetry(ctx context.Context, dlqID string) {
+	if err := s.store.AbortRetry(context.WithoutCancel(ctx), dlqID); err != nil {
+		slog.Error("dlq scanner: failed to release retry", "dlq_id", dlqID, "error", err)
+	}
+}
+
+// backOff keeps entry out of the following scans for the backoff delay after
+// a failed automatic retry, or marks it exhausted once it has failed the
+// maximum number of times. It reports whether the entry was exhausted.
+func (s *Scanner) backOff(ctx context.Context, entry Entry, cause error) bool {
+	if s.dryRun {
+		return false
+	}
+	failures := entry.AutoRetryCount + 1
+	if s.maxRetries > 0 && failures >= s.maxRetries {
+		if err := s.store.MarkExhausted(ctx, entry.DLQID); err != nil {
+			slog.Error("dlq scanner: failed to mark exhausted", "dlq_id", entry.DLQID, "error", err)
+			return false
+		}
+		slog.Warn("dlq scanner: giving up on entry", "dlq_id", entry.DLQID, "attempts", failures)
+		publishEvent(ctx, s.nc, SubjectExhausted, ExhaustedEvent{
+			DLQID:           entry.DLQID,
+			Attempts:        failures,
+			LastError:       cause.Error(),
+			ExhaustedAt:     time.Now().UTC(),
+			OriginalSubject: entry.OriginalSubject,
+			Reason:          entry.Reason,
+			Source:          entry.Source,
+		})
+		s.live.Broadcast(liveEvent(LiveExhausted, entry, RecoveredByScanner))
+		n := entryNotification(NotifyExhausted, entry)
+		n.Attempts, n.Detail = failures, cause.Error()
+		notify(ctx, s.notifier, n)
+		return true
+	}
+	next := time.Now().Add(s.backoff.Delay(failures))
+	if err := s.store.RecordAutoRetryFailure(ctx, entry.DLQID, next); err != nil {
+		slog.Error("dlq scanner: failed to record retry backoff", "dlq_id", entry.DLQID, "error", err)
+	}
+	return false
+}
+
+// recordRetry writes an audit record for a retry of entry, if auditing is on.
+func (s *Scanner) recordRetry(ctx context.Context, entry Entry, result, detail string) {
+	if s.audit == nil {
+		return
 	}
+	recordAudit(ctx, s.audit, AuditRecord{
+		Actor: RecoveredByScanner, Action: AuditRetry, DLQID: entry.DLQID,
+		At: time.Now().UTC(), Result: result, Detail: detail,
+	})
 }
@@ -4,8 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
 func TestScanner_Scan_RecoverableEntries(t *testing.T) {
@@ -55,6 +59,30 @@ func TestScanner_Scan_NoRecoverableEntries(t *testing.T) {
 	}
 }
 
+func TestScanner_Scan_SkipsDeniedEntries(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(
+		Entry{DLQID: "sc-d1", OriginalSubject: "billing.charge", Reason: ReasonNoCapableAgent, Recoverable: true},
+		Entry{DLQID: "sc-d2", OriginalSubject: "swarm.task.request", Reason: ReasonBootFailure, Recoverable: true},
+		Entry{DLQID: "sc-d3", OriginalSubject: "swarm.task.request", Reason: ReasonNoCapableAgent, Recoverable: true},
+	)
+	deny := NewDenyList(DenyRules{Subjects: []string{"billing.charge"}, Reasons: []string{ReasonBootFailure}})
+
+	scanner := NewScanner(store, nc, time.Minute, WithScannerDenyList(deny))
+	scanner.scan(context.Background())
+
+	msgs := nc.published()
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 published message, got %d", len(msgs))
+	}
+	for _, id := range []string{"sc-d1", "sc-d2"} {
+		if e, _ := store.Get(context.Background(), id); e.Recovered {
+			t.Errorf("%s is denied and should not be recovered", id)
+		}
+	}
+}
+
 func TestScanner_Scan_NATSPublishError(t *testing.T) {
 	store := newMockStore()
 	nc := newMockNATS()
@@ -73,6 +101,72 @@ func TestScanner_Scan_NATSPublishError(t *testing.T) {
 	}
 }
 
+func TestScanner_Scan_BacksOffFailedEntries(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	nc.err = fmt.Errorf("nats connection lost")
+	store.seed(
+		Entry{DLQID: "sc-bo", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true},
+	)
+
+	scanner := NewScanner(store, nc, time.Minute, WithScannerBackoff(time.Minute, time.Hour))
+	before := time.Now()
+	scanner.scan(context.Background())
+
+	e, _ := store.Get(context.Background(), "sc-bo")
+	if e.AutoRetryCount != 1 {
+		t.Errorf("expected auto_retry_count 1, got %d", e.AutoRetryCount)
+	}
+	if e.NextRetryAt == nil || e.NextRetryAt.Before(before.Add(30*time.Second)) {
+		t.Fatalf("expected next_retry_at at least 30s out, got %v", e.NextRetryAt)
+	}
+
+	// The entry is not retried again until its backoff has elapsed.
+	nc.err = nil
+	scanner.scan(context.Background())
+	if msgs := nc.published(); len(msgs) != 0 {
+		t.Errorf("expected no retry during backoff, got %d messages", len(msgs))
+	}
+}
+
+func TestScanner_Scan_ExhaustsAfterMaxRetries(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	failing := NewTransformer(TransformRule{Transform: JSONPatch(PatchOp{Op: "remove", Path: "/missing"})})
+	store.seed(
+		Entry{DLQID: "sc-ex", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true, AutoRetryCount: 2},
+	)
+
+	scanner := NewScanner(store, nc, time.Minute, WithScannerTransformer(failing), WithScannerMaxRetries(3))
+	scanner.scan(context.Background())
+
+	e, _ := store.Get(context.Background(), "sc-ex")
+	if e.Status != StatusExhausted {
+		t.Fatalf("expected status exhausted, got %q", e.Status)
+	}
+	if e.Recovered {
+		t.Error("exhausted entry should stay open for operators")
+	}
+	if entries, _ := store.ListRecoverable(context.Background()); len(entries) != 0 {
+		t.Errorf("expected exhausted entry to leave ListRecoverable, got %d entries", len(entries))
+	}
+
+	events := nc.events(SubjectExhausted)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 exhausted event, got %d", len(events))
+	}
+	var ev ExhaustedEvent
+	_ = json.Unmarshal(events[0].Data, &ev)
+	if ev.DLQID != "sc-ex" || ev.Attempts != 3 || ev.LastError == "" {
+		t.Errorf("unexpected exhausted event: %+v", ev)
+	}
+	var summary ScanSummaryEvent
+	_ = json.Unmarshal(nc.events(SubjectScanSummary)[0].Data, &summary)
+	if summary.Failed != 1 || summary.Exhausted != 1 {
+		t.Errorf("expected 1 failed and 1 exhausted in summary, got %+v", summary)
+	}
+}
+
 func TestScanner_Scan_MarkRecoveredError(t *testing.T) {
 	store := newMockStore()
 	nc := newMockNATS()
@@ -165,3 +259,222 @@ func TestScanner_Scan_PublishesToCorrectSubject(t *testing.T) {
 		t.Errorf("expected subject swarm.agent.heartbeat, got %s", msgs[0].Subject)
 	}
 }
+
+// fakeScanLock is a ScanLock held by whoever set held.
+type fakeScanLock struct {
+	held     bool
+	err      error
+	released int
+}
+
+func (l *fakeScanLock) TryLock(context.Context) (func(), bool, error) {
+	if l.err != nil || l.held {
+		return nil, false, l.err
+	}
+	l.held = true
+	return func() { l.held = false; l.released++ }, true, nil
+}
+
+func TestScanner_Scan_Lock(t *testing.T) {
+	seed := func() (*mockStore, *mockNATS) {
+		store := newMockStore()
+		store.seed(Entry{DLQID: "sc-lock", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true})
+		return store, newMockNATS()
+	}
+
+	t.Run("held elsewhere", func(t *testing.T) {
+		store, nc := seed()
+		NewScanner(store, nc, time.Minute, WithScannerLock(&fakeScanLock{held: true})).scan(context.Background())
+		if len(nc.published()) != 0 || len(nc.events(SubjectScanSummary)) != 0 {
+			t.Error("expected the pass to be skipped without a summary")
+		}
+	})
+
+	t.Run("lock error", func(t *testing.T) {
+		store, nc := seed()
+		NewScanner(store, nc, time.Minute, WithScannerLock(&fakeScanLock{err: fmt.Errorf("db down")})).scan(context.Background())
+		if len(nc.published()) != 0 {
+			t.Error("expected no republish when the lock cannot be checked")
+		}
+	})
+
+	t.Run("acquired", func(t *testing.T) {
+		store, nc := seed()
+		lock := &fakeScanLock{}
+		NewScanner(store, nc, time.Minute, WithScannerLock(lock)).scan(context.Background())
+		if len(nc.published()) != 1 {
+			t.Errorf("expected 1 republish, got %d", len(nc.published()))
+		}
+		if lock.held || lock.released != 1 {
+			t.Errorf("expected the lock to be released once, held=%v released=%d", lock.held, lock.released)
+		}
+	})
+}
+
+func TestScanner_Scan_DryRun(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(
+		Entry{DLQID: "sc-dry", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true},
+		Entry{DLQID: "sc-dry-bad", OriginalSubject: "swarm.task.other", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true},
+	)
+	failing := NewTransformer(TransformRule{Subject: "swarm.task.other", Transform: JSONPatch(PatchOp{Op: "remove", Path: "/missing"})})
+
+	NewScanner(store, nc, time.Minute, WithScannerDryRun(), WithScannerTransformer(failing)).scan(context.Background())
+
+	if msgs := nc.published(); len(msgs) != 0 {
+		t.Errorf("expected no republish in dry run, got %d", len(msgs))
+	}
+	for _, id := range []string{"sc-dry", "sc-dry-bad"} {
+		e, _ := store.Get(context.Background(), id)
+		if e.Recovered || e.AutoRetryCount != 0 || e.NextRetryAt != nil {
+			t.Errorf("%s should be untouched in dry run: %+v", id, e)
+		}
+	}
+	var summary ScanSummaryEvent
+	_ = json.Unmarshal(nc.events(SubjectScanSummary)[0].Data, &summary)
+	if !summary.DryRun || summary.Retried != 1 || summary.Failed != 1 {
+		t.Errorf("expected dry-run summary with 1 retried and 1 failed, got %+v", summary)
+	}
+}
+
+func TestScanner_Scan_BeforeRetry(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(
+		Entry{DLQID: "sc-veto", OriginalSubject: "swarm.task.a", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true},
+		Entry{DLQID: "sc-patch", OriginalSubject: "swarm.task.b", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true},
+		Entry{DLQID: "sc-hookerr", OriginalSubject: "swarm.task.c", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true},
+	)
+	hook := func(_ context.Context, e *Entry) (bool, error) {
+		switch e.DLQID {
+		case "sc-veto":
+			return false, nil
+		case "sc-hookerr":
+			return false, fmt.Errorf("dispatch unreachable")
+		}
+		e.OriginalPayload = json.RawMessage(`{"agent":"new"}`)
+		return true, nil
+	}
+
+	NewScanner(store, nc, time.Minute, WithScannerBeforeRetry(hook)).scan(context.Background())
+
+	msgs := nc.published()
+	if len(msgs) != 1 || msgs[0].Subject != "swarm.task.b" || string(msgs[0].Data) != `{"agent":"new"}` {
+		t.Fatalf("expected only the patched entry to be republished, got %+v", msgs)
+	}
+	for _, id := range []string{"sc-veto", "sc-hookerr"} {
+		if e, _ := store.Get(context.Background(), id); e.Recovered {
+			t.Errorf("%s should stay unrecovered", id)
+		}
+	}
+	var summary ScanSummaryEvent
+	_ = json.Unmarshal(nc.events(SubjectScanSummary)[0].Data, &summary)
+	if summary.Retried != 1 || summary.Skipped != 1 || summary.Failed != 1 {
+		t.Errorf("expected 1 retried, 1 skipped, 1 failed, got %+v", summary)
+	}
+}
+
+func TestScanner_Status(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	m := NewMetrics()
+	store.seed(
+		Entry{DLQID: "sc-st-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true},
+		Entry{DLQID: "sc-st-2", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true},
+	)
+	scanner := NewScanner(store, nc, time.Minute, WithScannerMetrics(m))
+	if st := scanner.Status(); st.Runs != 0 || st.LastRun != nil {
+		t.Fatalf("expected empty status before the first pass, got %+v", st)
+	}
+
+	scanner.scan(context.Background())
+	store.listErr = fmt.Errorf("db down")
+	scanner.scan(context.Background())
+
+	st := scanner.Status()
+	if st.Runs != 2 || st.Considered != 2 || st.Retried != 2 {
+		t.Errorf("unexpected totals: %+v", st)
+	}
+	if st.LastRun == nil || st.LastRun.Error == "" {
+		t.Errorf("expected the last run to report the list error, got %+v", st.LastRun)
+	}
+	if st.LastSuccessAt == nil || st.LastSuccessAt.After(st.LastRun.StartedAt) {
+		t.Errorf("expected last_success_at from the first pass, got %v", st.LastSuccessAt)
+	}
+	if m.Get(MetricScannerRuns) != 2 || m.Get(MetricScannerRetried) != 2 {
+		t.Errorf("unexpected metrics: %v", m.Snapshot())
+	}
+
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, nc, WithScanner(scanner)).Routes())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/scanner/status", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var got ScannerStatus
+	_ = json.NewDecoder(w.Body).Decode(&got)
+	if got.Runs != 2 || got.Interval != "1m0s" {
+		t.Errorf("unexpected status response: %+v", got)
+	}
+}
+
+func TestScanner_Jitter(t *testing.T) {
+	plain := NewScanner(newMockStore(), newMockNATS(), time.Minute)
+	if plain.firstDelay() != time.Minute || plain.nextDelay() != time.Minute {
+		t.Errorf("expected fixed delays without jitter, got %v and %v", plain.firstDelay(), plain.nextDelay())
+	}
+
+	s := NewScanner(newMockStore(), newMockNATS(), time.Minute, WithScannerJitter(10*time.Second))
+	for i := 0; i < 50; i++ {
+		if d := s.firstDelay(); d < 0 || d >= time.Minute {
+			t.Fatalf("first delay %v outside [0, 1m)", d)
+		}
+		if d := s.nextDelay(); d < time.Minute || d >= time.Minute+10*time.Second {
+			t.Fatalf("next delay %v outside [1m, 1m10s)", d)
+		}
+	}
+}
+
+func TestScanner_Scan_ExpiresEntriesPastWindow(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	old := time.Now().Add(-RecoveryWindow - time.Hour)
+	store.seed(
+		Entry{DLQID: "sc-old", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true, FailedAt: old},
+		Entry{DLQID: "sc-old-exhausted", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true, FailedAt: old, Status: StatusExhausted},
+		Entry{DLQID: "sc-fresh", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, Recoverable: true, FailedAt: time.Now()},
+	)
+
+	NewScanner(store, nc, time.Minute).scan(context.Background())
+
+	e, _ := store.Get(context.Background(), "sc-old")
+	if e.Status != StatusExpired || !e.Recovered || e.ExpiredAt == nil {
+		t.Errorf("expected sc-old to be expired, got status=%s recovered=%v expired_at=%v", e.Status, e.Recovered, e.ExpiredAt)
+	}
+	if e, _ := store.Get(context.Background(), "sc-old-exhausted"); e.Status != StatusExhausted {
+		t.Errorf("exhausted entry should be left for operators, got %s", e.Status)
+	}
+	if e, _ := store.Get(context.Background(), "sc-fresh"); e.Status != StatusRecovered {
+		t.Errorf("fresh entry should be retried, got %s", e.Status)
+	}
+
+	events := nc.events(SubjectExpired)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 expired event, got %d", len(events))
+	}
+	var ev ExpiredEvent
+	_ = json.Unmarshal(events[0].Data, &ev)
+	if ev.DLQID != "sc-old" || ev.ExpiredAt.IsZero() {
+		t.Errorf("unexpected expired event: %+v", ev)
+	}
+	var summary ScanSummaryEvent
+	_ = json.Unmarshal(nc.events(SubjectScanSummary)[0].Data, &summary)
+	if summary.Expired != 1 || summary.Retried != 1 {
+		t.Errorf("expected 1 expired and 1 retried, got %+v", summary)
+	}
+	if st, _ := store.Stats(context.Background()); st.Expired != 1 {
+		t.Errorf("expected stats to count 1 expired entry, got %d", st.Expired)
+	}
+}
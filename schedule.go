@@ -0,0 +1,176 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// RecoveredByScheduledRetry is the recovered_by of entries the Scanner
+// retried because an operator scheduled it (see ScheduleRetry).
+const RecoveredByScheduledRetry = "scheduled-retry"
+
+// ScheduleRetry asks the Scanner to retry an open entry once at has passed,
+// on behalf of by. Until then automatic recovery leaves the entry alone; once
+// due it is retried even if it is not recoverable, has expired or was
+// exhausted. Scheduling clears any retry backoff. A zero at cancels the
+// scheduled retry. It fails with ErrAlreadyRecovered or a wrapped
+// pgx.ErrNoRows.
+func (s *Store) ScheduleRetry(ctx context.Context, dlqID, by string, at time.Time) (err error) {
+	defer s.observe("schedule_retry", time.Now(), &err)
+	var retryAt *time.Time
+	if !at.IsZero() {
+		retryAt = &at
+	}
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE swarm_dlq
+		SET retry_at = $2,
+		    retry_scheduled_by = CASE WHEN $2::timestamptz IS NULL THEN NULL ELSE nullif($3, '') END,
+		    next_retry_at = CASE WHEN $2::timestamptz IS NULL THEN next_retry_at END
+		WHERE dlq_id = $1 AND recovered = false AND `+tenantClause(4)+`
+	`, dlqID, retryAt, by, s.tenant(ctx))
+	if err != nil {
+		return fmt.Errorf("schedule retry: %w", err)
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+	var recovered bool
+	err = s.pool.QueryRow(ctx, `SELECT recovered FROM swarm_dlq WHERE dlq_id = $1 AND `+tenantClause(2), dlqID, s.tenant(ctx)).Scan(&recovered)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("schedule retry %s: %w", dlqID, pgx.ErrNoRows)
+	case err != nil:
+		return fmt.Errorf("schedule retry: %w", err)
+	}
+	return ErrAlreadyRecovered
+}
+
+// ListScheduledRetries returns open entries whose scheduled retry is due,
+// earliest first, skipping entries in a live retry or backing off after a
+// failed one.
+func (s *Store) ListScheduledRetries(ctx context.Context) (_ []Entry, err error) {
+	defer s.observe("list_scheduled_retries", time.Now(), &err)
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+entryColumns+`
+		FROM swarm_dlq
+		WHERE retry_at <= now()
+		  AND recovered = false
+		  AND (status <> 'retrying' OR retry_started_at <= now() - $1 * interval '1 microsecond')
+		  AND (next_retry_at IS NULL OR next_retry_at <= now())
+		  AND `+tenantClause(2)+`
+		ORDER BY retry_at
+	`, RetryLease.Microseconds(), s.tenant(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list scheduled retries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	return entries, rows.Err()
+}
+
+// retryScheduleFromQuery reads ?at= (RFC 3339) or ?delay= (a Go duration)
+// from POST /{dlqID}/retry. It returns the zero time for an immediate retry.
+func retryScheduleFromQuery(r *http.Request, now time.Time) (time.Time, error) {
+	q := r.URL.Query()
+	at, delay := q.Get("at"), q.Get("delay")
+	switch {
+	case at != "" && delay != "":
+		return time.Time{}, errors.New("use at or delay, not both")
+	case at != "":
+		t, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			return time.Time{}, errors.New("at must be an RFC 3339 timestamp")
+		}
+		return t.UTC(), nil
+	case delay != "":
+		d, err := time.ParseDuration(delay)
+		if err != nil || d <= 0 {
+			return time.Time{}, errors.New("delay must be a positive duration")
+		}
+		return now.Add(d).UTC(), nil
+	}
+	return time.Time{}, nil
+}
+
+// handleCancelRetry serves DELETE /{dlqID}/retry, which cancels a scheduled
+// retry.
+func (h *Handler) handleCancelRetry(w http.ResponseWriter, r *http.Request) {
+	h.scheduleRetry(w, r, chi.URLParam(r, "dlqID"), time.Time{})
+}
+
+// scheduleRetry records a retry of dlqID for the Scanner to perform at at,
+// or cancels it for the zero time. POST /{dlqID}/retry?at= and ?delay= end
+// up here.
+func (h *Handler) scheduleRetry(w http.ResponseWriter, r *http.Request, dlqID string, at time.Time) {
+	actor := actorFromRequest(r)
+	if e, err := h.store.Get(r.Context(), dlqID); err == nil && e.claimedByOther(actor, time.Now()) {
+		writeJSON(w, http.StatusLocked, map[string]string{"error": ErrEntryClaimed.Error(), "claimed_by": e.ClaimedBy})
+		return
+	}
+	err := h.store.ScheduleRetry(r.Context(), dlqID, actor, at)
+	switch {
+	case errors.Is(err, ErrAlreadyRecovered):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "already recovered"})
+		return
+	case errors.Is(err, pgx.ErrNoRows):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "dlq entry not found"})
+		return
+	case err != nil:
+		slog.Error("failed to schedule dlq retry", "dlq_id", dlqID, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	if at.IsZero() {
+		h.auditEntry(r, AuditScheduleRetry, dlqID, AuditResultOK, "cancelled")
+		writeJSON(w, http.StatusOK, map[string]string{"status": "unscheduled", "dlq_id": dlqID})
+		return
+	}
+	h.auditEntry(r, AuditScheduleRetry, dlqID, AuditResultOK, at.Format(time.RFC3339))
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "scheduled", "dlq_id": dlqID, "retry_at": at.Format(time.RFC3339)})
+}
+
+// dueEntries returns the scheduled retries that are due followed by the
+// recoverable entries, without duplicates.
+func (s *Scanner) dueEntries(ctx context.Context) ([]Entry, error) {
+	scheduled, err := s.store.ListScheduledRetries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recoverable, err := s.store.ListRecoverable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(scheduled))
+	for _, e := range scheduled {
+		seen[e.DLQID] = true
+	}
+	for _, e := range recoverable {
+		if !seen[e.DLQID] {
+			scheduled = append(scheduled, e)
+		}
+	}
+	return scheduled, nil
+}
+
+// scannerRecoveredBy is the recovered_by the Scanner records for entry.
+func scannerRecoveredBy(entry Entry) string {
+	if entry.RetryAt != nil {
+		return RecoveredByScheduledRetry
+	}
+	return RecoveredByScanner
+}
@@ -0,0 +1,79 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_Retry_Scheduled(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "s1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true})
+	nc := newMockNATS()
+	r := newTestRouter(store, nc)
+
+	for _, q := range []string{"delay=-5m", "delay=soon", "at=tomorrow", "at=2030-01-01T00:00:00Z&delay=1m"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", "/dlq/s1/retry?"+q, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/dlq/s1/retry?delay=10m", nil)
+	req.Header.Set(ActorHeader, "alice")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body)
+	}
+	e, _ := store.Get(context.Background(), "s1")
+	if e.RetryAt == nil || time.Until(*e.RetryAt) < 9*time.Minute || e.RetryScheduledBy != "alice" {
+		t.Fatalf("expected a retry scheduled by alice in 10m, got %v by %q", e.RetryAt, e.RetryScheduledBy)
+	}
+	NewScanner(store, nc, time.Minute).scan(context.Background())
+	if len(nc.published()) != 0 {
+		t.Fatal("expected the scanner to wait for the scheduled time")
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/dlq/s1/retry", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 cancelling, got %d", w.Code)
+	}
+	if e, _ := store.Get(context.Background(), "s1"); e.RetryAt != nil {
+		t.Error("expected the scheduled retry cancelled")
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/dlq/missing/retry?delay=1m", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestScanner_RetriesDueScheduledEntries(t *testing.T) {
+	store := newMockStore()
+	store.seed(
+		Entry{DLQID: "s2", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Status: StatusExhausted},
+		Entry{DLQID: "s3", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true},
+	)
+	ctx := context.Background()
+	_ = store.ScheduleRetry(ctx, "s2", "alice", time.Now().Add(-time.Second))
+	_ = store.ScheduleRetry(ctx, "s3", "alice", time.Now().Add(time.Hour))
+	nc := newMockNATS()
+	NewScanner(store, nc, time.Minute).scan(ctx)
+
+	if got := nc.published(); len(got) != 1 {
+		t.Fatalf("expected only the due entry retried, got %d publishes", len(got))
+	}
+	if e, _ := store.Get(ctx, "s2"); !e.Recovered || e.RecoveredBy != RecoveredByScheduledRetry {
+		t.Errorf("expected s2 recovered by %s, got %v %q", RecoveredByScheduledRetry, e.Recovered, e.RecoveredBy)
+	}
+	if e, _ := store.Get(ctx, "s3"); e.Recovered {
+		t.Error("expected s3 left until its scheduled time")
+	}
+}
@@ -0,0 +1,118 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// HighSeverityReasons are the reasons a SlackNotifier posts new entries for
+// by default: agent failures that usually need a human.
+var HighSeverityReasons = []string{ReasonCrashLoop, ReasonBootFailure, ReasonAgentCrashed}
+
+// SlackNotifier posts notifications to a Slack incoming webhook. New entries
+// are posted only for its reasons (HighSeverityReasons by default);
+// all other notifications are always posted.
+type SlackNotifier struct {
+	webhookURL string
+	reasons    []string
+	linkBase   string
+	client     *http.Client
+}
+
+// SlackOption configures a SlackNotifier.
+type SlackOption func(*SlackNotifier)
+
+// WithSlackReasons posts new entries with any of reasons instead of
+// HighSeverityReasons. With no reasons every new entry is posted.
+func WithSlackReasons(reasons ...string) SlackOption {
+	return func(s *SlackNotifier) { s.reasons = reasons }
+}
+
+// WithSlackLink links each entry's dlq_id to base + "/" + dlq_id, e.g. the
+// admin UI's entry page or the API's GET /{dlqID}.
+func WithSlackLink(base string) SlackOption {
+	return func(s *SlackNotifier) { s.linkBase = strings.TrimRight(base, "/") }
+}
+
+// WithSlackHTTPClient posts with c instead of a client with a 10 second
+// timeout.
+func WithSlackHTTPClient(c *http.Client) SlackOption {
+	return func(s *SlackNotifier) { s.client = c }
+}
+
+// NewSlackNotifier creates a notifier posting to the Slack incoming webhook
+// at webhookURL.
+func NewSlackNotifier(webhookURL string, opts ...SlackOption) *SlackNotifier {
+	s := &SlackNotifier{
+		webhookURL: webhookURL,
+		reasons:    HighSeverityReasons,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Notify implements Notifier.
+func (s *SlackNotifier) Notify(ctx context.Context, n Notification) error {
+	if n.Kind == NotifyEntryCreated && len(s.reasons) > 0 && !slices.Contains(s.reasons, n.Reason) {
+		return nil
+	}
+	body, err := json.Marshal(map[string]string{"text": s.format(n)})
+	if err != nil {
+		return fmt.Errorf("marshal slack message: %w", err)
+	}
+	if err := postJSON(ctx, s.client, s.webhookURL, nil, body); err != nil {
+		return fmt.Errorf("slack: %w", err)
+	}
+	return nil
+}
+
+// format renders n as Slack mrkdwn.
+func (s *SlackNotifier) format(n Notification) string {
+	var b strings.Builder
+	switch n.Kind {
+	case NotifyThreshold:
+		fmt.Fprintf(&b, ":warning: *DLQ backlog:* %d unrecovered entries (threshold %d)", n.Unrecovered, n.Threshold)
+		return b.String()
+	case NotifyAlert:
+		fmt.Fprintf(&b, ":rotating_light: *DLQ alert %s:* %d unrecovered entries (%s), more than %d", n.Rule, n.Unrecovered, n.Detail, n.Threshold)
+		return b.String()
+	case NotifyAlertResolved:
+		fmt.Fprintf(&b, ":white_check_mark: *DLQ alert %s resolved:* %d unrecovered entries (%s)", n.Rule, n.Unrecovered, n.Detail)
+		return b.String()
+	case NotifyExhausted:
+		fmt.Fprintf(&b, ":x: *DLQ auto-recovery gave up* after %d attempts", n.Attempts)
+	default:
+		b.WriteString(":rotating_light: *New dead letter*")
+	}
+	fmt.Fprintf(&b, "\n*dlq_id:* %s", s.link(n.DLQID))
+	fmt.Fprintf(&b, "\n*reason:* `%s`  *source:* %s", n.Reason, n.Source)
+	if n.OriginalSubject != "" {
+		fmt.Fprintf(&b, "\n*subject:* `%s`", n.OriginalSubject)
+	}
+	detail := n.Detail
+	if detail == "" {
+		detail = n.ReasonDetail
+	}
+	if detail != "" {
+		fmt.Fprintf(&b, "\n> %s", detail)
+	}
+	return b.String()
+}
+
+// link renders dlqID, as a Slack link when a link base is configured.
+func (s *SlackNotifier) link(dlqID string) string {
+	if s.linkBase == "" {
+		return "`" + dlqID + "`"
+	}
+	return "<" + s.linkBase + "/" + dlqID + "|" + dlqID + ">"
+}
+
+var _ Notifier = (*SlackNotifier)(nil)
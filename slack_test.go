@@ -0,0 +1,57 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSlackNotifier(t *testing.T) {
+	var texts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			Text string `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&msg)
+		texts = append(texts, msg.Text)
+	}))
+	defer srv.Close()
+
+	s := NewSlackNotifier(srv.URL, WithSlackLink("https://chronicle.example.com/dlq/"))
+	ctx := context.Background()
+
+	low := entryNotification(NotifyEntryCreated, Entry{DLQID: "sl-1", Reason: ReasonNoCapableAgent, Source: SourceDispatch})
+	high := entryNotification(NotifyEntryCreated, Entry{DLQID: "sl-2", Reason: ReasonCrashLoop, Source: SourceWarren, ReasonDetail: "restarted 5 times"})
+	for _, n := range []Notification{low, high, {Kind: NotifyThreshold, Unrecovered: 120, Threshold: 100}} {
+		if err := s.Notify(ctx, n); err != nil {
+			t.Fatalf("notify %s: %v", n.Kind, err)
+		}
+	}
+
+	if len(texts) != 2 {
+		t.Fatalf("expected the crash loop and threshold messages only, got %q", texts)
+	}
+	for _, want := range []string{"<https://chronicle.example.com/dlq/sl-2|sl-2>", "`crash_loop`", SourceWarren, "restarted 5 times"} {
+		if !strings.Contains(texts[0], want) {
+			t.Errorf("expected %q in %q", want, texts[0])
+		}
+	}
+	if !strings.Contains(texts[1], "120 unrecovered") {
+		t.Errorf("unexpected threshold message %q", texts[1])
+	}
+}
+
+func TestSlackNotifier_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	err := NewSlackNotifier(srv.URL, WithSlackReasons()).Notify(context.Background(), Notification{Kind: NotifyEntryCreated, DLQID: "sl-3"})
+	if err == nil {
+		t.Error("expected an error for a rejected webhook")
+	}
+}
@@ -0,0 +1,103 @@
+package dlq
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidSource is returned by RegisterSource for an empty name, a
+// subject prefix outside dlq.> or one that clashes with another source or
+// the lifecycle notification subjects.
+var ErrInvalidSource = errors.New("invalid dlq source")
+
+// sourcePrefixes maps each source to the subject prefix its events are
+// published under. Dispatch and Warren are built in; RegisterSource adds
+// more.
+var (
+	sourcesMu      sync.RWMutex
+	sourcePrefixes = map[string]string{
+		SourceDispatch: "dlq.task",
+		SourceWarren:   "dlq.agent",
+	}
+)
+
+// RegisterSource lets another component (e.g. "chronicle" or "gateway")
+// dead-letter under its own name. Events whose reason has no subject of its
+// own are published to subjectPrefix + ".unknown", and the Processor
+// attributes events under subjectPrefix to name when the publisher did not
+// set a source. Registering the same name and prefix again is a no-op.
+func RegisterSource(name, subjectPrefix string) error {
+	prefix := strings.TrimSuffix(subjectPrefix, ".")
+	switch {
+	case name == "" || strings.ContainsAny(name, " \t\n"):
+		return fmt.Errorf("%w: source name %q", ErrInvalidSource, name)
+	case !literalDLQSubject(prefix):
+		return fmt.Errorf("%w: subject prefix %q must be a literal subject under dlq.", ErrInvalidSource, subjectPrefix)
+	case eventSubjectUnder(prefix):
+		return fmt.Errorf("%w: subject prefix %q clashes with the lifecycle notifications", ErrInvalidSource, subjectPrefix)
+	}
+
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	if existing, ok := sourcePrefixes[name]; ok {
+		if existing == prefix {
+			return nil
+		}
+		return fmt.Errorf("%w: source %q is already registered with prefix %q", ErrInvalidSource, name, existing)
+	}
+	for other, p := range sourcePrefixes {
+		if p == prefix {
+			return fmt.Errorf("%w: subject prefix %q is already used by source %q", ErrInvalidSource, subjectPrefix, other)
+		}
+	}
+	sourcePrefixes[name] = prefix
+	return nil
+}
+
+// literalDLQSubject reports whether subject is a subject under dlq. without
+// wildcards or empty tokens.
+func literalDLQSubject(subject string) bool {
+	return strings.HasPrefix(subject, "dlq.") && !strings.HasSuffix(subject, ".") &&
+		!strings.ContainsAny(subject, "*> \t\n") && !strings.Contains(subject, "..")
+}
+
+// eventSubjectUnder reports whether a lifecycle notification subject is
+// prefix itself or lies under it.
+func eventSubjectUnder(prefix string) bool {
+	for _, s := range []string{
+		SubjectRecovered, SubjectEntryCreated, SubjectQuotaExceeded,
+		SubjectScanSummary, SubjectExhausted, SubjectExpired, SubjectQueryPrefix,
+	} {
+		if s == prefix || strings.HasPrefix(s, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceSubjectPrefix returns the subject prefix of source; unregistered
+// sources publish under Dispatch's.
+func sourceSubjectPrefix(source string) string {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+	if p, ok := sourcePrefixes[source]; ok {
+		return p
+	}
+	return sourcePrefixes[SourceDispatch]
+}
+
+// inferSource attributes an event to the source with the longest subject
+// prefix it falls under, or to Dispatch.
+func inferSource(subject string) string {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+	source, longest := SourceDispatch, 0
+	for name, p := range sourcePrefixes {
+		if len(p) > longest && strings.HasPrefix(subject, p+".") {
+			source, longest = name, len(p)
+		}
+	}
+	return source
+}
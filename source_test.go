@@ -0,0 +1,55 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRegisterSource(t *testing.T) {
+	if err := RegisterSource("chronicle", "dlq.chronicle."); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterSource("chronicle", "dlq.chronicle"); err != nil {
+		t.Errorf("re-registering the same prefix should be a no-op: %v", err)
+	}
+	if got := SubjectForReason("chronicle", "ingest_failed"); got != "dlq.chronicle.unknown" {
+		t.Errorf("unknown reason subject = %q", got)
+	}
+	if got := SubjectForReason("chronicle", ReasonNoCapableAgent); got != SubjectTaskUnassignable {
+		t.Errorf("known reason subject = %q", got)
+	}
+	if got := SubjectForReason("unregistered", "x"); got != "dlq.task.unknown" {
+		t.Errorf("unregistered source subject = %q", got)
+	}
+	if got := inferSource("dlq.chronicle.unknown"); got != "chronicle" {
+		t.Errorf("inferSource = %q", got)
+	}
+
+	for _, tt := range []struct{ name, prefix string }{
+		{"", "dlq.x"},
+		{"gateway", "gateway.dlq"},
+		{"gateway", "dlq.gw.>"},
+		{"gateway", "dlq.query"},
+		{"gateway", "dlq.entry"},
+		{"gateway", "dlq.chronicle"},
+		{"chronicle", "dlq.chron"},
+		{SourceWarren, "dlq.warren"},
+	} {
+		if err := RegisterSource(tt.name, tt.prefix); !errors.Is(err, ErrInvalidSource) {
+			t.Errorf("RegisterSource(%q, %q): expected ErrInvalidSource, got %v", tt.name, tt.prefix, err)
+		}
+	}
+}
+
+func TestProcessor_RegisteredSource(t *testing.T) {
+	if err := RegisterSource("gateway", "dlq.gateway"); err != nil {
+		t.Fatal(err)
+	}
+	store := newMockStore()
+	NewProcessor(store, WithProcessorAllowUnknownReasons()).Process(context.Background(), "dlq.gateway.unknown",
+		[]byte(`{"dlq_id":"src-1","original_subject":"gateway.request","reason":"upstream_502","failed_at":"2026-01-01T00:00:00Z"}`))
+	if e, _ := store.Get(context.Background(), "src-1"); e == nil || e.Source != "gateway" {
+		t.Errorf("expected source gateway, got %+v", e)
+	}
+}
@@ -3,6 +3,7 @@ package dlq
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -12,45 +13,235 @@ import (
 
 // Store handles DLQ persistence to Supabase/Postgres.
 type Store struct {
-	pool *pgxpool.Pool
+	pool        *pgxpool.Pool
+	upsert      bool
+	metrics     *Metrics
+	compression PayloadCompression
+	compressMin int
+	tenantID    string
+	blobs       BlobStore
+}
+
+// StoreOption configures a Store.
+type StoreOption func(*Store)
+
+// WithStoreUpsert makes Insert and InsertBatch refresh an existing entry with
+// the same dlq_id (retry_count, retry_history, reason_detail, last_seen_at)
+// instead of silently keeping the first version.
+func WithStoreUpsert() StoreOption {
+	return func(s *Store) { s.upsert = true }
+}
+
+// WithStoreMetrics records per-method call, error and latency counters and
+// connection pool gauges in m.
+func WithStoreMetrics(m *Metrics) StoreOption {
+	return func(s *Store) { s.metrics = m }
 }
 
 // NewStore creates a DLQ store from an existing connection pool.
-func NewStore(pool *pgxpool.Pool) *Store {
-	return &Store{pool: pool}
+func NewStore(pool *pgxpool.Pool, opts ...StoreOption) *Store {
+	s := &Store{pool: pool}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+const insertSQL = `
+	INSERT INTO swarm_dlq
+		(dlq_id, original_subject, original_payload, reason, reason_detail,
+		 failed_at, retry_count, max_retries, retry_history, source, recoverable,
+		 occurrences, payload_omitted, sample_rate,
+		 payload_truncated, payload_size, payload_ref,
+		 producer_service, producer_version, producer_host, producer_pid, metadata, status,
+		 original_headers, payload_redacted, sealed_payload, payload_compressed, payload_encoding,
+		 fingerprint, priority, task_id, tenant_id, payload_agents)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17,
+		$18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33)
+`
+
+// Both variants return (xmax = 0), which is true only for a freshly
+// inserted row. DO NOTHING returns no row at all on conflict, and neither
+// does the upsert when the existing row belongs to another tenant.
+const (
+	insertIgnoreSQL = insertSQL + `	ON CONFLICT (dlq_id) DO NOTHING
+	RETURNING (xmax = 0)`
+	insertUpsertSQL = insertSQL + `	ON CONFLICT (dlq_id) DO UPDATE SET
+		retry_count   = EXCLUDED.retry_count,
+		retry_history = EXCLUDED.retry_history,
+		reason_detail = EXCLUDED.reason_detail,
+		last_seen_at  = greatest(coalesce(swarm_dlq.last_seen_at, swarm_dlq.failed_at), EXCLUDED.failed_at)
+	WHERE swarm_dlq.tenant_id = EXCLUDED.tenant_id
+	RETURNING (xmax = 0)`
+)
+
+// observe records one call to method. Use as
+// defer s.observe("method", time.Now(), &err) with a named error result.
+func (s *Store) observe(method string, start time.Time, err *error) {
+	if s.metrics == nil {
+		return
+	}
+	prefix := "store_" + method
+	s.metrics.Inc(prefix + "_calls_total")
+	s.metrics.Add(prefix+"_duration_us_total", time.Since(start).Microseconds())
+	if *err != nil && !errors.Is(*err, pgx.ErrNoRows) {
+		s.metrics.Inc(prefix + "_errors_total")
+	}
+	s.RecordPoolStats()
+}
+
+// RecordPoolStats copies the connection pool's statistics into the store's
+// metrics. It runs after every store call; hosts may also call it before
+// exporting a Snapshot.
+func (s *Store) RecordPoolStats() {
+	if s.metrics == nil || s.pool == nil {
+		return
+	}
+	st := s.pool.Stat()
+	s.metrics.Set(MetricStorePoolAcquiredConns, int64(st.AcquiredConns()))
+	s.metrics.Set(MetricStorePoolIdleConns, int64(st.IdleConns()))
+	s.metrics.Set(MetricStorePoolTotalConns, int64(st.TotalConns()))
+	s.metrics.Set(MetricStorePoolMaxConns, int64(st.MaxConns()))
+	s.metrics.Set(MetricStorePoolAcquireCount, st.AcquireCount())
+	s.metrics.Set(MetricStorePoolEmptyAcquires, st.EmptyAcquireCount())
+	s.metrics.Set(MetricStorePoolAcquireWaitUS, st.AcquireDuration().Microseconds())
+}
+
+func (s *Store) insertQuery() string {
+	if s.upsert {
+		return insertUpsertSQL
+	}
+	return insertIgnoreSQL
 }
 
-// Insert writes a DLQ entry to the swarm_dlq table.
-func (s *Store) Insert(ctx context.Context, e Entry) error {
+func (s *Store) insertArgs(ctx context.Context, e Entry) ([]any, error) {
 	retryJSON, err := json.Marshal(e.RetryHistory)
 	if err != nil {
 		retryJSON = []byte("[]")
 	}
-
-	_, err = s.pool.Exec(ctx, `
-		INSERT INTO swarm_dlq
-			(dlq_id, original_subject, original_payload, reason, reason_detail,
-			 failed_at, retry_count, max_retries, retry_history, source, recoverable)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		ON CONFLICT (dlq_id) DO NOTHING
-	`,
-		e.DLQID, e.OriginalSubject, e.OriginalPayload, e.Reason, e.ReasonDetail,
+	payload, compressed, encoding, err := s.encodePayload(e.OriginalPayload)
+	if err != nil {
+		return nil, err
+	}
+	var agents []string
+	if compressed != nil {
+		agents = payloadAgents(e.OriginalPayload)
+	}
+	var fingerprint *string
+	if e.Fingerprint != "" {
+		fingerprint = &e.Fingerprint
+	}
+	var taskID *string
+	if e.TaskID != "" {
+		taskID = &e.TaskID
+	}
+	occurrences := e.Occurrences
+	if occurrences < 1 {
+		occurrences = 1
+	}
+	return []any{
+		e.DLQID, e.OriginalSubject, payload, e.Reason, e.ReasonDetail,
 		e.FailedAt, e.RetryCount, e.MaxRetries, retryJSON, e.Source, e.Recoverable,
-	)
+		occurrences, e.PayloadOmitted, e.SampleRate,
+		e.PayloadTruncated, e.PayloadSize, e.PayloadRef,
+		e.ProducerService, e.ProducerVersion, e.ProducerHost, e.ProducerPID, e.Metadata,
+		initialStatus(e), e.OriginalHeaders, e.PayloadRedacted, e.SealedPayload,
+		compressed, encoding, fingerprint, e.Priority, taskID, entryTenant(e, s.tenant(ctx)),
+		agents,
+	}, nil
+}
+
+// entryTenant is the tenant an entry is ingested under: its own TenantID, or
+// tenant when it has none.
+func entryTenant(e Entry, tenant string) string {
+	if e.TenantID == "" {
+		return tenant
+	}
+	return e.TenantID
+}
+
+// initialStatus is the status an entry is ingested with: pending unless the
+// caller set one.
+func initialStatus(e Entry) string {
+	if e.Status == "" {
+		return StatusPending
+	}
+	return e.Status
+}
+
+// Insert writes a DLQ entry to the swarm_dlq table. created is false when an
+// entry with the same dlq_id already existed, in which case the row was left
+// alone (or refreshed, with WithStoreUpsert).
+func (s *Store) Insert(ctx context.Context, e Entry) (created bool, err error) {
+	defer s.observe("insert", time.Now(), &err)
+	args, err := s.insertArgs(ctx, e)
 	if err != nil {
-		return fmt.Errorf("insert dlq entry: %w", err)
+		return false, fmt.Errorf("insert dlq entry: %w", err)
 	}
-	return nil
+	created, err = scanCreated(s.pool.QueryRow(ctx, s.insertQuery(), args...))
+	if err != nil {
+		return false, fmt.Errorf("insert dlq entry: %w", err)
+	}
+	return created, nil
+}
+
+// InsertBatch writes several DLQ entries in a single transaction and reports,
+// per entry, whether it was newly created.
+// Either all entries are written or none are; callers that need per-entry
+// error reporting should fall back to Insert when it fails.
+func (s *Store) InsertBatch(ctx context.Context, entries []Entry) (created []bool, err error) {
+	defer s.observe("insert_batch", time.Now(), &err)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("insert dlq batch: begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	batch := &pgx.Batch{}
+	for _, e := range entries {
+		args, err := s.insertArgs(ctx, e)
+		if err != nil {
+			return nil, fmt.Errorf("insert dlq batch: %w", err)
+		}
+		batch.Queue(s.insertQuery(), args...)
+	}
+	br := tx.SendBatch(ctx, batch)
+	created = make([]bool, len(entries))
+	for i := range entries {
+		if created[i], err = scanCreated(br.QueryRow()); err != nil {
+			_ = br.Close()
+			return nil, fmt.Errorf("insert dlq batch: %w", err)
+		}
+	}
+	if err := br.Close(); err != nil {
+		return nil, fmt.Errorf("insert dlq batch: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("insert dlq batch: commit: %w", err)
+	}
+	return created, nil
+}
+
+// scanCreated reads the RETURNING (xmax = 0) column of an insert.
+func scanCreated(row pgx.Row) (bool, error) {
+	var created bool
+	if err := row.Scan(&created); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return created, nil
 }
 
 // Get retrieves a single DLQ entry by ID.
-func (s *Store) Get(ctx context.Context, dlqID string) (*Entry, error) {
-	row := s.pool.QueryRow(ctx, `
-		SELECT dlq_id, original_subject, original_payload, reason, reason_detail,
-		       failed_at, retry_count, max_retries, retry_history, source,
-		       recoverable, recovered, recovered_at, recovered_by
-		FROM swarm_dlq WHERE dlq_id = $1
-	`, dlqID)
+func (s *Store) Get(ctx context.Context, dlqID string) (_ *Entry, err error) {
+	defer s.observe("get", time.Now(), &err)
+	row := s.pool.QueryRow(ctx, `SELECT `+entryColumns+` FROM swarm_dlq WHERE dlq_id = $1 AND `+tenantClause(2), dlqID, s.tenant(ctx))
 	return scanEntry(row)
 }
 
@@ -59,15 +250,70 @@ type ListOpts struct {
 	Recovered *bool
 	Reason    string
 	Source    string
-	Limit     int
+	// Status matches one of the Status* lifecycle values.
+	Status string
+	// Agent matches entries whose retry_history or payload ("agent" or
+	// "agent_id") references the given agent.
+	Agent string
+	// Fingerprint matches entries that are repeats of the same failure.
+	Fingerprint string
+	// AssignedTo matches entries assigned to this engineer.
+	AssignedTo string
+	// TaskID matches entries for this task (see Entry.TaskID).
+	TaskID string
+	// ProducerService, ProducerVersion and ProducerHost match the
+	// publisher provenance fields exactly.
+	ProducerService string
+	ProducerVersion string
+	ProducerHost    string
+	// FailedAfter matches entries that failed after this time.
+	FailedAfter time.Time
+	Limit       int
+	// Sort is ListSortFailedAt (the default) or ListSortPriority. Export
+	// ignores it.
+	Sort string
 }
 
 // List returns DLQ entries matching the given filters.
-func (s *Store) List(ctx context.Context, opts ListOpts) ([]Entry, error) {
-	q := `SELECT dlq_id, original_subject, original_payload, reason, reason_detail,
-	             failed_at, retry_count, max_retries, retry_history, source,
-	             recoverable, recovered, recovered_at, recovered_by
-	      FROM swarm_dlq WHERE 1=1`
+func (s *Store) List(ctx context.Context, opts ListOpts) (_ []Entry, err error) {
+	defer s.observe("list", time.Now(), &err)
+	where, args := s.tenantFilter(ctx, opts)
+	q := `SELECT ` + entryColumns + ` FROM swarm_dlq WHERE 1=1` + where +
+		listOrder(opts.Sort) + fmt.Sprintf(` LIMIT $%d`, len(args)+1)
+	args = append(args, listLimit(opts))
+
+	rows, err := s.pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list dlq: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	return entries, rows.Err()
+}
+
+// Count returns how many entries match the filters in opts. Limit is
+// ignored.
+func (s *Store) Count(ctx context.Context, opts ListOpts) (n int, err error) {
+	defer s.observe("count", time.Now(), &err)
+	where, args := s.tenantFilter(ctx, opts)
+	if err := s.pool.QueryRow(ctx, `SELECT count(*) FROM swarm_dlq WHERE 1=1`+where, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count dlq: %w", err)
+	}
+	return n, nil
+}
+
+// listFilter builds the AND clauses and arguments for opts, numbering
+// placeholders from $1.
+func listFilter(opts ListOpts) (string, []any) {
+	var q string
 	args := []any{}
 	n := 1
 
@@ -76,6 +322,11 @@ func (s *Store) List(ctx context.Context, opts ListOpts) ([]Entry, error) {
 		args = append(args, *opts.Recovered)
 		n++
 	}
+	if opts.Status != "" {
+		q += fmt.Sprintf(` AND status = $%d`, n)
+		args = append(args, opts.Status)
+		n++
+	}
 	if opts.Reason != "" {
 		q += fmt.Sprintf(` AND reason = $%d`, n)
 		args = append(args, opts.Reason)
@@ -86,42 +337,95 @@ func (s *Store) List(ctx context.Context, opts ListOpts) ([]Entry, error) {
 		args = append(args, opts.Source)
 		n++
 	}
+	if opts.Agent != "" {
+		q += fmt.Sprintf(` AND (retry_history @> jsonb_build_array(jsonb_build_object('agent', $%d::text))
+			OR original_payload->>'agent' = $%d OR original_payload->>'agent_id' = $%d
+			OR $%d = ANY(payload_agents))`, n, n, n, n)
+		args = append(args, opts.Agent)
+		n++
+	}
+	for _, f := range []struct{ col, val string }{
+		{"producer_service", opts.ProducerService},
+		{"producer_version", opts.ProducerVersion},
+		{"producer_host", opts.ProducerHost},
+		{"fingerprint", opts.Fingerprint},
+		{"assigned_to", opts.AssignedTo},
+		{"task_id", opts.TaskID},
+	} {
+		if f.val != "" {
+			q += fmt.Sprintf(` AND %s = $%d`, f.col, n)
+			args = append(args, f.val)
+			n++
+		}
+	}
+	if !opts.FailedAfter.IsZero() {
+		q += fmt.Sprintf(` AND failed_at > $%d`, n)
+		args = append(args, opts.FailedAfter)
+	}
+	return q, args
+}
 
-	q += ` ORDER BY failed_at DESC`
-
-	limit := opts.Limit
-	if limit <= 0 {
-		limit = 50
+func listLimit(opts ListOpts) int {
+	if opts.Limit <= 0 {
+		return 50
 	}
-	q += fmt.Sprintf(` LIMIT $%d`, n)
-	args = append(args, limit)
+	return opts.Limit
+}
 
-	rows, err := s.pool.Query(ctx, q, args...)
+// MarkRecovered marks a DLQ entry as recovered.
+func (s *Store) MarkRecovered(ctx context.Context, dlqID, recoveredBy string) (err error) {
+	defer s.observe("mark_recovered", time.Now(), &err)
+	return s.markRecovered(ctx, dlqID, recoveredBy, 0)
+}
+
+// markRecovered marks dlqID recovered if it is at version, or at any
+// version when version is 0.
+func (s *Store) markRecovered(ctx context.Context, dlqID, recoveredBy string, version int) error {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE swarm_dlq
+		SET recovered = true, recovered_at = now(), recovered_by = $2, status = 'recovered',
+		    retry_started_at = NULL, status_before_retry = NULL
+		WHERE dlq_id = $1 AND recovered = false AND ($3 = 0 OR version = $3) AND `+tenantClause(4)+`
+	`, dlqID, recoveredBy, version, s.tenant(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("list dlq: %w", err)
+		return fmt.Errorf("mark recovered: %w", err)
 	}
-	defer rows.Close()
-
-	var entries []Entry
-	for rows.Next() {
-		e, err := scanEntryFromRows(rows)
-		if err != nil {
-			return nil, err
+	if tag.RowsAffected() == 0 {
+		if version != 0 {
+			return s.versionConflict(ctx, dlqID, version)
 		}
-		entries = append(entries, *e)
+		return fmt.Errorf("dlq entry %s not found or already recovered", dlqID)
 	}
-	return entries, rows.Err()
+	return nil
 }
 
-// MarkRecovered marks a DLQ entry as recovered.
-func (s *Store) MarkRecovered(ctx context.Context, dlqID, recoveredBy string) error {
+// RecordAutoRetryFailure counts a failed automatic retry of an entry and
+// keeps it out of ListRecoverable until nextRetryAt.
+func (s *Store) RecordAutoRetryFailure(ctx context.Context, dlqID string, nextRetryAt time.Time) (err error) {
+	defer s.observe("record_auto_retry_failure", time.Now(), &err)
+	_, err = s.pool.Exec(ctx, `
+		UPDATE swarm_dlq
+		SET auto_retry_count = auto_retry_count + 1, next_retry_at = $2
+		WHERE dlq_id = $1 AND `+tenantClause(3)+`
+	`, dlqID, nextRetryAt, s.tenant(ctx))
+	if err != nil {
+		return fmt.Errorf("record auto retry failure: %w", err)
+	}
+	return nil
+}
+
+// MarkExhausted counts a final failed automatic retry of an entry and moves
+// it to StatusExhausted, so ListRecoverable no longer returns it. The entry
+// stays unrecovered for an operator to retry or discard.
+func (s *Store) MarkExhausted(ctx context.Context, dlqID string) (err error) {
+	defer s.observe("mark_exhausted", time.Now(), &err)
 	tag, err := s.pool.Exec(ctx, `
 		UPDATE swarm_dlq
-		SET recovered = true, recovered_at = now(), recovered_by = $2
-		WHERE dlq_id = $1 AND recovered = false
-	`, dlqID, recoveredBy)
+		SET auto_retry_count = auto_retry_count + 1, next_retry_at = NULL, status = 'exhausted', retry_at = NULL
+		WHERE dlq_id = $1 AND recovered = false AND `+tenantClause(2)+`
+	`, dlqID, s.tenant(ctx))
 	if err != nil {
-		return fmt.Errorf("mark recovered: %w", err)
+		return fmt.Errorf("mark exhausted: %w", err)
 	}
 	if tag.RowsAffected() == 0 {
 		return fmt.Errorf("dlq entry %s not found or already recovered", dlqID)
@@ -129,19 +433,147 @@ func (s *Store) MarkRecovered(ctx context.Context, dlqID, recoveredBy string) er
 	return nil
 }
 
-// ListRecoverable returns entries eligible for auto-recovery
-// (recoverable, not recovered, failed within the last 24 hours).
-func (s *Store) ListRecoverable(ctx context.Context) ([]Entry, error) {
+// DiscardOpts records why an entry was discarded. A non-zero Version makes
+// the discard conditional on the entry still being at that version. Actor
+// is the operator discarding: the discard fails with ErrEntryClaimed while
+// anyone else holds an unexpired claim on the entry.
+type DiscardOpts struct {
+	Reason  string `json:"reason"`
+	Note    string `json:"note"`
+	Version int    `json:"version,omitempty"`
+	Actor   string `json:"-"`
+}
+
+// MarkDiscarded marks a DLQ entry as handled without retrying it, recording
+// who discarded it and why in discarded_at/discarded_by. recovered is set so
+// the entry leaves the open queue, but recovered_at and recovered_by stay
+// empty.
+func (s *Store) MarkDiscarded(ctx context.Context, dlqID, discardedBy string, opts DiscardOpts) (err error) {
+	defer s.observe("mark_discarded", time.Now(), &err)
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE swarm_dlq
+		SET recovered = true, discarded_at = now(), discarded_by = $2, status = 'discarded',
+		    discard_reason = nullif($3, ''), discard_note = nullif($4, '')
+		WHERE dlq_id = $1 AND recovered = false AND ($5 = 0 OR version = $5) AND `+tenantClause(6)+`
+		  AND (claimed_by IS NULL OR claimed_by = $7 OR claim_expires_at <= now())
+	`, dlqID, discardedBy, opts.Reason, opts.Note, opts.Version, s.tenant(ctx), opts.Actor)
+	if err != nil {
+		return fmt.Errorf("mark discarded: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return s.discardConflict(ctx, dlqID, opts)
+	}
+	return nil
+}
+
+// discardConflict explains why MarkDiscarded matched no row.
+func (s *Store) discardConflict(ctx context.Context, dlqID string, opts DiscardOpts) error {
+	var recovered, claimed bool
+	var current int
+	err := s.pool.QueryRow(ctx, `
+		SELECT recovered, version,
+		       coalesce(claimed_by <> $2 AND claim_expires_at > now(), false)
+		FROM swarm_dlq WHERE dlq_id = $1 AND `+tenantClause(3),
+		dlqID, opts.Actor, s.tenant(ctx)).Scan(&recovered, &current, &claimed)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("dlq entry %s: %w", dlqID, pgx.ErrNoRows)
+	case err != nil:
+		return fmt.Errorf("mark discarded: %w", err)
+	case opts.Version != 0 && current != opts.Version:
+		return &VersionConflictError{DLQID: dlqID, Expected: opts.Version, Current: current}
+	case recovered:
+		return ErrAlreadyRecovered
+	case claimed:
+		return ErrEntryClaimed
+	}
+	return fmt.Errorf("dlq entry %s not discarded", dlqID)
+}
+
+// RecordOccurrences folds n further occurrences of an entry into its row,
+// advancing last_seen_at and appending any new payload samples.
+func (s *Store) RecordOccurrences(ctx context.Context, dlqID string, n int, lastSeen time.Time, samples []json.RawMessage) (err error) {
+	defer s.observe("record_occurrences", time.Now(), &err)
+	if samples == nil {
+		samples = []json.RawMessage{}
+	}
+	samplesJSON, err := json.Marshal(samples)
+	if err != nil {
+		return fmt.Errorf("record occurrences: marshal samples: %w", err)
+	}
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE swarm_dlq
+		SET occurrences     = occurrences + $2,
+		    last_seen_at    = greatest(coalesce(last_seen_at, failed_at), $3),
+		    payload_samples = coalesce(payload_samples, '[]'::jsonb) || $4::jsonb
+		WHERE dlq_id = $1 AND `+tenantClause(5)+`
+	`, dlqID, n, lastSeen, samplesJSON, s.tenant(ctx))
+	if err != nil {
+		return fmt.Errorf("record occurrences: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("dlq entry %s not found", dlqID)
+	}
+	return nil
+}
+
+// ExpireRecoverable moves up to limit recoverable, unrecovered entries that
+// failed (or, if reopened, were reopened) before failedBefore to
+// StatusExpired and returns them. Exhausted entries are left for an
+// operator.
+func (s *Store) ExpireRecoverable(ctx context.Context, failedBefore time.Time, limit int) (_ []Entry, err error) {
+	defer s.observe("expire_recoverable", time.Now(), &err)
 	rows, err := s.pool.Query(ctx, `
-		SELECT dlq_id, original_subject, original_payload, reason, reason_detail,
-		       failed_at, retry_count, max_retries, retry_history, source,
-		       recoverable, recovered, recovered_at, recovered_by
+		UPDATE swarm_dlq
+		SET recovered = true, status = 'expired', expired_at = now(), next_retry_at = NULL
+		WHERE dlq_id IN (
+			SELECT dlq_id FROM swarm_dlq
+			WHERE recoverable = true
+			  AND recovered = false
+			  AND status <> 'exhausted'
+			  AND (status <> 'retrying' OR retry_started_at <= now() - $3 * interval '1 microsecond')
+			  AND coalesce(reopened_at, failed_at) < $1
+			  AND `+tenantClause(4)+`
+			ORDER BY failed_at ASC
+			LIMIT $2
+		)
+		RETURNING `+entryColumns+`
+	`, failedBefore, limit, RetryLease.Microseconds(), s.tenant(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("expire recoverable: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	return entries, rows.Err()
+}
+
+// ListRecoverable returns entries eligible for auto-recovery (recoverable,
+// not recovered or exhausted, failed or reopened within RecoveryWindow, not
+// backing off after a failed auto-retry, and not scheduled for a later
+// retry).
+func (s *Store) ListRecoverable(ctx context.Context) (_ []Entry, err error) {
+	defer s.observe("list_recoverable", time.Now(), &err)
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+entryColumns+`
 		FROM swarm_dlq
 		WHERE recoverable = true
 		  AND recovered = false
-		  AND failed_at > now() - interval '24 hours'
-		ORDER BY failed_at ASC
-	`)
+		  AND status <> 'exhausted'
+		  AND (status <> 'retrying' OR retry_started_at <= now() - $1 * interval '1 microsecond')
+		  AND coalesce(reopened_at, failed_at) > now() - $2 * interval '1 second'
+		  AND (next_retry_at IS NULL OR next_retry_at <= now())
+		  AND (retry_at IS NULL OR retry_at <= now())
+		  AND `+tenantClause(3)+`
+		ORDER BY priority DESC, failed_at ASC
+	`, RetryLease.Microseconds(), RecoveryWindow.Seconds(), s.tenant(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("list recoverable: %w", err)
 	}
@@ -149,7 +581,7 @@ func (s *Store) ListRecoverable(ctx context.Context) ([]Entry, error) {
 
 	var entries []Entry
 	for rows.Next() {
-		e, err := scanEntryFromRows(rows)
+		e, err := scanEntry(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -165,19 +597,55 @@ type Stats struct {
 	Recoverable int            `json:"recoverable"`
 	ByReason    map[string]int `json:"by_reason"`
 	BySource    map[string]int `json:"by_source"`
+	// Expired counts entries that left the recovery window unrecovered.
+	Expired int `json:"expired"`
+	// ByStatus counts all entries, recovered or not, by lifecycle status.
+	ByStatus map[string]int `json:"by_status"`
+	// BySubject counts unrecovered entries by original subject, for the
+	// StatsTopSubjects subjects with the most entries.
+	BySubject map[string]int `json:"by_subject"`
+
+	// OldestUnrecoveredAge is how long, in seconds, the oldest unrecovered
+	// entry has been in the DLQ (0 when there is none).
+	OldestUnrecoveredAge float64 `json:"oldest_unrecovered_age"`
+	// RecoveredLast24h counts entries recovered in the last RecoveryWindow
+	// (24 hours).
+	RecoveredLast24h int `json:"recovered_last_24h"`
+	// TimeToRecovery covers entries recovered within StatsRecoveryWindow.
+	TimeToRecovery TimeToRecovery `json:"time_to_recovery"`
+}
+
+// TimeToRecovery summarises how long recovered entries stayed in the DLQ,
+// in seconds from failed_at to recovered_at. Percentiles are interpolated.
+type TimeToRecovery struct {
+	Count int     `json:"count"`
+	Avg   float64 `json:"avg"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
 }
 
-func (s *Store) Stats(ctx context.Context) (*Stats, error) {
+// StatsTopSubjects is how many original subjects Stats.BySubject reports.
+const StatsTopSubjects = 20
+
+// StatsRecoveryWindow is how far back Stats.TimeToRecovery looks.
+const StatsRecoveryWindow = 30 * 24 * time.Hour
+
+func (s *Store) Stats(ctx context.Context) (_ *Stats, err error) {
+	defer s.observe("stats", time.Now(), &err)
 	st := &Stats{
-		ByReason: make(map[string]int),
-		BySource: make(map[string]int),
+		ByReason:  make(map[string]int),
+		BySource:  make(map[string]int),
+		ByStatus:  make(map[string]int),
+		BySubject: make(map[string]int),
 	}
 
-	_ = s.pool.QueryRow(ctx, `SELECT count(*) FROM swarm_dlq`).Scan(&st.Total)
-	_ = s.pool.QueryRow(ctx, `SELECT count(*) FROM swarm_dlq WHERE recovered = false`).Scan(&st.Unrecovered)
-	_ = s.pool.QueryRow(ctx, `SELECT count(*) FROM swarm_dlq WHERE recoverable = true AND recovered = false`).Scan(&st.Recoverable)
+	tenant := s.tenant(ctx)
+	_ = s.pool.QueryRow(ctx, `SELECT count(*) FROM swarm_dlq WHERE `+tenantClause(1), tenant).Scan(&st.Total)
+	_ = s.pool.QueryRow(ctx, `SELECT count(*) FROM swarm_dlq WHERE recovered = false AND `+tenantClause(1), tenant).Scan(&st.Unrecovered)
+	_ = s.pool.QueryRow(ctx, `SELECT count(*) FROM swarm_dlq WHERE recoverable = true AND recovered = false AND `+tenantClause(1), tenant).Scan(&st.Recoverable)
 
-	rows, err := s.pool.Query(ctx, `SELECT reason, count(*) FROM swarm_dlq WHERE recovered = false GROUP BY reason`)
+	rows, err := s.pool.Query(ctx, `SELECT reason, count(*) FROM swarm_dlq WHERE recovered = false AND `+tenantClause(1)+` GROUP BY reason`, tenant)
 	if err == nil {
 		defer rows.Close()
 		for rows.Next() {
@@ -190,7 +658,7 @@ func (s *Store) Stats(ctx context.Context) (*Stats, error) {
 		}
 	}
 
-	rows2, err := s.pool.Query(ctx, `SELECT source, count(*) FROM swarm_dlq WHERE recovered = false GROUP BY source`)
+	rows2, err := s.pool.Query(ctx, `SELECT source, count(*) FROM swarm_dlq WHERE recovered = false AND `+tenantClause(1)+` GROUP BY source`, tenant)
 	if err == nil {
 		defer rows2.Close()
 		for rows2.Next() {
@@ -203,69 +671,249 @@ func (s *Store) Stats(ctx context.Context) (*Stats, error) {
 		}
 	}
 
+	rows3, err := s.pool.Query(ctx, `SELECT status, count(*) FROM swarm_dlq WHERE `+tenantClause(1)+` GROUP BY status`, tenant)
+	if err == nil {
+		defer rows3.Close()
+		for rows3.Next() {
+			var status string
+			var count int
+			if err := rows3.Scan(&status, &count); err != nil {
+				continue
+			}
+			st.ByStatus[status] = count
+		}
+	}
+	st.Expired = st.ByStatus[StatusExpired]
+
+	now := time.Now().UTC()
+	ttr := &st.TimeToRecovery
+	_ = s.pool.QueryRow(ctx, `
+		SELECT
+			coalesce(extract(epoch FROM $1::timestamptz - min(failed_at) FILTER (WHERE recovered = false)), 0)::float8,
+			count(*) FILTER (WHERE recovered_at >= $1::timestamptz - $2 * interval '1 second'),
+			count(ttr),
+			coalesce(avg(ttr), 0),
+			coalesce(percentile_cont(0.5) WITHIN GROUP (ORDER BY ttr), 0),
+			coalesce(percentile_cont(0.9) WITHIN GROUP (ORDER BY ttr), 0),
+			coalesce(percentile_cont(0.99) WITHIN GROUP (ORDER BY ttr), 0)
+		FROM (
+			SELECT failed_at, recovered, recovered_at,
+				CASE WHEN recovered_at >= $3 THEN extract(epoch FROM recovered_at - failed_at)::float8 END AS ttr
+			FROM swarm_dlq
+			WHERE `+tenantClause(4)+`
+		) d`, now, RecoveryWindow.Seconds(), now.Add(-StatsRecoveryWindow), tenant,
+	).Scan(&st.OldestUnrecoveredAge, &st.RecoveredLast24h, &ttr.Count, &ttr.Avg, &ttr.P50, &ttr.P90, &ttr.P99)
+
+	rows4, err := s.pool.Query(ctx, `
+		SELECT original_subject, count(*) FROM swarm_dlq WHERE recovered = false AND `+tenantClause(2)+`
+		GROUP BY original_subject ORDER BY 2 DESC, 1 LIMIT $1`, StatsTopSubjects, tenant)
+	if err == nil {
+		defer rows4.Close()
+		for rows4.Next() {
+			var subject string
+			var count int
+			if err := rows4.Scan(&subject, &count); err != nil {
+				continue
+			}
+			st.BySubject[subject] = count
+		}
+	}
+
 	return st, nil
 }
 
-func scanEntry(row pgx.Row) (*Entry, error) {
-	var (
-		e            Entry
-		retryJSON    json.RawMessage
-		reasonDetail *string
-		recoveredAt  *time.Time
-		recoveredBy  *string
-	)
-	err := row.Scan(
-		&e.DLQID, &e.OriginalSubject, &e.OriginalPayload, &e.Reason, &reasonDetail,
-		&e.FailedAt, &e.RetryCount, &e.MaxRetries, &retryJSON, &e.Source,
-		&e.Recoverable, &e.Recovered, &recoveredAt, &recoveredBy,
-	)
-	if err != nil {
-		return nil, err
+// AgentFailureStats counts how often an agent appears in the retry history
+// of unrecovered entries.
+type AgentFailureStats struct {
+	Agent         string     `json:"agent"`
+	Failures      int        `json:"failures"`
+	Entries       int        `json:"entries"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+}
+
+// AgentStats returns the agents that appear most often as failed attempts in
+// the retry_history of unrecovered entries, most failures first.
+func (s *Store) AgentStats(ctx context.Context, limit int) (_ []AgentFailureStats, err error) {
+	defer s.observe("agent_stats", time.Now(), &err)
+	if limit <= 0 {
+		limit = 20
 	}
-	if reasonDetail != nil {
-		e.ReasonDetail = *reasonDetail
+	rows, err := s.pool.Query(ctx, `
+		SELECT a->>'agent', count(*), count(DISTINCT d.dlq_id), max((a->>'attempted_at')::timestamptz)
+		FROM swarm_dlq d, jsonb_array_elements(coalesce(d.retry_history, '[]'::jsonb)) a
+		WHERE d.recovered = false AND coalesce(a->>'agent', '') <> '' AND `+tenantClause(2)+`
+		GROUP BY 1
+		ORDER BY 2 DESC, 1
+		LIMIT $1
+	`, limit, s.tenant(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("agent stats: %w", err)
 	}
-	if recoveredAt != nil {
-		e.RecoveredAt = recoveredAt
+	defer rows.Close()
+
+	out := []AgentFailureStats{}
+	for rows.Next() {
+		var a AgentFailureStats
+		if err := rows.Scan(&a.Agent, &a.Failures, &a.Entries, &a.LastFailureAt); err != nil {
+			return nil, fmt.Errorf("agent stats: %w", err)
+		}
+		out = append(out, a)
 	}
-	if recoveredBy != nil {
-		e.RecoveredBy = *recoveredBy
+	return out, rows.Err()
+}
+
+// FailureReasonStats counts retry attempts of unrecovered entries by
+// failure_reason.
+func (s *Store) FailureReasonStats(ctx context.Context) (_ map[string]int, err error) {
+	defer s.observe("failure_reason_stats", time.Now(), &err)
+	rows, err := s.pool.Query(ctx, `
+		SELECT a->>'failure_reason', count(*)
+		FROM swarm_dlq d, jsonb_array_elements(coalesce(d.retry_history, '[]'::jsonb)) a
+		WHERE d.recovered = false AND coalesce(a->>'failure_reason', '') <> '' AND `+tenantClause(1)+`
+		GROUP BY 1
+	`, s.tenant(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failure reason stats: %w", err)
 	}
-	_ = json.Unmarshal(retryJSON, &e.RetryHistory)
-	if e.RetryHistory == nil {
-		e.RetryHistory = []RetryAttempt{}
+	defer rows.Close()
+
+	out := make(map[string]int)
+	for rows.Next() {
+		var reason string
+		var count int
+		if err := rows.Scan(&reason, &count); err != nil {
+			return nil, fmt.Errorf("failure reason stats: %w", err)
+		}
+		out[reason] = count
 	}
-	return &e, nil
+	return out, rows.Err()
 }
 
-func scanEntryFromRows(rows pgx.Rows) (*Entry, error) {
+// entryColumns is the column list scanEntry expects, in order.
+const entryColumns = `dlq_id, original_subject, original_payload, reason, reason_detail,
+	failed_at, retry_count, max_retries, retry_history, source,
+	recoverable, recovered, recovered_at, recovered_by,
+	occurrences, last_seen_at, payload_samples,
+	payload_omitted, sample_rate, discard_reason, discard_note,
+	payload_truncated, payload_size, payload_ref,
+	claimed_by, claim_expires_at, retry_ack,
+	producer_service, producer_version, producer_host, producer_pid, metadata,
+	payload_erased, status, discarded_at, discarded_by, original_headers,
+	auto_retry_count, next_retry_at, expired_at,
+	payload_redacted, sealed_payload, payload_compressed, payload_encoding, fingerprint,
+	retry_started_at, version, updated_at, notes, priority, assigned_to,
+	task_id, retry_at, retry_scheduled_by, reopened_at, reopened_by, tenant_id`
+
+// scanEntry scans one row selected with entryColumns, followed by any extra
+// columns into extra. pgx.Rows satisfies pgx.Row, so this serves both
+// QueryRow and Query callers.
+func scanEntry(row pgx.Row, extra ...any) (*Entry, error) {
 	var (
-		e            Entry
-		retryJSON    json.RawMessage
-		reasonDetail *string
-		recoveredAt  *time.Time
-		recoveredBy  *string
+		e             Entry
+		retryJSON     json.RawMessage
+		samplesJSON   json.RawMessage
+		reasonDetail  *string
+		recoveredAt   *time.Time
+		recoveredBy   *string
+		discardedBy   *string
+		discardReason *string
+		discardNote   *string
+		payloadRef    *string
+		claimedBy     *string
+		producerSvc   *string
+		producerVer   *string
+		producerHost  *string
+		compressed    []byte
+		encoding      *string
+		fingerprint   *string
+		notes         *string
+		assignedTo    *string
+		taskID        *string
+		scheduledBy   *string
+		reopenedBy    *string
 	)
-	err := rows.Scan(
+	dest := []any{
 		&e.DLQID, &e.OriginalSubject, &e.OriginalPayload, &e.Reason, &reasonDetail,
 		&e.FailedAt, &e.RetryCount, &e.MaxRetries, &retryJSON, &e.Source,
 		&e.Recoverable, &e.Recovered, &recoveredAt, &recoveredBy,
-	)
+		&e.Occurrences, &e.LastSeenAt, &samplesJSON,
+		&e.PayloadOmitted, &e.SampleRate, &discardReason, &discardNote,
+		&e.PayloadTruncated, &e.PayloadSize, &payloadRef,
+		&claimedBy, &e.ClaimExpiresAt, &e.RetryAck,
+		&producerSvc, &producerVer, &producerHost, &e.ProducerPID, &e.Metadata,
+		&e.PayloadErased, &e.Status, &e.DiscardedAt, &discardedBy, &e.OriginalHeaders,
+		&e.AutoRetryCount, &e.NextRetryAt, &e.ExpiredAt,
+		&e.PayloadRedacted, &e.SealedPayload, &compressed, &encoding,
+		&fingerprint, &e.RetryStartedAt, &e.Version, &e.UpdatedAt, &notes,
+		&e.Priority, &assignedTo, &taskID, &e.RetryAt, &scheduledBy,
+		&e.ReopenedAt, &reopenedBy, &e.TenantID,
+	}
+	err := row.Scan(append(dest, extra...)...)
 	if err != nil {
 		return nil, err
 	}
+	if encoding != nil {
+		if e.OriginalPayload, err = decompressPayload(*encoding, compressed); err != nil {
+			return nil, fmt.Errorf("dlq entry %s: %w", e.DLQID, err)
+		}
+	}
 	if reasonDetail != nil {
 		e.ReasonDetail = *reasonDetail
 	}
+	if fingerprint != nil {
+		e.Fingerprint = *fingerprint
+	}
+	if notes != nil {
+		e.Notes = *notes
+	}
+	if assignedTo != nil {
+		e.AssignedTo = *assignedTo
+	}
+	if taskID != nil {
+		e.TaskID = *taskID
+	}
+	if scheduledBy != nil {
+		e.RetryScheduledBy = *scheduledBy
+	}
+	if reopenedBy != nil {
+		e.ReopenedBy = *reopenedBy
+	}
 	if recoveredAt != nil {
 		e.RecoveredAt = recoveredAt
 	}
 	if recoveredBy != nil {
 		e.RecoveredBy = *recoveredBy
 	}
+	if discardedBy != nil {
+		e.DiscardedBy = *discardedBy
+	}
+	if discardReason != nil {
+		e.DiscardReason = *discardReason
+	}
+	if discardNote != nil {
+		e.DiscardNote = *discardNote
+	}
+	if payloadRef != nil {
+		e.PayloadRef = *payloadRef
+	}
+	if claimedBy != nil {
+		e.ClaimedBy = *claimedBy
+	}
+	if producerSvc != nil {
+		e.ProducerService = *producerSvc
+	}
+	if producerVer != nil {
+		e.ProducerVersion = *producerVer
+	}
+	if producerHost != nil {
+		e.ProducerHost = *producerHost
+	}
 	_ = json.Unmarshal(retryJSON, &e.RetryHistory)
 	if e.RetryHistory == nil {
 		e.RetryHistory = []RetryAttempt{}
 	}
+	if len(samplesJSON) > 0 {
+		_ = json.Unmarshal(samplesJSON, &e.PayloadSamples)
+	}
 	return &e, nil
 }
@@ -3,11 +3,17 @@ package dlq
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nats-io/nats.go"
 )
 
 func skipWithoutDB(t *testing.T) *pgxpool.Pool {
@@ -41,11 +47,14 @@ func TestIntegration_InsertAndGet(t *testing.T) {
 		RetryHistory: []RetryAttempt{
 			{Attempt: 1, AttemptedAt: time.Now().UTC(), Agent: "scout", FailureReason: "unavailable"},
 		},
-		Source:      SourceDispatch,
-		Recoverable: true,
+		Source:          SourceDispatch,
+		Recoverable:     true,
+		OriginalHeaders: map[string][]string{"Nats-Msg-Id": {"req-1"}},
+		PayloadRedacted: true,
+		SealedPayload:   []byte{1, 2, 3},
 	}
 
-	if err := s.Insert(ctx, entry); err != nil {
+	if _, err := s.Insert(ctx, entry); err != nil {
 		t.Fatalf("insert: %v", err)
 	}
 
@@ -63,6 +72,12 @@ func TestIntegration_InsertAndGet(t *testing.T) {
 	if len(got.RetryHistory) != 1 {
 		t.Errorf("expected 1 retry, got %d", len(got.RetryHistory))
 	}
+	if got.OriginalHeaders["Nats-Msg-Id"][0] != "req-1" {
+		t.Errorf("expected original headers to round-trip, got %v", got.OriginalHeaders)
+	}
+	if !got.PayloadRedacted || len(got.SealedPayload) != 3 {
+		t.Errorf("expected redaction columns to round-trip, got %v %v", got.PayloadRedacted, got.SealedPayload)
+	}
 
 	// Cleanup.
 	_, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", entry.DLQID)
@@ -81,7 +96,7 @@ func TestIntegration_ListAndFilter(t *testing.T) {
 	}
 
 	for _, e := range entries {
-		if err := s.Insert(ctx, e); err != nil {
+		if _, err := s.Insert(ctx, e); err != nil {
 			t.Fatalf("insert: %v", err)
 		}
 	}
@@ -128,7 +143,7 @@ func TestIntegration_MarkRecovered(t *testing.T) {
 		FailedAt:        time.Now().UTC(),
 		Recoverable:     true,
 	}
-	_ = s.Insert(ctx, entry)
+	_, _ = s.Insert(ctx, entry)
 
 	if err := s.MarkRecovered(ctx, id, "test-recovery"); err != nil {
 		t.Fatalf("mark recovered: %v", err)
@@ -141,6 +156,9 @@ func TestIntegration_MarkRecovered(t *testing.T) {
 	if got.RecoveredBy != "test-recovery" {
 		t.Errorf("expected recovered_by test-recovery, got %s", got.RecoveredBy)
 	}
+	if got.Status != StatusRecovered {
+		t.Errorf("expected status recovered, got %s", got.Status)
+	}
 
 	// Double-recover should fail.
 	if err := s.MarkRecovered(ctx, id, "again"); err == nil {
@@ -151,6 +169,37 @@ func TestIntegration_MarkRecovered(t *testing.T) {
 	_, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", id)
 }
 
+func TestIntegration_MarkDiscarded(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	id := "int-discard-" + time.Now().Format("150405.000")
+	_, _ = s.Insert(ctx, Entry{
+		DLQID:           id,
+		OriginalSubject: "swarm.task.request",
+		OriginalPayload: json.RawMessage(`{}`),
+		Reason:          ReasonPolicyDenied,
+		Source:          SourceDispatch,
+		FailedAt:        time.Now().UTC(),
+	})
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", id) })
+
+	if err := s.MarkDiscarded(ctx, id, RecoveredByDiscard, DiscardOpts{Note: "duplicate"}); err != nil {
+		t.Fatalf("mark discarded: %v", err)
+	}
+	got, _ := s.Get(ctx, id)
+	if got.Status != StatusDiscarded || got.DiscardedAt == nil || got.DiscardedBy != RecoveredByDiscard {
+		t.Errorf("expected discarded entry, got status %q at %v by %q", got.Status, got.DiscardedAt, got.DiscardedBy)
+	}
+	if got.RecoveredAt != nil || got.RecoveredBy != "" {
+		t.Errorf("discard set recovered_at/recovered_by: %v %q", got.RecoveredAt, got.RecoveredBy)
+	}
+	if got.DiscardNote != "duplicate" {
+		t.Errorf("expected note duplicate, got %q", got.DiscardNote)
+	}
+}
+
 func TestIntegration_ListRecoverable(t *testing.T) {
 	pool := skipWithoutDB(t)
 	s := NewStore(pool)
@@ -159,8 +208,8 @@ func TestIntegration_ListRecoverable(t *testing.T) {
 	prefix := "int-recoverable-" + time.Now().Format("150405")
 
 	// One recoverable, one not.
-	_ = s.Insert(ctx, Entry{DLQID: prefix + "-a", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC(), Recoverable: true})
-	_ = s.Insert(ctx, Entry{DLQID: prefix + "-b", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonPolicyDenied, Source: SourceDispatch, FailedAt: time.Now().UTC(), Recoverable: false})
+	_, _ = s.Insert(ctx, Entry{DLQID: prefix + "-a", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC(), Recoverable: true})
+	_, _ = s.Insert(ctx, Entry{DLQID: prefix + "-b", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonPolicyDenied, Source: SourceDispatch, FailedAt: time.Now().UTC(), Recoverable: false})
 
 	entries, err := s.ListRecoverable(ctx)
 	if err != nil {
@@ -180,6 +229,143 @@ func TestIntegration_ListRecoverable(t *testing.T) {
 	_, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id LIKE $1", prefix+"%")
 }
 
+func TestIntegration_AutoRetryBackoffAndExhaust(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	prefix := "int-autoretry-" + time.Now().Format("150405")
+	_, _ = s.Insert(ctx, Entry{DLQID: prefix + "-a", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC(), Recoverable: true})
+	defer func() { _, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id LIKE $1", prefix+"%") }()
+
+	listed := func() bool {
+		entries, err := s.ListRecoverable(ctx)
+		if err != nil {
+			t.Fatalf("list recoverable: %v", err)
+		}
+		for _, e := range entries {
+			if e.DLQID == prefix+"-a" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if err := s.RecordAutoRetryFailure(ctx, prefix+"-a", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("record auto retry failure: %v", err)
+	}
+	if listed() {
+		t.Error("entry should not be recoverable while backing off")
+	}
+	e, _ := s.Get(ctx, prefix+"-a")
+	if e.AutoRetryCount != 1 || e.NextRetryAt == nil {
+		t.Errorf("expected auto_retry_count 1 and next_retry_at set, got %d %v", e.AutoRetryCount, e.NextRetryAt)
+	}
+
+	if err := s.MarkExhausted(ctx, prefix+"-a"); err != nil {
+		t.Fatalf("mark exhausted: %v", err)
+	}
+	e, _ = s.Get(ctx, prefix+"-a")
+	if e.Status != StatusExhausted || e.AutoRetryCount != 2 || e.NextRetryAt != nil || e.Recovered {
+		t.Errorf("unexpected exhausted entry: status=%s count=%d next=%v recovered=%v", e.Status, e.AutoRetryCount, e.NextRetryAt, e.Recovered)
+	}
+	if listed() {
+		t.Error("exhausted entry should not be recoverable")
+	}
+}
+
+func TestIntegration_ExpireRecoverable(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	prefix := "int-expire-" + time.Now().Format("150405")
+	old := time.Now().Add(-RecoveryWindow - time.Hour).UTC()
+	_, _ = s.Insert(ctx, Entry{DLQID: prefix + "-old", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: old, Recoverable: true})
+	_, _ = s.Insert(ctx, Entry{DLQID: prefix + "-new", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC(), Recoverable: true})
+	defer func() { _, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id LIKE $1", prefix+"%") }()
+
+	expired, err := s.ExpireRecoverable(ctx, time.Now().Add(-RecoveryWindow), 1000)
+	if err != nil {
+		t.Fatalf("expire recoverable: %v", err)
+	}
+	found := false
+	for _, e := range expired {
+		if e.DLQID == prefix+"-new" {
+			t.Error("fresh entry should not expire")
+		}
+		if e.DLQID == prefix+"-old" {
+			found = true
+			if e.Status != StatusExpired || e.ExpiredAt == nil || !e.Recovered {
+				t.Errorf("unexpected expired entry: status=%s expired_at=%v recovered=%v", e.Status, e.ExpiredAt, e.Recovered)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected the old entry to expire")
+	}
+	st, err := s.Stats(ctx)
+	if err != nil || st.Expired < 1 {
+		t.Errorf("expected stats to count expired entries, got %v (err %v)", st, err)
+	}
+}
+
+func TestIntegration_Count(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	prefix := "int-count-" + time.Now().Format("150405")
+	source := "count-" + prefix
+	_, _ = s.Insert(ctx, Entry{DLQID: prefix + "-a", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: source, FailedAt: time.Now().UTC()})
+	_, _ = s.Insert(ctx, Entry{DLQID: prefix + "-b", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: source, FailedAt: time.Now().Add(-time.Hour).UTC()})
+	defer func() { _, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id LIKE $1", prefix+"%") }()
+
+	n, err := s.Count(ctx, ListOpts{Source: source})
+	if err != nil || n != 2 {
+		t.Errorf("expected 2 entries, got %d (err %v)", n, err)
+	}
+	n, err = s.Count(ctx, ListOpts{Source: source, FailedAfter: time.Now().Add(-10 * time.Minute)})
+	if err != nil || n != 1 {
+		t.Errorf("expected 1 recent entry, got %d (err %v)", n, err)
+	}
+}
+
+func TestIntegration_Export(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	prefix := "int-export-" + time.Now().Format("150405")
+	source := "export-" + prefix
+	base := time.Now().Add(-time.Hour).UTC()
+	entries := make([]Entry, exportBatch+20)
+	for i := range entries {
+		entries[i] = Entry{DLQID: fmt.Sprintf("%s-%04d", prefix, i), OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: source, FailedAt: base.Add(time.Duration(i) * time.Millisecond)}
+	}
+	if _, err := s.InsertBatch(ctx, entries); err != nil {
+		t.Fatalf("insert batch: %v", err)
+	}
+	defer func() { _, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id LIKE $1", prefix+"%") }()
+
+	var ids []string
+	if err := s.Export(ctx, ListOpts{Source: source}, func(e Entry) error {
+		ids = append(ids, e.DLQID)
+		return nil
+	}); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if len(ids) != len(entries) || ids[0] != entries[0].DLQID || ids[len(ids)-1] != entries[len(entries)-1].DLQID {
+		t.Errorf("expected %d entries across pages oldest first, got %d", len(entries), len(ids))
+	}
+
+	n := 0
+	_ = s.Export(ctx, ListOpts{Source: source, Limit: exportBatch + 5}, func(Entry) error { n++; return nil })
+	if n != exportBatch+5 {
+		t.Errorf("expected the limit to cap the export, got %d", n)
+	}
+}
+
 func TestIntegration_Stats(t *testing.T) {
 	pool := skipWithoutDB(t)
 	s := NewStore(pool)
@@ -197,4 +383,737 @@ func TestIntegration_Stats(t *testing.T) {
 	if stats.ByReason == nil {
 		t.Error("expected non-nil ByReason map")
 	}
+	if ttr := stats.TimeToRecovery; ttr.P50 > ttr.P90 || ttr.P90 > ttr.P99 || stats.OldestUnrecoveredAge < 0 {
+		t.Errorf("unexpected recovery stats: %+v %v", ttr, stats.OldestUnrecoveredAge)
+	}
+	if len(stats.BySubject) > StatsTopSubjects {
+		t.Errorf("expected at most %d subjects, got %d", StatsTopSubjects, len(stats.BySubject))
+	}
+}
+
+func TestIntegration_InsertBatch(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	prefix := "int-batch-" + time.Now().Format("150405")
+	entries := []Entry{
+		{DLQID: prefix + "-a", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC(), Recoverable: true},
+		{DLQID: prefix + "-b", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonBootFailure, Source: SourceWarren, FailedAt: time.Now().UTC(), Recoverable: true},
+	}
+
+	if _, err := s.InsertBatch(ctx, entries); err != nil {
+		t.Fatalf("insert batch: %v", err)
+	}
+	for _, e := range entries {
+		if _, err := s.Get(ctx, e.DLQID); err != nil {
+			t.Errorf("get %s: %v", e.DLQID, err)
+		}
+	}
+
+	// Cleanup.
+	_, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id LIKE $1", prefix+"%")
+}
+
+func TestIntegration_RecordOccurrences(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	id := "int-storm-" + time.Now().Format("150405.000")
+	_, _ = s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC()})
+
+	if err := s.RecordOccurrences(ctx, id, 3, time.Now().UTC(), []json.RawMessage{json.RawMessage(`{"n":1}`)}); err != nil {
+		t.Fatalf("record occurrences: %v", err)
+	}
+
+	got, _ := s.Get(ctx, id)
+	if got.Occurrences != 4 {
+		t.Errorf("expected 4 occurrences, got %d", got.Occurrences)
+	}
+	if got.LastSeenAt == nil {
+		t.Error("expected last_seen_at to be set")
+	}
+	if len(got.PayloadSamples) != 1 {
+		t.Errorf("expected 1 sample, got %d", len(got.PayloadSamples))
+	}
+
+	// Cleanup.
+	_, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", id)
+}
+
+func TestIntegration_InsertUpsert(t *testing.T) {
+	pool := skipWithoutDB(t)
+	ctx := context.Background()
+
+	id := "int-upsert-" + time.Now().Format("150405.000")
+	first := Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC(), RetryCount: 1}
+	newer := first
+	newer.RetryCount = 3
+	newer.ReasonDetail = "more detail"
+	newer.FailedAt = first.FailedAt.Add(time.Minute)
+
+	// Default mode keeps the first version.
+	s := NewStore(pool)
+	if created, _ := s.Insert(ctx, first); !created {
+		t.Error("expected first insert to create the row")
+	}
+	if created, _ := s.Insert(ctx, newer); created {
+		t.Error("expected second insert to be reported as a duplicate")
+	}
+	if got, _ := s.Get(ctx, id); got.RetryCount != 1 {
+		t.Errorf("expected first version to be kept, got retry_count %d", got.RetryCount)
+	}
+
+	upsert := NewStore(pool, WithStoreUpsert())
+	if _, err := upsert.Insert(ctx, newer); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	got, _ := upsert.Get(ctx, id)
+	if got.RetryCount != 3 || got.ReasonDetail != "more detail" {
+		t.Errorf("expected refreshed entry, got %+v", got)
+	}
+	if got.LastSeenAt == nil || !got.LastSeenAt.Equal(newer.FailedAt.Truncate(time.Microsecond)) {
+		t.Errorf("expected last_seen_at %v, got %v", newer.FailedAt, got.LastSeenAt)
+	}
+
+	// Cleanup.
+	_, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", id)
+}
+
+func TestIntegration_StoreMetrics(t *testing.T) {
+	pool := skipWithoutDB(t)
+	m := NewMetrics()
+	s := NewStore(pool, WithStoreMetrics(m))
+
+	if _, err := s.Stats(context.Background()); err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if m.Get("store_stats_calls_total") != 1 {
+		t.Error("expected stats call to be counted")
+	}
+	if m.Get(MetricStorePoolMaxConns) == 0 {
+		t.Error("expected pool stats to be recorded")
+	}
+}
+
+func TestIntegration_Archive(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	id := "int-archive-" + time.Now().Format("150405.000")
+	_, _ = s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC()})
+	_ = s.MarkRecovered(ctx, id, RecoveredByAPIRetry)
+
+	moved, err := s.Archive(ctx, ArchiveFilter{Recovered: true, Reason: ReasonNoCapableAgent})
+	if err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	if moved < 1 {
+		t.Errorf("expected at least 1 moved, got %d", moved)
+	}
+	if _, err := s.Get(ctx, id); err == nil {
+		t.Error("expected entry to leave swarm_dlq")
+	}
+	got, err := s.GetArchived(ctx, id)
+	if err != nil {
+		t.Fatalf("get archived: %v", err)
+	}
+	if got.ArchivedAt == nil || !got.Recovered {
+		t.Errorf("unexpected archived entry: %+v", got)
+	}
+
+	// Cleanup.
+	_, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq_archive WHERE dlq_id = $1", id)
+}
+
+func TestIntegration_Claim(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	id := "int-claim-" + time.Now().Format("150405.000")
+	_, _ = s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC()})
+
+	if _, err := s.Claim(ctx, id, "alice", time.Minute); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if _, err := s.Claim(ctx, id, "bob", time.Minute); !errors.Is(err, ErrEntryClaimed) {
+		t.Errorf("expected ErrEntryClaimed, got %v", err)
+	}
+	got, _ := s.Get(ctx, id)
+	if got.ClaimedBy != "alice" || got.ClaimExpiresAt == nil {
+		t.Errorf("unexpected claim: %+v", got)
+	}
+	if err := s.ReleaseClaim(ctx, id, "alice"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, err := s.Claim(ctx, id, "bob", time.Minute); err != nil {
+		t.Errorf("claim after release: %v", err)
+	}
+
+	// Cleanup.
+	_, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", id)
+}
+
+func TestIntegration_Erase(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	id := "int-erase-" + time.Now().Format("150405.000")
+	user := `"` + id + `"`
+	_, _ = s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{"user_id":` + user + `}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC(), Recoverable: true})
+
+	ids, err := s.Erase(ctx, ErasureRequest{Field: "user_id", Value: json.RawMessage(user), Mode: EraseScrub, DryRun: true})
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("dry run: %v %v", ids, err)
+	}
+	if _, err := s.Erase(ctx, ErasureRequest{Field: "user_id", Value: json.RawMessage(user), Mode: EraseScrub}); err != nil {
+		t.Fatalf("scrub: %v", err)
+	}
+	got, _ := s.Get(ctx, id)
+	if !got.PayloadErased || got.Recoverable || string(got.OriginalPayload) != "null" {
+		t.Errorf("unexpected scrubbed entry: %+v", got)
+	}
+	ids, _ = s.Erase(ctx, ErasureRequest{Field: "user_id", Value: json.RawMessage(user), Mode: EraseDelete})
+	if len(ids) != 0 {
+		t.Errorf("scrubbed payload should no longer match, got %v", ids)
+	}
+
+	// Cleanup.
+	_, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", id)
+}
+
+func TestIntegration_EraseOffloaded(t *testing.T) {
+	pool := skipWithoutDB(t)
+	blobs := &memBlobStore{}
+	s := NewStore(pool, WithStoreBlobs(blobs))
+	ctx := context.Background()
+
+	id := "int-erase-blob-" + time.Now().Format("150405.000")
+	user := `"` + id + `"`
+	ref, _ := blobs.Put(ctx, id, []byte(`{"user_id":`+user+`,"big":true}`))
+	_, _ = s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`"{\"user_id\""`), PayloadTruncated: true, PayloadRef: ref, Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC()})
+
+	ids, err := s.Erase(ctx, ErasureRequest{Field: "user_id", Value: json.RawMessage(user), Mode: EraseDelete})
+	if err != nil || len(ids) != 1 || ids[0] != id {
+		t.Fatalf("expected the offloaded payload to match, got %v %v", ids, err)
+	}
+	if _, err := blobs.Get(ctx, ref); err == nil {
+		t.Error("expected the offloaded payload deleted")
+	}
+}
+
+func TestIntegration_Compression(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool, WithStoreCompression(CompressZstd, 0))
+	ctx := context.Background()
+
+	id := "int-zstd-" + time.Now().Format("150405.000")
+	user := `"` + id + `"`
+	payload := json.RawMessage(`{"user_id":` + user + `,"agent":` + user + `,"body":"` + strings.Repeat("z", 1024) + `"}`)
+	if _, err := s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: payload, Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC(), Recoverable: true}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	// The agent filter still sees inside the compressed payload.
+	if entries, err := s.List(ctx, ListOpts{Agent: id}); err != nil || len(entries) != 1 || entries[0].DLQID != id {
+		t.Errorf("expected the agent filter to match the compressed payload, got %v %v", entries, err)
+	}
+
+	// Reads decompress whatever the store's own setting.
+	got, err := NewStore(pool).Get(ctx, id)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if string(got.OriginalPayload) != string(payload) {
+		t.Errorf("expected the payload to round-trip, got %s", got.OriginalPayload)
+	}
+
+	ids, err := s.Erase(ctx, ErasureRequest{Field: "user_id", Value: json.RawMessage(user), Mode: EraseScrub})
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("erase should find the compressed payload: %v %v", ids, err)
+	}
+	got, _ = s.Get(ctx, id)
+	if !got.PayloadErased || string(got.OriginalPayload) != "null" {
+		t.Errorf("unexpected scrubbed entry: %+v", got)
+	}
+
+	// Cleanup.
+	_, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", id)
+}
+
+func TestIntegration_Fingerprint(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	prefix := "int-fp-" + time.Now().Format("150405.000")
+	fp := Fingerprint("swarm.task.request", ReasonNoCapableAgent, json.RawMessage(`{"task":"`+prefix+`"}`))
+	for i, at := range []time.Time{time.Now().Add(-time.Hour), time.Now()} {
+		_, _ = s.Insert(ctx, Entry{DLQID: fmt.Sprintf("%s-%d", prefix, i), OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: at.UTC(), Fingerprint: fp})
+	}
+
+	into, err := s.RecordFingerprint(ctx, fp, prefix+"-new", time.Now().UTC())
+	if err != nil || into != prefix+"-1" {
+		t.Fatalf("expected the newest open entry, got %q %v", into, err)
+	}
+	if into, _ := s.RecordFingerprint(ctx, fp, prefix+"-1", time.Now().UTC()); into != "" {
+		t.Errorf("a redelivery of the entry itself should not be collapsed, got %q", into)
+	}
+
+	groups, err := s.ListFingerprintGroups(ctx, ListOpts{Fingerprint: fp})
+	if err != nil || len(groups) != 1 {
+		t.Fatalf("groups: %v %v", groups, err)
+	}
+	if g := groups[0]; g.Entries != 2 || g.Occurrences != 3 || g.LatestDLQID != prefix+"-1" {
+		t.Errorf("unexpected group: %+v", g)
+	}
+
+	// Cleanup.
+	_, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE fingerprint = $1", fp)
+}
+
+func TestIntegration_Timeseries(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	id := "int-ts-" + time.Now().Format("150405.000")
+	_, _ = s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC(), Recoverable: true})
+	_ = s.MarkRecovered(ctx, id, "int-test")
+
+	ts, err := s.Timeseries(ctx, TimeseriesOpts{Window: time.Hour, Bucket: time.Minute, GroupBy: "reason"})
+	if err != nil {
+		t.Fatalf("timeseries: %v", err)
+	}
+	var ingested, recovered int
+	for _, series := range ts.Series {
+		if series.Group != ReasonNoCapableAgent {
+			continue
+		}
+		for _, p := range series.Points {
+			ingested += p.Ingested
+			recovered += p.Recovered
+		}
+	}
+	if ingested < 1 || recovered < 1 {
+		t.Errorf("expected the entry to be counted in and out, got %d/%d", ingested, recovered)
+	}
+
+	// Cleanup.
+	_, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", id)
+}
+
+func TestIntegration_BeginRetry(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	id := "int-lock-" + time.Now().Format("150405.000")
+	_, _ = s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC(), Recoverable: true})
+
+	e, err := s.BeginRetry(ctx, id, "op")
+	if err != nil || e.Status != StatusRetrying || e.RetryStartedAt == nil {
+		t.Fatalf("begin retry: %+v %v", e, err)
+	}
+	if _, err := s.BeginRetry(ctx, id, "op"); !errors.Is(err, ErrRetryInProgress) {
+		t.Errorf("expected ErrRetryInProgress, got %v", err)
+	}
+	if err := s.AbortRetry(ctx, id); err != nil {
+		t.Fatalf("abort retry: %v", err)
+	}
+	if got, _ := s.Get(ctx, id); got.Status != StatusPending || got.RetryStartedAt != nil {
+		t.Errorf("expected the entry back in pending, got %s %v", got.Status, got.RetryStartedAt)
+	}
+	if _, err := s.BeginRetry(ctx, id, "op"); err != nil {
+		t.Fatalf("begin retry after abort: %v", err)
+	}
+	_ = s.MarkRecovered(ctx, id, "int-test")
+	if _, err := s.BeginRetry(ctx, id, "op"); !errors.Is(err, ErrAlreadyRecovered) {
+		t.Errorf("expected ErrAlreadyRecovered, got %v", err)
+	}
+
+	// Cleanup.
+	_, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", id)
+}
+
+func TestIntegration_Outbox(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	id := "int-outbox-" + time.Now().Format("150405.000")
+	_, _ = s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC(), Recoverable: true})
+	t.Cleanup(func() {
+		_, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", id)
+		_, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq_outbox WHERE dlq_id = $1", id)
+	})
+
+	rec, err := s.EnqueueRecovery(ctx, OutboxRecord{DLQID: id, Subject: "swarm.task.request", Payload: []byte(`{}`), Header: nats.Header{HeaderDLQID: {id}}, RecoveredBy: "int-test"})
+	if err != nil || rec.ID == 0 {
+		t.Fatalf("enqueue recovery: %+v %v", rec, err)
+	}
+	if got, _ := s.Get(ctx, id); !got.Recovered || got.RecoveredBy != "int-test" {
+		t.Errorf("expected the entry recovered by int-test, got %+v", got)
+	}
+	if _, err := s.EnqueueRecovery(ctx, OutboxRecord{DLQID: id, RecoveredBy: "int-test"}); !errors.Is(err, ErrAlreadyRecovered) {
+		t.Errorf("expected ErrAlreadyRecovered, got %v", err)
+	}
+
+	if err := s.MarkRecoveryPublished(ctx, rec.ID, errors.New("nats down")); err != nil {
+		t.Fatalf("mark failed attempt: %v", err)
+	}
+	pending, err := s.PendingRecoveries(ctx, time.Now().Add(time.Minute), 1000)
+	if err != nil {
+		t.Fatalf("pending recoveries: %v", err)
+	}
+	var found *OutboxRecord
+	for i := range pending {
+		if pending[i].ID == rec.ID {
+			found = &pending[i]
+		}
+	}
+	if found == nil || found.Attempts != 1 || found.LastError != "nats down" || found.Header.Get(HeaderDLQID) != id {
+		t.Fatalf("expected the pending record with one failed attempt, got %+v", found)
+	}
+
+	if err := s.MarkRecoveryPublished(ctx, rec.ID, nil); err != nil {
+		t.Fatalf("mark published: %v", err)
+	}
+	var n int
+	_ = pool.QueryRow(ctx, "SELECT count(*) FROM swarm_dlq_outbox WHERE id = $1", rec.ID).Scan(&n)
+	if n != 0 {
+		t.Errorf("expected the published record removed, got %d", n)
+	}
+}
+
+func TestIntegration_Version(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	id := "int-version-" + time.Now().Format("150405.000")
+	_, _ = s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC()})
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", id) })
+
+	e, _ := s.Get(ctx, id)
+	if e.Version != 1 {
+		t.Fatalf("expected a new entry at version 1, got %d", e.Version)
+	}
+	_ = s.RecordRetryAck(ctx, id, RetryAck{Status: RetryAckTimeout})
+	if got, _ := s.Get(ctx, id); got.Version != 1 || got.UpdatedAt == nil {
+		t.Errorf("expected bookkeeping writes to keep the version and set updated_at, got v%d %v", got.Version, got.UpdatedAt)
+	}
+	if _, err := s.Claim(ctx, id, "alice", time.Minute); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	err := s.MarkRecoveredIfVersion(ctx, id, "int-test", 1)
+	var conflict *VersionConflictError
+	if !errors.As(err, &conflict) || conflict.Current != 2 {
+		t.Fatalf("expected a version conflict at version 2, got %v", err)
+	}
+	if err := s.MarkRecoveredIfVersion(ctx, id, "int-test", 2); err != nil {
+		t.Fatalf("mark recovered at the current version: %v", err)
+	}
+	if err := s.MarkRecoveredIfVersion(ctx, id, "int-test", 3); !errors.Is(err, ErrAlreadyRecovered) {
+		t.Errorf("expected ErrAlreadyRecovered, got %v", err)
+	}
+}
+
+func TestIntegration_IdempotencyKeys(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	key := "int-idem-" + time.Now().Format("150405.000")
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq_idempotency WHERE key = $1", key) })
+
+	if resp, err := s.ReserveIdempotencyKey(ctx, key, "req"); err != nil || resp != nil {
+		t.Fatalf("reserve: %v %v", resp, err)
+	}
+	if _, err := s.ReserveIdempotencyKey(ctx, key, "req"); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Errorf("expected ErrIdempotencyInProgress, got %v", err)
+	}
+	if err := s.CompleteIdempotencyKey(ctx, key, IdempotentResponse{Status: 200, Body: []byte(`{"status":"retried"}`)}); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	resp, err := s.ReserveIdempotencyKey(ctx, key, "req")
+	if err != nil || resp == nil || resp.Status != 200 || string(resp.Body) != `{"status":"retried"}` {
+		t.Fatalf("expected the stored response, got %+v %v", resp, err)
+	}
+	if _, err := s.ReserveIdempotencyKey(ctx, key, "other"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("expected ErrIdempotencyKeyReused, got %v", err)
+	}
+	if err := s.ReleaseIdempotencyKey(ctx, key); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if resp, _ := s.ReserveIdempotencyKey(ctx, key, "req"); resp == nil {
+		t.Error("expected release to leave a completed key in place")
+	}
+}
+
+func TestIntegration_UpdateEntry(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	id := "int-notes-" + time.Now().Format("150405.000")
+	_, _ = s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC()})
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", id) })
+
+	notes := "waiting on agent image fix"
+	e, err := s.UpdateEntry(ctx, id, EntryPatch{Notes: &notes, Version: 1})
+	if err != nil || e.Notes != notes || e.Version != 2 {
+		t.Fatalf("update: %+v %v", e, err)
+	}
+	if _, err := s.UpdateEntry(ctx, id, EntryPatch{Notes: &notes, Version: 1}); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected a version conflict, got %v", err)
+	}
+	cleared := ""
+	if e, err := s.UpdateEntry(ctx, id, EntryPatch{Notes: &cleared}); err != nil || e.Notes != "" {
+		t.Errorf("expected the notes cleared, got %+v %v", e, err)
+	}
+	assignee := "oncall-" + id
+	if e, err := s.UpdateEntry(ctx, id, EntryPatch{AssignedTo: &assignee}); err != nil || e.AssignedTo != assignee {
+		t.Errorf("expected the entry assigned, got %+v %v", e, err)
+	}
+	if page, err := s.List(ctx, ListOpts{AssignedTo: assignee, Limit: 10}); err != nil || len(page) != 1 || page[0].DLQID != id {
+		t.Errorf("expected the assigned entry listed, got %d (%v)", len(page), err)
+	}
+}
+
+func TestIntegration_TaskID(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	id := "int-task-" + time.Now().Format("150405.000")
+	_, _ = s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC(), TaskID: "task-" + id})
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", id) })
+
+	page, err := s.List(ctx, ListOpts{TaskID: "task-" + id, Limit: 10})
+	if err != nil || len(page) != 1 || page[0].TaskID != "task-"+id {
+		t.Fatalf("expected the entry listed by task_id, got %+v (%v)", page, err)
+	}
+}
+
+func TestIntegration_ScheduleRetry(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	id := "int-schedule-" + time.Now().Format("150405.000")
+	_, _ = s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC(), Recoverable: true})
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", id) })
+
+	listed := func(entries []Entry) bool {
+		for _, e := range entries {
+			if e.DLQID == id {
+				return true
+			}
+		}
+		return false
+	}
+	if err := s.ScheduleRetry(ctx, id, "alice", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	if entries, _ := s.ListRecoverable(ctx); listed(entries) {
+		t.Error("expected an entry scheduled for later to be left out of ListRecoverable")
+	}
+	_ = s.ScheduleRetry(ctx, id, "alice", time.Now().Add(-time.Second))
+	entries, err := s.ListScheduledRetries(ctx)
+	if err != nil || !listed(entries) {
+		t.Fatalf("expected the due entry listed, got %v", err)
+	}
+	if e, _ := s.Get(ctx, id); e.RetryAt == nil || e.RetryScheduledBy != "alice" {
+		t.Errorf("expected retry_at and retry_scheduled_by set, got %+v", e)
+	}
+	_ = s.MarkRecovered(ctx, id, RecoveredByScheduledRetry)
+	if err := s.ScheduleRetry(ctx, id, "alice", time.Now()); !errors.Is(err, ErrAlreadyRecovered) {
+		t.Errorf("expected ErrAlreadyRecovered, got %v", err)
+	}
+}
+
+func TestIntegration_Delete(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	id := "int-delete-" + time.Now().Format("150405.000")
+	_, _ = s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC()})
+
+	if err := s.Delete(ctx, id); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s.Get(ctx, id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expected the entry gone, got %v", err)
+	}
+	if err := s.Delete(ctx, id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expected ErrNoRows deleting twice, got %v", err)
+	}
+}
+
+func TestIntegration_Reopen(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	id := "int-reopen-" + time.Now().Format("150405.000")
+	_, _ = s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC()})
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", id) })
+
+	if _, err := s.Reopen(ctx, id, "alice", 0); !errors.Is(err, ErrNotRecovered) {
+		t.Errorf("expected ErrNotRecovered for an open entry, got %v", err)
+	}
+	_ = s.MarkDiscarded(ctx, id, "bob", DiscardOpts{Reason: "dup"})
+	e, err := s.Reopen(ctx, id, "alice", 0)
+	if err != nil || e.Recovered || e.Status != StatusPending || e.DiscardedAt != nil || e.ReopenedBy != "alice" {
+		t.Fatalf("reopen: %+v %v", e, err)
+	}
+}
+
+func TestIntegration_Tenant(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool, WithStoreTenant("int-prod"))
+	ctx := context.Background()
+	staging := ContextWithTenant(ctx, "int-staging")
+
+	id := "int-tenant-" + time.Now().Format("150405.000")
+	_, _ = s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC()})
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq WHERE dlq_id = $1", id) })
+
+	if e, err := s.Get(ctx, id); err != nil || e.TenantID != "int-prod" {
+		t.Fatalf("expected the store tenant to be stamped: %+v %v", e, err)
+	}
+	if _, err := s.Get(staging, id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("another tenant should not see the entry, got %v", err)
+	}
+	if err := s.MarkRecovered(staging, id, RecoveredByAPIRetry); err == nil {
+		t.Error("another tenant should not recover the entry")
+	}
+	if entries, err := s.List(staging, ListOpts{Reason: ReasonNoCapableAgent}); err != nil || slices.ContainsFunc(entries, func(e Entry) bool { return e.DLQID == id }) {
+		t.Errorf("another tenant should not list the entry: %v", err)
+	}
+	if created, err := s.Insert(staging, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC()}); err != nil || created {
+		t.Errorf("a clashing dlq_id from another tenant must not be created: %v %v", created, err)
+	}
+}
+
+func TestIntegration_ParkInvalid(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := ContextWithTenant(context.Background(), "int-park-"+time.Now().Format("150405.000"))
+	t.Cleanup(func() {
+		_, _ = pool.Exec(context.Background(), "DELETE FROM swarm_dlq_invalid WHERE tenant_id = $1", TenantFromContext(ctx))
+	})
+
+	raw := []byte(`{"dlq_id":"x"}`)
+	if err := s.ParkInvalid(ctx, InvalidEvent{Subject: SubjectTaskUnassignable, Data: raw, Error: "failed_at is required"}); err != nil {
+		t.Fatalf("park: %v", err)
+	}
+	events, err := s.ListInvalid(ctx, 10)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("list: %+v %v", events, err)
+	}
+	if ev := events[0]; string(ev.Data) != string(raw) || ev.Error != "failed_at is required" || ev.TenantID != TenantFromContext(ctx) || ev.ReceivedAt.IsZero() {
+		t.Errorf("unexpected parked event %+v", ev)
+	}
+
+	pii := []byte(`{"dlq_id":"y","original_payload":{"user_id":"u-park"}}`)
+	_ = s.ParkInvalid(ctx, InvalidEvent{Subject: SubjectTaskUnassignable, Data: pii, Error: "reason is required"})
+	ids, err := s.Erase(ctx, ErasureRequest{Field: "user_id", Value: json.RawMessage(`"u-park"`), Mode: EraseDelete})
+	if err != nil || len(ids) != 1 || !strings.HasPrefix(ids[0], "invalid:") {
+		t.Fatalf("expected the parked event erased, got %v %v", ids, err)
+	}
+	if events, _ := s.ListInvalid(ctx, 10); len(events) != 1 || string(events[0].Data) != string(raw) {
+		t.Errorf("expected only the unrelated parked event left, got %+v", events)
+	}
+}
+
+func TestIntegration_DeleteOlderThan(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	id := "int-retention-" + time.Now().Format("150405.000")
+	_, _ = s.Insert(ctx, Entry{DLQID: id, OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC()})
+	_ = s.MarkRecovered(ctx, id, RecoveredByAPIRetry)
+
+	if _, err := s.DeleteOlderThan(ctx, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s.Get(ctx, id); err != nil {
+		t.Fatalf("recently recovered entry must be kept: %v", err)
+	}
+	deleted, err := s.DeleteOlderThan(ctx, time.Now().Add(time.Minute))
+	if err != nil || deleted < 1 {
+		t.Fatalf("expected at least 1 deleted, got %d (%v)", deleted, err)
+	}
+	if _, err := s.Get(ctx, id); err == nil {
+		t.Error("expected entry to be deleted")
+	}
+
+	blobs := &memBlobStore{}
+	s = NewStore(pool, WithStoreBlobs(blobs))
+	ref, _ := blobs.Put(ctx, id+"-blob", []byte(`{}`))
+	_, _ = s.Insert(ctx, Entry{DLQID: id + "-blob", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), PayloadRef: ref, Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: time.Now().UTC()})
+	_ = s.MarkRecovered(ctx, id+"-blob", RecoveredByAPIRetry)
+	if _, err := s.DeleteOlderThan(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("delete with blobs: %v", err)
+	}
+	if _, err := blobs.Get(ctx, ref); err == nil {
+		t.Error("expected the offloaded payload deleted with its entry")
+	}
+}
+
+func TestIntegration_Audit(t *testing.T) {
+	pool := skipWithoutDB(t)
+	s := NewStore(pool)
+	ctx := context.Background()
+
+	id := "int-audit-" + time.Now().Format("150405.000")
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, "DELETE FROM swarm_dlq_audit WHERE dlq_id = $1", id) })
+	now := time.Now().UTC()
+	err := s.RecordAudit(ctx,
+		AuditRecord{Actor: "alice", Action: AuditRetry, DLQID: id, At: now, Result: AuditResultFailed, Detail: "no responders"},
+		AuditRecord{Actor: "alice", Action: AuditRetry, DLQID: id, At: now.Add(time.Second), Result: AuditResultOK},
+	)
+	if err != nil {
+		t.Fatalf("record audit: %v", err)
+	}
+
+	trail, err := s.ListAudit(ctx, id)
+	if err != nil {
+		t.Fatalf("list audit: %v", err)
+	}
+	if len(trail) != 2 || trail[0].Result != AuditResultFailed || trail[1].Result != AuditResultOK {
+		t.Errorf("unexpected trail: %+v", trail)
+	}
+	if trail[0].Detail != "no responders" || trail[1].Detail != "" {
+		t.Errorf("unexpected details: %q %q", trail[0].Detail, trail[1].Detail)
+	}
+}
+
+func TestIntegration_AdvisoryLock(t *testing.T) {
+	pool := skipWithoutDB(t)
+	ctx := context.Background()
+	key := time.Now().UnixNano()
+
+	a, b := NewAdvisoryLock(pool, key), NewAdvisoryLock(pool, key)
+	release, ok, err := a.TryLock(ctx)
+	if err != nil || !ok {
+		t.Fatalf("first lock: acquired=%v err=%v", ok, err)
+	}
+	if _, ok, err := b.TryLock(ctx); err != nil || ok {
+		t.Fatalf("second lock should fail while held: acquired=%v err=%v", ok, err)
+	}
+	release()
+	release, ok, err = b.TryLock(ctx)
+	if err != nil || !ok {
+		t.Fatalf("lock after release: acquired=%v err=%v", ok, err)
+	}
+	release()
 }
@@ -0,0 +1,140 @@
+package dlq
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// DefaultStormSamples is how many distinct extra payloads are kept per storm.
+const DefaultStormSamples = 5
+
+// stormDetector collapses bursts of near-identical events — same fingerprint
+// (original subject, reason and canonical payload) and source, each arriving
+// within window of the previous one — into the first entry of the burst.
+type stormDetector struct {
+	mu         sync.Mutex
+	window     time.Duration
+	maxSamples int
+	now        func() time.Time
+	storms     map[string]*storm
+	lastSweep  time.Time
+}
+
+type storm struct {
+	dlqID     string
+	lastSeen  time.Time
+	persisted bool
+	seen      map[[sha256.Size]byte]struct{}
+	pending   stormDelta
+}
+
+// stormDelta is a set of occurrences not yet written to the store.
+type stormDelta struct {
+	count    int
+	lastSeen time.Time
+	samples  []json.RawMessage
+}
+
+func newStormDetector(window time.Duration, maxSamples int) *stormDetector {
+	return &stormDetector{
+		window:     window,
+		maxSamples: maxSamples,
+		now:        time.Now,
+		storms:     make(map[string]*storm),
+	}
+}
+
+// stormKey groups events by fingerprint and source, so failures of distinct
+// payloads are never folded into each other's entry.
+func stormKey(e Entry) string {
+	fp := e.Fingerprint
+	if fp == "" {
+		fp = Fingerprint(e.OriginalSubject, e.Reason, e.OriginalPayload)
+	}
+	return fp + "\x00" + e.Source
+}
+
+// observe records e. If e starts a new burst it returns collapsed=false and
+// the caller persists it as usual. Otherwise e is folded into the burst's
+// head entry; delta holds occurrences ready to be written with
+// RecordOccurrences against dlqID, which may be empty while the head entry
+// itself has not been persisted yet.
+func (d *stormDetector) observe(e Entry) (dlqID string, delta stormDelta, collapsed bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now().UTC()
+	d.sweep(now)
+
+	key := stormKey(e)
+	s := d.storms[key]
+	if s == nil || now.Sub(s.lastSeen) > d.window {
+		d.storms[key] = &storm{
+			dlqID:    e.DLQID,
+			lastSeen: now,
+			seen:     map[[sha256.Size]byte]struct{}{sha256.Sum256(e.OriginalPayload): {}},
+		}
+		return "", stormDelta{}, false
+	}
+
+	s.lastSeen = now
+	s.pending.count++
+	s.pending.lastSeen = now
+	if h := sha256.Sum256(e.OriginalPayload); len(s.seen) <= d.maxSamples {
+		if _, dup := s.seen[h]; !dup {
+			s.seen[h] = struct{}{}
+			s.pending.samples = append(s.pending.samples, e.OriginalPayload)
+		}
+	}
+
+	if !s.persisted {
+		return "", stormDelta{}, true
+	}
+	delta = s.pending
+	s.pending = stormDelta{}
+	return s.dlqID, delta, true
+}
+
+// persisted marks e as written to the store and returns any occurrences that
+// were collapsed into it in the meantime.
+func (d *stormDetector) persisted(e Entry) stormDelta {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s := d.storms[stormKey(e)]
+	if s == nil || s.dlqID != e.DLQID {
+		return stormDelta{}
+	}
+	s.persisted = true
+	delta := s.pending
+	s.pending = stormDelta{}
+	return delta
+}
+
+// forget drops the burst headed by e, e.g. because its insert failed, so the
+// next matching event starts a fresh one.
+func (d *stormDetector) forget(e Entry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := stormKey(e)
+	if s := d.storms[key]; s != nil && s.dlqID == e.DLQID {
+		delete(d.storms, key)
+	}
+}
+
+// sweep discards bursts that have been quiet for longer than the window.
+// Must be called with d.mu held.
+func (d *stormDetector) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.window {
+		return
+	}
+	d.lastSweep = now
+	for key, s := range d.storms {
+		if now.Sub(s.lastSeen) > d.window && s.pending.count == 0 {
+			delete(d.storms, key)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestProcessor_StormCollapse(t *testing.T) {
+	store := newMockStore()
+	metrics := NewMetrics()
+	proc := NewProcessor(store, WithProcessorStormCollapse(time.Minute, 2), WithProcessorMetrics(metrics))
+
+	// Four encodings of one payload: the same fingerprint, distinct bytes.
+	encodings := []string{`{"a":"x","b":2}`, `{"b":2,"a":"x"}`, `{"a":"\u0078","b":2}`, `{"b":2,"a":"\u0078"}`}
+	for i := 0; i < 6; i++ {
+		data := eventJSON(Entry{
+			DLQID:           fmt.Sprintf("storm-%d", i),
+			OriginalSubject: "swarm.task.request",
+			OriginalPayload: json.RawMessage(encodings[i%4]),
+			Reason:          ReasonNoCapableAgent,
+			Source:          SourceDispatch,
+		})
+		proc.Process(context.Background(), SubjectTaskUnassignable, data)
+	}
+
+	if store.insertCalls != 1 {
+		t.Fatalf("expected 1 insert, got %d", store.insertCalls)
+	}
+	head, err := store.Get(context.Background(), "storm-0")
+	if err != nil {
+		t.Fatalf("get head: %v", err)
+	}
+	if head.Occurrences != 6 {
+		t.Errorf("expected 6 occurrences, got %d", head.Occurrences)
+	}
+	if head.LastSeenAt == nil {
+		t.Error("expected last_seen_at to be set")
+	}
+	if len(head.PayloadSamples) != 2 {
+		t.Errorf("expected 2 distinct samples, got %d", len(head.PayloadSamples))
+	}
+	if got := metrics.Get(MetricProcessorCollapsed); got != 5 {
+		t.Errorf("expected 5 collapsed, got %d", got)
+	}
+}
+
+func TestProcessor_StormCollapse_DistinctKeysNotCollapsed(t *testing.T) {
+	store := newMockStore()
+	proc := NewProcessor(store, WithProcessorStormCollapse(time.Minute, 2))
+
+	for i, reason := range []string{ReasonNoCapableAgent, ReasonPolicyDenied, ReasonAgentCrashed} {
+		data := eventJSON(Entry{DLQID: fmt.Sprintf("nostorm-%d", i), OriginalSubject: "swarm.task.request", Reason: reason})
+		proc.Process(context.Background(), SubjectTaskUnassignable, data)
+	}
+	if store.insertCalls != 3 {
+		t.Errorf("expected 3 inserts, got %d", store.insertCalls)
+	}
+}
+
+func TestProcessor_StormCollapse_DistinctPayloadsNotCollapsed(t *testing.T) {
+	store := newMockStore()
+	proc := NewProcessor(store, WithProcessorStormCollapse(time.Minute, 2))
+
+	for i := 0; i < 3; i++ {
+		data := eventJSON(Entry{
+			DLQID:           fmt.Sprintf("payload-%d", i),
+			OriginalSubject: "swarm.task.request",
+			OriginalPayload: json.RawMessage(fmt.Sprintf(`{"task_id":"t%d"}`, i)),
+			Reason:          ReasonNoCapableAgent,
+			Source:          SourceDispatch,
+		})
+		proc.Process(context.Background(), SubjectTaskUnassignable, data)
+	}
+	if store.insertCalls != 3 {
+		t.Errorf("expected each distinct payload stored on its own, got %d inserts", store.insertCalls)
+	}
+	for i := 0; i < 3; i++ {
+		if e, err := store.Get(context.Background(), fmt.Sprintf("payload-%d", i)); err != nil || e.Occurrences > 1 {
+			t.Errorf("payload-%d: expected its own entry, got %+v, %v", i, e, err)
+		}
+	}
+}
+
+func TestStormDetector_WindowExpiry(t *testing.T) {
+	d := newStormDetector(time.Second, 1)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+
+	e := Entry{DLQID: "a", OriginalSubject: "s", Reason: "r"}
+	if _, _, collapsed := d.observe(e); collapsed {
+		t.Fatal("first event should start a storm")
+	}
+
+	// Head not yet persisted: collapsed but deferred.
+	now = now.Add(500 * time.Millisecond)
+	if id, delta, collapsed := d.observe(Entry{DLQID: "b", OriginalSubject: "s", Reason: "r"}); !collapsed || id != "" || delta.count != 0 {
+		t.Fatalf("expected deferred collapse, got id=%q delta=%+v collapsed=%v", id, delta, collapsed)
+	}
+	if delta := d.persisted(e); delta.count != 1 {
+		t.Errorf("expected 1 pending occurrence on persist, got %d", delta.count)
+	}
+
+	now = now.Add(2 * time.Second)
+	if _, _, collapsed := d.observe(Entry{DLQID: "c", OriginalSubject: "s", Reason: "r"}); collapsed {
+		t.Error("event after the window should start a new storm")
+	}
+}
+
+func TestStormDetector_ForgetOnFailedInsert(t *testing.T) {
+	store := newMockStore()
+	store.insertErr = fmt.Errorf("db down")
+	proc := NewProcessor(store, WithProcessorStormCollapse(time.Minute, 1))
+
+	for i := 0; i < 2; i++ {
+		data := eventJSON(Entry{DLQID: fmt.Sprintf("ff-%d", i), OriginalSubject: "s", Reason: ReasonNoCapableAgent})
+		proc.Process(context.Background(), SubjectTaskUnassignable, data)
+	}
+	// The failed head is forgotten, so the second event is inserted on its own.
+	if store.insertCalls != 2 {
+		t.Errorf("expected 2 insert attempts, got %d", store.insertCalls)
+	}
+}
@@ -0,0 +1,165 @@
+package dlq
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// streamFlushEvery is how many entries are written between flushes of the
+// underlying ResponseWriter.
+const streamFlushEvery = 100
+
+// streamFormat is the wire format of an entry stream.
+type streamFormat int
+
+const (
+	streamJSON streamFormat = iota
+	streamNDJSON
+	streamCSV
+)
+
+// csvHeader lists the columns written in CSV mode. The payload is included
+// last, as compact JSON.
+var csvHeader = []string{
+	"dlq_id", "original_subject", "reason", "reason_detail", "source",
+	"failed_at", "retry_count", "max_retries", "recoverable", "recovered",
+	"recovered_at", "status", "recovered_by", "original_payload",
+}
+
+// negotiateFormat picks the stream format from the request's Accept header:
+// application/x-ndjson or text/csv if listed, otherwise a JSON array.
+func negotiateFormat(r *http.Request) streamFormat {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mt {
+		case "application/x-ndjson":
+			return streamNDJSON
+		case "text/csv":
+			return streamCSV
+		case "application/json":
+			return streamJSON
+		}
+	}
+	return streamJSON
+}
+
+// entryStream encodes entries to an HTTP response one at a time, as a single
+// JSON array, as newline-delimited JSON or as CSV, flushing periodically so
+// large result sets never have to be fully encoded in memory.
+type entryStream struct {
+	w       io.Writer
+	flusher http.Flusher
+	enc     *json.Encoder
+	csv     *csv.Writer
+	format  streamFormat
+	n       int
+}
+
+// newEntryStream writes the response headers and, for array mode, the opening
+// bracket (for CSV, the header row). Callers must call Close when done.
+func newEntryStream(w http.ResponseWriter, status int, format streamFormat) *entryStream {
+	switch format {
+	case streamNDJSON:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	case streamCSV:
+		w.Header().Set("Content-Type", "text/csv")
+	default:
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+
+	s := &entryStream{w: w, enc: json.NewEncoder(w), format: format}
+	if f, ok := w.(http.Flusher); ok {
+		s.flusher = f
+	}
+	switch format {
+	case streamJSON:
+		_, _ = io.WriteString(w, "[")
+	case streamCSV:
+		s.csv = csv.NewWriter(w)
+		_ = s.csv.Write(csvHeader)
+	}
+	return s
+}
+
+// Write encodes a single entry.
+func (s *entryStream) Write(e Entry) error {
+	switch s.format {
+	case streamCSV:
+		if err := s.csv.Write(csvRecord(e)); err != nil {
+			return err
+		}
+	case streamJSON:
+		if s.n > 0 {
+			if _, err := io.WriteString(s.w, ","); err != nil {
+				return err
+			}
+		}
+		fallthrough
+	default:
+		if err := s.enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	s.n++
+	if s.n%streamFlushEvery == 0 {
+		s.flush()
+	}
+	return nil
+}
+
+// Close terminates the stream and flushes any buffered output.
+func (s *entryStream) Close() error {
+	if s.format == streamJSON {
+		if _, err := io.WriteString(s.w, "]\n"); err != nil {
+			return err
+		}
+	}
+	s.flush()
+	if s.csv != nil {
+		return s.csv.Error()
+	}
+	return nil
+}
+
+func (s *entryStream) flush() {
+	if s.csv != nil {
+		s.csv.Flush()
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+}
+
+func csvRecord(e Entry) []string {
+	var recoveredAt string
+	if e.RecoveredAt != nil {
+		recoveredAt = e.RecoveredAt.Format(time.RFC3339)
+	}
+	return []string{
+		e.DLQID, e.OriginalSubject, e.Reason, e.ReasonDetail, e.Source,
+		e.FailedAt.Format(time.RFC3339), strconv.Itoa(e.RetryCount), strconv.Itoa(e.MaxRetries),
+		strconv.FormatBool(e.Recoverable), strconv.FormatBool(e.Recovered),
+		recoveredAt, e.Status, e.RecoveredBy, string(e.OriginalPayload),
+	}
+}
+
+// writeEntries streams entries in the given format.
+func writeEntries(w http.ResponseWriter, status int, format streamFormat, entries []Entry) {
+	s := newEntryStream(w, status, format)
+	for _, e := range entries {
+		if err := s.Write(e); err != nil {
+			return
+		}
+	}
+	_ = s.Close()
+}
@@ -0,0 +1,133 @@
+package dlq
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEntryStream_JSONArray(t *testing.T) {
+	w := httptest.NewRecorder()
+	var entries []Entry
+	for i := 0; i < streamFlushEvery+5; i++ {
+		entries = append(entries, Entry{DLQID: fmt.Sprintf("s-%d", i), RetryHistory: []RetryAttempt{}})
+	}
+	writeEntries(w, http.StatusOK, streamJSON, entries)
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %s", ct)
+	}
+	if !w.Flushed {
+		t.Error("expected response to be flushed")
+	}
+
+	var decoded []Entry
+	if err := json.NewDecoder(w.Body).Decode(&decoded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(decoded) != len(entries) {
+		t.Errorf("expected %d entries, got %d", len(entries), len(decoded))
+	}
+	if decoded[len(decoded)-1].DLQID != entries[len(entries)-1].DLQID {
+		t.Errorf("expected last entry %s, got %s", entries[len(entries)-1].DLQID, decoded[len(decoded)-1].DLQID)
+	}
+}
+
+func TestEntryStream_EmptyArray(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeEntries(w, http.StatusOK, streamJSON, nil)
+
+	if got := strings.TrimSpace(w.Body.String()); got != "[]" {
+		t.Errorf("expected [], got %q", got)
+	}
+}
+
+func TestEntryStream_NDJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	s := newEntryStream(w, http.StatusOK, streamNDJSON)
+	_ = s.Write(Entry{DLQID: "n-1"})
+	_ = s.Write(Entry{DLQID: "n-2"})
+	_ = s.Close()
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected application/x-ndjson, got %s", ct)
+	}
+
+	sc := bufio.NewScanner(w.Body)
+	var ids []string
+	for sc.Scan() {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("line %d: %v", len(ids)+1, err)
+		}
+		ids = append(ids, e.DLQID)
+	}
+	if len(ids) != 2 || ids[0] != "n-1" || ids[1] != "n-2" {
+		t.Errorf("expected [n-1 n-2], got %v", ids)
+	}
+}
+
+func TestEntryStream_CSV(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeEntries(w, http.StatusOK, streamCSV, []Entry{
+		{DLQID: "c-1", Reason: ReasonNoCapableAgent, ReasonDetail: "no agent, at all", OriginalPayload: json.RawMessage(`{"a":1}`)},
+	})
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("expected text/csv, got %s", ct)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected header and 1 row, got %d records", len(records))
+	}
+	if records[0][0] != "dlq_id" || records[1][0] != "c-1" || records[1][3] != "no agent, at all" {
+		t.Errorf("unexpected records: %v", records)
+	}
+	if records[1][len(records[1])-1] != `{"a":1}` {
+		t.Errorf("expected payload column, got %q", records[1][len(records[1])-1])
+	}
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := map[string]streamFormat{
+		"":                                streamJSON,
+		"application/json":                streamJSON,
+		"application/x-ndjson":            streamNDJSON,
+		"text/csv; charset=utf-8":         streamCSV,
+		"text/html, application/x-ndjson": streamNDJSON,
+		"*/*":                             streamJSON,
+	}
+	for accept, want := range tests {
+		r := httptest.NewRequest("GET", "/dlq/", nil)
+		r.Header.Set("Accept", accept)
+		if got := negotiateFormat(r); got != want {
+			t.Errorf("Accept %q: expected %d, got %d", accept, want, got)
+		}
+	}
+}
+
+func TestHandler_List_NDJSON(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "nd-1"}, Entry{DLQID: "nd-2"})
+	r := newTestRouter(store, newMockNATS())
+
+	req := httptest.NewRequest("GET", "/dlq/", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected application/x-ndjson, got %s", ct)
+	}
+	if lines := strings.Count(w.Body.String(), "\n"); lines != 2 {
+		t.Errorf("expected 2 lines, got %d", lines)
+	}
+}
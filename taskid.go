@@ -0,0 +1,43 @@
+package dlq
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// DefaultTaskIDFields are the payload fields the Processor reads
+// Entry.TaskID from unless WithProcessorTaskIDFields says otherwise.
+var DefaultTaskIDFields = []string{"task_id"}
+
+// WithProcessorTaskIDFields sets the top-level payload fields, in order of
+// preference, that the Processor copies into Entry.TaskID (e.g. "task_id",
+// "agent_id"). With no fields nothing is extracted.
+func WithProcessorTaskIDFields(fields ...string) ProcessorOption {
+	return func(p *Processor) { p.taskIDFields = fields }
+}
+
+// extractTaskID returns the first non-empty string or number among the
+// top-level payload fields, or "" if there is none or the payload is not a
+// JSON object.
+func extractTaskID(payload json.RawMessage, fields []string) string {
+	if len(fields) == 0 || len(payload) == 0 {
+		return ""
+	}
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return ""
+	}
+	for _, f := range fields {
+		switch v := doc[f].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case json.Number:
+			return v.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExtractTaskID(t *testing.T) {
+	tests := []struct {
+		payload string
+		fields  []string
+		want    string
+	}{
+		{`{"task_id":"t1"}`, DefaultTaskIDFields, "t1"},
+		{`{"task_id":12345678901234567890}`, DefaultTaskIDFields, "12345678901234567890"},
+		{`{"task_id":"","agent_id":"a1"}`, []string{"task_id", "agent_id"}, "a1"},
+		{`{"task_id":{"nested":true}}`, DefaultTaskIDFields, ""},
+		{`{"task_id":"t1"}`, nil, ""},
+		{`["t1"]`, DefaultTaskIDFields, ""},
+		{``, DefaultTaskIDFields, ""},
+	}
+	for _, tt := range tests {
+		if got := extractTaskID(json.RawMessage(tt.payload), tt.fields); got != tt.want {
+			t.Errorf("extractTaskID(%s, %v) = %q, want %q", tt.payload, tt.fields, got, tt.want)
+		}
+	}
+}
+
+func TestProcessor_ExtractsTaskID(t *testing.T) {
+	store := newMockStore()
+	ctx := context.Background()
+	NewProcessor(store).Process(ctx, "dlq.task.unassignable",
+		[]byte(`{"dlq_id":"tid-1","original_subject":"swarm.task.request","original_payload":{"task_id":"t1"},"failed_at":"2026-01-01T00:00:00Z"}`))
+	NewProcessor(store, WithProcessorTaskIDFields("agent_id")).Process(ctx, "dlq.agent.crash_loop",
+		[]byte(`{"dlq_id":"tid-2","original_subject":"swarm.agent.boot","original_payload":{"task_id":"t1","agent_id":"scout"},"failed_at":"2026-01-01T00:00:00Z"}`))
+	NewProcessor(store).Process(ctx, "dlq.task.unassignable",
+		[]byte(`{"dlq_id":"tid-3","original_subject":"swarm.task.request","original_payload":{"task_id":"t1"},"task_id":"t9","failed_at":"2026-01-01T00:00:00Z"}`))
+
+	for id, want := range map[string]string{"tid-1": "t1", "tid-2": "scout", "tid-3": "t9"} {
+		if e, _ := store.Get(ctx, id); e == nil || e.TaskID != want {
+			t.Errorf("%s: expected task_id %q, got %+v", id, want, e)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	newTestRouter(store, newMockNATS()).ServeHTTP(w, httptest.NewRequest("GET", "/dlq/?task_id=t1", nil))
+	var entries []Entry
+	_ = json.Unmarshal(w.Body.Bytes(), &entries)
+	if len(entries) != 1 || entries[0].DLQID != "tid-1" {
+		t.Errorf("expected only tid-1 for task t1, got %+v", entries)
+	}
+}
@@ -0,0 +1,62 @@
+package dlq
+
+import (
+	"context"
+	"fmt"
+)
+
+// TaskStatus is a task's state as reported by Dispatch.
+type TaskStatus string
+
+// Task states understood by the retry paths. Any other value, including
+// TaskStatusUnknown, lets the retry go ahead.
+const (
+	TaskStatusUnknown   TaskStatus = ""
+	TaskStatusCompleted TaskStatus = "completed"
+	TaskStatusCancelled TaskStatus = "cancelled"
+)
+
+// finished reports whether re-driving a task in this state would be wasted
+// or harmful.
+func (s TaskStatus) finished() bool {
+	return s == TaskStatusCompleted || s == TaskStatusCancelled
+}
+
+// TaskStatusChecker is consulted before an entry is republished. If the task
+// the entry refers to is already completed or cancelled, the entry is marked
+// recovered with recovered_by=stale instead of being re-driven.
+type TaskStatusChecker interface {
+	TaskStatus(ctx context.Context, e Entry) (TaskStatus, error)
+}
+
+// TaskStatusCheckerFunc adapts a function to TaskStatusChecker.
+type TaskStatusCheckerFunc func(ctx context.Context, e Entry) (TaskStatus, error)
+
+// TaskStatus calls f.
+func (f TaskStatusCheckerFunc) TaskStatus(ctx context.Context, e Entry) (TaskStatus, error) {
+	return f(ctx, e)
+}
+
+// staleTask reports whether c says e's task no longer needs retrying.
+// A nil checker never reports a task as stale. Errors are returned so callers
+// hold the entry back rather than risk re-executing a finished task.
+func staleTask(ctx context.Context, c TaskStatusChecker, e Entry) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+	status, err := c.TaskStatus(ctx, e)
+	if err != nil {
+		return false, fmt.Errorf("check task status for %s: %w", e.DLQID, err)
+	}
+	return status.finished(), nil
+}
+
+// markStale records that e no longer needs retrying because its task has
+// already finished.
+func markStale(ctx context.Context, store Writer, nc NATSPublisher, e Entry) error {
+	if err := store.MarkRecovered(ctx, e.DLQID, RecoveredByStale); err != nil {
+		return err
+	}
+	publishRecovered(ctx, nc, e, RecoveredByStale)
+	return nil
+}
@@ -0,0 +1,98 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// statusByTask reports the status of the task_id in each entry's payload.
+func statusByTask(statuses map[string]TaskStatus) TaskStatusChecker {
+	return TaskStatusCheckerFunc(func(_ context.Context, e Entry) (TaskStatus, error) {
+		var p struct {
+			TaskID string `json:"task_id"`
+		}
+		_ = json.Unmarshal(e.OriginalPayload, &p)
+		return statuses[p.TaskID], nil
+	})
+}
+
+func TestHandler_Retry_StaleTask(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(Entry{DLQID: "st-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{"task_id":"done"}`)})
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, nc, WithTaskStatusChecker(statusByTask(map[string]TaskStatus{"done": TaskStatusCompleted}))).Routes())
+
+	req := httptest.NewRequest("POST", "/dlq/st-1/retry", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var body map[string]string
+	_ = json.NewDecoder(w.Body).Decode(&body)
+	if body["status"] != "stale" {
+		t.Errorf("expected stale status, got %v", body)
+	}
+	if len(nc.published()) != 0 {
+		t.Error("a finished task must not be republished")
+	}
+	e, _ := store.Get(context.Background(), "st-1")
+	if !e.Recovered || e.RecoveredBy != RecoveredByStale {
+		t.Errorf("expected recovered by stale, got %+v", e)
+	}
+}
+
+func TestHandler_Retry_TaskStatusError(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(Entry{DLQID: "st-2", OriginalSubject: "swarm.task.request"})
+	failing := TaskStatusCheckerFunc(func(context.Context, Entry) (TaskStatus, error) {
+		return TaskStatusUnknown, fmt.Errorf("dispatch unreachable")
+	})
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, nc, WithTaskStatusChecker(failing)).Routes())
+
+	req := httptest.NewRequest("POST", "/dlq/st-2/retry", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected 502, got %d", w.Code)
+	}
+	if len(nc.published()) != 0 {
+		t.Error("expected nothing republished")
+	}
+}
+
+func TestScanner_StaleTasks(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(
+		Entry{DLQID: "st-3", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{"task_id":"cancelled"}`), Recoverable: true},
+		Entry{DLQID: "st-4", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{"task_id":"pending"}`), Recoverable: true},
+	)
+	checker := statusByTask(map[string]TaskStatus{"cancelled": TaskStatusCancelled, "pending": "pending"})
+
+	NewScanner(store, nc, time.Minute, WithScannerTaskStatusChecker(checker)).scan(context.Background())
+
+	if n := len(nc.published()); n != 1 {
+		t.Errorf("expected only the pending task to be republished, got %d", n)
+	}
+	if e, _ := store.Get(context.Background(), "st-3"); e.RecoveredBy != RecoveredByStale {
+		t.Errorf("expected st-3 recovered by stale, got %q", e.RecoveredBy)
+	}
+	var ev ScanSummaryEvent
+	_ = json.Unmarshal(nc.events(SubjectScanSummary)[0].Data, &ev)
+	if ev.Stale != 1 || ev.Retried != 1 {
+		t.Errorf("unexpected summary: %+v", ev)
+	}
+}
@@ -0,0 +1,107 @@
+package dlq
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// tenantKey is the context key for the tenant set by ContextWithTenant.
+type tenantKey struct{}
+
+// ContextWithTenant returns a copy of ctx scoped to tenant, for swarm
+// environments that share one database. Store calls made with it only see
+// and change that tenant's entries, and entries inserted without a TenantID
+// are stamped with it. An empty tenant leaves ctx unscoped.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	if tenant == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant set by ContextWithTenant, or "".
+func TenantFromContext(ctx context.Context) string {
+	t, _ := ctx.Value(tenantKey{}).(string)
+	return t
+}
+
+// WithStoreTenant scopes the Store to tenant for calls whose context carries
+// no tenant of its own (see ContextWithTenant). Without it and without a
+// context tenant, queries span every tenant.
+func WithStoreTenant(tenant string) StoreOption {
+	return func(s *Store) { s.tenantID = tenant }
+}
+
+// tenant returns the tenant queries made with ctx are scoped to; "" means
+// every tenant.
+func (s *Store) tenant(ctx context.Context) string {
+	if t := TenantFromContext(ctx); t != "" {
+		return t
+	}
+	return s.tenantID
+}
+
+// tenantClause restricts a query to the tenant bound to placeholder $n. An
+// empty tenant matches every row.
+func tenantClause(n int) string {
+	return fmt.Sprintf("($%d::text = '' OR tenant_id = $%d)", n, n)
+}
+
+// tenantFilter is listFilter restricted to the tenant of ctx.
+func (s *Store) tenantFilter(ctx context.Context, opts ListOpts) (string, []any) {
+	where, args := listFilter(opts)
+	args = append(args, s.tenant(ctx))
+	return where + " AND " + tenantClause(len(args)), args
+}
+
+// WithPublisherTenant stamps tenant on every entry published without a
+// context tenant (see ContextWithTenant).
+func WithPublisherTenant(tenant string) PublisherOption {
+	return func(p *Publisher) { p.tenant = tenant }
+}
+
+// WithProcessorTenant stores entries that arrive without a tenant_id under
+// tenant, for a Processor consuming one environment's DLQ subjects.
+func WithProcessorTenant(tenant string) ProcessorOption {
+	return func(p *Processor) { p.tenant = tenant }
+}
+
+// WithTenant scopes every request to tenant unless the request carries one
+// already, via ContextWithTenant in an outer middleware, the authenticated
+// Principal or WithTenantFunc.
+func WithTenant(tenant string) HandlerOption {
+	return func(h *Handler) { h.tenant = tenant }
+}
+
+// WithTenantFunc derives the tenant of requests whose Principal has none
+// with fn, e.g. from a header set by a gateway. An empty result falls back to
+// WithTenant.
+func WithTenantFunc(fn func(*http.Request) string) HandlerOption {
+	return func(h *Handler) { h.tenantFunc = fn }
+}
+
+// scopeTenant puts the tenant of the request into its context so every store
+// call it makes is scoped to it.
+func (h *Handler) scopeTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(ContextWithTenant(r.Context(), h.requestTenant(r))))
+	})
+}
+
+// requestTenant resolves the tenant of r: an existing context tenant, then
+// the Principal, then WithTenantFunc, then WithTenant.
+func (h *Handler) requestTenant(r *http.Request) string {
+	if t := TenantFromContext(r.Context()); t != "" {
+		return t
+	}
+	if p, ok := PrincipalFromContext(r.Context()); ok && p.Tenant != "" {
+		return p.Tenant
+	}
+	if h.tenantFunc != nil {
+		if t := h.tenantFunc(r); t != "" {
+			return t
+		}
+	}
+	return h.tenant
+}
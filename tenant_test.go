@@ -0,0 +1,72 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProcessor_Tenant(t *testing.T) {
+	store := newMockStore()
+	ctx := context.Background()
+	p := NewProcessor(store, WithProcessorTenant("staging"))
+	p.Process(ctx, "dlq.task.unassignable", []byte(`{"dlq_id":"ten-1","original_subject":"swarm.task.request","failed_at":"2026-01-01T00:00:00Z"}`))
+	p.Process(ctx, "dlq.task.unassignable", []byte(`{"dlq_id":"ten-2","original_subject":"swarm.task.request","tenant_id":"prod","failed_at":"2026-01-01T00:00:00Z"}`))
+
+	for id, want := range map[string]string{"ten-1": "staging", "ten-2": "prod"} {
+		if e, _ := store.Get(ctx, id); e == nil || e.TenantID != want {
+			t.Errorf("%s: expected tenant %q, got %+v", id, want, e)
+		}
+	}
+	if e, _ := store.Get(ContextWithTenant(ctx, "staging"), "ten-2"); e != nil {
+		t.Errorf("another tenant's entry should not be visible, got %+v", e)
+	}
+}
+
+func TestHandler_Tenant(t *testing.T) {
+	store := newMockStore()
+	store.seed(
+		Entry{DLQID: "ten-a", Reason: "agent_crash", TenantID: "prod"},
+		Entry{DLQID: "ten-b", Reason: "agent_crash", TenantID: "staging"},
+	)
+	r := authRouter(store, WithTenant("prod"), WithTenantFunc(func(r *http.Request) string {
+		return r.Header.Get("X-Swarm-Tenant")
+	}))
+
+	list := func(tenant string) []Entry {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/dlq/", nil)
+		if tenant != "" {
+			req.Header.Set("X-Swarm-Tenant", tenant)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var entries []Entry
+		if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+			t.Fatalf("list: %d %s", w.Code, w.Body)
+		}
+		return entries
+	}
+	if got := list(""); len(got) != 1 || got[0].DLQID != "ten-a" {
+		t.Errorf("expected the configured tenant's entry only, got %+v", got)
+	}
+	if got := list("staging"); len(got) != 1 || got[0].DLQID != "ten-b" {
+		t.Errorf("expected the request tenant's entry only, got %+v", got)
+	}
+
+	if w := doWithKey(r, "GET", "/dlq/ten-b", ""); w.Code != http.StatusNotFound {
+		t.Errorf("another tenant's entry: expected 404, got %d", w.Code)
+	}
+
+	keyed := authRouter(store,
+		WithAuthMiddleware(APIKeyAuth(map[string]Principal{"k1": {Subject: "ops", Tenant: "staging"}})),
+		WithTenantFunc(func(*http.Request) string { return "prod" }))
+	if w := doWithKey(keyed, "GET", "/dlq/ten-b", "k1"); w.Code != http.StatusOK {
+		t.Errorf("principal tenant: expected 200, got %d", w.Code)
+	}
+	if w := doWithKey(keyed, "GET", "/dlq/ten-a", "k1"); w.Code != http.StatusNotFound {
+		t.Errorf("principal tenant should win over WithTenantFunc: expected 404, got %d", w.Code)
+	}
+}
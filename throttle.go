@@ -0,0 +1,68 @@
+package dlq
+
+import (
+	"sync"
+	"time"
+)
+
+// throttleWindow is the length of the per-subject republish window.
+const throttleWindow = time.Minute
+
+// SubjectThrottle caps how many entries per minute may be republished to each
+// original subject, so re-driving a backlog never exceeds the known capacity
+// of a consumer. Subjects without a limit are not throttled. A single
+// SubjectThrottle may be shared by the Scanner and the Handler so that both
+// count against the same budget.
+type SubjectThrottle struct {
+	mu      sync.Mutex
+	limits  map[string]int
+	windows map[string]*throttleCount
+	now     func() time.Time
+}
+
+type throttleCount struct {
+	start time.Time
+	count int
+}
+
+// NewSubjectThrottle creates a throttle from a map of original subject to
+// maximum republishes per minute.
+func NewSubjectThrottle(perMinute map[string]int) *SubjectThrottle {
+	limits := make(map[string]int, len(perMinute))
+	for subject, n := range perMinute {
+		limits[subject] = n
+	}
+	return &SubjectThrottle{
+		limits:  limits,
+		windows: make(map[string]*throttleCount),
+		now:     time.Now,
+	}
+}
+
+// Allow reports whether one more entry may be republished to subject now,
+// and if so counts it against the current window. A nil *SubjectThrottle
+// allows everything.
+func (t *SubjectThrottle) Allow(subject string) bool {
+	if t == nil {
+		return true
+	}
+	limit, ok := t.limits[subject]
+	if !ok || limit <= 0 {
+		return true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	w := t.windows[subject]
+	if w == nil || !now.Before(w.start.Add(throttleWindow)) {
+		w = &throttleCount{start: now}
+		t.windows[subject] = w
+	}
+	if w.count >= limit {
+		return false
+	}
+	w.count++
+	return true
+}
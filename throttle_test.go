@@ -0,0 +1,93 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestSubjectThrottle_Allow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	th := NewSubjectThrottle(map[string]int{"swarm.task.request": 2})
+	th.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if !th.Allow("swarm.task.request") {
+			t.Fatalf("attempt %d should be allowed", i)
+		}
+	}
+	if th.Allow("swarm.task.request") {
+		t.Error("third attempt within the minute should be throttled")
+	}
+	if !th.Allow("other.subject") {
+		t.Error("subjects without a limit should not be throttled")
+	}
+
+	now = now.Add(throttleWindow)
+	if !th.Allow("swarm.task.request") {
+		t.Error("a new window should reset the count")
+	}
+
+	var nilThrottle *SubjectThrottle
+	if !nilThrottle.Allow("swarm.task.request") {
+		t.Error("nil throttle should allow everything")
+	}
+}
+
+func TestScanner_Scan_Throttled(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	for i := 0; i < 5; i++ {
+		store.seed(Entry{DLQID: fmt.Sprintf("th-%d", i), OriginalSubject: "swarm.task.request", Recoverable: true})
+	}
+	store.seed(Entry{DLQID: "th-other", OriginalSubject: "other.subject", Recoverable: true})
+
+	th := NewSubjectThrottle(map[string]int{"swarm.task.request": 3})
+	NewScanner(store, nc, time.Minute, WithScannerThrottle(th)).scan(context.Background())
+
+	if n := len(nc.published()); n != 4 {
+		t.Errorf("expected 3 throttled-subject + 1 other publishes, got %d", n)
+	}
+	recovered := 0
+	for i := 0; i < 5; i++ {
+		if e, _ := store.Get(context.Background(), fmt.Sprintf("th-%d", i)); e.Recovered {
+			recovered++
+		}
+	}
+	if recovered != 3 {
+		t.Errorf("expected 3 recovered, got %d", recovered)
+	}
+}
+
+func TestHandler_RetryAll_Throttled(t *testing.T) {
+	store := newMockStore()
+	nc := newMockNATS()
+	for i := 0; i < 10; i++ {
+		store.seed(Entry{DLQID: fmt.Sprintf("th-%d", i), OriginalSubject: "swarm.task.request", Recoverable: true})
+	}
+
+	th := NewSubjectThrottle(map[string]int{"swarm.task.request": 4})
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, nc, WithRetryAllConcurrency(3), WithRetryAllThrottle(th)).Routes())
+
+	req := httptest.NewRequest("POST", "/dlq/retry-all", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body map[string]any
+	_ = json.NewDecoder(w.Body).Decode(&body)
+	if retried := int(body["retried"].(float64)); retried != 4 {
+		t.Errorf("expected 4 retried, got %d", retried)
+	}
+	if throttled := int(body["throttled"].(float64)); throttled != 6 {
+		t.Errorf("expected 6 throttled, got %d", throttled)
+	}
+	if n := len(nc.published()); n != 4 {
+		t.Errorf("expected 4 published messages, got %d", n)
+	}
+}
@@ -0,0 +1,213 @@
+package dlq
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Timeseries defaults and bounds for GET /stats/timeseries.
+const (
+	DefaultTimeseriesWindow = 24 * time.Hour
+	DefaultTimeseriesBucket = time.Hour
+	MaxTimeseriesBuckets    = 1000
+)
+
+// timeseriesGroups maps the group_by values of GET /stats/timeseries to
+// columns.
+var timeseriesGroups = map[string]string{
+	"":       "''",
+	"reason": "reason",
+	"source": "source",
+}
+
+// TimeseriesOpts selects the range and resolution of a Timeseries. Buckets
+// are aligned to multiples of Bucket since the Unix epoch, so the same
+// bucket always covers the same interval.
+type TimeseriesOpts struct {
+	Window  time.Duration
+	Bucket  time.Duration
+	GroupBy string // "", "reason" or "source"
+}
+
+// validate fills in defaults and checks that opts describes a bounded
+// series.
+func (o *TimeseriesOpts) validate() error {
+	if o.Window == 0 {
+		o.Window = DefaultTimeseriesWindow
+	}
+	if o.Bucket == 0 {
+		o.Bucket = DefaultTimeseriesBucket
+	}
+	switch {
+	case o.Window < 0 || o.Bucket < time.Second || o.Bucket%time.Second != 0:
+		return fmt.Errorf("window must be positive and bucket a whole number of seconds")
+	case o.Window/o.Bucket > MaxTimeseriesBuckets:
+		return fmt.Errorf("window/bucket must not exceed %d buckets", MaxTimeseriesBuckets)
+	}
+	if _, ok := timeseriesGroups[o.GroupBy]; !ok {
+		return fmt.Errorf("group_by must be reason or source")
+	}
+	return nil
+}
+
+// Timeseries is DLQ inflow (entries failing) and outflow (entries being
+// recovered) per bucket, one series per group.
+type Timeseries struct {
+	Window  string             `json:"window"`
+	Bucket  string             `json:"bucket"`
+	GroupBy string             `json:"group_by,omitempty"`
+	Series  []TimeseriesSeries `json:"series"`
+}
+
+// TimeseriesSeries holds every bucket of the window for one group, zeros
+// included. Group is empty when the series is not grouped.
+type TimeseriesSeries struct {
+	Group  string            `json:"group,omitempty"`
+	Points []TimeseriesPoint `json:"points"`
+}
+
+// TimeseriesPoint counts entries that failed and were recovered in the
+// bucket starting at Start.
+type TimeseriesPoint struct {
+	Start     time.Time `json:"start"`
+	Ingested  int       `json:"ingested"`
+	Recovered int       `json:"recovered"`
+}
+
+// timeseriesRow is one non-empty (bucket, group) cell.
+type timeseriesRow struct {
+	start     time.Time
+	group     string
+	ingested  int
+	recovered int
+}
+
+// bucketStart returns the start of the epoch-aligned bucket containing t.
+func bucketStart(t time.Time, bucket time.Duration) time.Time {
+	sec := int64(bucket / time.Second)
+	u := t.Unix()
+	if u < 0 && u%sec != 0 {
+		u -= sec
+	}
+	return time.Unix(u/sec*sec, 0).UTC()
+}
+
+// buildTimeseries lays rows out on the buckets covering [now-Window, now],
+// filling the gaps with zeros so charts need no client-side padding.
+func buildTimeseries(opts TimeseriesOpts, now time.Time, rows []timeseriesRow) *Timeseries {
+	first := bucketStart(now.Add(-opts.Window), opts.Bucket)
+	n := int(bucketStart(now, opts.Bucket).Sub(first)/opts.Bucket) + 1
+
+	series := map[string][]TimeseriesPoint{}
+	points := func(group string) []TimeseriesPoint {
+		if p, ok := series[group]; ok {
+			return p
+		}
+		p := make([]TimeseriesPoint, n)
+		for i := range p {
+			p[i].Start = first.Add(time.Duration(i) * opts.Bucket)
+		}
+		series[group] = p
+		return p
+	}
+	if opts.GroupBy == "" {
+		points("")
+	}
+	for _, r := range rows {
+		i := int(r.start.Sub(first) / opts.Bucket)
+		if i < 0 || i >= n {
+			continue
+		}
+		p := points(r.group)
+		p[i].Ingested += r.ingested
+		p[i].Recovered += r.recovered
+	}
+
+	ts := &Timeseries{
+		Window: opts.Window.String(), Bucket: opts.Bucket.String(), GroupBy: opts.GroupBy,
+		Series: []TimeseriesSeries{},
+	}
+	for group, p := range series {
+		ts.Series = append(ts.Series, TimeseriesSeries{Group: group, Points: p})
+	}
+	sort.Slice(ts.Series, func(i, j int) bool { return ts.Series[i].Group < ts.Series[j].Group })
+	return ts
+}
+
+// Timeseries counts entries failing and being recovered per bucket over the
+// last opts.Window.
+func (s *Store) Timeseries(ctx context.Context, opts TimeseriesOpts) (_ *Timeseries, err error) {
+	defer s.observe("timeseries", time.Now(), &err)
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	since := bucketStart(now.Add(-opts.Window), opts.Bucket)
+	group := timeseriesGroups[opts.GroupBy]
+
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`
+		WITH events AS (
+			SELECT failed_at AS at, %[1]s AS grp, 1 AS ingested, 0 AS recovered
+			FROM swarm_dlq WHERE failed_at >= $1 AND `+tenantClause(3)+`
+			UNION ALL
+			SELECT recovered_at, %[1]s, 0, 1
+			FROM swarm_dlq WHERE recovered_at >= $1 AND `+tenantClause(3)+`
+		)
+		SELECT to_timestamp(floor(extract(epoch FROM at) / $2) * $2), grp, sum(ingested), sum(recovered)
+		FROM events
+		GROUP BY 1, 2
+	`, group), since, int64(opts.Bucket/time.Second), s.tenant(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("timeseries: %w", err)
+	}
+	defer rows.Close()
+
+	var cells []timeseriesRow
+	for rows.Next() {
+		var c timeseriesRow
+		if err := rows.Scan(&c.start, &c.group, &c.ingested, &c.recovered); err != nil {
+			return nil, fmt.Errorf("timeseries: %w", err)
+		}
+		cells = append(cells, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("timeseries: %w", err)
+	}
+	return buildTimeseries(opts, now, cells), nil
+}
+
+// handleTimeseries serves GET /stats/timeseries?window=24h&bucket=1h&group_by=reason.
+func (h *Handler) handleTimeseries(w http.ResponseWriter, r *http.Request) {
+	var opts TimeseriesOpts
+	q := r.URL.Query()
+	for _, p := range []struct {
+		name string
+		dst  *time.Duration
+	}{{"window", &opts.Window}, {"bucket", &opts.Bucket}} {
+		if v := q.Get(p.name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": p.name + " must be a positive duration such as 24h"})
+				return
+			}
+			*p.dst = d
+		}
+	}
+	opts.GroupBy = q.Get("group_by")
+	if err := opts.validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ts, err := h.store.Timeseries(r.Context(), opts)
+	if err != nil {
+		slog.Error("dlq timeseries failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, ts)
+}
@@ -0,0 +1,103 @@
+package dlq
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBuildTimeseries(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+	opts := TimeseriesOpts{Window: 3 * time.Hour, Bucket: time.Hour}
+	ts := buildTimeseries(opts, now, []timeseriesRow{
+		{start: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), ingested: 2},
+		{start: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), ingested: 1, recovered: 3},
+		{start: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), ingested: 9},
+	})
+
+	if len(ts.Series) != 1 {
+		t.Fatalf("expected one ungrouped series, got %+v", ts.Series)
+	}
+	p := ts.Series[0].Points
+	if len(p) != 4 || !p[0].Start.Equal(time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected 4 hourly buckets from 09:00, got %+v", p)
+	}
+	want := []TimeseriesPoint{{Ingested: 0}, {Ingested: 2}, {}, {Ingested: 1, Recovered: 3}}
+	for i := range want {
+		if p[i].Ingested != want[i].Ingested || p[i].Recovered != want[i].Recovered {
+			t.Errorf("bucket %d: got %+v, want %+v", i, p[i], want[i])
+		}
+	}
+
+	grouped := buildTimeseries(TimeseriesOpts{Window: time.Hour, Bucket: time.Hour, GroupBy: "reason"}, now, []timeseriesRow{
+		{start: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), group: "b", ingested: 1},
+		{start: time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC), group: "a", recovered: 1},
+	})
+	if len(grouped.Series) != 2 || grouped.Series[0].Group != "a" || len(grouped.Series[1].Points) != 2 {
+		t.Errorf("expected zero-filled series a and b, got %+v", grouped.Series)
+	}
+}
+
+func TestTimeseriesOpts_Validate(t *testing.T) {
+	var opts TimeseriesOpts
+	if err := opts.validate(); err != nil || opts.Window != DefaultTimeseriesWindow || opts.Bucket != DefaultTimeseriesBucket {
+		t.Errorf("expected defaults, got %+v %v", opts, err)
+	}
+	for _, bad := range []TimeseriesOpts{
+		{Window: time.Hour, Bucket: 500 * time.Millisecond},
+		{Window: time.Hour, Bucket: 1500 * time.Millisecond},
+		{Window: 30 * 24 * time.Hour, Bucket: time.Minute},
+		{Window: time.Hour, Bucket: time.Minute, GroupBy: "status"},
+	} {
+		if err := bad.validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestHandler_Timeseries(t *testing.T) {
+	store := newMockStore()
+	now := time.Now().UTC()
+	recovered := now.Add(-10 * time.Minute)
+	store.seed(
+		Entry{DLQID: "ts1", Reason: ReasonBootFailure, Source: SourceWarren, FailedAt: now.Add(-2 * time.Hour)},
+		Entry{DLQID: "ts2", Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: now.Add(-time.Hour),
+			Recovered: true, RecoveredAt: &recovered},
+		Entry{DLQID: "ts3", Reason: ReasonNoCapableAgent, Source: SourceDispatch, FailedAt: now.Add(-48 * time.Hour)},
+	)
+	r := newTestRouter(store, newMockNATS())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/stats/timeseries?window=6h&bucket=30m&group_by=reason", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var ts Timeseries
+	_ = json.NewDecoder(w.Body).Decode(&ts)
+	if ts.GroupBy != "reason" || len(ts.Series) != 2 {
+		t.Fatalf("expected a series per reason, got %+v", ts)
+	}
+	totals := map[string][2]int{}
+	for _, s := range ts.Series {
+		if len(s.Points) != 13 {
+			t.Errorf("expected 13 half-hour buckets in 6h, got %d", len(s.Points))
+		}
+		for _, p := range s.Points {
+			c := totals[s.Group]
+			totals[s.Group] = [2]int{c[0] + p.Ingested, c[1] + p.Recovered}
+		}
+	}
+	if totals[ReasonBootFailure] != [2]int{1, 0} || totals[ReasonNoCapableAgent] != [2]int{1, 1} {
+		t.Errorf("unexpected totals (entries outside the window must be left out): %v", totals)
+	}
+
+	for _, q := range []string{"window=abc", "bucket=-1h", "group_by=agent", "window=720h&bucket=1m"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/dlq/stats/timeseries?"+q, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package dlq
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Spans are created with the global TracerProvider and trace context is
+// propagated with the global TextMapPropagator (otel.SetTracerProvider,
+// otel.SetTextMapPropagator). Both are no-ops until the hosting service
+// installs an OpenTelemetry SDK.
+const tracerName = "github.com/MikeSquared-Agency/swarm-dlq"
+
+// Span attributes set by the DLQ components.
+const (
+	AttrDLQID           = attribute.Key("dlq.id")
+	AttrDLQReason       = attribute.Key("dlq.reason")
+	AttrDLQSource       = attribute.Key("dlq.source")
+	AttrOriginalSubject = attribute.Key("dlq.original_subject")
+)
+
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// NATSMsgPublisher publishes messages with headers. *nats.Conn satisfies it;
+// when the NATSPublisher given to a component also implements it, republished
+// messages carry the trace context so consumers can continue the trace.
+type NATSMsgPublisher interface {
+	PublishMsg(msg *nats.Msg) error
+}
+
+// natsMsgRequester sends requests with headers. *nats.Conn satisfies it.
+type natsMsgRequester interface {
+	RequestMsg(msg *nats.Msg, timeout time.Duration) (*nats.Msg, error)
+}
+
+// tracedMsg returns a message for subject with headers hdr (which may be nil)
+// plus ctx's trace context.
+func tracedMsg(ctx context.Context, subject string, data []byte, hdr nats.Header) *nats.Msg {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	for k, vs := range hdr {
+		msg.Header[k] = vs
+	}
+	otel.GetTextMapPropagator().Inject(ctx, natsHeaderCarrier(msg.Header))
+	return msg
+}
+
+// natsHeaderCarrier adapts nats.Header to propagation.TextMapCarrier. Unlike
+// propagation.HeaderCarrier it keeps keys as given (e.g. "traceparent"), since
+// NATS headers are case-sensitive.
+type natsHeaderCarrier nats.Header
+
+func (c natsHeaderCarrier) Get(key string) string { return nats.Header(c).Get(key) }
+
+func (c natsHeaderCarrier) Set(key, value string) { nats.Header(c).Set(key, value) }
+
+func (c natsHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// startPublishSpan starts a producer span for a publish to subject.
+func startPublishSpan(ctx context.Context, subject string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, "dlq.publish "+subject,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(append(attrs, attribute.String("messaging.system", "nats"),
+			attribute.String("messaging.destination.name", subject))...),
+	)
+}
+
+// publishTraced publishes data to subject inside a producer span. When nc
+// supports headers the message carries hdr (which may be nil) and the trace
+// context; otherwise both are dropped.
+func publishTraced(ctx context.Context, nc NATSPublisher, subject string, data []byte, hdr nats.Header, attrs ...attribute.KeyValue) (err error) {
+	ctx, span := startPublishSpan(ctx, subject, attrs...)
+	defer func() { endSpan(span, err) }()
+	if mp, ok := nc.(NATSMsgPublisher); ok {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return mp.PublishMsg(tracedMsg(ctx, subject, data, hdr))
+	}
+	return publishContext(ctx, nc, subject, data)
+}
+
+// publishContext publishes data to subject, through PublishContext when nc
+// implements NATSContextPublisher. Otherwise ctx is only checked up front:
+// a core NATS publish buffers rather than blocks.
+func publishContext(ctx context.Context, nc NATSPublisher, subject string, data []byte) error {
+	if cp, ok := nc.(NATSContextPublisher); ok {
+		return cp.PublishContext(ctx, subject, data)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return nc.Publish(subject, data)
+}
+
+// traceRoutes starts a server span for every request, continuing any trace
+// context in the request headers. The span is named after the matched route
+// pattern once routing is done.
+func traceRoutes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer().Start(ctx, "dlq.http "+r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attribute.String("http.request.method", r.Method)),
+		)
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		if rc := chi.RouteContext(r.Context()); rc != nil && rc.RoutePattern() != "" {
+			span.SetName("dlq.http " + r.Method + " " + rc.RoutePattern())
+			span.SetAttributes(attribute.String("http.route", rc.RoutePattern()))
+		}
+		if id := chi.URLParam(r, "dlqID"); id != "" {
+			span.SetAttributes(AttrDLQID.String(id))
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", ww.Status()))
+		if ww.Status() >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(ww.Status()))
+		}
+	})
+}
+
+// QueryTracer is a pgx.QueryTracer that wraps every SQL statement run by the
+// Store in a client span. Install it on the pool configuration:
+//
+//	cfg.ConnConfig.Tracer = dlq.QueryTracer{}
+type QueryTracer struct{}
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = tracer().Start(ctx, "dlq.store.query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.query.text", data.SQL),
+		),
+	)
+	return ctx
+}
+
+// TraceQueryEnd implements pgx.QueryTracer.
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	endSpan(trace.SpanFromContext(ctx), data.Err)
+}
+
+var _ pgx.QueryTracer = QueryTracer{}
@@ -0,0 +1,138 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs an in-memory tracer provider and the W3C propagator
+// for the duration of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+	return rec
+}
+
+func spanNamed(rec *tracetest.SpanRecorder, prefix string) sdktrace.ReadOnlySpan {
+	for _, s := range rec.Ended() {
+		if strings.HasPrefix(s.Name(), prefix) {
+			return s
+		}
+	}
+	return nil
+}
+
+func hasAttr(s sdktrace.ReadOnlySpan, kv attribute.KeyValue) bool {
+	for _, a := range s.Attributes() {
+		if a == kv {
+			return true
+		}
+	}
+	return false
+}
+
+// msgNATS is a mockNATS that also accepts messages with headers.
+type msgNATS struct {
+	mockNATS
+	mu   sync.Mutex
+	msgs []*nats.Msg
+}
+
+func (m *msgNATS) PublishMsg(msg *nats.Msg) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.msgs = append(m.msgs, msg)
+	return nil
+}
+
+func TestRepublish_PropagatesTraceContext(t *testing.T) {
+	rec := recordSpans(t)
+	nc := &msgNATS{}
+
+	if err := republish(context.Background(), nc, nil, newMockStore(), Entry{DLQID: "tr-1"}, RecoveredByAPIRetry, "swarm.task.request", []byte(`{}`)); err != nil {
+		t.Fatalf("republish: %v", err)
+	}
+
+	if len(nc.msgs) != 1 || nc.msgs[0].Header.Get("traceparent") == "" {
+		t.Fatalf("expected a message with a traceparent header, got %+v", nc.msgs)
+	}
+	span := spanNamed(rec, "dlq.republish")
+	if span == nil || !hasAttr(span, AttrDLQID.String("tr-1")) {
+		t.Fatalf("expected a dlq.republish span with dlq.id, got %v", rec.Ended())
+	}
+	if !strings.Contains(nc.msgs[0].Header.Get("traceparent"), span.SpanContext().TraceID().String()) {
+		t.Error("republished message should carry the republish trace id")
+	}
+}
+
+func TestRepublish_PlainPublisherStillWorks(t *testing.T) {
+	recordSpans(t)
+	nc := newMockNATS()
+	if err := republish(context.Background(), nc, nil, newMockStore(), Entry{DLQID: "tr-2"}, RecoveredByAPIRetry, "swarm.task.request", []byte(`{}`)); err != nil {
+		t.Fatalf("republish: %v", err)
+	}
+	if len(nc.published()) != 1 {
+		t.Errorf("expected 1 message, got %d", len(nc.published()))
+	}
+}
+
+func TestHandler_TracesRoutes(t *testing.T) {
+	rec := recordSpans(t)
+	store := newMockStore()
+	store.seed(Entry{DLQID: "tr-3", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`)})
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, newMockNATS()).Routes())
+
+	if w := doAs(r, "POST", "/dlq/tr-3/retry", "alice", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	server := spanNamed(rec, "dlq.http")
+	if server == nil {
+		t.Fatal("expected a server span")
+	}
+	if !strings.HasSuffix(server.Name(), "POST /dlq/{dlqID}/retry") || !hasAttr(server, AttrDLQID.String("tr-3")) {
+		t.Errorf("unexpected server span %q %v", server.Name(), server.Attributes())
+	}
+	republished := spanNamed(rec, "dlq.republish")
+	if republished == nil || republished.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("republish span should be a child of the route span")
+	}
+}
+
+func TestQueryTracer(t *testing.T) {
+	rec := recordSpans(t)
+	var qt QueryTracer
+
+	ctx := qt.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	qt.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("boom")})
+
+	span := spanNamed(rec, "dlq.store.query")
+	if span == nil || !hasAttr(span, attribute.String("db.query.text", "SELECT 1")) {
+		t.Fatalf("expected a query span, got %v", rec.Ended())
+	}
+	if span.Status().Description != "boom" {
+		t.Errorf("expected error status, got %+v", span.Status())
+	}
+}
@@ -0,0 +1,219 @@
+package dlq
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// PayloadTransform rewrites an entry's payload before it is republished.
+type PayloadTransform interface {
+	Transform(e Entry, payload json.RawMessage) (json.RawMessage, error)
+}
+
+// PayloadTransformFunc adapts a function to PayloadTransform.
+type PayloadTransformFunc func(e Entry, payload json.RawMessage) (json.RawMessage, error)
+
+// Transform calls f.
+func (f PayloadTransformFunc) Transform(e Entry, payload json.RawMessage) (json.RawMessage, error) {
+	return f(e, payload)
+}
+
+// TransformRule applies Transform to entries matching Subject and Reason.
+// An empty Subject or Reason matches anything.
+type TransformRule struct {
+	Subject   string
+	Reason    string
+	Transform PayloadTransform
+}
+
+func (r TransformRule) matches(e Entry) bool {
+	return (r.Subject == "" || r.Subject == e.OriginalSubject) &&
+		(r.Reason == "" || r.Reason == e.Reason)
+}
+
+// Transformer applies an ordered list of TransformRules to payloads at
+// republish time, e.g. to bump schema_version after a contract change.
+// A nil *Transformer leaves payloads unchanged.
+type Transformer struct {
+	rules []TransformRule
+}
+
+// NewTransformer creates a Transformer. Every matching rule is applied, in order.
+func NewTransformer(rules ...TransformRule) *Transformer {
+	return &Transformer{rules: rules}
+}
+
+// Apply returns the payload to republish for e.
+func (t *Transformer) Apply(e Entry) (json.RawMessage, error) {
+	payload := e.OriginalPayload
+	if t == nil {
+		return payload, nil
+	}
+	for _, r := range t.rules {
+		if !r.matches(e) {
+			continue
+		}
+		out, err := r.Transform.Transform(e, payload)
+		if err != nil {
+			return nil, fmt.Errorf("transform payload for %s: %w", e.DLQID, err)
+		}
+		if !json.Valid(out) {
+			return nil, fmt.Errorf("transform payload for %s: result is not valid JSON", e.DLQID)
+		}
+		payload = out
+	}
+	return payload, nil
+}
+
+// decodePayload unmarshals payload into v with numbers kept as json.Number,
+// so integers beyond 2^53 survive being decoded and re-encoded.
+func decodePayload(payload json.RawMessage, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after the JSON value")
+	}
+	return nil
+}
+
+// TemplateData is the data passed to template transforms. Numbers in
+// Payload are json.Number.
+type TemplateData struct {
+	Entry   Entry
+	Payload any
+}
+
+// TemplateTransform renders a text/template whose output is the new payload.
+// The template receives TemplateData; the "json" function marshals a value.
+func TemplateTransform(text string) (PayloadTransform, error) {
+	tmpl, err := template.New("payload").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse payload template: %w", err)
+	}
+	return PayloadTransformFunc(func(e Entry, payload json.RawMessage) (json.RawMessage, error) {
+		var decoded any
+		if err := decodePayload(payload, &decoded); err != nil {
+			return nil, fmt.Errorf("decode payload: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, TemplateData{Entry: e, Payload: decoded}); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}), nil
+}
+
+// PatchOp is a JSON Patch (RFC 6902) operation. The add, replace and remove
+// operations are supported.
+type PatchOp struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value,omitempty"`
+}
+
+// JSONPatch returns a transform applying ops to the payload in order.
+func JSONPatch(ops ...PatchOp) PayloadTransform {
+	return PayloadTransformFunc(func(_ Entry, payload json.RawMessage) (json.RawMessage, error) {
+		var doc any
+		if err := decodePayload(payload, &doc); err != nil {
+			return nil, fmt.Errorf("decode payload: %w", err)
+		}
+		for _, op := range ops {
+			var err error
+			if doc, err = applyPatchOp(doc, op); err != nil {
+				return nil, err
+			}
+		}
+		return json.Marshal(doc)
+	})
+}
+
+func applyPatchOp(doc any, op PatchOp) (any, error) {
+	if op.Path == "" {
+		switch op.Op {
+		case "add", "replace":
+			return op.Value, nil
+		default:
+			return nil, fmt.Errorf("json patch: cannot %s the document root", op.Op)
+		}
+	}
+	if !strings.HasPrefix(op.Path, "/") {
+		return nil, fmt.Errorf("json patch: invalid path %q", op.Path)
+	}
+	tokens := strings.Split(op.Path[1:], "/")
+	for i, tok := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+	}
+
+	parent := doc
+	for _, tok := range tokens[:len(tokens)-1] {
+		switch node := parent.(type) {
+		case map[string]any:
+			next, ok := node[tok]
+			if !ok {
+				return nil, fmt.Errorf("json patch: path %q not found", op.Path)
+			}
+			parent = next
+		case []any:
+			idx, err := strconv.Atoi(tok)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, fmt.Errorf("json patch: path %q not found", op.Path)
+			}
+			parent = node[idx]
+		default:
+			return nil, fmt.Errorf("json patch: path %q not found", op.Path)
+		}
+	}
+
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		_, exists := node[last]
+		switch op.Op {
+		case "add":
+			node[last] = op.Value
+		case "replace":
+			if !exists {
+				return nil, fmt.Errorf("json patch: cannot replace missing %q", op.Path)
+			}
+			node[last] = op.Value
+		case "remove":
+			if !exists {
+				return nil, fmt.Errorf("json patch: cannot remove missing %q", op.Path)
+			}
+			delete(node, last)
+		default:
+			return nil, fmt.Errorf("json patch: unsupported op %q", op.Op)
+		}
+		return doc, nil
+	case []any:
+		// Array elements can only be replaced in place; add and remove would
+		// have to reallocate the slice held by the parent.
+		idx, err := strconv.Atoi(last)
+		if err != nil || idx < 0 || idx >= len(node) {
+			return nil, fmt.Errorf("json patch: path %q not found", op.Path)
+		}
+		switch op.Op {
+		case "replace":
+			node[idx] = op.Value
+			return doc, nil
+		default:
+			return nil, fmt.Errorf("json patch: op %q on array elements is not supported", op.Op)
+		}
+	default:
+		return nil, fmt.Errorf("json patch: path %q not found", op.Path)
+	}
+}
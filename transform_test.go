@@ -0,0 +1,150 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestJSONPatch_AddReplaceRemove(t *testing.T) {
+	patch := JSONPatch(
+		PatchOp{Op: "replace", Path: "/schema_version", Value: 2},
+		PatchOp{Op: "remove", Path: "/legacy_flag"},
+		PatchOp{Op: "add", Path: "/meta/replayed", Value: true},
+	)
+	out, err := patch.Transform(Entry{}, json.RawMessage(`{"schema_version":1,"legacy_flag":true,"meta":{}}`))
+	if err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+
+	var got map[string]any
+	_ = json.Unmarshal(out, &got)
+	if got["schema_version"] != float64(2) {
+		t.Errorf("expected schema_version 2, got %v", got["schema_version"])
+	}
+	if _, ok := got["legacy_flag"]; ok {
+		t.Error("expected legacy_flag removed")
+	}
+	if got["meta"].(map[string]any)["replayed"] != true {
+		t.Error("expected meta.replayed added")
+	}
+}
+
+func TestJSONPatch_MissingPath(t *testing.T) {
+	patch := JSONPatch(PatchOp{Op: "replace", Path: "/missing", Value: 1})
+	if _, err := patch.Transform(Entry{}, json.RawMessage(`{}`)); err == nil {
+		t.Error("expected error replacing a missing key")
+	}
+}
+
+func TestTemplateTransform(t *testing.T) {
+	tmpl, err := TemplateTransform(`{"task_id":{{json .Payload.task_id}},"schema_version":2,"dlq_id":{{json .Entry.DLQID}}}`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	out, err := tmpl.Transform(Entry{DLQID: "tt-1"}, json.RawMessage(`{"task_id":"t1","old":true}`))
+	if err != nil {
+		t.Fatalf("transform: %v", err)
+	}
+	if string(out) != `{"task_id":"t1","schema_version":2,"dlq_id":"tt-1"}` {
+		t.Errorf("unexpected output %s", out)
+	}
+}
+
+func TestTransforms_KeepLargeIntegers(t *testing.T) {
+	const payload = `{"id":9007199254740993,"old":true}`
+	patch := JSONPatch(PatchOp{Op: "remove", Path: "/old"})
+	out, err := patch.Transform(Entry{}, json.RawMessage(payload))
+	if err != nil || string(out) != `{"id":9007199254740993}` {
+		t.Errorf("patch: expected the id kept exactly, got %s (%v)", out, err)
+	}
+
+	tmpl, err := TemplateTransform(`{"id":{{json .Payload.id}}}`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	out, err = tmpl.Transform(Entry{}, json.RawMessage(payload))
+	if err != nil || string(out) != `{"id":9007199254740993}` {
+		t.Errorf("template: expected the id kept exactly, got %s (%v)", out, err)
+	}
+}
+
+func TestTransformer_RulesMatchSubjectAndReason(t *testing.T) {
+	tr := NewTransformer(
+		TransformRule{Subject: "swarm.task.request", Transform: JSONPatch(PatchOp{Op: "add", Path: "/v", Value: 2})},
+		TransformRule{Reason: ReasonBootFailure, Transform: JSONPatch(PatchOp{Op: "add", Path: "/boot", Value: true})},
+	)
+
+	out, _ := tr.Apply(Entry{OriginalSubject: "swarm.task.request", Reason: ReasonNoCapableAgent, OriginalPayload: json.RawMessage(`{}`)})
+	if string(out) != `{"v":2}` {
+		t.Errorf("expected only subject rule applied, got %s", out)
+	}
+
+	out, _ = tr.Apply(Entry{OriginalSubject: "other", Reason: ReasonNoCapableAgent, OriginalPayload: json.RawMessage(`{"x":1}`)})
+	if string(out) != `{"x":1}` {
+		t.Errorf("expected payload unchanged, got %s", out)
+	}
+
+	var nilTr *Transformer
+	out, _ = nilTr.Apply(Entry{OriginalPayload: json.RawMessage(`{"x":1}`)})
+	if string(out) != `{"x":1}` {
+		t.Errorf("expected nil transformer to pass through, got %s", out)
+	}
+}
+
+func TestTransformer_AppliedOnRetryAndScan(t *testing.T) {
+	tr := NewTransformer(TransformRule{Transform: JSONPatch(PatchOp{Op: "add", Path: "/schema_version", Value: 2})})
+
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(
+		Entry{DLQID: "tr-1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true},
+		Entry{DLQID: "tr-2", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: false},
+	)
+
+	NewScanner(store, nc, time.Minute, WithScannerTransformer(tr)).scan(context.Background())
+
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, nc, WithPayloadTransformer(tr)).Routes())
+	req := httptest.NewRequest("POST", "/dlq/tr-2/retry", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	msgs := nc.published()
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 republished messages, got %d", len(msgs))
+	}
+	for _, m := range msgs {
+		if string(m.Data) != `{"schema_version":2}` {
+			t.Errorf("expected transformed payload, got %s", m.Data)
+		}
+	}
+}
+
+func TestHandler_Retry_TransformFails(t *testing.T) {
+	tr := NewTransformer(TransformRule{Transform: JSONPatch(PatchOp{Op: "remove", Path: "/missing"})})
+	store := newMockStore()
+	nc := newMockNATS()
+	store.seed(Entry{DLQID: "tr-3", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`)})
+
+	r := chi.NewRouter()
+	r.Mount("/dlq", NewHandler(store, nc, WithPayloadTransformer(tr)).Routes())
+	req := httptest.NewRequest("POST", "/dlq/tr-3/retry", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422, got %d", w.Code)
+	}
+	if e, _ := store.Get(context.Background(), "tr-3"); e.Recovered {
+		t.Error("entry should not be recovered when the transform fails")
+	}
+}
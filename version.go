@@ -0,0 +1,56 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrVersionConflict is matched (with errors.Is) by the *VersionConflictError
+// that conditional updates return when the entry changed since it was read.
+var ErrVersionConflict = errors.New("dlq entry was modified concurrently")
+
+// VersionConflictError reports that a conditional update expected the entry
+// at Expected but found it at Current.
+type VersionConflictError struct {
+	DLQID    string
+	Expected int
+	Current  int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%s: %s is at version %d, not %d", ErrVersionConflict, e.DLQID, e.Current, e.Expected)
+}
+
+// Is reports whether target is ErrVersionConflict.
+func (e *VersionConflictError) Is(target error) bool { return target == ErrVersionConflict }
+
+// MarkRecoveredIfVersion marks a DLQ entry as recovered only if it is still
+// at version. It fails with a *VersionConflictError if the entry changed,
+// ErrAlreadyRecovered if it left the open queue, or a wrapped pgx.ErrNoRows.
+func (s *Store) MarkRecoveredIfVersion(ctx context.Context, dlqID, recoveredBy string, version int) (err error) {
+	defer s.observe("mark_recovered_if_version", time.Now(), &err)
+	return s.markRecovered(ctx, dlqID, recoveredBy, version)
+}
+
+// versionConflict explains why a conditional update expecting version
+// matched no row.
+func (s *Store) versionConflict(ctx context.Context, dlqID string, version int) error {
+	var recovered bool
+	var current int
+	err := s.pool.QueryRow(ctx, `SELECT recovered, version FROM swarm_dlq WHERE dlq_id = $1 AND `+tenantClause(2), dlqID, s.tenant(ctx)).Scan(&recovered, &current)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("dlq entry %s: %w", dlqID, pgx.ErrNoRows)
+	case err != nil:
+		return fmt.Errorf("check version: %w", err)
+	case current != version:
+		return &VersionConflictError{DLQID: dlqID, Expected: version, Current: current}
+	case recovered:
+		return ErrAlreadyRecovered
+	}
+	return fmt.Errorf("dlq entry %s not updated", dlqID)
+}
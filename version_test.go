@@ -0,0 +1,62 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_Discard_VersionConflict(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "v1", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`)})
+	r := newTestRouter(store, newMockNATS())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/dlq/v1/discard", strings.NewReader(`{"reason":"dup","version":7}`)))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a stale version, got %d: %s", w.Code, w.Body)
+	}
+	var resp map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["version"] != float64(1) {
+		t.Errorf("expected the current version in the response, got %v", resp)
+	}
+	if e, _ := store.Get(context.Background(), "v1"); e.Recovered {
+		t.Fatal("expected a conflicting discard to leave the entry open")
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/dlq/v1/discard", strings.NewReader(`{"reason":"dup","version":1}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for the current version, got %d: %s", w.Code, w.Body)
+	}
+	if e, _ := store.Get(context.Background(), "v1"); e.Status != StatusDiscarded || e.Version != 2 {
+		t.Errorf("expected a discarded entry at version 2, got %s v%d", e.Status, e.Version)
+	}
+}
+
+func TestScanner_DoesNotRecoverEntryChangedConcurrently(t *testing.T) {
+	store := newMockStore()
+	store.seed(Entry{DLQID: "v2", OriginalSubject: "swarm.task.request", OriginalPayload: json.RawMessage(`{}`), Recoverable: true})
+	// An operator claims the entry after the scanner listed it.
+	hook := func(ctx context.Context, e *Entry) (bool, error) {
+		_, err := store.Claim(ctx, e.DLQID, "alice", time.Minute)
+		return true, err
+	}
+	NewScanner(store, newMockNATS(), time.Minute, WithScannerBeforeRetry(hook)).scan(context.Background())
+
+	e, _ := store.Get(context.Background(), "v2")
+	if e.Recovered || e.ClaimedBy != "alice" {
+		t.Errorf("expected the claimed entry left open, got recovered=%v claimed_by=%q", e.Recovered, e.ClaimedBy)
+	}
+	err := store.MarkRecoveredIfVersion(context.Background(), "v2", RecoveredByScanner, 1)
+	var conflict *VersionConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrVersionConflict) || conflict.Current != 2 {
+		t.Errorf("expected a version conflict at version 2, got %v", err)
+	}
+}